@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/easy"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// batchOptions are the flags of the batch command
+type batchOptions struct {
+	input       string
+	processor   string
+	pipeline    string
+	config      string
+	output      string
+	format      string
+	textField   string
+	idField     string
+	extensions  string
+	concurrency int
+	checkpoint  string
+	progress    bool
+	replay      string
+	replayItems bool
+	report      string
+	lists       string
+	explode     string
+}
+
+// runBatch runs a processor or pipeline over every item of a CSV file, JSON Lines file or
+// directory, writing one result per item
+func runBatch(ctx context.Context, args []string) error {
+	var opts batchOptions
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: agentic-text batch -processor name | -pipeline file [flags] <input>")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "The input is a .csv or .jsonl file, or a directory whose files are each one item.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.processor, "processor", "", "registered processor to run")
+	flags.StringVar(&opts.pipeline, "pipeline", "", "pipeline config file (YAML or JSON) to run instead of a processor")
+	flags.StringVar(&opts.config, "config", "", "provider config file (JSON, YAML or TOML); AGENTIC_TEXT_* variables are used if unset")
+	flags.StringVar(&opts.output, "output", "-", `output file, or "-" for standard output`)
+	flags.StringVar(&opts.format, "format", "", "output format: jsonl, csv, json or xlsx (defaults to the output file's extension, else jsonl)")
+	flags.StringVar(&opts.textField, "text-field", "text", "CSV column or JSON Lines field holding the text")
+	flags.StringVar(&opts.idField, "id-field", "id", "CSV column or JSON Lines field holding the item ID")
+	flags.StringVar(&opts.extensions, "ext", "", "comma-separated file extensions read from a directory, e.g. .txt,.md (all if unset)")
+	flags.IntVar(&opts.concurrency, "concurrency", data.DefaultWorkers, "items processed at once")
+	flags.StringVar(&opts.checkpoint, "checkpoint", "", "checkpoint file recording finished items; rerunning with it skips them and appends to the output")
+	flags.BoolVar(&opts.progress, "progress", true, "show a progress bar on a terminal")
+	flags.StringVar(&opts.replay, "replay", "", "interaction log to answer from instead of calling providers, re-executing a logged run offline")
+	flags.StringVar(&opts.report, "report", "", "file to write a JSON run report to: counts, errors by type, latency percentiles, usage and slowest items per processor")
+	flags.StringVar(&opts.lists, "lists", "", "how csv and xlsx outputs write result lists: join, json or index (defaults to json for csv and join for xlsx)")
+	flags.StringVar(&opts.explode, "explode", "", "dotted path of a result list written as one csv or xlsx row per element, e.g. recommendations")
+	flags.BoolVar(&opts.replayItems, "replay-by-item", false, "with -replay, answer prompts that changed since the run with the response recorded for the same processor and item")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return usageError("exactly one input is required")
+	}
+	opts.input = flags.Arg(0)
+	if (opts.processor == "") == (opts.pipeline == "") {
+		return usageError("exactly one of -processor or -pipeline is required")
+	}
+	if opts.format == "" {
+		opts.format = formatFromPath(opts.output)
+	}
+	if _, ok := resultFormats[opts.format]; !ok {
+		return usageError(fmt.Sprintf("unknown format %q; use jsonl, csv, json or xlsx", opts.format))
+	}
+	if err := flattenConfig(opts).Validate(); err != nil {
+		return usageError(err.Error())
+	}
+	if opts.checkpoint != "" && (opts.output == "-" || opts.format == "json" || opts.format == "xlsx") {
+		return usageError("-checkpoint needs a jsonl or csv output file to append to")
+	}
+	if opts.replayItems && opts.replay == "" {
+		return usageError("-replay-by-item needs -replay")
+	}
+
+	var replay *llm.ReplayProvider
+	if opts.replay != "" {
+		interactions, err := llm.LoadInteractions(opts.replay)
+		if err != nil {
+			return err
+		}
+		replay = llm.NewReplayProvider(interactions)
+		if opts.replayItems {
+			replay.WithItemMatching()
+		}
+		defer printReplayStats(os.Stderr, replay)
+	}
+
+	process, err := newBatchProcess(opts, replay)
+	if err != nil {
+		return err
+	}
+
+	var checkpoints data.CheckpointStore = data.NewMemoryCheckpointStore()
+	if opts.checkpoint != "" {
+		if checkpoints, err = data.NewFileCheckpointStore(opts.checkpoint); err != nil {
+			return err
+		}
+	}
+	defer checkpoints.Close()
+
+	// Items are counted first so the progress bar knows the total
+	total, err := countPending(ctx, opts, checkpoints)
+	if err != nil {
+		return err
+	}
+
+	writer, err := newResultWriter(opts)
+	if err != nil {
+		return err
+	}
+	source, err := openSource(opts)
+	if err != nil {
+		writer.Close()
+		return err
+	}
+	defer source.Close()
+	source = pending(ctx, source, checkpoints)
+
+	// The run is cancelled if writing fails, so the workers stop
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var recorder *data.RunRecorder
+	if opts.report != "" {
+		if recorder, err = newBatchRecorder(opts); err != nil {
+			return err
+		}
+		ctx = data.WithRunRecorder(ctx, recorder)
+	}
+
+	bar := newProgressBar(os.Stderr, total, opts.progress)
+	var (
+		mu     sync.Mutex
+		failed = make(map[*data.ProcessItem]error)
+	)
+	results := data.ProcessStream(ctx, source, opts.concurrency, func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		// Failed items are passed through so one failure doesn't stop the batch
+		result, err := process(ctx, item)
+		if err != nil {
+			mu.Lock()
+			failed[item] = err
+			mu.Unlock()
+			return item, nil
+		}
+		return result, nil
+	})
+
+	var done, failures int
+	var runErr error
+	for res := range results {
+		if res.Err != nil {
+			runErr = fmt.Errorf("failed to read input: %w", res.Err)
+			continue
+		}
+
+		mu.Lock()
+		itemErr := failed[res.Item]
+		delete(failed, res.Item)
+		mu.Unlock()
+
+		if err := writer.Write(newResultRecord(res.Item, itemErr, opts)); err != nil {
+			runErr = err
+			break
+		}
+		done++
+		if itemErr != nil {
+			failures++
+		} else if err := checkpoints.MarkComplete(ctx, res.Item.ID); err != nil {
+			// Failed items aren't checkpointed so a resumed run retries them
+			runErr = err
+			break
+		}
+		bar.update(done, failures)
+	}
+	bar.finish()
+
+	if err := writer.Close(); err != nil && runErr == nil {
+		runErr = err
+	}
+	if recorder != nil {
+		if err := writeReport(opts.report, recorder.Report(done, runErr)); err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+	if runErr != nil {
+		return runErr
+	}
+	if ctx.Err() != nil {
+		if opts.checkpoint != "" {
+			return fmt.Errorf("interrupted after %d items; rerun with the same -checkpoint to resume", done)
+		}
+		return fmt.Errorf("interrupted after %d items", done)
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d items failed", failures, done)
+	}
+	return nil
+}
+
+// newBatchProcess returns the function run on each item: the processor, whose result is
+// its processing info, or the pipeline, whose result is the processing info of every step.
+// If replay is set, it answers every step instead of the configured providers.
+func newBatchProcess(opts batchOptions, replay *llm.ReplayProvider) (func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error), error) {
+	if opts.pipeline != "" {
+		config, err := pipeline.LoadConfig(opts.pipeline)
+		if err != nil {
+			return nil, err
+		}
+		var p pipeline.Pipeline
+		if replay != nil {
+			p, err = config.BuildWithProvider(replay)
+		} else {
+			p, err = config.Build()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return p.Process, nil
+	}
+
+	config, err := loadProviderConfig(opts.config)
+	if err != nil {
+		return nil, err
+	}
+	if replay != nil {
+		return newProcessWithProvider(opts.processor, replay, config)
+	}
+	return newProcess(opts.processor, config)
+}
+
+// newBatchRecorder creates the recorder of the run report, pricing usage with the provider
+// config's token costs when running a processor
+func newBatchRecorder(opts batchOptions) (*data.RunRecorder, error) {
+	name := opts.processor
+	var config data.ReportConfig
+	if opts.pipeline != "" {
+		name = strings.TrimSuffix(filepath.Base(opts.pipeline), filepath.Ext(opts.pipeline))
+	} else {
+		providerConfig, err := loadProviderConfig(opts.config)
+		if err != nil {
+			return nil, err
+		}
+		config.InputTokenCost = providerConfig.InputTokenCost
+		config.OutputTokenCost = providerConfig.OutputTokenCost
+	}
+	return data.NewRunRecorder(name, config), nil
+}
+
+// writeReport writes a run report as indented JSON
+func writeReport(path string, report *data.RunReport) error {
+	encoded, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run report: %w", err)
+	}
+	if err := os.WriteFile(path, append(encoded, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write run report: %w", err)
+	}
+	return nil
+}
+
+// printReplayStats reports how many calls a replayed run answered from the log
+func printReplayStats(w io.Writer, replay *llm.ReplayProvider) {
+	stats := replay.Stats()
+	fmt.Fprintf(w, "replayed %d responses", stats.Replayed)
+	if stats.MatchedByItem > 0 {
+		fmt.Fprintf(w, " (%d matched by item)", stats.MatchedByItem)
+	}
+	if stats.Missing > 0 {
+		fmt.Fprintf(w, "; %d prompts weren't in the log", stats.Missing)
+	}
+	fmt.Fprintln(w)
+}
+
+// loadProviderConfig reads the provider config from a file, or from the AGENTIC_TEXT_*
+// environment variables if path is empty
+func loadProviderConfig(path string) (*easy.Config, error) {
+	if path != "" {
+		return easy.ConfigFromFile(path)
+	}
+	return easy.ConfigFromEnv()
+}
+
+// newProcess creates a registered processor with the configured provider
+func newProcess(name string, config *easy.Config) (func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error), error) {
+	if !slices.Contains(processor.ListProcessors(), name) {
+		return nil, usageError(fmt.Sprintf("unknown processor %s", name))
+	}
+	provider, err := config.NewProvider()
+	if err != nil {
+		return nil, err
+	}
+	return newProcessWithProvider(name, provider, config)
+}
+
+// newProcessWithProvider creates a registered processor with a provider and the config's
+// processor options
+func newProcessWithProvider(name string, provider llm.Provider, config *easy.Config) (func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error), error) {
+	if !slices.Contains(processor.ListProcessors(), name) {
+		return nil, usageError(fmt.Sprintf("unknown processor %s", name))
+	}
+	proc, err := processor.Create(name, provider, processor.Options{LLMOptions: config.Options})
+	if err != nil {
+		return nil, err
+	}
+	return proc.Process, nil
+}
+
+// openSource opens the input as a CSV, JSON Lines or directory source
+func openSource(opts batchOptions) (data.ProcessItemSource, error) {
+	info, err := os.Stat(opts.input)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		var extensions []string
+		for _, ext := range strings.Split(opts.extensions, ",") {
+			if ext = strings.TrimSpace(ext); ext != "" {
+				extensions = append(extensions, "."+strings.TrimPrefix(ext, "."))
+			}
+		}
+		return data.NewDirectorySource(data.DirectorySourceConfig{Path: opts.input, Extensions: extensions})
+	}
+
+	switch strings.ToLower(filepath.Ext(opts.input)) {
+	case ".csv":
+		return data.NewCSVSource(data.CSVSourceConfig{Path: opts.input, IDColumn: opts.idField, ContentColumn: opts.textField})
+	case ".jsonl", ".ndjson":
+		return data.NewJSONLSource(data.JSONLSourceConfig{Path: opts.input, IDField: opts.idField, ContentField: opts.textField})
+	default:
+		return nil, usageError(fmt.Sprintf("unsupported input %s; use a .csv or .jsonl file, or a directory", opts.input))
+	}
+}
+
+// pending skips the items of a source that earlier runs completed
+func pending(ctx context.Context, source data.ProcessItemSource, checkpoints data.CheckpointStore) data.ProcessItemSource {
+	return data.Filter(source, func(item *data.ProcessItem) bool {
+		done, _ := checkpoints.IsComplete(ctx, item.ID)
+		return !done
+	})
+}
+
+// countPending reads the input once to count the items left to process
+func countPending(ctx context.Context, opts batchOptions, checkpoints data.CheckpointStore) (int, error) {
+	source, err := openSource(opts)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+
+	source = pending(ctx, source, checkpoints)
+	count := 0
+	for {
+		_, err := source.NextProcessItem(ctx)
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read input: %w", err)
+		}
+		count++
+	}
+}
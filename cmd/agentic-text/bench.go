@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/bench"
+)
+
+// benchOptions are the flags of the bench command
+type benchOptions struct {
+	processor  string
+	chain      string
+	items      int
+	workers    string
+	latency    time.Duration
+	duration   time.Duration
+	scenarios  string
+	format     string
+	cpuProfile string
+	memProfile string
+}
+
+// runBench measures the throughput and allocations of processing items with a mock
+// provider at several worker counts
+func runBench(ctx context.Context, args []string) error {
+	var opts benchOptions
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: agentic-text bench [flags]")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Scenarios:")
+		for _, scenario := range bench.Scenarios {
+			fmt.Fprintf(flags.Output(), "  %-15s %s\n", scenario, bench.Describe(scenario))
+		}
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.processor, "processor", "sentiment", "registered processor of the process_batch and process_source scenarios")
+	flags.StringVar(&opts.chain, "chain", "sentiment,intent", "comma-separated processors of the chain scenarios")
+	flags.IntVar(&opts.items, "items", 100, "items processed per operation")
+	flags.StringVar(&opts.workers, "workers", "1,2,4,8", "comma-separated worker counts")
+	flags.DurationVar(&opts.latency, "latency", 0, "simulated response time of the mock provider, e.g. 50ms")
+	flags.DurationVar(&opts.duration, "duration", 2*time.Second, "time each measurement runs at least")
+	flags.StringVar(&opts.scenarios, "scenarios", strings.Join(bench.Scenarios, ","), "comma-separated scenarios to run")
+	flags.StringVar(&opts.format, "format", "text", "output format: text or json")
+	flags.StringVar(&opts.cpuProfile, "cpuprofile", "", "file to write a CPU profile to")
+	flags.StringVar(&opts.memProfile, "memprofile", "", "file to write an allocation profile to")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 0 {
+		return usageError("unexpected arguments")
+	}
+	if opts.format != "text" && opts.format != "json" {
+		return usageError("-format must be text or json")
+	}
+	if opts.items <= 0 {
+		return usageError("-items must be positive")
+	}
+	workers, err := parseWorkerCounts(opts.workers)
+	if err != nil {
+		return err
+	}
+	scenarios := splitList(opts.scenarios)
+	for _, scenario := range scenarios {
+		if bench.Describe(scenario) == "" {
+			return usageError(fmt.Sprintf("unknown scenario %q", scenario))
+		}
+	}
+
+	if opts.cpuProfile != "" {
+		f, err := os.Create(opts.cpuProfile)
+		if err != nil {
+			return fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	// Each measurement is reported on standard error as it finishes, since a run takes a while
+	report := func(result bench.Result) {
+		fmt.Fprintf(os.Stderr, "measured %s with %d workers\n", result.Scenario, result.Workers)
+	}
+	results, err := bench.Run(ctx, bench.Config{
+		Processor: opts.processor,
+		Chain:     splitList(opts.chain),
+		Items:     opts.items,
+		Workers:   workers,
+		Latency:   opts.latency,
+		Duration:  opts.duration,
+		Scenarios: scenarios,
+	}, report)
+	if err != nil {
+		return err
+	}
+
+	if opts.memProfile != "" {
+		if err := writeMemProfile(opts.memProfile); err != nil {
+			return err
+		}
+	}
+	if opts.format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+	fmt.Printf("mock latency %s, %d items per operation, GOMAXPROCS %d\n\n", opts.latency, opts.items, runtime.GOMAXPROCS(0))
+	printBenchResults(os.Stdout, results)
+	return nil
+}
+
+// printBenchResults prints results as a table
+func printBenchResults(w io.Writer, results []bench.Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "scenario\tworkers\titems/s\tns/item\tallocs/item\tB/item")
+	for _, result := range results {
+		workers := strconv.Itoa(result.Workers)
+		if !bench.TakesWorkers(result.Scenario) {
+			workers = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%.0f\t%.1f\t%.0f\n",
+			result.Scenario, workers, result.ItemsPerSecond, result.NsPerItem, result.AllocsPerItem, result.BytesPerItem)
+	}
+	tw.Flush()
+}
+
+// writeMemProfile writes the allocations made so far to a file
+func writeMemProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create allocation profile: %w", err)
+	}
+	defer f.Close()
+	if err := pprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+		return fmt.Errorf("failed to write allocation profile: %w", err)
+	}
+	return nil
+}
+
+// parseWorkerCounts parses a comma-separated list of positive worker counts
+func parseWorkerCounts(list string) ([]int, error) {
+	var counts []int
+	for _, field := range splitList(list) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n <= 0 {
+			return nil, usageError(fmt.Sprintf("invalid worker count %q", field))
+		}
+		counts = append(counts, n)
+	}
+	if len(counts) == 0 {
+		return nil, usageError("-workers needs at least one worker count")
+	}
+	return counts, nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/eval"
+)
+
+// evalOptions are the flags of the eval command
+type evalOptions struct {
+	dataset        string
+	processor      string
+	config         string
+	textField      string
+	idField        string
+	expectedField  string
+	tolerance      float64
+	judge          string
+	judgeThreshold float64
+	agreement      bool
+	concurrency    int
+	baseline       string
+	updateBaseline bool
+	maxDrop        float64
+	output         string
+	progress       bool
+}
+
+// runEval runs a processor against a labeled dataset, reports its quality and compares it
+// with a baseline, failing if quality regressed
+func runEval(ctx context.Context, args []string) error {
+	var opts evalOptions
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: agentic-text eval -dataset cases.jsonl -processor name [flags]")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), `Each dataset line is an object such as {"id": "1", "text": "...", "expected": {"sentiment": "positive"}}.`)
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.dataset, "dataset", "", "JSON Lines or CSV file of labeled cases")
+	flags.StringVar(&opts.processor, "processor", "", "registered processor to evaluate")
+	flags.StringVar(&opts.config, "config", "", "provider config file (JSON, YAML or TOML); AGENTIC_TEXT_* variables are used if unset")
+	flags.StringVar(&opts.textField, "text-field", "text", "field holding each case's text")
+	flags.StringVar(&opts.idField, "id-field", "id", "field holding each case's ID")
+	flags.StringVar(&opts.expectedField, "expected-field", "expected", "field holding each case's expected result fields, or the prefix of CSV columns holding them")
+	flags.Float64Var(&opts.tolerance, "tolerance", eval.DefaultTolerance, "largest difference at which numbers match")
+	flags.StringVar(&opts.judge, "judge", "", "comma-separated fields scored by LLM-judged similarity instead of exact match")
+	flags.Float64Var(&opts.judgeThreshold, "judge-threshold", eval.DefaultJudgeThreshold, "similarity at which a judged field matches")
+	flags.BoolVar(&opts.agreement, "agreement", false, "also score label and number fields by Cohen's kappa and Krippendorff's alpha")
+	flags.IntVar(&opts.concurrency, "concurrency", data.DefaultWorkers, "cases processed at once")
+	flags.StringVar(&opts.baseline, "baseline", "", "run report of an earlier run to compare with")
+	flags.BoolVar(&opts.updateBaseline, "update-baseline", false, "write this run's report to -baseline instead of comparing with it")
+	flags.Float64Var(&opts.maxDrop, "max-drop", 0, "largest drop of a metric from the baseline that isn't a regression")
+	flags.StringVar(&opts.output, "output", "", "file to write this run's report to")
+	flags.BoolVar(&opts.progress, "progress", true, "show a progress bar on a terminal")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 0 {
+		return usageError("unexpected arguments; the dataset is given with -dataset")
+	}
+	if opts.dataset == "" || opts.processor == "" {
+		return usageError("-dataset and -processor are required")
+	}
+	if opts.updateBaseline && opts.baseline == "" {
+		return usageError("-update-baseline needs -baseline")
+	}
+
+	config, err := loadProviderConfig(opts.config)
+	if err != nil {
+		return err
+	}
+	process, err := newProcess(opts.processor, config)
+	if err != nil {
+		return err
+	}
+	cases, err := eval.LoadDataset(opts.dataset, eval.DatasetConfig{
+		TextField:     opts.textField,
+		IDField:       opts.idField,
+		ExpectedField: opts.expectedField,
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics := eval.DefaultMetrics(cases, opts.tolerance)
+	if opts.agreement {
+		for field, agreement := range eval.AgreementMetrics(cases) {
+			metrics[field] = append(metrics[field], agreement...)
+		}
+	}
+	if opts.judge != "" {
+		judge, err := config.NewProvider()
+		if err != nil {
+			return err
+		}
+		for _, field := range strings.Split(opts.judge, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				metrics[field] = []eval.Metric{eval.LLMJudge{Provider: judge, Threshold: opts.judgeThreshold}}
+			}
+		}
+	}
+
+	bar := newProgressBar(os.Stderr, len(cases), opts.progress)
+	run, err := eval.Evaluate(ctx, cases, process, eval.Config{
+		Name:       opts.processor,
+		ResultKey:  opts.processor,
+		Metrics:    metrics,
+		Workers:    opts.concurrency,
+		OnProgress: bar.update,
+	})
+	bar.finish()
+	if err != nil {
+		return err
+	}
+
+	if opts.output != "" {
+		if err := run.Save(opts.output); err != nil {
+			return err
+		}
+	}
+
+	var baseline *eval.Run
+	if opts.baseline != "" && !opts.updateBaseline {
+		if baseline, err = eval.LoadRun(opts.baseline); err != nil {
+			return err
+		}
+	}
+	printEvalRun(os.Stdout, run, baseline)
+
+	if opts.updateBaseline {
+		if err := run.Save(opts.baseline); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote baseline %s\n", opts.baseline)
+		return nil
+	}
+	if baseline == nil {
+		return nil
+	}
+	if comparison := eval.Compare(baseline, run, opts.maxDrop); comparison.Regressed() {
+		printEvalDiff(os.Stdout, comparison)
+		return fmt.Errorf("quality regressed against %s", opts.baseline)
+	}
+	return nil
+}
+
+// printEvalRun prints a run's metrics, next to the baseline's if there is one
+func printEvalRun(w io.Writer, run, baseline *eval.Run) {
+	fmt.Fprintf(w, "%s: %d cases, %d errors\n\n", run.Name, run.Cases, run.Errors)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if baseline != nil {
+		fmt.Fprintln(tw, "metric\tcurrent\tbaseline\tchange")
+	} else {
+		fmt.Fprintln(tw, "metric\tvalue")
+	}
+	row := func(name string, current float64, before *float64) {
+		switch {
+		case baseline == nil:
+			fmt.Fprintf(tw, "%s\t%.3f\n", name, current)
+		case before == nil:
+			fmt.Fprintf(tw, "%s\t%.3f\t-\t\n", name, current)
+		default:
+			fmt.Fprintf(tw, "%s\t%.3f\t%.3f\t%+.3f\n", name, current, *before, current-*before)
+		}
+	}
+
+	var baseAccuracy, baseF1 *float64
+	if baseline != nil {
+		baseAccuracy, baseF1 = &baseline.Accuracy, baseline.F1
+	}
+	row("accuracy", run.Accuracy, baseAccuracy)
+	if run.F1 != nil {
+		row("f1", *run.F1, baseF1)
+	}
+	for _, field := range sortedNames(run.Fields) {
+		for _, metric := range sortedNames(run.Fields[field]) {
+			var before *float64
+			if baseline != nil {
+				if value, ok := baseline.Fields[field][metric]; ok {
+					before = &value
+				}
+			}
+			row(field+" "+metric, run.Fields[field][metric], before)
+		}
+	}
+	tw.Flush()
+}
+
+// printEvalDiff prints the regressions and the cases that passed in the baseline but fail now
+func printEvalDiff(w io.Writer, comparison *eval.Comparison) {
+	fmt.Fprintln(w, "\nRegressions:")
+	for _, regression := range comparison.Regressions {
+		fmt.Fprintf(w, "  %s\n", regression)
+	}
+
+	if len(comparison.NewFailures) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nCases that passed in the baseline and fail now:")
+	for _, result := range comparison.NewFailures {
+		if result.Error != "" {
+			fmt.Fprintf(w, "  %s: error: %s\n", result.ID, result.Error)
+			continue
+		}
+		for _, mismatch := range result.Mismatches {
+			expected, _ := json.Marshal(mismatch.Expected)
+			actual, _ := json.Marshal(mismatch.Actual)
+			fmt.Fprintf(w, "  %s: %s: expected %s, got %s\n", result.ID, mismatch.Field, expected, actual)
+		}
+	}
+}
+
+// sortedNames returns the keys of a map in order
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/eval"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// improveOptions are the flags of the improve command
+type improveOptions struct {
+	dataset       string
+	definition    string
+	config        string
+	criticConfig  string
+	textField     string
+	idField       string
+	expectedField string
+	iterations    int
+	minGain       float64
+	reviewCases   int
+	concurrency   int
+	output        string
+	report        string
+}
+
+// runImprove iterates on the prompt of a declarative processor with quality_reviewer
+// critiques and writes the best-scoring version
+func runImprove(ctx context.Context, args []string) error {
+	var opts improveOptions
+	flags := flag.NewFlagSet("improve", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: agentic-text improve -dataset samples.jsonl -definition processor.yaml -output improved.yaml [flags]")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), `Each dataset line is a case such as {"id": "1", "text": "...", "expected": {}}; expected fields are optional.`)
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.dataset, "dataset", "", "JSON Lines or CSV file of sample cases, labeled or not")
+	flags.StringVar(&opts.definition, "definition", "", "YAML or JSON processor definition whose prompt is improved")
+	flags.StringVar(&opts.config, "config", "", "provider config file (JSON, YAML or TOML); AGENTIC_TEXT_* variables are used if unset")
+	flags.StringVar(&opts.criticConfig, "critic-config", "", "provider config file of the model reviewing outputs and revising the prompt (defaults to -config)")
+	flags.StringVar(&opts.textField, "text-field", "text", "field holding each case's text")
+	flags.StringVar(&opts.idField, "id-field", "id", "field holding each case's ID")
+	flags.StringVar(&opts.expectedField, "expected-field", "expected", "field holding each case's expected result fields, or the prefix of CSV columns holding them")
+	flags.IntVar(&opts.iterations, "iterations", eval.DefaultImproveIterations, "most prompt versions to evaluate, including the original")
+	flags.Float64Var(&opts.minGain, "min-gain", eval.DefaultImproveMinGain, "smallest score gain that counts as progress")
+	flags.IntVar(&opts.reviewCases, "review-cases", eval.DefaultReviewCases, "outputs reviewed per version")
+	flags.IntVar(&opts.concurrency, "concurrency", data.DefaultWorkers, "cases processed at once")
+	flags.StringVar(&opts.output, "output", "", "file to write the recommended definition to (YAML or JSON)")
+	flags.StringVar(&opts.report, "report", "", "file to write every version, its scores and the reviewer's suggestions to as JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 0 {
+		return usageError("unexpected arguments; the dataset is given with -dataset")
+	}
+	if opts.dataset == "" || opts.definition == "" || opts.output == "" {
+		return usageError("-dataset, -definition and -output are required")
+	}
+
+	definition, err := processor.LoadDefinition(opts.definition)
+	if err != nil {
+		return err
+	}
+	config, err := loadProviderConfig(opts.config)
+	if err != nil {
+		return err
+	}
+	provider, err := config.NewProvider()
+	if err != nil {
+		return err
+	}
+	critic := provider
+	if opts.criticConfig != "" {
+		criticConfig, err := loadProviderConfig(opts.criticConfig)
+		if err != nil {
+			return err
+		}
+		if critic, err = criticConfig.NewProvider(); err != nil {
+			return err
+		}
+	}
+	cases, err := eval.LoadDataset(opts.dataset, eval.DatasetConfig{
+		TextField:     opts.textField,
+		IDField:       opts.idField,
+		ExpectedField: opts.expectedField,
+	})
+	if err != nil {
+		return err
+	}
+
+	improvement, err := eval.Improve(ctx, cases, *definition, provider, eval.ImproveConfig{
+		Critic:        critic,
+		Options:       processor.Options{LLMOptions: config.Options},
+		MaxIterations: opts.iterations,
+		MinGain:       opts.minGain,
+		ReviewCases:   opts.reviewCases,
+		Eval:          eval.Config{Workers: opts.concurrency},
+		OnIteration: func(iteration eval.PromptIteration) {
+			fmt.Fprintf(os.Stderr, "version %d: score %.3f\n", iteration.Version, iteration.Score)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.report != "" {
+		if err := improvement.Save(opts.report); err != nil {
+			return err
+		}
+	}
+	recommended := improvement.RecommendedDefinition()
+	if err := recommended.Save(opts.output); err != nil {
+		return err
+	}
+	printImprovement(os.Stdout, improvement)
+	fmt.Fprintf(os.Stderr, "wrote version %d to %s\n", improvement.Recommended, opts.output)
+	return nil
+}
+
+// printImprovement prints the scores of every prompt version, marking the recommended one
+func printImprovement(w io.Writer, improvement *eval.Improvement) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "version\tscore\taccuracy\treview score\tsuggestions\t")
+	for _, iteration := range improvement.Iterations {
+		marker := ""
+		if iteration.Version == improvement.Recommended {
+			marker = "recommended"
+		}
+		fmt.Fprintf(tw, "%d\t%.3f\t%.3f\t%.3f\t%d\t%s\n", iteration.Version, iteration.Score,
+			iteration.Run.Accuracy, iteration.ReviewScore, len(iteration.Suggestions), marker)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,114 @@
+// Command agentic-text runs the registered processors and pipelines from the command line.
+//
+// Usage:
+//
+//	agentic-text <command> [flags] [arguments]
+//
+// Run "agentic-text <command> -h" for a command's flags.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	// Import the builtin processors
+	_ "github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+// command is a subcommand of the CLI
+type command struct {
+	// summary is the one-line description shown in the usage message
+	summary string
+	// run runs the command with its arguments, which don't include the command name
+	run func(ctx context.Context, args []string) error
+}
+
+// commands are the CLI's subcommands, by name
+var commands = map[string]command{
+	"batch":   {summary: "Run a processor or pipeline over a CSV, JSON Lines or directory input", run: runBatch},
+	"bench":   {summary: "Measure throughput and allocations of processing with a mock provider", run: runBench},
+	"eval":    {summary: "Score a processor against labeled cases and compare with a baseline", run: runEval},
+	"improve": {summary: "Iterate on a processor definition's prompt with reviewer critiques", run: runImprove},
+	"new":     {summary: "Generate the files of a new processor", run: runNew},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "-help" || os.Args[1] == "help" {
+		usage()
+		if len(os.Args) < 2 {
+			os.Exit(2)
+		}
+		return
+	}
+
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "agentic-text: unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	// Interrupting stops the command cleanly, e.g. so a batch can resume from its checkpoint
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := cmd.run(ctx, os.Args[2:])
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.As(err, new(usageError)):
+		if err.Error() != "" {
+			fmt.Fprintf(os.Stderr, "agentic-text %s: %v\n", name, err)
+		}
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "agentic-text %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the commands to standard error
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: agentic-text <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `Run "agentic-text <command> -h" for a command's flags.`)
+}
+
+// usageError is a mistake in a command's arguments, reported with exit status 2
+type usageError string
+
+// Error implements error
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// parseFlags parses a command's flags. The flag set prints its own message for -h and
+// for invalid flags, so the usage error returned for the latter is empty.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	flags.SetOutput(os.Stderr)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError("")
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// scaffoldTemplates are the files generated by "new processor"
+//
+//go:embed templates/*.tmpl
+var scaffoldTemplates embed.FS
+
+// processorNamePattern matches the processor names "new processor" accepts
+var processorNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// packageNamePattern matches the package names "new processor" accepts
+var packageNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// scaffold is the data of the generated files
+type scaffold struct {
+	// Name is the processor's registered name, e.g. "ticket_topic"
+	Name string
+	// Type is the Go name derived from Name, e.g. "TicketTopic"
+	Type string
+	// Package is the package of the generated Go files
+	Package string
+}
+
+// runNew generates the starting files of a new processor
+func runNew(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("new", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: agentic-text new processor [flags] <name>")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Generates <name>.go, <name>_test.go and <name>.yaml for a processor named in snake_case.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	dir := flags.String("dir", ".", "directory to write the files to")
+	pkg := flags.String("package", "", "package of the Go files (defaults to the directory's name, else processors)")
+	force := flags.Bool("force", false, "overwrite existing files")
+
+	if len(args) == 0 || args[0] != "processor" {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help") {
+			flags.SetOutput(os.Stderr)
+			flags.Usage()
+			return flag.ErrHelp
+		}
+		return usageError(`the only kind of thing "new" generates is "processor"`)
+	}
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError("exactly one processor name is required")
+	}
+
+	name := flags.Arg(0)
+	if !processorNamePattern.MatchString(name) {
+		return usageError(fmt.Sprintf("invalid processor name %q; use snake_case, e.g. ticket_topic", name))
+	}
+	data := scaffold{Name: name, Type: camelCase(name), Package: *pkg}
+	if data.Package == "" {
+		data.Package = defaultPackage(*dir)
+	}
+	if !packageNamePattern.MatchString(data.Package) {
+		return usageError(fmt.Sprintf("invalid package name %q", data.Package))
+	}
+
+	files := []struct{ path, template string }{
+		{filepath.Join(*dir, name+".go"), "processor.go.tmpl"},
+		{filepath.Join(*dir, name+"_test.go"), "processor_test.go.tmpl"},
+		{filepath.Join(*dir, name+".yaml"), "processor.yaml.tmpl"},
+	}
+
+	// Nothing is written if any file exists, so a refused run leaves no partial scaffold
+	if !*force {
+		for _, file := range files {
+			if _, err := os.Stat(file.path); err == nil {
+				return fmt.Errorf("%s already exists; use -force to overwrite it", file.path)
+			}
+		}
+	}
+
+	rendered := make([][]byte, len(files))
+	for i, file := range files {
+		content, err := renderScaffold(file.template, data)
+		if err != nil {
+			return err
+		}
+		rendered[i] = content
+	}
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", *dir, err)
+	}
+	for i, file := range files {
+		if err := os.WriteFile(file.path, rendered[i], 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.path, err)
+		}
+		fmt.Fprintln(os.Stderr, "created", file.path)
+	}
+	fmt.Fprintf(os.Stderr, "\nEdit the result struct and prompt in %s, then run go test in %s.\n", files[0].path, *dir)
+	fmt.Fprintf(os.Stderr, "To define the processor without code, load %s with processor.RegisterDefinitionFile instead.\n", files[2].path)
+	return nil
+}
+
+// renderScaffold executes a template, formatting the result if it's Go source
+func renderScaffold(name string, data scaffold) ([]byte, error) {
+	tmpl, err := template.ParseFS(scaffoldTemplates, "templates/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	if !strings.HasSuffix(name, ".go.tmpl") {
+		return buf.Bytes(), nil
+	}
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format %s: %w", name, err)
+	}
+	return formatted, nil
+}
+
+// camelCase converts a snake_case name to an exported Go identifier, e.g. "ticket_topic"
+// to "TicketTopic"
+func camelCase(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// defaultPackage returns the package name for a directory: its base name if that is a
+// valid package name, else "processors"
+func defaultPackage(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "processors"
+	}
+	base := strings.ToLower(strings.ReplaceAll(filepath.Base(abs), "-", ""))
+	if !packageNamePattern.MatchString(base) {
+		return "processors"
+	}
+	return base
+}
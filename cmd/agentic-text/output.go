@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// resultFormats are the output formats of the batch command, by name
+var resultFormats = map[string]bool{"jsonl": true, "csv": true, "json": true, "xlsx": true}
+
+// resultRecord is the output of one item
+type resultRecord struct {
+	ID string `json:"id"`
+	// Result is the processor's result, or the results of every pipeline step by step name
+	Result interface{} `json:"result,omitempty"`
+	// Metadata holds the input's other columns or fields
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// Error describes why the item failed
+	Error string `json:"error,omitempty"`
+}
+
+// newResultRecord creates the record of a processed or failed item
+func newResultRecord(item *data.ProcessItem, err error, opts batchOptions) resultRecord {
+	record := resultRecord{ID: item.ID, Metadata: item.Metadata}
+	switch {
+	case err != nil:
+		record.Error = err.Error()
+	case opts.processor != "":
+		record.Result = item.ProcessingInfo[opts.processor]
+	default:
+		record.Result = item.ProcessingInfo
+	}
+	return record
+}
+
+// resultWriter writes result records in one output format
+type resultWriter interface {
+	// Write writes a record; it is in the output before Write returns unless the format
+	// can only be written once every record is known
+	Write(record resultRecord) error
+	// Close writes anything left and closes the output
+	Close() error
+}
+
+// formatFromPath returns the output format matching a file's extension, or jsonl
+func formatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json"
+	case ".xlsx":
+		return "xlsx"
+	default:
+		return "jsonl"
+	}
+}
+
+// flattenConfig returns how the csv and xlsx formats flatten results into columns
+func flattenConfig(opts batchOptions) data.FlattenConfig {
+	config := data.FlattenConfig{Lists: data.ListMode(opts.lists), Explode: opts.explode}
+	if config.Lists == "" && opts.format == "csv" {
+		config.Lists = data.ListJSON
+	}
+	return config
+}
+
+// newResultWriter creates a writer for the output format writing to the output file ("-"
+// for standard output), appending to an existing file if the run is checkpointed
+func newResultWriter(opts batchOptions) (resultWriter, error) {
+	var (
+		file     *os.File
+		existing int64
+		path     = opts.output
+	)
+	if path == "-" {
+		file = os.Stdout
+	} else {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if opts.checkpoint != "" {
+			flags = os.O_CREATE | os.O_RDWR | os.O_APPEND
+		}
+		var err error
+		if file, err = os.OpenFile(path, flags, 0o644); err != nil {
+			return nil, fmt.Errorf("failed to open output: %w", err)
+		}
+		if info, err := file.Stat(); err == nil {
+			existing = info.Size()
+		}
+	}
+
+	switch opts.format {
+	case "csv":
+		w := &csvResultWriter{file: file, writer: csv.NewWriter(file), flatten: flattenConfig(opts)}
+		if existing > 0 {
+			// A resumed run keeps the columns of the rows already written
+			header, err := csv.NewReader(io.NewSectionReader(file, 0, existing)).Read()
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to read CSV header of %s: %w", path, err)
+			}
+			w.header = header
+		}
+		return w, nil
+	case "json":
+		return &jsonResultWriter{file: file}, nil
+	case "xlsx":
+		sink, err := data.NewXLSXSink(file, flattenConfig(opts))
+		if err != nil {
+			closeOutput(file)
+			return nil, err
+		}
+		return &xlsxResultWriter{file: file, sink: sink, processor: opts.processor}, nil
+	default:
+		return &jsonlResultWriter{file: file, writer: bufio.NewWriter(file)}, nil
+	}
+}
+
+// closeOutput closes an output file, leaving standard output open
+func closeOutput(file *os.File) error {
+	if file == os.Stdout {
+		return nil
+	}
+	return file.Close()
+}
+
+// jsonlResultWriter writes one JSON record per line
+type jsonlResultWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// Write implements resultWriter
+func (w *jsonlResultWriter) Write(record resultRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode result of %s: %w", record.ID, err)
+	}
+	w.writer.Write(append(line, '\n'))
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// Close implements resultWriter
+func (w *jsonlResultWriter) Close() error {
+	return closeOutput(w.file)
+}
+
+// jsonResultWriter writes every record as one indented JSON array once all are known
+type jsonResultWriter struct {
+	file    *os.File
+	records []resultRecord
+}
+
+// Write implements resultWriter
+func (w *jsonResultWriter) Write(record resultRecord) error {
+	w.records = append(w.records, record)
+	return nil
+}
+
+// Close implements resultWriter
+func (w *jsonResultWriter) Close() error {
+	records := w.records
+	if records == nil {
+		records = []resultRecord{}
+	}
+	encoder := json.NewEncoder(w.file)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(records)
+	if closeErr := closeOutput(w.file); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// xlsxResultWriter writes an Excel workbook with a sheet per processor or pipeline step
+// and an errors sheet listing failed items, once all records are known
+type xlsxResultWriter struct {
+	file *os.File
+	sink *data.XLSXSink
+	// processor is the name of the processor's sheet, or empty for a pipeline, whose
+	// results are keyed by step name
+	processor string
+}
+
+// Write implements resultWriter
+func (w *xlsxResultWriter) Write(record resultRecord) error {
+	item := &data.ProcessItem{ID: record.ID, ProcessingInfo: make(map[string]interface{})}
+	switch {
+	case record.Error != "":
+		item.ProcessingInfo["errors"] = map[string]interface{}{"error": record.Error}
+	case w.processor != "":
+		item.ProcessingInfo[w.processor] = record.Result
+	default:
+		steps, _ := record.Result.(map[string]interface{})
+		for name, result := range steps {
+			item.ProcessingInfo[name] = result
+		}
+	}
+	return w.sink.Write(context.Background(), []*data.ProcessItem{item})
+}
+
+// Close implements resultWriter
+func (w *xlsxResultWriter) Close() error {
+	err := w.sink.Close()
+	if closeErr := closeOutput(w.file); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// csvResultWriter writes one row per record: the ID, the result flattened into columns
+// named "result.<field>" and the error, or a row per element of an exploded list. The
+// columns are those of the first result written; results of later records that don't fit
+// them are left out.
+type csvResultWriter struct {
+	file    *os.File
+	writer  *csv.Writer
+	flatten data.FlattenConfig
+	header  []string
+	// pending are failed records held back until a result sets the columns
+	pending []resultRecord
+}
+
+// Write implements resultWriter
+func (w *csvResultWriter) Write(record resultRecord) error {
+	rows, err := resultRows(record, w.flatten)
+	if err != nil {
+		return err
+	}
+
+	if w.header == nil {
+		if record.Result == nil {
+			w.pending = append(w.pending, record)
+			return nil
+		}
+		columns := data.FlattenedColumns(rows)
+		if err := w.writeHeader(append(append([]string{"id"}, columns...), "error")); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		if err := w.writeRow(record, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader writes the header row, then the failed records held back for it
+func (w *csvResultWriter) writeHeader(header []string) error {
+	w.header = header
+	w.writer.Write(header)
+	for _, record := range w.pending {
+		if err := w.writeRow(record, nil); err != nil {
+			return err
+		}
+	}
+	w.pending = nil
+	return nil
+}
+
+// writeRow writes and flushes a row of a record with its result columns
+func (w *csvResultWriter) writeRow(record resultRecord, columns map[string]interface{}) error {
+	row := make([]string, len(w.header))
+	for i, name := range w.header {
+		switch name {
+		case "id":
+			row[i] = record.ID
+		case "error":
+			row[i] = record.Error
+		default:
+			row[i] = data.FormatFlattened(columns[name])
+		}
+	}
+	w.writer.Write(row)
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// Close implements resultWriter
+func (w *csvResultWriter) Close() error {
+	var err error
+	if w.header == nil && len(w.pending) > 0 {
+		// No item succeeded, so there are no result columns
+		err = w.writeHeader([]string{"id", "error"})
+	}
+	if closeErr := closeOutput(w.file); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// resultRows flattens a record's result into the columns of one or more rows, under
+// "result", so structs use their JSON names
+func resultRows(record resultRecord, config data.FlattenConfig) ([]map[string]interface{}, error) {
+	if record.Result == nil {
+		return []map[string]interface{}{nil}, nil
+	}
+	if config.Explode != "" {
+		config.Explode = "result." + config.Explode
+	}
+	rows, err := data.Flatten(map[string]interface{}{"result": record.Result}, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result of %s: %w", record.ID, err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// progressWidth is the number of characters in the progress bar
+const progressWidth = 30
+
+// progressBar redraws a progress line on a terminal as items finish
+type progressBar struct {
+	w       io.Writer
+	total   int
+	start   time.Time
+	enabled bool
+	drawn   time.Time
+	last    string
+}
+
+// newProgressBar creates a bar for total items writing to w. It draws nothing unless
+// enabled is set and w is a terminal, so redirected output stays clean.
+func newProgressBar(w io.Writer, total int, enabled bool) *progressBar {
+	if file, ok := w.(*os.File); ok && enabled {
+		info, err := file.Stat()
+		enabled = err == nil && info.Mode()&os.ModeCharDevice != 0
+	} else {
+		enabled = false
+	}
+	return &progressBar{w: w, total: total, start: time.Now(), enabled: enabled}
+}
+
+// update redraws the bar, at most ten times a second
+func (b *progressBar) update(done, failed int) {
+	if !b.enabled {
+		return
+	}
+	if time.Since(b.drawn) < 100*time.Millisecond && done < b.total {
+		return
+	}
+	b.drawn = time.Now()
+
+	fraction := 1.0
+	if b.total > 0 {
+		fraction = min(1, float64(done)/float64(b.total))
+	}
+	filled := int(fraction * progressWidth)
+	elapsed := time.Since(b.start)
+
+	line := fmt.Sprintf("[%s%s] %3.0f%% %d/%d", strings.Repeat("=", filled),
+		strings.Repeat(" ", progressWidth-filled), fraction*100, done, b.total)
+	if failed > 0 {
+		line += fmt.Sprintf(" (%d failed)", failed)
+	}
+	line += " " + elapsed.Round(time.Second).String()
+	if done > 0 && done < b.total {
+		remaining := time.Duration(float64(elapsed) / float64(done) * float64(b.total-done))
+		line += " ETA " + remaining.Round(time.Second).String()
+	}
+
+	// Trailing spaces erase the end of a longer previous line
+	padding := max(0, len(b.last)-len(line))
+	fmt.Fprintf(b.w, "\r%s%s", line, strings.Repeat(" ", padding))
+	b.last = line
+}
+
+// finish ends the progress line so later output starts on a new line
+func (b *progressBar) finish() {
+	if b.enabled && b.last != "" {
+		fmt.Fprintln(b.w)
+	}
+}
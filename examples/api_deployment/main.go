@@ -1,168 +1,50 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/eisenzopf/agentic-text/pkg/data"
-	"github.com/eisenzopf/agentic-text/pkg/llm"
-	"github.com/eisenzopf/agentic-text/pkg/processor"
-
-	// Import the builtin package for registration
-	_ "github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+	"github.com/eisenzopf/agentic-text/pkg/serve"
 )
 
-// Server holds the API server configuration
-type Server struct {
-	provider llm.Provider
-}
-
-// ProcessRequest represents a text processing request
-type ProcessRequest struct {
-	Text      string `json:"text"`
-	Processor string `json:"processor"`
-}
-
-// ProcessResponse represents a text processing response
-type ProcessResponse struct {
-	Original string      `json:"original"`
-	Result   interface{} `json:"result"`
-	Success  bool        `json:"success"`
-	Error    string      `json:"error,omitempty"`
-}
-
-// NewServer creates a new API server
-func NewServer(provider llm.Provider) *Server {
-	return &Server{
-		provider: provider,
-	}
-}
-
-// HandleProcess handles text processing requests
-func (s *Server) HandleProcess(w http.ResponseWriter, r *http.Request) {
-	// Only accept POST requests
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Parse the request body
-	var req ProcessRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	// Get the processor
-	proc, err := processor.Create(req.Processor, s.provider, processor.Options{})
-	if err != nil {
-		respondWithError(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	// Create a ProcessItem from the text
-	item := data.NewTextProcessItem("api-request", req.Text, nil)
-
-	// Process the text
-	result, err := proc.Process(r.Context(), item)
-	if err != nil {
-		respondWithError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Extract processing results
-	var processorResult interface{}
-	if result.ProcessingInfo != nil {
-		// Get the processing info from the processor
-		for _, info := range result.ProcessingInfo {
-			processorResult = info
-			break
+func main() {
+	// Load the server config from the file given as an argument, if any
+	config := serve.DefaultConfig()
+	if len(os.Args) > 1 {
+		loaded, err := serve.LoadConfig(os.Args[1])
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
 		}
+		config = *loaded
 	}
 
-	// Send the response
-	response := ProcessResponse{
-		Original: req.Text,
-		Result:   processorResult,
-		Success:  true,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-// HandleListProcessors lists available processors
-func (s *Server) HandleListProcessors(w http.ResponseWriter, r *http.Request) {
-	// Only accept GET requests
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Get the list of processors
-	processors := processor.ListProcessors()
-
-	// Send the response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"processors": processors,
-		"count":      len(processors),
-	})
-}
-
-// respondWithError sends an error response
-func respondWithError(w http.ResponseWriter, message string, status int) {
-	response := ProcessResponse{
-		Success: false,
-		Error:   message,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(response)
-}
-
-func main() {
-	// Get API key from environment
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		apiKey = "your-api-key" // For demo purposes only
-		fmt.Println("Warning: Using demo API key. Set LLM_API_KEY environment variable for production.")
+	// The PORT environment variable overrides the configured address
+	if port := os.Getenv("PORT"); port != "" {
+		config.Addr = ":" + port
 	}
-
-	// Initialize LLM provider
-	config := llm.Config{
-		APIKey:      apiKey,
-		Model:       "gemini-2.0-flash",
-		MaxTokens:   1024,
-		Temperature: 0.2,
+	if config.Addr == "" {
+		config.Addr = ":8080"
 	}
 
-	provider, err := llm.NewProvider(llm.Google, config)
+	// Create the server; the provider's API key is read from GEMINI_API_KEY by default
+	server, err := serve.New(config)
 	if err != nil {
-		log.Fatalf("Failed to initialize provider: %v", err)
+		log.Fatalf("Failed to create server: %v", err)
 	}
 
-	// Create and start the server
-	server := NewServer(provider)
-
-	// Register routes
-	http.HandleFunc("/api/process", server.HandleProcess)
-	http.HandleFunc("/api/processors", server.HandleListProcessors)
-
-	// Start the server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	// Shut down gracefully on Ctrl+C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	fmt.Printf("Server starting on port %s...\n", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	fmt.Printf("Server starting on %s...\n", config.Addr)
+	if err := server.ListenAndServe(ctx); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
+	fmt.Println("Server stopped")
 }
 
 /*
@@ -6,10 +6,10 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 
 	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/easy"
 	"github.com/eisenzopf/agentic-text/pkg/llm"
 	"github.com/eisenzopf/agentic-text/pkg/processor"
 
@@ -17,15 +17,6 @@ import (
 	_ "github.com/eisenzopf/agentic-text/pkg/processor/builtin"
 )
 
-// Config represents the structure of the config.json file
-type Config struct {
-	Provider     string  `json:"provider"`
-	Model        string  `json:"model"`
-	APIKeyEnvVar string  `json:"api_key_env_var"`
-	MaxTokens    int     `json:"max_tokens"`
-	Temperature  float64 `json:"temperature"`
-}
-
 func main() {
 	// Initialize all the built-in processors
 	processor.InitializeBuiltInProcessors()
@@ -65,19 +56,14 @@ func main() {
 	}
 
 	// Load the configuration
-	configData, err := os.ReadFile(*configPath)
+	config, err := easy.ConfigFromFile(*configPath)
 	if err != nil {
-		log.Fatalf("Error reading config file: %v", err)
-	}
-
-	var config Config
-	if err := json.Unmarshal(configData, &config); err != nil {
-		log.Fatalf("Error parsing config file: %v", err)
+		log.Fatalf("Error loading config file: %v", err)
 	}
 
 	// Apply command-line overrides if provided
 	if *providerFlag != "" {
-		config.Provider = *providerFlag
+		config.Provider = llm.ProviderType(strings.ToLower(*providerFlag))
 	}
 	if *modelFlag != "" {
 		config.Model = *modelFlag
@@ -92,54 +78,20 @@ func main() {
 		config.Temperature = *temperatureFlag
 	}
 
-	// Get API key from environment variable
-	apiKey := os.Getenv(config.APIKeyEnvVar)
-	if apiKey == "" {
-		log.Fatalf("Error: Environment variable %s not set or empty", config.APIKeyEnvVar)
-	}
-
-	// Create provider options to capture debug info if verbose is enabled
-	providerOptions := map[string]interface{}{}
+	// Capture debug info if verbose is enabled
 	if *verbose {
-		providerOptions["debug"] = true
+		config.Debug = true
 		fmt.Println("Debug mode: enabled - LLM requests and responses will be shown")
 	}
 
-	// Update provider config with options
-	providerConfig := llm.Config{
-		APIKey:      apiKey,
-		Model:       config.Model,
-		MaxTokens:   config.MaxTokens,
-		Temperature: config.Temperature,
-		Options:     providerOptions,
-	}
-
-	// Map string provider to provider type
-	var providerType llm.ProviderType
-	switch strings.ToLower(config.Provider) {
-	case "google":
-		providerType = llm.Google
-	case "openai":
-		providerType = llm.OpenAI
-	// Add more providers as they become available in the library
-	// case "anthropic":
-	// 	providerType = llm.Anthropic
-	// case "amazon":
-	// 	providerType = llm.Amazon
-	// case "groq":
-	// 	providerType = llm.Groq
-	default:
-		log.Fatalf("Unsupported provider: %s", config.Provider)
-	}
-
-	provider, err := llm.NewProvider(providerType, providerConfig)
+	provider, err := config.NewProvider()
 	if err != nil {
 		log.Fatalf("Failed to initialize provider: %v", err)
 	}
 
 	// Create processor options with the LLM options
 	processorOptions := processor.Options{
-		LLMOptions: providerConfig.Options,
+		LLMOptions: map[string]interface{}{"debug": config.Debug},
 	}
 
 	// Create a processor
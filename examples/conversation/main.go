@@ -62,35 +62,25 @@ func main() {
 
 	fmt.Println()
 
+	// Create one analyzer, sharing a provider, for every conversation
+	analyzer, err := easy.NewAnalyzer([]string{"sentiment", "intent"}, config)
+	if err != nil {
+		log.Fatalf("Failed to create analyzer: %v", err)
+	}
+
 	// Process each conversation for both sentiment and intent
 	for i, conversation := range conversations {
 		fmt.Printf("Conversation #%d:\n", i+1)
 		fmt.Printf("----------------%s\n", "-" /* padding to match number width */)
 		fmt.Printf("Excerpt: %s...\n\n", truncateString(conversation, 80))
 
-		// Create wrapper for sentiment analysis
-		sentimentWrapper, err := easy.NewWithConfig("sentiment", config)
-		if err != nil {
-			log.Fatalf("Failed to create sentiment processor: %v", err)
-		}
-
-		// Process sentiment
-		sentimentResult, err := sentimentWrapper.Process(conversation)
-		if err != nil {
-			log.Fatalf("Sentiment analysis failed: %v", err)
-		}
-
-		// Create wrapper for intent analysis
-		intentWrapper, err := easy.NewWithConfig("intent", config)
-		if err != nil {
-			log.Fatalf("Failed to create intent processor: %v", err)
-		}
-
-		// Process intent
-		intentResult, err := intentWrapper.Process(conversation)
+		// Analyze sentiment and intent concurrently
+		results, err := analyzer.Analyze(conversation)
 		if err != nil {
-			log.Fatalf("Intent analysis failed: %v", err)
+			log.Fatalf("Analysis failed: %v", err)
 		}
+		sentimentResult := results["sentiment"]
+		intentResult := results["intent"]
 
 		// Display the results
 		fmt.Println("SENTIMENT ANALYSIS:")
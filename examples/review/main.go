@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+
+	// Import the builtin package for processor registration
+	_ "github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+const usage = `Usage:
+  go run main.go run <text>...                          Analyze texts, pausing low-confidence results for review
+  go run main.go list                                   List items waiting for review
+  go run main.go approve [-correct file.json] <token>   Approve an item, optionally with corrected results, and resume it
+  go run main.go reject [-comment text] <token>         Reject an item`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	queueDir := os.Getenv("REVIEW_QUEUE_DIR")
+	if queueDir == "" {
+		queueDir = "review_queue"
+	}
+	queue, err := pipeline.NewDirectoryReviewQueue(queueDir)
+	if err != nil {
+		log.Fatalf("Failed to open review queue: %v", err)
+	}
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "run":
+		texts := os.Args[2:]
+		if len(texts) == 0 {
+			log.Fatal(usage)
+		}
+		results, err := buildChain(queue).ProcessSource(ctx, data.NewTextStringsProcessItemSource(texts), len(texts), 2)
+		if err != nil {
+			log.Fatalf("Failed to process texts: %v", err)
+		}
+		for _, result := range results {
+			if ticket, ok := pipeline.GetReviewTicket(result); ok && ticket.Status == pipeline.ReviewPending {
+				fmt.Printf("%s: paused for review (token %s)\n", result.ID, ticket.Token)
+				continue
+			}
+			printResult(result)
+		}
+
+	case "list":
+		tokens, err := queue.Pending()
+		if err != nil {
+			log.Fatalf("Failed to list review queue: %v", err)
+		}
+		for _, token := range tokens {
+			item, _, err := queue.Get(ctx, token)
+			if err != nil {
+				log.Fatalf("Failed to read item %s: %v", token, err)
+			}
+			encoded, _ := json.Marshal(item.ProcessingInfo["sentiment"])
+			fmt.Printf("%s  %s  %s\n", token, item.Metadata["original_text"], encoded)
+		}
+
+	case "approve", "reject":
+		flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+		correctionsFile := flags.String("correct", "", "JSON file of corrected results keyed by processor name")
+		comment := flags.String("comment", "", "Reviewer comment")
+		reviewer := flags.String("reviewer", os.Getenv("USER"), "Reviewer name")
+		flags.Parse(os.Args[2:])
+		if flags.NArg() != 1 {
+			log.Fatal(usage)
+		}
+
+		decision := pipeline.ReviewDecision{
+			Approved: os.Args[1] == "approve",
+			Reviewer: *reviewer,
+			Comment:  *comment,
+		}
+		if *correctionsFile != "" {
+			encoded, err := os.ReadFile(*correctionsFile)
+			if err != nil {
+				log.Fatalf("Failed to read corrections: %v", err)
+			}
+			if err := json.Unmarshal(encoded, &decision.Corrections); err != nil {
+				log.Fatalf("Failed to parse corrections: %v", err)
+			}
+		}
+
+		result, err := buildChain(queue).ResumeReview(ctx, queue, flags.Arg(0), decision)
+		if err != nil {
+			log.Fatalf("Failed to resume item: %v", err)
+		}
+		printResult(result)
+
+	default:
+		log.Fatal(usage)
+	}
+}
+
+// buildChain creates the analysis pipeline: sentiment, a review of low-confidence
+// sentiment results, then intent classification
+func buildChain(queue pipeline.ReviewQueue) *pipeline.Chain {
+	provider, err := llm.NewProvider(llm.Google, llm.Config{
+		APIKey:      os.Getenv("GEMINI_API_KEY"),
+		Model:       "gemini-2.0-flash",
+		MaxTokens:   1024,
+		Temperature: 0.2,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create provider: %v", err)
+	}
+
+	sentiment, err := processor.Create("sentiment", provider, processor.Options{})
+	if err != nil {
+		log.Fatalf("Failed to create sentiment processor: %v", err)
+	}
+	intent, err := processor.Create("intent", provider, processor.Options{})
+	if err != nil {
+		log.Fatalf("Failed to create intent processor: %v", err)
+	}
+
+	return pipeline.NewChain("reviewed_analysis", sentiment).
+		AddReview("sentiment_review", pipeline.ResultBelow("sentiment", "confidence", 0.7), queue).
+		Add(intent)
+}
+
+// printResult prints an item's results as indented JSON
+func printResult(item *data.ProcessItem) {
+	encoded, _ := json.MarshalIndent(item.ProcessingInfo, "", "  ")
+	fmt.Printf("%s:\n%s\n", item.ID, encoded)
+}
@@ -0,0 +1,178 @@
+package analytics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// analyticsItem returns an item with intent and sentiment results and a churned outcome
+func analyticsItem(id, intent, sentiment string, score float64, keywords []interface{}, churned bool) *data.ProcessItem {
+	item := data.NewTextProcessItem(id, "", map[string]interface{}{"churned": churned})
+	item.AddProcessingInfo("intent", map[string]interface{}{"label_name": intent})
+	item.AddProcessingInfo("sentiment", map[string]interface{}{"sentiment": sentiment, "score": score, "keywords": keywords})
+	return item
+}
+
+func TestAnalytics(t *testing.T) {
+	items := []*data.ProcessItem{
+		analyticsItem("1", "cancel", "negative", -0.8, []interface{}{"Price", "price", "contract"}, true),
+		analyticsItem("2", "cancel", "negative", -0.6, []interface{}{"price"}, true),
+		analyticsItem("3", "cancel", "positive", 0.4, []interface{}{"support"}, false),
+		analyticsItem("4", "billing", "positive", 0.6, []interface{}{"Support "}, false),
+		analyticsItem("5", "", "neutral", 0, nil, false),
+	}
+	intent := Field{Processor: "intent", Path: "label_name"}
+	sentiment := Field{Processor: "sentiment", Path: "sentiment"}
+	score := Field{Processor: "sentiment", Path: "score"}
+	churned, err := ParseField("metadata.churned")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("distribution", func(t *testing.T) {
+		distribution := CountValues(items, intent)
+		want := []ValueShare{{Value: "cancel", Count: 3, Share: 0.75}, {Value: "billing", Count: 1, Share: 0.25}}
+		if distribution.Items != 4 || distribution.Missing != 1 || !reflect.DeepEqual(distribution.Values, want) {
+			t.Errorf("expected %v with 1 missing, got %+v", want, distribution)
+		}
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		summary := Summarize(items, score)
+		if summary.Count != 5 || summary.Min != -0.8 || summary.Max != 0.6 || summary.Median != 0 {
+			t.Errorf("unexpected summary %+v", summary)
+		}
+		if math.Abs(summary.Mean-(-0.08)) > 1e-9 {
+			t.Errorf("expected mean -0.08, got %g", summary.Mean)
+		}
+		if rate := Summarize(items, churned); rate.Mean != 0.4 {
+			t.Errorf("expected a churn rate of 0.4, got %g", rate.Mean)
+		}
+	})
+
+	t.Run("top terms", func(t *testing.T) {
+		terms := TopTerms(items, Field{Processor: "sentiment", Path: "keywords"}, 2)
+		want := []TermCount{{Term: "price", Count: 3, Items: 2}, {Term: "support", Count: 2, Items: 2}}
+		if !reflect.DeepEqual(terms, want) {
+			t.Errorf("expected %v, got %v", want, terms)
+		}
+	})
+
+	t.Run("co-occurrence", func(t *testing.T) {
+		matrix := CoOccurrence(items, intent, sentiment)
+		if matrix.Items != 4 || !reflect.DeepEqual(matrix.RowValues, []string{"cancel", "billing"}) {
+			t.Fatalf("unexpected matrix %+v", matrix)
+		}
+		if matrix.Count("cancel", "negative") != 2 || matrix.Count("billing", "negative") != 0 {
+			t.Errorf("unexpected counts %v", matrix.Counts)
+		}
+		if shares := matrix.RowShares(); shares[1][1] != 1 {
+			t.Errorf("expected every billing item to be positive, got %v", shares)
+		}
+	})
+
+	t.Run("correlation", func(t *testing.T) {
+		correlation, err := Correlate(items, score, churned)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if correlation.N != 5 || correlation.Coefficient > -0.8 {
+			t.Errorf("expected a strong negative correlation of score with churn, got %+v", correlation)
+		}
+		if _, err := Correlate(items[:1], score, churned); err == nil {
+			t.Error("expected an error for a single item")
+		}
+		if _, err := Correlate(items[3:], score, churned); err == nil {
+			t.Error("expected an error for a constant outcome")
+		}
+	})
+
+	t.Run("outcome by value", func(t *testing.T) {
+		groups := OutcomeByValue(items, intent, churned)
+		if len(groups) != 2 || groups[0].Value != "cancel" || groups[0].Items != 3 {
+			t.Fatalf("unexpected groups %+v", groups)
+		}
+		// 2 of 3 cancellations churned, against 2 of the 4 items with an intent
+		if math.Abs(groups[0].Mean-2.0/3) > 1e-9 || math.Abs(groups[0].Lift-4.0/3) > 1e-9 {
+			t.Errorf("unexpected cancel group %+v", groups[0])
+		}
+	})
+
+	if _, err := ParseField("intent"); err == nil {
+		t.Error("expected an error for a field without a path")
+	}
+}
+
+func TestSelectSamples(t *testing.T) {
+	texts := []string{
+		"Cancel it now, my email is jo@example.com",
+		"Please cancel, I'm unhappy",
+		"I want to cancel but the offer convinced me to stay",
+		"Can you fix my bill? Card 4111 1111 1111 1111",
+		"",
+	}
+	items := []*data.ProcessItem{
+		analyticsItem("1", "cancel", "negative", -0.8, nil, true),
+		analyticsItem("2", "cancel", "negative", -0.6, nil, true),
+		analyticsItem("3", "cancel", "positive", 0.4, nil, false),
+		analyticsItem("4", "billing", "positive", 0.6, nil, false),
+		analyticsItem("5", "", "neutral", 0, nil, false),
+	}
+	for i, item := range items {
+		item.Metadata["original_text"] = texts[i]
+	}
+	intent := Field{Processor: "intent", Path: "label_name"}
+	score := Field{Processor: "sentiment", Path: "score"}
+
+	tests := []struct {
+		name   string
+		config SampleConfig
+		want   []SampleGroup
+	}{
+		{
+			name:   "most negative per intent",
+			config: SampleConfig{GroupBy: intent, Rank: score, Metadata: []string{"churned"}},
+			want: []SampleGroup{
+				{Value: "cancel", Items: 3, Samples: []Sample{{Rank: -0.8, Text: "Cancel it now, my email is [REDACTED:email]",
+					Metadata: map[string]interface{}{"churned": true}}}},
+				{Value: "billing", Items: 1, Samples: []Sample{{Rank: 0.6, Text: "Can you fix my bill? Card [REDACTED:account_number]",
+					Metadata: map[string]interface{}{"churned": false}}}},
+			},
+		},
+		{
+			name: "best saves",
+			config: SampleConfig{Rank: score, Order: SampleHighest, PerGroup: 2, KeepIDs: true, MaxLength: 20,
+				Filter: func(item *data.ProcessItem) bool { return item.Metadata["churned"] == false }},
+			want: []SampleGroup{{Value: "", Items: 3, Samples: []Sample{
+				{ID: "4", Rank: 0.6, Text: "Can you fix my bill?…"},
+				{ID: "3", Rank: 0.4, Text: "I want to cancel but…"},
+			}}},
+		},
+		{
+			name:   "typical",
+			config: SampleConfig{GroupBy: intent, Rank: score, Order: SampleTypical},
+			want: []SampleGroup{
+				{Value: "cancel", Items: 3, Samples: []Sample{{Rank: -0.6, Text: "Please cancel, I'm unhappy"}}},
+				{Value: "billing", Items: 1, Samples: []Sample{{Rank: 0.6, Text: "Can you fix my bill? Card [REDACTED:account_number]"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := SelectSamples(items, tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(groups, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, groups)
+			}
+		})
+	}
+
+	if _, err := SelectSamples(items, SampleConfig{Rank: score, Order: "random"}); err == nil {
+		t.Error("expected an error for an unknown order")
+	}
+}
@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"sort"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// Matrix counts the items with each combination of the values of two fields, such as
+// intent × sentiment
+type Matrix struct {
+	Rows    Field `json:"rows"`
+	Columns Field `json:"columns"`
+	// RowValues and ColumnValues are the fields' values, most frequent first
+	RowValues    []string `json:"row_values"`
+	ColumnValues []string `json:"column_values"`
+	// Counts holds the number of items with each pair of values, indexed by row, then column.
+	// Items with several values count once towards each pair.
+	Counts [][]int `json:"counts"`
+	// Items is the number of items with a value of both fields
+	Items int `json:"items"`
+}
+
+// CoOccurrence returns the matrix of how often the values of two fields occur together
+// in the same item
+func CoOccurrence(items []*data.ProcessItem, rows, columns Field) Matrix {
+	pairs := make(map[[2]string]int)
+	rowTotals := make(map[string]int)
+	columnTotals := make(map[string]int)
+	matrix := Matrix{Rows: rows, Columns: columns}
+	for _, item := range items {
+		rowLabels := rows.labels(item)
+		columnLabels := columns.labels(item)
+		if len(rowLabels) == 0 || len(columnLabels) == 0 {
+			continue
+		}
+		matrix.Items++
+		for _, row := range rowLabels {
+			for _, column := range columnLabels {
+				pairs[[2]string{row, column}]++
+				rowTotals[row]++
+				columnTotals[column]++
+			}
+		}
+	}
+
+	matrix.RowValues = byTotal(rowTotals)
+	matrix.ColumnValues = byTotal(columnTotals)
+	matrix.Counts = make([][]int, len(matrix.RowValues))
+	for i, row := range matrix.RowValues {
+		matrix.Counts[i] = make([]int, len(matrix.ColumnValues))
+		for j, column := range matrix.ColumnValues {
+			matrix.Counts[i][j] = pairs[[2]string{row, column}]
+		}
+	}
+	return matrix
+}
+
+// byTotal returns the values of a count map, most frequent first
+func byTotal(totals map[string]int) []string {
+	values := make([]string, 0, len(totals))
+	for value := range totals {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if totals[values[i]] != totals[values[j]] {
+			return totals[values[i]] > totals[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	return values
+}
+
+// Count returns the number of items with a pair of values
+func (m Matrix) Count(row, column string) int {
+	for i, value := range m.RowValues {
+		if value != row {
+			continue
+		}
+		for j, other := range m.ColumnValues {
+			if other == column {
+				return m.Counts[i][j]
+			}
+		}
+	}
+	return 0
+}
+
+// RowShares returns each row's counts as shares of the row's total, e.g. the share of each
+// sentiment within an intent
+func (m Matrix) RowShares() [][]float64 {
+	shares := make([][]float64, len(m.Counts))
+	for i, counts := range m.Counts {
+		total := 0
+		for _, count := range counts {
+			total += count
+		}
+		shares[i] = make([]float64, len(counts))
+		for j, count := range counts {
+			if total > 0 {
+				shares[i][j] = float64(count) / float64(total)
+			}
+		}
+	}
+	return shares
+}
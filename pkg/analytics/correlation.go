@@ -0,0 +1,115 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// Correlation is the Pearson correlation of two numeric fields across the items having both
+type Correlation struct {
+	X Field `json:"x"`
+	Y Field `json:"y"`
+	// N is the number of items with a numeric value of both fields
+	N int `json:"n"`
+	// Coefficient is between -1 and 1; 0 means no linear relationship
+	Coefficient float64 `json:"coefficient"`
+}
+
+// Correlate returns the correlation of two numeric fields, such as an extracted attribute and
+// an outcome like "metadata.churned". Booleans count as 0 or 1 and numeric strings are
+// parsed. It fails if fewer than two items have both values or either field is constant.
+func Correlate(items []*data.ProcessItem, x, y Field) (Correlation, error) {
+	correlation := Correlation{X: x, Y: y}
+	var xs, ys []float64
+	for _, item := range items {
+		xValue, xOK := x.number(item)
+		yValue, yOK := y.number(item)
+		if xOK && yOK {
+			xs = append(xs, xValue)
+			ys = append(ys, yValue)
+		}
+	}
+	correlation.N = len(xs)
+	if correlation.N < 2 {
+		return correlation, fmt.Errorf("correlation of %s and %s needs at least 2 items with both values, got %d", x, y, correlation.N)
+	}
+
+	xMean, yMean := mean(xs), mean(ys)
+	var covariance, xVariance, yVariance float64
+	for i := range xs {
+		dx, dy := xs[i]-xMean, ys[i]-yMean
+		covariance += dx * dy
+		xVariance += dx * dx
+		yVariance += dy * dy
+	}
+	if xVariance == 0 || yVariance == 0 {
+		return correlation, fmt.Errorf("correlation of %s and %s is undefined: a field has the same value in every item", x, y)
+	}
+	correlation.Coefficient = covariance / math.Sqrt(xVariance*yVariance)
+	return correlation, nil
+}
+
+// mean returns the mean of values
+func mean(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// OutcomeGroup is the mean outcome of the items with one value of a categorical field
+type OutcomeGroup struct {
+	Value string `json:"value"`
+	// Items is the number of items with the value and a numeric outcome
+	Items int     `json:"items"`
+	Mean  float64 `json:"mean"`
+	// Lift is the group's mean relative to the mean of all items with an outcome, so 1.5
+	// means 50% above average; it is 0 when the overall mean is 0
+	Lift float64 `json:"lift"`
+}
+
+// OutcomeByValue returns the mean of a numeric outcome, such as "metadata.churned" or
+// "sentiment.score", for each value of a categorical field like an intent, largest group
+// first. It relates categorical attributes to outcomes, which Correlate can't.
+func OutcomeByValue(items []*data.ProcessItem, category, outcome Field) []OutcomeGroup {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	var total float64
+	n := 0
+	for _, item := range items {
+		value, ok := outcome.number(item)
+		if !ok {
+			continue
+		}
+		labels := category.labels(item)
+		if len(labels) == 0 {
+			continue
+		}
+		total += value
+		n++
+		for _, label := range labels {
+			sums[label] += value
+			counts[label]++
+		}
+	}
+
+	groups := make([]OutcomeGroup, 0, len(counts))
+	for label, count := range counts {
+		group := OutcomeGroup{Value: label, Items: count, Mean: sums[label] / float64(count)}
+		if overall := total / float64(n); overall != 0 {
+			group.Lift = group.Mean / overall
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Items != groups[j].Items {
+			return groups[i].Items > groups[j].Items
+		}
+		return groups[i].Value < groups[j].Value
+	})
+	return groups
+}
@@ -0,0 +1,182 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// Distribution counts the items with each value of a field
+type Distribution struct {
+	Field Field `json:"field"`
+	// Items is the number of items with a value; Missing is the number without one
+	Items   int `json:"items"`
+	Missing int `json:"missing"`
+	// Values are the field's values, most frequent first. Items with several values, such
+	// as a list of labels, count once towards each.
+	Values []ValueShare `json:"values"`
+}
+
+// ValueShare is a value, the number of items with it and their share of the items with a value
+type ValueShare struct {
+	Value string  `json:"value"`
+	Count int     `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// CountValues returns the distribution of a field's values across items
+func CountValues(items []*data.ProcessItem, field Field) Distribution {
+	distribution := Distribution{Field: field, Values: []ValueShare{}}
+	counts := make(map[string]int)
+	for _, item := range items {
+		labels := field.labels(item)
+		if len(labels) == 0 {
+			distribution.Missing++
+			continue
+		}
+		distribution.Items++
+		for _, label := range labels {
+			counts[label]++
+		}
+	}
+
+	for value, count := range counts {
+		distribution.Values = append(distribution.Values, ValueShare{
+			Value: value,
+			Count: count,
+			Share: float64(count) / float64(distribution.Items),
+		})
+	}
+	sortValueShares(distribution.Values)
+	return distribution
+}
+
+// sortValueShares sorts values by descending count, then by value
+func sortValueShares(values []ValueShare) {
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+}
+
+// NumericSummary describes the distribution of a numeric field, such as sentiment scores
+type NumericSummary struct {
+	Field Field `json:"field"`
+	// Count is the number of items with a numeric value; Missing is the number without one
+	Count   int     `json:"count"`
+	Missing int     `json:"missing"`
+	Mean    float64 `json:"mean"`
+	StdDev  float64 `json:"std_dev"`
+	Min     float64 `json:"min"`
+	P25     float64 `json:"p25"`
+	Median  float64 `json:"median"`
+	P75     float64 `json:"p75"`
+	Max     float64 `json:"max"`
+}
+
+// Summarize returns the mean, standard deviation and quartiles of a numeric field across
+// items, taking each item's first numeric value. Booleans count as 0 or 1, so the mean of
+// a boolean field is the share of items where it's true.
+func Summarize(items []*data.ProcessItem, field Field) NumericSummary {
+	summary := NumericSummary{Field: field}
+	var values []float64
+	for _, item := range items {
+		if number, ok := field.number(item); ok {
+			values = append(values, number)
+		} else {
+			summary.Missing++
+		}
+	}
+	summary.Count = len(values)
+	if len(values) == 0 {
+		return summary
+	}
+
+	sort.Float64s(values)
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	summary.Mean = sum / float64(len(values))
+	var squares float64
+	for _, value := range values {
+		squares += (value - summary.Mean) * (value - summary.Mean)
+	}
+	if len(values) > 1 {
+		summary.StdDev = math.Sqrt(squares / float64(len(values)-1))
+	}
+	summary.Min = values[0]
+	summary.P25 = quantile(values, 0.25)
+	summary.Median = quantile(values, 0.5)
+	summary.P75 = quantile(values, 0.75)
+	summary.Max = values[len(values)-1]
+	return summary
+}
+
+// quantile returns the q quantile of sorted values, interpolating between neighbors
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
+
+// TermCount is a keyword or theme, the number of times it occurred and the number of items
+// it occurred in
+type TermCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+	Items int    `json:"items"`
+}
+
+// TopTerms returns the n most frequent terms of a field holding keywords or themes, such as
+// "sentiment.keywords", ranked by the number of items they occur in. Terms are compared
+// ignoring case and surrounding whitespace and reported in lower case. n <= 0 returns all.
+func TopTerms(items []*data.ProcessItem, field Field, n int) []TermCount {
+	terms := make(map[string]*TermCount)
+	for _, item := range items {
+		seen := make(map[string]bool)
+		for _, value := range field.Values(item) {
+			text, ok := value.(string)
+			if !ok {
+				continue
+			}
+			term := strings.ToLower(strings.Join(strings.Fields(text), " "))
+			if term == "" {
+				continue
+			}
+			count, ok := terms[term]
+			if !ok {
+				count = &TermCount{Term: term}
+				terms[term] = count
+			}
+			count.Count++
+			if !seen[term] {
+				seen[term] = true
+				count.Items++
+			}
+		}
+	}
+
+	top := make([]TermCount, 0, len(terms))
+	for _, count := range terms {
+		top = append(top, *count)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Items != top[j].Items {
+			return top[i].Items > top[j].Items
+		}
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Term < top[j].Term
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
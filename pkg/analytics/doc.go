@@ -0,0 +1,31 @@
+/*
+Package analytics computes statistical rollups over the results of processed items, so
+basic analyses don't need an export to pandas.
+
+Every analysis reads a Field of each item: a path within a processor's result, such as
+intent.label_name or sentiment.keywords, or a metadata key such as an outcome recorded
+with the input. Results are serializable to JSON.
+
+Core components:
+
+1. Fields (field.go):
+  - Field / ParseField: The processor result path or metadata key an analysis reads
+
+2. Distributions (distribution.go):
+  - CountValues: Items per value of a categorical field, with shares
+  - Summarize: Mean, standard deviation and quartiles of a numeric field
+  - TopTerms: Most frequent keywords or themes across items
+
+3. Co-occurrence (cooccurrence.go):
+  - CoOccurrence: Matrix of items per pair of values of two fields, e.g. intent × sentiment
+  - Matrix.RowShares: Each row's counts as shares of the row
+
+4. Correlation (correlation.go):
+  - Correlate: Pearson correlation of two numeric or boolean fields
+  - OutcomeByValue: Mean outcome and lift per value of a categorical field
+
+5. Samples (samples.go):
+  - SelectSamples: Lowest, highest or typical items per value of a field, e.g. the most
+    negative call per intent, with sensitive values redacted
+*/
+package analytics
@@ -0,0 +1,115 @@
+package analytics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+)
+
+// Field names the values analyzed in each item: a dot-separated path within a processor's
+// result, as for pipeline.ResultValues, or an item metadata key when Processor is empty.
+// Lists along the path yield each of their elements.
+type Field struct {
+	// Processor is the processor whose result holds the field, or empty for metadata
+	Processor string `json:"processor,omitempty" yaml:"processor,omitempty"`
+	// Path is the path of the field in the result, or the metadata key
+	Path string `json:"path" yaml:"path"`
+}
+
+// ParseField parses a field written as "<processor>.<path>", e.g. "intent.label_name", or
+// as "metadata.<key>" for a metadata key
+func ParseField(s string) (Field, error) {
+	source, path, ok := strings.Cut(s, ".")
+	if !ok || source == "" || path == "" {
+		return Field{}, fmt.Errorf("invalid field %q: use <processor>.<path> or metadata.<key>", s)
+	}
+	if source == "metadata" {
+		return Field{Path: path}, nil
+	}
+	return Field{Processor: source, Path: path}, nil
+}
+
+// String implements fmt.Stringer, in the form ParseField reads
+func (f Field) String() string {
+	if f.Processor == "" {
+		return "metadata." + f.Path
+	}
+	return f.Processor + "." + f.Path
+}
+
+// Values returns the field's values in an item
+func (f Field) Values(item *data.ProcessItem) []interface{} {
+	if f.Processor != "" {
+		return pipeline.ResultValues(item, f.Processor, f.Path)
+	}
+	switch value := item.Metadata[f.Path].(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return value
+	case []string:
+		values := make([]interface{}, len(value))
+		for i, v := range value {
+			values[i] = v
+		}
+		return values
+	default:
+		return []interface{}{value}
+	}
+}
+
+// labels returns the field's distinct values in an item as labels, ignoring empty values
+func (f Field) labels(item *data.ProcessItem) []string {
+	var labels []string
+	seen := make(map[string]bool)
+	for _, value := range f.Values(item) {
+		label := strings.TrimSpace(fmt.Sprint(value))
+		if number, ok := value.(float64); ok {
+			label = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// number returns the field's first numeric value in an item. Booleans count as 0 or 1 and
+// numeric strings are parsed.
+func (f Field) number(item *data.ProcessItem) (float64, bool) {
+	for _, value := range f.Values(item) {
+		if number, ok := toNumber(value); ok {
+			return number, true
+		}
+	}
+	return 0, false
+}
+
+// toNumber converts a value to a number
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil
+	default:
+		return 0, false
+	}
+}
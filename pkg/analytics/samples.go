@@ -0,0 +1,190 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// SampleOrder is which items of a group SelectSamples picks
+type SampleOrder string
+
+const (
+	// SampleLowest picks the items with the lowest rank, e.g. the most negative calls
+	SampleLowest SampleOrder = "lowest"
+	// SampleHighest picks the items with the highest rank, e.g. the best agent saves
+	SampleHighest SampleOrder = "highest"
+	// SampleTypical picks the items whose rank is closest to the group's median
+	SampleTypical SampleOrder = "typical"
+)
+
+// SampleConfig configures SelectSamples
+type SampleConfig struct {
+	// GroupBy is the categorical field samples are picked per value of, e.g. intent.label_name.
+	// Without a path, samples are picked from all items as one group.
+	GroupBy Field `json:"group_by,omitempty" yaml:"group_by,omitempty"`
+	// Rank is the numeric field ranking the items, e.g. sentiment.score
+	Rank Field `json:"rank" yaml:"rank"`
+	// Order is which items are picked (defaults to SampleLowest)
+	Order SampleOrder `json:"order,omitempty" yaml:"order,omitempty"`
+	// PerGroup is the number of samples per group (defaults to 1)
+	PerGroup int `json:"per_group,omitempty" yaml:"per_group,omitempty"`
+	// Filter, if set, limits the candidates to the items it accepts, e.g. retained customers
+	Filter func(item *data.ProcessItem) bool `json:"-" yaml:"-"`
+	// Redaction selects what is removed from the samples' text and metadata (defaults to
+	// processor.DefaultRedaction)
+	Redaction *processor.RedactionConfig `json:"redaction,omitempty" yaml:"redaction,omitempty"`
+	// Metadata are the metadata keys copied into the samples; others are left out, as
+	// metadata often identifies the customer
+	Metadata []string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	// KeepIDs keeps the items' IDs in the samples, for looking them up; by default they are
+	// left out
+	KeepIDs bool `json:"keep_ids,omitempty" yaml:"keep_ids,omitempty"`
+	// MaxLength, if positive, truncates the samples' text to that many characters
+	MaxLength int `json:"max_length,omitempty" yaml:"max_length,omitempty"`
+}
+
+// SampleGroup holds the samples picked from the items with one value of the grouping field
+type SampleGroup struct {
+	Value string `json:"value"`
+	// Items is the number of candidates in the group, i.e. items with a rank
+	Items   int      `json:"items"`
+	Samples []Sample `json:"samples"`
+}
+
+// Sample is an anonymized item picked as an example
+type Sample struct {
+	// ID is the item's ID, if the config keeps IDs
+	ID   string  `json:"id,omitempty"`
+	Rank float64 `json:"rank"`
+	// Text is the item's original text with sensitive values redacted
+	Text     string                 `json:"text"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// rankedItem is a candidate sample and its rank
+type rankedItem struct {
+	item *data.ProcessItem
+	rank float64
+}
+
+// SelectSamples picks representative or extreme items per value of a field, such as the
+// most negative call per intent, with sensitive values redacted, for reports and calibration
+// sessions. Groups are ordered by their number of candidates, largest first. Items without
+// a numeric rank aren't candidates.
+func SelectSamples(items []*data.ProcessItem, config SampleConfig) ([]SampleGroup, error) {
+	if config.Rank.Path == "" {
+		return nil, fmt.Errorf("sample selection requires a rank field")
+	}
+	if config.Order == "" {
+		config.Order = SampleLowest
+	}
+	if config.Order != SampleLowest && config.Order != SampleHighest && config.Order != SampleTypical {
+		return nil, fmt.Errorf("invalid sample order %q: use %s, %s or %s", config.Order, SampleLowest, SampleHighest, SampleTypical)
+	}
+	if config.PerGroup <= 0 {
+		config.PerGroup = 1
+	}
+	redaction := processor.DefaultRedaction()
+	if config.Redaction != nil {
+		redaction = *config.Redaction
+	}
+	redactor, err := processor.NewRedactor(redaction)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]rankedItem)
+	for _, item := range items {
+		if config.Filter != nil && !config.Filter(item) {
+			continue
+		}
+		rank, ok := config.Rank.number(item)
+		if !ok {
+			continue
+		}
+		values := []string{""}
+		if config.GroupBy.Path != "" {
+			values = config.GroupBy.labels(item)
+		}
+		for _, value := range values {
+			groups[value] = append(groups[value], rankedItem{item: item, rank: rank})
+		}
+	}
+
+	selected := make([]SampleGroup, 0, len(groups))
+	for value, candidates := range groups {
+		group := SampleGroup{Value: value, Items: len(candidates)}
+		for _, candidate := range pickSamples(candidates, config.Order, config.PerGroup) {
+			group.Samples = append(group.Samples, anonymize(candidate, config, redactor))
+		}
+		selected = append(selected, group)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if selected[i].Items != selected[j].Items {
+			return selected[i].Items > selected[j].Items
+		}
+		return selected[i].Value < selected[j].Value
+	})
+	return selected, nil
+}
+
+// pickSamples returns the n candidates an order picks, in the order they rank
+func pickSamples(candidates []rankedItem, order SampleOrder, n int) []rankedItem {
+	ranked := make([]rankedItem, len(candidates))
+	copy(ranked, candidates)
+	switch order {
+	case SampleHighest:
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].rank > ranked[j].rank })
+	case SampleTypical:
+		ranks := make([]float64, len(ranked))
+		for i, candidate := range ranked {
+			ranks[i] = candidate.rank
+		}
+		sort.Float64s(ranks)
+		median := quantile(ranks, 0.5)
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return math.Abs(ranked[i].rank-median) < math.Abs(ranked[j].rank-median)
+		})
+	default:
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].rank < ranked[j].rank })
+	}
+	return ranked[:min(len(ranked), n)]
+}
+
+// anonymize turns a candidate into a sample, redacting its text and metadata
+func anonymize(candidate rankedItem, config SampleConfig, redactor *processor.Redactor) Sample {
+	sample := Sample{Rank: candidate.rank, Text: redactor.Redact(originalText(candidate.item))}
+	if config.KeepIDs {
+		sample.ID = candidate.item.ID
+	}
+	if config.MaxLength > 0 {
+		if runes := []rune(sample.Text); len(runes) > config.MaxLength {
+			sample.Text = strings.TrimSpace(string(runes[:config.MaxLength])) + "…"
+		}
+	}
+	for _, key := range config.Metadata {
+		value, ok := candidate.item.Metadata[key]
+		if !ok {
+			continue
+		}
+		if sample.Metadata == nil {
+			sample.Metadata = make(map[string]interface{})
+		}
+		sample.Metadata[key] = redactor.RedactValue(value)
+	}
+	return sample
+}
+
+// originalText returns the text an item was processed from
+func originalText(item *data.ProcessItem) string {
+	if text, ok := item.Metadata["original_text"].(string); ok {
+		return text
+	}
+	text, _ := item.GetTextForProcessing()
+	return text
+}
@@ -0,0 +1,234 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+	_ "github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+// Scenarios are the benchmarked workloads, in the order they run
+var Scenarios = []string{"process_batch", "process_source", "chain", "chain_stream"}
+
+// scenarioDescriptions describe each scenario
+var scenarioDescriptions = map[string]string{
+	"process_batch":  "Processor.ProcessBatch, which processes items one after another",
+	"process_source": "Processor.ProcessSource with the given number of workers",
+	"chain":          "Chain.ProcessSource, which runs the first step with the given number of workers",
+	"chain_stream":   "Chain.ProcessSourceStream, which runs every step with the given number of workers",
+}
+
+// Describe returns the description of a scenario
+func Describe(scenario string) string {
+	return scenarioDescriptions[scenario]
+}
+
+// DefaultResponse is the mock provider's answer to every prompt
+const DefaultResponse = `{"sentiment": "positive", "score": 0.8, "confidence": 0.9, "keywords": ["great"]}`
+
+// Config holds the configuration of a benchmark run
+type Config struct {
+	// Processor is the processor of the processor scenarios (defaults to "sentiment")
+	Processor string
+	// Chain are the processors of the chain scenarios (defaults to sentiment, then intent)
+	Chain []string
+	// Items is the number of items processed per operation (defaults to 100)
+	Items int
+	// Workers are the worker counts of the scenarios that take one (defaults to 1, 2, 4, 8)
+	Workers []int
+	// Latency is the simulated response time of the mock provider (defaults to none, which
+	// measures the framework's own overhead)
+	Latency time.Duration
+	// Duration is how long each measurement runs at least (defaults to one second)
+	Duration time.Duration
+	// Scenarios are the scenarios to run (defaults to Scenarios)
+	Scenarios []string
+	// Response is the mock provider's answer to every prompt (defaults to DefaultResponse)
+	Response string
+}
+
+// withDefaults returns the config with its defaults applied
+func (c Config) withDefaults() Config {
+	if c.Processor == "" {
+		c.Processor = "sentiment"
+	}
+	if len(c.Chain) == 0 {
+		c.Chain = []string{"sentiment", "intent"}
+	}
+	if c.Items <= 0 {
+		c.Items = 100
+	}
+	if len(c.Workers) == 0 {
+		c.Workers = []int{1, 2, 4, 8}
+	}
+	if c.Duration <= 0 {
+		c.Duration = time.Second
+	}
+	if len(c.Scenarios) == 0 {
+		c.Scenarios = Scenarios
+	}
+	if c.Response == "" {
+		c.Response = DefaultResponse
+	}
+	return c
+}
+
+// Result is the measurement of one scenario at one worker count
+type Result struct {
+	Scenario string `json:"scenario"`
+	// Workers is the worker count, or 1 for scenarios that don't take one
+	Workers int `json:"workers"`
+	// Operations is the number of times Items items were processed
+	Operations int `json:"operations"`
+	Items      int `json:"items"`
+	// ItemsPerSecond is the throughput
+	ItemsPerSecond float64 `json:"items_per_second"`
+	// NsPerItem is the mean time per item
+	NsPerItem float64 `json:"ns_per_item"`
+	// AllocsPerItem and BytesPerItem are the mean heap allocations per item
+	AllocsPerItem float64 `json:"allocs_per_item"`
+	BytesPerItem  float64 `json:"bytes_per_item"`
+}
+
+// TakesWorkers reports whether a scenario runs with a worker count
+func TakesWorkers(scenario string) bool {
+	return scenario != "process_batch"
+}
+
+// Run measures every scenario of the config at every worker count, calling report, if set,
+// after each measurement
+func Run(ctx context.Context, config Config, report func(Result)) ([]Result, error) {
+	config = config.withDefaults()
+
+	var results []Result
+	for _, scenario := range config.Scenarios {
+		workerCounts := config.Workers
+		if !TakesWorkers(scenario) {
+			workerCounts = []int{1}
+		}
+		for _, workers := range workerCounts {
+			op, err := Workload(scenario, workers, config)
+			if err != nil {
+				return nil, err
+			}
+			result, err := measure(ctx, op, config.Items, config.Duration)
+			if err != nil {
+				return nil, fmt.Errorf("scenario %s with %d workers failed: %w", scenario, workers, err)
+			}
+			result.Scenario = scenario
+			result.Workers = workers
+			results = append(results, result)
+			if report != nil {
+				report(result)
+			}
+		}
+	}
+	return results, nil
+}
+
+// measure runs op repeatedly for at least duration, after a warm-up run, and computes the
+// throughput and allocations per item
+func measure(ctx context.Context, op func(ctx context.Context) error, items int, duration time.Duration) (Result, error) {
+	if err := op(ctx); err != nil {
+		return Result{}, err
+	}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	operations := 0
+	for operations == 0 || time.Since(start) < duration {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		if err := op(ctx); err != nil {
+			return Result{}, err
+		}
+		operations++
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	total := float64(operations * items)
+	return Result{
+		Operations:     operations,
+		Items:          items,
+		ItemsPerSecond: total / elapsed.Seconds(),
+		NsPerItem:      float64(elapsed.Nanoseconds()) / total,
+		AllocsPerItem:  float64(after.Mallocs-before.Mallocs) / total,
+		BytesPerItem:   float64(after.TotalAlloc-before.TotalAlloc) / total,
+	}, nil
+}
+
+// Workload prepares a scenario and returns a function that processes the config's items
+// once. Processors and chains are created once, so only processing is measured.
+func Workload(scenario string, workers int, config Config) (func(ctx context.Context) error, error) {
+	config = config.withDefaults()
+	provider := llm.NewMockProviderWithResponse(config.Response).WithLatency(config.Latency)
+	// The provider's recorded prompts are reset with every operation so memory stays flat
+	newItems := func() []*data.ProcessItem {
+		provider.ResetPrompts()
+		items := make([]*data.ProcessItem, config.Items)
+		for i := range items {
+			items[i] = data.NewTextProcessItem(fmt.Sprintf("item-%d", i), "The checkout was fast and the support team was great.", nil)
+		}
+		return items
+	}
+
+	switch scenario {
+	case "process_batch", "process_source":
+		proc, err := processor.Create(config.Processor, provider, processor.Options{})
+		if err != nil {
+			return nil, err
+		}
+		if scenario == "process_batch" {
+			return func(ctx context.Context) error {
+				_, err := proc.ProcessBatch(ctx, newItems())
+				return err
+			}, nil
+		}
+		return func(ctx context.Context) error {
+			_, err := proc.ProcessSource(ctx, data.NewProcessItemSliceSource(newItems()), 0, workers)
+			return err
+		}, nil
+
+	case "chain", "chain_stream":
+		builder := pipeline.NewChainBuilder("bench", provider)
+		for _, name := range config.Chain {
+			builder.Step(name)
+		}
+		chain, err := builder.Build()
+		if err != nil {
+			return nil, err
+		}
+		if scenario == "chain" {
+			return func(ctx context.Context) error {
+				_, err := chain.ProcessSource(ctx, data.NewProcessItemSliceSource(newItems()), 0, workers)
+				return err
+			}, nil
+		}
+		return func(ctx context.Context) error {
+			results, err := chain.ProcessSourceStream(ctx, data.NewProcessItemSliceSource(newItems()), workers)
+			if err != nil {
+				return err
+			}
+			var firstErr error
+			for res := range results {
+				if res.Err != nil && firstErr == nil {
+					firstErr = res.Err
+				}
+			}
+			return firstErr
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown scenario %s", scenario)
+	}
+}
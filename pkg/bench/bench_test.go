@@ -0,0 +1,66 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// benchmark runs a scenario at each worker count, reporting items per second and allocations
+func benchmark(b *testing.B, scenario string, workerCounts ...int) {
+	config := Config{Items: 50}
+	for _, workers := range workerCounts {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			op, err := Workload(scenario, workers, config)
+			if err != nil {
+				b.Fatal(err)
+			}
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := op(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*config.Items)/b.Elapsed().Seconds(), "items/s")
+		})
+	}
+}
+
+func BenchmarkProcessBatch(b *testing.B) {
+	benchmark(b, "process_batch", 1)
+}
+
+func BenchmarkProcessSource(b *testing.B) {
+	benchmark(b, "process_source", 1, 2, 4, 8)
+}
+
+func BenchmarkChain(b *testing.B) {
+	benchmark(b, "chain", 1, 2, 4, 8)
+}
+
+func BenchmarkChainStream(b *testing.B) {
+	benchmark(b, "chain_stream", 1, 2, 4, 8)
+}
+
+func TestRun(t *testing.T) {
+	results, err := Run(context.Background(), Config{Items: 5, Workers: []int{1, 2}, Duration: time.Millisecond}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// process_batch runs once; the other scenarios once per worker count
+	if len(results) != 7 {
+		t.Fatalf("expected 7 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Operations == 0 || result.ItemsPerSecond <= 0 {
+			t.Errorf("%s with %d workers measured nothing: %+v", result.Scenario, result.Workers, result)
+		}
+	}
+
+	if _, err := Workload("unknown", 1, Config{}); err == nil {
+		t.Error("expected an error for an unknown scenario")
+	}
+}
@@ -0,0 +1,28 @@
+/*
+Package bench measures the throughput and allocations of processing items, so changes to
+concurrency can be compared objectively.
+
+Every workload runs the registered processors against a mock provider, optionally with a
+simulated response time, so the measurements are repeatable offline and show the framework's
+own overhead. The agentic-text bench command and the package's Go benchmarks are built on it.
+
+Core components:
+
+1. Scenarios (bench.go):
+  - process_batch: Processor.ProcessBatch, which processes items one after another
+  - process_source: Processor.ProcessSource with a number of workers
+  - chain: Chain.ProcessSource, which runs the first step with a number of workers
+  - chain_stream: Chain.ProcessSourceStream, which runs every step with a number of workers
+  - Workload: Prepares a scenario and returns a function processing its items once
+
+2. Measurements (bench.go):
+  - Run: Measures every scenario at every worker count
+  - Result: Items per second, time per item and allocations per item
+
+BenchmarkClone, comparing ProcessItem.Clone with a JSON round trip, lives with Clone in the
+data package.
+
+Worker counts are capped at runtime.NumCPU by the data package, so counts above it measure
+the same as NumCPU.
+*/
+package bench
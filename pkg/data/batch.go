@@ -40,6 +40,9 @@ func (b *ProcessItemBatchProcessor) NextBatch(ctx context.Context) ([]*ProcessIt
 	// Otherwise, fetch a new batch
 	batch := make([]*ProcessItem, 0, b.batchSize)
 	for i := 0; i < b.batchSize; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		item, err := b.source.NextProcessItem(ctx)
 		if err == io.EOF {
 			if len(batch) == 0 {
@@ -63,14 +66,25 @@ func (b *ProcessItemBatchProcessor) Close() error {
 
 // Process applies a processor function to each ProcessItem in a batch
 func (b *ProcessItemBatchProcessor) Process(ctx context.Context, processor func(ctx context.Context, item *ProcessItem) (*ProcessItem, error)) ([]*ProcessItem, error) {
+	return b.process(ctx, processor, nil)
+}
+
+// process applies a processor function to the next batch, reporting each completed
+// item to tracker. If tracker is nil a tracker sized to the batch is created.
+func (b *ProcessItemBatchProcessor) process(ctx context.Context, processor func(ctx context.Context, item *ProcessItem) (*ProcessItem, error), tracker *ProgressTracker) ([]*ProcessItem, error) {
 	batch, err := b.NextBatch(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if tracker == nil {
+		tracker = NewProgressTracker(ctx, len(batch))
+	}
+
 	results := make([]*ProcessItem, len(batch))
 	for i, item := range batch {
 		result, err := processor(ctx, item)
+		tracker.Record(err)
 		if err != nil {
 			return nil, err
 		}
@@ -84,8 +98,10 @@ func (b *ProcessItemBatchProcessor) Process(ctx context.Context, processor func(
 func (b *ProcessItemBatchProcessor) ProcessAll(ctx context.Context, processor func(ctx context.Context, item *ProcessItem) (*ProcessItem, error)) ([]*ProcessItem, error) {
 	var allResults []*ProcessItem
 
+	tracker := NewProgressTracker(ctx, SourceLen(b.source))
+
 	for {
-		results, err := b.Process(ctx, processor)
+		results, err := b.process(ctx, processor, tracker)
 		if err == io.EOF {
 			break
 		}
@@ -0,0 +1,48 @@
+package data
+
+import (
+	"context"
+	"io"
+)
+
+// ChannelSource implements ProcessItemSource for a channel of ProcessItems.
+// It is suitable for unbounded streams: items are consumed as they arrive and
+// the source is exhausted once the channel is closed.
+type ChannelSource struct {
+	ch <-chan *ProcessItem
+}
+
+// NewChannelSource creates a new source that reads ProcessItems from a channel
+func NewChannelSource(ch <-chan *ProcessItem) *ChannelSource {
+	return &ChannelSource{
+		ch: ch,
+	}
+}
+
+// NextProcessItem implements the ProcessItemSource interface. It blocks until an
+// item is available, the channel is closed, or the context is cancelled.
+func (s *ChannelSource) NextProcessItem(ctx context.Context) (*ProcessItem, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case item, ok := <-s.ch:
+		if !ok {
+			return nil, io.EOF
+		}
+		return item, nil
+	}
+}
+
+// Close implements the ProcessItemSource interface. The channel is owned by the
+// sender and is not closed here.
+func (s *ChannelSource) Close() error {
+	return nil
+}
+
+// ProcessResult pairs a processed item with the error encountered while producing it
+type ProcessResult struct {
+	// Item is the processed item, or nil if processing failed
+	Item *ProcessItem
+	// Err is the processing or source error, if any
+	Err error
+}
@@ -0,0 +1,151 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// CheckpointStore records which units of work have completed so that an interrupted
+// run can resume without repeating them. Keys are opaque strings chosen by the caller,
+// such as an item ID or an (item, step) pair.
+type CheckpointStore interface {
+	// IsComplete reports whether key has been marked complete
+	IsComplete(ctx context.Context, key string) (bool, error)
+
+	// MarkComplete records key as complete
+	MarkComplete(ctx context.Context, key string) error
+
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// MemoryCheckpointStore is a CheckpointStore held in memory, useful for tests and for
+// resuming within a single process
+type MemoryCheckpointStore struct {
+	mu   sync.RWMutex
+	done map[string]bool
+}
+
+// NewMemoryCheckpointStore creates a new empty in-memory checkpoint store
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{done: make(map[string]bool)}
+}
+
+// IsComplete implements the CheckpointStore interface
+func (s *MemoryCheckpointStore) IsComplete(_ context.Context, key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.done[key], nil
+}
+
+// MarkComplete implements the CheckpointStore interface
+func (s *MemoryCheckpointStore) MarkComplete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done[key] = true
+	return nil
+}
+
+// Completed returns the number of keys marked complete
+func (s *MemoryCheckpointStore) Completed() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.done)
+}
+
+// Close implements the CheckpointStore interface
+func (s *MemoryCheckpointStore) Close() error {
+	return nil
+}
+
+// FileCheckpointStore is a CheckpointStore backed by an append-only file holding one
+// JSON-encoded key per line. Each key is synced to disk before MarkComplete returns.
+type FileCheckpointStore struct {
+	mu   sync.RWMutex
+	file *os.File
+	done map[string]bool
+}
+
+// NewFileCheckpointStore opens the checkpoint file at path, loading the keys completed by
+// earlier runs, or creates it if it doesn't exist
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint file: %w", err)
+	}
+
+	done := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var key string
+		// A line cut short by a crash is ignored; that unit of work simply runs again
+		if err := json.Unmarshal(scanner.Bytes(), &key); err == nil {
+			done[key] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+
+	// Terminate a line cut short by a crash so new keys start on a fresh line
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			if _, err := file.Write([]byte{'\n'}); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to repair checkpoint file: %w", err)
+			}
+		}
+	}
+
+	return &FileCheckpointStore{file: file, done: done}, nil
+}
+
+// IsComplete implements the CheckpointStore interface
+func (s *FileCheckpointStore) IsComplete(_ context.Context, key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.done[key], nil
+}
+
+// MarkComplete implements the CheckpointStore interface
+func (s *FileCheckpointStore) MarkComplete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done[key] {
+		return nil
+	}
+
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint key: %w", err)
+	}
+	if _, err := s.file.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync checkpoint file: %w", err)
+	}
+
+	s.done[key] = true
+	return nil
+}
+
+// Completed returns the number of keys marked complete
+func (s *FileCheckpointStore) Completed() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.done)
+}
+
+// Close implements the CheckpointStore interface
+func (s *FileCheckpointStore) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,286 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Content types understood by ProcessItem
+const (
+	// ContentTypeText is plain text held as a string
+	ContentTypeText = "text"
+	// ContentTypeJSON is structured data held as a map or slice
+	ContentTypeJSON = "json"
+	// ContentTypeHTML is an HTML document held as a string
+	ContentTypeHTML = "html"
+	// ContentTypeMarkdown is a Markdown document held as a string
+	ContentTypeMarkdown = "markdown"
+	// ContentTypePDFText is text extracted from a PDF, held as a *PDFContent
+	ContentTypePDFText = "pdf_text"
+	// ContentTypeTranscript is a timestamped audio transcript, held as a *Transcript
+	ContentTypeTranscript = "transcript"
+	// ContentTypeBinary is raw bytes held as a []byte (base64 encoded in JSON)
+	ContentTypeBinary = "binary"
+)
+
+// PDFPage holds the text extracted from a single PDF page
+type PDFPage struct {
+	// Number is the 1-based page number
+	Number int `json:"number"`
+	// Text is the extracted page text
+	Text string `json:"text"`
+}
+
+// PDFContent holds text extracted from a PDF, page by page
+type PDFContent struct {
+	// Pages are the extracted pages in document order
+	Pages []PDFPage `json:"pages"`
+}
+
+// TranscriptSegment is a single timestamped utterance in an audio transcript
+type TranscriptSegment struct {
+	// Start is the offset of the utterance from the start of the audio, in seconds
+	Start float64 `json:"start"`
+	// End is the offset at which the utterance ends, in seconds
+	End float64 `json:"end"`
+	// Speaker identifies who is speaking, if known
+	Speaker string `json:"speaker,omitempty"`
+	// Text is what was said
+	Text string `json:"text"`
+}
+
+// Transcript holds an audio transcript with timestamps
+type Transcript struct {
+	// Segments are the utterances in chronological order
+	Segments []TranscriptSegment `json:"segments"`
+}
+
+// NewHTMLProcessItem creates a new ProcessItem from an HTML document
+func NewHTMLProcessItem(id string, document string, metadata map[string]interface{}) *ProcessItem {
+	return newProcessItem(id, document, ContentTypeHTML, metadata)
+}
+
+// NewMarkdownProcessItem creates a new ProcessItem from a Markdown document
+func NewMarkdownProcessItem(id string, document string, metadata map[string]interface{}) *ProcessItem {
+	return newProcessItem(id, document, ContentTypeMarkdown, metadata)
+}
+
+// NewPDFProcessItem creates a new ProcessItem from text extracted from a PDF
+func NewPDFProcessItem(id string, pages []PDFPage, metadata map[string]interface{}) *ProcessItem {
+	return newProcessItem(id, &PDFContent{Pages: pages}, ContentTypePDFText, metadata)
+}
+
+// NewTranscriptProcessItem creates a new ProcessItem from a timestamped transcript
+func NewTranscriptProcessItem(id string, segments []TranscriptSegment, metadata map[string]interface{}) *ProcessItem {
+	return newProcessItem(id, &Transcript{Segments: segments}, ContentTypeTranscript, metadata)
+}
+
+// NewBinaryProcessItem creates a new ProcessItem holding raw bytes. The MIME type,
+// if given, is stored in the "mime_type" metadata field.
+func NewBinaryProcessItem(id string, content []byte, mimeType string, metadata map[string]interface{}) *ProcessItem {
+	if mimeType != "" {
+		if metadata == nil {
+			metadata = make(map[string]interface{})
+		}
+		metadata["mime_type"] = mimeType
+	}
+	return newProcessItem(id, content, ContentTypeBinary, metadata)
+}
+
+// newProcessItem creates a ProcessItem with the given content and content type
+func newProcessItem(id string, content interface{}, contentType string, metadata map[string]interface{}) *ProcessItem {
+	return &ProcessItem{
+		ID:             id,
+		Content:        content,
+		ContentType:    contentType,
+		Metadata:       metadata,
+		ProcessingInfo: make(map[string]interface{}),
+	}
+}
+
+// GetHTMLContent returns the content as an HTML string if it's html type
+func (p *ProcessItem) GetHTMLContent() (string, error) {
+	return p.stringContent(ContentTypeHTML)
+}
+
+// GetMarkdownContent returns the content as a Markdown string if it's markdown type
+func (p *ProcessItem) GetMarkdownContent() (string, error) {
+	return p.stringContent(ContentTypeMarkdown)
+}
+
+// GetPDFContent returns the extracted PDF pages if the content is pdf_text type
+func (p *ProcessItem) GetPDFContent() (*PDFContent, error) {
+	if p.ContentType != ContentTypePDFText {
+		return nil, fmt.Errorf("content type is not %s: %s", ContentTypePDFText, p.ContentType)
+	}
+
+	var pdf PDFContent
+	if err := p.decodeContent(&pdf); err != nil {
+		return nil, err
+	}
+	return &pdf, nil
+}
+
+// GetTranscriptContent returns the transcript if the content is transcript type
+func (p *ProcessItem) GetTranscriptContent() (*Transcript, error) {
+	if p.ContentType != ContentTypeTranscript {
+		return nil, fmt.Errorf("content type is not %s: %s", ContentTypeTranscript, p.ContentType)
+	}
+
+	var transcript Transcript
+	if err := p.decodeContent(&transcript); err != nil {
+		return nil, err
+	}
+	return &transcript, nil
+}
+
+// GetBinaryContent returns the raw bytes if the content is binary type
+func (p *ProcessItem) GetBinaryContent() ([]byte, error) {
+	if p.ContentType != ContentTypeBinary {
+		return nil, fmt.Errorf("content type is not %s: %s", ContentTypeBinary, p.ContentType)
+	}
+
+	switch content := p.Content.(type) {
+	case []byte:
+		return content, nil
+	case string:
+		// Binary content is base64 encoded once it has been through JSON (e.g. after Clone)
+		return base64.StdEncoding.DecodeString(content)
+	default:
+		return nil, fmt.Errorf("content cannot be converted to bytes")
+	}
+}
+
+// GetTextForProcessing renders any textual content type as plain text suitable for a prompt:
+// HTML has its markup removed, PDF pages are prefixed with page markers and transcript
+// segments with timestamps and speakers. Binary and JSON content return an error.
+func (p *ProcessItem) GetTextForProcessing() (string, error) {
+	switch p.ContentType {
+	case ContentTypeText:
+		return p.GetTextContent()
+	case ContentTypeMarkdown:
+		return p.GetMarkdownContent()
+	case ContentTypeHTML:
+		document, err := p.GetHTMLContent()
+		if err != nil {
+			return "", err
+		}
+		return HTMLToText(document), nil
+	case ContentTypePDFText:
+		pdf, err := p.GetPDFContent()
+		if err != nil {
+			return "", err
+		}
+		return pdf.String(), nil
+	case ContentTypeTranscript:
+		transcript, err := p.GetTranscriptContent()
+		if err != nil {
+			return "", err
+		}
+		return transcript.String(), nil
+	default:
+		return "", fmt.Errorf("content type has no text representation: %s", p.ContentType)
+	}
+}
+
+// stringContent returns the content as a string if the item has the expected content type
+func (p *ProcessItem) stringContent(contentType string) (string, error) {
+	if p.ContentType != contentType {
+		return "", fmt.Errorf("content type is not %s: %s", contentType, p.ContentType)
+	}
+
+	if content, ok := p.Content.(string); ok {
+		return content, nil
+	}
+
+	return "", fmt.Errorf("content cannot be converted to string")
+}
+
+// decodeContent converts the content into target, handling both typed values
+// and the generic maps produced by a JSON round trip
+func (p *ProcessItem) decodeContent(target interface{}) error {
+	switch content := p.Content.(type) {
+	case *PDFContent:
+		if pdf, ok := target.(*PDFContent); ok {
+			*pdf = *content
+			return nil
+		}
+	case *Transcript:
+		if transcript, ok := target.(*Transcript); ok {
+			*transcript = *content
+			return nil
+		}
+	}
+
+	encoded, err := json.Marshal(p.Content)
+	if err != nil {
+		return fmt.Errorf("failed to encode content: %w", err)
+	}
+	if err := json.Unmarshal(encoded, target); err != nil {
+		return fmt.Errorf("content cannot be converted to %T: %w", target, err)
+	}
+	return nil
+}
+
+// String renders the PDF text with a marker before each page
+func (c *PDFContent) String() string {
+	var sb strings.Builder
+	for i, page := range c.Pages {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "[Page %d]\n%s", page.Number, page.Text)
+	}
+	return sb.String()
+}
+
+// String renders the transcript one segment per line as "[hh:mm:ss] Speaker: text"
+func (t *Transcript) String() string {
+	lines := make([]string, 0, len(t.Segments))
+	for _, segment := range t.Segments {
+		line := fmt.Sprintf("[%s] ", formatTimestamp(segment.Start))
+		if segment.Speaker != "" {
+			line += segment.Speaker + ": "
+		}
+		lines = append(lines, line+segment.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatTimestamp formats a number of seconds as hh:mm:ss
+func formatTimestamp(seconds float64) string {
+	total := int(seconds)
+	return fmt.Sprintf("%02d:%02d:%02d", total/3600, (total/60)%60, total%60)
+}
+
+var (
+	// htmlDropPattern matches elements whose content is never visible text
+	htmlDropPattern = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	// htmlBreakPattern matches tags that start a new line of text
+	htmlBreakPattern = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6])[^>]*>`)
+	// htmlTagPattern matches any remaining tag
+	htmlTagPattern = regexp.MustCompile(`<[^>]+>`)
+	// blankLinesPattern matches runs of blank lines
+	blankLinesPattern = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// HTMLToText strips markup from an HTML document, keeping line breaks between blocks
+func HTMLToText(document string) string {
+	text := htmlDropPattern.ReplaceAllString(document, "")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = strings.Join(lines, "\n")
+	text = blankLinesPattern.ReplaceAllString(text, "\n\n")
+
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+)
+
+// CSVSourceConfig holds configuration for a CSVSource
+type CSVSourceConfig struct {
+	// Path is the CSV file to read; its first row holds the column names
+	Path string
+	// IDColumn is the column holding item IDs (defaults to "id"). Rows without one are
+	// identified by their row number, e.g. "row-1".
+	IDColumn string
+	// ContentColumn is the column holding the text to process (defaults to "content")
+	ContentColumn string
+	// Comma is the field delimiter (defaults to ',')
+	Comma rune
+}
+
+// CSVSource implements ProcessItemSource for a CSV file. The content column becomes the
+// item text and the remaining columns become metadata.
+type CSVSource struct {
+	file          *os.File
+	reader        *csv.Reader
+	header        []string
+	idColumn      string
+	contentColumn string
+	row           int
+}
+
+// NewCSVSource creates a new source that reads rows from a CSV file
+func NewCSVSource(config CSVSourceConfig) (*CSVSource, error) {
+	if config.IDColumn == "" {
+		config.IDColumn = "id"
+	}
+	if config.ContentColumn == "" {
+		config.ContentColumn = "content"
+	}
+
+	file, err := os.Open(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+
+	reader := csv.NewReader(file)
+	if config.Comma != 0 {
+		reader.Comma = config.Comma
+	}
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	found := false
+	for _, column := range header {
+		if column == config.ContentColumn {
+			found = true
+			break
+		}
+	}
+	if !found {
+		file.Close()
+		return nil, fmt.Errorf("CSV file has no column named %s", config.ContentColumn)
+	}
+
+	return &CSVSource{
+		file:          file,
+		reader:        reader,
+		header:        header,
+		idColumn:      config.IDColumn,
+		contentColumn: config.ContentColumn,
+	}, nil
+}
+
+// NextProcessItem implements the ProcessItemSource interface
+func (s *CSVSource) NextProcessItem(_ context.Context) (*ProcessItem, error) {
+	record, err := s.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV row: %w", err)
+	}
+	s.row++
+
+	var id, content string
+	metadata := make(map[string]interface{})
+	for i, column := range s.header {
+		if i >= len(record) {
+			break
+		}
+		switch column {
+		case s.idColumn:
+			id = record[i]
+		case s.contentColumn:
+			content = record[i]
+		default:
+			metadata[column] = record[i]
+		}
+	}
+	if id == "" {
+		id = fmt.Sprintf("row-%d", s.row)
+	}
+
+	return NewTextProcessItem(id, content, metadata), nil
+}
+
+// Close implements the ProcessItemSource interface
+func (s *CSVSource) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DirectorySourceConfig holds configuration for a DirectorySource
+type DirectorySourceConfig struct {
+	// Path is the directory to read, including its subdirectories
+	Path string
+	// Extensions limits the files read to those with these extensions, e.g. ".txt"
+	// (all files if empty). Hidden files and directories are always skipped.
+	Extensions []string
+}
+
+// DirectorySource implements ProcessItemSource for the files of a directory tree, one item
+// per file in lexical order. Each item's ID is the file's slash-separated path relative to
+// the directory. HTML and Markdown files become items of those content types; any other
+// file becomes text.
+type DirectorySource struct {
+	root  string
+	paths []string
+	index int
+}
+
+// NewDirectorySource creates a new source that reads the files of a directory tree
+func NewDirectorySource(config DirectorySourceConfig) (*DirectorySource, error) {
+	var paths []string
+	err := filepath.WalkDir(config.Path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != config.Path && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if len(config.Extensions) > 0 && !slices.ContainsFunc(config.Extensions, func(ext string) bool {
+			return strings.EqualFold(filepath.Ext(path), ext)
+		}) {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	return &DirectorySource{root: config.Path, paths: paths}, nil
+}
+
+// NextProcessItem implements the ProcessItemSource interface
+func (s *DirectorySource) NextProcessItem(_ context.Context) (*ProcessItem, error) {
+	if s.index >= len(s.paths) {
+		return nil, io.EOF
+	}
+	path := s.paths[s.index]
+	s.index++
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	id := path
+	if rel, err := filepath.Rel(s.root, path); err == nil {
+		id = filepath.ToSlash(rel)
+	}
+	metadata := map[string]interface{}{"path": path}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return NewHTMLProcessItem(id, string(content), metadata), nil
+	case ".md", ".markdown":
+		return NewMarkdownProcessItem(id, string(content), metadata), nil
+	default:
+		return NewTextProcessItem(id, string(content), metadata), nil
+	}
+}
+
+// Len implements the SizedSource interface
+func (s *DirectorySource) Len() int {
+	return len(s.paths) - s.index
+}
+
+// Close implements the ProcessItemSource interface
+func (s *DirectorySource) Close() error {
+	return nil
+}
@@ -0,0 +1,201 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ListMode is how Flatten writes lists
+type ListMode string
+
+const (
+	// ListJoin joins lists of strings, numbers and booleans into one column with the join
+	// separator; lists holding objects or lists are kept as JSON
+	ListJoin ListMode = "join"
+	// ListJSON keeps every list as JSON in one column
+	ListJSON ListMode = "json"
+	// ListIndex writes each element in its own columns, numbered from 0, e.g. "labels.0"
+	ListIndex ListMode = "index"
+)
+
+// FlattenConfig configures how Flatten turns nested results into columns
+type FlattenConfig struct {
+	// Separator separates the field names of nested columns (defaults to ".")
+	Separator string `json:"separator,omitempty" yaml:"separator,omitempty"`
+	// Lists is how lists are written (defaults to ListJoin)
+	Lists ListMode `json:"lists,omitempty" yaml:"lists,omitempty"`
+	// JoinSeparator separates the values of joined lists (defaults to "; ")
+	JoinSeparator string `json:"join_separator,omitempty" yaml:"join_separator,omitempty"`
+	// Explode is the dotted path of a list written as one row per element instead, such as
+	// "recommendations". The other columns are repeated on every row, and a missing or
+	// empty list gives one row without the element's columns.
+	Explode string `json:"explode,omitempty" yaml:"explode,omitempty"`
+}
+
+// withDefaults returns the config with defaults applied
+func (c FlattenConfig) withDefaults() FlattenConfig {
+	if c.Separator == "" {
+		c.Separator = "."
+	}
+	if c.Lists == "" {
+		c.Lists = ListJoin
+	}
+	if c.JoinSeparator == "" {
+		c.JoinSeparator = "; "
+	}
+	return c
+}
+
+// Validate checks the config's list mode
+func (c FlattenConfig) Validate() error {
+	switch c.Lists {
+	case "", ListJoin, ListJSON, ListIndex:
+		return nil
+	default:
+		return fmt.Errorf("invalid list mode %q: use join, json or index", c.Lists)
+	}
+}
+
+// Flatten turns a result, such as a result struct or map, into rows of columns named by the
+// paths of its nested fields, e.g. "entities.type". Values are strings, float64s, bools or
+// nil, as in the result's JSON form. There is one row unless the config explodes a list.
+// A result that isn't an object is written to a "value" column.
+func Flatten(value interface{}, config FlattenConfig) ([]map[string]interface{}, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = config.withDefaults()
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten result: %w", err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to flatten result: %w", err)
+	}
+
+	var explode []string
+	if config.Explode != "" {
+		explode = strings.Split(config.Explode, ".")
+	}
+	base := make(map[string]interface{})
+	config.flatten(base, nil, decoded, explode)
+	if explode == nil {
+		return []map[string]interface{}{base}, nil
+	}
+
+	elements, _ := fieldAtPath(decoded, explode).([]interface{})
+	if len(elements) == 0 {
+		return []map[string]interface{}{base}, nil
+	}
+	rows := make([]map[string]interface{}, len(elements))
+	for i, element := range elements {
+		row := make(map[string]interface{}, len(base))
+		for name, cell := range base {
+			row[name] = cell
+		}
+		config.flatten(row, explode, element, nil)
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// FlattenedColumns returns the columns of flattened rows, sorted
+func FlattenedColumns(rows []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for name := range row {
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// FormatFlattened writes a flattened value as text, with "" for nil
+func FormatFlattened(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// flatten adds a JSON value at a path to row, skipping the field at skip
+func (c FlattenConfig) flatten(row map[string]interface{}, path []string, value interface{}, skip []string) {
+	if skip != nil && len(skip) == len(path) && equalPaths(path, skip) {
+		return
+	}
+	name := strings.Join(path, c.Separator)
+	if name == "" {
+		name = "value"
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			c.flatten(row, append(path[:len(path):len(path)], key), field, skip)
+		}
+	case []interface{}:
+		switch c.Lists {
+		case ListIndex:
+			for i, element := range v {
+				c.flatten(row, append(path[:len(path):len(path)], strconv.Itoa(i)), element, skip)
+			}
+		case ListJSON:
+			encoded, _ := json.Marshal(v)
+			row[name] = string(encoded)
+		default:
+			parts := make([]string, 0, len(v))
+			for _, element := range v {
+				switch element.(type) {
+				case map[string]interface{}, []interface{}:
+					encoded, _ := json.Marshal(v)
+					row[name] = string(encoded)
+					return
+				}
+				parts = append(parts, FormatFlattened(element))
+			}
+			row[name] = strings.Join(parts, c.JoinSeparator)
+		}
+	default:
+		row[name] = v
+	}
+}
+
+// fieldAtPath returns the field of a JSON value at a path of field names, or nil
+func fieldAtPath(value interface{}, path []string) interface{} {
+	for _, key := range path {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = fields[key]
+	}
+	return value
+}
+
+// equalPaths reports whether two paths are the same
+func equalPaths(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+// flattenRecommendation is a nested result like the recommendation processor's
+type flattenRecommendation struct {
+	Action   string   `json:"action"`
+	Priority float64  `json:"priority"`
+	Owners   []string `json:"owners"`
+}
+
+type flattenResult struct {
+	Summary         string                  `json:"summary"`
+	Details         map[string]interface{}  `json:"details"`
+	Recommendations []flattenRecommendation `json:"recommendations"`
+}
+
+func TestFlatten(t *testing.T) {
+	result := flattenResult{
+		Summary: "late delivery",
+		Details: map[string]interface{}{"resolved": false},
+		Recommendations: []flattenRecommendation{
+			{Action: "refund", Priority: 1, Owners: []string{"billing", "support"}},
+			{Action: "apologize", Priority: 2},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		value   interface{}
+		config  FlattenConfig
+		want    []map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:  "joined lists",
+			value: map[string]interface{}{"labels": []interface{}{"billing", 2.0, true}, "scores": map[string]interface{}{"a": 0.5}},
+			want:  []map[string]interface{}{{"labels": "billing; 2; true", "scores.a": 0.5}},
+		},
+		{
+			name:   "lists of objects as JSON",
+			value:  map[string]interface{}{"items": []interface{}{map[string]interface{}{"a": 1.0}}},
+			config: FlattenConfig{Separator: "/"},
+			want:   []map[string]interface{}{{"items": `[{"a":1}]`}},
+		},
+		{
+			name:   "JSON lists",
+			value:  map[string]interface{}{"labels": []interface{}{"a", "b"}},
+			config: FlattenConfig{Lists: ListJSON},
+			want:   []map[string]interface{}{{"labels": `["a","b"]`}},
+		},
+		{
+			name:   "indexed lists",
+			value:  map[string]interface{}{"labels": []interface{}{"a", map[string]interface{}{"b": "c"}}},
+			config: FlattenConfig{Lists: ListIndex, Separator: "_"},
+			want:   []map[string]interface{}{{"labels_0": "a", "labels_1_b": "c"}},
+		},
+		{
+			name:   "exploded struct list",
+			value:  result,
+			config: FlattenConfig{Explode: "recommendations"},
+			want: []map[string]interface{}{
+				{"summary": "late delivery", "details.resolved": false, "recommendations.action": "refund", "recommendations.priority": 1.0, "recommendations.owners": "billing; support"},
+				{"summary": "late delivery", "details.resolved": false, "recommendations.action": "apologize", "recommendations.priority": 2.0, "recommendations.owners": nil},
+			},
+		},
+		{
+			name:   "missing exploded list",
+			value:  map[string]interface{}{"summary": "ok"},
+			config: FlattenConfig{Explode: "recommendations"},
+			want:   []map[string]interface{}{{"summary": "ok"}},
+		},
+		{
+			name:  "scalar result",
+			value: "positive",
+			want:  []map[string]interface{}{{"value": "positive"}},
+		},
+		{
+			name:    "invalid list mode",
+			value:   result,
+			config:  FlattenConfig{Lists: "split"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Flatten(tt.value, tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", rows)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// JSONLSourceConfig holds configuration for a JSONLSource
+type JSONLSourceConfig struct {
+	// Path is the JSON Lines file to read
+	Path string
+	// IDField is the field holding item IDs (defaults to "id"). Lines without one are
+	// identified by their line number, e.g. "line-1".
+	IDField string
+	// ContentField is the field holding the text to process (defaults to "content")
+	ContentField string
+	// Items reads every line as a complete item, as written by JSONLSink, e.g. to re-process
+	// the results of an earlier run. IDField and ContentField are then ignored.
+	Items bool
+}
+
+// JSONLSource implements ProcessItemSource for a JSON Lines file with one object per line.
+// The content field of each object becomes the item text and the remaining fields become
+// metadata; with JSONLSourceConfig.Items, files written by JSONLSink are read back as the
+// original items.
+type JSONLSource struct {
+	closer       io.Closer
+	scanner      *bufio.Scanner
+	idField      string
+	contentField string
+	items        bool
+	line         int
+}
+
+// NewJSONLSource creates a new source that reads objects from a JSON Lines file
+func NewJSONLSource(config JSONLSourceConfig) (*JSONLSource, error) {
+	file, err := os.Open(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open JSONL file: %w", err)
+	}
+
+	return NewJSONLReaderSource(file, config), nil
+}
+
+// NewJSONLReaderSource creates a new source that reads objects in JSON Lines format from
+// r, e.g. an uploaded request body. The config's Path is ignored; Close closes r if it
+// is an io.Closer.
+func NewJSONLReaderSource(r io.Reader, config JSONLSourceConfig) *JSONLSource {
+	if config.IDField == "" {
+		config.IDField = "id"
+	}
+	if config.ContentField == "" {
+		config.ContentField = "content"
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	closer, _ := r.(io.Closer)
+	return &JSONLSource{
+		closer:       closer,
+		scanner:      scanner,
+		idField:      config.IDField,
+		contentField: config.ContentField,
+		items:        config.Items,
+	}
+}
+
+// NextProcessItem implements the ProcessItemSource interface
+func (s *JSONLSource) NextProcessItem(_ context.Context) (*ProcessItem, error) {
+	for s.scanner.Scan() {
+		s.line++
+		line := strings.TrimSpace(s.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// Numbers are kept exact so that numeric IDs aren't reformatted
+		var object map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil {
+			return nil, fmt.Errorf("invalid JSON on line %d: %w", s.line, err)
+		}
+
+		// Lines written by JSONLSink are complete items
+		if s.items {
+			var item ProcessItem
+			if err := json.Unmarshal([]byte(line), &item); err != nil {
+				return nil, fmt.Errorf("invalid item on line %d: %w", s.line, err)
+			}
+			if item.ID == "" {
+				item.ID = fmt.Sprintf("line-%d", s.line)
+			}
+			if item.ProcessingInfo == nil {
+				item.ProcessingInfo = make(map[string]interface{})
+			}
+			return &item, nil
+		}
+
+		content, ok := object[s.contentField].(string)
+		if !ok {
+			return nil, fmt.Errorf("line %d has no string field named %s", s.line, s.contentField)
+		}
+
+		id := fmt.Sprintf("line-%d", s.line)
+		if value, ok := object[s.idField]; ok && value != nil {
+			id = fmt.Sprint(value)
+		}
+
+		metadata := make(map[string]interface{})
+		for field, value := range object {
+			if field != s.idField && field != s.contentField {
+				metadata[field] = value
+			}
+		}
+		return NewTextProcessItem(id, content, metadata), nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read JSONL: %w", err)
+	}
+	return nil, io.EOF
+}
+
+// Close implements the ProcessItemSource interface
+func (s *JSONLSource) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
@@ -0,0 +1,71 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestJSONLSourceItems(t *testing.T) {
+	// A line written by JSONLSink and a plain object that happens to have a content_type field
+	var exported bytes.Buffer
+	item := NewTextProcessItem("ticket-1", "Great support!", map[string]interface{}{"channel": "chat"})
+	item.AddProcessingInfo("sentiment", map[string]interface{}{"sentiment": "positive"})
+	sink := NewJSONLSink(&exported)
+	if err := sink.Write(context.Background(), []*ProcessItem{item}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	plain := `{"id": "ticket-2", "content": "Slow shipping.", "content_type": "email"}` + "\n"
+
+	tests := []struct {
+		name     string
+		input    string
+		items    bool
+		wantID   string
+		wantText string
+		wantMeta map[string]interface{}
+		wantInfo bool
+	}{
+		{
+			name:     "plain object with content_type",
+			input:    plain,
+			wantID:   "ticket-2",
+			wantText: "Slow shipping.",
+			wantMeta: map[string]interface{}{"content_type": "email"},
+		},
+		{
+			name:     "exported item",
+			input:    exported.String(),
+			items:    true,
+			wantID:   "ticket-1",
+			wantText: "Great support!",
+			wantMeta: map[string]interface{}{"channel": "chat"},
+			wantInfo: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := NewJSONLReaderSource(strings.NewReader(tt.input), JSONLSourceConfig{Items: tt.items})
+			got, err := source.NextProcessItem(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != tt.wantID || got.Content != tt.wantText || got.ContentType != "text" {
+				t.Errorf("unexpected item %s: %v (%s)", got.ID, got.Content, got.ContentType)
+			}
+			for key, want := range tt.wantMeta {
+				if got.Metadata[key] != want {
+					t.Errorf("metadata %s = %v, want %v", key, got.Metadata[key], want)
+				}
+			}
+			if _, ok := got.ProcessingInfo["sentiment"]; ok != tt.wantInfo {
+				t.Errorf("unexpected processing info %v", got.ProcessingInfo)
+			}
+		})
+	}
+}
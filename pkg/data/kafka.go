@@ -0,0 +1,238 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaMessageKey is the metadata key used to link a ProcessItem back to its Kafka message.
+// Steps must keep it for KafkaSink to commit the message's offset.
+const KafkaMessageKey = "kafka_message"
+
+// KafkaSourceConfig holds configuration for a KafkaSource
+type KafkaSourceConfig struct {
+	// Brokers is the list of Kafka broker addresses
+	Brokers []string
+	// Topic is the topic to consume transcripts from
+	Topic string
+	// GroupID is the consumer group used for offset tracking
+	GroupID string
+}
+
+// KafkaSource implements ProcessItemSource by consuming messages from a Kafka topic.
+// Offsets are not committed when a message is read; call Commit once the resulting
+// items have been processed successfully (KafkaSink does this automatically).
+type KafkaSource struct {
+	reader  *kafka.Reader
+	mu      sync.Mutex
+	pending map[string]kafka.Message
+}
+
+// NewKafkaSource creates a new source that consumes from a Kafka topic
+func NewKafkaSource(config KafkaSourceConfig) (*KafkaSource, error) {
+	if len(config.Brokers) == 0 {
+		return nil, fmt.Errorf("at least one Kafka broker is required")
+	}
+	if config.Topic == "" {
+		return nil, fmt.Errorf("Kafka topic is required")
+	}
+	if config.GroupID == "" {
+		return nil, fmt.Errorf("Kafka consumer group ID is required")
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: config.Brokers,
+		Topic:   config.Topic,
+		GroupID: config.GroupID,
+	})
+
+	return NewKafkaSourceFromReader(reader), nil
+}
+
+// NewKafkaSourceFromReader creates a new source from an existing Kafka reader.
+// The reader must be configured with a consumer group for Commit to work.
+func NewKafkaSourceFromReader(reader *kafka.Reader) *KafkaSource {
+	return &KafkaSource{
+		reader:  reader,
+		pending: make(map[string]kafka.Message),
+	}
+}
+
+// NextProcessItem implements the ProcessItemSource interface. It blocks until a
+// message is available or the context is cancelled.
+func (s *KafkaSource) NextProcessItem(ctx context.Context) (*ProcessItem, error) {
+	msg, err := s.reader.FetchMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset)
+	item := kafkaMessageToItem(msg, ref)
+
+	s.mu.Lock()
+	s.pending[ref] = msg
+	s.mu.Unlock()
+
+	return item, nil
+}
+
+// kafkaMessageToItem converts a Kafka message into a ProcessItem.
+// Messages holding a JSON-encoded ProcessItem are decoded as-is; anything else is treated as text.
+func kafkaMessageToItem(msg kafka.Message, ref string) *ProcessItem {
+	var item ProcessItem
+	if err := json.Unmarshal(msg.Value, &item); err != nil || item.Content == nil {
+		item = *NewTextProcessItem(string(msg.Key), string(msg.Value), nil)
+	}
+
+	if item.ID == "" {
+		item.ID = ref
+	}
+	if item.ContentType == "" {
+		item.ContentType = "text"
+	}
+	if item.Metadata == nil {
+		item.Metadata = make(map[string]interface{})
+	}
+	if item.ProcessingInfo == nil {
+		item.ProcessingInfo = make(map[string]interface{})
+	}
+	item.Metadata[KafkaMessageKey] = ref
+	item.Metadata["kafka_topic"] = msg.Topic
+	item.Metadata["kafka_partition"] = msg.Partition
+	item.Metadata["kafka_offset"] = msg.Offset
+
+	return &item
+}
+
+// Commit commits the offsets of the messages the given items were read from.
+// Items that did not originate from this source are ignored.
+func (s *KafkaSource) Commit(ctx context.Context, items []*ProcessItem) error {
+	s.mu.Lock()
+	msgs := make([]kafka.Message, 0, len(items))
+	refs := make([]string, 0, len(items))
+	for _, item := range items {
+		if item == nil || item.Metadata == nil {
+			continue
+		}
+		ref, ok := item.Metadata[KafkaMessageKey].(string)
+		if !ok {
+			continue
+		}
+		if msg, ok := s.pending[ref]; ok {
+			msgs = append(msgs, msg)
+			refs = append(refs, ref)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if err := s.reader.CommitMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("failed to commit Kafka offsets: %w", err)
+	}
+
+	s.mu.Lock()
+	for _, ref := range refs {
+		delete(s.pending, ref)
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Close implements the ProcessItemSource interface
+func (s *KafkaSource) Close() error {
+	return s.reader.Close()
+}
+
+// KafkaSinkConfig holds configuration for a KafkaSink
+type KafkaSinkConfig struct {
+	// Brokers is the list of Kafka broker addresses
+	Brokers []string
+	// Topic is the topic structured results are published to
+	Topic string
+	// Source, if set, has its offsets committed after each successful write
+	Source *KafkaSource
+}
+
+// KafkaSink implements ProcessItemSink by publishing JSON-encoded ProcessItems to a Kafka topic
+type KafkaSink struct {
+	writer *kafka.Writer
+	source *KafkaSource
+}
+
+// NewKafkaSink creates a new sink that publishes to a Kafka topic
+func NewKafkaSink(config KafkaSinkConfig) (*KafkaSink, error) {
+	if len(config.Brokers) == 0 {
+		return nil, fmt.Errorf("at least one Kafka broker is required")
+	}
+	if config.Topic == "" {
+		return nil, fmt.Errorf("Kafka topic is required")
+	}
+
+	writer := &kafka.Writer{
+		Addr:     kafka.TCP(config.Brokers...),
+		Topic:    config.Topic,
+		Balancer: &kafka.Hash{},
+	}
+
+	return NewKafkaSinkFromWriter(writer, config.Source), nil
+}
+
+// NewKafkaSinkFromWriter creates a new sink from an existing Kafka writer.
+// If source is non-nil its offsets are committed after each successful write.
+func NewKafkaSinkFromWriter(writer *kafka.Writer, source *KafkaSource) *KafkaSink {
+	return &KafkaSink{
+		writer: writer,
+		source: source,
+	}
+}
+
+// Write implements the ProcessItemSink interface
+func (s *KafkaSink) Write(ctx context.Context, items []*ProcessItem) error {
+	msgs := make([]kafka.Message, 0, len(items))
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		value, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to marshal item %s: %w", item.ID, err)
+		}
+		msgs = append(msgs, kafka.Message{
+			Key:   []byte(item.ID),
+			Value: value,
+		})
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if err := s.writer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("failed to publish to Kafka: %w", err)
+	}
+
+	// Only commit input offsets once the results are safely published
+	if s.source != nil {
+		return s.source.Commit(ctx, items)
+	}
+	return nil
+}
+
+// Flush implements the ProcessItemSink interface. WriteMessages is synchronous,
+// so there is nothing to flush.
+func (s *KafkaSink) Flush(_ context.Context) error {
+	return nil
+}
+
+// Close implements the ProcessItemSink interface
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
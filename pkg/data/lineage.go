@@ -0,0 +1,83 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LibraryVersion is the version of agentic-text recorded in lineage information
+const LibraryVersion = "0.2.0"
+
+// LineageKey is the metadata key under which lineage information is stored
+const LineageKey = "lineage"
+
+// LineageStep records a single processing step applied to an item
+type LineageStep struct {
+	// Processor is the name of the processor that ran
+	Processor string `json:"processor"`
+	// Pipeline is the name of the pipeline the step ran in, if any
+	Pipeline string `json:"pipeline,omitempty"`
+	// StartedAt is when the step started
+	StartedAt time.Time `json:"started_at"`
+	// CompletedAt is when the step completed
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Lineage describes where an item came from and how it was produced,
+// so that each output record is self-describing for audits
+type Lineage struct {
+	// Source identifies where the item was read from
+	Source string `json:"source,omitempty"`
+	// Steps are the processing steps applied to the item, in order
+	Steps []LineageStep `json:"steps"`
+	// LibraryVersion is the agentic-text version that produced the item
+	LibraryVersion string `json:"library_version"`
+}
+
+// GetLineage returns the lineage recorded in the item's metadata, or nil if there is none
+func (p *ProcessItem) GetLineage() (*Lineage, error) {
+	raw, ok := p.Metadata[LineageKey]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	if lineage, ok := raw.(*Lineage); ok {
+		return lineage, nil
+	}
+
+	// Lineage is a generic map once the item has been through JSON (e.g. after Clone)
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lineage: %w", err)
+	}
+	var lineage Lineage
+	if err := json.Unmarshal(encoded, &lineage); err != nil {
+		return nil, fmt.Errorf("invalid lineage metadata: %w", err)
+	}
+	return &lineage, nil
+}
+
+// AddLineageStep appends a processing step to the item's lineage, creating the lineage
+// with the given source if the item has none yet
+func (p *ProcessItem) AddLineageStep(source string, step LineageStep) error {
+	lineage, err := p.GetLineage()
+	if err != nil {
+		return err
+	}
+	if lineage == nil {
+		lineage = &Lineage{Source: source}
+	}
+	if lineage.Source == "" {
+		lineage.Source = source
+	}
+
+	lineage.Steps = append(lineage.Steps, step)
+	lineage.LibraryVersion = LibraryVersion
+
+	if p.Metadata == nil {
+		p.Metadata = make(map[string]interface{})
+	}
+	p.Metadata[LineageKey] = lineage
+	return nil
+}
@@ -2,21 +2,67 @@ package data
 
 import (
 	"context"
+	"io"
+	"math"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 )
 
 // DefaultWorkers is the default number of parallel workers
 const DefaultWorkers = 4
 
+// ParallelConfig holds configuration for a ProcessItemParallelProcessor
+type ParallelConfig struct {
+	// BatchSize is the number of items pulled per ProcessBatch call (defaults to DefaultBatchSize)
+	BatchSize int
+	// MaxWorkers is the number of concurrent workers (defaults to DefaultWorkers, capped at the CPU count)
+	MaxWorkers int
+	// QueueDepth bounds how many items may be read from the source ahead of the workers
+	// in ProcessAll and ProcessStream. When the queue is full the source is not read
+	// until a worker frees a slot. Defaults to BatchSize.
+	QueueDepth int
+	// Priority, if set, ranks items in ProcessAll and ProcessStream: of the items read
+	// ahead into the queue, those of higher priority are started first, and items of equal
+	// priority in source order. See PriorityFromMetadata.
+	Priority func(item *ProcessItem) int
+	// Order is the order ProcessStream delivers results in (defaults to OrderCompletion)
+	Order ResultOrder
+}
+
+// ResultOrder is the order results are streamed in
+type ResultOrder string
+
+const (
+	// OrderCompletion delivers each result as soon as its item completes
+	OrderCompletion ResultOrder = "completion"
+	// OrderSource delivers results in source order, holding back results of items that
+	// complete before an earlier item
+	OrderSource ResultOrder = "source"
+)
+
 // ProcessItemParallelProcessor processes ProcessItems using multiple goroutines
 type ProcessItemParallelProcessor struct {
 	batchProcessor *ProcessItemBatchProcessor
 	maxWorkers     int
+	queueDepth     int
+	priority       func(item *ProcessItem) int
+	order          ResultOrder
 }
 
 // NewProcessItemParallelProcessor creates a new parallel processor for ProcessItems
 func NewProcessItemParallelProcessor(source ProcessItemSource, batchSize, maxWorkers int) *ProcessItemParallelProcessor {
+	return NewProcessItemParallelProcessorWithConfig(source, ParallelConfig{
+		BatchSize:  batchSize,
+		MaxWorkers: maxWorkers,
+	})
+}
+
+// NewProcessItemParallelProcessorWithConfig creates a new parallel processor from a ParallelConfig
+func NewProcessItemParallelProcessorWithConfig(source ProcessItemSource, config ParallelConfig) *ProcessItemParallelProcessor {
+	maxWorkers := config.MaxWorkers
 	if maxWorkers <= 0 {
 		maxWorkers = DefaultWorkers
 	}
@@ -26,9 +72,19 @@ func NewProcessItemParallelProcessor(source ProcessItemSource, batchSize, maxWor
 		maxWorkers = runtime.NumCPU()
 	}
 
+	batchProcessor := NewProcessItemBatchProcessor(source, config.BatchSize)
+
+	queueDepth := config.QueueDepth
+	if queueDepth <= 0 {
+		queueDepth = batchProcessor.batchSize
+	}
+
 	return &ProcessItemParallelProcessor{
-		batchProcessor: NewProcessItemBatchProcessor(source, batchSize),
+		batchProcessor: batchProcessor,
 		maxWorkers:     maxWorkers,
+		queueDepth:     queueDepth,
+		priority:       config.Priority,
+		order:          config.Order,
 	}
 }
 
@@ -37,13 +93,16 @@ func (p *ProcessItemParallelProcessor) Close() error {
 	return p.batchProcessor.Close()
 }
 
-// ProcessBatch processes a batch of ProcessItems in parallel
+// ProcessBatch processes a batch of ProcessItems in parallel. If ctx is cancelled, no further
+// item is started and, once the items in progress have finished, the results of the
+// completed items are returned in batch order with ctx.Err().
 func (p *ProcessItemParallelProcessor) ProcessBatch(ctx context.Context, processor func(ctx context.Context, item *ProcessItem) (*ProcessItem, error)) ([]*ProcessItem, error) {
 	batch, err := p.batchProcessor.NextBatch(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	tracker := NewProgressTracker(ctx, len(batch))
 	results := make([]*ProcessItem, len(batch))
 	errs := make([]error, len(batch))
 
@@ -56,19 +115,33 @@ func (p *ProcessItemParallelProcessor) ProcessBatch(ctx context.Context, process
 		go func(i int, item *ProcessItem) {
 			defer wg.Done()
 
-			// Acquire semaphore
-			semaphore <- struct{}{}
+			// Acquire semaphore, unless cancelled while waiting for it
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
 			defer func() { <-semaphore }()
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
 
 			// Process the item
 			result, err := processor(ctx, item)
 			results[i] = result
 			errs[i] = err
+			tracker.Record(err)
 		}(i, item)
 	}
 
 	wg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		return completed(results, errs), err
+	}
+
 	// Check for errors
 	for _, err := range errs {
 		if err != nil {
@@ -79,28 +152,310 @@ func (p *ProcessItemParallelProcessor) ProcessBatch(ctx context.Context, process
 	return results, nil
 }
 
-// ProcessAll processes all ProcessItems in parallel
+// ProcessAll processes all ProcessItems in parallel and returns the results in source order.
+// Items are pulled from the source lazily through a bounded queue, so workers start as soon
+// as the first item is read. Processing stops at the first error.
+//
+// If ctx is cancelled, the source is no longer read, queued items are dropped without being
+// processed and ProcessAll returns once the items in progress have finished, with the
+// results of the items completed until then, in source order, and ctx.Err(). How soon
+// items in progress finish depends on the processor honoring ctx.
 func (p *ProcessItemParallelProcessor) ProcessAll(ctx context.Context, processor func(ctx context.Context, item *ProcessItem) (*ProcessItem, error)) ([]*ProcessItem, error) {
-	var allResults []*ProcessItem
-	var mu sync.Mutex
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu         sync.Mutex
+		allResults []*ProcessItem
+		firstErr   error
+		errIndex   int
+	)
+
+	p.run(runCtx, processor, func(index int, res ProcessResult) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if res.Err != nil {
+			// Source errors sort after any item error
+			if index < 0 {
+				index = math.MaxInt
+			}
+			// Keep the error of the earliest item, mirroring sequential processing
+			if firstErr == nil || index < errIndex {
+				firstErr = res.Err
+				errIndex = index
+			}
+			cancel()
+			return
+		}
+
+		for len(allResults) <= index {
+			allResults = append(allResults, nil)
+		}
+		allResults[index] = res.Item
+	})
+
+	// Items failing because of the cancellation don't count as failures
+	if err := ctx.Err(); err != nil {
+		return completed(allResults, nil), err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return allResults, nil
+}
+
+// completed returns the results of the items that completed, skipping those that failed or
+// never ran, in order
+func completed(results []*ProcessItem, errs []error) []*ProcessItem {
+	var done []*ProcessItem
+	for i, result := range results {
+		if result != nil && (errs == nil || errs[i] == nil) {
+			done = append(done, result)
+		}
+	}
+	return done
+}
+
+// ProcessStream processes items from the source as they become available and
+// sends each result on the returned channel. Items are pulled lazily, so the source
+// is never fully materialized in memory. Results are delivered in completion order, or
+// with OrderSource in source order, in which case the results of items completing before
+// an earlier, slower item are held in memory until it completes.
+// The channel is closed once the source is exhausted, the context is cancelled,
+// or the source returns an error (which is delivered as a final ProcessResult).
+// After cancellation the source is no longer read, queued items are dropped and results
+// may be dropped too; the channel is closed once the items in progress have finished, so
+// no processor call is running after it closes.
+func (p *ProcessItemParallelProcessor) ProcessStream(ctx context.Context, processor func(ctx context.Context, item *ProcessItem) (*ProcessItem, error)) <-chan ProcessResult {
+	results := make(chan ProcessResult, p.maxWorkers)
+
+	go func() {
+		defer close(results)
+		send := func(_ int, res ProcessResult) {
+			select {
+			case results <- res:
+			case <-ctx.Done():
+			}
+		}
+		if p.order != OrderSource {
+			p.run(ctx, processor, send)
+			return
+		}
+
+		ordered := newSourceOrder(send)
+		p.run(ctx, processor, ordered.emit)
+		ordered.flush()
+	}()
+
+	return results
+}
+
+// sourceOrder passes results on in source order, holding back those that arrive before
+// the results of earlier items
+type sourceOrder struct {
+	mu      sync.Mutex
+	send    func(index int, res ProcessResult)
+	next    int
+	pending map[int]ProcessResult
+}
+
+// newSourceOrder creates a sourceOrder passing results on to send
+func newSourceOrder(send func(index int, res ProcessResult)) *sourceOrder {
+	return &sourceOrder{send: send, pending: make(map[int]ProcessResult)}
+}
 
-	// Process batches sequentially, but items within each batch in parallel
+// emit passes a result on, with the held back results it releases. Source errors, with an
+// index of -1, are passed on at once.
+func (o *sourceOrder) emit(index int, res ProcessResult) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if index < 0 {
+		o.send(index, res)
+		return
+	}
+	o.pending[index] = res
 	for {
-		results, err := p.ProcessBatch(ctx, processor)
-		if err == nil {
-			mu.Lock()
-			allResults = append(allResults, results...)
-			mu.Unlock()
-			continue
+		res, ok := o.pending[o.next]
+		if !ok {
+			return
+		}
+		delete(o.pending, o.next)
+		o.send(o.next, res)
+		o.next++
+	}
+}
+
+// flush passes on the results still held back, in source order, as left when items before
+// them were dropped on cancellation
+func (o *sourceOrder) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	indexes := make([]int, 0, len(o.pending))
+	for index := range o.pending {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		o.send(index, o.pending[index])
+	}
+	o.pending = nil
+}
+
+// run feeds items from the source through a queue of at most queueDepth items to the
+// workers, highest priority first, calling emit with the source index of each result.
+// Source errors are emitted with an index of -1. run returns once all workers have finished.
+func (p *ProcessItemParallelProcessor) run(ctx context.Context, processor func(ctx context.Context, item *ProcessItem) (*ProcessItem, error), emit func(index int, res ProcessResult)) {
+	source := p.batchProcessor.source
+	tracker := NewProgressTracker(ctx, SourceLen(source))
+	queue := newJobQueue(p.priority)
+
+	// slots bounds the items read ahead; ready holds a token per queued item
+	slots := make(chan struct{}, p.queueDepth)
+	ready := make(chan struct{}, p.queueDepth)
+
+	// Dispatcher pulls from the source only while there is room in the queue
+	go func() {
+		defer close(ready)
+		for index := 0; ; index++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			// Stop reading once cancelled, even if the source doesn't check ctx
+			if ctx.Err() != nil {
+				return
+			}
+			item, err := source.NextProcessItem(ctx)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					emit(-1, ProcessResult{Err: err})
+				}
+				return
+			}
+
+			queue.push(queuedJob{index: index, item: item})
+			ready <- struct{}{}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.maxWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range ready {
+				j := queue.pop()
+				<-slots
+				if ctx.Err() != nil {
+					continue
+				}
+				result, err := processor(ctx, j.item)
+				tracker.Record(err)
+				emit(j.index, ProcessResult{Item: result, Err: err})
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// queuedJob is an item read from the source, with its source index
+type queuedJob struct {
+	index    int
+	item     *ProcessItem
+	priority int
+}
+
+// jobQueue is a heap of queued items, by descending priority and then source order
+type jobQueue struct {
+	mu       sync.Mutex
+	jobs     []queuedJob
+	priority func(item *ProcessItem) int
+}
+
+// newJobQueue creates a queue ranking items with priority, or in source order if it is nil
+func newJobQueue(priority func(item *ProcessItem) int) *jobQueue {
+	return &jobQueue{priority: priority}
+}
+
+// push adds a job to the queue
+func (q *jobQueue) push(j queuedJob) {
+	if q.priority != nil {
+		j.priority = q.priority(j.item)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs = append(q.jobs, j)
+	for i := len(q.jobs) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if !q.before(i, parent) {
+			break
 		}
+		q.jobs[i], q.jobs[parent] = q.jobs[parent], q.jobs[i]
+		i = parent
+	}
+}
 
-		// Break on EOF
-		if err.Error() == "EOF" {
+// pop removes and returns the first job; the queue must not be empty
+func (q *jobQueue) pop() queuedJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	first := q.jobs[0]
+	last := len(q.jobs) - 1
+	q.jobs[0] = q.jobs[last]
+	q.jobs = q.jobs[:last]
+	for i := 0; ; {
+		top := i
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(q.jobs) && q.before(child, top) {
+				top = child
+			}
+		}
+		if top == i {
 			break
 		}
+		q.jobs[i], q.jobs[top] = q.jobs[top], q.jobs[i]
+		i = top
+	}
+	return first
+}
 
-		return nil, err
+// before reports whether job i comes before job j
+func (q *jobQueue) before(i, j int) bool {
+	if q.jobs[i].priority != q.jobs[j].priority {
+		return q.jobs[i].priority > q.jobs[j].priority
+	}
+	return q.jobs[i].index < q.jobs[j].index
+}
+
+// PriorityFromMetadata returns a Priority function reading an item's priority from a
+// numeric metadata field, such as "priority"; items without one have priority 0
+func PriorityFromMetadata(field string) func(item *ProcessItem) int {
+	return func(item *ProcessItem) int {
+		switch value := item.Metadata[field].(type) {
+		case int:
+			return value
+		case int64:
+			return int(value)
+		case float64:
+			return int(value)
+		case string:
+			priority, _ := strconv.Atoi(strings.TrimSpace(value))
+			return priority
+		default:
+			return 0
+		}
 	}
+}
 
-	return allResults, nil
+// ProcessStream processes items from a source with the given number of workers and
+// streams the results over a channel. See ProcessItemParallelProcessor.ProcessStream.
+func ProcessStream(ctx context.Context, source ProcessItemSource, workers int, processor func(ctx context.Context, item *ProcessItem) (*ProcessItem, error)) <-chan ProcessResult {
+	p := NewProcessItemParallelProcessor(source, 0, workers)
+	return p.ProcessStream(ctx, processor)
 }
@@ -0,0 +1,240 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSource is a source of n text items that counts the items read and ignores ctx,
+// like sources that don't block
+type countingSource struct {
+	n    int
+	read atomic.Int64
+}
+
+func (s *countingSource) NextProcessItem(ctx context.Context) (*ProcessItem, error) {
+	i := int(s.read.Add(1))
+	if i > s.n {
+		return nil, io.EOF
+	}
+	return NewTextProcessItem(fmt.Sprint(i), "text", nil), nil
+}
+
+func (s *countingSource) Close() error {
+	return nil
+}
+
+// cancelAfter is a processor that completes n items, cancelling the run as the n-th
+// starts, and blocks later items until ctx is done. It counts the calls started after the
+// cancellation.
+type cancelAfter struct {
+	n         int64
+	cancel    context.CancelFunc
+	started   atomic.Int64
+	late      atomic.Int64
+	cancelled atomic.Bool
+}
+
+func (c *cancelAfter) process(ctx context.Context, item *ProcessItem) (*ProcessItem, error) {
+	if c.cancelled.Load() {
+		c.late.Add(1)
+	}
+	count := c.started.Add(1)
+	if count > c.n {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if count == c.n {
+		c.cancelled.Store(true)
+		c.cancel()
+	}
+	return item, nil
+}
+
+func TestProcessAllCancellation(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			source := &countingSource{n: 1000}
+			proc := &cancelAfter{n: 5, cancel: cancel}
+			p := NewProcessItemParallelProcessorWithConfig(source, ParallelConfig{MaxWorkers: workers, QueueDepth: 4})
+
+			done := make(chan struct{})
+			var (
+				results []*ProcessItem
+				err     error
+			)
+			go func() {
+				defer close(done)
+				results, err = p.ProcessAll(ctx, proc.process)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("ProcessAll didn't return after cancellation")
+			}
+
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled, got %v", err)
+			}
+			if len(results) != 5 {
+				t.Fatalf("expected the 5 completed results, got %d", len(results))
+			}
+			for i := 1; i < len(results); i++ {
+				previous, _ := strconv.Atoi(results[i-1].ID)
+				current, _ := strconv.Atoi(results[i].ID)
+				if previous >= current {
+					t.Errorf("results out of source order: %d before %d", previous, current)
+				}
+			}
+			// Items in progress and queued may have been read, but not the rest of the source
+			if read := source.read.Load(); read > int64(5+workers+4+1) {
+				t.Errorf("read %d items from the source after cancellation", read)
+			}
+			if late := proc.late.Load(); late > int64(workers) {
+				t.Errorf("%d items started after cancellation", late)
+			}
+		})
+	}
+}
+
+func TestProcessBatchCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	proc := &cancelAfter{n: 3, cancel: cancel}
+	p := NewProcessItemParallelProcessor(&countingSource{n: 50}, 50, 1)
+
+	results, err := p.ProcessBatch(ctx, proc.process)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected the 3 completed results, got %d", len(results))
+	}
+	if late := proc.late.Load(); late > 1 {
+		t.Errorf("%d items started after cancellation", late)
+	}
+}
+
+func TestProcessStreamCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		running int
+		closed  bool
+	)
+	process := func(ctx context.Context, item *ProcessItem) (*ProcessItem, error) {
+		mu.Lock()
+		if closed {
+			t.Error("processor called after the channel closed")
+		}
+		running++
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Millisecond):
+			return item, nil
+		}
+	}
+
+	received := 0
+	for range ProcessStream(ctx, &countingSource{n: 1000}, 4, process) {
+		if received++; received == 3 {
+			cancel()
+		}
+	}
+	mu.Lock()
+	closed = true
+	if running != 0 {
+		t.Errorf("%d processor calls still running after the channel closed", running)
+	}
+	mu.Unlock()
+	if received >= 1000 {
+		t.Error("the stream kept processing after cancellation")
+	}
+}
+
+// exhaustSignal is a source that closes done once the items of its source have all been read
+type exhaustSignal struct {
+	ProcessItemSource
+	done chan struct{}
+	once sync.Once
+}
+
+func (s *exhaustSignal) NextProcessItem(ctx context.Context) (*ProcessItem, error) {
+	item, err := s.ProcessItemSource.NextProcessItem(ctx)
+	if err == io.EOF {
+		s.once.Do(func() { close(s.done) })
+	}
+	return item, err
+}
+
+func TestProcessPriorityAndOrder(t *testing.T) {
+	// The first item may start before the others are read, so it ranks first either way
+	priorities := []interface{}{9, 1, 5.0, "1", 3}
+	tests := []struct {
+		name  string
+		order ResultOrder
+		want  []string
+	}{
+		{name: "completion order", order: OrderCompletion, want: []string{"0", "2", "4", "1", "3"}},
+		{name: "source order", order: OrderSource, want: []string{"0", "1", "2", "3", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var items []*ProcessItem
+			for i, priority := range priorities {
+				items = append(items, NewTextProcessItem(strconv.Itoa(i), "text", map[string]interface{}{"priority": priority}))
+			}
+			source := &exhaustSignal{ProcessItemSource: NewProcessItemSliceSource(items), done: make(chan struct{})}
+
+			var mu sync.Mutex
+			var started []string
+			p := NewProcessItemParallelProcessorWithConfig(source, ParallelConfig{
+				MaxWorkers: 1,
+				QueueDepth: len(items),
+				Priority:   PriorityFromMetadata("priority"),
+				Order:      tt.order,
+			})
+			results := p.ProcessStream(context.Background(), func(ctx context.Context, item *ProcessItem) (*ProcessItem, error) {
+				// Hold the items until all are queued
+				<-source.done
+				mu.Lock()
+				started = append(started, item.ID)
+				mu.Unlock()
+				return item, nil
+			})
+
+			var got []string
+			for res := range results {
+				if res.Err != nil {
+					t.Fatal(res.Err)
+				}
+				got = append(got, res.Item.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected results %v, got %v", tt.want, got)
+			}
+			if want := []string{"0", "2", "4", "1", "3"}; fmt.Sprint(started) != fmt.Sprint(want) {
+				t.Errorf("expected items started in order %v, got %v", want, started)
+			}
+		})
+	}
+}
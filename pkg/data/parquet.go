@@ -0,0 +1,240 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRow is the on-disk layout written by ParquetSink. Structured values are stored
+// as JSON strings so they can be queried with json_extract in Athena or Spark.
+type parquetRow struct {
+	ID             string `parquet:"id"`
+	Content        string `parquet:"content"`
+	ContentType    string `parquet:"content_type"`
+	Metadata       string `parquet:"metadata"`
+	ProcessingInfo string `parquet:"processing_info"`
+}
+
+// ParquetSourceConfig holds configuration for a ParquetSource
+type ParquetSourceConfig struct {
+	// Path is the Parquet file to read
+	Path string
+	// IDColumn is the column holding item IDs (defaults to "id")
+	IDColumn string
+	// ContentColumn is the column holding the text to process (defaults to "content")
+	ContentColumn string
+}
+
+// ParquetSource implements ProcessItemSource for a Parquet file.
+// Files written by ParquetSink are read back as the original items; for any other
+// schema the content column becomes the item text and remaining columns become metadata.
+type ParquetSource struct {
+	file          *os.File
+	reader        *parquet.Reader
+	idColumn      string
+	contentColumn string
+	remaining     int
+}
+
+// NewParquetSource creates a new source that reads rows from a Parquet file
+func NewParquetSource(config ParquetSourceConfig) (*ParquetSource, error) {
+	if config.IDColumn == "" {
+		config.IDColumn = "id"
+	}
+	if config.ContentColumn == "" {
+		config.ContentColumn = "content"
+	}
+
+	file, err := os.Open(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Parquet file: %w", err)
+	}
+
+	reader := parquet.NewReader(file)
+	if _, ok := reader.Schema().Lookup(config.ContentColumn); !ok {
+		reader.Close()
+		file.Close()
+		return nil, fmt.Errorf("Parquet file has no column named %s", config.ContentColumn)
+	}
+
+	return &ParquetSource{
+		file:          file,
+		reader:        reader,
+		idColumn:      config.IDColumn,
+		contentColumn: config.ContentColumn,
+		remaining:     int(reader.NumRows()),
+	}, nil
+}
+
+// NextProcessItem implements the ProcessItemSource interface
+func (s *ParquetSource) NextProcessItem(_ context.Context) (*ProcessItem, error) {
+	row := make(map[string]interface{})
+	if err := s.reader.Read(&row); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read Parquet row: %w", err)
+	}
+	s.remaining--
+
+	id := fmt.Sprint(row[s.idColumn])
+	if row[s.idColumn] == nil {
+		id = ""
+	}
+	content := fmt.Sprint(row[s.contentColumn])
+
+	// Rows written by ParquetSink carry their content type and encoded maps
+	if contentType, ok := row["content_type"].(string); ok && contentType != "" {
+		item := &ProcessItem{
+			ID:          id,
+			Content:     content,
+			ContentType: contentType,
+		}
+		if contentType == "json" {
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(content), &decoded); err == nil {
+				item.Content = decoded
+			}
+		}
+		if encoded, ok := row["metadata"].(string); ok && encoded != "" {
+			json.Unmarshal([]byte(encoded), &item.Metadata)
+		}
+		if encoded, ok := row["processing_info"].(string); ok && encoded != "" {
+			json.Unmarshal([]byte(encoded), &item.ProcessingInfo)
+		}
+		if item.ProcessingInfo == nil {
+			item.ProcessingInfo = make(map[string]interface{})
+		}
+		return item, nil
+	}
+
+	// Any other schema: remaining columns become metadata
+	metadata := make(map[string]interface{})
+	for column, value := range row {
+		if column != s.idColumn && column != s.contentColumn {
+			metadata[column] = value
+		}
+	}
+
+	return NewTextProcessItem(id, content, metadata), nil
+}
+
+// Len implements the SizedSource interface
+func (s *ParquetSource) Len() int {
+	return s.remaining
+}
+
+// Close implements the ProcessItemSource interface
+func (s *ParquetSource) Close() error {
+	err := s.reader.Close()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ParquetSink implements ProcessItemSink by writing items to a Parquet file with the
+// columns id, content, content_type, metadata and processing_info. Non-text content and
+// the metadata and processing info maps are stored as JSON strings.
+type ParquetSink struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *parquet.GenericWriter[parquetRow]
+}
+
+// NewParquetSink creates a new sink that writes to a Parquet file, truncating it if it exists
+func NewParquetSink(path string) (*ParquetSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet file: %w", err)
+	}
+
+	return &ParquetSink{
+		file:   file,
+		writer: parquet.NewGenericWriter[parquetRow](file),
+	}, nil
+}
+
+// Write implements the ProcessItemSink interface
+func (s *ParquetSink) Write(_ context.Context, items []*ProcessItem) error {
+	rows := make([]parquetRow, 0, len(items))
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		row, err := toParquetRow(item)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.writer.Write(rows); err != nil {
+		return fmt.Errorf("failed to write Parquet rows: %w", err)
+	}
+	return nil
+}
+
+// toParquetRow converts a ProcessItem into its Parquet row representation
+func toParquetRow(item *ProcessItem) (parquetRow, error) {
+	row := parquetRow{
+		ID:          item.ID,
+		ContentType: item.ContentType,
+	}
+
+	if text, ok := item.Content.(string); ok {
+		row.Content = text
+	} else {
+		encoded, err := json.Marshal(item.Content)
+		if err != nil {
+			return row, fmt.Errorf("failed to marshal content for item %s: %w", item.ID, err)
+		}
+		row.Content = string(encoded)
+	}
+
+	if len(item.Metadata) > 0 {
+		encoded, err := json.Marshal(item.Metadata)
+		if err != nil {
+			return row, fmt.Errorf("failed to marshal metadata for item %s: %w", item.ID, err)
+		}
+		row.Metadata = string(encoded)
+	}
+
+	if len(item.ProcessingInfo) > 0 {
+		encoded, err := json.Marshal(item.ProcessingInfo)
+		if err != nil {
+			return row, fmt.Errorf("failed to marshal processing info for item %s: %w", item.ID, err)
+		}
+		row.ProcessingInfo = string(encoded)
+	}
+
+	return row, nil
+}
+
+// Flush implements the ProcessItemSink interface. It ends the current row group.
+func (s *ParquetSink) Flush(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer.Flush()
+}
+
+// Close implements the ProcessItemSink interface. It writes the file footer and closes the file.
+func (s *ParquetSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.writer.Close()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
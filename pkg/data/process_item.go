@@ -3,6 +3,8 @@ package data
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"unicode/utf8"
 )
 
 // ProcessItem represents a standard item flowing through processors
@@ -21,6 +23,10 @@ type ProcessItem struct {
 
 	// ProcessingInfo contains history and context of processing operations
 	ProcessingInfo map[string]interface{} `json:"processing_info,omitempty"`
+
+	// Provenance is the hash of the input, processor, prompt version and model that
+	// produced the item, see ProvenanceHash
+	Provenance string `json:"provenance,omitempty"`
 }
 
 // NewTextProcessItem creates a new ProcessItem from a string
@@ -55,18 +61,188 @@ func (p *ProcessItem) AddProcessingInfo(processorName string, info interface{})
 	p.ProcessingInfo[processorName] = info
 }
 
-// Clone creates a deep copy of the ProcessItem
+// InfoKeyPolicy controls what happens when processing info is added under a processor name
+// the item already has an entry for, e.g. when a chain runs the same processor twice
+type InfoKeyPolicy string
+
+const (
+	// InfoKeyOverwrite replaces the earlier entry (the default)
+	InfoKeyOverwrite InfoKeyPolicy = "overwrite"
+	// InfoKeySuffixIndex keeps the earlier entry and adds the new one under the name with
+	// the next free number appended, e.g. "sentiment_2", then "sentiment_3"
+	InfoKeySuffixIndex InfoKeyPolicy = "suffix_index"
+	// InfoKeyError keeps the earlier entry and fails
+	InfoKeyError InfoKeyPolicy = "error"
+)
+
+// Validate checks that the policy is known; the empty policy means InfoKeyOverwrite
+func (p InfoKeyPolicy) Validate() error {
+	switch p {
+	case "", InfoKeyOverwrite, InfoKeySuffixIndex, InfoKeyError:
+		return nil
+	}
+	return fmt.Errorf("unknown info key policy: %s", p)
+}
+
+// AddProcessingInfoWithPolicy adds information about a processing step like
+// AddProcessingInfo, resolving a collision with an existing entry according to policy.
+// It returns the key the information was added under.
+func (p *ProcessItem) AddProcessingInfoWithPolicy(processorName string, info interface{}, policy InfoKeyPolicy) (string, error) {
+	if err := policy.Validate(); err != nil {
+		return "", err
+	}
+	key := processorName
+	if _, exists := p.ProcessingInfo[processorName]; exists {
+		switch policy {
+		case InfoKeySuffixIndex:
+			for n := 2; ; n++ {
+				key = fmt.Sprintf("%s_%d", processorName, n)
+				if _, taken := p.ProcessingInfo[key]; !taken {
+					break
+				}
+			}
+		case InfoKeyError:
+			return "", fmt.Errorf("item '%s' already has processing info for '%s'", p.ID, processorName)
+		}
+	}
+	p.AddProcessingInfo(key, info)
+	return key, nil
+}
+
+// Clone creates a deep copy of the ProcessItem. The copy is the item as encoding it to JSON
+// and decoding it again gives it, e.g. structs become maps and numbers float64, but plain
+// JSON values such as text, maps and slices are copied directly, which is many times faster
+// than a JSON round trip. Other values are round-tripped through JSON one by one.
 func (p *ProcessItem) Clone() (*ProcessItem, error) {
-	// Convert to JSON and back to create a deep copy
-	data, err := json.Marshal(p)
+	id, err := cloneString(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	contentType, err := cloneString(p.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	content, err := cloneValue(p.Content)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := cloneOmitEmpty(p.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	processingInfo, err := cloneOmitEmpty(p.ProcessingInfo)
 	if err != nil {
 		return nil, err
 	}
 
-	var clone ProcessItem
-	if err := json.Unmarshal(data, &clone); err != nil {
+	return &ProcessItem{
+		ID:             id,
+		Content:        content,
+		ContentType:    contentType,
+		Metadata:       metadata,
+		ProcessingInfo: processingInfo,
+		Provenance:     p.Provenance,
+	}, nil
+}
+
+// cloneOmitEmpty copies a map field tagged omitempty, which JSON leaves out if it is empty
+func cloneOmitEmpty(m map[string]interface{}) (map[string]interface{}, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	clone, err := cloneValue(m)
+	if err != nil {
 		return nil, err
 	}
+	object, _ := clone.(map[string]interface{})
+	return object, nil
+}
 
-	return &clone, nil
+// cloneString copies a string; JSON replaces invalid UTF-8, so only those are round-tripped
+func cloneString(s string) (string, error) {
+	if utf8.ValidString(s) {
+		return s, nil
+	}
+	clone, err := roundTrip(s)
+	if err != nil {
+		return "", err
+	}
+	text, _ := clone.(string)
+	return text, nil
+}
+
+// cloneValue deep-copies a value as a JSON round trip would
+func cloneValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, bool:
+		return v, nil
+	case string:
+		if utf8.ValidString(v) {
+			return v, nil
+		}
+	case float64:
+		// JSON can't encode these, so the round trip reports the error
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return v, nil
+		}
+	case int:
+		return float64(v), nil
+	case []string:
+		if v == nil {
+			return nil, nil
+		}
+		clone := make([]interface{}, len(v))
+		for i, element := range v {
+			copied, err := cloneValue(element)
+			if err != nil {
+				return nil, err
+			}
+			clone[i] = copied
+		}
+		return clone, nil
+	case map[string]interface{}:
+		if v == nil {
+			return nil, nil
+		}
+		clone := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			if !utf8.ValidString(key) {
+				return roundTrip(v)
+			}
+			copied, err := cloneValue(element)
+			if err != nil {
+				return nil, err
+			}
+			clone[key] = copied
+		}
+		return clone, nil
+	case []interface{}:
+		if v == nil {
+			return nil, nil
+		}
+		clone := make([]interface{}, len(v))
+		for i, element := range v {
+			copied, err := cloneValue(element)
+			if err != nil {
+				return nil, err
+			}
+			clone[i] = copied
+		}
+		return clone, nil
+	}
+	return roundTrip(value)
+}
+
+// roundTrip copies a value by encoding it to JSON and decoding it again
+func roundTrip(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var clone interface{}
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
 }
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cloneItem returns an item the size of one leaving the third step of a pipeline, with
+// text content, metadata and the result of every step
+func cloneItem() *ProcessItem {
+	item := NewTextProcessItem("ticket-42", strings.Repeat("The refund still hasn't arrived. ", 60), map[string]interface{}{
+		"source":   "zendesk",
+		"priority": 2,
+		"tags":     []string{"billing", "refund"},
+	})
+	item.AddProcessingInfo("sentiment", map[string]interface{}{
+		"sentiment": "negative", "score": -0.7, "confidence": 0.9,
+		"keywords": []interface{}{"refund", "hasn't arrived"}, "processor_type": "sentiment",
+	})
+	item.AddProcessingInfo("intent", map[string]interface{}{
+		"label_name": "refund_status", "label": "refund_status", "description": "Asks where a refund is",
+		"processor_type": "intent",
+	})
+	item.AddProcessingInfo("keyword_extraction", map[string]interface{}{
+		"keywords": []interface{}{
+			map[string]interface{}{"term": "refund", "relevance": 0.95, "category": "billing"},
+			map[string]interface{}{"term": "arrived", "relevance": 0.6, "category": "delivery"},
+		},
+		"processor_type": "keyword_extraction",
+	})
+	return item
+}
+
+// jsonClone is the JSON round trip Clone must match
+func jsonClone(item *ProcessItem) (*ProcessItem, error) {
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var clone ProcessItem
+	if err := json.Unmarshal(encoded, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// BenchmarkClone compares ProcessItem.Clone with the JSON round trip it replaces
+func BenchmarkClone(b *testing.B) {
+	item := cloneItem()
+	b.Run("structured", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := item.Clone(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := jsonClone(item); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestCloneMatchesJSON(t *testing.T) {
+	type result struct {
+		Label string  `json:"label"`
+		Score float64 `json:"score"`
+	}
+	withStruct := cloneItem()
+	withStruct.Content = &result{Label: "refund", Score: 0.5}
+	withStruct.AddProcessingInfo("typed", result{Label: "x"})
+	empty := &ProcessItem{ID: "empty", Metadata: map[string]interface{}{}, ProcessingInfo: map[string]interface{}{}}
+	invalid := NewTextProcessItem("bad\xff", "text \xfe", map[string]interface{}{"key\xff": "value"})
+
+	for _, item := range []*ProcessItem{cloneItem(), withStruct, empty, invalid} {
+		clone, err := item.Clone()
+		if err != nil {
+			t.Fatalf("%s: %v", item.ID, err)
+		}
+		expected, err := jsonClone(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(clone, expected) {
+			t.Errorf("%s: clone differs from the JSON round trip:\n%#v\n%#v", item.ID, clone, expected)
+		}
+	}
+
+	// The copy is deep
+	item := cloneItem()
+	clone, _ := item.Clone()
+	clone.ProcessingInfo["sentiment"].(map[string]interface{})["score"] = 1.0
+	if item.ProcessingInfo["sentiment"].(map[string]interface{})["score"] != -0.7 {
+		t.Error("changing the clone changed the original")
+	}
+
+	// Values JSON can't encode fail as before
+	item.AddProcessingInfo("nan", map[string]interface{}{"score": math.NaN()})
+	if _, err := item.Clone(); err == nil {
+		t.Error("expected an error for NaN")
+	}
+}
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ProgressFunc is called after each item completes during a batch or source run.
+// done counts finished items (including failures), total is the number of items
+// expected or -1 when the source size is unknown, failed counts items that returned
+// an error, and elapsed is the time since the run started.
+type ProgressFunc func(done, total, failed int, elapsed time.Duration)
+
+// progressKey is the context key used to carry a ProgressFunc
+type progressKey struct{}
+
+// WithProgress returns a context that reports progress to fn from ProcessSource,
+// ProcessBatch and the pipeline helpers that receive it
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// WithoutProgress returns a context that suppresses progress reporting,
+// for callers that report progress themselves
+func WithoutProgress(ctx context.Context) context.Context {
+	if ProgressFromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, ProgressFunc(nil))
+}
+
+// ProgressFromContext returns the ProgressFunc carried by ctx, or nil if there is none
+func ProgressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// SizedSource is implemented by sources that know how many items remain
+type SizedSource interface {
+	// Len returns the number of items not yet returned by the source
+	Len() int
+}
+
+// SourceLen returns the number of remaining items in a source, or -1 if unknown
+func SourceLen(source ProcessItemSource) int {
+	if sized, ok := source.(SizedSource); ok {
+		return sized.Len()
+	}
+	return -1
+}
+
+// ProgressTracker counts completed and failed items and reports them to a ProgressFunc.
+// A tracker with a nil ProgressFunc is a no-op, so callers need not check.
+type ProgressTracker struct {
+	mu     sync.Mutex
+	fn     ProgressFunc
+	total  int
+	done   int
+	failed int
+	start  time.Time
+}
+
+// NewProgressTracker creates a tracker reporting to the ProgressFunc carried by ctx
+func NewProgressTracker(ctx context.Context, total int) *ProgressTracker {
+	return &ProgressTracker{
+		fn:    ProgressFromContext(ctx),
+		total: total,
+		start: time.Now(),
+	}
+}
+
+// Record marks one item as complete, counting it as failed if err is non-nil
+func (t *ProgressTracker) Record(err error) {
+	if t == nil || t.fn == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.done++
+	if err != nil {
+		t.failed++
+	}
+	t.fn(t.done, t.total, t.failed, time.Since(t.start))
+}
@@ -0,0 +1,103 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ProvenanceHash returns a deterministic hash of processing the item's content with a
+// processor, prompt version and model. The item's own provenance is included, so the hash
+// of a chained step covers every step before it. Re-runs over the same input with the same
+// processor, prompt and model produce the same hash, which caches, checkpoints and sinks
+// use to process and write each result exactly once.
+func (p *ProcessItem) ProvenanceHash(processor, promptVersion, model string) string {
+	content, ok := p.Content.(string)
+	if !ok {
+		// Maps are encoded with sorted keys, so equal content always encodes the same
+		encoded, _ := json.Marshal(p.Content)
+		content = string(encoded)
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%s\x00%s\x00%s", p.Provenance, p.ContentType, content,
+		processor, promptVersion, model)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// IdempotentSink wraps a sink so each result is written once across re-runs: items whose
+// provenance is recorded in the checkpoint store as written are skipped, and the
+// provenance of items written is recorded after the wrapped sink has flushed them. Items
+// without a provenance are always written.
+type IdempotentSink struct {
+	sink    ProcessItemSink
+	written CheckpointStore
+	mu      sync.Mutex
+}
+
+// NewIdempotentSink creates a sink writing to sink the items whose provenance written
+// doesn't hold yet. Use a FileCheckpointStore for written to skip results written by
+// earlier runs.
+func NewIdempotentSink(sink ProcessItemSink, written CheckpointStore) *IdempotentSink {
+	return &IdempotentSink{sink: sink, written: written}
+}
+
+// Write implements the ProcessItemSink interface
+func (s *IdempotentSink) Write(ctx context.Context, items []*ProcessItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := make([]*ProcessItem, 0, len(items))
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if item.Provenance != "" {
+			done, err := s.written.IsComplete(ctx, item.Provenance)
+			if err != nil {
+				return fmt.Errorf("failed to check item %s: %w", item.ID, err)
+			}
+			if done || seen[item.Provenance] {
+				continue
+			}
+			seen[item.Provenance] = true
+		}
+		pending = append(pending, item)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// Items are flushed before they are recorded, so a crash can't record items the
+	// wrapped sink had only buffered
+	if err := s.sink.Write(ctx, pending); err != nil {
+		return err
+	}
+	if err := s.sink.Flush(ctx); err != nil {
+		return err
+	}
+	for _, item := range pending {
+		if item.Provenance == "" {
+			continue
+		}
+		if err := s.written.MarkComplete(ctx, item.Provenance); err != nil {
+			return fmt.Errorf("failed to record item %s: %w", item.ID, err)
+		}
+	}
+	return nil
+}
+
+// Flush implements the ProcessItemSink interface
+func (s *IdempotentSink) Flush(ctx context.Context) error {
+	return s.sink.Flush(ctx)
+}
+
+// Close implements the ProcessItemSink interface, closing the wrapped sink but not the
+// checkpoint store
+func (s *IdempotentSink) Close() error {
+	return s.sink.Close()
+}
@@ -0,0 +1,294 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSlowestItems is the number of slowest items a RunReport lists per processor
+const DefaultSlowestItems = 10
+
+// ReportConfig configures a RunRecorder
+type ReportConfig struct {
+	// SlowestItems is the number of slowest items listed per processor (defaults to
+	// DefaultSlowestItems; -1 lists none)
+	SlowestItems int
+	// InputTokenCost and OutputTokenCost are prices per million tokens, used to estimate cost
+	InputTokenCost  float64
+	OutputTokenCost float64
+}
+
+// RunReport summarizes a run for dashboards: counts, errors by type, latency percentiles,
+// token and cost totals and the slowest items of each processor. It is serializable to JSON.
+type RunReport struct {
+	Name       string    `json:"name,omitempty"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	DurationMS float64   `json:"duration_ms"`
+	// Items is the number of items the run returned
+	Items int `json:"items"`
+	// Error is the error that stopped the run, if any
+	Error string `json:"error,omitempty"`
+	// InputTokens and OutputTokens are estimated at four characters per token, as
+	// providers don't report usage
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+	// Processors are the reports of each processor, in the order they first finished an item
+	Processors []ProcessorReport `json:"processors"`
+}
+
+// ProcessorReport summarizes the items one processor handled in a run
+type ProcessorReport struct {
+	Name      string `json:"name"`
+	Items     int    `json:"items"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	// Errors counts the failures by type, as classified by ErrorType
+	Errors       map[string]int `json:"errors,omitempty"`
+	Latency      LatencyStats   `json:"latency"`
+	InputTokens  int64          `json:"input_tokens"`
+	OutputTokens int64          `json:"output_tokens"`
+	Cost         float64        `json:"cost"`
+	// Slowest are the items that took longest, slowest first
+	Slowest []ItemLatency `json:"slowest,omitempty"`
+}
+
+// LatencyStats are the mean, percentiles and maximum of item latencies, in milliseconds
+type LatencyStats struct {
+	MeanMS float64 `json:"mean_ms"`
+	P50MS  float64 `json:"p50_ms"`
+	P90MS  float64 `json:"p90_ms"`
+	P95MS  float64 `json:"p95_ms"`
+	P99MS  float64 `json:"p99_ms"`
+	MaxMS  float64 `json:"max_ms"`
+}
+
+// ItemLatency is the time a processor took on one item
+type ItemLatency struct {
+	ID        string  `json:"id"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ItemOutcome is the result of a processor handling one item, as recorded by a RunRecorder
+type ItemOutcome struct {
+	ItemID       string
+	Latency      time.Duration
+	InputTokens  int64
+	OutputTokens int64
+	Err          error
+}
+
+// RunRecorder collects the outcome of every item processed with a context carrying it
+// (see WithRunRecorder) and summarizes them in a RunReport. It is safe for concurrent use.
+type RunRecorder struct {
+	mu         sync.Mutex
+	name       string
+	config     ReportConfig
+	started    time.Time
+	processors map[string]*processorRecord
+	order      []string
+}
+
+// processorRecord accumulates the outcomes of one processor
+type processorRecord struct {
+	items        int
+	failed       int
+	errors       map[string]int
+	latencies    []time.Duration
+	inputTokens  int64
+	outputTokens int64
+	// slowest is kept sorted, slowest first, and bounded by the configured length
+	slowest []ItemLatency
+}
+
+// NewRunRecorder creates a recorder for a run, which starts now
+func NewRunRecorder(name string, config ReportConfig) *RunRecorder {
+	if config.SlowestItems == 0 {
+		config.SlowestItems = DefaultSlowestItems
+	}
+	return &RunRecorder{
+		name:       name,
+		config:     config,
+		started:    time.Now(),
+		processors: make(map[string]*processorRecord),
+	}
+}
+
+// runRecorderKey is the context key used to carry a RunRecorder
+type runRecorderKey struct{}
+
+// WithRunRecorder returns a context whose processors record the outcome of every item in r
+func WithRunRecorder(ctx context.Context, r *RunRecorder) context.Context {
+	return context.WithValue(ctx, runRecorderKey{}, r)
+}
+
+// RunRecorderFromContext returns the RunRecorder carried by ctx, or nil if there is none.
+// Recording to a nil recorder does nothing, so callers need not check.
+func RunRecorderFromContext(ctx context.Context) *RunRecorder {
+	r, _ := ctx.Value(runRecorderKey{}).(*RunRecorder)
+	return r
+}
+
+// Record adds the outcome of a processor handling one item
+func (r *RunRecorder) Record(processor string, outcome ItemOutcome) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.processors[processor]
+	if !ok {
+		record = &processorRecord{errors: make(map[string]int)}
+		r.processors[processor] = record
+		r.order = append(r.order, processor)
+	}
+	record.items++
+	record.latencies = append(record.latencies, outcome.Latency)
+	record.inputTokens += outcome.InputTokens
+	record.outputTokens += outcome.OutputTokens
+
+	item := ItemLatency{ID: outcome.ItemID, LatencyMS: milliseconds(outcome.Latency)}
+	if outcome.Err != nil {
+		record.failed++
+		record.errors[ErrorType(outcome.Err)]++
+		item.Error = outcome.Err.Error()
+	}
+	record.addSlowest(item, r.config.SlowestItems)
+}
+
+// addSlowest keeps item if it is among the limit slowest seen so far
+func (p *processorRecord) addSlowest(item ItemLatency, limit int) {
+	if limit <= 0 {
+		return
+	}
+	if len(p.slowest) == limit && item.LatencyMS <= p.slowest[limit-1].LatencyMS {
+		return
+	}
+	i := sort.Search(len(p.slowest), func(i int) bool { return p.slowest[i].LatencyMS < item.LatencyMS })
+	p.slowest = append(p.slowest, ItemLatency{})
+	copy(p.slowest[i+1:], p.slowest[i:])
+	p.slowest[i] = item
+	if len(p.slowest) > limit {
+		p.slowest = p.slowest[:limit]
+	}
+}
+
+// Report summarizes the outcomes recorded so far. items is the number of items the run
+// returned and err the error that stopped it, if any.
+func (r *RunRecorder) Report(items int, err error) *RunReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	finished := time.Now()
+	report := &RunReport{
+		Name:       r.name,
+		Started:    r.started,
+		Finished:   finished,
+		DurationMS: milliseconds(finished.Sub(r.started)),
+		Items:      items,
+		Processors: make([]ProcessorReport, 0, len(r.order)),
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	for _, name := range r.order {
+		record := r.processors[name]
+		processor := ProcessorReport{
+			Name:         name,
+			Items:        record.items,
+			Succeeded:    record.items - record.failed,
+			Failed:       record.failed,
+			Latency:      latencyStats(record.latencies),
+			InputTokens:  record.inputTokens,
+			OutputTokens: record.outputTokens,
+			Cost: (float64(record.inputTokens)*r.config.InputTokenCost +
+				float64(record.outputTokens)*r.config.OutputTokenCost) / 1e6,
+			Slowest: append([]ItemLatency(nil), record.slowest...),
+		}
+		if len(record.errors) > 0 {
+			processor.Errors = make(map[string]int, len(record.errors))
+			for errorType, count := range record.errors {
+				processor.Errors[errorType] = count
+			}
+		}
+		report.InputTokens += processor.InputTokens
+		report.OutputTokens += processor.OutputTokens
+		report.Cost += processor.Cost
+		report.Processors = append(report.Processors, processor)
+	}
+	return report
+}
+
+// latencyStats computes the statistics of a set of latencies, using nearest-rank
+// percentiles
+func latencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		return milliseconds(sorted[max(rank, 1)-1])
+	}
+	return LatencyStats{
+		MeanMS: milliseconds(total / time.Duration(len(sorted))),
+		P50MS:  percentile(50),
+		P90MS:  percentile(90),
+		P95MS:  percentile(95),
+		P99MS:  percentile(99),
+		MaxMS:  milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// ErrorTyper is implemented by errors that name their type for run reports
+type ErrorTyper interface {
+	ErrorType() string
+}
+
+// ErrorType classifies an error for run reports: the type named by an ErrorTyper in its
+// chain, "canceled" or "timeout" for context errors, or else the message of the innermost
+// wrapped error, which names the failure without the details added around it
+func ErrorType(err error) string {
+	var typer ErrorTyper
+	switch {
+	case errors.As(err, &typer):
+		return typer.ErrorType()
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	}
+
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			break
+		}
+		err = inner
+	}
+	message, _, _ := strings.Cut(err.Error(), "\n")
+	if runes := []rune(message); len(runes) > 80 {
+		message = string(runes[:80]) + "…"
+	}
+	return message
+}
@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Default retry settings used when a RetryPolicy leaves them unset
+const (
+	DefaultRetryInitialBackoff = 500 * time.Millisecond
+	DefaultRetryMaxBackoff     = 30 * time.Second
+	DefaultRetryMultiplier     = 2.0
+)
+
+// RetryPolicy describes how a failed item is retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per item, including the first (values below 1 mean 1)
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after each retry
+	Multiplier float64
+	// RetryIf decides whether an error should be retried. If nil, every error is retried
+	// except context cancellation and errors marked with Permanent.
+	RetryIf func(error) bool
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps err so that retry policies never retry it
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err has been marked with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// shouldRetry reports whether err is eligible for another attempt under the policy
+func (r RetryPolicy) shouldRetry(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if IsPermanent(err) {
+		return false
+	}
+	if r.RetryIf != nil {
+		return r.RetryIf(err)
+	}
+	return true
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the attempts are
+// exhausted or the context is done. It returns the number of attempts made.
+func (r RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) (int, error) {
+	maxAttempts := r.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	backoff := r.InitialBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryInitialBackoff
+	}
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+	multiplier := r.Multiplier
+	if multiplier < 1 {
+		multiplier = DefaultRetryMultiplier
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || attempt >= maxAttempts || !r.shouldRetry(err) {
+			return attempt, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+
+		backoff = time.Duration(float64(backoff) * multiplier)
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
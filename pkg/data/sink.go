@@ -0,0 +1,213 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ProcessItemSink defines an interface for destinations that consume processed ProcessItems
+type ProcessItemSink interface {
+	// Write writes a batch of ProcessItems to the sink
+	Write(ctx context.Context, items []*ProcessItem) error
+	// Flush forces any buffered items to be written
+	Flush(ctx context.Context) error
+	// Close flushes remaining items and releases any resources used by the sink
+	Close() error
+}
+
+// JSONLSink implements ProcessItemSink by writing one JSON-encoded item per line
+type JSONLSink struct {
+	mu     sync.Mutex
+	writer *bufio.Writer
+	closer io.Closer
+}
+
+// NewJSONLSink creates a new sink that writes JSON lines to w.
+// The writer is not closed when the sink is closed.
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{
+		writer: bufio.NewWriter(w),
+	}
+}
+
+// NewJSONLFileSink creates a new sink that writes JSON lines to a file, truncating it if it exists
+func NewJSONLFileSink(path string) (*JSONLSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	return &JSONLSink{
+		writer: bufio.NewWriter(file),
+		closer: file,
+	}, nil
+}
+
+// NewStdoutSink creates a new sink that writes JSON lines to standard output
+func NewStdoutSink() *JSONLSink {
+	return NewJSONLSink(os.Stdout)
+}
+
+// Write implements the ProcessItemSink interface
+func (s *JSONLSink) Write(_ context.Context, items []*ProcessItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		line, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to marshal item %s: %w", item.ID, err)
+		}
+		if _, err := s.writer.Write(line); err != nil {
+			return err
+		}
+		if err := s.writer.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush implements the ProcessItemSink interface
+func (s *JSONLSink) Flush(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer.Flush()
+}
+
+// Close implements the ProcessItemSink interface
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.writer.Flush()
+	if s.closer != nil {
+		if closeErr := s.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// JSONFileSink implements ProcessItemSink by streaming items into a file as a single JSON array
+type JSONFileSink struct {
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	written int
+}
+
+// NewJSONFileSink creates a new sink that writes a JSON array to a file, truncating it if it exists
+func NewJSONFileSink(path string) (*JSONFileSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	if _, err := writer.WriteString("[\n"); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &JSONFileSink{
+		file:   file,
+		writer: writer,
+	}, nil
+}
+
+// Write implements the ProcessItemSink interface
+func (s *JSONFileSink) Write(_ context.Context, items []*ProcessItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		encoded, err := json.MarshalIndent(item, "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal item %s: %w", item.ID, err)
+		}
+		if s.written > 0 {
+			if _, err := s.writer.WriteString(",\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := s.writer.WriteString("  "); err != nil {
+			return err
+		}
+		if _, err := s.writer.Write(encoded); err != nil {
+			return err
+		}
+		s.written++
+	}
+
+	return nil
+}
+
+// Flush implements the ProcessItemSink interface
+func (s *JSONFileSink) Flush(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer.Flush()
+}
+
+// Close implements the ProcessItemSink interface. It terminates the JSON array and closes the file.
+func (s *JSONFileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.writer.WriteString("\n]\n")
+	if err == nil {
+		err = s.writer.Flush()
+	}
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WriteStream drains a results channel into a sink, writing in batches of batchSize.
+// It stops at the first processing or write error and flushes the sink before returning.
+// If ctx is cancelled, the results received until then are written and ctx.Err() is
+// returned, so partial output isn't reported as success.
+func WriteStream(ctx context.Context, results <-chan ProcessResult, sink ProcessItemSink, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	batch := make([]*ProcessItem, 0, batchSize)
+	for res := range results {
+		if res.Err != nil {
+			return res.Err
+		}
+		batch = append(batch, res.Item)
+		if len(batch) >= batchSize {
+			if err := sink.Write(ctx, batch); err != nil {
+				return err
+			}
+			batch = make([]*ProcessItem, 0, batchSize)
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := sink.Write(ctx, batch); err != nil {
+			return err
+		}
+	}
+
+	if err := sink.Flush(ctx); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		cancel  bool
+		wantErr error
+	}{
+		{name: "exhausted"},
+		{name: "cancelled", cancel: true, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// A cancelled stream closes its channel without an error, like ProcessStream
+			results := make(chan ProcessResult, 3)
+			for _, id := range []string{"1", "2", "3"} {
+				results <- ProcessResult{Item: NewTextProcessItem(id, "text", nil)}
+			}
+			close(results)
+			if tt.cancel {
+				cancel()
+			}
+
+			var out bytes.Buffer
+			err := WriteStream(ctx, results, NewJSONLSink(&out), 2)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			// The results received are written either way
+			if lines := strings.Count(out.String(), "\n"); lines != 3 {
+				t.Errorf("expected 3 written items, got %d", lines)
+			}
+		})
+	}
+}
+
+func TestIdempotentSink(t *testing.T) {
+	item := func(id, text string) *ProcessItem {
+		result := NewTextProcessItem(id, text, nil)
+		result.Provenance = NewTextProcessItem("", text, nil).ProvenanceHash("sentiment", "v1", "openai/gpt-4o")
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		runs      [][]*ProcessItem
+		wantLines int
+	}{
+		{
+			name:      "re-run writes nothing again",
+			runs:      [][]*ProcessItem{{item("1", "a"), item("2", "b")}, {item("1", "a"), item("2", "b")}},
+			wantLines: 2,
+		},
+		{
+			name:      "changed text is written",
+			runs:      [][]*ProcessItem{{item("1", "a")}, {item("1", "a, edited")}},
+			wantLines: 2,
+		},
+		{
+			name:      "duplicates within a batch",
+			runs:      [][]*ProcessItem{{item("1", "a"), item("1", "a")}},
+			wantLines: 1,
+		},
+		{
+			name:      "items without provenance",
+			runs:      [][]*ProcessItem{{NewTextProcessItem("1", "a", nil)}, {NewTextProcessItem("1", "a", nil)}},
+			wantLines: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			written := NewMemoryCheckpointStore()
+			for _, items := range tt.runs {
+				// Each run opens a new sink over the same checkpoint store
+				sink := NewIdempotentSink(NewJSONLSink(&out), written)
+				if err := sink.Write(context.Background(), items); err != nil {
+					t.Fatal(err)
+				}
+				if err := sink.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if lines := strings.Count(out.String(), "\n"); lines != tt.wantLines {
+				t.Errorf("expected %d written items, got %d", tt.wantLines, lines)
+			}
+		})
+	}
+}
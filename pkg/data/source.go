@@ -38,6 +38,11 @@ func (s *ProcessItemSliceSource) NextProcessItem(_ context.Context) (*ProcessIte
 	return item, nil
 }
 
+// Len implements the SizedSource interface
+func (s *ProcessItemSliceSource) Len() int {
+	return len(s.items) - s.index
+}
+
 // Close implements the ProcessItemSource interface
 func (s *ProcessItemSliceSource) Close() error {
 	return nil
@@ -73,6 +78,11 @@ func (s *TextStringsProcessItemSource) NextProcessItem(_ context.Context) (*Proc
 	return item, nil
 }
 
+// Len implements the SizedSource interface
+func (s *TextStringsProcessItemSource) Len() int {
+	return len(s.items) - s.index
+}
+
 // Close implements the ProcessItemSource interface
 func (s *TextStringsProcessItemSource) Close() error {
 	return nil
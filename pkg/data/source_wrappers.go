@@ -0,0 +1,133 @@
+package data
+
+import (
+	"context"
+	"io"
+	"math/rand"
+)
+
+// LimitSource wraps a ProcessItemSource and stops after n items
+type LimitSource struct {
+	source    ProcessItemSource
+	remaining int
+}
+
+// Limit returns a source that yields at most n items from source
+func Limit(source ProcessItemSource, n int) *LimitSource {
+	if n < 0 {
+		n = 0
+	}
+	return &LimitSource{
+		source:    source,
+		remaining: n,
+	}
+}
+
+// NextProcessItem implements the ProcessItemSource interface
+func (s *LimitSource) NextProcessItem(ctx context.Context) (*ProcessItem, error) {
+	if s.remaining <= 0 {
+		return nil, io.EOF
+	}
+
+	item, err := s.source.NextProcessItem(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.remaining--
+	return item, nil
+}
+
+// Len implements the SizedSource interface. If the wrapped source size is unknown,
+// the remaining limit is reported.
+func (s *LimitSource) Len() int {
+	n := SourceLen(s.source)
+	if n < 0 || n > s.remaining {
+		return s.remaining
+	}
+	return n
+}
+
+// Close implements the ProcessItemSource interface
+func (s *LimitSource) Close() error {
+	return s.source.Close()
+}
+
+// FilterSource wraps a ProcessItemSource and skips items rejected by a predicate
+type FilterSource struct {
+	source ProcessItemSource
+	keep   func(*ProcessItem) bool
+}
+
+// Filter returns a source that yields only the items for which keep returns true
+func Filter(source ProcessItemSource, keep func(*ProcessItem) bool) *FilterSource {
+	return &FilterSource{
+		source: source,
+		keep:   keep,
+	}
+}
+
+// NextProcessItem implements the ProcessItemSource interface
+func (s *FilterSource) NextProcessItem(ctx context.Context) (*ProcessItem, error) {
+	for {
+		item, err := s.source.NextProcessItem(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if s.keep(item) {
+			return item, nil
+		}
+	}
+}
+
+// Close implements the ProcessItemSource interface
+func (s *FilterSource) Close() error {
+	return s.source.Close()
+}
+
+// Sample returns a source that yields each item from source with probability rate.
+// A rate of 1 or more keeps every item; 0 or less keeps none. Pass a seeded
+// *rand.Rand for reproducible samples, or nil to use the global generator.
+func Sample(source ProcessItemSource, rate float64, rng *rand.Rand) *FilterSource {
+	random := rand.Float64
+	if rng != nil {
+		random = rng.Float64
+	}
+
+	return Filter(source, func(*ProcessItem) bool {
+		return random() < rate
+	})
+}
+
+// MapSource wraps a ProcessItemSource and transforms each item
+type MapSource struct {
+	source    ProcessItemSource
+	transform func(*ProcessItem) (*ProcessItem, error)
+}
+
+// Map returns a source that applies transform to each item from source.
+// An error from transform is returned from NextProcessItem.
+func Map(source ProcessItemSource, transform func(*ProcessItem) (*ProcessItem, error)) *MapSource {
+	return &MapSource{
+		source:    source,
+		transform: transform,
+	}
+}
+
+// NextProcessItem implements the ProcessItemSource interface
+func (s *MapSource) NextProcessItem(ctx context.Context) (*ProcessItem, error) {
+	item, err := s.source.NextProcessItem(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.transform(item)
+}
+
+// Len implements the SizedSource interface when the wrapped source is sized
+func (s *MapSource) Len() int {
+	return SourceLen(s.source)
+}
+
+// Close implements the ProcessItemSource interface
+func (s *MapSource) Close() error {
+	return s.source.Close()
+}
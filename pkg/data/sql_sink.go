@@ -0,0 +1,183 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// SQLDialect identifies the SQL flavour used by a SQLSink
+type SQLDialect string
+
+const (
+	// SQLite dialect
+	SQLite SQLDialect = "sqlite"
+	// Postgres dialect
+	Postgres SQLDialect = "postgres"
+)
+
+// DefaultSQLTable is the default table name used by SQLSink
+const DefaultSQLTable = "process_results"
+
+// validTableName restricts table names to safe SQL identifiers
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLSinkConfig holds configuration for a SQLSink
+type SQLSinkConfig struct {
+	// Dialect selects SQLite or Postgres syntax
+	Dialect SQLDialect
+	// Table is the name of the results table (defaults to DefaultSQLTable)
+	Table string
+	// SkipCreateTable disables automatic schema creation
+	SkipCreateTable bool
+}
+
+// SQLSink implements ProcessItemSink by writing one row per processor result
+// into a relational table. The caller owns the *sql.DB and its driver.
+type SQLSink struct {
+	db      *sql.DB
+	dialect SQLDialect
+	table   string
+}
+
+// NewSQLSink creates a new SQL sink and creates the results table if needed
+func NewSQLSink(ctx context.Context, db *sql.DB, config SQLSinkConfig) (*SQLSink, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+
+	switch config.Dialect {
+	case SQLite, Postgres:
+	case "":
+		config.Dialect = SQLite
+	default:
+		return nil, fmt.Errorf("unsupported SQL dialect: %s", config.Dialect)
+	}
+
+	if config.Table == "" {
+		config.Table = DefaultSQLTable
+	}
+	if !validTableName.MatchString(config.Table) {
+		return nil, fmt.Errorf("invalid table name: %s", config.Table)
+	}
+
+	s := &SQLSink{
+		db:      db,
+		dialect: config.Dialect,
+		table:   config.Table,
+	}
+
+	if !config.SkipCreateTable {
+		if _, err := db.ExecContext(ctx, s.createTableSQL()); err != nil {
+			return nil, fmt.Errorf("failed to create table %s: %w", s.table, err)
+		}
+	}
+
+	return s, nil
+}
+
+// createTableSQL returns the schema statement for the configured dialect
+func (s *SQLSink) createTableSQL() string {
+	if s.dialect == Postgres {
+		return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id BIGSERIAL PRIMARY KEY,
+	item_id TEXT NOT NULL,
+	processor TEXT NOT NULL,
+	result JSONB,
+	tokens BIGINT NOT NULL DEFAULT 0,
+	cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+	processed_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`, s.table)
+	}
+
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	item_id TEXT NOT NULL,
+	processor TEXT NOT NULL,
+	result TEXT,
+	tokens INTEGER NOT NULL DEFAULT 0,
+	cost REAL NOT NULL DEFAULT 0,
+	processed_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`, s.table)
+}
+
+// insertSQL returns the insert statement for the configured dialect
+func (s *SQLSink) insertSQL() string {
+	if s.dialect == Postgres {
+		return fmt.Sprintf("INSERT INTO %s (item_id, processor, result, tokens, cost, processed_at) VALUES ($1, $2, $3, $4, $5, $6)", s.table)
+	}
+	return fmt.Sprintf("INSERT INTO %s (item_id, processor, result, tokens, cost, processed_at) VALUES (?, ?, ?, ?, ?, ?)", s.table)
+}
+
+// Write implements the ProcessItemSink interface.
+// Each entry in an item's ProcessingInfo becomes one row, written in a single transaction.
+// The tokens and cost columns hold the estimated usage processors record in their entry.
+func (s *SQLSink) Write(ctx context.Context, items []*ProcessItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, s.insertSQL())
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+
+		// Sort processor names so rows are written in a stable order
+		names := make([]string, 0, len(item.ProcessingInfo))
+		for name := range item.ProcessingInfo {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			info := item.ProcessingInfo[name]
+			resultJSON, err := json.Marshal(info)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to marshal result for item %s: %w", item.ID, err)
+			}
+
+			tokens, cost := UsageFromInfo(info)
+			if _, err := stmt.ExecContext(ctx, item.ID, name, string(resultJSON), tokens, cost, now); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to insert result for item %s: %w", item.ID, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Flush implements the ProcessItemSink interface. Writes are committed immediately,
+// so there is nothing to flush.
+func (s *SQLSink) Flush(_ context.Context) error {
+	return nil
+}
+
+// Close implements the ProcessItemSink interface. The database handle is owned by the
+// caller and is not closed.
+func (s *SQLSink) Close() error {
+	return nil
+}
@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingDriver is a database/sql driver that accepts every statement and records the
+// arguments of inserts
+type recordingDriver struct {
+	mu      sync.Mutex
+	inserts [][]driver.Value
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) {
+	return &recordingConn{driver: d}, nil
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{conn: c, query: query}, nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	return nil
+}
+
+type recordingStmt struct {
+	conn  *recordingConn
+	query string
+}
+
+func (s *recordingStmt) Close() error {
+	return nil
+}
+
+func (s *recordingStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.HasPrefix(s.query, "INSERT") {
+		s.conn.driver.mu.Lock()
+		s.conn.driver.inserts = append(s.conn.driver.inserts, args)
+		s.conn.driver.mu.Unlock()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, fmt.Errorf("queries are not supported")
+}
+
+func TestSQLSinkWritesUsage(t *testing.T) {
+	recorder := &recordingDriver{}
+	name := "recording-" + t.Name()
+	sql.Register(name, recorder)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	sink, err := NewSQLSink(ctx, db, SQLSinkConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := NewTextProcessItem("1", "text", nil)
+	// The usage processors record with their results, before and after a JSON round trip
+	item.AddProcessingInfo("sentiment", map[string]interface{}{"sentiment": "positive", "tokens": int64(120), "cost": 0.002})
+	item.AddProcessingInfo("intent", map[string]interface{}{"label": "refund", "tokens": float64(80), "cost": 0.001})
+	if err := sink.Write(ctx, []*ProcessItem{item}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorder.inserts) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(recorder.inserts))
+	}
+	// Rows are written in processor name order: item_id, processor, result, tokens, cost, processed_at
+	for i, want := range []struct {
+		processor string
+		tokens    int64
+		cost      float64
+	}{{"intent", 80, 0.001}, {"sentiment", 120, 0.002}} {
+		row := recorder.inserts[i]
+		if row[1] != want.processor || row[3] != want.tokens || row[4] != want.cost {
+			t.Errorf("row %d: expected %s with %d tokens and cost %g, got %v", i, want.processor, want.tokens, want.cost, row)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package data
+
+import "context"
+
+// StateKey is the metadata key holding an item's state: values that pipeline steps
+// share with later steps, such as a detected language or extracted attributes
+const StateKey = "state"
+
+// stateContextKey is the context key for the state of the item being processed
+type stateContextKey struct{}
+
+// SetState stores a value in the item's state for later steps to read
+func (p *ProcessItem) SetState(key string, value interface{}) {
+	if p.Metadata == nil {
+		p.Metadata = make(map[string]interface{})
+	}
+	state, ok := p.Metadata[StateKey].(map[string]interface{})
+	if !ok {
+		state = make(map[string]interface{})
+		p.Metadata[StateKey] = state
+	}
+	state[key] = value
+}
+
+// GetState returns a value from the item's state
+func (p *ProcessItem) GetState(key string) (interface{}, bool) {
+	state, ok := p.Metadata[StateKey].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := state[key]
+	return value, ok
+}
+
+// State returns a copy of the item's state, or nil if it has none
+func (p *ProcessItem) State() map[string]interface{} {
+	state, ok := p.Metadata[StateKey].(map[string]interface{})
+	if !ok || len(state) == 0 {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(state))
+	for k, v := range state {
+		copied[k] = v
+	}
+	return copied
+}
+
+// WithItemState returns a context carrying the state of the item being processed, so
+// prompt generators and other hooks that only receive text can read it
+func WithItemState(ctx context.Context, state map[string]interface{}) context.Context {
+	return context.WithValue(ctx, stateContextKey{}, state)
+}
+
+// ItemStateFromContext returns the item state attached with WithItemState, or nil
+func ItemStateFromContext(ctx context.Context) map[string]interface{} {
+	state, _ := ctx.Value(stateContextKey{}).(map[string]interface{})
+	return state
+}
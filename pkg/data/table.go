@@ -0,0 +1,500 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// ColumnType is the type of the values of a table column
+type ColumnType string
+
+const (
+	// ColumnString columns hold strings
+	ColumnString ColumnType = "string"
+	// ColumnNumber columns hold float64 values
+	ColumnNumber ColumnType = "number"
+	// ColumnBoolean columns hold bool values
+	ColumnBoolean ColumnType = "boolean"
+	// ColumnDate columns hold time.Time values, stored as dates
+	ColumnDate ColumnType = "date"
+)
+
+// TableDateLayout is the layout dates are written with as text
+const TableDateLayout = "2006-01-02"
+
+// TableColumn is a column of a table written by a TableWriter
+type TableColumn struct {
+	// Name is the column's name, a SQL identifier for SQL tables
+	Name string `json:"name" yaml:"name"`
+	// Type is the type of the column's values (defaults to ColumnString)
+	Type ColumnType `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+// TableWriter writes rows of a table with typed columns, such as a CSV file, a Parquet
+// file or a SQL table. Row values are in column order: a string, float64, bool or
+// time.Time as the column's type asks, or nil for null.
+type TableWriter interface {
+	// WriteRows writes rows to the table
+	WriteRows(ctx context.Context, rows [][]interface{}) error
+	// Flush forces any buffered rows to be written
+	Flush(ctx context.Context) error
+	// Close flushes remaining rows and releases any resources used by the writer
+	Close() error
+}
+
+// validateColumns checks that a table has columns of known types with distinct names,
+// defaulting their types to ColumnString
+func validateColumns(columns []TableColumn) ([]TableColumn, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table has no columns")
+	}
+	validated := make([]TableColumn, len(columns))
+	seen := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		if column.Name == "" {
+			return nil, fmt.Errorf("column %d has no name", i+1)
+		}
+		if seen[column.Name] {
+			return nil, fmt.Errorf("duplicate column %s", column.Name)
+		}
+		seen[column.Name] = true
+		switch column.Type {
+		case "":
+			column.Type = ColumnString
+		case ColumnString, ColumnNumber, ColumnBoolean, ColumnDate:
+		default:
+			return nil, fmt.Errorf("column %s: unknown type %s", column.Name, column.Type)
+		}
+		validated[i] = column
+	}
+	return validated, nil
+}
+
+// checkRow checks that a row has a value of the right type for every column
+func checkRow(columns []TableColumn, row []interface{}) error {
+	if len(row) != len(columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(row), len(columns))
+	}
+	for i, value := range row {
+		if value == nil {
+			continue
+		}
+		var ok bool
+		switch columns[i].Type {
+		case ColumnNumber:
+			_, ok = value.(float64)
+		case ColumnBoolean:
+			_, ok = value.(bool)
+		case ColumnDate:
+			_, ok = value.(time.Time)
+		default:
+			_, ok = value.(string)
+		}
+		if !ok {
+			return fmt.Errorf("column %s: %T value in a %s column", columns[i].Name, value, columns[i].Type)
+		}
+	}
+	return nil
+}
+
+// formatCell writes a value as text, with "" for null
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(TableDateLayout)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// CSVTableWriter implements TableWriter by writing a CSV file with a header row
+type CSVTableWriter struct {
+	mu      sync.Mutex
+	columns []TableColumn
+	writer  *csv.Writer
+	buffer  *bufio.Writer
+	closer  io.Closer
+}
+
+// NewCSVTableWriter creates a table writer writing CSV to w, starting with the header row.
+// The writer is not closed when the table writer is closed.
+func NewCSVTableWriter(w io.Writer, columns []TableColumn) (*CSVTableWriter, error) {
+	columns, err := validateColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+	buffer := bufio.NewWriter(w)
+	t := &CSVTableWriter{columns: columns, writer: csv.NewWriter(buffer), buffer: buffer}
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	if err := t.writer.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	return t, nil
+}
+
+// NewCSVTableFileWriter creates a table writer writing a CSV file, truncating it if it exists
+func NewCSVTableFileWriter(path string, columns []TableColumn) (*CSVTableWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	t, err := NewCSVTableWriter(file, columns)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	t.closer = file
+	return t, nil
+}
+
+// WriteRows implements the TableWriter interface
+func (t *CSVTableWriter) WriteRows(_ context.Context, rows [][]interface{}) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record := make([]string, len(t.columns))
+	for _, row := range rows {
+		if err := checkRow(t.columns, row); err != nil {
+			return err
+		}
+		for i, value := range row {
+			record[i] = formatCell(value)
+		}
+		if err := t.writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	return nil
+}
+
+// Flush implements the TableWriter interface
+func (t *CSVTableWriter) Flush(_ context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.flush()
+}
+
+// flush writes the buffered rows
+func (t *CSVTableWriter) flush() error {
+	t.writer.Flush()
+	if err := t.writer.Error(); err != nil {
+		return err
+	}
+	return t.buffer.Flush()
+}
+
+// Close implements the TableWriter interface
+func (t *CSVTableWriter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := t.flush()
+	if t.closer != nil {
+		if closeErr := t.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// ParquetTableWriter implements TableWriter by writing a Parquet file with a typed,
+// optional column per table column: numbers as doubles, booleans and dates as such and
+// strings as UTF-8 strings
+type ParquetTableWriter struct {
+	mu      sync.Mutex
+	columns []TableColumn
+	// indexes are the Parquet column indexes of the table columns, which Parquet orders by name
+	indexes []int
+	file    *os.File
+	writer  *parquet.Writer
+}
+
+// NewParquetTableWriter creates a table writer writing a Parquet file, truncating it if it exists
+func NewParquetTableWriter(path string, columns []TableColumn) (*ParquetTableWriter, error) {
+	columns, err := validateColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	group := make(parquet.Group, len(columns))
+	for _, column := range columns {
+		var node parquet.Node
+		switch column.Type {
+		case ColumnNumber:
+			node = parquet.Leaf(parquet.DoubleType)
+		case ColumnBoolean:
+			node = parquet.Leaf(parquet.BooleanType)
+		case ColumnDate:
+			node = parquet.Date()
+		default:
+			node = parquet.String()
+		}
+		group[column.Name] = parquet.Optional(node)
+	}
+	schema := parquet.NewSchema("table", group)
+
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		leaf, _ := schema.Lookup(column.Name)
+		indexes[i] = leaf.ColumnIndex
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet file: %w", err)
+	}
+	return &ParquetTableWriter{
+		columns: columns,
+		indexes: indexes,
+		file:    file,
+		writer:  parquet.NewWriter(file, schema),
+	}, nil
+}
+
+// WriteRows implements the TableWriter interface
+func (t *ParquetTableWriter) WriteRows(_ context.Context, rows [][]interface{}) error {
+	parquetRows := make([]parquet.Row, 0, len(rows))
+	for _, row := range rows {
+		if err := checkRow(t.columns, row); err != nil {
+			return err
+		}
+		parquetRow := make(parquet.Row, len(row))
+		for i, value := range row {
+			index := t.indexes[i]
+			switch v := value.(type) {
+			case nil:
+				parquetRow[index] = parquet.NullValue().Level(0, 0, index)
+				continue
+			case float64:
+				parquetRow[index] = parquet.DoubleValue(v)
+			case bool:
+				parquetRow[index] = parquet.BooleanValue(v)
+			case time.Time:
+				days := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+				parquetRow[index] = parquet.Int32Value(int32(days))
+			case string:
+				parquetRow[index] = parquet.ByteArrayValue([]byte(v))
+			}
+			parquetRow[index] = parquetRow[index].Level(0, 1, index)
+		}
+		parquetRows = append(parquetRows, parquetRow)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.writer.WriteRows(parquetRows); err != nil {
+		return fmt.Errorf("failed to write Parquet rows: %w", err)
+	}
+	return nil
+}
+
+// Flush implements the TableWriter interface. It ends the current row group.
+func (t *ParquetTableWriter) Flush(_ context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.writer.Flush()
+}
+
+// Close implements the TableWriter interface. It writes the file footer and closes the file.
+func (t *ParquetTableWriter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := t.writer.Close()
+	if closeErr := t.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SQLTableConfig holds configuration for a SQLTableWriter
+type SQLTableConfig struct {
+	// Dialect selects SQLite or Postgres syntax (defaults to SQLite)
+	Dialect SQLDialect
+	// Table is the name of the table
+	Table string
+	// Columns are the table's columns; their names must be SQL identifiers
+	Columns []TableColumn
+	// SkipCreateTable disables automatic schema creation
+	SkipCreateTable bool
+}
+
+// SQLTableWriter implements TableWriter by inserting rows into a SQL table with a typed
+// column per table column. SQLite stores dates as TEXT in TableDateLayout and booleans as
+// INTEGER. The caller owns the *sql.DB and its driver.
+type SQLTableWriter struct {
+	db      *sql.DB
+	config  SQLTableConfig
+	columns []TableColumn
+}
+
+// NewSQLTableWriter creates a table writer inserting into a SQL table, creating the table
+// if needed
+func NewSQLTableWriter(ctx context.Context, db *sql.DB, config SQLTableConfig) (*SQLTableWriter, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is required")
+	}
+	switch config.Dialect {
+	case SQLite, Postgres:
+	case "":
+		config.Dialect = SQLite
+	default:
+		return nil, fmt.Errorf("unsupported SQL dialect: %s", config.Dialect)
+	}
+	if !validTableName.MatchString(config.Table) {
+		return nil, fmt.Errorf("invalid table name: %s", config.Table)
+	}
+	columns, err := validateColumns(config.Columns)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range columns {
+		if !validTableName.MatchString(column.Name) {
+			return nil, fmt.Errorf("invalid column name: %s", column.Name)
+		}
+	}
+
+	t := &SQLTableWriter{db: db, config: config, columns: columns}
+	if !config.SkipCreateTable {
+		if _, err := db.ExecContext(ctx, t.createTableSQL()); err != nil {
+			return nil, fmt.Errorf("failed to create table %s: %w", config.Table, err)
+		}
+	}
+	return t, nil
+}
+
+// createTableSQL returns the schema statement for the configured dialect
+func (t *SQLTableWriter) createTableSQL() string {
+	definitions := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		definitions[i] = fmt.Sprintf("\t%s %s", column.Name, t.sqlType(column.Type))
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n)", t.config.Table, strings.Join(definitions, ",\n"))
+}
+
+// sqlType returns the SQL type of a column type in the configured dialect
+func (t *SQLTableWriter) sqlType(columnType ColumnType) string {
+	postgres := t.config.Dialect == Postgres
+	switch columnType {
+	case ColumnNumber:
+		if postgres {
+			return "DOUBLE PRECISION"
+		}
+		return "REAL"
+	case ColumnBoolean:
+		if postgres {
+			return "BOOLEAN"
+		}
+		return "INTEGER"
+	case ColumnDate:
+		if postgres {
+			return "DATE"
+		}
+		return "TEXT"
+	default:
+		return "TEXT"
+	}
+}
+
+// insertSQL returns the insert statement for the configured dialect
+func (t *SQLTableWriter) insertSQL() string {
+	names := make([]string, len(t.columns))
+	placeholders := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		names[i] = column.Name
+		placeholders[i] = "?"
+		if t.config.Dialect == Postgres {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+		}
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", t.config.Table, strings.Join(names, ", "), strings.Join(placeholders, ", "))
+}
+
+// WriteRows implements the TableWriter interface, inserting the rows in a single transaction
+func (t *SQLTableWriter) WriteRows(ctx context.Context, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	for _, row := range rows {
+		if err := checkRow(t.columns, row); err != nil {
+			return err
+		}
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, t.insertSQL())
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		args := make([]interface{}, len(row))
+		for i, value := range row {
+			args[i] = t.sqlValue(value)
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert row: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// sqlValue converts a row value for the configured dialect
+func (t *SQLTableWriter) sqlValue(value interface{}) interface{} {
+	if t.config.Dialect == Postgres {
+		return value
+	}
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	case time.Time:
+		return v.Format(TableDateLayout)
+	default:
+		return value
+	}
+}
+
+// Flush implements the TableWriter interface. Writes are committed immediately, so there
+// is nothing to flush.
+func (t *SQLTableWriter) Flush(_ context.Context) error {
+	return nil
+}
+
+// Close implements the TableWriter interface. The database handle is owned by the caller
+// and is not closed.
+func (t *SQLTableWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,130 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestTableWriters(t *testing.T) {
+	ctx := context.Background()
+	columns := []TableColumn{
+		{Name: "id"},
+		{Name: "amount", Type: ColumnNumber},
+		{Name: "resolved", Type: ColumnBoolean},
+		{Name: "due", Type: ColumnDate},
+	}
+	rows := [][]interface{}{
+		{"1", 12.5, true, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"2", nil, nil, nil},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buffer bytes.Buffer
+		writer, err := NewCSVTableWriter(&buffer, columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.WriteRows(ctx, rows); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		want := "id,amount,resolved,due\n1,12.5,true,2024-03-01\n2,,,\n"
+		if buffer.String() != want {
+			t.Errorf("expected %q, got %q", want, buffer.String())
+		}
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "table.parquet")
+		writer, err := NewParquetTableWriter(path, columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.WriteRows(ctx, rows); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		reader := parquet.NewReader(file)
+		var got []map[string]interface{}
+		for {
+			row := make(map[string]interface{})
+			if err := reader.Read(&row); err != nil {
+				break
+			}
+			got = append(got, row)
+		}
+		if len(got) != 2 {
+			t.Fatalf("expected 2 rows, got %v", got)
+		}
+		if got[0]["id"] != "1" || got[0]["amount"] != 12.5 || got[0]["resolved"] != true {
+			t.Errorf("unexpected first row %v", got[0])
+		}
+		if got[1]["amount"] != nil || got[1]["resolved"] != nil {
+			t.Errorf("expected nulls in the second row, got %v", got[1])
+		}
+	})
+
+	t.Run("sql", func(t *testing.T) {
+		recorder := &recordingDriver{}
+		name := "recording-" + t.Name()
+		sql.Register(name, recorder)
+		db, err := sql.Open(name, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		writer, err := NewSQLTableWriter(ctx, db, SQLTableConfig{Table: "attributes", Columns: columns})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.WriteRows(ctx, rows); err != nil {
+			t.Fatal(err)
+		}
+		want := [][]driver.Value{
+			{"1", 12.5, int64(1), "2024-03-01"},
+			{"2", nil, nil, nil},
+		}
+		if !reflect.DeepEqual(recorder.inserts, want) {
+			t.Errorf("expected inserts %v, got %v", want, recorder.inserts)
+		}
+	})
+
+	t.Run("invalid rows", func(t *testing.T) {
+		writer, err := NewCSVTableWriter(&bytes.Buffer{}, columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range [][]interface{}{
+			{"1", 12.5, true},
+			{"1", "12.5", true, nil},
+		} {
+			if err := writer.WriteRows(ctx, [][]interface{}{row}); err == nil {
+				t.Errorf("expected an error for row %v", row)
+			}
+		}
+	})
+
+	if _, err := NewCSVTableWriter(&bytes.Buffer{}, []TableColumn{{Name: "id"}, {Name: "id"}}); err == nil {
+		t.Error("expected an error for duplicate columns")
+	}
+}
@@ -0,0 +1,23 @@
+package data
+
+// UsageFromInfo extracts the token and cost figures a processor recorded in its
+// ProcessingInfo entry under the "tokens" and "cost" keys, returning zeros if absent
+func UsageFromInfo(info interface{}) (int64, float64) {
+	infoMap, ok := info.(map[string]interface{})
+	if !ok {
+		return 0, 0
+	}
+
+	var tokens int64
+	switch v := infoMap["tokens"].(type) {
+	case int:
+		tokens = int64(v)
+	case int64:
+		tokens = v
+	case float64:
+		tokens = int64(v)
+	}
+
+	cost, _ := infoMap["cost"].(float64)
+	return tokens, cost
+}
@@ -0,0 +1,323 @@
+package data
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// xlsxMaxSheetName is the longest sheet name Excel accepts
+	xlsxMaxSheetName = 31
+	// xlsxMaxCell is the longest text Excel accepts in a cell
+	xlsxMaxCell = 32767
+)
+
+// XLSXSink implements ProcessItemSink by writing an Excel workbook with one sheet per
+// processor. Each sheet has an id column and the columns of the results flattened with
+// Flatten. Workbooks can't be appended to, so the items are held in memory and the
+// workbook is written when the sink is closed.
+type XLSXSink struct {
+	mu      sync.Mutex
+	flatten FlattenConfig
+	writer  io.Writer
+	closer  io.Closer
+	sheets  []*xlsxSheet
+	// byName holds the sheets by processor name
+	byName map[string]*xlsxSheet
+}
+
+// xlsxSheet holds the rows of one processor's sheet
+type xlsxSheet struct {
+	name    string
+	columns []string
+	index   map[string]int
+	rows    []map[string]interface{}
+	ids     []string
+}
+
+// NewXLSXSink creates a new sink that writes a workbook to w when closed, flattening
+// results with config. The writer is not closed when the sink is closed.
+func NewXLSXSink(w io.Writer, config FlattenConfig) (*XLSXSink, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &XLSXSink{flatten: config, writer: w, byName: make(map[string]*xlsxSheet)}, nil
+}
+
+// NewXLSXFileSink creates a new sink that writes a workbook to a file, truncating it if it exists
+func NewXLSXFileSink(path string, config FlattenConfig) (*XLSXSink, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	s, _ := NewXLSXSink(file, config)
+	s.closer = file
+	return s, nil
+}
+
+// Write implements the ProcessItemSink interface, adding the rows of each item to the
+// sheet of every processor in its processing info
+func (s *XLSXSink) Write(_ context.Context, items []*ProcessItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		processors := make([]string, 0, len(item.ProcessingInfo))
+		for name := range item.ProcessingInfo {
+			processors = append(processors, name)
+		}
+		sort.Strings(processors)
+
+		for _, name := range processors {
+			rows, err := Flatten(item.ProcessingInfo[name], s.flatten)
+			if err != nil {
+				return fmt.Errorf("item %s: %s result: %w", item.ID, name, err)
+			}
+			for _, row := range rows {
+				s.sheet(name).addRow(item.ID, row)
+			}
+		}
+	}
+	return nil
+}
+
+// sheet returns the sheet of a processor, adding it if needed
+func (s *XLSXSink) sheet(processor string) *xlsxSheet {
+	if sheet, ok := s.byName[processor]; ok {
+		return sheet
+	}
+	sheet := &xlsxSheet{name: s.sheetName(processor), index: make(map[string]int)}
+	s.sheets = append(s.sheets, sheet)
+	s.byName[processor] = sheet
+	return sheet
+}
+
+// sheetName returns a valid sheet name for a processor that no other sheet has, ignoring case
+func (s *XLSXSink) sheetName(processor string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, processor)
+	if name == "" {
+		name = "Sheet"
+	}
+
+	for n := 1; ; n++ {
+		suffix := ""
+		if n > 1 {
+			suffix = fmt.Sprintf(" (%d)", n)
+		}
+		candidate := truncateRunes(name, xlsxMaxSheetName-len(suffix)) + suffix
+		taken := false
+		for _, sheet := range s.sheets {
+			if strings.EqualFold(sheet.name, candidate) {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return candidate
+		}
+	}
+}
+
+// addRow adds a row, adding columns for fields the sheet doesn't have yet
+func (s *xlsxSheet) addRow(id string, cells map[string]interface{}) {
+	names := make([]string, 0, len(cells))
+	for name := range cells {
+		if _, ok := s.index[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.index[name] = len(s.columns)
+		s.columns = append(s.columns, name)
+	}
+	s.ids = append(s.ids, id)
+	s.rows = append(s.rows, cells)
+}
+
+// Flush implements the ProcessItemSink interface. The workbook is written when the sink is
+// closed, so there is nothing to flush.
+func (s *XLSXSink) Flush(_ context.Context) error {
+	return nil
+}
+
+// Close implements the ProcessItemSink interface, writing the workbook
+func (s *XLSXSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.writeWorkbook()
+	if s.closer != nil {
+		if closeErr := s.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write workbook: %w", err)
+	}
+	return nil
+}
+
+// writeWorkbook writes the sheets as an Office Open XML workbook
+func (s *XLSXSink) writeWorkbook() error {
+	sheets := s.sheets
+	if len(sheets) == 0 {
+		// A workbook needs at least one sheet
+		sheets = []*xlsxSheet{{name: "Sheet1"}}
+	}
+
+	archive := zip.NewWriter(s.writer)
+	var sheetOverrides, workbookSheets, workbookRels strings.Builder
+	for i, sheet := range sheets {
+		fmt.Fprintf(&sheetOverrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&workbookSheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.name), i+1, i+1)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			sheetOverrides.String() + `</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + workbookSheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			workbookRels.String() + `</Relationships>`},
+		// Style 1 is the bold header row
+		{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+	for _, part := range parts {
+		w, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+
+	for i, sheet := range sheets {
+		w, err := archive.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := sheet.write(w); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// write writes the sheet's XML: a frozen, bold header row, then a row per item
+func (s *xlsxSheet) write(w io.Writer) error {
+	buffer := bufio.NewWriter(w)
+	buffer.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	buffer.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	buffer.WriteString(`<sheetData>`)
+
+	header := append([]interface{}{"id"}, make([]interface{}, len(s.columns))...)
+	for i, column := range s.columns {
+		header[i+1] = column
+	}
+	writeXLSXRow(buffer, 1, header, 1)
+	for i, cells := range s.rows {
+		row := make([]interface{}, len(s.columns)+1)
+		row[0] = s.ids[i]
+		for name, value := range cells {
+			row[s.index[name]+1] = value
+		}
+		writeXLSXRow(buffer, i+2, row, 0)
+	}
+
+	buffer.WriteString(`</sheetData></worksheet>`)
+	return buffer.Flush()
+}
+
+// writeXLSXRow writes a row of cells with a style: numbers and booleans as such, other
+// values as inline strings and nil as no cell
+func writeXLSXRow(w *bufio.Writer, number int, values []interface{}, style int) {
+	fmt.Fprintf(w, `<row r="%d">`, number)
+	for i, value := range values {
+		ref := xlsxColumn(i) + strconv.Itoa(number)
+		styleAttr := ""
+		if style > 0 {
+			styleAttr = fmt.Sprintf(` s="%d"`, style)
+		}
+		switch v := value.(type) {
+		case nil:
+		case float64:
+			fmt.Fprintf(w, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, strconv.FormatFloat(v, 'g', -1, 64))
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			fmt.Fprintf(w, `<c r="%s"%s t="b"><v>%d</v></c>`, ref, styleAttr, b)
+		default:
+			text := truncateRunes(fmt.Sprint(v), xlsxMaxCell)
+			fmt.Fprintf(w, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, styleAttr, xmlEscape(text))
+		}
+	}
+	w.WriteString(`</row>`)
+}
+
+// xlsxColumn returns the letters of a zero-based column index: A, B, ..., Z, AA, ...
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xmlEscape escapes text for XML, replacing characters XML can't hold
+func xmlEscape(text string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
+
+// truncateRunes shortens text to at most n runes
+func truncateRunes(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return string([]rune(text)[:n])
+}
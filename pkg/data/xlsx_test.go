@@ -0,0 +1,70 @@
+package data
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestXLSXSink(t *testing.T) {
+	var buffer bytes.Buffer
+	sink, err := NewXLSXSink(&buffer, FlattenConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := NewTextProcessItem("1", "", nil)
+	first.AddProcessingInfo("sentiment", map[string]interface{}{"sentiment": "positive", "score": 0.8, "keywords": []interface{}{"fast", "friendly"}})
+	first.AddProcessingInfo("get_attributes", map[string]interface{}{"attributes": []interface{}{map[string]interface{}{"field_name": "amount"}}})
+	second := NewTextProcessItem("2", "", nil)
+	second.AddProcessingInfo("sentiment", map[string]interface{}{"sentiment": "negative <angry>", "details": map[string]interface{}{"resolved": false}})
+	if err := sink.Write(context.Background(), []*ProcessItem{first, second}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("expected a zip archive: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		parts[file.Name] = string(content)
+	}
+
+	// Sheets are added in the order processors are first seen, which is sorted per item
+	if workbook := parts["xl/workbook.xml"]; !strings.Contains(workbook, `<sheet name="get_attributes" sheetId="1" r:id="rId1"/><sheet name="sentiment" sheetId="2" r:id="rId2"/>`) {
+		t.Errorf("expected a sheet per processor, got %s", workbook)
+	}
+	sentiment := parts["xl/worksheets/sheet2.xml"]
+	for _, want := range []string{
+		// Columns are added as fields are first seen: keywords, score, sentiment, then details.resolved
+		`<c r="E1" s="1" t="inlineStr"><is><t xml:space="preserve">details.resolved</t></is></c>`,
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">fast; friendly</t></is></c>`,
+		`<c r="C2"><v>0.8</v></c>`,
+		`<c r="D3" t="inlineStr"><is><t xml:space="preserve">negative &lt;angry&gt;</t></is></c>`,
+		`<c r="E3" t="b"><v>0</v></c>`,
+	} {
+		if !strings.Contains(sentiment, want) {
+			t.Errorf("expected the sentiment sheet to contain %s, got %s", want, sentiment)
+		}
+	}
+	if attributes := parts["xl/worksheets/sheet1.xml"]; !strings.Contains(attributes, `[{&#34;field_name&#34;:&#34;amount&#34;}]`) {
+		t.Errorf("expected lists of objects to be kept as JSON, got %s", attributes)
+	}
+
+	if got := xlsxColumn(27); got != "AB" {
+		t.Errorf("expected column 27 to be AB, got %s", got)
+	}
+}
@@ -0,0 +1,88 @@
+package easy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+)
+
+// processorAliases are short names accepted in place of registered processor types
+var processorAliases = map[string]string{
+	"keywords": "keyword_extraction",
+}
+
+// resolveProcessorType returns the registered processor type for a name or alias
+func resolveProcessorType(name string) string {
+	if processorType, ok := processorAliases[name]; ok {
+		return processorType
+	}
+	return name
+}
+
+// Analyzer runs several processors concurrently over the same text with a shared provider
+type Analyzer struct {
+	names    []string
+	types    []string
+	parallel pipeline.Step
+}
+
+// NewAnalyzer creates an analyzer running the given processors, which may use short
+// aliases such as "keywords", with the provided configuration (DefaultConfig if nil)
+func NewAnalyzer(processorTypes []string, config *Config) (*Analyzer, error) {
+	if len(processorTypes) == 0 {
+		return nil, errors.New("no processors given")
+	}
+	if config == nil {
+		config = DefaultConfig
+	}
+
+	analyzer := &Analyzer{names: processorTypes}
+	procs := make([]pipeline.Step, len(processorTypes))
+	for i, name := range processorTypes {
+		processorType := resolveProcessorType(name)
+		_, proc, err := config.sharedProcessor(processorType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		analyzer.types = append(analyzer.types, processorType)
+		procs[i] = proc
+	}
+	analyzer.parallel = pipeline.Parallel(procs...)
+	return analyzer, nil
+}
+
+// Analyze runs every processor over the text and returns their results keyed by the
+// processor names the analyzer was created with
+func (a *Analyzer) Analyze(text string) (map[string]map[string]interface{}, error) {
+	result, err := a.parallel.Process(context.Background(), data.NewTextProcessItem("input", text, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]map[string]interface{}, len(a.names))
+	for i, name := range a.names {
+		if resultMap, ok := result.ProcessingInfo[a.types[i]].(map[string]interface{}); ok {
+			results[name] = CleanLLMResponse(resultMap)
+		}
+	}
+	return results, nil
+}
+
+// Analyze runs several processors concurrently over the same text with the default
+// configuration and returns their results keyed by processor, e.g.
+// easy.Analyze(text, []string{"sentiment", "intent", "keywords"})
+func Analyze(text string, processorTypes []string) (map[string]map[string]interface{}, error) {
+	return AnalyzeWithConfig(text, processorTypes, DefaultConfig)
+}
+
+// AnalyzeWithConfig runs several processors concurrently over the same text with a custom configuration
+func AnalyzeWithConfig(text string, processorTypes []string, config *Config) (map[string]map[string]interface{}, error) {
+	analyzer, err := NewAnalyzer(processorTypes, config)
+	if err != nil {
+		return nil, err
+	}
+	return analyzer.Analyze(text)
+}
@@ -0,0 +1,124 @@
+package easy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// BatchOptions configures a batch run with callbacks for progress and partial results
+type BatchOptions struct {
+	// Concurrency is the number of texts processed at once (defaults to 2)
+	Concurrency int
+	// Config is the configuration to use (defaults to DefaultConfig)
+	Config *Config
+	// OnItemDone, if set, is called as each text completes with its index in the input,
+	// its result and any error. Calls are never concurrent.
+	OnItemDone func(i int, result map[string]interface{}, err error)
+	// OnProgress, if set, is called after each text completes with the number done so far
+	OnProgress func(done, total int)
+	// ContinueOnError keeps processing the remaining texts after a failure. Failed texts
+	// have a nil result and their errors are returned together at the end.
+	ContinueOnError bool
+}
+
+// ProcessBatchTextWith processes a batch of texts with a specified processor type,
+// reporting each result as it completes
+func ProcessBatchTextWith(texts []string, processorType string, options BatchOptions) ([]map[string]interface{}, error) {
+	wrapper, err := NewWithConfig(processorType, options.Config)
+	if err != nil {
+		return nil, err
+	}
+	return wrapper.ProcessBatchWith(texts, options)
+}
+
+// ProcessBatchWith processes multiple inputs in parallel, calling the options' callbacks as
+// each input completes, and returns the results in input order. The options' Config is
+// ignored; the wrapper's own configuration is used.
+func (w *ProcessorWrapper) ProcessBatchWith(inputs []string, options BatchOptions) ([]map[string]interface{}, error) {
+	if w.processor == nil {
+		return nil, errors.New("processor not initialized")
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = 2 // Default concurrency
+	}
+
+	ctx, run := startRun(context.Background(), w.config, len(inputs))
+	defer run.finish()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if w.config.Progress != nil {
+		ctx = data.WithProgress(ctx, w.config.Progress)
+	}
+	tracker := data.NewProgressTracker(ctx, len(inputs))
+
+	var (
+		mu      sync.Mutex
+		done    int
+		errs    []error
+		results = make([]map[string]interface{}, len(inputs))
+	)
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				item := data.NewTextProcessItem(fmt.Sprintf("input-%d", i), inputs[i], nil)
+				processed, err := w.processor.Process(ctx, item)
+				tracker.Record(err)
+
+				var result map[string]interface{}
+				if err == nil {
+					result = w.extractResult(processed)
+				} else {
+					err = fmt.Errorf("input %d: %w", i, err)
+				}
+
+				mu.Lock()
+				results[i] = result
+				done++
+				if err != nil {
+					errs = append(errs, err)
+					if !options.ContinueOnError {
+						cancel()
+					}
+				}
+				if options.OnItemDone != nil {
+					options.OnItemDone(i, result, err)
+				}
+				if options.OnProgress != nil {
+					options.OnProgress(done, len(inputs))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range inputs {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		if !options.ContinueOnError {
+			return nil, errs[0]
+		}
+		return results, errors.Join(errs...)
+	}
+	return results, nil
+}
@@ -0,0 +1,100 @@
+package easy
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// newTestWrapper returns a sentiment wrapper using provider, metered for LastRunStats like
+// the wrappers of NewWithConfig
+func newTestWrapper(t *testing.T, provider llm.Provider, config *Config) *ProcessorWrapper {
+	t.Helper()
+	metered := meter(provider, "sentiment")
+	proc, err := processor.Create("sentiment", metered, config.processorOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ProcessorWrapper{config: config, provider: provider, processor: proc, procType: "sentiment"}
+}
+
+func TestProcessBatchWith(t *testing.T) {
+	// The second input is too long for the provider's context, so it fails permanently
+	inputs := make([]string, 10)
+	for i := range inputs {
+		inputs[i] = "The support team fixed my issue in minutes."
+	}
+	inputs[1] = strings.Repeat("Nobody answered my calls. ", 2000)
+
+	tests := []struct {
+		name            string
+		continueOnError bool
+		checkDone       func(done int) bool
+	}{
+		{
+			// With one worker, at most the input dispatched while the failure was
+			// handled runs after it
+			name:      "stops at the first failure",
+			checkDone: func(done int) bool { return done >= 2 && done <= 3 },
+		},
+		{
+			name:            "continues on error",
+			continueOnError: true,
+			checkDone:       func(done int) bool { return done == len(inputs) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := llm.NewMockProviderWithResponse(sentimentResponse).WithContextLimit(2000)
+			wrapper := newTestWrapper(t, provider, copyConfig(DefaultConfig))
+
+			var (
+				mu       sync.Mutex
+				done     int
+				progress int
+				failed   []int
+			)
+			results, err := wrapper.ProcessBatchWith(inputs, BatchOptions{
+				Concurrency:     1,
+				ContinueOnError: tt.continueOnError,
+				OnItemDone: func(i int, result map[string]interface{}, err error) {
+					mu.Lock()
+					defer mu.Unlock()
+					done++
+					if err != nil {
+						failed = append(failed, i)
+					}
+				},
+				OnProgress: func(n, total int) {
+					progress = n
+				},
+			})
+
+			if err == nil || !strings.Contains(err.Error(), "input 1") {
+				t.Fatalf("expected the error of input 1, got %v", err)
+			}
+			if !tt.checkDone(done) || progress != done {
+				t.Errorf("unexpected number of inputs done: %d (progress %d)", done, progress)
+			}
+			if len(failed) != 1 || failed[0] != 1 {
+				t.Errorf("expected only input 1 to fail, got %v", failed)
+			}
+
+			if !tt.continueOnError {
+				if results != nil {
+					t.Errorf("expected no results, got %v", results)
+				}
+				return
+			}
+			for i, result := range results {
+				if (result == nil) != (i == 1) {
+					t.Errorf("result %d: %v", i, result)
+				}
+			}
+		})
+	}
+}
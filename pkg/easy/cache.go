@@ -0,0 +1,154 @@
+package easy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// clients holds the providers and processors shared by easy calls, so repeated one-line
+// calls reuse the same clients. Entries are keyed by the resolved provider configuration,
+// so configurations with the same settings share clients even if they are different values.
+var clients = struct {
+	sync.Mutex
+	providers  map[string]llm.Provider
+	processors map[string]processor.Processor
+	// validated are the keys of the providers that passed validation
+	validated map[string]bool
+}{
+	providers:  make(map[string]llm.Provider),
+	processors: make(map[string]processor.Processor),
+	validated:  make(map[string]bool),
+}
+
+// interactionLogs holds the interaction logs opened by providers, by path, so providers
+// logging to the same file share one writer. They stay open for the life of the process.
+var interactionLogs = struct {
+	sync.Mutex
+	sinks map[string]*llm.JSONLInteractionSink
+}{sinks: make(map[string]*llm.JSONLInteractionSink)}
+
+// interactionLog returns the sink appending to the interaction log at path
+func interactionLog(path string) (*llm.JSONLInteractionSink, error) {
+	interactionLogs.Lock()
+	defer interactionLogs.Unlock()
+	if sink, ok := interactionLogs.sinks[path]; ok {
+		return sink, nil
+	}
+	sink, err := llm.OpenInteractionLog(path)
+	if err != nil {
+		return nil, err
+	}
+	interactionLogs.sinks[path] = sink
+	return sink, nil
+}
+
+// Init sets the configuration used by the one-line functions and New, creating its
+// provider up front so configuration errors surface immediately. Call it once at startup,
+// before any concurrent use of the package.
+func Init(config *Config) error {
+	if config == nil {
+		return fmt.Errorf("config is required")
+	}
+	ClearCache()
+	if _, _, err := config.sharedProvider(); err != nil {
+		return err
+	}
+	DefaultConfig = config
+	return nil
+}
+
+// ClearCache releases the cached providers and processors, so the next calls create new ones
+func ClearCache() {
+	clients.Lock()
+	defer clients.Unlock()
+	clients.providers = make(map[string]llm.Provider)
+	clients.processors = make(map[string]processor.Processor)
+	clients.validated = make(map[string]bool)
+}
+
+// sharedProvider returns the cached provider for the configuration, creating it on first
+// use, along with its cache key. A new provider is created every time if FreshClients is set.
+// If Validate is set, the provider is validated before it is first returned.
+func (c *Config) sharedProvider() (llm.Provider, string, error) {
+	llmConfig, err := c.providerConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	key := fmt.Sprintf("%s|%v|%s|%s|%d|%s", c.Provider, llmConfig, c.CacheDir, c.InteractionLog, c.MaxRetries, c.Timeout)
+
+	if !c.FreshClients {
+		clients.Lock()
+		defer clients.Unlock()
+		if provider, ok := clients.providers[key]; ok {
+			if err := c.validate(provider, key); err != nil {
+				return nil, "", err
+			}
+			return provider, key, nil
+		}
+	}
+
+	provider, err := llm.NewProvider(c.Provider, llmConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create provider: %w", err)
+	}
+	if provider, err = c.wrapProvider(provider); err != nil {
+		return nil, "", err
+	}
+	if err := c.validate(provider, key); err != nil {
+		return nil, "", err
+	}
+	if !c.FreshClients {
+		clients.providers[key] = provider
+	}
+	return provider, key, nil
+}
+
+// validate verifies the provider's API key and model if Validate is set, once per shared
+// provider. The clients lock must be held unless FreshClients is set.
+func (c *Config) validate(provider llm.Provider, key string) error {
+	if !c.Validate || (!c.FreshClients && clients.validated[key]) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), processor.ValidationTimeout)
+	defer cancel()
+	if err := llm.Validate(ctx, provider); err != nil {
+		return err
+	}
+	if !c.FreshClients {
+		clients.validated[key] = true
+	}
+	return nil
+}
+
+// sharedProcessor returns the cached processor of the given type for the configuration,
+// creating it and its provider on first use
+func (c *Config) sharedProcessor(processorType string) (llm.Provider, processor.Processor, error) {
+	provider, key, err := c.sharedProvider()
+	if err != nil {
+		return nil, nil, err
+	}
+	key += fmt.Sprintf("|%s|%t", processorType, c.RedactDebug)
+
+	if !c.FreshClients {
+		clients.Lock()
+		defer clients.Unlock()
+		if proc, ok := clients.processors[key]; ok {
+			return provider, proc, nil
+		}
+	}
+
+	// The processor's calls are metered for LastRunStats
+	metered := meter(provider, processorType)
+	proc, err := processor.Create(processorType, metered, c.processorOptions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create processor: %w", err)
+	}
+	if !c.FreshClients {
+		clients.processors[key] = proc
+	}
+	return provider, proc, nil
+}
@@ -0,0 +1,84 @@
+package easy
+
+import (
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// mockConfig returns a configuration of the mock provider answering with sentimentResponse
+func mockConfig() *Config {
+	return &Config{Provider: llm.Mock, Options: map[string]interface{}{"response": sentimentResponse}}
+}
+
+func TestSharedClients(t *testing.T) {
+	tests := []struct {
+		name          string
+		modify        func(*Config)
+		sameProvider  bool
+		sameProcessor bool
+	}{
+		{name: "equal settings", modify: func(*Config) {}, sameProvider: true, sameProcessor: true},
+		{name: "token prices", modify: func(c *Config) { c.InputTokenCost = 1 }, sameProvider: true, sameProcessor: true},
+		{name: "redaction", modify: func(c *Config) { c.RedactDebug = true }, sameProvider: true},
+		{name: "model", modify: func(c *Config) { c.Model = "mock-large" }},
+		{name: "provider option", modify: func(c *Config) { c.Options["response"] = "{}" }},
+		{name: "debug", modify: func(c *Config) { c.Debug = true }},
+		{name: "retries", modify: func(c *Config) { c.MaxRetries = 2 }},
+		{name: "fresh clients", modify: func(c *Config) { c.FreshClients = true }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ClearCache()
+			first, err := NewWithConfig("sentiment", mockConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := mockConfig()
+			tt.modify(config)
+			second, err := NewWithConfig("sentiment", config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if same := first.GetProvider() == second.GetProvider(); same != tt.sameProvider {
+				t.Errorf("expected shared provider %v, got %v", tt.sameProvider, same)
+			}
+			if same := first.GetProcessor() == second.GetProcessor(); same != tt.sameProcessor {
+				t.Errorf("expected shared processor %v, got %v", tt.sameProcessor, same)
+			}
+		})
+	}
+
+	t.Run("processor type", func(t *testing.T) {
+		ClearCache()
+		sentiment, err := NewWithConfig("sentiment", mockConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		intent, err := NewWithConfig("intent", mockConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sentiment.GetProvider() != intent.GetProvider() || sentiment.GetProcessor() == intent.GetProcessor() {
+			t.Error("expected processors of different types to share only the provider")
+		}
+	})
+
+	t.Run("ClearCache", func(t *testing.T) {
+		ClearCache()
+		first, err := NewWithConfig("sentiment", mockConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ClearCache()
+		second, err := NewWithConfig("sentiment", mockConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.GetProcessor() == second.GetProcessor() {
+			t.Error("expected a new processor after ClearCache")
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package easy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+	"github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+// chainTransforms reshape an item between two processors that are commonly chained, keyed
+// by "previous>next", so the later processor receives the input it expects
+var chainTransforms = map[string]pipeline.TransformFunc{
+	// get_attributes extracts the attributes required_attributes defined from the original text
+	"required_attributes>get_attributes": pipeline.ResultAsInput(builtin.AttributesInput, "required_attributes"),
+}
+
+// Chain runs text through several processors in sequence with the default configuration
+// and returns each processor's result keyed by processor type, e.g.
+// easy.Chain(questions, "required_attributes", "get_attributes")
+func Chain(text string, processorTypes ...string) (map[string]interface{}, error) {
+	return ChainWithConfig(text, DefaultConfig, processorTypes...)
+}
+
+// ChainWithConfig runs text through several processors in sequence with a custom configuration
+func ChainWithConfig(text string, config *Config, processorTypes ...string) (map[string]interface{}, error) {
+	chain, err := newChain(config, processorTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := chain.Process(context.Background(), data.NewTextProcessItem("input", text, nil))
+	if err != nil {
+		return nil, err
+	}
+	return chainResults(result, processorTypes), nil
+}
+
+// ChainBatch runs each text through several processors in sequence, processing up to
+// concurrency texts at once, and returns the results in input order
+func ChainBatch(texts []string, concurrency int, processorTypes ...string) ([]map[string]interface{}, error) {
+	return ChainBatchWithConfig(texts, concurrency, DefaultConfig, processorTypes...)
+}
+
+// ChainBatchWithConfig runs a batch of texts through several processors with a custom configuration
+func ChainBatchWithConfig(texts []string, concurrency int, config *Config, processorTypes ...string) ([]map[string]interface{}, error) {
+	if concurrency <= 0 {
+		concurrency = 2
+	}
+	if config == nil {
+		config = DefaultConfig
+	}
+
+	chain, err := newChain(config, processorTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*data.ProcessItem, len(texts))
+	for i, text := range texts {
+		items[i] = data.NewTextProcessItem(fmt.Sprintf("input-%d", i), text, nil)
+	}
+
+	ctx, run := startRun(context.Background(), config, len(texts))
+	if config.Progress != nil {
+		ctx = data.WithProgress(ctx, config.Progress)
+	}
+	results, err := chain.ProcessSource(ctx, data.NewProcessItemSliceSource(items), len(texts)/concurrency+1, concurrency)
+	run.finish()
+	if err != nil {
+		return nil, err
+	}
+
+	outputResults := make([]map[string]interface{}, len(results))
+	for i, result := range results {
+		outputResults[i] = chainResults(result, processorTypes)
+	}
+	return outputResults, nil
+}
+
+// newChain builds a pipeline chain of the given processor types sharing one provider
+func newChain(config *Config, processorTypes []string) (*pipeline.Chain, error) {
+	if len(processorTypes) == 0 {
+		return nil, errors.New("no processors given")
+	}
+	if config == nil {
+		config = DefaultConfig
+	}
+
+	provider, _, err := config.sharedProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	builder := pipeline.NewChainBuilder(strings.Join(processorTypes, ">"), provider).
+		WithOptions(config.processorOptions())
+	for i, processorType := range processorTypes {
+		if i > 0 {
+			if transform, ok := chainTransforms[processorTypes[i-1]+">"+processorType]; ok {
+				builder.AddStep(pipeline.Transform(processorTypes[i-1]+"_to_"+processorType, transform))
+			}
+		}
+		// The processor's calls are metered for LastRunStats
+		builder.StepWith(processorType, pipeline.StepOptions{
+			Provider: meter(provider, processorType),
+		})
+	}
+
+	chain, err := builder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chain: %w", err)
+	}
+	return chain, nil
+}
+
+// chainResults collects each processor's cleaned result from a processed item
+func chainResults(item *data.ProcessItem, processorTypes []string) map[string]interface{} {
+	results := make(map[string]interface{}, len(processorTypes))
+	for _, processorType := range processorTypes {
+		info, ok := item.ProcessingInfo[processorType]
+		if !ok {
+			continue
+		}
+		if resultMap, ok := info.(map[string]interface{}); ok {
+			results[processorType] = CleanLLMResponse(resultMap)
+		} else {
+			results[processorType] = info
+		}
+	}
+	return results
+}
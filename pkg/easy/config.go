@@ -0,0 +1,317 @@
+package easy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// EnvPrefix is the prefix of the environment variables read by ConfigFromEnv
+const EnvPrefix = "AGENTIC_TEXT_"
+
+// configKeys are the keys accepted in config files; ConfigFromEnv reads each one from
+// the upper-cased variable with EnvPrefix, e.g. AGENTIC_TEXT_MAX_TOKENS
+var configKeys = []string{
+	"provider",
+	"model",
+	"api_key",
+	"api_key_env_var",
+	"max_tokens",
+	"temperature",
+	"debug",
+	"redact_debug",
+	"validate",
+	"cache_dir",
+	"interaction_log",
+	"max_retries",
+	"timeout",
+	"input_token_cost",
+	"output_token_cost",
+}
+
+// ConfigFromEnv returns the default configuration overridden by any AGENTIC_TEXT_*
+// environment variables that are set: AGENTIC_TEXT_PROVIDER, AGENTIC_TEXT_MODEL,
+// AGENTIC_TEXT_API_KEY, AGENTIC_TEXT_API_KEY_ENV_VAR, AGENTIC_TEXT_MAX_TOKENS,
+// AGENTIC_TEXT_TEMPERATURE, AGENTIC_TEXT_DEBUG, AGENTIC_TEXT_REDACT_DEBUG, AGENTIC_TEXT_VALIDATE, AGENTIC_TEXT_CACHE_DIR,
+// AGENTIC_TEXT_INTERACTION_LOG, AGENTIC_TEXT_MAX_RETRIES, AGENTIC_TEXT_TIMEOUT, AGENTIC_TEXT_INPUT_TOKEN_COST and
+// AGENTIC_TEXT_OUTPUT_TOKEN_COST
+func ConfigFromEnv() (*Config, error) {
+	values := make(map[string]interface{})
+	for _, key := range configKeys {
+		if value := os.Getenv(EnvPrefix + strings.ToUpper(key)); value != "" {
+			values[key] = value
+		}
+	}
+
+	config := copyConfig(DefaultConfig)
+	if err := config.apply(values); err != nil {
+		return nil, fmt.Errorf("invalid environment configuration: %w", err)
+	}
+	return config, nil
+}
+
+// ConfigFromFile returns the default configuration overridden by the values in a JSON
+// (.json), YAML (.yaml, .yml) or TOML (.toml) file. The file uses the keys provider,
+// model, api_key, api_key_env_var, max_tokens, temperature, debug, redact_debug, validate, cache_dir,
+// interaction_log, max_retries,
+// timeout (a duration such as "30s", or a number of seconds), input_token_cost and
+// output_token_cost, plus an options table of provider-specific options.
+func ConfigFromFile(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(raw, &values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &values)
+	case ".toml":
+		_, err = toml.Decode(string(raw), &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	config := copyConfig(DefaultConfig)
+	if err := config.apply(values); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// NewProvider creates the LLM provider described by the configuration
+func (c *Config) NewProvider() (llm.Provider, error) {
+	llmConfig, err := c.providerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := llm.NewProvider(c.Provider, llmConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	return c.wrapProvider(provider)
+}
+
+// wrapProvider adds the configured interaction log, timeout, retries and response cache to
+// a provider. The log is innermost, so it records every attempt that reaches the provider
+// and none of the responses answered from the cache.
+func (c *Config) wrapProvider(provider llm.Provider) (llm.Provider, error) {
+	if c.InteractionLog != "" {
+		sink, err := interactionLog(c.InteractionLog)
+		if err != nil {
+			return nil, err
+		}
+		provider = llm.WithInteractionLog(provider, sink, llm.InteractionLogConfig{
+			InputTokenCost:  c.InputTokenCost,
+			OutputTokenCost: c.OutputTokenCost,
+		})
+	}
+	provider = llm.WithTimeout(provider, c.Timeout)
+	provider = llm.WithRetry(provider, llm.RetryConfig{MaxRetries: c.MaxRetries})
+	if c.CacheDir != "" {
+		cache, err := llm.NewDirectoryCache(c.CacheDir)
+		if err != nil {
+			return nil, err
+		}
+		provider = llm.WithCache(provider, cache)
+	}
+	return provider, nil
+}
+
+// providerConfig returns the provider configuration, with the API key resolved
+func (c *Config) providerConfig() (llm.Config, error) {
+	// Get API key from environment variable if not specified directly; the mock and
+	// replay providers don't need one
+	apiKey := c.APIKey
+	if apiKey == "" && llm.NeedsAPIKey(c.Provider) {
+		envVar := c.APIKeyEnvVar
+		if envVar == "" {
+			// Default environment variable names based on provider
+			envVar = llm.DefaultAPIKeyEnvVar(c.Provider)
+			if envVar == "" {
+				return llm.Config{}, fmt.Errorf("unknown provider type: %s", c.Provider)
+			}
+		}
+
+		apiKey = os.Getenv(envVar)
+		if apiKey == "" {
+			return llm.Config{}, fmt.Errorf("API key not found in environment variable: %s", envVar)
+		}
+	}
+
+	return llm.Config{
+		APIKey:      apiKey,
+		Model:       c.Model,
+		MaxTokens:   c.MaxTokens,
+		Temperature: c.Temperature,
+		Options:     c.llmOptions(),
+	}, nil
+}
+
+// llmOptions returns the provider options, including debug mode if enabled
+func (c *Config) llmOptions() map[string]interface{} {
+	options := make(map[string]interface{}, len(c.Options)+1)
+	for k, v := range c.Options {
+		options[k] = v
+	}
+	if c.Debug {
+		options["debug"] = true
+	}
+	return options
+}
+
+// processorOptions returns the processor options, including debug redaction if enabled
+func (c *Config) processorOptions() processor.Options {
+	options := processor.Options{LLMOptions: c.llmOptions()}
+	if c.RedactDebug {
+		redaction := processor.DefaultRedaction()
+		options.Redaction = &redaction
+	}
+	return options
+}
+
+// copyConfig returns a copy of config that doesn't share its options map
+func copyConfig(config *Config) *Config {
+	copied := *config
+	if config.Options != nil {
+		copied.Options = make(map[string]interface{}, len(config.Options))
+		for k, v := range config.Options {
+			copied.Options[k] = v
+		}
+	}
+	return &copied
+}
+
+// apply sets the configuration fields named by the keys in values. Values may be strings,
+// as read from the environment, or the typed values decoded from a file.
+func (c *Config) apply(values map[string]interface{}) error {
+	for key, value := range values {
+		var err error
+		switch key {
+		case "provider":
+			var provider string
+			if provider, err = stringValue(value); err == nil {
+				c.Provider = llm.ProviderType(strings.ToLower(provider))
+			}
+		case "model":
+			c.Model, err = stringValue(value)
+		case "api_key":
+			c.APIKey, err = stringValue(value)
+		case "api_key_env_var":
+			c.APIKeyEnvVar, err = stringValue(value)
+		case "max_tokens":
+			var maxTokens float64
+			if maxTokens, err = numberValue(value); err == nil {
+				c.MaxTokens = int(maxTokens)
+			}
+		case "temperature":
+			c.Temperature, err = numberValue(value)
+		case "debug":
+			c.Debug, err = boolValue(value)
+		case "redact_debug":
+			c.RedactDebug, err = boolValue(value)
+		case "validate":
+			c.Validate, err = boolValue(value)
+		case "cache_dir":
+			c.CacheDir, err = stringValue(value)
+		case "interaction_log":
+			c.InteractionLog, err = stringValue(value)
+		case "max_retries":
+			var maxRetries float64
+			if maxRetries, err = numberValue(value); err == nil {
+				c.MaxRetries = int(maxRetries)
+			}
+		case "timeout":
+			c.Timeout, err = durationValue(value)
+		case "input_token_cost":
+			c.InputTokenCost, err = numberValue(value)
+		case "output_token_cost":
+			c.OutputTokenCost, err = numberValue(value)
+		case "options":
+			options, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("options must be a table of key/value pairs")
+			}
+			if c.Options == nil {
+				c.Options = make(map[string]interface{}, len(options))
+			}
+			for k, v := range options {
+				c.Options[k] = v
+			}
+		default:
+			return fmt.Errorf("unknown configuration key: %s", key)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// stringValue converts a configuration value to a string
+func stringValue(value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string, got %v", value)
+	}
+	return s, nil
+}
+
+// numberValue converts a configuration value to a number
+func numberValue(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	default:
+		return 0, fmt.Errorf("expected a number, got %v", value)
+	}
+}
+
+// durationValue converts a configuration value, either a duration string or a number of
+// seconds, to a duration
+func durationValue(value interface{}) (time.Duration, error) {
+	if s, ok := value.(string); ok {
+		if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+			return d, nil
+		}
+	}
+	seconds, err := numberValue(value)
+	if err != nil {
+		return 0, fmt.Errorf("expected a duration such as 30s, got %v", value)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// boolValue converts a configuration value to a boolean
+func boolValue(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	default:
+		return false, fmt.Errorf("expected true or false, got %v", value)
+	}
+}
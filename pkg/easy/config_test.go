@@ -0,0 +1,187 @@
+package easy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// sentimentResponse is a valid response of the builtin sentiment processor
+const sentimentResponse = `{"sentiment": "positive", "score": 0.8, "confidence": 0.9, "keywords": ["great"]}`
+
+// writeFile writes content to name in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFromFile(t *testing.T) {
+	want := copyConfig(DefaultConfig)
+	want.Provider = llm.Mock
+	want.Model = "mock-large"
+	want.MaxTokens = 512
+	want.Temperature = 0.2
+	want.RedactDebug = true
+	want.MaxRetries = 3
+	want.Timeout = 30 * time.Second
+	want.InputTokenCost = 0.1
+	want.Options = map[string]interface{}{"response": sentimentResponse}
+
+	files := map[string]string{
+		"config.json": `{
+			"provider": "Mock", "model": "mock-large", "max_tokens": 512, "temperature": 0.2,
+			"redact_debug": true, "max_retries": 3, "timeout": "30s", "input_token_cost": 0.1,
+			"options": {"response": ` + quoteJSON(sentimentResponse) + `}
+		}`,
+		"config.yaml": `
+provider: mock
+model: mock-large
+max_tokens: 512
+temperature: 0.2
+redact_debug: true
+max_retries: 3
+timeout: 30
+input_token_cost: 0.1
+options:
+  response: '` + sentimentResponse + `'
+`,
+		"config.toml": `
+# Offline configuration
+provider = "mock"
+model = "mock-large"
+max_tokens = 512
+temperature = 0.2
+redact_debug = true
+max_retries = 3
+timeout = "30s"
+input_token_cost = 0.1
+
+[options]
+response = '` + sentimentResponse + `'
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			config, err := ConfigFromFile(writeFile(t, name, content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(config, want) {
+				t.Errorf("config = %+v, want %+v", config, want)
+			}
+
+			result, err := ProcessTextWithConfig("The support team fixed my issue in minutes.", "sentiment", config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result["sentiment"] != "positive" {
+				t.Errorf("expected the mock provider's sentiment, got %v", result)
+			}
+		})
+	}
+}
+
+func TestConfigFromFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{name: "unsupported format", file: "config.ini", content: "provider=mock", wantErr: "unsupported config file format"},
+		{name: "invalid toml", file: "config.toml", content: "provider = ", wantErr: "failed to parse config file"},
+		{name: "unknown key", file: "config.toml", content: `model_name = "x"`, wantErr: "unknown configuration key: model_name"},
+		{name: "wrong type", file: "config.toml", content: `max_tokens = "many"`, wantErr: "max_tokens"},
+		{name: "options not a table", file: "config.json", content: `{"options": 3}`, wantErr: "options must be a table"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ConfigFromFile(writeFile(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(*Config) bool
+		wantErr string
+	}{
+		{
+			name:  "defaults",
+			env:   map[string]string{},
+			check: func(c *Config) bool { return reflect.DeepEqual(c, copyConfig(DefaultConfig)) },
+		},
+		{
+			name: "overrides",
+			env: map[string]string{
+				"AGENTIC_TEXT_PROVIDER":    "MOCK",
+				"AGENTIC_TEXT_MAX_TOKENS":  "256",
+				"AGENTIC_TEXT_TEMPERATURE": "0.5",
+				"AGENTIC_TEXT_DEBUG":       "true",
+				"AGENTIC_TEXT_TIMEOUT":     "1m",
+			},
+			check: func(c *Config) bool {
+				return c.Provider == llm.Mock && c.MaxTokens == 256 && c.Temperature == 0.5 &&
+					c.Debug && c.Timeout == time.Minute
+			},
+		},
+		{
+			name:    "invalid number",
+			env:     map[string]string{"AGENTIC_TEXT_TEMPERATURE": "warm"},
+			wantErr: "temperature",
+		},
+		{
+			name:    "invalid boolean",
+			env:     map[string]string{"AGENTIC_TEXT_VALIDATE": "sometimes"},
+			wantErr: "validate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range configKeys {
+				t.Setenv(EnvPrefix+strings.ToUpper(key), "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			config, err := ConfigFromEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(config) {
+				t.Errorf("unexpected config %+v", config)
+			}
+		})
+	}
+}
+
+// quoteJSON returns s as a JSON string literal
+func quoteJSON(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
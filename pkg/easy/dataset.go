@@ -0,0 +1,126 @@
+package easy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// DatasetBatchSize is the number of items read from a dataset per batch
+const DatasetBatchSize = 10
+
+// ProcessFile processes the whole content of a text file with a specified processor type
+func ProcessFile(path, processorType string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return ProcessText(string(content), processorType)
+}
+
+// ProcessCSV processes the text in textColumn of every row of a CSV file, whose first row
+// holds the column names. Results are returned in row order and, if outputPath is not
+// empty, written with each row's other columns to a JSON Lines file (or a JSON array if
+// outputPath ends in .json). Rows that fail are left out of the results and the output;
+// their errors, naming each row's item ID, are returned together once the other rows are
+// written.
+func ProcessCSV(path, textColumn, processorType, outputPath string) ([]map[string]interface{}, error) {
+	source, err := data.NewCSVSource(data.CSVSourceConfig{Path: path, ContentColumn: textColumn})
+	if err != nil {
+		return nil, err
+	}
+	return processDataset(source, processorType, outputPath)
+}
+
+// ProcessJSONL processes the text in textField of every object in a JSON Lines file.
+// Results are returned in line order and, if outputPath is not empty, written with each
+// object's other fields to a JSON Lines file (or a JSON array if outputPath ends in .json).
+// Lines that fail are handled like failed rows of ProcessCSV.
+func ProcessJSONL(path, textField, processorType, outputPath string) ([]map[string]interface{}, error) {
+	source, err := data.NewJSONLSource(data.JSONLSourceConfig{Path: path, ContentField: textField})
+	if err != nil {
+		return nil, err
+	}
+	return processDataset(source, processorType, outputPath)
+}
+
+// processDataset runs a processor over every item of a source with the default
+// configuration and writes the processed items to outputPath. Items that fail don't stop
+// the others and their errors are joined. An error reading the source stops the run; the
+// items processed until then are still written.
+func processDataset(source data.ProcessItemSource, processorType, outputPath string) ([]map[string]interface{}, error) {
+	defer source.Close()
+
+	wrapper, err := New(processorType)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, run := startRun(context.Background(), wrapper.config, 0)
+	if wrapper.config.Progress != nil {
+		ctx = data.WithProgress(ctx, wrapper.config.Progress)
+	}
+	parallel := data.NewProcessItemParallelProcessorWithConfig(source, data.ParallelConfig{
+		BatchSize:  DatasetBatchSize,
+		MaxWorkers: 2,
+		Order:      data.OrderSource,
+	})
+	process := func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		result, err := wrapper.processor.Process(ctx, item)
+		if err != nil {
+			return nil, fmt.Errorf("item '%s': %w", item.ID, err)
+		}
+		return result, nil
+	}
+
+	var (
+		processed     []*data.ProcessItem
+		outputResults []map[string]interface{}
+		errs          []error
+	)
+	for res := range parallel.ProcessStream(ctx, process) {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+			continue
+		}
+		processed = append(processed, res.Item)
+		outputResults = append(outputResults, wrapper.extractResult(res.Item))
+	}
+	run.items = len(processed) + len(errs)
+	run.finish()
+
+	if outputPath != "" {
+		if err := writeDataset(ctx, outputPath, processed); err != nil {
+			return nil, err
+		}
+	}
+	return outputResults, errors.Join(errs...)
+}
+
+// writeDataset writes processed items to a JSON Lines file, or a JSON array for .json paths
+func writeDataset(ctx context.Context, path string, items []*data.ProcessItem) error {
+	var sink data.ProcessItemSink
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		sink, err = data.NewJSONFileSink(path)
+	} else {
+		sink, err = data.NewJSONLFileSink(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := sink.Write(ctx, items); err != nil {
+		sink.Close()
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	if err := sink.Close(); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
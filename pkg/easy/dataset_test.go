@@ -0,0 +1,65 @@
+package easy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// withDefaultConfig makes config the default configuration for the rest of the test
+func withDefaultConfig(t *testing.T, config *Config) {
+	t.Helper()
+	previous := DefaultConfig
+	DefaultConfig = config
+	t.Cleanup(func() { DefaultConfig = previous })
+}
+
+func TestProcessCSVFailedRows(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "llm.jsonl")
+
+	// Record the responses to the first two rows, then replay a file with a third row the
+	// log doesn't hold
+	recorded := writeFile(t, "recorded.csv", "id,text\n1,Great support!\n2,Slow shipping.\n")
+	withDefaultConfig(t, &Config{
+		Provider:       llm.Mock,
+		Options:        map[string]interface{}{"response": sentimentResponse},
+		InteractionLog: logPath,
+	})
+	if _, err := ProcessCSV(recorded, "text", "sentiment", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	input := writeFile(t, "tickets.csv", "id,text\n1,Great support!\n3,Never again.\n2,Slow shipping.\n")
+	output := filepath.Join(dir, "tickets_sentiment.jsonl")
+	withDefaultConfig(t, &Config{Provider: llm.Replay, Options: map[string]interface{}{"log": logPath}})
+
+	results, err := ProcessCSV(input, "text", "sentiment", output)
+	if !errors.Is(err, llm.ErrNotRecorded) || !strings.Contains(err.Error(), "item '3'") {
+		t.Fatalf("expected the error of row 3, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected the results of the other rows, got %v", results)
+	}
+	for _, result := range results {
+		if result["sentiment"] != "positive" {
+			t.Errorf("unexpected result %v", result)
+		}
+	}
+
+	written, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(written)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"id":"1"`) || !strings.Contains(lines[1], `"id":"2"`) {
+		t.Errorf("expected rows 1 and 2 in the output, got:\n%s", written)
+	}
+	if stats := LastRunStats(); stats.Items != 3 {
+		t.Errorf("expected the run to count 3 items, got %d", stats.Items)
+	}
+}
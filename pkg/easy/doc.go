@@ -9,19 +9,40 @@ Core components:
 1. Configuration (easy.go):
   - Config: Simplified configuration structure
   - DefaultConfig: Sensible default configuration values
+  - ConfigFromEnv / ConfigFromFile (config.go): Load configuration from the environment or a JSON, YAML or TOML file
+  - NewProvider (config.go): Create the LLM provider a configuration describes, with its cache, retries and timeout
+  - Init / ClearCache (cache.go): Set the default configuration and manage the clients shared across calls
 
 2. ProcessorWrapper (easy.go):
   - ProcessorWrapper: Handles the creation and management of processors
   - Process: For processing single text items
+  - ProcessStream: For processing a text item with streamed model output
   - ProcessBatch: For processing multiple text items in parallel
+  - ProcessBatchWith (batch.go): Batch processing with per-item and progress callbacks
+  - LastRunStats (stats.go): Duration, estimated tokens and cost of the last batch run
 
 3. Convenience Functions (utils.go):
   - Sentiment: One-liner for sentiment analysis
+  - AspectSentiment: One-liner for sentiment per aspect, such as price or support quality
   - Intent: One-liner for intent detection
   - ProcessText: Generic text processing
+  - ProcessTextStream: Text processing with the raw model output streamed as it is generated
   - ProcessBatchText: Batch processing of multiple texts
+  - ProcessBatchTextWith (batch.go): Batch processing with BatchOptions callbacks
   - PrettyPrint: For formatting results as JSON
 
+4. Chains (chain.go):
+  - Chain / ChainWithConfig: Run a text through several processors in sequence
+  - ChainBatch / ChainBatchWithConfig: Run a batch of texts through several processors
+
+5. Analysis (analyze.go):
+  - Analyze / AnalyzeWithConfig: Run several processors concurrently over the same text
+  - Analyzer: Reusable set of processors sharing one provider
+
+6. Files (dataset.go):
+  - ProcessFile: Process the whole content of a text file
+  - ProcessCSV / ProcessJSONL: Process every row of an export and write the results to a file
+
 This package abstracts away the creation of providers, processors, and data structures,
 making it ideal for simple applications or quick prototyping.
 */
@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"time"
 
 	"github.com/eisenzopf/agentic-text/pkg/data"
 	"github.com/eisenzopf/agentic-text/pkg/llm"
@@ -38,8 +38,32 @@ type Config struct {
 	APIKeyEnvVar string
 	// Debug enables debug mode with additional information
 	Debug bool
+	// RedactDebug redacts API keys, email addresses and account numbers from the prompts and
+	// raw responses debug mode adds to results, so they can be persisted and shared
+	RedactDebug bool
+	// Validate verifies the API key and model when a provider is first created, by New,
+	// Init or the one-line functions, so misconfiguration fails before any text is processed
+	Validate bool
 	// Additional provider-specific options
 	Options map[string]interface{}
+	// CacheDir, if set, stores responses in this directory so repeated prompts aren't sent again
+	CacheDir string
+	// InteractionLog, if set, appends every LLM call to this JSON Lines file, e.g. to
+	// debug prompts offline or replay the run with the replay provider
+	InteractionLog string
+	// MaxRetries is the number of times a failed LLM call is retried with backoff
+	MaxRetries int
+	// Timeout limits each LLM call attempt (no limit if zero)
+	Timeout time.Duration
+	// InputTokenCost and OutputTokenCost are the prices per million prompt and response
+	// tokens, used to estimate the cost of batch runs in LastRunStats
+	InputTokenCost  float64
+	OutputTokenCost float64
+	// Progress, if set, is called as each item in a batch completes
+	Progress data.ProgressFunc
+	// FreshClients creates a new provider and processor for every wrapper instead of
+	// reusing the ones cached for the same settings
+	FreshClients bool
 }
 
 // ProcessorWrapper provides a simple interface to use processors
@@ -62,68 +86,10 @@ func NewWithConfig(processorType string, config *Config) (*ProcessorWrapper, err
 		config = DefaultConfig
 	}
 
-	// Get API key from environment variable if not specified directly
-	apiKey := config.APIKey
-	if apiKey == "" {
-		envVar := config.APIKeyEnvVar
-		if envVar == "" {
-			// Default environment variable names based on provider
-			switch config.Provider {
-			case llm.Google:
-				envVar = "GEMINI_API_KEY"
-			case llm.OpenAI:
-				envVar = "OPENAI_API_KEY"
-			case llm.Groq:
-				envVar = "GROQ_API_KEY"
-			case llm.Amazon:
-				envVar = "AMAZON_API_KEY"
-			default:
-				return nil, fmt.Errorf("unknown provider type: %s", config.Provider)
-			}
-		}
-
-		apiKey = os.Getenv(envVar)
-		if apiKey == "" {
-			return nil, fmt.Errorf("API key not found in environment variable: %s", envVar)
-		}
-	}
-
-	// Prepare LLM configuration
-	llmConfig := llm.Config{
-		APIKey:      apiKey,
-		Model:       config.Model,
-		MaxTokens:   config.MaxTokens,
-		Temperature: config.Temperature,
-		Options:     map[string]interface{}{},
-	}
-
-	// Copy any additional options
-	if config.Options != nil {
-		for k, v := range config.Options {
-			llmConfig.Options[k] = v
-		}
-	}
-
-	// Set debug mode if enabled
-	if config.Debug {
-		llmConfig.Options["debug"] = true
-	}
-
-	// Create the provider
-	provider, err := llm.NewProvider(config.Provider, llmConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create provider: %w", err)
-	}
-
-	// Create processor options
-	procOptions := processor.Options{
-		LLMOptions: llmConfig.Options,
-	}
-
-	// Create the processor
-	proc, err := processor.Create(processorType, provider, procOptions)
+	// Reuse the provider and processor of earlier wrappers with the same settings
+	provider, proc, err := config.sharedProcessor(processorType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create processor: %w", err)
+		return nil, err
 	}
 
 	return &ProcessorWrapper{
@@ -131,7 +97,7 @@ func NewWithConfig(processorType string, config *Config) (*ProcessorWrapper, err
 		provider:   provider,
 		processor:  proc,
 		procType:   processorType,
-		procConfig: procOptions,
+		procConfig: config.processorOptions(),
 	}, nil
 }
 
@@ -149,11 +115,33 @@ func (w *ProcessorWrapper) Process(input string) (map[string]interface{}, error)
 		return nil, err
 	}
 
+	return w.extractResult(result), nil
+}
+
+// ProcessStream processes a string input like Process, calling onToken with each chunk of
+// the raw model output as it is generated. Providers that can't stream deliver the whole
+// output as one chunk.
+func (w *ProcessorWrapper) ProcessStream(input string, onToken func(string)) (map[string]interface{}, error) {
+	if w.processor == nil {
+		return nil, errors.New("processor not initialized")
+	}
+
+	ctx := llm.WithStream(context.Background(), onToken)
+	result, err := w.processor.Process(ctx, data.NewTextProcessItem("input", input, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return w.extractResult(result), nil
+}
+
+// extractResult returns the processor's result for a processed item
+func (w *ProcessorWrapper) extractResult(result *data.ProcessItem) map[string]interface{} {
 	// Extract processor results based on processor type
 	if procInfo, ok := result.ProcessingInfo[w.procType]; ok {
 		if resultMap, ok := procInfo.(map[string]interface{}); ok {
 			// Clean the response in case it contains JSON in a response field
-			return CleanLLMResponse(resultMap), nil
+			return CleanLLMResponse(resultMap)
 		}
 	}
 
@@ -161,13 +149,13 @@ func (w *ProcessorWrapper) Process(input string) (map[string]interface{}, error)
 	if result.ContentType == "json" {
 		if contentMap, ok := result.Content.(map[string]interface{}); ok {
 			// Clean the response in case it contains JSON in a response field
-			return CleanLLMResponse(contentMap), nil
+			return CleanLLMResponse(contentMap)
 		}
 	}
 
 	return map[string]interface{}{
 		"result": result.Content,
-	}, nil
+	}
 }
 
 // ProcessBatch processes multiple inputs in parallel and returns results
@@ -190,8 +178,12 @@ func (w *ProcessorWrapper) ProcessBatch(inputs []string, concurrency int) ([]map
 	source := data.NewProcessItemSliceSource(items)
 
 	// Process in parallel
-	ctx := context.Background()
+	ctx, run := startRun(context.Background(), w.config, len(inputs))
+	if w.config.Progress != nil {
+		ctx = data.WithProgress(ctx, w.config.Progress)
+	}
 	results, err := w.processor.ProcessSource(ctx, source, len(inputs)/concurrency+1, concurrency)
+	run.finish()
 	if err != nil {
 		return nil, err
 	}
@@ -199,27 +191,7 @@ func (w *ProcessorWrapper) ProcessBatch(inputs []string, concurrency int) ([]map
 	// Extract results
 	outputResults := make([]map[string]interface{}, len(results))
 	for i, result := range results {
-		// Extract processor results based on processor type
-		if procInfo, ok := result.ProcessingInfo[w.procType]; ok {
-			if resultMap, ok := procInfo.(map[string]interface{}); ok {
-				// Clean the response in case it contains JSON in a response field
-				outputResults[i] = CleanLLMResponse(resultMap)
-				continue
-			}
-		}
-
-		// Return content as is if no specific processing info is available
-		if result.ContentType == "json" {
-			if contentMap, ok := result.Content.(map[string]interface{}); ok {
-				// Clean the response in case it contains JSON in a response field
-				outputResults[i] = CleanLLMResponse(contentMap)
-				continue
-			}
-		}
-
-		outputResults[i] = map[string]interface{}{
-			"result": result.Content,
-		}
+		outputResults[i] = w.extractResult(result)
 	}
 
 	return outputResults, nil
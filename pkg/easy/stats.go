@@ -0,0 +1,158 @@
+package easy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// ProcessorStats holds the estimated LLM usage of one processor in a run
+type ProcessorStats struct {
+	// Calls is the number of LLM calls the processor made
+	Calls int
+	// InputTokens is the estimated number of prompt tokens sent
+	InputTokens int64
+	// OutputTokens is the estimated number of response tokens received
+	OutputTokens int64
+	// Cost is the estimated cost, using the configuration's token prices
+	Cost float64
+}
+
+// Tokens returns the estimated total number of tokens
+func (s ProcessorStats) Tokens() int64 {
+	return s.InputTokens + s.OutputTokens
+}
+
+// RunStats summarizes a batch run, to estimate what processing at scale would cost.
+// Token counts are estimated from the length of prompts and responses (about four
+// characters per token). Responses answered from the cache are counted too, so the
+// estimate reflects processing without a cache.
+type RunStats struct {
+	// Items is the number of texts in the run
+	Items int
+	// Duration is the wall-clock time of the run
+	Duration time.Duration
+	// Processors holds the usage of each processor, keyed by processor type
+	Processors map[string]ProcessorStats
+}
+
+// Calls returns the number of LLM calls across all processors
+func (s RunStats) Calls() int {
+	total := 0
+	for _, stats := range s.Processors {
+		total += stats.Calls
+	}
+	return total
+}
+
+// Tokens returns the estimated number of tokens across all processors
+func (s RunStats) Tokens() int64 {
+	var total int64
+	for _, stats := range s.Processors {
+		total += stats.Tokens()
+	}
+	return total
+}
+
+// Cost returns the estimated cost across all processors
+func (s RunStats) Cost() float64 {
+	var total float64
+	for _, stats := range s.Processors {
+		total += stats.Cost
+	}
+	return total
+}
+
+// CostPerItem returns the estimated average cost of processing one text
+func (s RunStats) CostPerItem() float64 {
+	if s.Items == 0 {
+		return 0
+	}
+	return s.Cost() / float64(s.Items)
+}
+
+// lastRun holds the statistics of the most recently finished batch run
+var lastRun struct {
+	sync.Mutex
+	stats RunStats
+}
+
+// LastRunStats returns the statistics of the most recently finished batch run, such as
+// ProcessBatchText, ChainBatch or ProcessCSV. With concurrent runs, the last to finish wins.
+func LastRunStats() RunStats {
+	lastRun.Lock()
+	defer lastRun.Unlock()
+
+	stats := lastRun.stats
+	stats.Processors = make(map[string]ProcessorStats, len(lastRun.stats.Processors))
+	for name, processorStats := range lastRun.stats.Processors {
+		stats.Processors[name] = processorStats
+	}
+	return stats
+}
+
+// runKey is the context key of the run recording LLM usage
+type runKey struct{}
+
+// run records the LLM usage of a batch run
+type run struct {
+	mu         sync.Mutex
+	config     *Config
+	items      int
+	start      time.Time
+	processors map[string]ProcessorStats
+}
+
+// startRun returns a context recording the LLM usage of a batch run of items texts
+func startRun(ctx context.Context, config *Config, items int) (context.Context, *run) {
+	r := &run{
+		config:     config,
+		items:      items,
+		start:      time.Now(),
+		processors: make(map[string]ProcessorStats),
+	}
+	return context.WithValue(ctx, runKey{}, r), r
+}
+
+// record adds an LLM call by a processor to the run
+func (r *run) record(processorType, prompt, response string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.processors[processorType]
+	stats.Calls++
+	stats.InputTokens += llm.EstimateTokens(prompt)
+	stats.OutputTokens += llm.EstimateTokens(response)
+	r.processors[processorType] = stats
+}
+
+// finish prices the run's usage and makes it the last run
+func (r *run) finish() {
+	r.mu.Lock()
+	stats := RunStats{
+		Items:      r.items,
+		Duration:   time.Since(r.start),
+		Processors: make(map[string]ProcessorStats, len(r.processors)),
+	}
+	for name, processorStats := range r.processors {
+		processorStats.Cost = llm.EstimateCost(processorStats.InputTokens, processorStats.OutputTokens,
+			r.config.InputTokenCost, r.config.OutputTokenCost)
+		stats.Processors[name] = processorStats
+	}
+	r.mu.Unlock()
+
+	lastRun.Lock()
+	lastRun.stats = stats
+	lastRun.Unlock()
+}
+
+// meter wraps a processor's provider to record its calls in the run of their context, if any
+func meter(provider llm.Provider, processorType string) llm.Provider {
+	return llm.WithMetering(provider, func(ctx context.Context, prompt, response string) {
+		if r, ok := ctx.Value(runKey{}).(*run); ok {
+			r.record(processorType, prompt, response)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package easy
+
+import (
+	"math"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+func TestLastRunStats(t *testing.T) {
+	provider := llm.NewMockProviderWithResponse(sentimentResponse)
+	config := copyConfig(DefaultConfig)
+	config.InputTokenCost = 0.5
+	config.OutputTokenCost = 2
+	wrapper := newTestWrapper(t, provider, config)
+
+	inputs := []string{"Great support!", "Slow shipping.", "The refund took three weeks."}
+	if _, err := wrapper.ProcessBatch(inputs, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	var inputTokens int64
+	for _, prompt := range provider.Prompts() {
+		inputTokens += llm.EstimateTokens(prompt)
+	}
+	outputTokens := int64(len(inputs)) * llm.EstimateTokens(sentimentResponse)
+
+	stats := LastRunStats()
+	if stats.Items != len(inputs) || stats.Calls() != len(inputs) {
+		t.Errorf("expected %d items and calls, got %d items and %d calls", len(inputs), stats.Items, stats.Calls())
+	}
+	sentiment, ok := stats.Processors["sentiment"]
+	if !ok || len(stats.Processors) != 1 {
+		t.Fatalf("expected the usage of the sentiment processor, got %v", stats.Processors)
+	}
+	if sentiment.InputTokens != inputTokens || sentiment.OutputTokens != outputTokens {
+		t.Errorf("expected %d input and %d output tokens, got %d and %d",
+			inputTokens, outputTokens, sentiment.InputTokens, sentiment.OutputTokens)
+	}
+	wantCost := (float64(inputTokens)*0.5 + float64(outputTokens)*2) / 1e6
+	if math.Abs(stats.Cost()-wantCost) > 1e-12 || math.Abs(stats.CostPerItem()-wantCost/3) > 1e-12 {
+		t.Errorf("expected cost %g, got %g (%g per item)", wantCost, stats.Cost(), stats.CostPerItem())
+	}
+
+	// The returned stats are a copy
+	stats.Processors["sentiment"] = ProcessorStats{}
+	if LastRunStats().Processors["sentiment"].Calls != len(inputs) {
+		t.Error("modifying the returned stats changed the last run")
+	}
+
+	// Calls outside batch runs aren't recorded
+	if _, err := wrapper.Process("Fine."); err != nil {
+		t.Fatal(err)
+	}
+	if LastRunStats().Calls() != len(inputs) {
+		t.Error("a single call replaced the last run")
+	}
+}
@@ -1,11 +1,14 @@
 package easy
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/eisenzopf/agentic-text/pkg/data"
 	"github.com/eisenzopf/agentic-text/pkg/processor"
+	"github.com/eisenzopf/agentic-text/pkg/processor/builtin"
 )
 
 // CleanLLMResponse ensures that we extract proper JSON from LLM responses
@@ -90,6 +93,22 @@ func ProcessTextWithConfig(text, processorType string, config *Config) (map[stri
 	return CleanLLMResponse(result), nil
 }
 
+// ProcessTextStream processes text with a specified processor type, calling onToken with
+// each chunk of the raw model output as it is generated, e.g. to display it live, before
+// returning the parsed result
+func ProcessTextStream(text, processorType string, onToken func(string)) (map[string]interface{}, error) {
+	return ProcessTextStreamWithConfig(text, processorType, DefaultConfig, onToken)
+}
+
+// ProcessTextStreamWithConfig processes text with streamed output and a custom configuration
+func ProcessTextStreamWithConfig(text, processorType string, config *Config, onToken func(string)) (map[string]interface{}, error) {
+	wrapper, err := NewWithConfig(processorType, config)
+	if err != nil {
+		return nil, err
+	}
+	return wrapper.ProcessStream(text, onToken)
+}
+
 // ProcessBatchText processes a batch of texts with a specified processor type
 func ProcessBatchText(texts []string, processorType string, concurrency int) ([]map[string]interface{}, error) {
 	if concurrency <= 0 {
@@ -210,6 +229,60 @@ func Sentiment(text string) (map[string]interface{}, error) {
 	return ProcessText(text, "sentiment")
 }
 
+// AspectSentiment analyzes the sentiment toward each aspect the given text discusses, such
+// as price or support quality. Without aspects, price, support quality, product and billing
+// are rated.
+func AspectSentiment(text string, aspects ...string) (map[string]interface{}, error) {
+	wrapper, err := New("aspect_sentiment")
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata map[string]interface{}
+	if len(aspects) > 0 {
+		metadata = map[string]interface{}{builtin.AspectsInput: aspects}
+	}
+	result, err := wrapper.processor.Process(context.Background(), data.NewTextProcessItem("input", text, metadata))
+	if err != nil {
+		return nil, err
+	}
+	return CleanLLMResponse(wrapper.extractResult(result)), nil
+}
+
+// Compare compares text with reference, e.g. a response draft with the policy it must
+// follow, listing agreements, contradictions and missing elements with a similarity score
+func Compare(text, reference string) (map[string]interface{}, error) {
+	wrapper, err := New("compare")
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{builtin.ReferenceInput: reference}
+	result, err := wrapper.processor.Process(context.Background(), data.NewTextProcessItem("input", text, metadata))
+	if err != nil {
+		return nil, err
+	}
+	return CleanLLMResponse(wrapper.extractResult(result)), nil
+}
+
+// Tags assigns every applicable tag from tags to the given text
+func Tags(text string, tags ...string) (map[string]interface{}, error) {
+	wrapper, err := New("tags")
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata map[string]interface{}
+	if len(tags) > 0 {
+		metadata = map[string]interface{}{builtin.TagsInput: tags}
+	}
+	result, err := wrapper.processor.Process(context.Background(), data.NewTextProcessItem("input", text, metadata))
+	if err != nil {
+		return nil, err
+	}
+	return CleanLLMResponse(wrapper.extractResult(result)), nil
+}
+
 // Intent analyzes the intent in the given text
 func Intent(text string) (map[string]interface{}, error) {
 	return ProcessText(text, "intent")
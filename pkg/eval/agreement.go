@@ -0,0 +1,335 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// MeasurementLevel is how ratings are compared by Krippendorff's alpha
+type MeasurementLevel string
+
+const (
+	// Nominal ratings are labels that either match or don't
+	Nominal MeasurementLevel = "nominal"
+	// Interval ratings are numbers whose disagreement is their squared difference
+	Interval MeasurementLevel = "interval"
+)
+
+// missingRating stands in for a prediction the result lacks, so it disagrees with any label
+const missingRating = "\x00missing"
+
+// Kappa returns Cohen's kappa of two raters' labels of the same units: their agreement
+// beyond chance, 1 for perfect agreement and 0 for agreement expected by chance. Labels are
+// compared ignoring case and surrounding space; units either rater left nil are skipped.
+func Kappa(a, b []interface{}) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("kappa needs the same units from both raters, got %d and %d", len(a), len(b))
+	}
+	var pairs [][2]string
+	for i := range a {
+		if a[i] == nil || b[i] == nil {
+			continue
+		}
+		pairs = append(pairs, [2]string{ratingLabel(a[i]), ratingLabel(b[i])})
+	}
+	if len(pairs) == 0 {
+		return 0, fmt.Errorf("kappa needs at least one unit rated by both raters")
+	}
+	return kappa(pairs), nil
+}
+
+// kappa returns Cohen's kappa of label pairs
+func kappa(pairs [][2]string) float64 {
+	n := float64(len(pairs))
+	first, second := make(map[string]float64), make(map[string]float64)
+	var agreed float64
+	for _, pair := range pairs {
+		first[pair[0]]++
+		second[pair[1]]++
+		if pair[0] == pair[1] {
+			agreed++
+		}
+	}
+	observed := agreed / n
+	var expected float64
+	for label, count := range first {
+		expected += (count / n) * (second[label] / n)
+	}
+	if expected == 1 {
+		// Both raters gave every unit the same label
+		return 1
+	}
+	return (observed - expected) / (1 - expected)
+}
+
+// Alpha returns Krippendorff's alpha of the ratings of units by any number of raters: 1 for
+// perfect agreement, 0 for agreement expected by chance. Each unit holds its ratings, with
+// nil for a rater who didn't rate it; units with fewer than two ratings are skipped. Nominal
+// ratings are compared as labels, ignoring case and surrounding space; interval ratings
+// must be numbers.
+func Alpha(units [][]interface{}, level MeasurementLevel) (float64, error) {
+	if level == "" {
+		level = Nominal
+	}
+	if level != Nominal && level != Interval {
+		return 0, fmt.Errorf("invalid measurement level %q: use %s or %s", level, Nominal, Interval)
+	}
+
+	var pairable [][]interface{}
+	for i, unit := range units {
+		var ratings []interface{}
+		for _, rating := range unit {
+			if rating == nil {
+				continue
+			}
+			if level == Interval {
+				number, ok := rating.(float64)
+				if !ok {
+					return 0, fmt.Errorf("unit %d: interval rating %v is not a number", i+1, rating)
+				}
+				rating = number
+			} else {
+				rating = ratingLabel(rating)
+			}
+			ratings = append(ratings, rating)
+		}
+		if len(ratings) >= 2 {
+			pairable = append(pairable, ratings)
+		}
+	}
+	if len(pairable) == 0 {
+		return 0, fmt.Errorf("alpha needs at least one unit with two ratings")
+	}
+	return alpha(pairable, level), nil
+}
+
+// alpha returns Krippendorff's alpha of units with at least two ratings each, which are
+// labels for nominal ratings and numbers for interval ones
+func alpha(units [][]interface{}, level MeasurementLevel) float64 {
+	distance := func(x, y interface{}) float64 {
+		if level == Interval {
+			d := x.(float64) - y.(float64)
+			return d * d
+		}
+		if x == y {
+			return 0
+		}
+		return 1
+	}
+
+	// Observed disagreement: within units, each unit's pairs weighted by 1/(m-1)
+	var n, observed float64
+	for _, unit := range units {
+		m := float64(len(unit))
+		n += m
+		var sum float64
+		for i := range unit {
+			for j := range unit {
+				if i != j {
+					sum += distance(unit[i], unit[j])
+				}
+			}
+		}
+		observed += sum / (m - 1)
+	}
+	observed /= n
+
+	// Expected disagreement: between all pairable values
+	var expected float64
+	if level == Interval {
+		var sum, squares float64
+		for _, unit := range units {
+			for _, rating := range unit {
+				x := rating.(float64)
+				sum += x
+				squares += x * x
+			}
+		}
+		expected = (2*n*squares - 2*sum*sum) / (n * (n - 1))
+	} else {
+		counts := make(map[interface{}]float64)
+		for _, unit := range units {
+			for _, rating := range unit {
+				counts[rating]++
+			}
+		}
+		same := 0.0
+		for _, count := range counts {
+			same += count * count
+		}
+		expected = (n*n - same) / (n * (n - 1))
+	}
+	if expected == 0 {
+		// Every rating is the same
+		return 1
+	}
+	return 1 - observed/expected
+}
+
+// ratingLabel returns the label a rating is compared by: strings ignoring case and
+// surrounding space, and other values by their JSON form
+func ratingLabel(rating interface{}) string {
+	switch v := rating.(type) {
+	case string:
+		return normalizeLabel(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	encoded, err := json.Marshal(normalizeJSON(rating))
+	if err != nil {
+		return fmt.Sprint(rating)
+	}
+	return string(encoded)
+}
+
+// CohensKappa is Cohen's kappa between the expected and actual labels of a field, i.e. the
+// processor's agreement with the human labels beyond chance. A missing prediction disagrees
+// with every label.
+type CohensKappa struct{}
+
+// Name implements Metric
+func (m CohensKappa) Name() string {
+	return "cohens_kappa"
+}
+
+// Score implements Metric
+func (m CohensKappa) Score(_ context.Context, _ string, observations []Observation) (Score, error) {
+	pairs := make([][2]string, 0, len(observations))
+	for _, o := range observations {
+		actual := missingRating
+		if o.Present && o.Actual != nil {
+			actual = ratingLabel(o.Actual)
+		}
+		pairs = append(pairs, [2]string{ratingLabel(o.Expected), actual})
+	}
+	if len(pairs) == 0 {
+		return Score{}, nil
+	}
+	return Score{Value: kappa(pairs)}, nil
+}
+
+// KrippendorffAlpha is Krippendorff's alpha between the expected and actual values of a
+// field at a measurement level (defaults to Nominal). Cases without a prediction, or at the
+// interval level without numeric values, are left out.
+type KrippendorffAlpha struct {
+	Level MeasurementLevel
+}
+
+// Name implements Metric
+func (m KrippendorffAlpha) Name() string {
+	return "krippendorff_alpha"
+}
+
+// Score implements Metric
+func (m KrippendorffAlpha) Score(_ context.Context, _ string, observations []Observation) (Score, error) {
+	units := make([][]interface{}, 0, len(observations))
+	for _, o := range observations {
+		if !o.Present || o.Actual == nil {
+			continue
+		}
+		if m.Level == Interval {
+			_, expectedOK := o.Expected.(float64)
+			_, actualOK := o.Actual.(float64)
+			if !expectedOK || !actualOK {
+				continue
+			}
+		}
+		units = append(units, []interface{}{o.Expected, o.Actual})
+	}
+	if len(units) == 0 {
+		return Score{}, nil
+	}
+	value, err := Alpha(units, m.Level)
+	if err != nil {
+		return Score{}, err
+	}
+	return Score{Value: value}, nil
+}
+
+// AgreementMetrics returns agreement metrics of the expected fields of the cases, to add to
+// the metrics of an evaluation: Cohen's kappa and nominal Krippendorff's alpha for label
+// fields and interval Krippendorff's alpha for number fields
+func AgreementMetrics(cases []Case) map[string][]Metric {
+	metrics := make(map[string][]Metric)
+	for field, kind := range fieldKinds(cases) {
+		switch kind {
+		case "string":
+			metrics[field] = []Metric{CohensKappa{}, KrippendorffAlpha{Level: Nominal}}
+		case "number":
+			metrics[field] = []Metric{KrippendorffAlpha{Level: Interval}}
+		}
+	}
+	return metrics
+}
+
+// Agreement is the agreement of two raters, such as two prompt versions, on a field
+type Agreement struct {
+	Field string `json:"field"`
+	// Units is the number of items both raters have a value of the field for
+	Units int `json:"units"`
+	// Observed is the fraction of units the raters gave the same label
+	Observed float64 `json:"observed"`
+	Kappa    float64 `json:"kappa"`
+	Alpha    float64 `json:"alpha"`
+}
+
+// MeasureAgreement compares the results of two processors, or two versions of one, on the
+// same items: a field of the result under each resultKey is compared for the items with the
+// same ID in a and b. An empty resultKey reads the field from the whole ProcessingInfo, as
+// for Config.ResultKey. Kappa and Observed compare labels; Alpha uses the level.
+func MeasureAgreement(a, b []*data.ProcessItem, resultKey, field string, level MeasurementLevel) (Agreement, error) {
+	agreement := Agreement{Field: field}
+	values := make(map[string]interface{}, len(a))
+	for _, item := range a {
+		if value, ok := resultValue(item, resultKey, field); ok {
+			values[item.ID] = value
+		}
+	}
+	var first, second []interface{}
+	var units [][]interface{}
+	for _, item := range b {
+		x, ok := values[item.ID]
+		if !ok {
+			continue
+		}
+		y, ok := resultValue(item, resultKey, field)
+		if !ok {
+			continue
+		}
+		first = append(first, x)
+		second = append(second, y)
+		units = append(units, []interface{}{x, y})
+		if ratingLabel(x) == ratingLabel(y) {
+			agreement.Observed++
+		}
+	}
+	agreement.Units = len(units)
+	if agreement.Units == 0 {
+		return agreement, fmt.Errorf("no item has a value of %s from both raters", field)
+	}
+	agreement.Observed /= float64(agreement.Units)
+
+	var err error
+	if agreement.Kappa, err = Kappa(first, second); err != nil {
+		return agreement, err
+	}
+	if agreement.Alpha, err = Alpha(units, level); err != nil {
+		return agreement, fmt.Errorf("failed to compute alpha of %s: %w", field, err)
+	}
+	return agreement, nil
+}
+
+// resultValue returns a field of an item's result under a ProcessingInfo key as plain JSON
+func resultValue(item *data.ProcessItem, resultKey, field string) (interface{}, bool) {
+	var result interface{} = item.ProcessingInfo
+	if resultKey != "" {
+		result = item.ProcessingInfo[resultKey]
+	}
+	fields, _ := normalizeJSON(result).(map[string]interface{})
+	value, ok := fields[field]
+	return value, ok && value != nil
+}
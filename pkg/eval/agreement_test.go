@@ -0,0 +1,257 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// repeat returns n copies of a label
+func repeat(label interface{}, n int) []interface{} {
+	labels := make([]interface{}, n)
+	for i := range labels {
+		labels[i] = label
+	}
+	return labels
+}
+
+// reliabilityData is Krippendorff's example of four observers rating twelve units, with nil
+// for the units an observer skipped; its alpha is 0.743 nominal and 0.849 interval
+func reliabilityData() [][]interface{} {
+	observers := [][]interface{}{
+		{1.0, 2.0, 3.0, 3.0, 2.0, 1.0, 4.0, 1.0, 2.0, nil, nil, nil},
+		{1.0, 2.0, 3.0, 3.0, 2.0, 2.0, 4.0, 1.0, 2.0, 5.0, nil, 3.0},
+		{nil, 3.0, 3.0, 3.0, 2.0, 3.0, 4.0, 2.0, 2.0, 5.0, 1.0, nil},
+		{1.0, 2.0, 3.0, 3.0, 2.0, 4.0, 4.0, 1.0, 2.0, 5.0, 1.0, nil},
+	}
+	units := make([][]interface{}, len(observers[0]))
+	for i := range units {
+		for _, ratings := range observers {
+			units[i] = append(units[i], ratings[i])
+		}
+	}
+	return units
+}
+
+func TestKappa(t *testing.T) {
+	// The textbook example: 20 units both rated yes, 15 both no, and 5 and 10 they split on
+	var yesNo, noYes []interface{}
+	yesNo = append(append(append(append(yesNo, repeat("yes", 20)...), repeat("yes", 5)...), repeat("no", 10)...), repeat("no", 15)...)
+	noYes = append(append(append(append(noYes, repeat("yes", 20)...), repeat("no", 5)...), repeat("yes", 10)...), repeat("no", 15)...)
+
+	tests := []struct {
+		name    string
+		a, b    []interface{}
+		want    float64
+		wantErr string
+	}{
+		{name: "textbook example", a: yesNo, b: noYes, want: 0.4},
+		{name: "units rated by one rater skipped", a: append([]interface{}{nil, "yes"}, yesNo...), b: append([]interface{}{"no", nil}, noYes...), want: 0.4},
+		{name: "perfect agreement", a: []interface{}{"yes", "no", "no"}, b: []interface{}{" Yes", "NO", "no"}, want: 1},
+		{name: "same label everywhere", a: repeat("yes", 3), b: repeat("yes", 3), want: 1},
+		{name: "chance agreement", a: []interface{}{"yes", "yes", "no", "no"}, b: []interface{}{"yes", "no", "yes", "no"}, want: 0},
+		{name: "different lengths", a: []interface{}{"yes"}, b: []interface{}{"yes", "no"}, wantErr: "same units"},
+		{name: "no unit rated by both", a: []interface{}{"yes", nil}, b: []interface{}{nil, "no"}, wantErr: "at least one unit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Kappa(tt.a, tt.b)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("expected kappa %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAlpha(t *testing.T) {
+	tests := []struct {
+		name    string
+		units   [][]interface{}
+		level   MeasurementLevel
+		want    float64
+		wantErr string
+	}{
+		{name: "nominal reliability data", units: reliabilityData(), level: Nominal, want: 0.743},
+		{name: "nominal by default", units: reliabilityData(), want: 0.743},
+		{name: "interval reliability data", units: reliabilityData(), level: Interval, want: 0.849},
+		{name: "labels ignoring case", units: [][]interface{}{{"yes", " YES"}, {"no", "No"}, {"yes", "yes"}}, want: 1},
+		{name: "same rating everywhere", units: [][]interface{}{{"yes", "yes"}, {"yes", "yes", nil}}, want: 1},
+		{name: "same number everywhere", units: [][]interface{}{{2.0, 2.0}, {2.0, 2.0}}, level: Interval, want: 1},
+		{name: "invalid level", units: reliabilityData(), level: "ordinal", wantErr: "invalid measurement level"},
+		{name: "interval label", units: [][]interface{}{{1.0, "high"}}, level: Interval, wantErr: "unit 1: interval rating high is not a number"},
+		{name: "no pairable unit", units: [][]interface{}{{"yes", nil}, {nil, "no"}}, wantErr: "at least one unit with two ratings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Alpha(tt.units, tt.level)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("expected alpha %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAgreementMetrics(t *testing.T) {
+	ctx := context.Background()
+	// observe returns an observation of a value, or of a missing prediction if actual is nil
+	observe := func(expected, actual interface{}) Observation {
+		return Observation{Expected: expected, Actual: actual, Present: actual != nil}
+	}
+
+	t.Run("kappa counts missing predictions as disagreements", func(t *testing.T) {
+		observations := []Observation{
+			observe("positive", "positive"),
+			observe("negative", "negative"),
+			observe("positive", nil),
+			observe("negative", "Negative"),
+		}
+		// Observed 0.75, expected 0.5*0.25 + 0.5*0.5
+		score, err := CohensKappa{}.Score(ctx, "sentiment", observations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(score.Value-0.6) > 1e-9 {
+			t.Errorf("expected kappa 0.6, got %v", score.Value)
+		}
+
+		// A present but null prediction is missing too
+		observations[2] = Observation{Expected: "positive", Present: true}
+		if score, _ := (CohensKappa{}).Score(ctx, "sentiment", observations); math.Abs(score.Value-0.6) > 1e-9 {
+			t.Errorf("expected kappa 0.6 with a null prediction, got %v", score.Value)
+		}
+	})
+
+	t.Run("alpha leaves out missing and non-numeric predictions", func(t *testing.T) {
+		observations := []Observation{
+			observe(1.0, 1.0),
+			observe(2.0, nil),
+			observe(3.0, "three"),
+			observe(4.0, 4.0),
+		}
+		score, err := KrippendorffAlpha{Level: Interval}.Score(ctx, "score", observations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score.Value != 1 {
+			t.Errorf("expected alpha 1 from the two agreeing cases, got %v", score.Value)
+		}
+	})
+
+	t.Run("no observations", func(t *testing.T) {
+		for _, metric := range []Metric{CohensKappa{}, KrippendorffAlpha{}} {
+			score, err := metric.Score(ctx, "sentiment", nil)
+			if err != nil || score.Value != 0 {
+				t.Errorf("%s: expected a zero score, got %v, %v", metric.Name(), score.Value, err)
+			}
+		}
+	})
+
+	t.Run("metrics by field kind", func(t *testing.T) {
+		cases := []Case{
+			{Expected: map[string]interface{}{"sentiment": "positive", "score": 0.8, "tags": []interface{}{"billing"}}},
+			{Expected: map[string]interface{}{"sentiment": "negative", "score": 0.1}},
+		}
+		metrics := AgreementMetrics(cases)
+		names := func(field string) []string {
+			var result []string
+			for _, metric := range metrics[field] {
+				result = append(result, metric.Name())
+			}
+			return result
+		}
+		if got := strings.Join(names("sentiment"), ","); got != "cohens_kappa,krippendorff_alpha" {
+			t.Errorf("expected kappa and alpha of sentiment, got %s", got)
+		}
+		if got := names("score"); len(got) != 1 || metrics["score"][0].(KrippendorffAlpha).Level != Interval {
+			t.Errorf("expected interval alpha of score, got %v", got)
+		}
+		if _, ok := metrics["tags"]; ok {
+			t.Error("expected no agreement metrics of a list field")
+		}
+	})
+}
+
+func TestMeasureAgreement(t *testing.T) {
+	// labeled returns an item with a sentiment result, or without its label if label is empty
+	labeled := func(id, label string) *data.ProcessItem {
+		item := data.NewTextProcessItem(id, "text "+id, nil)
+		result := map[string]interface{}{"confidence": 0.9}
+		if label != "" {
+			result["label"] = label
+		}
+		item.AddProcessingInfo("sentiment", result)
+		return item
+	}
+	a := []*data.ProcessItem{
+		labeled("1", "positive"),
+		labeled("2", "negative"),
+		labeled("3", "positive"),
+		labeled("4", "negative"),
+		labeled("5", ""),
+	}
+	b := []*data.ProcessItem{
+		labeled("1", "positive"),
+		labeled("2", "positive"),
+		labeled("3", "Positive"),
+		labeled("4", "negative"),
+		labeled("5", "negative"),
+		labeled("6", "negative"),
+	}
+
+	agreement, err := MeasureAgreement(a, b, "sentiment", "label", Nominal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Items 1 to 4: observed 0.75, expected 0.5*0.75 + 0.5*0.25 for kappa; alpha 1 - 0.25/(30/56)
+	want := Agreement{Field: "label", Units: 4, Observed: 0.75, Kappa: 0.5, Alpha: 8.0 / 15}
+	if agreement.Field != want.Field || agreement.Units != want.Units ||
+		math.Abs(agreement.Observed-want.Observed) > 1e-9 ||
+		math.Abs(agreement.Kappa-want.Kappa) > 1e-9 ||
+		math.Abs(agreement.Alpha-want.Alpha) > 1e-9 {
+		t.Errorf("expected %+v, got %+v", want, agreement)
+	}
+
+	// An empty result key reads the field from the whole processing info
+	flat := func(id, label string) *data.ProcessItem {
+		item := data.NewTextProcessItem(id, "text "+id, nil)
+		item.AddProcessingInfo("label", label)
+		return item
+	}
+	agreement, err = MeasureAgreement(
+		[]*data.ProcessItem{flat("1", "positive"), flat("2", "negative")},
+		[]*data.ProcessItem{flat("1", "positive"), flat("2", "negative")},
+		"", "label", Nominal)
+	if err != nil || agreement.Units != 2 || agreement.Kappa != 1 || agreement.Alpha != 1 {
+		t.Errorf("expected perfect agreement of two units, got %+v, %v", agreement, err)
+	}
+
+	if _, err := MeasureAgreement(a, b, "sentiment", "reason", Nominal); err == nil || !strings.Contains(err.Error(), "no item has a value of reason") {
+		t.Errorf("expected an error for a field neither rater has, got %v", err)
+	}
+	if _, err := MeasureAgreement(a, b, "sentiment", "label", Interval); err == nil || !strings.Contains(err.Error(), "failed to compute alpha of label") {
+		t.Errorf("expected an error for interval alpha of labels, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package eval
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Regression is a metric that dropped from the baseline
+type Regression struct {
+	// Metric names the metric, e.g. "accuracy" or "sentiment f1"
+	Metric   string  `json:"metric"`
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	// Missing reports that the current run no longer has the metric
+	Missing bool `json:"missing,omitempty"`
+}
+
+// String describes the regression
+func (r Regression) String() string {
+	if r.Missing {
+		return fmt.Sprintf("%s is no longer evaluated", r.Metric)
+	}
+	return fmt.Sprintf("%s dropped from %.3f to %.3f", r.Metric, r.Baseline, r.Current)
+}
+
+// Comparison is the difference between a run and its baseline
+type Comparison struct {
+	// Regressions are the metrics that dropped by more than the allowed amount
+	Regressions []Regression `json:"regressions,omitempty"`
+	// NewFailures are the cases that passed in the baseline but fail now
+	NewFailures []CaseResult `json:"new_failures,omitempty"`
+	// NewPasses are the IDs of the cases that failed in the baseline but pass now
+	NewPasses []string `json:"new_passes,omitempty"`
+}
+
+// Regressed reports whether any metric regressed
+func (c *Comparison) Regressed() bool {
+	return len(c.Regressions) > 0
+}
+
+// Compare compares a run with a baseline. A metric regresses if it dropped by more than
+// maxDrop, or if the baseline has it and the run doesn't.
+func Compare(baseline, current *Run, maxDrop float64) *Comparison {
+	comparison := &Comparison{}
+	check := func(metric string, before, after float64) {
+		// The epsilon ignores drops caused by float rounding
+		if before-after > maxDrop+1e-9 {
+			comparison.Regressions = append(comparison.Regressions, Regression{Metric: metric, Baseline: before, Current: after})
+		}
+	}
+
+	check("accuracy", baseline.Accuracy, current.Accuracy)
+	if baseline.F1 != nil && current.F1 != nil {
+		check("f1", *baseline.F1, *current.F1)
+	}
+	for _, field := range sortedKeys(baseline.Fields) {
+		for _, metric := range sortedKeys(baseline.Fields[field]) {
+			name := field + " " + metric
+			after, ok := current.Fields[field][metric]
+			if !ok {
+				comparison.Regressions = append(comparison.Regressions, Regression{Metric: name, Baseline: baseline.Fields[field][metric], Missing: true})
+				continue
+			}
+			check(name, baseline.Fields[field][metric], after)
+		}
+	}
+
+	passedBefore := make(map[string]bool, len(baseline.Results))
+	for _, result := range baseline.Results {
+		passedBefore[result.ID] = result.Passed
+	}
+	for _, result := range current.Results {
+		before, ok := passedBefore[result.ID]
+		switch {
+		case !ok:
+		case before && !result.Passed:
+			comparison.NewFailures = append(comparison.NewFailures, result)
+		case !before && result.Passed:
+			comparison.NewPasses = append(comparison.NewPasses, result.ID)
+		}
+	}
+	return comparison
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	f1 := func(value float64) *float64 { return &value }
+	baseline := &Run{
+		Accuracy: 0.8,
+		F1:       f1(0.7),
+		Fields: map[string]map[string]float64{
+			"sentiment": {"exact_match": 0.8, "f1": 0.7},
+			"score":     {"within_tolerance": 0.9},
+		},
+	}
+	// run returns a run with the baseline's metrics, changed by modify
+	run := func(modify func(r *Run)) *Run {
+		r := &Run{
+			Accuracy: baseline.Accuracy,
+			F1:       f1(*baseline.F1),
+			Fields: map[string]map[string]float64{
+				"sentiment": {"exact_match": 0.8, "f1": 0.7},
+				"score":     {"within_tolerance": 0.9},
+			},
+		}
+		if modify != nil {
+			modify(r)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		current *Run
+		maxDrop float64
+		want    []Regression
+	}{
+		{name: "unchanged", current: run(nil)},
+		{name: "improved", current: run(func(r *Run) { r.Accuracy = 0.9 })},
+		// 0.8 - 0.75 is slightly more than 0.05 in floating point
+		{name: "drop of exactly maxDrop", current: run(func(r *Run) { r.Accuracy = 0.75 }), maxDrop: 0.05},
+		{
+			name:    "drop beyond maxDrop",
+			current: run(func(r *Run) { r.Accuracy = 0.74 }),
+			maxDrop: 0.05,
+			want:    []Regression{{Metric: "accuracy", Baseline: 0.8, Current: 0.74}},
+		},
+		{
+			name:    "any drop without maxDrop",
+			current: run(func(r *Run) { r.F1 = f1(0.69) }),
+			want:    []Regression{{Metric: "f1", Baseline: 0.7, Current: 0.69}},
+		},
+		{name: "f1 no longer computed", current: run(func(r *Run) { r.F1 = nil })},
+		{
+			name:    "field metric dropped",
+			current: run(func(r *Run) { r.Fields["sentiment"]["f1"] = 0.5 }),
+			maxDrop: 0.1,
+			want:    []Regression{{Metric: "sentiment f1", Baseline: 0.7, Current: 0.5}},
+		},
+		{
+			name:    "field metric missing",
+			current: run(func(r *Run) { delete(r.Fields, "score") }),
+			want:    []Regression{{Metric: "score within_tolerance", Baseline: 0.9, Missing: true}},
+		},
+		{
+			name: "regressions in order",
+			current: run(func(r *Run) {
+				r.Accuracy = 0.5
+				r.Fields["score"]["within_tolerance"] = 0.5
+				r.Fields["sentiment"]["exact_match"] = 0.5
+			}),
+			want: []Regression{
+				{Metric: "accuracy", Baseline: 0.8, Current: 0.5},
+				{Metric: "score within_tolerance", Baseline: 0.9, Current: 0.5},
+				{Metric: "sentiment exact_match", Baseline: 0.8, Current: 0.5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparison := Compare(baseline, tt.current, tt.maxDrop)
+			if !reflect.DeepEqual(comparison.Regressions, tt.want) {
+				t.Errorf("expected regressions %+v, got %+v", tt.want, comparison.Regressions)
+			}
+			if comparison.Regressed() != (len(tt.want) > 0) {
+				t.Errorf("expected Regressed to be %t", len(tt.want) > 0)
+			}
+		})
+	}
+}
+
+func TestCompareCases(t *testing.T) {
+	baseline := &Run{Results: []CaseResult{
+		{ID: "1", Passed: true},
+		{ID: "2", Passed: false},
+		{ID: "3", Passed: true},
+		{ID: "4", Passed: false},
+	}}
+	current := &Run{Results: []CaseResult{
+		{ID: "1", Passed: true},
+		{ID: "2", Passed: true},
+		{ID: "3", Passed: false, Error: "timeout"},
+		{ID: "4", Passed: false},
+		{ID: "5", Passed: false},
+	}}
+
+	comparison := Compare(baseline, current, 0)
+	if len(comparison.NewFailures) != 1 || comparison.NewFailures[0].ID != "3" || comparison.NewFailures[0].Error != "timeout" {
+		t.Errorf("expected case 3 to be a new failure, got %+v", comparison.NewFailures)
+	}
+	if !reflect.DeepEqual(comparison.NewPasses, []string{"2"}) {
+		t.Errorf("expected case 2 to be a new pass, got %v", comparison.NewPasses)
+	}
+}
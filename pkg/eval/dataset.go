@@ -0,0 +1,184 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// Case is a labeled example: a text and the result fields a processor should produce for it
+type Case struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// Expected are the expected result fields by JSON name, as plain JSON values
+	Expected map[string]interface{} `json:"expected"`
+	// Metadata holds the case's other fields or columns, passed to the processor
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Item returns the item processed for the case
+func (c Case) Item() *data.ProcessItem {
+	metadata := make(map[string]interface{}, len(c.Metadata))
+	for key, value := range c.Metadata {
+		metadata[key] = value
+	}
+	return data.NewTextProcessItem(c.ID, c.Text, metadata)
+}
+
+// DatasetConfig holds the field names of a dataset
+type DatasetConfig struct {
+	// TextField is the field or column holding each case's text (defaults to "text")
+	TextField string
+	// IDField is the field or column holding each case's ID (defaults to "id")
+	IDField string
+	// ExpectedField is the JSON Lines field holding the expected result object, and the
+	// prefix of the CSV columns holding expected fields, e.g. "expected.sentiment"
+	// (defaults to "expected")
+	ExpectedField string
+}
+
+// withDefaults returns the config with its defaults applied
+func (c DatasetConfig) withDefaults() DatasetConfig {
+	if c.TextField == "" {
+		c.TextField = "text"
+	}
+	if c.IDField == "" {
+		c.IDField = "id"
+	}
+	if c.ExpectedField == "" {
+		c.ExpectedField = "expected"
+	}
+	return c
+}
+
+// LoadDataset reads the cases of a JSON Lines (.jsonl, .ndjson) or CSV (.csv) file
+func LoadDataset(path string, config DatasetConfig) ([]Case, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return LoadJSONL(path, config)
+	case ".csv":
+		return LoadCSV(path, config)
+	default:
+		return nil, fmt.Errorf("unsupported dataset format: %s", filepath.Ext(path))
+	}
+}
+
+// LoadJSONL reads cases from a JSON Lines file with one object per line, such as
+// {"id": "1", "text": "...", "expected": {"sentiment": "positive"}}
+func LoadJSONL(path string, config DatasetConfig) ([]Case, error) {
+	config = config.withDefaults()
+	source, err := data.NewJSONLSource(data.JSONLSourceConfig{Path: path, IDField: config.IDField, ContentField: config.TextField})
+	if err != nil {
+		return nil, err
+	}
+	return readCases(source, func(item *data.ProcessItem) (map[string]interface{}, error) {
+		expected, ok := item.Metadata[config.ExpectedField].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("case %s has no %s object", item.ID, config.ExpectedField)
+		}
+		delete(item.Metadata, config.ExpectedField)
+		return expected, nil
+	})
+}
+
+// LoadCSV reads cases from a CSV file whose expected fields are columns named with the
+// expected field prefix, e.g. "expected.sentiment". Values that are valid JSON numbers,
+// booleans or arrays are decoded; anything else is a string.
+func LoadCSV(path string, config DatasetConfig) ([]Case, error) {
+	config = config.withDefaults()
+	source, err := data.NewCSVSource(data.CSVSourceConfig{Path: path, IDColumn: config.IDField, ContentColumn: config.TextField})
+	if err != nil {
+		return nil, err
+	}
+	prefix := config.ExpectedField + "."
+	return readCases(source, func(item *data.ProcessItem) (map[string]interface{}, error) {
+		expected := make(map[string]interface{})
+		for column, value := range item.Metadata {
+			if !strings.HasPrefix(column, prefix) {
+				continue
+			}
+			delete(item.Metadata, column)
+			text, _ := value.(string)
+			if text == "" {
+				// An empty cell leaves the field out of the case
+				continue
+			}
+			expected[strings.TrimPrefix(column, prefix)] = parseCSVValue(text)
+		}
+		return expected, nil
+	})
+}
+
+// readCases reads every item of a source as a case, with its expected fields taken from the
+// item by expected
+func readCases(source data.ProcessItemSource, expected func(item *data.ProcessItem) (map[string]interface{}, error)) ([]Case, error) {
+	defer source.Close()
+
+	var cases []Case
+	seen := make(map[string]bool)
+	for {
+		item, err := source.NextProcessItem(context.Background())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dataset: %w", err)
+		}
+		if seen[item.ID] {
+			return nil, fmt.Errorf("dataset has more than one case with ID %s", item.ID)
+		}
+		seen[item.ID] = true
+
+		fields, err := expected(item)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("case %s has no expected fields", item.ID)
+		}
+		text, _ := item.GetTextContent()
+		cases = append(cases, Case{
+			ID:       item.ID,
+			Text:     text,
+			Expected: normalizeJSON(fields).(map[string]interface{}),
+			Metadata: item.Metadata,
+		})
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("dataset has no cases")
+	}
+	return cases, nil
+}
+
+// parseCSVValue decodes a CSV cell holding a JSON number, boolean or array, else returns it
+// as a string
+func parseCSVValue(text string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err == nil {
+		switch value.(type) {
+		case float64, bool, []interface{}:
+			return value
+		}
+	}
+	return text
+}
+
+// normalizeJSON converts a value to its plain JSON form, so results of any Go type compare
+// with values decoded from a dataset
+func normalizeJSON(value interface{}) interface{} {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return nil
+	}
+	return normalized
+}
@@ -0,0 +1,46 @@
+/*
+Package eval measures the quality of processors against labeled examples.
+
+The eval package runs a processor or pipeline over a dataset of cases with expected results,
+scores each expected field with a set of metrics and compares the run with a stored baseline,
+so prompt and model changes can be checked like code changes. The agentic-text eval command
+is built on it.
+
+Core components:
+
+1. Datasets (dataset.go):
+  - Case: A text and the result fields a processor should produce for it
+  - LoadDataset / LoadJSONL / LoadCSV: Read cases from JSON Lines or CSV files
+  - DatasetConfig: Names of the text, ID and expected fields
+
+2. Metrics (metric.go, judge.go):
+  - Metric: Scores one field over every case, optionally deciding which cases match
+  - ExactMatch: Fraction of values equal to the expected ones
+  - NumericTolerance: Fraction of numbers within a tolerance
+  - FieldF1: Macro-averaged F1 over the values of a label field
+  - LLMJudge: Mean similarity rated by an LLM, for free-text fields
+  - DefaultMetrics: Metrics chosen from the types of the expected values
+
+3. Runs (run.go):
+  - Evaluate / EvaluateProcessor: Process every case and score the results
+  - Run: Accuracy, F1, per-field metrics and per-case results of an evaluation
+  - Save / LoadRun: Store runs as JSON, e.g. as baselines
+
+4. Baselines (compare.go):
+  - Compare: Metrics that dropped from a baseline, and cases that started or stopped failing
+
+5. Prompt improvement (improve.go):
+  - Improve: Revise a processor definition's prompt from quality_reviewer critiques until scores plateau
+  - Improvement: Every prompt version with its scores, and the recommended one
+
+6. Splits (split.go):
+  - SplitDataset: Stratified sampling of a corpus into calibration and evaluation sets
+  - CasesFromItems: Corpus items as cases to label, optionally pre-labeled from a processor
+  - SaveDataset: Write cases as JSON Lines or CSV in the format LoadDataset reads
+
+7. Agreement (agreement.go):
+  - Kappa / Alpha: Cohen's kappa and Krippendorff's alpha of raters' labels or ratings
+  - CohensKappa / KrippendorffAlpha: Agreement with human labels as metrics
+  - MeasureAgreement: Agreement of two processors or prompt versions on the same items
+*/
+package eval
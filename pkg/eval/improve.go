@@ -0,0 +1,370 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+	"github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+// Defaults used by Improve when ImproveConfig leaves them unset
+const (
+	DefaultImproveIterations = 5
+	DefaultImproveMinGain    = 0.01
+	DefaultReviewCases       = 10
+)
+
+// maxRevisionFailures is the number of failed cases shown to the critic revising a prompt
+const maxRevisionFailures = 5
+
+// ImproveConfig configures a prompt improvement loop
+type ImproveConfig struct {
+	// Critic reviews the outputs with the quality_reviewer processor and revises the prompt
+	// (defaults to the processor's provider), e.g. a stronger model
+	Critic llm.Provider
+	// Options are the options of the processor being improved
+	Options processor.Options
+	// MaxIterations bounds the number of prompt versions evaluated, including the original
+	// (defaults to DefaultImproveIterations)
+	MaxIterations int
+	// MinGain is the smallest score gain over the best version that counts as progress
+	// (defaults to DefaultImproveMinGain). The loop stops at the first version without it.
+	MinGain float64
+	// ReviewCases is the number of cases whose outputs are reviewed for each version
+	// (defaults to DefaultReviewCases)
+	ReviewCases int
+	// Eval configures the evaluation of each version; Name and ResultKey are set by Improve
+	Eval Config
+	// OnIteration, if set, is called after each version is scored
+	OnIteration func(iteration PromptIteration)
+}
+
+// PromptIteration is one version of a prompt and its scores
+type PromptIteration struct {
+	Version    int                  `json:"version"`
+	Definition processor.Definition `json:"definition"`
+	// Score is the version's accuracy if the cases are labeled, or else its review score
+	Score float64 `json:"score"`
+	// ReviewScore is the mean overall quality score the reviewer gave the version's outputs
+	ReviewScore float64 `json:"review_score"`
+	// Reviewed is the number of outputs reviewed
+	Reviewed int `json:"reviewed"`
+	// Suggestions are the reviewer's suggestions for improving the prompt
+	Suggestions []string `json:"suggestions,omitempty"`
+	// Run is the evaluation of the version on the cases
+	Run *Run `json:"run"`
+}
+
+// Improvement is the record of a prompt improvement loop
+type Improvement struct {
+	Iterations []PromptIteration `json:"iterations"`
+	// Recommended is the version with the best score
+	Recommended int `json:"recommended"`
+}
+
+// RecommendedDefinition returns the definition of the recommended version
+func (i *Improvement) RecommendedDefinition() processor.Definition {
+	return i.Iterations[i.Recommended-1].Definition
+}
+
+// Save writes the improvement to a file as indented JSON
+func (i *Improvement) Save(path string) error {
+	encoded, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode improvement: %w", err)
+	}
+	if err := os.WriteFile(path, append(encoded, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write improvement: %w", err)
+	}
+	return nil
+}
+
+// Improve improves the prompt of a processor definition offline. Each version of the prompt
+// is run on the cases and scored, the built-in quality_reviewer processor critiques the
+// outputs of up to ReviewCases cases along with the prompt, and the critic rewrites the
+// role, objective and instructions following its suggestions and the failed cases. The
+// loop stops once a version doesn't beat the best score by MinGain, a version scores 1, or
+// after MaxIterations versions. Labeled cases are scored by accuracy, unlabeled ones by the
+// mean review score.
+func Improve(ctx context.Context, cases []Case, definition processor.Definition, provider llm.Provider, config ImproveConfig) (*Improvement, error) {
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no cases to improve the prompt with")
+	}
+	if config.MaxIterations <= 0 {
+		config.MaxIterations = DefaultImproveIterations
+	}
+	if config.MinGain <= 0 {
+		config.MinGain = DefaultImproveMinGain
+	}
+	if config.ReviewCases <= 0 {
+		config.ReviewCases = DefaultReviewCases
+	}
+	if config.Critic == nil {
+		config.Critic = provider
+	}
+
+	reviewer, err := processor.Create("quality_reviewer", config.Critic, processor.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reviewer: %w", err)
+	}
+
+	labeled := false
+	for _, c := range cases {
+		if len(c.Expected) > 0 {
+			labeled = true
+			break
+		}
+	}
+
+	improvement := &Improvement{}
+	best := 0.0
+	current := cloneDefinition(definition)
+	for version := 1; version <= config.MaxIterations; version++ {
+		iteration, err := evaluateVersion(ctx, cases, current, provider, reviewer, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate prompt version %d: %w", version, err)
+		}
+		iteration.Version = version
+		iteration.Score = iteration.ReviewScore
+		if labeled {
+			iteration.Score = iteration.Run.Accuracy
+		}
+		improvement.Iterations = append(improvement.Iterations, iteration)
+		if config.OnIteration != nil {
+			config.OnIteration(iteration)
+		}
+
+		// Scores have plateaued once a version brings no real gain
+		gained := version == 1 || iteration.Score >= best+config.MinGain
+		if version == 1 || iteration.Score > best {
+			best = iteration.Score
+			improvement.Recommended = version
+		}
+		if !gained || best >= 1 || version == config.MaxIterations {
+			break
+		}
+
+		current, err = revisePrompt(ctx, config.Critic, current, iteration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to revise prompt version %d: %w", version, err)
+		}
+	}
+	return improvement, nil
+}
+
+// evaluateVersion runs a version of the prompt on the cases and reviews its outputs
+func evaluateVersion(ctx context.Context, cases []Case, definition processor.Definition, provider llm.Provider, reviewer processor.Processor, config ImproveConfig) (PromptIteration, error) {
+	proc, err := definition.Create(provider, config.Options)
+	if err != nil {
+		return PromptIteration{}, err
+	}
+
+	// The outputs are kept for review
+	var mu sync.Mutex
+	outputs := make(map[string]interface{}, len(cases))
+	evalConfig := config.Eval
+	evalConfig.Name = definition.Name
+	evalConfig.ResultKey = definition.Name
+	run, err := Evaluate(ctx, cases, func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		result, err := proc.Process(ctx, item)
+		if err == nil {
+			mu.Lock()
+			outputs[item.ID] = result.ProcessingInfo[definition.Name]
+			mu.Unlock()
+		}
+		return result, err
+	}, evalConfig)
+	if err != nil {
+		return PromptIteration{}, err
+	}
+
+	iteration := PromptIteration{Definition: definition, Run: run}
+	seen := make(map[string]bool)
+	var sum float64
+	for _, c := range cases {
+		if iteration.Reviewed == config.ReviewCases {
+			break
+		}
+		output, ok := outputs[c.ID]
+		if !ok {
+			continue
+		}
+
+		review, err := reviewOutput(ctx, reviewer, definition, c, output)
+		if err != nil {
+			return PromptIteration{}, fmt.Errorf("failed to review case %s: %w", c.ID, err)
+		}
+		sum += review.OverallQuality.Score
+		iteration.Reviewed++
+
+		suggestions := review.PromptEffectiveness.SuggestedImprovements
+		for _, improvement := range review.Improvements {
+			if strings.EqualFold(improvement.Category, "prompt") {
+				suggestions = append(suggestions, improvement.Suggestion)
+			}
+		}
+		for _, suggestion := range suggestions {
+			if suggestion = strings.TrimSpace(suggestion); suggestion != "" && !seen[suggestion] {
+				seen[suggestion] = true
+				iteration.Suggestions = append(iteration.Suggestions, suggestion)
+			}
+		}
+	}
+	if iteration.Reviewed > 0 {
+		iteration.ReviewScore = sum / float64(iteration.Reviewed)
+	}
+	return iteration, nil
+}
+
+// reviewOutput has the reviewer critique the output of one case and the prompt producing it
+func reviewOutput(ctx context.Context, reviewer processor.Processor, definition processor.Definition, c Case, output interface{}) (builtin.ReviewResult, error) {
+	encoded, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return builtin.ReviewResult{}, err
+	}
+	text := fmt.Sprintf("**Prompt:**\n%s\n\n**Input:**\n%s\n\n**Output:**\n%s", formatPrompt(definition), c.Text, encoded)
+	if len(c.Expected) > 0 {
+		expected, err := json.MarshalIndent(c.Expected, "", "  ")
+		if err != nil {
+			return builtin.ReviewResult{}, err
+		}
+		text += fmt.Sprintf("\n\n**Expected Output Fields:**\n%s", expected)
+	}
+
+	result, err := reviewer.Process(ctx, data.NewTextProcessItem(c.ID, text, nil))
+	if err != nil {
+		return builtin.ReviewResult{}, err
+	}
+
+	// The review is decoded from its JSON form, whatever type the result was stored as
+	var review builtin.ReviewResult
+	encoded, err = json.Marshal(result.ProcessingInfo[reviewer.GetName()])
+	if err != nil {
+		return builtin.ReviewResult{}, err
+	}
+	if err := json.Unmarshal(encoded, &review); err != nil {
+		return builtin.ReviewResult{}, fmt.Errorf("failed to parse review: %w", err)
+	}
+	return review, nil
+}
+
+// revision is the critic's rewrite of a prompt
+type revision struct {
+	Role         string   `json:"role"`
+	Objective    string   `json:"objective"`
+	Instructions []string `json:"instructions"`
+}
+
+// revisePrompt has the critic rewrite the prompt sections of a definition following the
+// reviewer's suggestions and the cases the version failed
+func revisePrompt(ctx context.Context, critic llm.Provider, definition processor.Definition, iteration PromptIteration) (processor.Definition, error) {
+	var fields []string
+	for _, field := range definition.Fields {
+		line := "- " + field.Name
+		if field.Description != "" {
+			line += ": " + field.Description
+		}
+		fields = append(fields, line)
+	}
+
+	suggestions := "None"
+	if len(iteration.Suggestions) > 0 {
+		suggestions = "- " + strings.Join(iteration.Suggestions, "\n- ")
+	}
+
+	var failures []string
+	for _, result := range iteration.Run.Results {
+		if len(failures) == maxRevisionFailures {
+			break
+		}
+		for _, mismatch := range result.Mismatches {
+			failures = append(failures, fmt.Sprintf("- case %s: %s was %v, expected %v", result.ID, mismatch.Field, mismatch.Actual, mismatch.Expected))
+		}
+	}
+	if len(failures) == 0 {
+		failures = []string{"None"}
+	}
+
+	prompt := fmt.Sprintf(`**Role:** You are an expert prompt engineer that ONLY outputs valid JSON.
+
+**Objective:** Revise the prompt of the "%s" text processor so that its outputs improve.
+
+**Current Prompt:**
+%s
+
+**Output Fields:**
+%s
+
+**Reviewer Suggestions:**
+%s
+
+**Failed Cases:**
+%s
+
+**Instructions:**
+1. Apply the suggestions and address the failed cases where doing so improves the outputs.
+2. Keep what works; change the role and objective only if they contribute to the problems.
+3. Write instructions as short, concrete, imperative sentences.
+4. Never ask for output fields other than the ones listed.
+
+**Required JSON Output Structure:**
+{"role": "...", "objective": "...", "instructions": ["...", "..."]}
+
+*** IMPORTANT: Your ENTIRE response must be a single JSON object. ***`,
+		definition.Name, formatPrompt(definition), strings.Join(fields, "\n"), suggestions, strings.Join(failures, "\n"))
+
+	var revised revision
+	if err := critic.GenerateJSON(ctx, prompt, &revised); err != nil {
+		return processor.Definition{}, err
+	}
+	if len(revised.Instructions) == 0 {
+		return processor.Definition{}, fmt.Errorf("revision has no instructions")
+	}
+
+	result := cloneDefinition(definition)
+	if revised.Role != "" {
+		result.Role = revised.Role
+	}
+	if revised.Objective != "" {
+		result.Objective = revised.Objective
+	}
+	result.Instructions = revised.Instructions
+	return result, nil
+}
+
+// formatPrompt formats the prompt sections of a definition for review and revision
+func formatPrompt(definition processor.Definition) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Role: %s\nObjective: %s\nInstructions:", definition.Role, definition.Objective)
+	for i, instruction := range definition.Instructions {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, instruction)
+	}
+	return b.String()
+}
+
+// cloneDefinition copies a definition, so revisions don't share its slices and maps
+func cloneDefinition(definition processor.Definition) processor.Definition {
+	definition.ContentTypes = append([]string(nil), definition.ContentTypes...)
+	definition.Instructions = append([]string(nil), definition.Instructions...)
+	definition.StateKeys = append([]string(nil), definition.StateKeys...)
+	definition.StandardSections = append([]string(nil), definition.StandardSections...)
+	definition.PreferredModels = append([]string(nil), definition.PreferredModels...)
+	definition.Inputs = append([]processor.InputDefinition(nil), definition.Inputs...)
+	definition.Fields = append([]processor.FieldDefinition(nil), definition.Fields...)
+	definition.Examples = append([]processor.PromptExample(nil), definition.Examples...)
+	if definition.Localizations != nil {
+		localizations := make(map[string]processor.PromptLocalization, len(definition.Localizations))
+		for language, localization := range definition.Localizations {
+			localizations[language] = localization
+		}
+		definition.Localizations = localizations
+	}
+	return definition
+}
@@ -0,0 +1,101 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// DefaultJudgeThreshold is the similarity at which LLMJudge counts a value as a match
+const DefaultJudgeThreshold = 0.7
+
+// LLMJudge is the mean similarity of values to the expected ones as rated by an LLM, for
+// free-text fields such as summaries where exact matches are rare. It calls the provider
+// once per case that has the field.
+type LLMJudge struct {
+	// Provider rates the similarity
+	Provider llm.Provider
+	// Threshold is the similarity at which a value matches (defaults to DefaultJudgeThreshold)
+	Threshold float64
+}
+
+// judgement is the response of the judge
+type judgement struct {
+	Similarity float64 `json:"similarity"`
+	Reason     string  `json:"reason"`
+}
+
+// Name implements Metric
+func (m LLMJudge) Name() string {
+	return "similarity"
+}
+
+// Score implements Metric
+func (m LLMJudge) Score(ctx context.Context, field string, observations []Observation) (Score, error) {
+	if m.Provider == nil {
+		return Score{}, fmt.Errorf("LLM judge needs a provider")
+	}
+	threshold := m.Threshold
+	if threshold == 0 {
+		threshold = DefaultJudgeThreshold
+	}
+
+	score := Score{Matched: make([]bool, len(observations))}
+	if len(observations) == 0 {
+		return score, nil
+	}
+	var sum float64
+	for i, o := range observations {
+		if !o.Present {
+			continue
+		}
+		similarity, err := m.judge(ctx, field, o)
+		if err != nil {
+			return Score{}, fmt.Errorf("failed to judge %s of case %s: %w", field, o.CaseID, err)
+		}
+		sum += similarity
+		score.Matched[i] = similarity >= threshold
+	}
+	score.Value = sum / float64(len(observations))
+	return score, nil
+}
+
+// judge asks the provider for the similarity of one value, between 0 and 1
+func (m LLMJudge) judge(ctx context.Context, field string, o Observation) (float64, error) {
+	expected, err := json.Marshal(o.Expected)
+	if err != nil {
+		return 0, err
+	}
+	actual, err := json.Marshal(o.Actual)
+	if err != nil {
+		return 0, err
+	}
+
+	prompt := fmt.Sprintf(`**Role:** You are an impartial evaluator that ONLY outputs valid JSON.
+
+**Objective:** Rate how closely the Actual value of the field "%s" matches the meaning of the Expected value.
+
+**Expected:**
+%s
+
+**Actual:**
+%s
+
+**Instructions:**
+1. Compare the meaning of the two values, ignoring wording, formatting and order.
+2. Rate their similarity from 0.0 (unrelated or contradictory) to 1.0 (the same meaning).
+3. Explain the rating in one short sentence.
+
+**Required JSON Output Structure:**
+{"similarity": 0.8, "reason": "..."}
+
+*** IMPORTANT: Your ENTIRE response must be a single JSON object. ***`, field, expected, actual)
+
+	var result judgement
+	if err := m.Provider.GenerateJSON(ctx, prompt, &result); err != nil {
+		return 0, err
+	}
+	return min(1, max(0, result.Similarity)), nil
+}
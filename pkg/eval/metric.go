@@ -0,0 +1,232 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// DefaultTolerance is the largest difference at which numbers match in the default metrics
+const DefaultTolerance = 0.1
+
+// Observation is one case's expected and actual value of a field
+type Observation struct {
+	CaseID   string
+	Expected interface{}
+	// Actual is the result's value as plain JSON, nil if the result lacks the field or the
+	// case failed
+	Actual interface{}
+	// Present reports whether the result has the field
+	Present bool
+}
+
+// Score is a metric's value for one field
+type Score struct {
+	// Value is the metric's value; higher is better
+	Value float64
+	// Matched reports for each observation whether it counts as a match. Metrics that only
+	// score the field as a whole leave it nil.
+	Matched []bool
+}
+
+// Metric scores one field over every case
+type Metric interface {
+	// Name identifies the metric in a Run, e.g. "exact_match"
+	Name() string
+	// Score scores the observations of a field
+	Score(ctx context.Context, field string, observations []Observation) (Score, error)
+}
+
+// DefaultMetrics returns the metrics of every expected field of the cases: exact match and
+// F1 for label fields (those expected as strings in every case), numeric tolerance for
+// number fields and exact match for anything else
+func DefaultMetrics(cases []Case, tolerance float64) map[string][]Metric {
+	kinds := fieldKinds(cases)
+	metrics := make(map[string][]Metric, len(kinds))
+	for field, kind := range kinds {
+		switch kind {
+		case "string":
+			metrics[field] = []Metric{ExactMatch{}, FieldF1{}}
+		case "number":
+			metrics[field] = []Metric{NumericTolerance{Tolerance: tolerance}}
+		default:
+			metrics[field] = []Metric{ExactMatch{}}
+		}
+	}
+	return metrics
+}
+
+// fieldKinds returns the kind of every expected field of the cases: "string" or "number" if
+// every expected value is one, else empty
+func fieldKinds(cases []Case) map[string]string {
+	kinds := make(map[string]string)
+	for _, c := range cases {
+		for field, value := range c.Expected {
+			kind := ""
+			switch value.(type) {
+			case string:
+				kind = "string"
+			case float64:
+				kind = "number"
+			}
+			if previous, ok := kinds[field]; ok && previous != kind {
+				kind = ""
+			}
+			kinds[field] = kind
+		}
+	}
+	return kinds
+}
+
+// ExactMatch is the fraction of values equal to the expected ones. Strings are compared
+// ignoring surrounding space and, unless CaseSensitive is set, case; lists are compared as
+// sets.
+type ExactMatch struct {
+	CaseSensitive bool
+}
+
+// Name implements Metric
+func (m ExactMatch) Name() string {
+	return "exact_match"
+}
+
+// Score implements Metric
+func (m ExactMatch) Score(_ context.Context, _ string, observations []Observation) (Score, error) {
+	return matchRate(observations, func(expected, actual interface{}) bool {
+		return valuesEqual(expected, actual, m.CaseSensitive, 0)
+	}), nil
+}
+
+// NumericTolerance is the fraction of numbers within Tolerance of the expected ones; values
+// that aren't numbers must be equal as in ExactMatch
+type NumericTolerance struct {
+	Tolerance float64
+}
+
+// Name implements Metric
+func (m NumericTolerance) Name() string {
+	return "within_tolerance"
+}
+
+// Score implements Metric
+func (m NumericTolerance) Score(_ context.Context, _ string, observations []Observation) (Score, error) {
+	return matchRate(observations, func(expected, actual interface{}) bool {
+		return valuesEqual(expected, actual, false, m.Tolerance)
+	}), nil
+}
+
+// FieldF1 is the macro-averaged F1 over the values of a label field, which treats every
+// value expected or predicted as a class. Labels are compared ignoring case and
+// surrounding space.
+type FieldF1 struct{}
+
+// Name implements Metric
+func (m FieldF1) Name() string {
+	return "f1"
+}
+
+// Score implements Metric
+func (m FieldF1) Score(_ context.Context, _ string, observations []Observation) (Score, error) {
+	tp, fp, fn := make(map[string]int), make(map[string]int), make(map[string]int)
+	matched := make([]bool, len(observations))
+	for i, o := range observations {
+		expected, ok := o.Expected.(string)
+		if !ok {
+			continue
+		}
+		expected = normalizeLabel(expected)
+		predicted, ok := o.Actual.(string)
+		if !ok {
+			// A missing prediction only misses the expected class
+			fn[expected]++
+			continue
+		}
+		predicted = normalizeLabel(predicted)
+		if predicted == expected {
+			tp[expected]++
+			matched[i] = true
+			continue
+		}
+		fn[expected]++
+		fp[predicted]++
+	}
+
+	classes := make(map[string]bool)
+	for _, counts := range []map[string]int{tp, fp, fn} {
+		for class := range counts {
+			classes[class] = true
+		}
+	}
+	if len(classes) == 0 {
+		return Score{Matched: matched}, nil
+	}
+	var sum float64
+	for class := range classes {
+		if t := float64(tp[class]); t > 0 {
+			sum += 2 * t / (2*t + float64(fp[class]) + float64(fn[class]))
+		}
+	}
+	return Score{Value: sum / float64(len(classes)), Matched: matched}, nil
+}
+
+// matchRate scores the fraction of observations whose actual value matches the expected one
+func matchRate(observations []Observation, match func(expected, actual interface{}) bool) Score {
+	score := Score{Matched: make([]bool, len(observations))}
+	if len(observations) == 0 {
+		return score
+	}
+	matches := 0
+	for i, o := range observations {
+		if o.Present && match(o.Expected, o.Actual) {
+			score.Matched[i] = true
+			matches++
+		}
+	}
+	score.Value = float64(matches) / float64(len(observations))
+	return score
+}
+
+// valuesEqual compares plain JSON values: strings ignoring surrounding space (and case unless
+// caseSensitive is set), numbers within tolerance and lists as sets
+func valuesEqual(expected, actual interface{}, caseSensitive bool, tolerance float64) bool {
+	switch e := expected.(type) {
+	case string:
+		a, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		if caseSensitive {
+			return strings.TrimSpace(a) == strings.TrimSpace(e)
+		}
+		return normalizeLabel(a) == normalizeLabel(e)
+	case float64:
+		a, ok := actual.(float64)
+		// The epsilon keeps differences such as 0.8 - 0.7 within a tolerance of 0.1
+		return ok && math.Abs(a-e) <= tolerance+1e-9
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		used := make([]bool, len(a))
+	elements:
+		for _, element := range e {
+			for i, candidate := range a {
+				if !used[i] && valuesEqual(element, candidate, caseSensitive, tolerance) {
+					used[i] = true
+					continue elements
+				}
+			}
+			return false
+		}
+		return true
+	default:
+		return reflect.DeepEqual(expected, actual)
+	}
+}
+
+// normalizeLabel makes labels that differ only in case or surrounding space equal
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
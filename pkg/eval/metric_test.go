@@ -0,0 +1,160 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestMatchMetrics(t *testing.T) {
+	list := func(values ...interface{}) []interface{} { return values }
+	tests := []struct {
+		name     string
+		metric   Metric
+		expected interface{}
+		actual   interface{}
+		missing  bool
+		want     bool
+	}{
+		{name: "label ignoring case and space", metric: ExactMatch{}, expected: "positive", actual: " Positive ", want: true},
+		{name: "label case sensitive", metric: ExactMatch{CaseSensitive: true}, expected: "positive", actual: "Positive", want: false},
+		{name: "different label", metric: ExactMatch{}, expected: "positive", actual: "negative", want: false},
+		{name: "missing field", metric: ExactMatch{}, expected: nil, actual: nil, missing: true, want: false},
+		{name: "list as set", metric: ExactMatch{}, expected: list("billing", "urgent"), actual: list("Urgent", "billing"), want: true},
+		{name: "list as set, case sensitive", metric: ExactMatch{CaseSensitive: true}, expected: list("billing", "urgent"), actual: list("Urgent", "billing"), want: false},
+		{name: "list with extra element", metric: ExactMatch{}, expected: list("billing"), actual: list("billing", "urgent"), want: false},
+		{name: "list with repeated element", metric: ExactMatch{}, expected: list("billing", "billing"), actual: list("billing", "urgent"), want: false},
+		{name: "number exactly", metric: ExactMatch{}, expected: 0.8, actual: 0.8, want: true},
+		{name: "number not exactly", metric: ExactMatch{}, expected: 0.8, actual: 0.7, want: false},
+		// 0.8 - 0.7 is slightly more than 0.1 in floating point
+		{name: "number at tolerance", metric: NumericTolerance{Tolerance: 0.1}, expected: 0.8, actual: 0.7, want: true},
+		{name: "number beyond tolerance", metric: NumericTolerance{Tolerance: 0.1}, expected: 0.8, actual: 0.69, want: false},
+		{name: "numbers in list within tolerance", metric: NumericTolerance{Tolerance: 0.1}, expected: list(0.2, 0.9), actual: list(0.85, 0.25), want: true},
+		{name: "number as string", metric: NumericTolerance{Tolerance: 0.1}, expected: 0.8, actual: "0.8", want: false},
+		{name: "object", metric: ExactMatch{}, expected: map[string]interface{}{"a": 1.0}, actual: map[string]interface{}{"a": 1.0}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observation := Observation{CaseID: "1", Expected: tt.expected, Actual: tt.actual, Present: !tt.missing}
+			score, err := tt.metric.Score(context.Background(), "field", []Observation{observation})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if score.Matched[0] != tt.want {
+				t.Errorf("expected match %t, got %t", tt.want, score.Matched[0])
+			}
+			want := 0.0
+			if tt.want {
+				want = 1
+			}
+			if score.Value != want {
+				t.Errorf("expected value %v, got %v", want, score.Value)
+			}
+		})
+	}
+}
+
+func TestFieldF1(t *testing.T) {
+	// observe returns an observation of a label, or of a missing prediction if actual is nil
+	observe := func(expected string, actual interface{}) Observation {
+		return Observation{Expected: expected, Actual: actual, Present: actual != nil}
+	}
+	tests := []struct {
+		name         string
+		observations []Observation
+		want         float64
+		wantMatched  []bool
+	}{
+		{name: "no observations", want: 0, wantMatched: []bool{}},
+		{
+			name:         "all correct",
+			observations: []Observation{observe("positive", "positive"), observe("negative", " Negative")},
+			want:         1,
+			wantMatched:  []bool{true, true},
+		},
+		{
+			// positive: tp 1, fn 1 gives 2/3; negative: fp 1 and no tp gives 0
+			name:         "missing prediction only misses its class",
+			observations: []Observation{observe("positive", "positive"), observe("positive", nil), observe("neutral", "negative")},
+			want:         (2.0 / 3) / 3,
+			wantMatched:  []bool{true, false, false},
+		},
+		{
+			name:         "every prediction missing",
+			observations: []Observation{observe("positive", nil), observe("negative", nil)},
+			want:         0,
+			wantMatched:  []bool{false, false},
+		},
+		{
+			// positive: 2*1/(2+1+0) and negative: 2*1/(2+0+1)
+			name:         "confused classes",
+			observations: []Observation{observe("positive", "positive"), observe("negative", "negative"), observe("negative", "positive")},
+			want:         2.0 / 3,
+			wantMatched:  []bool{true, true, false},
+		},
+		{
+			name:         "non-label expected value is skipped",
+			observations: []Observation{observe("positive", "positive"), {Expected: 0.5, Actual: 0.5, Present: true}},
+			want:         1,
+			wantMatched:  []bool{true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := FieldF1{}.Score(context.Background(), "sentiment", tt.observations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(score.Value-tt.want) > 1e-9 {
+				t.Errorf("expected F1 %v, got %v", tt.want, score.Value)
+			}
+			if len(score.Matched) != len(tt.wantMatched) {
+				t.Fatalf("expected %d matches, got %v", len(tt.wantMatched), score.Matched)
+			}
+			for i, want := range tt.wantMatched {
+				if score.Matched[i] != want {
+					t.Errorf("observation %d: expected match %t, got %t", i, want, score.Matched[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultMetrics(t *testing.T) {
+	cases := []Case{
+		{ID: "1", Expected: map[string]interface{}{"sentiment": "positive", "score": 0.8, "mixed": "high", "tags": []interface{}{"a"}}},
+		{ID: "2", Expected: map[string]interface{}{"sentiment": "negative", "score": 0.1, "mixed": 0.3}},
+	}
+	metrics := DefaultMetrics(cases, DefaultTolerance)
+
+	names := func(field string) []string {
+		var names []string
+		for _, metric := range metrics[field] {
+			names = append(names, metric.Name())
+		}
+		return names
+	}
+	tests := []struct {
+		field string
+		want  []string
+	}{
+		{field: "sentiment", want: []string{"exact_match", "f1"}},
+		{field: "score", want: []string{"within_tolerance"}},
+		{field: "mixed", want: []string{"exact_match"}},
+		{field: "tags", want: []string{"exact_match"}},
+	}
+	for _, tt := range tests {
+		got := names(tt.field)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected metrics %v, got %v", tt.field, tt.want, got)
+			continue
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: expected metrics %v, got %v", tt.field, tt.want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// ProcessFunc processes one item, such as a processor's or pipeline's Process method
+type ProcessFunc func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error)
+
+// Config holds the configuration of an evaluation
+type Config struct {
+	// Name identifies the run, e.g. the processor's name
+	Name string
+	// ResultKey is the ProcessingInfo key holding the result compared with each case's
+	// expected fields. If empty, the whole ProcessingInfo is compared, e.g. for a pipeline
+	// whose expected fields are step results.
+	ResultKey string
+	// Metrics are the metrics of each field. Fields without an entry use DefaultMetrics
+	// with DefaultTolerance.
+	Metrics map[string][]Metric
+	// Workers is the number of cases processed at once (defaults to data.DefaultWorkers)
+	Workers int
+	// OnProgress, if set, is called after each case with the cases done and failed so far
+	OnProgress func(done, failed int)
+}
+
+// Run is the record of an evaluation, which can be saved and used as the baseline of
+// later runs
+type Run struct {
+	Name     string    `json:"name,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Cases    int       `json:"cases"`
+	// Errors is the number of cases the processor failed on
+	Errors int `json:"errors"`
+	// Accuracy is the fraction of cases matching every expected field
+	Accuracy float64 `json:"accuracy"`
+	// F1 is the mean of the fields' F1 metrics, if any field has one
+	F1 *float64 `json:"f1,omitempty"`
+	// Fields are the metric values of each field, by field and metric name
+	Fields map[string]map[string]float64 `json:"fields"`
+	// Results are the outcomes of every case, in dataset order
+	Results []CaseResult `json:"results"`
+}
+
+// CaseResult is the outcome of one case
+type CaseResult struct {
+	ID string `json:"id"`
+	// Passed reports whether the case matched every expected field
+	Passed     bool       `json:"passed"`
+	Error      string     `json:"error,omitempty"`
+	Mismatches []Mismatch `json:"mismatches,omitempty"`
+}
+
+// Mismatch is an expected field a case's result didn't match
+type Mismatch struct {
+	Field    string      `json:"field"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+}
+
+// EvaluateProcessor runs a processor on every case and scores its results. The config's
+// Name and ResultKey default to the processor's name.
+func EvaluateProcessor(ctx context.Context, cases []Case, proc processor.Processor, config Config) (*Run, error) {
+	if config.Name == "" {
+		config.Name = proc.GetName()
+	}
+	if config.ResultKey == "" {
+		config.ResultKey = proc.GetName()
+	}
+	return Evaluate(ctx, cases, proc.Process, config)
+}
+
+// Evaluate processes every case and scores the results. A case the process fails on counts
+// as a mismatch of every expected field; only a failure to read the cases or score a metric,
+// or cancelling ctx, stops the evaluation.
+func Evaluate(ctx context.Context, cases []Case, process ProcessFunc, config Config) (*Run, error) {
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no cases to evaluate")
+	}
+	run := &Run{Name: config.Name, Started: time.Now(), Cases: len(cases)}
+
+	items := make([]*data.ProcessItem, len(cases))
+	index := make(map[string]int, len(cases))
+	for i, c := range cases {
+		if _, ok := index[c.ID]; ok {
+			return nil, fmt.Errorf("more than one case has ID %s", c.ID)
+		}
+		items[i] = c.Item()
+		index[c.ID] = i
+	}
+
+	// Results and errors are kept by case so the run reads in dataset order
+	var mu sync.Mutex
+	actual := make([]map[string]interface{}, len(cases))
+	errs := make([]error, len(cases))
+	stream := data.ProcessStream(ctx, data.NewProcessItemSliceSource(items), config.Workers, func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		i := index[item.ID]
+		result, err := process(ctx, item)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs[i] = err
+			return item, nil
+		}
+		var value interface{} = result.ProcessingInfo
+		if config.ResultKey != "" {
+			value = result.ProcessingInfo[config.ResultKey]
+		}
+		actual[i], _ = normalizeJSON(value).(map[string]interface{})
+		return item, nil
+	})
+
+	done, failed := 0, 0
+	for res := range stream {
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		done++
+		mu.Lock()
+		if errs[index[res.Item.ID]] != nil {
+			failed++
+		}
+		mu.Unlock()
+		if config.OnProgress != nil {
+			config.OnProgress(done, failed)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("evaluation interrupted after %d of %d cases: %w", done, len(cases), err)
+	}
+
+	if err := score(ctx, run, cases, actual, errs, config); err != nil {
+		return nil, err
+	}
+	run.Finished = time.Now()
+	return run, nil
+}
+
+// score fills in the metrics and case results of a run
+func score(ctx context.Context, run *Run, cases []Case, actual []map[string]interface{}, errs []error, config Config) error {
+	metrics := DefaultMetrics(cases, DefaultTolerance)
+	for field, fieldMetrics := range config.Metrics {
+		metrics[field] = fieldMetrics
+	}
+
+	run.Results = make([]CaseResult, len(cases))
+	for i, c := range cases {
+		run.Results[i] = CaseResult{ID: c.ID, Passed: errs[i] == nil}
+		if errs[i] != nil {
+			run.Errors++
+			run.Results[i].Error = errs[i].Error()
+		}
+	}
+
+	run.Fields = make(map[string]map[string]float64)
+	var f1Sum float64
+	f1Count := 0
+	for _, field := range sortedKeys(metrics) {
+		// Only cases expecting the field are observed
+		var (
+			observations []Observation
+			caseIndex    []int
+		)
+		for i, c := range cases {
+			expected, ok := c.Expected[field]
+			if !ok {
+				continue
+			}
+			value, present := actual[i][field]
+			observations = append(observations, Observation{CaseID: c.ID, Expected: expected, Actual: value, Present: present})
+			caseIndex = append(caseIndex, i)
+		}
+		if len(observations) == 0 {
+			continue
+		}
+
+		// The first metric that matches single values decides whether each case matches
+		var decided bool
+		run.Fields[field] = make(map[string]float64)
+		for _, metric := range metrics[field] {
+			result, err := metric.Score(ctx, field, observations)
+			if err != nil {
+				return fmt.Errorf("failed to score %s with %s: %w", field, metric.Name(), err)
+			}
+			run.Fields[field][metric.Name()] = result.Value
+			if _, ok := metric.(FieldF1); ok {
+				f1Sum += result.Value
+				f1Count++
+			}
+			if decided || result.Matched == nil {
+				continue
+			}
+			decided = true
+			for j, matched := range result.Matched {
+				if matched {
+					continue
+				}
+				r := &run.Results[caseIndex[j]]
+				r.Passed = false
+				if r.Error == "" {
+					r.Mismatches = append(r.Mismatches, Mismatch{Field: field, Expected: observations[j].Expected, Actual: observations[j].Actual})
+				}
+			}
+		}
+	}
+
+	passed := 0
+	for _, result := range run.Results {
+		if result.Passed {
+			passed++
+		}
+	}
+	run.Accuracy = float64(passed) / float64(len(cases))
+	if f1Count > 0 {
+		f1 := f1Sum / float64(f1Count)
+		run.F1 = &f1
+	}
+	return nil
+}
+
+// LoadRun reads a run saved with Save
+func LoadRun(path string) (*Run, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run: %w", err)
+	}
+	var run Run
+	if err := json.Unmarshal(raw, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse run %s: %w", path, err)
+	}
+	return &run, nil
+}
+
+// Save writes the run to a file as indented JSON
+func (r *Run) Save(path string) error {
+	encoded, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+	if err := os.WriteFile(path, append(encoded, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+package eval
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// Split is a named share of a dataset, such as a calibration or evaluation set
+type Split struct {
+	Name string `json:"name" yaml:"name"`
+	// Fraction is the split's share of the cases; fractions are relative to their sum
+	Fraction float64 `json:"fraction" yaml:"fraction"`
+}
+
+// SplitConfig configures SplitDataset
+type SplitConfig struct {
+	// Splits are the sets the cases are divided into, e.g. calibration 0.2 and eval 0.8
+	Splits []Split `json:"splits" yaml:"splits"`
+	// StratifyBy are the metadata keys, such as "category" or "language", whose values are
+	// kept in the same proportions in every split
+	StratifyBy []string `json:"stratify_by,omitempty" yaml:"stratify_by,omitempty"`
+	// LengthBuckets, if set, also stratifies by text length: the ascending upper bounds of
+	// the length buckets in characters, e.g. [500, 2000] for short, medium and long texts
+	LengthBuckets []int `json:"length_buckets,omitempty" yaml:"length_buckets,omitempty"`
+	// Size, if positive, samples that many cases from the corpus before splitting, keeping
+	// the strata's proportions
+	Size int `json:"size,omitempty" yaml:"size,omitempty"`
+	// Seed seeds the random sampling, so the same corpus and seed give the same splits
+	Seed int64 `json:"seed,omitempty" yaml:"seed,omitempty"`
+}
+
+// DatasetSplit is the cases of one split, in corpus order
+type DatasetSplit struct {
+	Name  string `json:"name"`
+	Cases []Case `json:"cases"`
+}
+
+// SplitDataset samples cases by stratum, the combination of their StratifyBy metadata
+// values and length bucket, and divides them into the configured splits, so every split
+// has the corpus's mix of categories, lengths and languages. Splits are returned in config
+// order. With a single split, it draws a stratified sample.
+func SplitDataset(cases []Case, config SplitConfig) ([]DatasetSplit, error) {
+	if len(config.Splits) == 0 {
+		return nil, fmt.Errorf("dataset split requires at least one split")
+	}
+	fractions := make([]float64, len(config.Splits))
+	for i, split := range config.Splits {
+		if split.Name == "" || split.Fraction <= 0 {
+			return nil, fmt.Errorf("split %d requires a name and a positive fraction", i+1)
+		}
+		fractions[i] = split.Fraction
+	}
+	if !sort.IntsAreSorted(config.LengthBuckets) {
+		return nil, fmt.Errorf("length buckets must be in ascending order: %v", config.LengthBuckets)
+	}
+
+	// Group the cases by stratum, in a random order within each
+	strata := make(map[string][]int)
+	var keys []string
+	for i, c := range cases {
+		key := config.stratum(c)
+		if _, ok := strata[key]; !ok {
+			keys = append(keys, key)
+		}
+		strata[key] = append(strata[key], i)
+	}
+	sort.Strings(keys)
+	random := rand.New(rand.NewSource(config.Seed))
+	sizes := make([]float64, len(keys))
+	for i, key := range keys {
+		members := strata[key]
+		random.Shuffle(len(members), func(a, b int) { members[a], members[b] = members[b], members[a] })
+		sizes[i] = float64(len(members))
+	}
+
+	// Sample each stratum in proportion to its size
+	if config.Size > 0 && config.Size < len(cases) {
+		for i, n := range allocate(config.Size, sizes, random) {
+			strata[keys[i]] = strata[keys[i]][:n]
+		}
+	}
+
+	// Divide each stratum between the splits. Each case goes to the split furthest below
+	// its share of the cases divided so far, so the remainders of small strata carry over
+	// to the next stratum instead of all going to the first split.
+	assigned := make([][]int, len(config.Splits))
+	divided := 0
+	for _, key := range keys {
+		for _, member := range strata[key] {
+			divided++
+			i := neediest(divided, fractions, assigned, random)
+			assigned[i] = append(assigned[i], member)
+		}
+	}
+
+	splits := make([]DatasetSplit, len(config.Splits))
+	for i, split := range config.Splits {
+		sort.Ints(assigned[i])
+		splits[i] = DatasetSplit{Name: split.Name, Cases: make([]Case, len(assigned[i]))}
+		for j, index := range assigned[i] {
+			splits[i].Cases[j] = cases[index]
+		}
+	}
+	return splits, nil
+}
+
+// stratum returns the key of a case's stratum
+func (c SplitConfig) stratum(example Case) string {
+	parts := make([]string, 0, len(c.StratifyBy)+1)
+	for _, key := range c.StratifyBy {
+		value := ""
+		if v, ok := example.Metadata[key]; ok && v != nil {
+			value = strings.ToLower(strings.TrimSpace(fmt.Sprint(v)))
+		}
+		parts = append(parts, key+"="+value)
+	}
+	if len(c.LengthBuckets) > 0 {
+		length := len([]rune(example.Text))
+		bucket := sort.SearchInts(c.LengthBuckets, length)
+		parts = append(parts, "length="+strconv.Itoa(bucket))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// allocate divides n between weights by the largest remainder method, so the counts sum
+// to n; ties go to weights in random order
+func allocate(n int, weights []float64, random *rand.Rand) []int {
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+	counts := make([]int, len(weights))
+	remainders := make([]float64, len(weights))
+	left := n
+	for i, weight := range weights {
+		share := float64(n) * weight / total
+		counts[i] = int(math.Floor(share))
+		remainders[i] = share - float64(counts[i])
+		left -= counts[i]
+	}
+	order := random.Perm(len(weights))
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for i := 0; i < left; i++ {
+		counts[order[i%len(order)]]++
+	}
+	return counts
+}
+
+// neediest returns the split whose count is furthest below its share of the first divided
+// cases, choosing at random between ties
+func neediest(divided int, fractions []float64, assigned [][]int, random *rand.Rand) int {
+	var total float64
+	for _, fraction := range fractions {
+		total += fraction
+	}
+	best, ties := -1, 0
+	var bestDeficit float64
+	for i, fraction := range fractions {
+		deficit := float64(divided)*fraction/total - float64(len(assigned[i]))
+		switch {
+		case best < 0 || deficit > bestDeficit+1e-9:
+			best, bestDeficit, ties = i, deficit, 1
+		case deficit > bestDeficit-1e-9:
+			// Keep each tied split with equal probability
+			ties++
+			if random.Intn(ties) == 0 {
+				best = i
+			}
+		}
+	}
+	return best
+}
+
+// CasesFromItems turns the items of a corpus into cases to label. If processor is set, the
+// listed fields of its result are copied into each case's expected fields as pre-labels for
+// annotators to review; otherwise the cases have no expected fields yet.
+func CasesFromItems(items []*data.ProcessItem, processor string, fields []string) []Case {
+	cases := make([]Case, 0, len(items))
+	for _, item := range items {
+		c := Case{ID: item.ID, Expected: make(map[string]interface{}), Metadata: make(map[string]interface{})}
+		if text, ok := item.Metadata["original_text"].(string); ok {
+			c.Text = text
+		} else {
+			c.Text, _ = item.GetTextForProcessing()
+		}
+		for key, value := range item.Metadata {
+			if key != "original_text" {
+				c.Metadata[key] = value
+			}
+		}
+		if processor != "" {
+			result, _ := normalizeJSON(item.ProcessingInfo[processor]).(map[string]interface{})
+			for _, field := range fields {
+				if value, ok := result[field]; ok {
+					c.Expected[field] = value
+				}
+			}
+		}
+		cases = append(cases, c)
+	}
+	return cases
+}
+
+// SaveDataset writes cases to a JSON Lines (.jsonl, .ndjson) or CSV (.csv) file in the
+// format LoadDataset reads. Cases without expected fields are written for labeling: in
+// JSON Lines with an empty expected object, in CSV with empty cells in the expected columns,
+// which are those of the fields any case has.
+func SaveDataset(path string, cases []Case, config DatasetConfig) error {
+	config = config.withDefaults()
+	var write func(*os.File, []Case, DatasetConfig) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		write = writeJSONLCases
+	case ".csv":
+		write = writeCSVCases
+	default:
+		return fmt.Errorf("unsupported dataset format: %s", filepath.Ext(path))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create dataset: %w", err)
+	}
+	defer file.Close()
+	if err := write(file, cases, config); err != nil {
+		return fmt.Errorf("failed to write dataset %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+// writeJSONLCases writes one object per case, with its metadata as top-level fields
+func writeJSONLCases(file *os.File, cases []Case, config DatasetConfig) error {
+	encoder := json.NewEncoder(file)
+	for _, c := range cases {
+		record := make(map[string]interface{}, len(c.Metadata)+3)
+		for key, value := range c.Metadata {
+			record[key] = value
+		}
+		expected := c.Expected
+		if expected == nil {
+			expected = map[string]interface{}{}
+		}
+		record[config.IDField] = c.ID
+		record[config.TextField] = c.Text
+		record[config.ExpectedField] = expected
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSVCases writes a row per case, with columns for the ID, the text, every metadata
+// key and every expected field of any case
+func writeCSVCases(file *os.File, cases []Case, config DatasetConfig) error {
+	metadataKeys := make(map[string]bool)
+	expectedKeys := make(map[string]bool)
+	for _, c := range cases {
+		for key := range c.Metadata {
+			metadataKeys[key] = true
+		}
+		for key := range c.Expected {
+			expectedKeys[key] = true
+		}
+	}
+	metadata, expected := sortedKeys(metadataKeys), sortedKeys(expectedKeys)
+
+	writer := csv.NewWriter(file)
+	header := []string{config.IDField, config.TextField}
+	header = append(header, metadata...)
+	for _, key := range expected {
+		header = append(header, config.ExpectedField+"."+key)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, c := range cases {
+		row := []string{c.ID, c.Text}
+		for _, key := range metadata {
+			row = append(row, csvCell(c.Metadata[key]))
+		}
+		for _, key := range expected {
+			row = append(row, csvCell(c.Expected[key]))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvCell renders a value as a CSV cell: strings as is and other values as JSON, which
+// LoadCSV decodes
+func csvCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
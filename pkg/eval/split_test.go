@@ -0,0 +1,215 @@
+package eval
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// categoryCases returns cases with the category metadata of each count, numbered in order
+func categoryCases(counts map[string]int, categories ...string) []Case {
+	var cases []Case
+	for _, category := range categories {
+		for i := 0; i < counts[category]; i++ {
+			cases = append(cases, Case{
+				ID:       fmt.Sprintf("%s-%d", category, i),
+				Text:     strings.Repeat("x", i+1),
+				Metadata: map[string]interface{}{"category": category},
+			})
+		}
+	}
+	return cases
+}
+
+// splitCounts returns the number of cases of each category in each split
+func splitCounts(splits []DatasetSplit) map[string]map[string]int {
+	counts := make(map[string]map[string]int, len(splits))
+	for _, split := range splits {
+		counts[split.Name] = make(map[string]int)
+		for _, c := range split.Cases {
+			counts[split.Name][c.Metadata["category"].(string)]++
+		}
+	}
+	return counts
+}
+
+func TestSplitDataset(t *testing.T) {
+	halves := []Split{{Name: "calibration", Fraction: 1}, {Name: "eval", Fraction: 1}}
+	calibrationEval := []Split{{Name: "calibration", Fraction: 0.2}, {Name: "eval", Fraction: 0.8}}
+	singletons := make(map[string]int)
+	var singletonCategories []string
+	for i := 0; i < 10; i++ {
+		category := fmt.Sprintf("c%d", i)
+		singletons[category] = 1
+		singletonCategories = append(singletonCategories, category)
+	}
+
+	tests := []struct {
+		name   string
+		cases  []Case
+		config SplitConfig
+		// want are the number of cases of each category in each split
+		want map[string]map[string]int
+		// wantSizes are the number of cases in each split, checked instead of want
+		wantSizes []int
+	}{
+		{
+			name:   "stratified proportions",
+			cases:  categoryCases(map[string]int{"a": 60, "b": 40}, "a", "b"),
+			config: SplitConfig{Splits: calibrationEval, StratifyBy: []string{"category"}},
+			want: map[string]map[string]int{
+				"calibration": {"a": 12, "b": 8},
+				"eval":        {"a": 48, "b": 32},
+			},
+		},
+		{
+			name:   "sampled size",
+			cases:  categoryCases(map[string]int{"a": 60, "b": 40}, "a", "b"),
+			config: SplitConfig{Splits: calibrationEval, StratifyBy: []string{"category"}, Size: 50},
+			want: map[string]map[string]int{
+				"calibration": {"a": 6, "b": 4},
+				"eval":        {"a": 24, "b": 16},
+			},
+		},
+		{
+			name:      "single-member strata split evenly",
+			cases:     categoryCases(singletons, singletonCategories...),
+			config:    SplitConfig{Splits: halves, StratifyBy: []string{"category"}},
+			wantSizes: []int{5, 5},
+		},
+		{
+			name:      "small strata carry remainders",
+			cases:     categoryCases(map[string]int{"a": 3, "b": 3, "c": 3, "d": 1}, "a", "b", "c", "d"),
+			config:    SplitConfig{Splits: calibrationEval, StratifyBy: []string{"category"}},
+			wantSizes: []int{2, 8},
+		},
+		{
+			name:      "sample without strata",
+			cases:     categoryCases(map[string]int{"a": 60, "b": 40}, "a", "b"),
+			config:    SplitConfig{Splits: []Split{{Name: "sample", Fraction: 1}}, Size: 25},
+			wantSizes: []int{25},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			splits, err := SplitDataset(tt.cases, tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(splits) != len(tt.config.Splits) {
+				t.Fatalf("expected %d splits, got %d", len(tt.config.Splits), len(splits))
+			}
+			for i, split := range splits {
+				if split.Name != tt.config.Splits[i].Name {
+					t.Errorf("split %d: expected %s, got %s", i, tt.config.Splits[i].Name, split.Name)
+				}
+			}
+			if tt.want != nil {
+				if got := splitCounts(splits); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("expected counts %v, got %v", tt.want, got)
+				}
+			}
+			for i, size := range tt.wantSizes {
+				if len(splits[i].Cases) != size {
+					t.Errorf("split %s: expected %d cases, got %d", splits[i].Name, size, len(splits[i].Cases))
+				}
+			}
+
+			// Every case is in at most one split, in corpus order
+			order := make(map[string]int, len(tt.cases))
+			for i, c := range tt.cases {
+				order[c.ID] = i
+			}
+			seen := make(map[string]bool)
+			for _, split := range splits {
+				for j, c := range split.Cases {
+					if seen[c.ID] {
+						t.Errorf("case %s is in more than one split", c.ID)
+					}
+					seen[c.ID] = true
+					if j > 0 && order[c.ID] < order[split.Cases[j-1].ID] {
+						t.Errorf("split %s: case %s is out of corpus order", split.Name, c.ID)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSplitDatasetLengthBuckets(t *testing.T) {
+	// 20 cases in each bucket; a sample of 30 takes 10 from each
+	cases := categoryCases(map[string]int{"a": 60}, "a")
+	splits, err := SplitDataset(cases, SplitConfig{
+		Splits:        []Split{{Name: "sample", Fraction: 1}},
+		LengthBuckets: []int{20, 40},
+		Size:          30,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buckets := make([]int, 3)
+	for _, c := range splits[0].Cases {
+		buckets[(len(c.Text)-1)/20]++
+	}
+	if !reflect.DeepEqual(buckets, []int{10, 10, 10}) {
+		t.Errorf("expected 10 cases of each length bucket, got %v", buckets)
+	}
+}
+
+func TestSplitDatasetSeed(t *testing.T) {
+	cases := categoryCases(map[string]int{"a": 60, "b": 40}, "a", "b")
+	// ids returns the case IDs of each split made with a seed
+	ids := func(seed int64) [][]string {
+		splits, err := SplitDataset(cases, SplitConfig{
+			Splits:     []Split{{Name: "calibration", Fraction: 0.2}, {Name: "eval", Fraction: 0.8}},
+			StratifyBy: []string{"category"},
+			Size:       50,
+			Seed:       seed,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := make([][]string, len(splits))
+		for i, split := range splits {
+			for _, c := range split.Cases {
+				result[i] = append(result[i], c.ID)
+			}
+		}
+		return result
+	}
+
+	if first, second := ids(7), ids(7); !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same seed to give the same splits, got %v and %v", first, second)
+	}
+	if first, other := ids(7), ids(8); reflect.DeepEqual(first, other) {
+		t.Error("expected different seeds to give different splits")
+	}
+}
+
+func TestSplitDatasetErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SplitConfig
+		wantErr string
+	}{
+		{name: "no splits", wantErr: "at least one split"},
+		{name: "unnamed split", config: SplitConfig{Splits: []Split{{Fraction: 1}}}, wantErr: "split 1 requires a name"},
+		{name: "zero fraction", config: SplitConfig{Splits: []Split{{Name: "eval"}}}, wantErr: "split 1 requires a name and a positive fraction"},
+		{
+			name:    "unsorted length buckets",
+			config:  SplitConfig{Splits: []Split{{Name: "eval", Fraction: 1}}, LengthBuckets: []int{40, 20}},
+			wantErr: "ascending order",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SplitDataset(categoryCases(map[string]int{"a": 2}, "a"), tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
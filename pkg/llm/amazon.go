@@ -21,7 +21,7 @@ func NewAmazonProvider(config Config) (*AmazonProvider, error) {
 
 	if config.Model == "" {
 		// Set a default model if none specified
-		config.Model = "anthropic.claude-v2"
+		config.Model = DefaultModel(Amazon)
 	}
 
 	return &AmazonProvider{
@@ -34,7 +34,9 @@ func NewAmazonProvider(config Config) (*AmazonProvider, error) {
 func (p *AmazonProvider) Generate(ctx context.Context, prompt string) (string, error) {
 	// In a real implementation, this would call the Amazon Bedrock API
 	// This is a placeholder implementation
-	return fmt.Sprintf("Amazon Bedrock response to: %s", prompt), nil
+	response := fmt.Sprintf("Amazon Bedrock response to: %s", prompt)
+	streamWhole(ctx, response)
+	return response, nil
 }
 
 // GenerateJSON implements the Provider interface
@@ -43,8 +45,8 @@ func (p *AmazonProvider) GenerateJSON(ctx context.Context, prompt string, respon
 	// 1. Call the Amazon Bedrock API with JSON formatting instructions
 	// 2. Parse the response into the provided struct
 
-	// Placeholder implementation
-	_, err := p.Generate(ctx, prompt)
+	// Placeholder implementation; only the JSON response is streamed
+	_, err := p.Generate(WithStream(ctx, nil), prompt)
 	if err != nil {
 		return err
 	}
@@ -52,6 +54,8 @@ func (p *AmazonProvider) GenerateJSON(ctx context.Context, prompt string, respon
 	// Pretend we got valid JSON
 	mockJSON := `{"result": "Success", "data": "Sample data from Amazon Bedrock"}`
 
+	streamWhole(ctx, mockJSON)
+
 	// If debug is enabled, wrap the response with debug info
 	if p.config.IsDebugEnabled() {
 		if err := WrapWithDebugInfo(ctx, p.config, prompt, mockJSON, responseStruct); err != nil {
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Cache stores provider responses by key
+type Cache interface {
+	// Get returns the value stored for key, if any
+	Get(key string) ([]byte, bool)
+	// Set stores a value for key
+	Set(key string, value []byte) error
+}
+
+// DirectoryCache is a Cache storing each response as a file in a directory, so cached
+// responses survive restarts and can be shared between processes
+type DirectoryCache struct {
+	dir string
+}
+
+// NewDirectoryCache creates a cache in dir, creating the directory if needed
+func NewDirectoryCache(dir string) (*DirectoryCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DirectoryCache{dir: dir}, nil
+}
+
+// Get implements Cache
+func (c *DirectoryCache) Get(key string) ([]byte, bool) {
+	value, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Set implements Cache. The file is written atomically so concurrent readers never see
+// a partial response.
+func (c *DirectoryCache) Set(key string, value []byte) error {
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// path returns the file holding the value for key
+func (c *DirectoryCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// cachedProvider answers repeated prompts from a cache
+type cachedProvider struct {
+	Provider
+	cache Cache
+}
+
+// WithCache wraps a provider so responses are stored in cache and repeated prompts with the
+// same provider type, model, max tokens, temperature and debug mode are answered without
+// calling the LLM. Failures to store a response are ignored.
+func WithCache(provider Provider, cache Cache) Provider {
+	return &cachedProvider{Provider: provider, cache: cache}
+}
+
+// Generate implements Provider
+func (p *cachedProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	key := p.key(ctx, "text", prompt)
+	if value, ok := p.cache.Get(key); ok {
+		var response string
+		if err := json.Unmarshal(value, &response); err == nil {
+			streamWhole(ctx, response)
+			return response, nil
+		}
+	}
+
+	response, err := p.Provider.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	if value, err := json.Marshal(response); err == nil {
+		_ = p.cache.Set(key, value)
+	}
+	return response, nil
+}
+
+// GenerateJSON implements Provider
+func (p *cachedProvider) GenerateJSON(ctx context.Context, prompt string, responseStruct interface{}) error {
+	key := p.key(ctx, "json", prompt)
+	if value, ok := p.cache.Get(key); ok {
+		if err := json.Unmarshal(value, responseStruct); err == nil {
+			streamWhole(ctx, string(value))
+			return nil
+		}
+	}
+
+	if err := p.Provider.GenerateJSON(ctx, prompt, responseStruct); err != nil {
+		return err
+	}
+	if value, err := json.Marshal(responseStruct); err == nil {
+		_ = p.cache.Set(key, value)
+	}
+	return nil
+}
+
+// unwrap implements wrapper
+func (p *cachedProvider) unwrap() Provider {
+	return p.Provider
+}
+
+// withModel implements modelSwitcher
+func (p *cachedProvider) withModel(model string) (Provider, error) {
+	provider, err := WithModel(p.Provider, model)
+	if err != nil {
+		return nil, err
+	}
+	return WithCache(provider, p.cache), nil
+}
+
+// key returns the cache key for a prompt and the settings that affect its response
+func (p *cachedProvider) key(ctx context.Context, kind, prompt string) string {
+	config := p.GetConfig()
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%d\x00%g\x00%t\x00%s\x00%s", p.GetType(), config.Model, config.MaxTokens,
+		config.Temperature, config.IsDebugEnabled(), kind, prompt)
+	if limits, ok := GenerationLimitsFromContext(ctx); ok {
+		fmt.Fprintf(hash, "\x00%d\x00%q", limits.MaxOutputTokens, limits.StopSequences)
+		if limits.Temperature != nil {
+			fmt.Fprintf(hash, "\x00%g", *limits.Temperature)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,90 @@
+package llm
+
+import "context"
+
+// Capabilities are the optional features a provider supports
+type Capabilities struct {
+	// JSONMode means GenerateJSON constrains responses to valid JSON natively
+	JSONMode bool `json:"json_mode"`
+	// JSONSchema means GenerateJSON constrains responses to the schema of the context's
+	// StructuredOutput natively
+	JSONSchema bool `json:"json_schema"`
+	// ToolCalling means GenerateJSON can return the response as the arguments of a tool
+	// declared with the context's StructuredOutput
+	ToolCalling bool `json:"tool_calling"`
+}
+
+// CapabilityReporter is implemented by providers reporting their capabilities. Providers
+// that don't implement it support none.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities of a provider, looking through wrappers such as
+// those returned by WithRetry
+func CapabilitiesOf(provider Provider) Capabilities {
+	for {
+		if reporter, ok := provider.(CapabilityReporter); ok {
+			return reporter.Capabilities()
+		}
+		w, ok := provider.(wrapper)
+		if !ok {
+			return Capabilities{}
+		}
+		provider = w.unwrap()
+	}
+}
+
+// StructuredOutputMode is how a provider is asked for structured output
+type StructuredOutputMode string
+
+const (
+	// StructuredOutputPrompt asks for JSON in the prompt only and parses it from the text
+	// response
+	StructuredOutputPrompt StructuredOutputMode = "prompt"
+	// StructuredOutputJSONMode uses the provider's JSON mode
+	StructuredOutputJSONMode StructuredOutputMode = "json_mode"
+	// StructuredOutputJSONSchema constrains the response to a JSON Schema
+	StructuredOutputJSONSchema StructuredOutputMode = "json_schema"
+	// StructuredOutputToolCalling returns the response as the arguments of a tool call
+	StructuredOutputToolCalling StructuredOutputMode = "tool_calling"
+)
+
+// StructuredOutputMode returns the most constrained structured output mode the
+// capabilities allow: a JSON Schema, then tool calling, then JSON mode, then the prompt
+func (c Capabilities) StructuredOutputMode() StructuredOutputMode {
+	switch {
+	case c.JSONSchema:
+		return StructuredOutputJSONSchema
+	case c.ToolCalling:
+		return StructuredOutputToolCalling
+	case c.JSONMode:
+		return StructuredOutputJSONMode
+	default:
+		return StructuredOutputPrompt
+	}
+}
+
+// StructuredOutput describes the structured response wanted from GenerateJSON
+type StructuredOutput struct {
+	Mode StructuredOutputMode
+	// Name names the response, e.g. the tool called in tool calling mode
+	Name string
+	// Schema is the JSON Schema of the response
+	Schema map[string]interface{}
+}
+
+// structuredOutputKey is the context key of the structured output wanted
+type structuredOutputKey struct{}
+
+// WithStructuredOutput returns a context asking providers for structured output in the
+// given mode. Providers use the modes their Capabilities report and ignore the others.
+func WithStructuredOutput(ctx context.Context, output StructuredOutput) context.Context {
+	return context.WithValue(ctx, structuredOutputKey{}, output)
+}
+
+// StructuredOutputFromContext returns the structured output wanted by ctx, if any
+func StructuredOutputFromContext(ctx context.Context) (StructuredOutput, bool) {
+	output, ok := ctx.Value(structuredOutputKey{}).(StructuredOutput)
+	return output, ok
+}
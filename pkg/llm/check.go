@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+)
+
+// Checker is implemented by providers that can verify they are reachable and their model
+// exists without generating anything
+type Checker interface {
+	// Check returns an error if the provider can't be reached or its model doesn't exist
+	Check(ctx context.Context) error
+}
+
+// wrapper is implemented by providers wrapping another provider, such as those returned by
+// WithRetry, so Check reaches the provider underneath
+type wrapper interface {
+	unwrap() Provider
+}
+
+// Check verifies that a provider is reachable and its model exists. Providers that can't be
+// checked, such as the placeholder providers, pass.
+func Check(ctx context.Context, provider Provider) error {
+	for {
+		if checker, ok := provider.(Checker); ok {
+			return checker.Check(ctx)
+		}
+		w, ok := provider.(wrapper)
+		if !ok {
+			return nil
+		}
+		provider = w.unwrap()
+	}
+}
+
+// validationPrompt is the prompt Validate sends to providers that can't be checked
+const validationPrompt = "Reply with OK."
+
+// Validate verifies that a provider's API key and model work before it is used, so
+// misconfiguration fails immediately instead of on the first call of a batch. Providers
+// implementing Checker are checked, which generates nothing; others are sent a minimal
+// prompt, directly so it is neither cached, retried nor logged by any wrappers.
+func Validate(ctx context.Context, provider Provider) error {
+	for {
+		if checker, ok := provider.(Checker); ok {
+			if err := checker.Check(ctx); err != nil {
+				return fmt.Errorf("%s provider failed validation: %w", provider.GetType(), err)
+			}
+			return nil
+		}
+		w, ok := provider.(wrapper)
+		if !ok {
+			break
+		}
+		provider = w.unwrap()
+	}
+	if _, err := provider.Generate(ctx, validationPrompt); err != nil {
+		return fmt.Errorf("%s provider failed validation: %w", provider.GetType(), err)
+	}
+	return nil
+}
+
+// Check implements Checker by looking up the model
+func (p *GoogleProvider) Check(ctx context.Context) error {
+	if _, err := p.client.Models.Get(ctx, p.config.Model, nil); err != nil {
+		return fmt.Errorf("model %s is not available: %w", p.config.Model, err)
+	}
+	return nil
+}
@@ -22,8 +22,17 @@ func NewProviderClient(provider Provider) *ProviderClient {
 	}
 }
 
-// Complete implements the Client interface
+// Complete implements the Client interface. The "max_output_tokens", "stop_sequences" and
+// "temperature" options bound and tune the response, see GenerationLimits.
 func (c *ProviderClient) Complete(ctx context.Context, prompt string, options map[string]interface{}) (interface{}, error) {
+	limits, err := generationLimitsFromOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if !limits.IsZero() {
+		ctx = WithGenerationLimits(ctx, limits)
+	}
+
 	// If options specify JSON output
 	if jsonOutput, ok := options["json_output"].(bool); ok && jsonOutput {
 		var responseData interface{}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrContextLength is matched by errors.Is for calls rejected because the prompt exceeds
+// the model's context length
+var ErrContextLength = errors.New("prompt exceeds the model's context length")
+
+// ContextLengthError is the error of a call rejected because the prompt exceeds the
+// model's context length. Retrying the same prompt can't succeed.
+type ContextLengthError struct {
+	// Err is the provider's error
+	Err error
+}
+
+// Error implements the error interface
+func (e *ContextLengthError) Error() string {
+	return "context length exceeded: " + e.Err.Error()
+}
+
+// Unwrap returns the provider's error
+func (e *ContextLengthError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes ContextLengthErrors match ErrContextLength
+func (e *ContextLengthError) Is(target error) bool {
+	return target == ErrContextLength
+}
+
+// contextLengthMessages are fragments of the messages providers reject over-long prompts with
+var contextLengthMessages = []string{
+	"context length",
+	"context window",
+	"context_length_exceeded",
+	"maximum context",
+	"exceeds the maximum number of tokens",
+	"input token count",
+	"prompt is too long",
+	"too many tokens",
+}
+
+// classifyError returns err as a ContextLengthError if its message says the prompt exceeds
+// the model's context length, and err unchanged otherwise
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, ErrContextLength) {
+		return err
+	}
+	message := strings.ToLower(err.Error())
+	for _, fragment := range contextLengthMessages {
+		if strings.Contains(message, fragment) {
+			return &ContextLengthError{Err: err}
+		}
+	}
+	return err
+}
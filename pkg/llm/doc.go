@@ -16,6 +16,8 @@ Core components:
   - OpenAI (openai.go): Implementation for OpenAI's GPT models
   - Groq (groq.go): Implementation for Groq's models
   - Amazon (amazon.go): Implementation for Amazon Bedrock
+  - Mock (mock.go): Canned responses for tests and offline runs
+  - Replay (replay.go): Responses recorded in an interaction log
 
 3. Configuration:
   - Config: Standardized configuration for all providers
@@ -24,6 +26,22 @@ Core components:
 4. Utilities:
   - ExtractJSONResponse: Handling JSON responses from LLMs
   - WrapWithDebugInfo: Adding debug information to responses
+  - WithModel: Switching a provider to another model
+  - WithStream / GenerateStream (stream.go): Receiving responses chunk by chunk as they are generated
+  - Check / Checker (check.go): Verifying a provider is reachable and its model exists
+  - Validate (check.go): Verifying a provider's API key and model before it is used
+  - Capabilities / WithStructuredOutput (capabilities.go): Provider features and native structured output
+  - WithGenerationLimits (generation.go): Maximum output tokens and stop sequences of calls
+  - ContextLengthError / ErrContextLength (context_length.go): Prompts exceeding the model's context length
+  - Embedder / EmbedderOf / CosineSimilarity (embed.go): Embedding texts as vectors and comparing them
+
+5. Wrappers:
+  - WithRetry (retry.go): Retrying failed calls with exponential backoff
+  - WithTimeout (retry.go): Limiting the duration of each call
+  - WithCache / DirectoryCache (cache.go): Answering repeated prompts from a cache
+  - WithInteractionLog / InteractionSink (interaction.go): Recording every call as JSON Lines
+  - ProviderPool (pool.go): Per-tenant providers with metered budgets
+  - WithMetering / EstimateTokens / EstimateCost (usage.go): Estimating the tokens and cost of calls
 
 To use an LLM provider, create it with the appropriate configuration and use
 the Provider interface methods to interact with it.
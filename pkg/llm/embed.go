@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"google.golang.org/genai"
+)
+
+// Embedder is implemented by providers that can embed texts as vectors, e.g. to find
+// results that mean the same thing although they are worded differently
+type Embedder interface {
+	// Embed returns a vector for each text, in order
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// EmbedderOf returns the Embedder of a provider, looking through wrappers such as those
+// returned by WithRetry, or false if the provider can't embed
+func EmbedderOf(provider Provider) (Embedder, bool) {
+	for {
+		if embedder, ok := provider.(Embedder); ok {
+			return embedder, true
+		}
+		w, ok := provider.(wrapper)
+		if !ok {
+			return nil, false
+		}
+		provider = w.unwrap()
+	}
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, from -1 to 1, or 0 if
+// either is zero or their lengths differ
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// DefaultGoogleEmbeddingModel is the model GoogleProvider embeds with unless the
+// "embedding_model" option is set
+const DefaultGoogleEmbeddingModel = "text-embedding-004"
+
+// googleEmbedBatch is the most texts the Gemini API embeds per request
+const googleEmbedBatch = 100
+
+// Embed implements Embedder with the "embedding_model" option's model, or
+// DefaultGoogleEmbeddingModel
+func (p *GoogleProvider) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	model, _ := p.config.Options["embedding_model"].(string)
+	if model == "" {
+		model = DefaultGoogleEmbeddingModel
+	}
+
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += googleEmbedBatch {
+		end := min(start+googleEmbedBatch, len(texts))
+		contents := make([]*genai.Content, 0, end-start)
+		for _, text := range texts[start:end] {
+			contents = append(contents, genai.NewContentFromText(text, genai.RoleUser))
+		}
+
+		response, err := p.client.Models.EmbedContent(ctx, model, contents, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Google API embed error: %w", err)
+		}
+		if len(response.Embeddings) != end-start {
+			return nil, fmt.Errorf("Google API returned %d embeddings for %d texts", len(response.Embeddings), end-start)
+		}
+		for _, embedding := range response.Embeddings {
+			vector := make([]float64, len(embedding.Values))
+			for i, value := range embedding.Values {
+				vector[i] = float64(value)
+			}
+			vectors = append(vectors, vector)
+		}
+	}
+	return vectors, nil
+}
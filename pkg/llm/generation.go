@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// GenerationLimits bound and tune the response of an LLM call
+type GenerationLimits struct {
+	// MaxOutputTokens is the most tokens the response may have (0 for the provider's limit)
+	MaxOutputTokens int
+	// StopSequences end the response where the model generates any of them; the sequence
+	// itself is not part of the response
+	StopSequences []string
+	// Temperature, if set, overrides the provider's configured temperature
+	Temperature *float64
+}
+
+// IsZero reports whether the limits leave the response unbounded
+func (l GenerationLimits) IsZero() bool {
+	return l.MaxOutputTokens <= 0 && len(l.StopSequences) == 0 && l.Temperature == nil
+}
+
+// generationLimitsKey is the context key of the generation limits of LLM calls
+type generationLimitsKey struct{}
+
+// WithGenerationLimits returns a context whose LLM calls are bounded by limits. Providers
+// that can't enforce a limit natively ignore it.
+func WithGenerationLimits(ctx context.Context, limits GenerationLimits) context.Context {
+	return context.WithValue(ctx, generationLimitsKey{}, limits)
+}
+
+// GenerationLimitsFromContext returns the generation limits set by WithGenerationLimits, if any
+func GenerationLimitsFromContext(ctx context.Context) (GenerationLimits, bool) {
+	limits, ok := ctx.Value(generationLimitsKey{}).(GenerationLimits)
+	return limits, ok && !limits.IsZero()
+}
+
+// generationLimitsFromOptions reads the "max_output_tokens", "stop_sequences" and
+// "temperature" LLM options, as set directly or decoded from YAML or JSON configuration
+func generationLimitsFromOptions(options map[string]interface{}) (GenerationLimits, error) {
+	var limits GenerationLimits
+	switch v := options["max_output_tokens"].(type) {
+	case nil:
+	case int:
+		limits.MaxOutputTokens = v
+	case int64:
+		limits.MaxOutputTokens = int(v)
+	case float64:
+		limits.MaxOutputTokens = int(v)
+	default:
+		return limits, fmt.Errorf("invalid max_output_tokens option: %v", v)
+	}
+
+	switch v := options["stop_sequences"].(type) {
+	case nil:
+	case string:
+		limits.StopSequences = []string{v}
+	case []string:
+		limits.StopSequences = v
+	case []interface{}:
+		for _, sequence := range v {
+			s, ok := sequence.(string)
+			if !ok {
+				return limits, fmt.Errorf("invalid stop sequence: %v", sequence)
+			}
+			limits.StopSequences = append(limits.StopSequences, s)
+		}
+	default:
+		return limits, fmt.Errorf("invalid stop_sequences option: %v", v)
+	}
+
+	switch v := options["temperature"].(type) {
+	case nil:
+	case float64:
+		limits.Temperature = &v
+	case int:
+		temperature := float64(v)
+		limits.Temperature = &temperature
+	default:
+		return limits, fmt.Errorf("invalid temperature option: %v", v)
+	}
+	return limits, nil
+}
+
+// applyGenerationLimits cuts a response at the first stop sequence and at the output token
+// limit, estimated at four characters per token, for providers that can't enforce them
+func applyGenerationLimits(ctx context.Context, response string) string {
+	limits, ok := GenerationLimitsFromContext(ctx)
+	if !ok {
+		return response
+	}
+	for _, sequence := range limits.StopSequences {
+		if i := strings.Index(response, sequence); sequence != "" && i >= 0 {
+			response = response[:i]
+		}
+	}
+	if limits.MaxOutputTokens > 0 && len(response) > limits.MaxOutputTokens*4 {
+		response = response[:limits.MaxOutputTokens*4]
+	}
+	return response
+}
@@ -29,7 +29,7 @@ func NewGoogleProvider(config Config) (*GoogleProvider, error) {
 
 	if config.Model == "" {
 		// Set a default model if none specified
-		config.Model = "gemini-1.0-pro"
+		config.Model = DefaultModel(Google)
 	}
 
 	// Initialize the Google GenAI client
@@ -51,13 +51,12 @@ func NewGoogleProvider(config Config) (*GoogleProvider, error) {
 // Generate implements the Provider interface
 func (p *GoogleProvider) Generate(ctx context.Context, prompt string) (string, error) {
 	// Call the GenerateContent method with the prompt
-	result, err := p.client.Models.GenerateContent(ctx, p.config.Model, genai.Text(prompt), nil)
+	response, err := p.generateContent(ctx, prompt, &genai.GenerateContentConfig{})
 	if err != nil {
 		return "", fmt.Errorf("Google API generate error: %w", err)
 	}
 
-	// Extract and return the text response
-	return result.Text(), nil
+	return response, nil
 }
 
 // GenerateJSON implements the Provider interface
@@ -74,15 +73,25 @@ func (p *GoogleProvider) GenerateJSON(ctx context.Context, prompt string, respon
 		SystemInstruction: jsonInstruction,
 	}
 
+	// Constrain the response natively if the caller asks for structured output
+	if output, ok := StructuredOutputFromContext(ctx); ok {
+		switch output.Mode {
+		case StructuredOutputJSONSchema:
+			config.ResponseMIMEType = "application/json"
+			if schema, ok := googleSchema(output.Schema); ok {
+				config.ResponseSchema = schema
+			}
+		case StructuredOutputJSONMode:
+			config.ResponseMIMEType = "application/json"
+		}
+	}
+
 	// Call the GenerateContent method with the JSON instruction
-	result, err := p.client.Models.GenerateContent(ctx, p.config.Model, genai.Text(prompt), config)
+	jsonResponse, err := p.generateContent(ctx, prompt, config)
 	if err != nil {
 		return fmt.Errorf("Google API JSON generate error: %w", err)
 	}
 
-	// Extract the text response and parse it as JSON
-	jsonResponse := result.Text()
-
 	// Remove any markdown formatting if present (```json and ```)
 	jsonResponse = strings.TrimPrefix(jsonResponse, "```json")
 	jsonResponse = strings.TrimPrefix(jsonResponse, "```")
@@ -106,6 +115,125 @@ func (p *GoogleProvider) GenerateJSON(ctx context.Context, prompt string, respon
 	return nil
 }
 
+// generateContent calls the API and returns the text response, streaming it to the
+// context's StreamFunc as it is generated if one is set
+func (p *GoogleProvider) generateContent(ctx context.Context, prompt string, config *genai.GenerateContentConfig) (string, error) {
+	if limits, ok := GenerationLimitsFromContext(ctx); ok {
+		if limits.MaxOutputTokens > 0 {
+			config.MaxOutputTokens = int32(limits.MaxOutputTokens)
+		}
+		config.StopSequences = limits.StopSequences
+		if limits.Temperature != nil {
+			temperature := float32(*limits.Temperature)
+			config.Temperature = &temperature
+		}
+	}
+
+	fn, ok := StreamFromContext(ctx)
+	if !ok {
+		result, err := p.client.Models.GenerateContent(ctx, p.config.Model, genai.Text(prompt), config)
+		if err != nil {
+			return "", classifyError(err)
+		}
+		return result.Text(), nil
+	}
+
+	var response strings.Builder
+	for chunk, err := range p.client.Models.GenerateContentStream(ctx, p.config.Model, genai.Text(prompt), config) {
+		if err != nil {
+			return "", classifyError(err)
+		}
+		if text := chunk.Text(); text != "" {
+			response.WriteString(text)
+			fn(text)
+		}
+	}
+	return response.String(), nil
+}
+
+// googleSchema converts a JSON Schema to the schema subset Gemini accepts. It returns false
+// if the schema can't be represented, e.g. because it has free-form objects, in which case
+// the response is only constrained to JSON.
+func googleSchema(schema map[string]interface{}) (*genai.Schema, bool) {
+	converted := &genai.Schema{}
+	if description, ok := schema["description"].(string); ok {
+		converted.Description = description
+	}
+	if format, ok := schema["format"].(string); ok {
+		converted.Format = format
+	}
+	for _, value := range toSlice(schema["enum"]) {
+		if s, ok := value.(string); ok {
+			converted.Enum = append(converted.Enum, s)
+		}
+	}
+
+	switch schema["type"] {
+	case "string":
+		converted.Type = genai.TypeString
+	case "integer":
+		converted.Type = genai.TypeInteger
+	case "number":
+		converted.Type = genai.TypeNumber
+	case "boolean":
+		converted.Type = genai.TypeBoolean
+	case "array":
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if converted.Items, ok = googleSchema(items); !ok {
+			return nil, false
+		}
+		converted.Type = genai.TypeArray
+	case "object":
+		properties, _ := schema["properties"].(map[string]interface{})
+		if len(properties) == 0 {
+			return nil, false
+		}
+		converted.Type = genai.TypeObject
+		converted.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, property := range properties {
+			propertySchema, ok := property.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if converted.Properties[name], ok = googleSchema(propertySchema); !ok {
+				return nil, false
+			}
+		}
+		for _, value := range toSlice(schema["required"]) {
+			if name, ok := value.(string); ok {
+				converted.Required = append(converted.Required, name)
+			}
+		}
+	default:
+		return nil, false
+	}
+	return converted, true
+}
+
+// toSlice returns the elements of a []interface{} or []string
+func toSlice(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		slice := make([]interface{}, len(v))
+		for i, s := range v {
+			slice[i] = s
+		}
+		return slice
+	default:
+		return nil
+	}
+}
+
+// Capabilities implements CapabilityReporter: Gemini supports JSON mode and response schemas
+func (p *GoogleProvider) Capabilities() Capabilities {
+	return Capabilities{JSONMode: true, JSONSchema: true}
+}
+
 // GetType implements the Provider interface
 func (p *GoogleProvider) GetType() ProviderType {
 	return Google
@@ -21,7 +21,7 @@ func NewGroqProvider(config Config) (*GroqProvider, error) {
 
 	if config.Model == "" {
 		// Set a default model if none specified
-		config.Model = "llama2-70b-4096"
+		config.Model = DefaultModel(Groq)
 	}
 
 	return &GroqProvider{
@@ -34,7 +34,9 @@ func NewGroqProvider(config Config) (*GroqProvider, error) {
 func (p *GroqProvider) Generate(ctx context.Context, prompt string) (string, error) {
 	// In a real implementation, this would call the Groq API
 	// This is a placeholder implementation
-	return fmt.Sprintf("Groq response to: %s", prompt), nil
+	response := fmt.Sprintf("Groq response to: %s", prompt)
+	streamWhole(ctx, response)
+	return response, nil
 }
 
 // GenerateJSON implements the Provider interface
@@ -43,8 +45,8 @@ func (p *GroqProvider) GenerateJSON(ctx context.Context, prompt string, response
 	// 1. Call the Groq API with JSON formatting instructions
 	// 2. Parse the response into the provided struct
 
-	// Placeholder implementation
-	_, err := p.Generate(ctx, prompt)
+	// Placeholder implementation; only the JSON response is streamed
+	_, err := p.Generate(WithStream(ctx, nil), prompt)
 	if err != nil {
 		return err
 	}
@@ -52,6 +54,8 @@ func (p *GroqProvider) GenerateJSON(ctx context.Context, prompt string, response
 	// Pretend we got valid JSON
 	mockJSON := `{"result": "Success", "data": "Sample data from Groq"}`
 
+	streamWhole(ctx, mockJSON)
+
 	// If debug is enabled, wrap the response with debug info
 	if p.config.IsDebugEnabled() {
 		if err := WrapWithDebugInfo(ctx, p.config, prompt, mockJSON, responseStruct); err != nil {
@@ -0,0 +1,242 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Interaction is the record of one call to a provider
+type Interaction struct {
+	Time time.Time `json:"time"`
+	// Processor and ItemID identify the processor and item that made the call, if it was
+	// made with a context from WithInteractionSource
+	Processor string `json:"processor,omitempty"`
+	ItemID    string `json:"item_id,omitempty"`
+	Provider  string `json:"provider"`
+	Model     string `json:"model,omitempty"`
+	// Kind is "text" for Generate and "json" for GenerateJSON
+	Kind string `json:"kind"`
+	// PromptHash identifies the full prompt, even if Prompt is truncated
+	PromptHash string `json:"prompt_hash"`
+	// Prompt is the prompt, truncated to the log's MaxPromptChars
+	Prompt string `json:"prompt,omitempty"`
+	// Response is the raw response; for JSON calls, the decoded response encoded again
+	Response string `json:"response,omitempty"`
+	// InputTokens and OutputTokens are estimated at four characters per token, as
+	// providers don't report usage
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+	LatencyMS    float64 `json:"latency_ms"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// InteractionSink receives the interactions of a logged provider
+type InteractionSink interface {
+	Record(ctx context.Context, interaction Interaction) error
+}
+
+// JSONLInteractionSink writes interactions as JSON Lines
+type JSONLInteractionSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLInteractionSink creates a sink writing one interaction per line to w
+func NewJSONLInteractionSink(w io.Writer) *JSONLInteractionSink {
+	return &JSONLInteractionSink{w: w}
+}
+
+// OpenInteractionLog creates a sink appending to the JSON Lines file at path, creating it
+// if needed. Close the sink when done.
+func OpenInteractionLog(path string) (*JSONLInteractionSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open interaction log: %w", err)
+	}
+	return NewJSONLInteractionSink(file), nil
+}
+
+// Record implements InteractionSink
+func (s *JSONLInteractionSink) Record(_ context.Context, interaction Interaction) error {
+	line, err := json.Marshal(interaction)
+	if err != nil {
+		return fmt.Errorf("failed to encode interaction: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write interaction: %w", err)
+	}
+	return nil
+}
+
+// Close closes the writer if it is an io.Closer
+func (s *JSONLInteractionSink) Close() error {
+	if closer, ok := s.w.(io.Closer); ok && s.w != os.Stdout {
+		return closer.Close()
+	}
+	return nil
+}
+
+// LoadInteractions reads the interactions of a JSON Lines log
+func LoadInteractions(path string) ([]Interaction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open interaction log: %w", err)
+	}
+	defer file.Close()
+	return ReadInteractions(file)
+}
+
+// ReadInteractions reads interactions from JSON Lines, skipping blank lines
+func ReadInteractions(r io.Reader) ([]Interaction, error) {
+	var interactions []Interaction
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("failed to parse interaction on line %d: %w", line, err)
+		}
+		interactions = append(interactions, interaction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read interaction log: %w", err)
+	}
+	return interactions, nil
+}
+
+// PromptHash returns the hash identifying a prompt in interaction logs
+func PromptHash(prompt string) string {
+	hash := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(hash[:])
+}
+
+// interactionSourceKey is the context key of the processor and item making a call
+type interactionSourceKey struct{}
+
+// interactionSource is the processor and item making a call
+type interactionSource struct {
+	processor string
+	itemID    string
+}
+
+// WithInteractionSource returns a context naming the processor and item that calls made
+// with it are for, so logged interactions can be traced back to them
+func WithInteractionSource(ctx context.Context, processor, itemID string) context.Context {
+	return context.WithValue(ctx, interactionSourceKey{}, interactionSource{processor: processor, itemID: itemID})
+}
+
+// InteractionLogConfig configures an interaction log
+type InteractionLogConfig struct {
+	// MaxPromptChars limits the prompt kept in each interaction (defaults to 2000; -1 keeps
+	// none). The prompt hash always covers the full prompt.
+	MaxPromptChars int
+	// InputTokenCost and OutputTokenCost are prices per million tokens, used to estimate cost
+	InputTokenCost  float64
+	OutputTokenCost float64
+}
+
+// loggedProvider records every call to a provider in an interaction sink
+type loggedProvider struct {
+	Provider
+	sink   InteractionSink
+	config InteractionLogConfig
+}
+
+// WithInteractionLog wraps a provider so every call, including failed ones, is recorded in
+// sink with its prompt, raw response, estimated usage and latency. Failures to record an
+// interaction don't fail the call; they are reported on standard error.
+func WithInteractionLog(provider Provider, sink InteractionSink, config InteractionLogConfig) Provider {
+	if config.MaxPromptChars == 0 {
+		config.MaxPromptChars = 2000
+	}
+	return &loggedProvider{Provider: provider, sink: sink, config: config}
+}
+
+// Generate implements Provider
+func (p *loggedProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	start := time.Now()
+	response, err := p.Provider.Generate(ctx, prompt)
+	p.record(ctx, "text", prompt, response, start, err)
+	return response, err
+}
+
+// GenerateJSON implements Provider
+func (p *loggedProvider) GenerateJSON(ctx context.Context, prompt string, responseStruct interface{}) error {
+	start := time.Now()
+	err := p.Provider.GenerateJSON(ctx, prompt, responseStruct)
+	var response string
+	if err == nil {
+		encoded, _ := json.Marshal(responseStruct)
+		response = string(encoded)
+	}
+	p.record(ctx, "json", prompt, response, start, err)
+	return err
+}
+
+// unwrap implements wrapper
+func (p *loggedProvider) unwrap() Provider {
+	return p.Provider
+}
+
+// withModel implements modelSwitcher
+func (p *loggedProvider) withModel(model string) (Provider, error) {
+	provider, err := WithModel(p.Provider, model)
+	if err != nil {
+		return nil, err
+	}
+	return WithInteractionLog(provider, p.sink, p.config), nil
+}
+
+// record sends the interaction of a call to the sink
+func (p *loggedProvider) record(ctx context.Context, kind, prompt, response string, start time.Time, err error) {
+	source, _ := ctx.Value(interactionSourceKey{}).(interactionSource)
+	interaction := Interaction{
+		Time:         start,
+		Processor:    source.processor,
+		ItemID:       source.itemID,
+		Provider:     string(p.GetType()),
+		Model:        p.GetConfig().Model,
+		Kind:         kind,
+		PromptHash:   PromptHash(prompt),
+		Prompt:       truncate(prompt, p.config.MaxPromptChars),
+		Response:     response,
+		InputTokens:  EstimateTokens(prompt),
+		OutputTokens: EstimateTokens(response),
+		LatencyMS:    float64(time.Since(start).Microseconds()) / 1000,
+	}
+	interaction.Cost = EstimateCost(interaction.InputTokens, interaction.OutputTokens, p.config.InputTokenCost, p.config.OutputTokenCost)
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+	if err := p.sink.Record(ctx, interaction); err != nil {
+		fmt.Fprintf(os.Stderr, "interaction log: %v\n", err)
+	}
+}
+
+// truncate returns the first max characters of text, or none if max is negative
+func truncate(text string, max int) string {
+	if max < 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
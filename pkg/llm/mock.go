@@ -0,0 +1,236 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// MockProvider is a Provider answering prompts with canned responses, for tests and
+// offline runs. It never calls an API.
+type MockProvider struct {
+	config Config
+
+	mu        sync.Mutex
+	responses []mockResponse
+	fallback  string
+	err       error
+	latency   time.Duration
+	prompts   []string
+	// capabilities are the capabilities reported, set with WithCapabilities
+	capabilities Capabilities
+	// embeddings are the vectors given to texts containing a substring, set with WithEmbedding
+	embeddings []mockEmbedding
+	// contextLimit is the most prompt tokens accepted, set with WithContextLimit
+	contextLimit int
+}
+
+// mockEmbedding is a vector given to texts containing a substring
+type mockEmbedding struct {
+	contains string
+	vector   []float64
+}
+
+// mockResponse is a response given to prompts containing a substring
+type mockResponse struct {
+	contains string
+	response string
+}
+
+// NewMockProvider creates a mock provider answering every prompt with the "response"
+// option, or "{}" if it isn't set
+func NewMockProvider(config Config) (*MockProvider, error) {
+	if config.Model == "" {
+		config.Model = "mock"
+	}
+	fallback := "{}"
+	if response, ok := config.Options["response"].(string); ok {
+		fallback = response
+	}
+	return &MockProvider{config: config, fallback: fallback}, nil
+}
+
+// NewMockProviderWithResponse creates a mock provider answering every prompt with response
+func NewMockProviderWithResponse(response string) *MockProvider {
+	return &MockProvider{config: Config{Model: "mock"}, fallback: response}
+}
+
+// WithResponse answers prompts containing contains with response instead of the default.
+// Responses added earlier take precedence.
+func (p *MockProvider) WithResponse(contains, response string) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responses = append(p.responses, mockResponse{contains: contains, response: response})
+	return p
+}
+
+// WithError makes every call fail with err, e.g. to test error handling
+func (p *MockProvider) WithError(err error) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+	return p
+}
+
+// WithLatency delays every response by d, or until the context is done, to simulate a
+// provider's response time, e.g. in benchmarks
+func (p *MockProvider) WithLatency(d time.Duration) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency = d
+	return p
+}
+
+// WithContextLimit rejects prompts of more than tokens estimated tokens, at four characters
+// per token, with a ContextLengthError, to simulate a model's context length
+func (p *MockProvider) WithContextLimit(tokens int) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.contextLimit = tokens
+	return p
+}
+
+// Prompts returns the prompts the provider has received, in order
+func (p *MockProvider) Prompts() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.prompts...)
+}
+
+// ResetPrompts forgets the prompts received so far, e.g. between benchmark iterations so
+// they don't accumulate
+func (p *MockProvider) ResetPrompts() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = nil
+}
+
+// Generate implements the Provider interface
+func (p *MockProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	p.mu.Lock()
+	p.prompts = append(p.prompts, prompt)
+	response, err, latency, contextLimit := p.fallback, p.err, p.latency, p.contextLimit
+	for _, r := range p.responses {
+		if strings.Contains(prompt, r.contains) {
+			response = r.response
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if tokens := EstimateTokens(prompt); contextLimit > 0 && tokens > int64(contextLimit) {
+		return "", &ContextLengthError{Err: fmt.Errorf("prompt of %d tokens exceeds the limit of %d", tokens, contextLimit)}
+	}
+	response = applyGenerationLimits(ctx, response)
+	streamWhole(ctx, response)
+	return response, nil
+}
+
+// GenerateJSON implements the Provider interface
+func (p *MockProvider) GenerateJSON(ctx context.Context, prompt string, responseStruct interface{}) error {
+	response, err := p.Generate(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(response), responseStruct); err != nil {
+		return fmt.Errorf("failed to parse mock response as JSON: %w", err)
+	}
+	return WrapWithDebugInfo(ctx, p.config, prompt, response, responseStruct)
+}
+
+// GetType implements the Provider interface
+func (p *MockProvider) GetType() ProviderType {
+	return Mock
+}
+
+// GetConfig implements the Provider interface
+func (p *MockProvider) GetConfig() Config {
+	return p.config
+}
+
+// WithCapabilities makes the provider report capabilities, e.g. to test how processors use
+// native structured output. Responses are the same whatever the mode.
+func (p *MockProvider) WithCapabilities(capabilities Capabilities) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.capabilities = capabilities
+	return p
+}
+
+// Capabilities implements CapabilityReporter
+func (p *MockProvider) Capabilities() Capabilities {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.capabilities
+}
+
+// Check implements Checker without recording a prompt, failing with the error set by
+// WithError, so misconfiguration can be simulated in tests
+func (p *MockProvider) Check(_ context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// WithEmbedding embeds texts containing contains as vector instead of the default, e.g. to
+// make differently worded texts equivalent in tests. Vectors added earlier take precedence.
+func (p *MockProvider) WithEmbedding(contains string, vector []float64) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.embeddings = append(p.embeddings, mockEmbedding{contains: contains, vector: vector})
+	return p
+}
+
+// mockEmbeddingSize is the length of the default mock embeddings
+const mockEmbeddingSize = 64
+
+// Embed implements Embedder without calling an API. Texts get the vector set with
+// WithEmbedding, or else a bag-of-words vector, so texts sharing words are similar. It
+// fails with the error set by WithError.
+func (p *MockProvider) Embed(_ context.Context, texts []string) ([][]float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		for _, e := range p.embeddings {
+			if strings.Contains(text, e.contains) {
+				vectors[i] = append([]float64(nil), e.vector...)
+				break
+			}
+		}
+		if vectors[i] != nil {
+			continue
+		}
+		vector := make([]float64, mockEmbeddingSize)
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			hash := fnv.New32a()
+			hash.Write([]byte(word))
+			vector[hash.Sum32()%mockEmbeddingSize]++
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
@@ -21,7 +21,7 @@ func NewOpenAIProvider(config Config) (*OpenAIProvider, error) {
 
 	if config.Model == "" {
 		// Set a default model if none specified
-		config.Model = "gpt-4"
+		config.Model = DefaultModel(OpenAI)
 	}
 
 	return &OpenAIProvider{
@@ -34,7 +34,9 @@ func NewOpenAIProvider(config Config) (*OpenAIProvider, error) {
 func (p *OpenAIProvider) Generate(ctx context.Context, prompt string) (string, error) {
 	// In a real implementation, this would call the OpenAI API
 	// This is a placeholder implementation
-	return fmt.Sprintf("OpenAI response to: %s", prompt), nil
+	response := fmt.Sprintf("OpenAI response to: %s", prompt)
+	streamWhole(ctx, response)
+	return response, nil
 }
 
 // GenerateJSON implements the Provider interface
@@ -43,8 +45,8 @@ func (p *OpenAIProvider) GenerateJSON(ctx context.Context, prompt string, respon
 	// 1. Call the OpenAI API with JSON mode enabled
 	// 2. Parse the response into the provided struct
 
-	// Placeholder implementation
-	_, err := p.Generate(ctx, prompt)
+	// Placeholder implementation; only the JSON response is streamed
+	_, err := p.Generate(WithStream(ctx, nil), prompt)
 	if err != nil {
 		return err
 	}
@@ -52,6 +54,8 @@ func (p *OpenAIProvider) GenerateJSON(ctx context.Context, prompt string, respon
 	// Pretend we got valid JSON
 	mockJSON := `{"result": "Success", "data": "Sample data from OpenAI"}`
 
+	streamWhole(ctx, mockJSON)
+
 	// If debug is enabled, wrap the response with debug info
 	if p.config.IsDebugEnabled() {
 		if err := WrapWithDebugInfo(ctx, p.config, prompt, mockJSON, responseStruct); err != nil {
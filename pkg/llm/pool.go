@@ -0,0 +1,189 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownTenant is matched by errors.Is for tenants a ProviderPool has no provider for
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// ErrBudgetExceeded is matched by errors.Is for calls refused because the tenant has used
+// up its budget. Retrying can't succeed.
+var ErrBudgetExceeded = errors.New("tenant budget exceeded")
+
+// TenantBudget limits the usage of a tenant's calls, with tokens estimated at four
+// characters per token. Zero values mean no limit.
+type TenantBudget struct {
+	// MaxTokens is the most prompt and response tokens the tenant's calls may use
+	MaxTokens int64 `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	// MaxCost is the most the tenant's calls may cost, priced at InputTokenCost and
+	// OutputTokenCost
+	MaxCost float64 `json:"max_cost,omitempty" yaml:"max_cost,omitempty"`
+	// InputTokenCost and OutputTokenCost are prices per million tokens
+	InputTokenCost  float64 `json:"input_token_cost,omitempty" yaml:"input_token_cost,omitempty"`
+	OutputTokenCost float64 `json:"output_token_cost,omitempty" yaml:"output_token_cost,omitempty"`
+}
+
+// TenantUsage is the estimated usage of a tenant's calls
+type TenantUsage struct {
+	Calls        int64   `json:"calls"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// exceeded reports whether usage has used up the budget
+func (b TenantBudget) exceeded(usage TenantUsage) bool {
+	return (b.MaxTokens > 0 && usage.InputTokens+usage.OutputTokens >= b.MaxTokens) ||
+		(b.MaxCost > 0 && usage.Cost >= b.MaxCost)
+}
+
+// ProviderPool maps tenants, such as the customers or workspaces sharing a deployment, to
+// their own provider, with its API key and model, and budget. It is safe for concurrent use.
+type ProviderPool struct {
+	mu      sync.RWMutex
+	tenants map[string]*tenantProvider
+}
+
+// NewProviderPool creates an empty provider pool
+func NewProviderPool() *ProviderPool {
+	return &ProviderPool{tenants: make(map[string]*tenantProvider)}
+}
+
+// Add sets the provider and budget of a tenant, starting its usage over
+func (p *ProviderPool) Add(tenant string, provider Provider, budget TenantBudget) *ProviderPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tenants[tenant] = &tenantProvider{Provider: provider, tenant: tenant, budget: budget, usage: &tenantMeter{}}
+	return p
+}
+
+// Provider returns the provider of a tenant, which refuses calls with ErrBudgetExceeded
+// once the tenant's budget is used up
+func (p *ProviderPool) Provider(tenant string) (Provider, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	provider, ok := p.tenants[tenant]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTenant, tenant)
+	}
+	return provider, nil
+}
+
+// Usage returns the usage of a tenant's calls so far
+func (p *ProviderPool) Usage(tenant string) (TenantUsage, error) {
+	provider, err := p.Provider(tenant)
+	if err != nil {
+		return TenantUsage{}, err
+	}
+	return provider.(*tenantProvider).usage.get(), nil
+}
+
+// Exceeded reports whether a tenant has used up its budget
+func (p *ProviderPool) Exceeded(tenant string) (bool, error) {
+	provider, err := p.Provider(tenant)
+	if err != nil {
+		return false, err
+	}
+	t := provider.(*tenantProvider)
+	return t.budget.exceeded(t.usage.get()), nil
+}
+
+// Tenants returns the tenants of the pool, sorted
+func (p *ProviderPool) Tenants() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	tenants := make([]string, 0, len(p.tenants))
+	for tenant := range p.tenants {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// tenantMeter accumulates a tenant's usage
+type tenantMeter struct {
+	mu    sync.Mutex
+	usage TenantUsage
+}
+
+// get returns the usage so far
+func (m *tenantMeter) get() TenantUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage
+}
+
+// tenantProvider meters a tenant's calls and refuses them once its budget is used up.
+// Calls in flight when the budget runs out complete, so usage can overshoot it.
+type tenantProvider struct {
+	Provider
+	tenant string
+	budget TenantBudget
+	usage  *tenantMeter
+}
+
+// Generate implements Provider
+func (p *tenantProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	if err := p.admit(); err != nil {
+		return "", err
+	}
+	response, err := p.Provider.Generate(ctx, prompt)
+	if err == nil {
+		p.record(prompt, response)
+	}
+	return response, err
+}
+
+// GenerateJSON implements Provider
+func (p *tenantProvider) GenerateJSON(ctx context.Context, prompt string, responseStruct interface{}) error {
+	if err := p.admit(); err != nil {
+		return err
+	}
+	err := p.Provider.GenerateJSON(ctx, prompt, responseStruct)
+	if err == nil {
+		response, _ := json.Marshal(responseStruct)
+		p.record(prompt, string(response))
+	}
+	return err
+}
+
+// unwrap implements wrapper
+func (p *tenantProvider) unwrap() Provider {
+	return p.Provider
+}
+
+// withModel implements modelSwitcher, sharing the tenant's budget with the new provider
+func (p *tenantProvider) withModel(model string) (Provider, error) {
+	provider, err := WithModel(p.Provider, model)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantProvider{Provider: provider, tenant: p.tenant, budget: p.budget, usage: p.usage}, nil
+}
+
+// admit returns ErrBudgetExceeded if the tenant has used up its budget
+func (p *tenantProvider) admit() error {
+	if p.budget.exceeded(p.usage.get()) {
+		return fmt.Errorf("tenant %s: %w", p.tenant, ErrBudgetExceeded)
+	}
+	return nil
+}
+
+// record adds the estimated usage of a call
+func (p *tenantProvider) record(prompt, response string) {
+	inputTokens := EstimateTokens(prompt)
+	outputTokens := EstimateTokens(response)
+
+	p.usage.mu.Lock()
+	defer p.usage.mu.Unlock()
+	p.usage.usage.Calls++
+	p.usage.usage.InputTokens += inputTokens
+	p.usage.usage.OutputTokens += outputTokens
+	p.usage.usage.Cost += EstimateCost(inputTokens, outputTokens, p.budget.InputTokenCost, p.budget.OutputTokenCost)
+}
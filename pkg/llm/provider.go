@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // ProviderType represents the type of LLM provider
@@ -18,6 +19,10 @@ const (
 	Groq ProviderType = "groq"
 	// OpenAI provider type
 	OpenAI ProviderType = "openai"
+	// Mock provider type, answering with canned responses for tests and offline runs
+	Mock ProviderType = "mock"
+	// Replay provider type, answering with the responses recorded in an interaction log
+	Replay ProviderType = "replay"
 )
 
 // Config holds common configuration for all providers
@@ -114,6 +119,94 @@ func WrapWithDebugInfo(ctx context.Context, config Config, prompt string, rawRes
 	return nil
 }
 
+// modelSwitcher is implemented by providers wrapping another provider, such as those
+// returned by WithRetry, so WithModel keeps the wrapping
+type modelSwitcher interface {
+	withModel(model string) (Provider, error)
+}
+
+// WithModel creates a provider of the same type and configuration as provider but using a
+// different model, e.g. a cheaper model for a simple pipeline step
+func WithModel(provider Provider, model string) (Provider, error) {
+	if model == "" || model == provider.GetConfig().Model {
+		return provider, nil
+	}
+	if switcher, ok := provider.(modelSwitcher); ok {
+		return switcher.withModel(model)
+	}
+
+	config := provider.GetConfig()
+	config.Model = model
+
+	// Copy options so the providers don't share a map
+	options := make(map[string]interface{}, len(config.Options))
+	for k, v := range config.Options {
+		options[k] = v
+	}
+	config.Options = options
+
+	return NewProvider(provider.GetType(), config)
+}
+
+// DefaultAPIKeyEnvVar returns the environment variable conventionally holding the API key
+// for a provider type, or an empty string for unknown types
+func DefaultAPIKeyEnvVar(providerType ProviderType) string {
+	switch providerType {
+	case Google:
+		return "GEMINI_API_KEY"
+	case OpenAI:
+		return "OPENAI_API_KEY"
+	case Groq:
+		return "GROQ_API_KEY"
+	case Amazon:
+		return "AMAZON_API_KEY"
+	default:
+		return ""
+	}
+}
+
+// DefaultModel returns the model a provider type uses when its config sets none, or an
+// empty string for types that don't call a model
+func DefaultModel(providerType ProviderType) string {
+	switch providerType {
+	case Google:
+		return "gemini-1.0-pro"
+	case OpenAI:
+		return "gpt-4"
+	case Groq:
+		return "llama2-70b-4096"
+	case Amazon:
+		return "anthropic.claude-v2"
+	default:
+		return ""
+	}
+}
+
+// modelPrefixes are the prefixes of the names of the models each provider type serves
+var modelPrefixes = map[ProviderType][]string{
+	Google: {"gemini", "gemma"},
+	OpenAI: {"gpt-", "chatgpt", "o1", "o3", "o4"},
+	Groq:   {"llama", "mixtral", "gemma", "qwen", "deepseek"},
+	Amazon: {"anthropic.", "amazon.", "meta.", "mistral.", "cohere.", "ai21."},
+}
+
+// ServesModel reports whether a provider type serves a model, judged by the model's name,
+// e.g. Google serves gemini-2.0-flash. Mock and replay providers serve no named model.
+func ServesModel(providerType ProviderType, model string) bool {
+	model = strings.ToLower(model)
+	for _, prefix := range modelPrefixes[providerType] {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsAPIKey reports whether a provider type calls an API and so needs an API key
+func NeedsAPIKey(providerType ProviderType) bool {
+	return providerType != Mock && providerType != Replay
+}
+
 // NewProvider creates a new LLM provider based on the type
 func NewProvider(providerType ProviderType, config Config) (Provider, error) {
 	switch providerType {
@@ -125,6 +218,10 @@ func NewProvider(providerType ProviderType, config Config) (Provider, error) {
 		return NewGroqProvider(config)
 	case OpenAI:
 		return NewOpenAIProvider(config)
+	case Mock:
+		return NewMockProvider(config)
+	case Replay:
+		return NewReplayProviderFromConfig(config)
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
 	}
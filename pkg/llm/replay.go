@@ -0,0 +1,185 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrNotRecorded is returned by a ReplayProvider for a prompt its log doesn't hold
+var ErrNotRecorded = errors.New("prompt not recorded")
+
+// ReplayProvider is a Provider answering prompts with the responses recorded in an
+// interaction log, so runs can be repeated offline, e.g. to re-test response parsing and
+// field mapping against real model outputs without paying for calls. It never calls an API.
+type ReplayProvider struct {
+	config Config
+	log    *replayLog
+	// matchItems falls back to the response recorded for the same processor and item
+	matchItems bool
+}
+
+// replayLog holds the recorded responses, shared by the providers WithModel derives
+type replayLog struct {
+	// byPrompt are the responses by kind and prompt hash
+	byPrompt map[string]string
+	// byItem are the responses by kind, processor and item ID
+	byItem map[string]string
+	// structured is whether the run recorded JSON calls, i.e. asked for structured output
+	structured bool
+
+	replayed      atomic.Int64
+	matchedByItem atomic.Int64
+	missing       atomic.Int64
+}
+
+// ReplayStats counts the calls a ReplayProvider has answered
+type ReplayStats struct {
+	// Replayed is the number of calls answered from the log
+	Replayed int64
+	// MatchedByItem is how many of those were matched by processor and item instead of
+	// prompt, because the prompt changed since it was recorded
+	MatchedByItem int64
+	// Missing is the number of calls that failed with ErrNotRecorded
+	Missing int64
+}
+
+// NewReplayProvider creates a provider answering with the successful interactions given.
+// When a prompt was recorded more than once, the latest response is used.
+func NewReplayProvider(interactions []Interaction) *ReplayProvider {
+	log := &replayLog{byPrompt: make(map[string]string), byItem: make(map[string]string)}
+	for _, interaction := range interactions {
+		if interaction.Error != "" {
+			continue
+		}
+		log.byPrompt[replayKey(interaction.Kind, interaction.PromptHash)] = interaction.Response
+		if interaction.Kind == "json" {
+			log.structured = true
+		}
+		if interaction.Processor != "" && interaction.ItemID != "" {
+			log.byItem[replayKey(interaction.Kind, interaction.Processor, interaction.ItemID)] = interaction.Response
+		}
+	}
+	return &ReplayProvider{config: Config{Model: "replay"}, log: log}
+}
+
+// NewReplayProviderFromConfig creates a replay provider from the interaction log named by
+// the "log" option, as with `type: replay` in a config file. Setting the "match_items"
+// option to true enables WithItemMatching.
+func NewReplayProviderFromConfig(config Config) (*ReplayProvider, error) {
+	path, ok := config.Options["log"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("replay provider needs the log option")
+	}
+	interactions, err := LoadInteractions(path)
+	if err != nil {
+		return nil, err
+	}
+	p := NewReplayProvider(interactions)
+	if config.Model == "" {
+		config.Model = "replay"
+	}
+	p.config = config
+	if match, _ := config.Options["match_items"].(bool); match {
+		p.matchItems = true
+	}
+	return p, nil
+}
+
+// WithItemMatching answers a prompt the log doesn't hold with the response recorded for the
+// same processor and item, if any, so a run can be replayed after its prompts changed. The
+// processor and item are those named with WithInteractionSource, as processors do.
+func (p *ReplayProvider) WithItemMatching() *ReplayProvider {
+	p.matchItems = true
+	return p
+}
+
+// Stats returns the counts of calls answered so far, by this provider and those derived
+// from it with WithModel
+func (p *ReplayProvider) Stats() ReplayStats {
+	return ReplayStats{
+		Replayed:      p.log.replayed.Load(),
+		MatchedByItem: p.log.matchedByItem.Load(),
+		Missing:       p.log.missing.Load(),
+	}
+}
+
+// Generate implements the Provider interface
+func (p *ReplayProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	response, err := p.lookup(ctx, "text", prompt)
+	if err != nil {
+		return "", err
+	}
+	streamWhole(ctx, response)
+	return response, nil
+}
+
+// GenerateJSON implements the Provider interface
+func (p *ReplayProvider) GenerateJSON(ctx context.Context, prompt string, responseStruct interface{}) error {
+	response, err := p.lookup(ctx, "json", prompt)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(response), responseStruct); err != nil {
+		return fmt.Errorf("failed to parse recorded response as JSON: %w", err)
+	}
+	return WrapWithDebugInfo(ctx, p.config, prompt, response, responseStruct)
+}
+
+// GetType implements the Provider interface
+func (p *ReplayProvider) GetType() ProviderType {
+	return Replay
+}
+
+// GetConfig implements the Provider interface
+func (p *ReplayProvider) GetConfig() Config {
+	return p.config
+}
+
+// Capabilities implements CapabilityReporter. If the recorded run made JSON calls, native
+// structured output is reported so processors make the same calls as when it was recorded.
+func (p *ReplayProvider) Capabilities() Capabilities {
+	return Capabilities{JSONMode: p.log.structured, JSONSchema: p.log.structured}
+}
+
+// Check implements Checker. A replay provider never calls an API, so there is nothing to check.
+func (p *ReplayProvider) Check(_ context.Context) error {
+	return nil
+}
+
+// withModel implements modelSwitcher. Recorded responses are answered whatever the model,
+// so the derived provider shares the log.
+func (p *ReplayProvider) withModel(model string) (Provider, error) {
+	derived := *p
+	derived.config.Model = model
+	return &derived, nil
+}
+
+// lookup returns the recorded response of a prompt
+func (p *ReplayProvider) lookup(ctx context.Context, kind, prompt string) (string, error) {
+	hash := PromptHash(prompt)
+	if response, ok := p.log.byPrompt[replayKey(kind, hash)]; ok {
+		p.log.replayed.Add(1)
+		return response, nil
+	}
+	if source, ok := ctx.Value(interactionSourceKey{}).(interactionSource); ok && p.matchItems {
+		if response, ok := p.log.byItem[replayKey(kind, source.processor, source.itemID)]; ok {
+			p.log.replayed.Add(1)
+			p.log.matchedByItem.Add(1)
+			return response, nil
+		}
+	}
+	p.log.missing.Add(1)
+	return "", fmt.Errorf("%w: %s prompt %s", ErrNotRecorded, kind, hash[:12])
+}
+
+// replayKey identifies a recorded response
+func replayKey(parts ...string) string {
+	key := parts[0]
+	for _, part := range parts[1:] {
+		key += "\x00" + part
+	}
+	return key
+}
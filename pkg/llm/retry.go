@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetryConfig configures how failed calls to a provider are retried
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry (defaults to 1s)
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries, which doubles after each retry (defaults to 30s)
+	MaxBackoff time.Duration
+}
+
+// retryProvider retries failed calls to a provider with exponential backoff
+type retryProvider struct {
+	Provider
+	config RetryConfig
+}
+
+// WithRetry wraps a provider so failed calls are retried with exponential backoff. Calls
+// are not retried once their context is done.
+func WithRetry(provider Provider, config RetryConfig) Provider {
+	if config.MaxRetries <= 0 {
+		return provider
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+	return &retryProvider{Provider: provider, config: config}
+}
+
+// Generate implements Provider
+func (p *retryProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	var response string
+	err := p.retry(ctx, func() error {
+		var err error
+		response, err = p.Provider.Generate(ctx, prompt)
+		return err
+	})
+	return response, err
+}
+
+// GenerateJSON implements Provider
+func (p *retryProvider) GenerateJSON(ctx context.Context, prompt string, responseStruct interface{}) error {
+	return p.retry(ctx, func() error {
+		return p.Provider.GenerateJSON(ctx, prompt, responseStruct)
+	})
+}
+
+// unwrap implements wrapper
+func (p *retryProvider) unwrap() Provider {
+	return p.Provider
+}
+
+// withModel implements modelSwitcher
+func (p *retryProvider) withModel(model string) (Provider, error) {
+	provider, err := WithModel(p.Provider, model)
+	if err != nil {
+		return nil, err
+	}
+	return WithRetry(provider, p.config), nil
+}
+
+// retry calls fn until it succeeds, the retries are used up or the context is done
+func (p *retryProvider) retry(ctx context.Context, fn func() error) error {
+	backoff := p.config.InitialBackoff
+	attempts := 0
+	for {
+		err := fn()
+		attempts++
+		if err == nil {
+			return nil
+		}
+		// The same prompt can't fit the next time either, nor a spent budget recover
+		if errors.Is(err, ErrContextLength) || errors.Is(err, ErrBudgetExceeded) {
+			return err
+		}
+		if attempts > p.config.MaxRetries || ctx.Err() != nil {
+			return fmt.Errorf("failed after %d attempts: %w", attempts, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, p.config.MaxBackoff)
+	}
+}
+
+// timeoutProvider limits the duration of each call to a provider
+type timeoutProvider struct {
+	Provider
+	timeout time.Duration
+}
+
+// WithTimeout wraps a provider so each call fails if it takes longer than timeout. With
+// WithRetry, wrap the timeout provider so the limit applies to each attempt.
+func WithTimeout(provider Provider, timeout time.Duration) Provider {
+	if timeout <= 0 {
+		return provider
+	}
+	return &timeoutProvider{Provider: provider, timeout: timeout}
+}
+
+// Generate implements Provider
+func (p *timeoutProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return p.Provider.Generate(ctx, prompt)
+}
+
+// GenerateJSON implements Provider
+func (p *timeoutProvider) GenerateJSON(ctx context.Context, prompt string, responseStruct interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return p.Provider.GenerateJSON(ctx, prompt, responseStruct)
+}
+
+// unwrap implements wrapper
+func (p *timeoutProvider) unwrap() Provider {
+	return p.Provider
+}
+
+// withModel implements modelSwitcher
+func (p *timeoutProvider) withModel(model string) (Provider, error) {
+	provider, err := WithModel(p.Provider, model)
+	if err != nil {
+		return nil, err
+	}
+	return WithTimeout(provider, p.timeout), nil
+}
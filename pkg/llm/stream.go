@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"context"
+)
+
+// StreamFunc receives the chunks of a response as they are generated
+type StreamFunc func(chunk string)
+
+// streamKey is the context key of the StreamFunc for LLM calls
+type streamKey struct{}
+
+// WithStream returns a context whose LLM calls deliver their raw response to fn chunk by
+// chunk as it is generated, in addition to returning the full response. Providers without
+// streaming support deliver the whole response as a single chunk. Because the function
+// travels in the context, it also reaches calls made inside processors and pipelines.
+func WithStream(ctx context.Context, fn StreamFunc) context.Context {
+	return context.WithValue(ctx, streamKey{}, fn)
+}
+
+// StreamFromContext returns the StreamFunc set by WithStream, if any
+func StreamFromContext(ctx context.Context) (StreamFunc, bool) {
+	fn, ok := ctx.Value(streamKey{}).(StreamFunc)
+	return fn, ok && fn != nil
+}
+
+// GenerateStream prompts the provider, delivering the response to fn as it is generated,
+// and returns the full response
+func GenerateStream(ctx context.Context, provider Provider, prompt string, fn StreamFunc) (string, error) {
+	return provider.Generate(WithStream(ctx, fn), prompt)
+}
+
+// streamWhole delivers a complete response as a single chunk if the context has a
+// StreamFunc, for providers that can't stream
+func streamWhole(ctx context.Context, response string) {
+	if fn, ok := StreamFromContext(ctx); ok {
+		fn(response)
+	}
+}
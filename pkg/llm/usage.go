@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// CharsPerToken is the approximate number of characters per token used to estimate usage,
+// since providers don't report it
+const CharsPerToken = 4
+
+// EstimateTokens estimates the number of tokens in a text from its length
+func EstimateTokens(text string) int64 {
+	return int64((len(text) + CharsPerToken - 1) / CharsPerToken)
+}
+
+// EstimateCost prices input and output tokens at prices per million tokens
+func EstimateCost(inputTokens, outputTokens int64, inputTokenCost, outputTokenCost float64) float64 {
+	return (float64(inputTokens)*inputTokenCost + float64(outputTokens)*outputTokenCost) / 1e6
+}
+
+// UsageFunc receives the prompt and response of a successful call, e.g. to add its
+// estimated tokens to the usage of the call's context
+type UsageFunc func(ctx context.Context, prompt, response string)
+
+// meteredProvider passes every successful call to a UsageFunc
+type meteredProvider struct {
+	Provider
+	record UsageFunc
+}
+
+// WithMetering wraps a provider to pass the prompt and response of every successful call to
+// record. The responses of GenerateJSON are passed encoded as JSON.
+func WithMetering(provider Provider, record UsageFunc) Provider {
+	return &meteredProvider{Provider: provider, record: record}
+}
+
+// Generate implements Provider
+func (p *meteredProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	response, err := p.Provider.Generate(ctx, prompt)
+	if err == nil {
+		p.record(ctx, prompt, response)
+	}
+	return response, err
+}
+
+// GenerateJSON implements Provider
+func (p *meteredProvider) GenerateJSON(ctx context.Context, prompt string, responseStruct interface{}) error {
+	err := p.Provider.GenerateJSON(ctx, prompt, responseStruct)
+	if err == nil {
+		response, _ := json.Marshal(responseStruct)
+		p.record(ctx, prompt, string(response))
+	}
+	return err
+}
+
+// unwrap implements wrapper
+func (p *meteredProvider) unwrap() Provider {
+	return p.Provider
+}
+
+// withModel implements modelSwitcher
+func (p *meteredProvider) withModel(model string) (Provider, error) {
+	provider, err := WithModel(p.Provider, model)
+	if err != nil {
+		return nil, err
+	}
+	return WithMetering(provider, p.record), nil
+}
@@ -0,0 +1,245 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// AggregateFunc reduces the results of a whole run to a single corpus-level value
+type AggregateFunc func(ctx context.Context, items []*data.ProcessItem) (interface{}, error)
+
+// aggregator is a step that runs once after every item has passed through the chain
+type aggregator struct {
+	name string
+	fn   AggregateFunc
+}
+
+// AddAggregator appends a step that runs across all items once the per-item steps have
+// completed. Results of the latest run are available from Aggregates.
+func (c *Chain) AddAggregator(name string, fn AggregateFunc) *Chain {
+	c.aggregators = append(c.aggregators, aggregator{name: name, fn: fn})
+	return c
+}
+
+// Aggregate runs the chain's aggregators over a set of results and returns their values by name
+func (c *Chain) Aggregate(ctx context.Context, items []*data.ProcessItem) (map[string]interface{}, error) {
+	aggregates := make(map[string]interface{}, len(c.aggregators))
+	for _, agg := range c.aggregators {
+		value, err := agg.fn(ctx, items)
+		if err != nil {
+			return nil, fmt.Errorf("aggregator '%s' error: %w", agg.name, err)
+		}
+		aggregates[agg.name] = value
+	}
+	return aggregates, nil
+}
+
+// Aggregates returns the aggregates computed by the latest ProcessBatch or ProcessSource run
+func (c *Chain) Aggregates() map[string]interface{} {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	aggregates := make(map[string]interface{}, len(c.aggregates))
+	for k, v := range c.aggregates {
+		aggregates[k] = v
+	}
+	return aggregates
+}
+
+// runAggregators computes and records the aggregates for a completed run
+func (c *Chain) runAggregators(ctx context.Context, items []*data.ProcessItem) error {
+	if len(c.aggregators) == 0 {
+		return nil
+	}
+
+	aggregates, err := c.Aggregate(ctx, items)
+	if err != nil {
+		return err
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	c.aggregates = aggregates
+	return nil
+}
+
+// ResultValues returns the values at a dot-separated path within a processor's result,
+// flattening arrays along the way. For example "intents.label" yields every intent label.
+func ResultValues(item *data.ProcessItem, processorName, path string) []interface{} {
+	info, ok := item.ProcessingInfo[processorName]
+	if !ok {
+		return nil
+	}
+
+	// Normalize typed results into generic maps and slices
+	encoded, err := json.Marshal(info)
+	if err != nil {
+		return nil
+	}
+	var current interface{}
+	if err := json.Unmarshal(encoded, &current); err != nil {
+		return nil
+	}
+
+	values := []interface{}{current}
+	for _, key := range strings.Split(path, ".") {
+		var next []interface{}
+		for _, value := range values {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch field := object[key].(type) {
+			case nil:
+			case []interface{}:
+				next = append(next, field...)
+			default:
+				next = append(next, field)
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// CountBy returns an AggregateFunc counting the values found at path in a processor's
+// results, e.g. CountBy("intent", "intents.label") for the most common intents.
+// The result is a []ValueCount sorted by descending count.
+func CountBy(processorName, path string) AggregateFunc {
+	return func(_ context.Context, items []*data.ProcessItem) (interface{}, error) {
+		counts := make(map[string]int)
+		for _, item := range items {
+			for _, value := range ResultValues(item, processorName, path) {
+				counts[fmt.Sprint(value)]++
+			}
+		}
+
+		result := make([]ValueCount, 0, len(counts))
+		for value, count := range counts {
+			result = append(result, ValueCount{Value: value, Count: count})
+		}
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].Count != result[j].Count {
+				return result[i].Count > result[j].Count
+			}
+			return result[i].Value < result[j].Value
+		})
+		return result, nil
+	}
+}
+
+// ValueCount is a value and the number of times it occurred
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Average returns an AggregateFunc averaging the numeric values found at path in a
+// processor's results, e.g. Average("sentiment", "score"). Items without a value are ignored.
+func Average(processorName, path string) AggregateFunc {
+	return func(_ context.Context, items []*data.ProcessItem) (interface{}, error) {
+		var sum float64
+		n := 0
+		for _, item := range items {
+			for _, value := range ResultValues(item, processorName, path) {
+				if number, ok := value.(float64); ok {
+					sum += number
+					n++
+				}
+			}
+		}
+		if n == 0 {
+			return 0.0, nil
+		}
+		return sum / float64(n), nil
+	}
+}
+
+// Collect returns an AggregateFunc gathering the distinct values found at path in a
+// processor's results, in first-seen order, e.g. Collect("required_attributes", "attributes")
+// for a consolidated attribute list
+func Collect(processorName, path string) AggregateFunc {
+	return func(_ context.Context, items []*data.ProcessItem) (interface{}, error) {
+		seen := make(map[string]bool)
+		var result []interface{}
+		for _, item := range items {
+			for _, value := range ResultValues(item, processorName, path) {
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return nil, err
+				}
+				if !seen[string(encoded)] {
+					seen[string(encoded)] = true
+					result = append(result, value)
+				}
+			}
+		}
+		return result, nil
+	}
+}
+
+// DefaultSummaryMaxItems is the number of items included in an LLM summary prompt by default
+const DefaultSummaryMaxItems = 200
+
+// LLMSummary returns an AggregateFunc asking an LLM to write a corpus-level summary of the
+// per-item results. instructions describe what the summary should cover; at most maxItems
+// items are included in the prompt (DefaultSummaryMaxItems if maxItems <= 0).
+func LLMSummary(provider llm.Provider, instructions string, maxItems int) AggregateFunc {
+	if maxItems <= 0 {
+		maxItems = DefaultSummaryMaxItems
+	}
+	if instructions == "" {
+		instructions = "Summarize the main patterns, trends and notable outliers across all items."
+	}
+
+	return func(ctx context.Context, items []*data.ProcessItem) (interface{}, error) {
+		var sb strings.Builder
+		sb.WriteString("You are an expert analyst summarizing the results of processing a collection of texts.\n\n")
+		sb.WriteString("**Instructions**\n")
+		sb.WriteString(instructions)
+		fmt.Fprintf(&sb, "\n\n**Results** (%d items", len(items))
+		if len(items) > maxItems {
+			fmt.Fprintf(&sb, ", first %d shown", maxItems)
+		}
+		sb.WriteString(")\n")
+
+		for i, item := range items {
+			if i >= maxItems {
+				break
+			}
+			results := make(map[string]interface{}, len(item.ProcessingInfo))
+			for name, info := range item.ProcessingInfo {
+				// Debug information only adds noise to the prompt
+				if infoMap, ok := info.(map[string]interface{}); ok {
+					if _, hasDebug := infoMap["debug"]; hasDebug {
+						trimmed := make(map[string]interface{}, len(infoMap))
+						for k, v := range infoMap {
+							if k != "debug" {
+								trimmed[k] = v
+							}
+						}
+						info = trimmed
+					}
+				}
+				results[name] = info
+			}
+			encoded, err := json.Marshal(results)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode results for item %s: %w", item.ID, err)
+			}
+			fmt.Fprintf(&sb, "- %s: %s\n", item.ID, encoded)
+		}
+
+		summary, err := provider.Generate(ctx, sb.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate summary: %w", err)
+		}
+		return strings.TrimSpace(summary), nil
+	}
+}
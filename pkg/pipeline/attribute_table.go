@@ -0,0 +1,214 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+	"github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+// AttributeTableConfig configures the extraction of attributes from a corpus into a table
+// with one row per item and one column per attribute
+type AttributeTableConfig struct {
+	// Attributes are the attributes to extract, which become the table's columns in order.
+	// Their types set the column types, see builtin.AttributeDefinition.
+	Attributes []builtin.AttributeDefinition `json:"attributes" yaml:"attributes"`
+	// Processor is the processor whose results are read (defaults to "get_attributes")
+	Processor string `json:"processor,omitempty" yaml:"processor,omitempty"`
+	// IDColumn is the column holding item IDs (defaults to "id")
+	IDColumn string `json:"id_column,omitempty" yaml:"id_column,omitempty"`
+	// MetadataColumns are item metadata keys written as string columns after the ID
+	MetadataColumns []string `json:"metadata_columns,omitempty" yaml:"metadata_columns,omitempty"`
+	// Confidence adds a number column named after each attribute with a "_confidence"
+	// suffix, holding the confidence of its value
+	Confidence bool `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+	// Workers is the number of items processed at once by ExtractAttributeTable
+	// (defaults to data.DefaultWorkers)
+	Workers int `json:"workers,omitempty" yaml:"workers,omitempty"`
+	// BatchSize is the number of rows written at once (defaults to data.DefaultBatchSize)
+	BatchSize int `json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+}
+
+// withDefaults returns the config with defaults applied
+func (c AttributeTableConfig) withDefaults() AttributeTableConfig {
+	if c.Processor == "" {
+		c.Processor = "get_attributes"
+	}
+	if c.IDColumn == "" {
+		c.IDColumn = "id"
+	}
+	return c
+}
+
+// Columns returns the columns of the table: the ID, the metadata columns, then each
+// attribute followed by its confidence if the config asks for it
+func (c AttributeTableConfig) Columns() []data.TableColumn {
+	c = c.withDefaults()
+	columns := []data.TableColumn{{Name: c.IDColumn, Type: data.ColumnString}}
+	for _, key := range c.MetadataColumns {
+		columns = append(columns, data.TableColumn{Name: key, Type: data.ColumnString})
+	}
+	for _, attribute := range c.Attributes {
+		columns = append(columns, data.TableColumn{Name: attribute.FieldName, Type: attributeColumnType(attribute)})
+		if c.Confidence {
+			columns = append(columns, data.TableColumn{Name: attribute.FieldName + "_confidence", Type: data.ColumnNumber})
+		}
+	}
+	return columns
+}
+
+// attributeColumnType returns the column type of an attribute's values
+func attributeColumnType(attribute builtin.AttributeDefinition) data.ColumnType {
+	switch attribute.AttributeType() {
+	case builtin.AttributeNumber:
+		return data.ColumnNumber
+	case builtin.AttributeBoolean:
+		return data.ColumnBoolean
+	case builtin.AttributeDate:
+		return data.ColumnDate
+	default:
+		return data.ColumnString
+	}
+}
+
+// Row returns an item's row of the table, in the order of Columns. Attributes the item's
+// result lacks, or whose value doesn't conform to their type, are null.
+func (c AttributeTableConfig) Row(item *data.ProcessItem) ([]interface{}, error) {
+	c = c.withDefaults()
+	row := []interface{}{item.ID}
+	for _, key := range c.MetadataColumns {
+		if value, ok := item.Metadata[key]; ok && value != nil {
+			row = append(row, fmt.Sprint(value))
+		} else {
+			row = append(row, nil)
+		}
+	}
+
+	extracted, err := extractedAttributes(item, c.Processor)
+	if err != nil {
+		return nil, err
+	}
+	for _, definition := range c.Attributes {
+		attribute, ok := extracted[strings.ToLower(definition.FieldName)]
+		var value interface{}
+		if ok {
+			// Values that don't conform are left null
+			if coerced, err := definition.Coerce(attribute.Value); err == nil {
+				value, _ = definition.TypedValue(coerced)
+			}
+		}
+		row = append(row, value)
+		if c.Confidence {
+			if ok && value != nil {
+				row = append(row, attribute.Confidence)
+			} else {
+				row = append(row, nil)
+			}
+		}
+	}
+	return row, nil
+}
+
+// extractedAttributes returns the attributes a processor extracted for an item, by
+// lower-cased field name
+func extractedAttributes(item *data.ProcessItem, processorName string) (map[string]builtin.Attribute, error) {
+	info, ok := item.ProcessingInfo[processorName]
+	if !ok {
+		return nil, fmt.Errorf("item '%s' has no %s result", item.ID, processorName)
+	}
+	// The result is a map holding the result struct's fields, or generic values once the
+	// item has been through JSON
+	encoded, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("item '%s': failed to read %s result: %w", item.ID, processorName, err)
+	}
+	var result builtin.AttributeResult
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return nil, fmt.Errorf("item '%s': failed to read %s result: %w", item.ID, processorName, err)
+	}
+
+	attributes := make(map[string]builtin.Attribute, len(result.Attributes))
+	for _, attribute := range result.Attributes {
+		key := strings.ToLower(attribute.FieldName)
+		if _, ok := attributes[key]; !ok {
+			attributes[key] = attribute
+		}
+	}
+	return attributes, nil
+}
+
+// AttributeTableSink implements data.ProcessItemSink by writing each item's extracted
+// attributes as a row of a table
+type AttributeTableSink struct {
+	config AttributeTableConfig
+	writer data.TableWriter
+}
+
+// NewAttributeTableSink creates a sink writing rows to a table writer created with the
+// config's Columns
+func NewAttributeTableSink(writer data.TableWriter, config AttributeTableConfig) *AttributeTableSink {
+	return &AttributeTableSink{config: config.withDefaults(), writer: writer}
+}
+
+// Write implements the data.ProcessItemSink interface
+func (s *AttributeTableSink) Write(ctx context.Context, items []*data.ProcessItem) error {
+	rows := make([][]interface{}, 0, len(items))
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		row, err := s.config.Row(item)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+	return s.writer.WriteRows(ctx, rows)
+}
+
+// Flush implements the data.ProcessItemSink interface
+func (s *AttributeTableSink) Flush(ctx context.Context) error {
+	return s.writer.Flush(ctx)
+}
+
+// Close implements the data.ProcessItemSink interface, closing the table writer
+func (s *AttributeTableSink) Close() error {
+	return s.writer.Close()
+}
+
+// ExtractAttributeTable runs the config's processor on every item of a source, with the
+// config's attributes as its attributes input, and writes one row per item to writer in
+// source order. The writer is flushed but not closed. Processing stops at the first item
+// that fails; set a retry policy in options to retry transient failures.
+func ExtractAttributeTable(ctx context.Context, provider llm.Provider, source data.ProcessItemSource, writer data.TableWriter, config AttributeTableConfig, options processor.Options) error {
+	config = config.withDefaults()
+	if len(config.Attributes) == 0 {
+		return fmt.Errorf("attribute table: at least one attribute is required")
+	}
+	for _, attribute := range config.Attributes {
+		if attribute.FieldName == "" {
+			return fmt.Errorf("attribute table: attribute field name is required")
+		}
+	}
+
+	options = options.WithInput(builtin.AttributesInput, config.Attributes)
+	if options.ResultOrder == "" {
+		options = options.WithResultOrder(data.OrderSource)
+	}
+	proc, err := processor.Create(config.Processor, provider, options)
+	if err != nil {
+		return fmt.Errorf("attribute table: %w", err)
+	}
+	workers := config.Workers
+	if workers <= 0 {
+		workers = data.DefaultWorkers
+	}
+
+	results := proc.ProcessSourceStream(ctx, source, workers)
+	return data.WriteStream(ctx, results, &AttributeTableSink{config: config, writer: writer}, config.BatchSize)
+}
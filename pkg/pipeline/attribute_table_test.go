@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+	"github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+func TestExtractAttributeTable(t *testing.T) {
+	provider := llm.NewMockProviderWithResponse(`{"attributes": [
+		{"field_name": "Refund_Amount", "value": "$1,200.50", "confidence": 0.9, "explanation": "stated"},
+		{"field_name": "resolved", "value": "maybe", "confidence": 0.4, "explanation": "unclear"},
+		{"field_name": "channel", "value": "phone", "confidence": 0.8, "explanation": "stated"}
+	]}`)
+	config := AttributeTableConfig{
+		Attributes: []builtin.AttributeDefinition{
+			{FieldName: "refund_amount", Title: "Refund amount", Description: "The amount refunded", Type: builtin.AttributeNumber},
+			{FieldName: "resolved", Title: "Resolved", Description: "Whether the issue was resolved", Type: builtin.AttributeBoolean},
+			{FieldName: "channel", Title: "Channel", Description: "The contact channel"},
+		},
+		MetadataColumns: []string{"agent"},
+		Confidence:      true,
+	}
+	source := data.NewProcessItemSliceSource([]*data.ProcessItem{
+		data.NewTextProcessItem("c1", "I was refunded $1,200.50 on the phone.", map[string]interface{}{"agent": "ana"}),
+		data.NewTextProcessItem("c2", "Still waiting on my refund.", nil),
+	})
+
+	var buffer bytes.Buffer
+	writer, err := data.NewCSVTableWriter(&buffer, config.Columns())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractAttributeTable(context.Background(), provider, source, writer, config, processor.NewDefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Values are coerced to their types, and those that don't conform are left empty
+	want := "id,agent,refund_amount,refund_amount_confidence,resolved,resolved_confidence,channel,channel_confidence\n" +
+		"c1,ana,1200.5,0.9,,,phone,0.8\n" +
+		"c2,,1200.5,0.9,,,phone,0.8\n"
+	if buffer.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buffer.String())
+	}
+
+	if err := ExtractAttributeTable(context.Background(), provider, source, writer, AttributeTableConfig{}, processor.NewDefaultOptions()); err == nil {
+		t.Error("expected an error without attributes")
+	}
+}
@@ -0,0 +1,181 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// Budget limits the resources a run may use. Zero values mean no limit.
+type Budget struct {
+	// MaxCost is the maximum total cost reported by the chain's processors, which price
+	// their usage with processor.Options.WithTokenCost
+	MaxCost float64 `json:"max_cost,omitempty"`
+	// MaxTokens is the maximum total token usage reported by the chain's processors
+	MaxTokens int64 `json:"max_tokens,omitempty"`
+	// MaxDuration is the maximum wall-clock time after which no new items are started
+	MaxDuration time.Duration `json:"max_duration,omitempty"`
+}
+
+// Budget limits that can stop a run
+const (
+	BudgetLimitCost     = "max_cost"
+	BudgetLimitTokens   = "max_tokens"
+	BudgetLimitDuration = "max_duration"
+)
+
+// BudgetReport describes the resources a budgeted run used and whether it was cut short
+type BudgetReport struct {
+	// Budget is the budget the run was given
+	Budget Budget `json:"budget"`
+	// Items is the number of items processed through the whole chain
+	Items int `json:"items"`
+	// Tokens is the total token usage
+	Tokens int64 `json:"tokens"`
+	// Cost is the total cost
+	Cost float64 `json:"cost"`
+	// Duration is the wall-clock time of the run
+	Duration time.Duration `json:"duration"`
+	// Exceeded reports whether the run stopped before the source was exhausted
+	Exceeded bool `json:"exceeded"`
+	// Limit names the limit that stopped the run (BudgetLimitCost, BudgetLimitTokens or BudgetLimitDuration)
+	Limit string `json:"limit,omitempty"`
+}
+
+// exceeded returns the first limit the usage has reached, or "" if none
+func (b Budget) exceeded(tokens int64, cost float64, elapsed time.Duration) string {
+	switch {
+	case b.MaxCost > 0 && cost >= b.MaxCost:
+		return BudgetLimitCost
+	case b.MaxTokens > 0 && tokens >= b.MaxTokens:
+		return BudgetLimitTokens
+	case b.MaxDuration > 0 && elapsed >= b.MaxDuration:
+		return BudgetLimitDuration
+	default:
+		return ""
+	}
+}
+
+// ProcessSourceWithBudget processes a data source through the chain one item at a time
+// with up to workers items in flight, and stops dispatching new items once the budget
+// is used up. Items already in flight are completed, so usage can overshoot the budget
+// by up to workers items. It returns the items completed so far, in source order, with
+// a report of the resources used. If ctx is cancelled or an item fails, the items
+// completed until then are returned with the error.
+func (c *Chain) ProcessSourceWithBudget(ctx context.Context, source data.ProcessItemSource, workers int, budget Budget) ([]*data.ProcessItem, *BudgetReport, error) {
+	if len(c.steps) == 0 {
+		return nil, nil, fmt.Errorf("empty processor chain")
+	}
+	if workers <= 0 {
+		workers = data.DefaultWorkers
+	}
+
+	start := time.Now()
+	report := &BudgetReport{Budget: budget}
+	tracker := data.NewProgressTracker(ctx, data.SourceLen(source))
+	itemCtx := data.WithoutProgress(ctx)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		index int
+		item  *data.ProcessItem
+	}
+
+	var (
+		mu       sync.Mutex
+		results  = make(map[int]*data.ProcessItem)
+		firstErr error
+	)
+
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result, err := c.Process(itemCtx, j.item)
+				tracker.Record(err)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("item '%s': %w", j.item.ID, err)
+						cancel()
+					}
+				} else {
+					results[j.index] = result
+					tokens, cost := itemUsage(result)
+					inputTokens, inputCost := itemUsage(j.item)
+					report.Tokens += tokens - inputTokens
+					report.Cost += cost - inputCost
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	dispatched := 0
+	var readErr error
+	for {
+		mu.Lock()
+		limit := budget.exceeded(report.Tokens, report.Cost, time.Since(start))
+		mu.Unlock()
+		if limit != "" {
+			report.Exceeded = true
+			report.Limit = limit
+			break
+		}
+
+		item, err := source.NextProcessItem(runCtx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+
+		select {
+		case jobs <- job{index: dispatched, item: item}:
+			dispatched++
+		case <-runCtx.Done():
+		}
+		if runCtx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	report.Duration = time.Since(start)
+	completed := make([]*data.ProcessItem, 0, len(results))
+	for i := 0; i < dispatched; i++ {
+		if result, ok := results[i]; ok {
+			completed = append(completed, result)
+		}
+	}
+	report.Items = len(completed)
+
+	// Items failing because of the cancellation don't count as failures
+	if err := ctx.Err(); err != nil {
+		return completed, report, err
+	}
+	if firstErr != nil {
+		return completed, report, firstErr
+	}
+	if readErr != nil {
+		return completed, report, readErr
+	}
+
+	if err := c.runAggregators(ctx, completed); err != nil {
+		return nil, report, err
+	}
+	return completed, report, nil
+}
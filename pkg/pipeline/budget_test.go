@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+	_ "github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+// sentimentResponse is a valid response of the builtin sentiment processor
+const sentimentResponse = `{"sentiment": "positive", "score": 0.8, "confidence": 0.9, "keywords": ["great"]}`
+
+// newSentiment creates the builtin sentiment processor answering every prompt with sentimentResponse
+func newSentiment(t *testing.T, options processor.Options) processor.Processor {
+	t.Helper()
+	proc, err := processor.Create("sentiment", llm.NewMockProviderWithResponse(sentimentResponse), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proc
+}
+
+// textSource returns a source of n text items with IDs item-0 to item-<n-1>
+func textSource(n int) data.ProcessItemSource {
+	items := make([]*data.ProcessItem, n)
+	for i := range items {
+		items[i] = data.NewTextProcessItem(fmt.Sprintf("item-%d", i), "The support team fixed my issue in minutes.", nil)
+	}
+	return data.NewProcessItemSliceSource(items)
+}
+
+func TestProcessSourceWithBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		budget    Budget
+		exceeded  bool
+		limit     string
+		wantItems func(int) bool
+	}{
+		{
+			name:      "no limit",
+			budget:    Budget{},
+			wantItems: func(n int) bool { return n == 10 },
+		},
+		{
+			// With one worker, at most the item in flight and the one dispatched while it
+			// ran complete before the usage is seen
+			name:      "token limit",
+			budget:    Budget{MaxTokens: 1},
+			exceeded:  true,
+			limit:     BudgetLimitTokens,
+			wantItems: func(n int) bool { return n >= 1 && n <= 2 },
+		},
+		{
+			name:      "cost limit",
+			budget:    Budget{MaxCost: 1e-9},
+			exceeded:  true,
+			limit:     BudgetLimitCost,
+			wantItems: func(n int) bool { return n >= 1 && n <= 2 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := processor.NewDefaultOptions().WithTokenCost(1, 1)
+			chain := NewChain("budget", newSentiment(t, options))
+
+			results, report, err := chain.ProcessSourceWithBudget(context.Background(), textSource(10), 1, tt.budget)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.wantItems(len(results)) {
+				t.Errorf("unexpected number of results: %d", len(results))
+			}
+			if report.Items != len(results) {
+				t.Errorf("report counts %d items, got %d results", report.Items, len(results))
+			}
+			if report.Exceeded != tt.exceeded || report.Limit != tt.limit {
+				t.Errorf("expected exceeded=%v limit=%q, got exceeded=%v limit=%q", tt.exceeded, tt.limit, report.Exceeded, report.Limit)
+			}
+			if report.Tokens <= 0 || report.Cost <= 0 {
+				t.Errorf("expected usage to be reported, got %d tokens and cost %g", report.Tokens, report.Cost)
+			}
+		})
+	}
+}
+
+func TestProcessSourceWithBudgetPartialResults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failure := errors.New("step failed")
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		fail    func(item *data.ProcessItem) error
+		wantErr error
+	}{
+		{
+			name: "step error",
+			ctx:  context.Background(),
+			// Items dispatched after the failure fail too
+			fail: func(item *data.ProcessItem) error {
+				if item.ID >= "item-3" {
+					return failure
+				}
+				return nil
+			},
+			wantErr: failure,
+		},
+		{
+			name: "cancellation",
+			ctx:  ctx,
+			fail: func(item *data.ProcessItem) error {
+				if item.ID == "item-3" {
+					cancel()
+				}
+				return ctx.Err()
+			},
+			wantErr: context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &funcStep{name: "step", fn: func(_ context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+				if err := tt.fail(item); err != nil {
+					return nil, err
+				}
+				return item, nil
+			}}
+			chain := NewChain("budget").AddStep(step)
+
+			results, report, err := chain.ProcessSourceWithBudget(tt.ctx, textSource(10), 1, Budget{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			var ids []string
+			for _, result := range results {
+				ids = append(ids, result.ID)
+			}
+			if want := []string{"item-0", "item-1", "item-2"}; !reflect.DeepEqual(ids, want) {
+				t.Errorf("expected completed items %v, got %v", want, ids)
+			}
+			if report.Items != len(results) {
+				t.Errorf("report counts %d items, got %d results", report.Items, len(results))
+			}
+		})
+	}
+}
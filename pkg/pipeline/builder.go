@@ -0,0 +1,123 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// StepOptions overrides the chain's defaults for a single step
+type StepOptions struct {
+	// Provider replaces the chain's default provider for this step
+	Provider llm.Provider
+	// Model runs the step on a different model of its provider
+	Model string
+	// Options are the processor options for this step (defaults to the chain's options)
+	Options *processor.Options
+}
+
+// ChainBuilder constructs a Chain from registered processor types, with a default provider
+// that individual steps can override
+type ChainBuilder struct {
+	name     string
+	provider llm.Provider
+	options  processor.Options
+	chain    *Chain
+	models   map[llm.Provider]map[string]llm.Provider
+	err      error
+}
+
+// NewChainBuilder creates a new chain builder whose steps use provider unless overridden
+func NewChainBuilder(name string, provider llm.Provider) *ChainBuilder {
+	return &ChainBuilder{
+		name:     name,
+		provider: provider,
+		options:  processor.NewDefaultOptions(),
+		chain:    NewChain(name),
+		models:   make(map[llm.Provider]map[string]llm.Provider),
+	}
+}
+
+// WithOptions sets the default processor options for the chain's steps
+func (b *ChainBuilder) WithOptions(options processor.Options) *ChainBuilder {
+	b.options = options
+	return b
+}
+
+// Step appends a processor of the given type using the chain's defaults
+func (b *ChainBuilder) Step(processorType string) *ChainBuilder {
+	return b.StepWith(processorType, StepOptions{})
+}
+
+// StepWith appends a processor of the given type with per-step overrides
+func (b *ChainBuilder) StepWith(processorType string, overrides StepOptions) *ChainBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	proc, err := b.create(processorType, overrides)
+	if err != nil {
+		b.err = fmt.Errorf("step '%s': %w", processorType, err)
+		return b
+	}
+	b.chain.Add(proc)
+	return b
+}
+
+// AddStep appends an already constructed step
+func (b *ChainBuilder) AddStep(step Step) *ChainBuilder {
+	b.chain.AddStep(step)
+	return b
+}
+
+// Build returns the chain, or the first error encountered while adding steps
+func (b *ChainBuilder) Build() (*Chain, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.chain, nil
+}
+
+// create builds a processor with the step's provider, model and options
+func (b *ChainBuilder) create(processorType string, overrides StepOptions) (processor.Processor, error) {
+	provider := overrides.Provider
+	if provider == nil {
+		provider = b.provider
+	}
+	if provider == nil {
+		return nil, fmt.Errorf("no provider configured")
+	}
+
+	provider, err := b.withModel(provider, overrides.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	options := b.options
+	if overrides.Options != nil {
+		options = *overrides.Options
+	}
+
+	return processor.Create(processorType, provider, options.Clone())
+}
+
+// withModel returns provider switched to model, reusing providers already created for the same model
+func (b *ChainBuilder) withModel(provider llm.Provider, model string) (llm.Provider, error) {
+	if model == "" {
+		return provider, nil
+	}
+	if cached, ok := b.models[provider][model]; ok {
+		return cached, nil
+	}
+
+	switched, err := llm.WithModel(provider, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider for model %s: %w", model, err)
+	}
+	if b.models[provider] == nil {
+		b.models[provider] = make(map[string]llm.Provider)
+	}
+	b.models[provider][model] = switched
+	return switched, nil
+}
@@ -0,0 +1,271 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+// DefaultCatalogSimilarity is the cosine similarity at which an AttributeCatalog merges
+// two attribute definitions with different field names
+const DefaultCatalogSimilarity = 0.85
+
+// CatalogConfig holds the configuration of an AttributeCatalog
+type CatalogConfig struct {
+	// Embedder embeds definitions to merge semantically equivalent ones, e.g. the Embedder
+	// of the provider (see llm.EmbedderOf). Without one, only definitions with the same
+	// field name are merged.
+	Embedder llm.Embedder
+	// Similarity is the cosine similarity at which definitions are merged (defaults to
+	// DefaultCatalogSimilarity)
+	Similarity float64
+	// Processor is the processor whose results AddItems reads (defaults to
+	// "required_attributes")
+	Processor string
+}
+
+// CatalogAttribute is a canonical attribute of an AttributeCatalog: the first definition
+// of the attribute seen, with the provenance of every definition merged into it
+type CatalogAttribute struct {
+	builtin.AttributeDefinition
+	// Aliases are the other field names merged into the attribute
+	Aliases []string `json:"aliases,omitempty"`
+	// Questions are the distinct question sets that required the attribute
+	Questions []string `json:"questions,omitempty"`
+	// Sources are the definitions merged into the attribute, in the order they were added
+	Sources []AttributeSource `json:"sources"`
+}
+
+// AttributeSource records a definition merged into a catalog attribute
+type AttributeSource struct {
+	// ItemID is the item whose questions the definition was produced for
+	ItemID string `json:"item_id"`
+	// FieldName is the definition's own field name
+	FieldName string `json:"field_name"`
+	// Similarity is the cosine similarity of the definition to the attribute's, 1 for the
+	// same field name
+	Similarity float64 `json:"similarity"`
+}
+
+// catalogEntry is a catalog attribute with the vector its definitions are compared with
+type catalogEntry struct {
+	attribute CatalogAttribute
+	vector    []float64
+	names     map[string]bool
+	questions map[string]bool
+	order     int
+}
+
+// AttributeCatalog merges the attribute definitions required_attributes produces for many
+// question sets into one catalog of canonical attributes. Definitions with the same field
+// name, or whose embeddings are similar enough, are merged, keeping where each came from.
+// A catalog can be added to across batches and runs; it is safe for concurrent use.
+type AttributeCatalog struct {
+	config  CatalogConfig
+	mu      sync.Mutex
+	entries []*catalogEntry
+}
+
+// NewAttributeCatalog creates an empty catalog
+func NewAttributeCatalog(config CatalogConfig) *AttributeCatalog {
+	if config.Similarity <= 0 {
+		config.Similarity = DefaultCatalogSimilarity
+	}
+	if config.Processor == "" {
+		config.Processor = "required_attributes"
+	}
+	return &AttributeCatalog{config: config}
+}
+
+// Add merges the attribute definitions produced for one question set into the catalog
+func (c *AttributeCatalog) Add(ctx context.Context, itemID, questions string, definitions []builtin.AttributeDefinition) error {
+	var valid []builtin.AttributeDefinition
+	for _, definition := range definitions {
+		if normalizeFieldName(definition.FieldName) != "" {
+			valid = append(valid, definition)
+		}
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+
+	var vectors [][]float64
+	if c.config.Embedder != nil {
+		texts := make([]string, len(valid))
+		for i, definition := range valid {
+			texts[i] = definitionText(definition)
+		}
+		var err error
+		if vectors, err = c.config.Embedder.Embed(ctx, texts); err != nil {
+			return fmt.Errorf("failed to embed attribute definitions: %w", err)
+		}
+		if len(vectors) != len(valid) {
+			return fmt.Errorf("failed to embed attribute definitions: got %d vectors for %d definitions", len(vectors), len(valid))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	questions = strings.TrimSpace(questions)
+	for i, definition := range valid {
+		var vector []float64
+		if vectors != nil {
+			vector = vectors[i]
+		}
+		entry, similarity := c.match(definition, vector)
+		if entry == nil {
+			entry = &catalogEntry{
+				attribute: CatalogAttribute{AttributeDefinition: definition},
+				vector:    vector,
+				names:     make(map[string]bool),
+				questions: make(map[string]bool),
+				order:     len(c.entries),
+			}
+			c.entries = append(c.entries, entry)
+			similarity = 1
+		}
+
+		name := normalizeFieldName(definition.FieldName)
+		if !entry.names[name] {
+			if len(entry.names) > 0 {
+				entry.attribute.Aliases = append(entry.attribute.Aliases, definition.FieldName)
+			}
+			entry.names[name] = true
+		}
+		if questions != "" && !entry.questions[questions] {
+			entry.questions[questions] = true
+			entry.attribute.Questions = append(entry.attribute.Questions, questions)
+		}
+		entry.attribute.Sources = append(entry.attribute.Sources, AttributeSource{
+			ItemID:     itemID,
+			FieldName:  definition.FieldName,
+			Similarity: similarity,
+		})
+	}
+	return nil
+}
+
+// match returns the entry a definition merges into and their similarity, or nil if it is
+// a new attribute. Field names match first, then the most similar embedding.
+func (c *AttributeCatalog) match(definition builtin.AttributeDefinition, vector []float64) (*catalogEntry, float64) {
+	name := normalizeFieldName(definition.FieldName)
+	for _, entry := range c.entries {
+		if entry.names[name] {
+			return entry, 1
+		}
+	}
+	if vector == nil {
+		return nil, 0
+	}
+
+	var (
+		best           *catalogEntry
+		bestSimilarity float64
+	)
+	for _, entry := range c.entries {
+		similarity := llm.CosineSimilarity(vector, entry.vector)
+		if similarity >= c.config.Similarity && similarity > bestSimilarity {
+			best, bestSimilarity = entry, similarity
+		}
+	}
+	return best, bestSimilarity
+}
+
+// AddItems merges the attribute definitions in the items' results of the configured
+// processor, using each item's original text as its questions
+func (c *AttributeCatalog) AddItems(ctx context.Context, items []*data.ProcessItem) error {
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		values := ResultValues(item, c.config.Processor, "attributes")
+		if len(values) == 0 {
+			continue
+		}
+		encoded, err := json.Marshal(values)
+		if err != nil {
+			return fmt.Errorf("failed to encode attributes of item %s: %w", item.ID, err)
+		}
+		var definitions []builtin.AttributeDefinition
+		if err := json.Unmarshal(encoded, &definitions); err != nil {
+			return fmt.Errorf("failed to decode attributes of item %s: %w", item.ID, err)
+		}
+		if err := c.Add(ctx, item.ID, itemQuestions(item), definitions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Attributes returns the catalog's attributes, those required by the most sources first
+// and otherwise in the order they were first seen
+func (c *AttributeCatalog) Attributes() []CatalogAttribute {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := append([]*catalogEntry(nil), c.entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return len(entries[i].attribute.Sources) > len(entries[j].attribute.Sources)
+	})
+
+	attributes := make([]CatalogAttribute, len(entries))
+	for i, entry := range entries {
+		attribute := entry.attribute
+		attribute.Aliases = append([]string(nil), attribute.Aliases...)
+		attribute.Questions = append([]string(nil), attribute.Questions...)
+		attribute.Sources = append([]AttributeSource(nil), attribute.Sources...)
+		attributes[i] = attribute
+	}
+	return attributes
+}
+
+// Aggregator returns an AggregateFunc adding each run's items to the catalog and returning
+// its attributes, e.g. chain.AddAggregator("attribute_catalog", catalog.Aggregator()). The
+// catalog keeps growing across runs; use a new catalog for a per-run catalog.
+func (c *AttributeCatalog) Aggregator() AggregateFunc {
+	return func(ctx context.Context, items []*data.ProcessItem) (interface{}, error) {
+		if err := c.AddItems(ctx, items); err != nil {
+			return nil, err
+		}
+		return c.Attributes(), nil
+	}
+}
+
+// normalizeFieldName returns a field name in lower snake_case, so "Customer ID" and
+// "customer_id" are the same attribute
+func normalizeFieldName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '_' || r == '-' || r == ' ' || r == '.'
+	})
+	return strings.Join(fields, "_")
+}
+
+// definitionText is the text of a definition that is embedded
+func definitionText(definition builtin.AttributeDefinition) string {
+	text := strings.ReplaceAll(definition.FieldName, "_", " ")
+	if definition.Title != "" {
+		text += ": " + definition.Title
+	}
+	if definition.Description != "" {
+		text += ". " + definition.Description
+	}
+	return text
+}
+
+// itemQuestions returns the text an item's attributes were produced for
+func itemQuestions(item *data.ProcessItem) string {
+	if text, ok := item.Metadata["original_text"].(string); ok {
+		return text
+	}
+	if text, ok := item.Content.(string); ok {
+		return text
+	}
+	return ""
+}
@@ -0,0 +1,212 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+// definition returns an attribute definition with a field name and title
+func definition(fieldName, title string) builtin.AttributeDefinition {
+	return builtin.AttributeDefinition{FieldName: fieldName, Title: title}
+}
+
+// catalogNames returns the field names of a catalog's attributes, in order
+func catalogNames(attributes []CatalogAttribute) []string {
+	names := make([]string, len(attributes))
+	for i, attribute := range attributes {
+		names[i] = attribute.FieldName
+	}
+	return names
+}
+
+func TestAttributeCatalogFieldNames(t *testing.T) {
+	ctx := context.Background()
+	catalog := NewAttributeCatalog(CatalogConfig{})
+	adds := []struct {
+		itemID, questions string
+		definitions       []builtin.AttributeDefinition
+	}{
+		{"1", "Why did they call?", []builtin.AttributeDefinition{definition("order_date", "Order Date"), definition("customer_id", "Customer ID")}},
+		{"2", " Was it resolved? ", []builtin.AttributeDefinition{definition("Customer ID", "Customer"), definition("refund_amount", "Refund"), definition(" _ ", "Blank")}},
+		{"3", "Was it resolved?", []builtin.AttributeDefinition{definition("customer-id", "Customer"), definition("refund_amount", "Refund")}},
+	}
+	for _, add := range adds {
+		if err := catalog.Add(ctx, add.itemID, add.questions, add.definitions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	attributes := catalog.Attributes()
+	// Most sources first, then in the order first seen; the blank field name is dropped
+	if names := catalogNames(attributes); !reflect.DeepEqual(names, []string{"customer_id", "refund_amount", "order_date"}) {
+		t.Fatalf("unexpected attributes %v", names)
+	}
+
+	customer := attributes[0]
+	if customer.Title != "Customer ID" {
+		t.Errorf("expected the first definition to be canonical, got title %q", customer.Title)
+	}
+	// Spellings of the same field name aren't aliases
+	if len(customer.Aliases) != 0 {
+		t.Errorf("expected no aliases, got %v", customer.Aliases)
+	}
+	if want := []string{"Why did they call?", "Was it resolved?"}; !reflect.DeepEqual(customer.Questions, want) {
+		t.Errorf("expected distinct questions %v, got %v", want, customer.Questions)
+	}
+	wantSources := []AttributeSource{
+		{ItemID: "1", FieldName: "customer_id", Similarity: 1},
+		{ItemID: "2", FieldName: "Customer ID", Similarity: 1},
+		{ItemID: "3", FieldName: "customer-id", Similarity: 1},
+	}
+	if !reflect.DeepEqual(customer.Sources, wantSources) {
+		t.Errorf("expected sources %+v, got %+v", wantSources, customer.Sources)
+	}
+	if refund := attributes[1]; len(refund.Aliases) != 0 || len(refund.Sources) != 2 {
+		t.Errorf("expected refund_amount from two sources without aliases, got %+v", refund)
+	}
+
+	// Attributes are copies
+	attributes[0].Sources[0].ItemID = "changed"
+	if catalog.Attributes()[0].Sources[0].ItemID != "1" {
+		t.Error("expected Attributes to return copies")
+	}
+}
+
+func TestAttributeCatalogSimilarity(t *testing.T) {
+	ctx := context.Background()
+	// newEmbedder embeds the client definition 0.95 similar to the customer one and the
+	// account holder 0.8 similar; entries are compared by their first definition's vector
+	newEmbedder := func() *llm.MockProvider {
+		return llm.NewMockProviderWithResponse(`{}`).
+			WithEmbedding("customer id", []float64{1, 0, 0}).
+			WithEmbedding("client number", []float64{0.95, 0.3, 0}).
+			WithEmbedding("account holder", []float64{0.8, 0.6, 0}).
+			WithEmbedding("refund amount", []float64{0, 0, 1})
+	}
+	definitions := []builtin.AttributeDefinition{
+		definition("customer_id", "Customer"),
+		definition("refund_amount", "Refund"),
+		definition("client_number", "Client"),
+		definition("account_holder", "Account holder"),
+	}
+
+	tests := []struct {
+		name       string
+		embedder   llm.Embedder
+		similarity float64
+		want       []string
+		// wantAliases are the aliases of the first attribute
+		wantAliases []string
+	}{
+		{
+			name:        "default similarity",
+			embedder:    newEmbedder(),
+			want:        []string{"customer_id", "refund_amount", "account_holder"},
+			wantAliases: []string{"client_number"},
+		},
+		{
+			name:        "low similarity",
+			embedder:    newEmbedder(),
+			similarity:  0.75,
+			want:        []string{"customer_id", "refund_amount"},
+			wantAliases: []string{"client_number", "account_holder"},
+		},
+		{
+			name:       "high similarity",
+			embedder:   newEmbedder(),
+			similarity: 0.96,
+			want:       []string{"customer_id", "refund_amount", "client_number", "account_holder"},
+		},
+		{
+			name: "no embedder",
+			want: []string{"customer_id", "refund_amount", "client_number", "account_holder"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := NewAttributeCatalog(CatalogConfig{Embedder: tt.embedder, Similarity: tt.similarity})
+			for i, definition := range definitions {
+				if err := catalog.Add(ctx, string(rune('1'+i)), "", []builtin.AttributeDefinition{definition}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			attributes := catalog.Attributes()
+			if names := catalogNames(attributes); !reflect.DeepEqual(names, tt.want) {
+				t.Fatalf("expected attributes %v, got %v", tt.want, names)
+			}
+			if !reflect.DeepEqual(attributes[0].Aliases, tt.wantAliases) {
+				t.Errorf("expected aliases %v, got %v", tt.wantAliases, attributes[0].Aliases)
+			}
+			for _, source := range attributes[0].Sources[1:] {
+				if source.Similarity >= 1 || source.Similarity < catalog.config.Similarity {
+					t.Errorf("expected the similarity of merged source %s, got %v", source.FieldName, source.Similarity)
+				}
+			}
+		})
+	}
+
+	t.Run("similarity recorded", func(t *testing.T) {
+		catalog := NewAttributeCatalog(CatalogConfig{Embedder: newEmbedder()})
+		if err := catalog.Add(ctx, "1", "", definitions[:1]); err != nil {
+			t.Fatal(err)
+		}
+		if err := catalog.Add(ctx, "2", "", definitions[2:3]); err != nil {
+			t.Fatal(err)
+		}
+		source := catalog.Attributes()[0].Sources[1]
+		if want := 0.95 / math.Sqrt(0.95*0.95+0.3*0.3); math.Abs(source.Similarity-want) > 1e-9 {
+			t.Errorf("expected similarity %v, got %v", want, source.Similarity)
+		}
+	})
+
+	t.Run("embedder error", func(t *testing.T) {
+		catalog := NewAttributeCatalog(CatalogConfig{Embedder: newEmbedder().WithError(errors.New("quota exceeded"))})
+		err := catalog.Add(ctx, "1", "", definitions)
+		if err == nil || len(catalog.Attributes()) != 0 {
+			t.Errorf("expected the error and no attributes, got %v", err)
+		}
+	})
+}
+
+func TestAttributeCatalogAddItems(t *testing.T) {
+	// attributeItem returns an item with required_attributes results for the questions
+	attributeItem := func(id, questions string, fieldNames ...string) *data.ProcessItem {
+		var attributes []interface{}
+		for _, name := range fieldNames {
+			attributes = append(attributes, map[string]interface{}{"field_name": name, "title": name})
+		}
+		item := data.NewTextProcessItem(id, "", map[string]interface{}{"original_text": questions})
+		item.AddProcessingInfo("required_attributes", map[string]interface{}{"attributes": attributes})
+		return item
+	}
+	items := []*data.ProcessItem{
+		attributeItem("1", "Why did they call?", "reason", "customer_id"),
+		nil,
+		data.NewTextProcessItem("2", "No results", nil),
+		attributeItem("3", "Who called?", "customer_id"),
+	}
+
+	catalog := NewAttributeCatalog(CatalogConfig{})
+	if err := catalog.AddItems(context.Background(), items); err != nil {
+		t.Fatal(err)
+	}
+	attributes := catalog.Attributes()
+	if names := catalogNames(attributes); !reflect.DeepEqual(names, []string{"customer_id", "reason"}) {
+		t.Fatalf("unexpected attributes %v", names)
+	}
+	if want := []string{"Why did they call?", "Who called?"}; !reflect.DeepEqual(attributes[0].Questions, want) {
+		t.Errorf("expected the items' original texts as questions %v, got %v", want, attributes[0].Questions)
+	}
+	if sources := attributes[0].Sources; len(sources) != 2 || sources[0].ItemID != "1" || sources[1].ItemID != "3" {
+		t.Errorf("expected sources from items 1 and 3, got %+v", sources)
+	}
+}
@@ -3,6 +3,9 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"io"
+	"sync"
+	"time"
 
 	"github.com/eisenzopf/agentic-text/pkg/data"
 	"github.com/eisenzopf/agentic-text/pkg/processor"
@@ -10,37 +13,65 @@ import (
 
 // Chain represents a pipeline of processors
 type Chain struct {
-	processors []processor.Processor
-	name       string
+	steps          []Step
+	name           string
+	metadataPolicy *MetadataPolicy
+	lineage        bool
+	lineageSource  string
+	store          IntermediateStore
+	checkpoints    data.CheckpointStore
+	infoKeys       data.InfoKeyPolicy
+	priority       func(item *data.ProcessItem) int
+	order          data.ResultOrder
+	aggregators    []aggregator
+	statsMu        sync.Mutex
+	stats          []StepStats
+	aggregates     map[string]interface{}
 }
 
 // NewChain creates a new processor chain
 func NewChain(name string, processors ...processor.Processor) *Chain {
+	steps := make([]Step, len(processors))
+	for i, proc := range processors {
+		steps[i] = proc
+	}
 	return &Chain{
-		processors: processors,
-		name:       name,
+		steps: steps,
+		name:  name,
 	}
 }
 
 // Process processes a ProcessItem through the entire chain
 func (c *Chain) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
-	if len(c.processors) == 0 {
+	if len(c.steps) == 0 {
 		return nil, fmt.Errorf("empty processor chain")
 	}
+	return c.processFrom(ctx, item, 0)
+}
 
-	// Process with the first processor
-	result, err := c.processors[0].Process(ctx, item)
-	if err != nil {
-		return nil, fmt.Errorf("processor '%s' error: %w", c.processors[0].GetName(), err)
-	}
-
-	// Process with remaining processors, using the result from the previous step
-	for i := 1; i < len(c.processors); i++ {
-		proc := c.processors[i]
-		result, err = proc.Process(ctx, result)
+// processFrom processes an item through the steps starting at index first. An item paused
+// for review is returned without running the remaining steps.
+func (c *Chain) processFrom(ctx context.Context, item *data.ProcessItem, first int) (*data.ProcessItem, error) {
+	// Process each step, using the result from the previous step
+	result := item
+	for i := first; i < len(c.steps); i++ {
+		if PendingReview(result) {
+			break
+		}
+		step := c.stepAt(i)
+		started := time.Now()
+		next, err := step.Process(ctx, result)
+		c.recordStep(i, started, []*data.ProcessItem{result}, []*data.ProcessItem{next}, err)
 		if err != nil {
-			return nil, fmt.Errorf("processor '%s' error: %w", proc.GetName(), err)
+			return nil, fmt.Errorf("processor '%s' error: %w", step.GetName(), err)
+		}
+		if err := c.resolveInfoKeys(step.GetName(), []*data.ProcessItem{result}, []*data.ProcessItem{next}); err != nil {
+			return nil, err
+		}
+		if err := c.afterStep(step.GetName(), started, next); err != nil {
+			return nil, err
 		}
+		result = next
 	}
 
 	return result, nil
@@ -48,38 +79,144 @@ func (c *Chain) Process(ctx context.Context, item *data.ProcessItem) (*data.Proc
 
 // ProcessSource processes a data source through the chain
 func (c *Chain) ProcessSource(ctx context.Context, source data.ProcessItemSource, batchSize, workers int) ([]*data.ProcessItem, error) {
-	if len(c.processors) == 0 {
+	if len(c.steps) == 0 {
 		return nil, fmt.Errorf("empty processor chain")
 	}
 
-	// Use the first processor to process the source
-	firstResults, err := c.processors[0].ProcessSource(ctx, source, batchSize, workers)
+	start := time.Now()
+	steps := len(c.steps)
+
+	// Use the first step to process the source, recording the items read so the results
+	// can be compared with them
+	firstCtx := stepProgress(ctx, 0, steps, data.SourceLen(source), start)
+	read := &sourceInputs{}
+	firstResults, err := processSource(firstCtx, c.stepAt(0), read.wrap(source), batchSize, workers)
+	firstInputs := read.match(firstResults)
+	c.recordStep(0, start, firstInputs, firstResults, err)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.resolveInfoKeys(c.steps[0].GetName(), firstInputs, firstResults); err != nil {
+		return nil, err
+	}
+	if err := c.afterStep(c.steps[0].GetName(), start, firstResults...); err != nil {
+		return nil, err
+	}
 
-	// If there's only one processor, return the results
-	if len(c.processors) == 1 {
+	// If there's only one step, return the results
+	if len(c.steps) == 1 {
+		if err := c.runAggregators(ctx, firstResults); err != nil {
+			return nil, err
+		}
 		return firstResults, nil
 	}
 
-	// Process the results through the remaining processors
+	// Process the results through the remaining steps
 	currentResults := firstResults
-	for i := 1; i < len(c.processors); i++ {
-		proc := c.processors[i]
+	for i := 1; i < len(c.steps); i++ {
+		step := c.stepAt(i)
 
-		// Process with the next processor
-		nextResults, err := proc.ProcessBatch(ctx, currentResults)
+		// Process with the next step
+		stepCtx := stepProgress(ctx, i, steps, len(firstResults), start)
+		started := time.Now()
+		nextResults, processed, err := processActive(stepCtx, step, currentResults)
+		c.recordStep(i, started, currentResults, nextResults, err)
 		if err != nil {
 			return nil, err
 		}
+		if err := c.resolveInfoKeys(step.GetName(), currentResults, nextResults); err != nil {
+			return nil, err
+		}
+		if err := c.afterStep(step.GetName(), started, processed...); err != nil {
+			return nil, err
+		}
 
 		currentResults = nextResults
 	}
 
+	if err := c.runAggregators(ctx, currentResults); err != nil {
+		return nil, err
+	}
+
 	return currentResults, nil
 }
 
+// ProcessSourceWithReport processes a data source through the chain like ProcessSource and
+// returns a report of the run with a section for each processor step. The report is
+// returned even if the run fails, covering the items processed until then.
+func (c *Chain) ProcessSourceWithReport(ctx context.Context, source data.ProcessItemSource, batchSize, workers int, config data.ReportConfig) ([]*data.ProcessItem, *data.RunReport, error) {
+	recorder := data.NewRunRecorder(c.name, config)
+	results, err := c.ProcessSource(data.WithRunRecorder(ctx, recorder), source, batchSize, workers)
+	return results, recorder.Report(len(results), err), err
+}
+
+// ProcessSourceStream processes items from a source through the whole chain with the given
+// number of workers and sends each item on the returned channel as soon as its final step
+// completes. Results arrive in completion order, unless set otherwise with WithResultOrder;
+// an item that fails is delivered with its error and the remaining items continue. The channel is closed once the source is
+// exhausted or the context is cancelled. Aggregators are not run on streamed results.
+func (c *Chain) ProcessSourceStream(ctx context.Context, source data.ProcessItemSource, workers int) (<-chan data.ProcessResult, error) {
+	if len(c.steps) == 0 {
+		return nil, fmt.Errorf("empty processor chain")
+	}
+
+	parallel := data.NewProcessItemParallelProcessorWithConfig(source, data.ParallelConfig{
+		MaxWorkers: workers,
+		Priority:   c.priority,
+		Order:      c.order,
+	})
+	return parallel.ProcessStream(ctx, func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		result, err := c.Process(data.WithoutProgress(ctx), item)
+		if err != nil {
+			return nil, fmt.Errorf("item '%s': %w", item.ID, err)
+		}
+		return result, nil
+	}), nil
+}
+
+// WithPriority makes ProcessSourceStream start items of higher priority first, e.g.
+// data.PriorityFromMetadata("priority")
+func (c *Chain) WithPriority(priority func(item *data.ProcessItem) int) *Chain {
+	c.priority = priority
+	return c
+}
+
+// WithResultOrder sets the order ProcessSourceStream delivers results in, e.g.
+// data.OrderSource for sinks whose consumers expect the source's order
+func (c *Chain) WithResultOrder(order data.ResultOrder) *Chain {
+	c.order = order
+	return c
+}
+
+// Run continuously reads items from a source, processes each one through the chain and
+// writes the result to a sink. It returns nil when the source is exhausted and stops with
+// an error on the first failure, leaving that item unacknowledged by sinks that commit
+// their source (such as data.KafkaSink).
+func (c *Chain) Run(ctx context.Context, source data.ProcessItemSource, sink data.ProcessItemSink) error {
+	if len(c.steps) == 0 {
+		return fmt.Errorf("empty processor chain")
+	}
+
+	for {
+		item, err := source.NextProcessItem(ctx)
+		if err == io.EOF {
+			return sink.Flush(ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := c.Process(ctx, item)
+		if err != nil {
+			return fmt.Errorf("item '%s': %w", item.ID, err)
+		}
+
+		if err := sink.Write(ctx, []*data.ProcessItem{result}); err != nil {
+			return err
+		}
+	}
+}
+
 // GetName returns the chain name
 func (c *Chain) GetName() string {
 	return c.name
@@ -87,24 +224,70 @@ func (c *Chain) GetName() string {
 
 // ProcessBatch processes a batch of items through the chain
 func (c *Chain) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
-	if len(c.processors) == 0 {
+	if len(c.steps) == 0 {
 		return nil, fmt.Errorf("empty processor chain")
 	}
 
-	// Process with the first processor
-	currentResults, err := c.processors[0].ProcessBatch(ctx, items)
-	if err != nil {
-		return nil, fmt.Errorf("processor '%s' error: %w", c.processors[0].GetName(), err)
-	}
+	start := time.Now()
+	steps := len(c.steps)
 
-	// Process with remaining processors
-	for i := 1; i < len(c.processors); i++ {
-		proc := c.processors[i]
-		currentResults, err = proc.ProcessBatch(ctx, currentResults)
+	// Process each step over the whole batch
+	currentResults := items
+	for i := range c.steps {
+		step := c.stepAt(i)
+		started := time.Now()
+		nextResults, processed, err := processActive(stepProgress(ctx, i, steps, len(items), start), step, currentResults)
+		c.recordStep(i, started, currentResults, nextResults, err)
 		if err != nil {
-			return nil, fmt.Errorf("processor '%s' error: %w", proc.GetName(), err)
+			return nil, fmt.Errorf("processor '%s' error: %w", step.GetName(), err)
+		}
+		if err := c.resolveInfoKeys(step.GetName(), currentResults, nextResults); err != nil {
+			return nil, err
 		}
+		if err := c.afterStep(step.GetName(), started, processed...); err != nil {
+			return nil, err
+		}
+		currentResults = nextResults
+	}
+
+	if err := c.runAggregators(ctx, currentResults); err != nil {
+		return nil, err
 	}
 
 	return currentResults, nil
 }
+
+// processSource runs a step over a source, reading it in parallel if the step can't
+// process sources itself
+func processSource(ctx context.Context, step Step, source data.ProcessItemSource, batchSize, workers int) ([]*data.ProcessItem, error) {
+	if s, ok := step.(sourceStep); ok {
+		return s.ProcessSource(ctx, source, batchSize, workers)
+	}
+
+	parallel := data.NewProcessItemParallelProcessor(source, batchSize, workers)
+	defer parallel.Close()
+	return parallel.ProcessAll(ctx, step.Process)
+}
+
+// stepProgress returns a context whose progress reports for one step of the chain are
+// translated into progress across the whole chain, where each item passing through each
+// processor counts as one unit of work. items may be -1 if the size is not yet known.
+func stepProgress(ctx context.Context, step, steps, items int, start time.Time) context.Context {
+	fn := data.ProgressFromContext(ctx)
+	if fn == nil {
+		return ctx
+	}
+
+	return data.WithProgress(ctx, func(done, total, failed int, _ time.Duration) {
+		n := items
+		if n < 0 {
+			n = total
+		}
+
+		overall := -1
+		if n >= 0 {
+			overall = n * steps
+		}
+		fn(step*n+done, overall, failed, time.Since(start))
+	})
+}
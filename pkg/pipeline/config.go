@@ -0,0 +1,575 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+	_ "github.com/eisenzopf/agentic-text/pkg/processor/builtin" // Register built-in processors
+)
+
+// Pipeline types that can be declared in a Config
+const (
+	PipelineTypeChain = "chain"
+	PipelineTypeDAG   = "dag"
+)
+
+// Pipeline is a runnable pipeline built from a Config: a *Chain or a *DAG
+type Pipeline interface {
+	Step
+
+	// ProcessSource processes all items from a source through the pipeline
+	ProcessSource(ctx context.Context, source data.ProcessItemSource, batchSize, workers int) ([]*data.ProcessItem, error)
+}
+
+// Config is a declarative pipeline definition, loaded from YAML or JSON
+type Config struct {
+	// Name is the pipeline name
+	Name string `json:"name" yaml:"name"`
+	// Type is "chain" (the default) or "dag"
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Providers are the LLM providers steps can refer to by name
+	Providers map[string]ProviderConfig `json:"providers" yaml:"providers"`
+	// Steps are the pipeline steps, in order
+	Steps []StepConfig `json:"steps" yaml:"steps"`
+	// InfoKeys is the chain's policy for a step recording ProcessingInfo under a name an
+	// item already has an entry for: "overwrite" (the default), "suffix_index" or "error"
+	InfoKeys data.InfoKeyPolicy `json:"info_keys,omitempty" yaml:"info_keys,omitempty"`
+	// PriorityField names a numeric metadata field ranking the items a chain streams,
+	// starting those of higher priority first
+	PriorityField string `json:"priority_field,omitempty" yaml:"priority_field,omitempty"`
+	// ResultOrder is the order a chain streams results in: "completion" (the default) or
+	// "source"
+	ResultOrder data.ResultOrder `json:"result_order,omitempty" yaml:"result_order,omitempty"`
+	// Tenants are the providers and budgets of the tenants the pipeline serves. Processor
+	// steps without a provider then run each item with the provider of the tenant its
+	// "tenant" metadata names.
+	Tenants map[string]TenantConfig `json:"tenants,omitempty" yaml:"tenants,omitempty"`
+}
+
+// ProviderConfig declares an LLM provider
+type ProviderConfig struct {
+	// Type is the provider type, e.g. "google" or "openai"
+	Type string `json:"type" yaml:"type"`
+	// Model is the model name
+	Model string `json:"model" yaml:"model"`
+	// APIKey is the API key; prefer APIKeyEnv so keys stay out of config files
+	APIKey string `json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	// APIKeyEnv names the environment variable holding the API key
+	// (defaults to the provider's usual variable, e.g. GEMINI_API_KEY)
+	APIKeyEnv string `json:"api_key_env,omitempty" yaml:"api_key_env,omitempty"`
+	// MaxTokens limits the response length
+	MaxTokens int `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	// Temperature controls randomness (0.0-1.0)
+	Temperature float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	// Options are additional provider-specific options
+	Options map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+	// InteractionLog, if set, appends every call to the provider to this JSON Lines file,
+	// which BuildWithProvider and an llm.ReplayProvider can replay
+	InteractionLog string `json:"interaction_log,omitempty" yaml:"interaction_log,omitempty"`
+}
+
+// StepConfig declares a single pipeline step
+type StepConfig struct {
+	// Name identifies the step; it defaults to the processor name and is what DAG nodes refer to
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Processor is the registered processor type to run
+	Processor string `json:"processor,omitempty" yaml:"processor,omitempty"`
+	// Provider names the provider to use (defaults to "default", or the only provider declared)
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty"`
+	// Model overrides the provider's model for this step
+	Model string `json:"model,omitempty" yaml:"model,omitempty"`
+	// Options are passed to the processor
+	Options StepOptionsConfig `json:"options,omitempty" yaml:"options,omitempty"`
+	// Retry is the item-level retry policy for the step
+	Retry *RetryConfig `json:"retry,omitempty" yaml:"retry,omitempty"`
+	// Routing sends each item to one of several models of the step's provider by size or
+	// complexity
+	Routing *processor.RoutingConfig `json:"routing,omitempty" yaml:"routing,omitempty"`
+	// Packing packs several short items into each of the step's LLM calls
+	Packing *processor.PackingConfig `json:"packing,omitempty" yaml:"packing,omitempty"`
+	// Redact redacts the prompts and raw responses the step stores as debug output
+	Redact *processor.RedactionConfig `json:"redact,omitempty" yaml:"redact,omitempty"`
+	// Chunking processes items too long for the model's context length in chunks
+	Chunking *processor.ChunkingConfig `json:"chunking,omitempty" yaml:"chunking,omitempty"`
+	// PromptBudget bounds the step's builder prompts to a share of the model's context window
+	PromptBudget *processor.PromptBudget `json:"prompt_budget,omitempty" yaml:"prompt_budget,omitempty"`
+	// MaxConcurrency limits the step's processor to that many LLM calls at once
+	MaxConcurrency int `json:"max_concurrency,omitempty" yaml:"max_concurrency,omitempty"`
+	// Sanitize sanitizes the step's results, e.g. trimming whitespace and normalizing the
+	// casing of labels
+	Sanitize *processor.SanitizeConfig `json:"sanitize,omitempty" yaml:"sanitize,omitempty"`
+	// OnError is the error policy: "fail" (the default) or "skip"
+	OnError ErrorPolicy `json:"on_error,omitempty" yaml:"on_error,omitempty"`
+	// When makes the step conditional
+	When *ConditionConfig `json:"when,omitempty" yaml:"when,omitempty"`
+	// Parallel runs the listed steps concurrently instead of a single processor
+	Parallel []StepConfig `json:"parallel,omitempty" yaml:"parallel,omitempty"`
+	// Pipeline runs a sub-pipeline registered with RegisterSubPipeline instead of a single processor
+	Pipeline string `json:"pipeline,omitempty" yaml:"pipeline,omitempty"`
+	// Namespace nests a sub-pipeline's results under the step's own ProcessingInfo entry
+	Namespace bool `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// After lists the parent nodes of the step in a DAG
+	After []string `json:"after,omitempty" yaml:"after,omitempty"`
+}
+
+// StepOptionsConfig holds the processor options for a step
+type StepOptionsConfig struct {
+	LLM         map[string]interface{} `json:"llm,omitempty" yaml:"llm,omitempty"`
+	PreProcess  map[string]interface{} `json:"pre_process,omitempty" yaml:"pre_process,omitempty"`
+	PostProcess map[string]interface{} `json:"post_process,omitempty" yaml:"post_process,omitempty"`
+	// Inputs are default structured prompt inputs, e.g. get_attributes' attribute definitions
+	Inputs map[string]interface{} `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+}
+
+// RetryConfig declares a data.RetryPolicy; durations use Go syntax such as "500ms" or "30s"
+type RetryConfig struct {
+	MaxAttempts    int     `json:"max_attempts" yaml:"max_attempts"`
+	InitialBackoff string  `json:"initial_backoff,omitempty" yaml:"initial_backoff,omitempty"`
+	MaxBackoff     string  `json:"max_backoff,omitempty" yaml:"max_backoff,omitempty"`
+	Multiplier     float64 `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+}
+
+// ConditionConfig declares a Predicate. Exactly one of Result or Metadata is set.
+type ConditionConfig struct {
+	// Result is the "processor.field" result to compare, e.g. "sentiment.sentiment"
+	Result string `json:"result,omitempty" yaml:"result,omitempty"`
+	// Metadata is the metadata key to compare
+	Metadata string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	// Equals is the value the field must have
+	Equals interface{} `json:"equals" yaml:"equals"`
+}
+
+// LoadFromFile builds a pipeline from a YAML (.yaml, .yml) or JSON (.json) config file
+func LoadFromFile(path string) (Pipeline, error) {
+	config, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Build()
+}
+
+// LoadConfig reads a pipeline config file without building it
+func LoadConfig(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline config: %w", err)
+	}
+
+	var config Config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(raw, &config)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &config)
+	default:
+		return nil, fmt.Errorf("unsupported pipeline config format: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline config %s: %w", path, err)
+	}
+
+	return &config, nil
+}
+
+// Build creates the providers and processors declared in the config and assembles the pipeline
+func (c *Config) Build() (Pipeline, error) {
+	if len(c.Tenants) == 0 {
+		return c.build(nil, nil)
+	}
+	pool, err := NewProviderPool(c.Tenants)
+	if err != nil {
+		return nil, err
+	}
+	return c.build(nil, pool)
+}
+
+// BuildWithProviderPool assembles the pipeline with processor steps without a provider
+// running each item with the provider of its tenant in pool, instead of the tenants the
+// config declares, e.g. to share a server's tenants and their budgets
+func (c *Config) BuildWithProviderPool(pool *llm.ProviderPool) (Pipeline, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("provider pool is required")
+	}
+	return c.build(nil, pool)
+}
+
+// BuildWithProvider assembles the pipeline with every step using provider instead of the
+// providers the config declares, switched to the step's model if it sets one. With an
+// llm.ReplayProvider this re-executes a logged run offline, e.g. to re-test parsing
+// changes against the responses the models gave.
+func (c *Config) BuildWithProvider(provider llm.Provider) (Pipeline, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("provider is required")
+	}
+	return c.build(provider, nil)
+}
+
+// build assembles the pipeline, using override for every step if it is set, and else the
+// tenants' providers in pool for processor steps without a provider if it is set
+func (c *Config) build(override llm.Provider, pool *llm.ProviderPool) (Pipeline, error) {
+	if len(c.Steps) == 0 {
+		return nil, fmt.Errorf("pipeline config has no steps")
+	}
+	if err := c.InfoKeys.Validate(); err != nil {
+		return nil, err
+	}
+	switch c.ResultOrder {
+	case "", data.OrderCompletion, data.OrderSource:
+	default:
+		return nil, fmt.Errorf("unknown result order: %s", c.ResultOrder)
+	}
+
+	b := &configBuilder{
+		config:    c,
+		providers: make(map[string]llm.Provider),
+		override:  override,
+		pool:      pool,
+	}
+
+	switch c.Type {
+	case "", PipelineTypeChain:
+		chain := NewChain(c.Name).WithInfoKeyPolicy(c.InfoKeys).WithResultOrder(c.ResultOrder)
+		if c.PriorityField != "" {
+			chain.WithPriority(data.PriorityFromMetadata(c.PriorityField))
+		}
+		for _, stepConfig := range c.Steps {
+			step, err := b.step(stepConfig)
+			if err != nil {
+				return nil, err
+			}
+			chain.AddStep(step)
+		}
+		return chain, nil
+
+	case PipelineTypeDAG:
+		if c.InfoKeys != "" && c.InfoKeys != data.InfoKeyOverwrite {
+			return nil, fmt.Errorf("info_keys is only supported by chain pipelines")
+		}
+		if c.PriorityField != "" || c.ResultOrder != "" {
+			return nil, fmt.Errorf("priority_field and result_order are only supported by chain pipelines")
+		}
+		dag := NewDAG(c.Name)
+		for _, stepConfig := range c.Steps {
+			step, err := b.step(stepConfig)
+			if err != nil {
+				return nil, err
+			}
+			dag.AddNode(stepName(stepConfig), step, stepConfig.After...)
+		}
+		return dag.Build()
+
+	default:
+		return nil, fmt.Errorf("unknown pipeline type: %s", c.Type)
+	}
+}
+
+// configBuilder creates steps from a Config, sharing providers between steps
+type configBuilder struct {
+	config    *Config
+	providers map[string]llm.Provider
+	// override, if set, replaces every declared provider
+	override llm.Provider
+	// pool, if set, provides the providers of processor steps without a provider, by tenant
+	pool *llm.ProviderPool
+}
+
+// step builds a single step, applying its condition and error policy
+func (b *configBuilder) step(config StepConfig) (Step, error) {
+	var step Step
+	if len(config.Parallel) > 0 {
+		branches := make([]Step, len(config.Parallel))
+		for i, branchConfig := range config.Parallel {
+			branch, err := b.step(branchConfig)
+			if err != nil {
+				return nil, err
+			}
+			branches[i] = branch
+		}
+		parallel := Parallel(branches...)
+		if config.Name != "" {
+			parallel.WithName(config.Name)
+		}
+		step = parallel
+	} else if config.Pipeline != "" {
+		sub, err := b.subPipeline(config)
+		if err != nil {
+			return nil, err
+		}
+		step = sub
+	} else {
+		proc, err := b.processor(config)
+		if err != nil {
+			return nil, err
+		}
+		step = proc
+	}
+
+	switch config.OnError {
+	case "", ErrorPolicyFail, ErrorPolicySkip:
+		step = WithErrorPolicy(step, config.OnError)
+	default:
+		return nil, fmt.Errorf("step '%s': unknown error policy: %s", stepName(config), config.OnError)
+	}
+
+	if config.When != nil {
+		predicate, err := config.When.predicate()
+		if err != nil {
+			return nil, fmt.Errorf("step '%s': %w", stepName(config), err)
+		}
+		step = &conditionalStep{predicate: predicate, step: step}
+	}
+
+	return step, nil
+}
+
+// processor creates the processor for a step, or a tenant step if the step runs with the
+// tenants' providers
+func (b *configBuilder) processor(config StepConfig) (Step, error) {
+	if config.Processor == "" {
+		if name := stepName(config); name != "" {
+			return nil, fmt.Errorf("step '%s': processor is required", name)
+		}
+		return nil, fmt.Errorf("unnamed step: processor, pipeline or parallel is required")
+	}
+
+	options, err := b.options(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.pool != nil && b.override == nil && config.Provider == "" {
+		if config.Model != "" {
+			return nil, fmt.Errorf("step '%s': model can't be set on steps using the tenants' providers", stepName(config))
+		}
+		if !slices.Contains(processor.ListProcessors(), config.Processor) {
+			return nil, fmt.Errorf("step '%s': processor not found: %s", stepName(config), config.Processor)
+		}
+		return NewTenantStep(config.Processor, b.pool, options), nil
+	}
+
+	provider, err := b.provider(config.Provider, config.Model)
+	if err != nil {
+		return nil, fmt.Errorf("step '%s': %w", stepName(config), err)
+	}
+
+	proc, err := processor.Create(config.Processor, provider, options)
+	if err != nil {
+		return nil, fmt.Errorf("step '%s': %w", stepName(config), err)
+	}
+	return proc, nil
+}
+
+// options creates the processor options of a step
+func (b *configBuilder) options(config StepConfig) (processor.Options, error) {
+	options := processor.NewDefaultOptions()
+	for k, v := range config.Options.LLM {
+		options.LLMOptions[k] = v
+	}
+	for k, v := range config.Options.PreProcess {
+		options.PreProcessOptions[k] = v
+	}
+	for k, v := range config.Options.PostProcess {
+		options.PostProcessOptions[k] = v
+	}
+	for k, v := range config.Options.Inputs {
+		options.Inputs[k] = v
+	}
+	if config.Retry != nil {
+		policy, err := config.Retry.policy()
+		if err != nil {
+			return options, fmt.Errorf("step '%s': %w", stepName(config), err)
+		}
+		options.Retry = &policy
+	}
+	options.Redaction = config.Redact
+	options.Routing = config.Routing
+	options.Packing = config.Packing
+	options.Chunking = config.Chunking
+	options.PromptBudget = config.PromptBudget
+	options.MaxConcurrency = config.MaxConcurrency
+	options.Sanitize = config.Sanitize
+	return options, nil
+}
+
+// subPipeline creates the registered sub-pipeline for a step
+func (b *configBuilder) subPipeline(config StepConfig) (Step, error) {
+	// Sub-pipelines may create their own providers, so none need be declared
+	var provider llm.Provider
+	if config.Provider != "" || len(b.config.Providers) > 0 || b.override != nil {
+		var err error
+		if provider, err = b.provider(config.Provider, config.Model); err != nil {
+			return nil, fmt.Errorf("step '%s': %w", stepName(config), err)
+		}
+	}
+
+	pipeline, err := CreateSubPipeline(config.Pipeline, provider)
+	if err != nil {
+		return nil, fmt.Errorf("step '%s': %w", stepName(config), err)
+	}
+	return &subPipelineStep{name: stepName(config), pipeline: pipeline, namespace: config.Namespace}, nil
+}
+
+// provider returns the named provider, switched to model if given, creating it on first use
+func (b *configBuilder) provider(name, model string) (llm.Provider, error) {
+	if b.override != nil {
+		return llm.WithModel(b.override, model)
+	}
+	if name == "" {
+		if _, ok := b.config.Providers["default"]; ok || len(b.config.Providers) != 1 {
+			name = "default"
+		} else {
+			for only := range b.config.Providers {
+				name = only
+			}
+		}
+	}
+
+	key := name
+	if model != "" {
+		key = name + "/" + model
+	}
+	if provider, ok := b.providers[key]; ok {
+		return provider, nil
+	}
+
+	config, ok := b.config.Providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+	if model != "" {
+		config.Model = model
+	}
+
+	provider, err := config.NewProvider()
+	if err != nil {
+		return nil, fmt.Errorf("provider '%s': %w", name, err)
+	}
+	if config.InteractionLog != "" {
+		sink, err := interactionLog(config.InteractionLog)
+		if err != nil {
+			return nil, fmt.Errorf("provider '%s': %w", name, err)
+		}
+		provider = llm.WithInteractionLog(provider, sink, llm.InteractionLogConfig{})
+	}
+	b.providers[key] = provider
+	return provider, nil
+}
+
+// NewProvider creates the LLM provider the config declares
+func (p ProviderConfig) NewProvider() (llm.Provider, error) {
+	providerType := llm.ProviderType(p.Type)
+
+	// The mock and replay providers don't need an API key
+	apiKey := p.APIKey
+	if apiKey == "" && llm.NeedsAPIKey(providerType) {
+		envVar := p.APIKeyEnv
+		if envVar == "" {
+			envVar = llm.DefaultAPIKeyEnvVar(providerType)
+		}
+		if envVar != "" {
+			apiKey = os.Getenv(envVar)
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("API key not found in environment variable: %s", envVar)
+		}
+	}
+
+	options := make(map[string]interface{}, len(p.Options))
+	for k, v := range p.Options {
+		options[k] = v
+	}
+
+	return llm.NewProvider(providerType, llm.Config{
+		APIKey:      apiKey,
+		Model:       p.Model,
+		MaxTokens:   p.MaxTokens,
+		Temperature: p.Temperature,
+		Options:     options,
+	})
+}
+
+// interactionLogs holds the interaction logs opened by pipeline providers, by path, so
+// providers logging to the same file share one writer. They stay open for the life of the
+// process.
+var interactionLogs = struct {
+	sync.Mutex
+	sinks map[string]*llm.JSONLInteractionSink
+}{sinks: make(map[string]*llm.JSONLInteractionSink)}
+
+// interactionLog returns the sink appending to the interaction log at path
+func interactionLog(path string) (*llm.JSONLInteractionSink, error) {
+	interactionLogs.Lock()
+	defer interactionLogs.Unlock()
+	if sink, ok := interactionLogs.sinks[path]; ok {
+		return sink, nil
+	}
+	sink, err := llm.OpenInteractionLog(path)
+	if err != nil {
+		return nil, err
+	}
+	interactionLogs.sinks[path] = sink
+	return sink, nil
+}
+
+// policy converts the config into a data.RetryPolicy
+func (r RetryConfig) policy() (data.RetryPolicy, error) {
+	policy := data.RetryPolicy{
+		MaxAttempts: r.MaxAttempts,
+		Multiplier:  r.Multiplier,
+	}
+
+	var err error
+	if r.InitialBackoff != "" {
+		if policy.InitialBackoff, err = time.ParseDuration(r.InitialBackoff); err != nil {
+			return policy, fmt.Errorf("invalid initial_backoff: %w", err)
+		}
+	}
+	if r.MaxBackoff != "" {
+		if policy.MaxBackoff, err = time.ParseDuration(r.MaxBackoff); err != nil {
+			return policy, fmt.Errorf("invalid max_backoff: %w", err)
+		}
+	}
+
+	return policy, nil
+}
+
+// predicate converts the condition into a Predicate
+func (c ConditionConfig) predicate() (Predicate, error) {
+	switch {
+	case c.Result != "" && c.Metadata != "":
+		return nil, fmt.Errorf("condition must set only one of result or metadata")
+	case c.Result != "":
+		processorName, field, ok := strings.Cut(c.Result, ".")
+		if !ok {
+			return nil, fmt.Errorf("condition result must be processor.field: %s", c.Result)
+		}
+		return ResultEquals(processorName, field, c.Equals), nil
+	case c.Metadata != "":
+		return MetadataEquals(c.Metadata, c.Equals), nil
+	default:
+		return nil, fmt.Errorf("condition must set result or metadata")
+	}
+}
+
+// stepName returns the name a step config is known by
+func stepName(config StepConfig) string {
+	if config.Name != "" {
+		return config.Name
+	}
+	if config.Processor == "" {
+		return config.Pipeline
+	}
+	return config.Processor
+}
@@ -0,0 +1,158 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+func TestConfigStepErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    StepConfig
+		wantErr string
+	}{
+		{
+			name:    "named step without processor",
+			step:    StepConfig{Name: "triage"},
+			wantErr: "step 'triage': processor is required",
+		},
+		{
+			name:    "unnamed step without processor",
+			step:    StepConfig{},
+			wantErr: "unnamed step: processor, pipeline or parallel is required",
+		},
+		{
+			name:    "unnamed step with an unknown error policy",
+			step:    StepConfig{Processor: "sentiment", OnError: "retry"},
+			wantErr: "step 'sentiment': unknown error policy: retry",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Name: "test", Steps: []StepConfig{tt.step}}
+			_, err := config.BuildWithProvider(llm.NewMockProviderWithResponse(sentimentResponse))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestInteractionLogReplay(t *testing.T) {
+	const intentResponse = `{"intents": [{"label_name": "Refund", "label": "refund", "description": "The customer wants a refund.", "confidence": 0.8}]}`
+	path := filepath.Join(t.TempDir(), "interactions.jsonl")
+	texts := []string{"Great support, but I want a refund", "Please cancel my plan"}
+
+	// results returns the sentiment and intent results of the texts processed by p
+	results := func(t *testing.T, p Pipeline) []map[string]interface{} {
+		t.Helper()
+		var results []map[string]interface{}
+		for i, text := range texts {
+			item, err := p.Process(context.Background(), data.NewTextProcessItem(string(rune('a'+i)), text, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, map[string]interface{}{
+				"sentiment": item.ProcessingInfo["sentiment"],
+				"intent":    item.ProcessingInfo["intent"],
+			})
+		}
+		return results
+	}
+
+	logged := func(response string) ProviderConfig {
+		return ProviderConfig{Type: "mock", Model: "mock", InteractionLog: path, Options: map[string]interface{}{"response": response}}
+	}
+	recording := &Config{
+		Name: "recorded",
+		Providers: map[string]ProviderConfig{
+			"sentiment": logged(sentimentResponse),
+			"intent":    logged(intentResponse),
+		},
+		Steps: []StepConfig{
+			{Processor: "sentiment", Provider: "sentiment"},
+			{Processor: "intent", Provider: "intent"},
+		},
+	}
+	p, err := recording.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := results(t, p)
+
+	interactions, err := llm.LoadInteractions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(interactions) != 4 {
+		t.Fatalf("expected 2 calls per text to be logged, got %d", len(interactions))
+	}
+
+	t.Run("pipeline", func(t *testing.T) {
+		replay := llm.NewReplayProvider(interactions)
+		p, err := (&Config{Name: "replayed", Steps: recording.Steps}).BuildWithProvider(replay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replayed := results(t, p); !reflect.DeepEqual(replayed, recorded) {
+			t.Errorf("expected the recorded results %v, got %v", recorded, replayed)
+		}
+		if stats := replay.Stats(); stats.Replayed != 4 || stats.Missing != 0 {
+			t.Errorf("expected every call to be replayed, got %+v", stats)
+		}
+	})
+
+	t.Run("replay provider config", func(t *testing.T) {
+		replayed := &Config{
+			Name:      "replayed",
+			Providers: map[string]ProviderConfig{"log": {Type: "replay", Options: map[string]interface{}{"log": path}}},
+			Steps: []StepConfig{
+				{Processor: "sentiment", Provider: "log"},
+				{Processor: "intent", Provider: "log"},
+			},
+		}
+		p, err := replayed.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := results(t, p); !reflect.DeepEqual(got, recorded) {
+			t.Errorf("expected the recorded results %v, got %v", recorded, got)
+		}
+	})
+
+	t.Run("processor", func(t *testing.T) {
+		replay := llm.NewReplayProvider(interactions)
+		proc, err := processor.Create("sentiment", replay, processor.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		item, err := proc.Process(context.Background(), data.NewTextProcessItem("a", texts[0], nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Pipelines store results as plain JSON values, so they are compared as JSON
+		got, _ := json.Marshal(item.ProcessingInfo["sentiment"])
+		want, _ := json.Marshal(recorded[0]["sentiment"])
+		if string(got) != string(want) {
+			t.Errorf("expected the recorded result %s, got %s", want, got)
+		}
+
+		_, err = proc.Process(context.Background(), data.NewTextProcessItem("c", "A text never processed", nil))
+		if !errors.Is(err, llm.ErrNotRecorded) {
+			t.Errorf("expected %v for an unrecorded prompt, got %v", llm.ErrNotRecorded, err)
+		}
+		if stats := replay.Stats(); stats.Replayed != 1 || stats.Missing == 0 {
+			t.Errorf("expected one replayed and one missing call, got %+v", stats)
+		}
+	})
+}
@@ -0,0 +1,304 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// MergeFunc combines the outputs of a node's parents into a single item.
+// inputs maps each parent node name to its output; original is the item given to the DAG.
+type MergeFunc func(ctx context.Context, original *data.ProcessItem, inputs map[string]*data.ProcessItem) (*data.ProcessItem, error)
+
+// MergeProcessingInfo is the default MergeFunc. It starts from a copy of the original
+// item and unions the metadata and processing info of every parent output, so each
+// branch's result is kept under its own processor name.
+func MergeProcessingInfo(_ context.Context, original *data.ProcessItem, inputs map[string]*data.ProcessItem) (*data.ProcessItem, error) {
+	merged, err := original.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if merged.Metadata == nil {
+		merged.Metadata = make(map[string]interface{})
+	}
+	if merged.ProcessingInfo == nil {
+		merged.ProcessingInfo = make(map[string]interface{})
+	}
+
+	// Merge in a stable order so conflicting keys resolve deterministically
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		input := inputs[name]
+		for k, v := range input.Metadata {
+			merged.Metadata[k] = v
+		}
+		for k, v := range input.ProcessingInfo {
+			merged.ProcessingInfo[k] = v
+		}
+	}
+
+	return merged, nil
+}
+
+// dagNode is a single step in a DAG
+type dagNode struct {
+	name    string
+	proc    Step
+	merge   MergeFunc
+	parents []string
+}
+
+// DAG is a pipeline in which a node's output can fan out to several nodes and
+// merge nodes combine several outputs back into one item. Nodes whose parents
+// have all completed run concurrently.
+type DAG struct {
+	name     string
+	nodes    []*dagNode
+	byName   map[string]*dagNode
+	terminal []string
+}
+
+// DAGBuilder provides a fluent interface for constructing a DAG
+type DAGBuilder struct {
+	name   string
+	nodes  []*dagNode
+	byName map[string]*dagNode
+	err    error
+}
+
+// NewDAG creates a new DAG builder
+func NewDAG(name string) *DAGBuilder {
+	return &DAGBuilder{
+		name:   name,
+		byName: make(map[string]*dagNode),
+	}
+}
+
+// AddNode adds a node running a processor or any other Step. Nodes without parents
+// receive the DAG's input item; a node with several parents receives their outputs
+// combined with MergeProcessingInfo. Parents must be added before their children.
+func (b *DAGBuilder) AddNode(name string, proc Step, parents ...string) *DAGBuilder {
+	if proc == nil {
+		b.fail(fmt.Errorf("node '%s': processor is required", name))
+		return b
+	}
+	b.add(&dagNode{name: name, proc: proc, merge: MergeProcessingInfo, parents: parents})
+	return b
+}
+
+// AddMerge adds a node that combines the outputs of its parents into one item without
+// running a processor. If merge is nil, MergeProcessingInfo is used.
+func (b *DAGBuilder) AddMerge(name string, merge MergeFunc, parents ...string) *DAGBuilder {
+	if len(parents) == 0 {
+		b.fail(fmt.Errorf("merge node '%s': at least one parent is required", name))
+		return b
+	}
+	if merge == nil {
+		merge = MergeProcessingInfo
+	}
+	b.add(&dagNode{name: name, merge: merge, parents: parents})
+	return b
+}
+
+// add validates and records a node
+func (b *DAGBuilder) add(node *dagNode) {
+	if node.name == "" {
+		b.fail(fmt.Errorf("node name is required"))
+		return
+	}
+	if _, exists := b.byName[node.name]; exists {
+		b.fail(fmt.Errorf("duplicate node name: %s", node.name))
+		return
+	}
+	// Requiring parents to exist already guarantees the graph is acyclic
+	for _, parent := range node.parents {
+		if _, ok := b.byName[parent]; !ok {
+			b.fail(fmt.Errorf("node '%s': unknown parent '%s'", node.name, parent))
+			return
+		}
+	}
+	b.nodes = append(b.nodes, node)
+	b.byName[node.name] = node
+}
+
+// fail records the first construction error
+func (b *DAGBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Build validates the graph and returns the DAG
+func (b *DAGBuilder) Build() (*DAG, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.nodes) == 0 {
+		return nil, fmt.Errorf("empty DAG")
+	}
+
+	// Terminal nodes are those no other node depends on
+	hasChildren := make(map[string]bool)
+	for _, node := range b.nodes {
+		for _, parent := range node.parents {
+			hasChildren[parent] = true
+		}
+	}
+	var terminal []string
+	for _, node := range b.nodes {
+		if !hasChildren[node.name] {
+			terminal = append(terminal, node.name)
+		}
+	}
+
+	return &DAG{
+		name:     b.name,
+		nodes:    b.nodes,
+		byName:   b.byName,
+		terminal: terminal,
+	}, nil
+}
+
+// GetName returns the DAG name
+func (d *DAG) GetName() string {
+	return d.name
+}
+
+// Process runs an item through the DAG. If the DAG has several terminal nodes their
+// outputs are combined with MergeProcessingInfo.
+func (d *DAG) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		outputs  = make(map[string]*data.ProcessItem, len(d.nodes))
+		done     = make(map[string]chan struct{}, len(d.nodes))
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for _, node := range d.nodes {
+		done[node.name] = make(chan struct{})
+	}
+
+	for _, node := range d.nodes {
+		wg.Add(1)
+		go func(node *dagNode) {
+			defer wg.Done()
+			defer close(done[node.name])
+
+			// Wait for every parent to finish
+			for _, parent := range node.parents {
+				select {
+				case <-done[parent]:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			mu.Lock()
+			failed := firstErr != nil
+			inputs := make(map[string]*data.ProcessItem, len(node.parents))
+			for _, parent := range node.parents {
+				inputs[parent] = outputs[parent]
+			}
+			mu.Unlock()
+			if failed {
+				return
+			}
+
+			output, err := d.runNode(ctx, node, item, inputs)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			outputs[node.name] = output
+		}(node)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(d.terminal) == 1 {
+		return outputs[d.terminal[0]], nil
+	}
+
+	finals := make(map[string]*data.ProcessItem, len(d.terminal))
+	for _, name := range d.terminal {
+		finals[name] = outputs[name]
+	}
+	return MergeProcessingInfo(ctx, item, finals)
+}
+
+// runNode computes a node's input from its parents and runs its processor, if any
+func (d *DAG) runNode(ctx context.Context, node *dagNode, original *data.ProcessItem, inputs map[string]*data.ProcessItem) (*data.ProcessItem, error) {
+	input := original
+	switch {
+	case len(node.parents) == 1 && node.proc != nil:
+		input = inputs[node.parents[0]]
+	case len(node.parents) > 0:
+		merged, err := node.merge(ctx, original, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("node '%s' merge error: %w", node.name, err)
+		}
+		input = merged
+	}
+
+	if node.proc == nil {
+		return input, nil
+	}
+
+	output, err := node.proc.Process(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("node '%s' processor '%s' error: %w", node.name, node.proc.GetName(), err)
+	}
+	return output, nil
+}
+
+// ProcessBatch processes a batch of items through the DAG
+func (d *DAG) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	results := make([]*data.ProcessItem, len(items))
+	tracker := data.NewProgressTracker(ctx, len(items))
+	inner := data.WithoutProgress(ctx)
+
+	for i, item := range items {
+		result, err := d.Process(inner, item)
+		tracker.Record(err)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+
+	return results, nil
+}
+
+// ProcessSource processes all items from a source through the DAG in parallel
+func (d *DAG) ProcessSource(ctx context.Context, source data.ProcessItemSource, batchSize, workers int) ([]*data.ProcessItem, error) {
+	parallel := data.NewProcessItemParallelProcessor(source, batchSize, workers)
+	defer parallel.Close()
+
+	return parallel.ProcessAll(ctx, func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		return d.Process(data.WithoutProgress(ctx), item)
+	})
+}
@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+func TestDAG(t *testing.T) {
+	errBranch := errors.New("branch failed")
+
+	tests := []struct {
+		name     string
+		build    func() *DAGBuilder
+		wantKeys []string
+		wantErr  string
+	}{
+		{
+			name: "fan-out and merge",
+			build: func() *DAGBuilder {
+				return NewDAG("fan").
+					AddNode("detect", recordingStep("detect", nil)).
+					AddNode("sentiment", recordingStep("sentiment", nil), "detect").
+					AddNode("intent", recordingStep("intent", nil), "detect").
+					AddMerge("join", nil, "sentiment", "intent").
+					AddNode("summary", recordingStep("summary", nil), "join")
+			},
+			wantKeys: []string{"detect", "intent", "sentiment", "summary"},
+		},
+		{
+			name: "several terminal nodes",
+			build: func() *DAGBuilder {
+				return NewDAG("leaves").
+					AddNode("detect", recordingStep("detect", nil)).
+					AddNode("sentiment", recordingStep("sentiment", nil), "detect").
+					AddNode("intent", recordingStep("intent", nil), "detect")
+			},
+			wantKeys: []string{"detect", "intent", "sentiment"},
+		},
+		{
+			name: "node with several parents merges their outputs",
+			build: func() *DAGBuilder {
+				return NewDAG("multi").
+					AddNode("sentiment", recordingStep("sentiment", nil)).
+					AddNode("intent", recordingStep("intent", nil)).
+					AddNode("summary", recordingStep("summary", nil), "sentiment", "intent")
+			},
+			wantKeys: []string{"intent", "sentiment", "summary"},
+		},
+		{
+			name: "failing branch",
+			build: func() *DAGBuilder {
+				return NewDAG("failing").
+					AddNode("detect", recordingStep("detect", nil)).
+					AddNode("broken", &funcStep{name: "broken", fn: func(context.Context, *data.ProcessItem) (*data.ProcessItem, error) {
+						return nil, errBranch
+					}}, "detect").
+					AddNode("intent", recordingStep("intent", nil), "detect")
+			},
+			wantErr: "node 'broken' processor 'broken' error: branch failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dag, err := tt.build().Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := dag.Process(context.Background(), data.NewTextProcessItem("1", "text", nil))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keys := infoKeys(result); strings.Join(keys, ",") != strings.Join(tt.wantKeys, ",") {
+				t.Errorf("expected keys %v, got %v", tt.wantKeys, keys)
+			}
+		})
+	}
+}
+
+func TestDAGErrorCancelsBranches(t *testing.T) {
+	var childRan atomic.Bool
+	blocking := &funcStep{name: "slow", fn: func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Second):
+			return item, nil
+		}
+	}}
+	failing := &funcStep{name: "broken", fn: func(context.Context, *data.ProcessItem) (*data.ProcessItem, error) {
+		return nil, errors.New("branch failed")
+	}}
+	child := &funcStep{name: "child", fn: func(_ context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		childRan.Store(true)
+		return item, nil
+	}}
+
+	dag, err := NewDAG("cancel").
+		AddNode("slow", blocking).
+		AddNode("broken", failing).
+		AddNode("child", child, "slow").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = dag.Process(context.Background(), data.NewTextProcessItem("1", "text", nil))
+	if err == nil || !strings.Contains(err.Error(), "branch failed") {
+		t.Fatalf("expected the failing branch's error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the slow branch to be cancelled, took %s", elapsed)
+	}
+	if childRan.Load() {
+		t.Error("expected the child of a cancelled branch not to run")
+	}
+}
+
+func TestDAGBuilderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func() *DAGBuilder
+		wantErr string
+	}{
+		{"empty", func() *DAGBuilder { return NewDAG("empty") }, "empty DAG"},
+		{"unknown parent", func() *DAGBuilder {
+			return NewDAG("d").AddNode("a", recordingStep("a", nil), "missing")
+		}, "node 'a': unknown parent 'missing'"},
+		{"duplicate node", func() *DAGBuilder {
+			return NewDAG("d").AddNode("a", recordingStep("a", nil)).AddNode("a", recordingStep("a", nil))
+		}, "duplicate node name: a"},
+		{"merge without parents", func() *DAGBuilder {
+			return NewDAG("d").AddMerge("join", nil)
+		}, "merge node 'join': at least one parent is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.build().Build(); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
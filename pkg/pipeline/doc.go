@@ -11,6 +11,82 @@ Core components:
   - Process: Method for processing a single item through the chain
   - ProcessBatch: Method for batch processing items through the chain
   - ProcessSource: Method for processing a data source through the chain
+  - ProcessSourceStream: Method for streaming each item's result as soon as it completes
+  - ProcessSourceWithReport: Method for processing a source and summarizing the run in a data.RunReport
+  - Run: Method for continuously processing a source into a sink
+
+2. Metadata (metadata.go):
+  - MetadataPolicy: Rules for how metadata flows between steps (copy-all, whitelist, derive)
+  - WithLineage: Automatic lineage tracking for audit-ready output records
+  - WithInfoKeyPolicy: Overwrite, number or reject ProcessingInfo of processors that run twice (infokeys.go)
+
+3. Steps (step.go):
+  - Step: Interface satisfied by processors, chains and DAGs
+  - AddStep / AddConditional: Append steps, optionally guarded by a Predicate
+  - ResultEquals / ResultBelow / MetadataEquals: Predicates over prior results and metadata
+  - WithErrorPolicy: Fail the pipeline or skip the item when a step errors
+
+4. Parallel (parallel.go):
+  - Parallel: Step that runs several processors concurrently on the same input
+
+5. DAG (dag.go):
+  - DAG: Pipeline whose steps can fan out to parallel branches and merge again
+  - DAGBuilder: Fluent builder with AddNode and AddMerge
+  - MergeProcessingInfo: Default merge combining branch results into one item
+
+6. Builder (builder.go):
+  - ChainBuilder: Builds a Chain from processor types with a default provider
+  - StepOptions: Per-step provider, model and option overrides
+
+7. Config (config.go):
+  - LoadFromFile: Build a Chain or DAG from a YAML or JSON config file
+  - Config: Declarative pipeline definition with providers, steps, options and error policies
+  - BuildWithProvider: Build a pipeline answering every step with one provider, e.g. to replay a logged run
+
+8. Transforms (transform.go):
+  - AddTransform / Transform: Reshape items between steps without calling an LLM
+  - ResultWithOriginalText: Combine a prior result with the original text
+  - ResultAsInput: Pass a prior result to the next processor as a structured prompt input
+  - CaptureState: Copy a prior result into the item's state for later steps
+
+9. Stats (stats.go):
+  - ChainStats: Per-step latency, item, error, token and cost metrics for a chain
+
+10. Intermediate results (intermediate.go):
+  - IntermediateStore: Persists each step's output so re-runs skip completed steps
+  - DirectoryStore / SinkStore: Store step outputs as files or write them to a sink
+  - WithCheckpoints: Resume interrupted runs at (item, step) granularity
+
+11. Aggregation (aggregate.go):
+  - AddAggregator: Corpus-level steps that run once all items are processed
+  - CountBy / Average / Collect / LLMSummary: Built-in aggregate functions
+  - CountTrend / AverageTrend / LLMTrendNarrative (trend.go): Time-bucketed series and a narrative of their shifts
+  - AttributeCatalog (catalog.go): Deduplicated catalog of required_attributes definitions with provenance
+
+12. Refinement (refine.go):
+  - Refine: Generate/validate loop that feeds critique back until a score threshold is met
+
+13. Review (review.go):
+  - Review / AddReview: Pause matching items in a review queue for a human decision
+  - DirectoryReviewQueue / SinkReviewQueue: Queues holding paused items and their resume tokens
+  - Resume / ResumeReview: Apply an approval, corrections or rejection and continue the chain
+
+14. Sub-pipelines (subpipeline.go):
+  - SubPipeline / AddSubPipeline: Run a Chain or DAG as one step with its own error policy
+  - RegisterSubPipeline: Share sub-flows by name across pipelines and config files
+
+15. Budgets (budget.go):
+  - ProcessSourceWithBudget: Stop a run at a cost, token or time limit and return partial results
+  - BudgetReport: Resources used by a run and the limit that stopped it
+
+16. Tenants (tenants.go):
+  - TenantConfig / NewProviderPool: Per-tenant providers and budgets declared in a config
+  - NewTenantStep: Run a processor on each item with the provider of its tenant
+
+17. Attribute tables (attribute_table.go):
+  - ExtractAttributeTable: Run get_attributes across a corpus into a CSV, Parquet or SQL table
+  - AttributeTableConfig: Attributes, metadata and confidence columns of the table
+  - AttributeTableSink: Sink writing one row per item from existing get_attributes results
 
 Using pipelines allows for modular, composable text processing workflows where each step
 is handled by a specialized processor.
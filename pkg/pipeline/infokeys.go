@@ -0,0 +1,88 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// WithInfoKeyPolicy sets what happens when a step records ProcessingInfo under a name an
+// item already has an entry for, e.g. a sentiment step run before and after translation.
+// By default the later result overwrites the earlier one; with data.InfoKeySuffixIndex it
+// is kept under "sentiment_2" instead, and with data.InfoKeyError the chain fails. Items
+// read from a source with ProcessingInfo from an earlier run are checked too.
+func (c *Chain) WithInfoKeyPolicy(policy data.InfoKeyPolicy) *Chain {
+	c.infoKeys = policy
+	return c
+}
+
+// resolveInfoKeys applies the chain's info key policy to the results of a step, given the
+// items the step ran on at the same positions. Items the step passed through unchanged,
+// such as those a conditional step skipped, are left alone.
+func (c *Chain) resolveInfoKeys(name string, inputs, results []*data.ProcessItem) error {
+	if c.infoKeys == "" || c.infoKeys == data.InfoKeyOverwrite {
+		return nil
+	}
+
+	for i, result := range results {
+		if result == nil || i >= len(inputs) || inputs[i] == nil || result == inputs[i] {
+			continue
+		}
+		previous, existed := inputs[i].ProcessingInfo[name]
+		info, recorded := result.ProcessingInfo[name]
+		if !existed || !recorded {
+			continue
+		}
+		// Restore the earlier entry and add the step's entry again under the policy
+		result.ProcessingInfo[name] = previous
+		if _, err := result.AddProcessingInfoWithPolicy(name, info, c.infoKeys); err != nil {
+			return fmt.Errorf("processor '%s' error: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// sourceInputs records the items a step reads from a source, so that its results can be
+// matched with the items they came from
+type sourceInputs struct {
+	mu    sync.Mutex
+	items []*data.ProcessItem
+}
+
+// wrap returns a source recording the items read from source
+func (s *sourceInputs) wrap(source data.ProcessItemSource) data.ProcessItemSource {
+	return data.Map(source, func(item *data.ProcessItem) (*data.ProcessItem, error) {
+		s.mu.Lock()
+		s.items = append(s.items, item)
+		s.mu.Unlock()
+		return item, nil
+	})
+}
+
+// match returns the recorded items at the positions of the results they produced. Results
+// are matched by position, or by ID if the step dropped items; unmatched results get nil.
+func (s *sourceInputs) match(results []*data.ProcessItem) []*data.ProcessItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(results) == len(s.items) {
+		return s.items
+	}
+
+	byID := make(map[string][]*data.ProcessItem, len(s.items))
+	for _, item := range s.items {
+		byID[item.ID] = append(byID[item.ID], item)
+	}
+	inputs := make([]*data.ProcessItem, len(results))
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		if candidates := byID[result.ID]; len(candidates) > 0 {
+			inputs[i] = candidates[0]
+			byID[result.ID] = candidates[1:]
+		}
+	}
+	return inputs
+}
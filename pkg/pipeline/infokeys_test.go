@@ -0,0 +1,155 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// funcStep is a step running a function on each item
+type funcStep struct {
+	name string
+	fn   func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error)
+}
+
+func (s *funcStep) GetName() string {
+	return s.name
+}
+
+func (s *funcStep) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	return s.fn(ctx, item)
+}
+
+func (s *funcStep) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	results := make([]*data.ProcessItem, len(items))
+	for i, item := range items {
+		result, err := s.fn(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// recordingStep returns a step that clones each item and records info under name, like
+// a processor
+func recordingStep(name string, info map[string]interface{}) *funcStep {
+	return &funcStep{name: name, fn: func(_ context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		result, err := item.Clone()
+		if err != nil {
+			return nil, err
+		}
+		copied := make(map[string]interface{}, len(info))
+		for key, value := range info {
+			copied[key] = value
+		}
+		result.AddProcessingInfo(name, copied)
+		return result, nil
+	}}
+}
+
+// earlierSentiment is the sentiment result items carry from an earlier run
+var earlierSentiment = map[string]interface{}{"sentiment": "negative", "processor_type": "sentiment"}
+
+// infoKeys returns the sorted ProcessingInfo keys of an item
+func infoKeys(item *data.ProcessItem) []string {
+	keys := make([]string, 0, len(item.ProcessingInfo))
+	for key := range item.ProcessingInfo {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestResolveInfoKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		// steps is the number of sentiment steps in the chain
+		steps int
+		// earlier gives the input items a sentiment result from an earlier run
+		earlier  bool
+		policy   data.InfoKeyPolicy
+		wantKeys []string
+		wantErr  string
+	}{
+		{name: "overwrite", steps: 2, policy: data.InfoKeyOverwrite, wantKeys: []string{"sentiment"}},
+		{name: "suffix index", steps: 2, policy: data.InfoKeySuffixIndex, wantKeys: []string{"sentiment", "sentiment_2"}},
+		{name: "three runs", steps: 3, policy: data.InfoKeySuffixIndex, wantKeys: []string{"sentiment", "sentiment_2", "sentiment_3"}},
+		{name: "error", steps: 2, policy: data.InfoKeyError, wantErr: "already has processing info for 'sentiment'"},
+		// The first step's collisions are with results the items were read with
+		{name: "first step suffix index", steps: 1, earlier: true, policy: data.InfoKeySuffixIndex, wantKeys: []string{"sentiment", "sentiment_2"}},
+		{name: "first step error", steps: 1, earlier: true, policy: data.InfoKeyError, wantErr: "already has processing info for 'sentiment'"},
+		{name: "first step overwrite", steps: 1, earlier: true, policy: data.InfoKeyOverwrite, wantKeys: []string{"sentiment"}},
+	}
+
+	// Every entry point applies the policy the same way
+	entryPoints := map[string]func(*Chain, []*data.ProcessItem) ([]*data.ProcessItem, error){
+		"Process": func(chain *Chain, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+			results := make([]*data.ProcessItem, len(items))
+			for i, item := range items {
+				result, err := chain.Process(context.Background(), item)
+				if err != nil {
+					return nil, err
+				}
+				results[i] = result
+			}
+			return results, nil
+		},
+		"ProcessBatch": func(chain *Chain, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+			return chain.ProcessBatch(context.Background(), items)
+		},
+		"ProcessSource": func(chain *Chain, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+			return chain.ProcessSource(context.Background(), data.NewProcessItemSliceSource(items), 2, 2)
+		},
+	}
+
+	for _, tt := range tests {
+		for entry, run := range entryPoints {
+			t.Run(tt.name+"/"+entry, func(t *testing.T) {
+				sentiment := recordingStep("sentiment", map[string]interface{}{"sentiment": "positive"})
+				chain := NewChain("repeated").WithInfoKeyPolicy(tt.policy)
+				for i := 0; i < tt.steps; i++ {
+					chain.AddStep(sentiment)
+				}
+
+				items := make([]*data.ProcessItem, 3)
+				for i := range items {
+					items[i] = data.NewTextProcessItem(fmt.Sprintf("item-%d", i), "Great support!", nil)
+					if tt.earlier {
+						items[i].AddProcessingInfo("sentiment", earlierSentiment)
+					}
+				}
+
+				results, err := run(chain, items)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(results) != len(items) {
+					t.Fatalf("expected %d results, got %d", len(items), len(results))
+				}
+				for _, result := range results {
+					if keys := infoKeys(result); strings.Join(keys, ",") != strings.Join(tt.wantKeys, ",") {
+						t.Errorf("%s: expected keys %v, got %v", result.ID, tt.wantKeys, keys)
+					}
+					// Suffixed policies keep the earlier result under the plain name
+					if tt.earlier && tt.policy == data.InfoKeySuffixIndex {
+						if got := result.ProcessingInfo["sentiment"].(map[string]interface{})["sentiment"]; got != "negative" {
+							t.Errorf("%s: expected the earlier result under sentiment, got %v", result.ID, got)
+						}
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// IntermediateStore persists the output of each chain step for each item so that a
+// re-run can pick up where a failed run stopped instead of repeating completed steps
+type IntermediateStore interface {
+	// Save records the output of a step for an item
+	Save(ctx context.Context, step string, item *data.ProcessItem) error
+
+	// Load returns the saved output of a step for an item ID, if there is one
+	Load(ctx context.Context, step, id string) (*data.ProcessItem, bool, error)
+}
+
+// DirectoryStore is an IntermediateStore keeping one JSON file per step and item
+// under dir/<step>/<item id>.json
+type DirectoryStore struct {
+	dir string
+}
+
+// NewDirectoryStore creates a new store rooted at dir, creating it if needed
+func NewDirectoryStore(dir string) (*DirectoryStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create intermediate result directory: %w", err)
+	}
+	return &DirectoryStore{dir: dir}, nil
+}
+
+// Save implements the IntermediateStore interface. Files are written atomically so an
+// interrupted run never leaves a truncated result behind.
+func (s *DirectoryStore) Save(_ context.Context, step string, item *data.ProcessItem) error {
+	path := s.path(step, item.ID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create step directory: %w", err)
+	}
+
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to marshal item %s: %w", item.ID, err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, encoded, 0o644); err != nil {
+		return fmt.Errorf("failed to write intermediate result: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write intermediate result: %w", err)
+	}
+	return nil
+}
+
+// Load implements the IntermediateStore interface
+func (s *DirectoryStore) Load(_ context.Context, step, id string) (*data.ProcessItem, bool, error) {
+	encoded, err := os.ReadFile(s.path(step, id))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read intermediate result: %w", err)
+	}
+
+	var item data.ProcessItem
+	if err := json.Unmarshal(encoded, &item); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal intermediate result for item %s: %w", id, err)
+	}
+	return &item, true, nil
+}
+
+// path returns the file holding a step's output for an item
+func (s *DirectoryStore) path(step, id string) string {
+	return filepath.Join(s.dir, url.PathEscape(step), url.PathEscape(id)+".json")
+}
+
+// SinkStore is an IntermediateStore that writes every step output to a sink for
+// inspection. It cannot load results back, so re-runs start from scratch.
+type SinkStore struct {
+	sink data.ProcessItemSink
+}
+
+// NewSinkStore creates a new store writing to sink. Each record is tagged with the step
+// that produced it in the "pipeline_step" metadata field.
+func NewSinkStore(sink data.ProcessItemSink) *SinkStore {
+	return &SinkStore{sink: sink}
+}
+
+// Save implements the IntermediateStore interface
+func (s *SinkStore) Save(ctx context.Context, step string, item *data.ProcessItem) error {
+	record, err := item.Clone()
+	if err != nil {
+		return err
+	}
+	if record.Metadata == nil {
+		record.Metadata = make(map[string]interface{})
+	}
+	record.Metadata["pipeline_step"] = step
+	return s.sink.Write(ctx, []*data.ProcessItem{record})
+}
+
+// Load implements the IntermediateStore interface; nothing is ever found
+func (s *SinkStore) Load(_ context.Context, _, _ string) (*data.ProcessItem, bool, error) {
+	return nil, false, nil
+}
+
+// WithIntermediateStore persists each step's output as it is produced and, on later runs,
+// reuses saved outputs for items with the same ID instead of running the step again
+func (c *Chain) WithIntermediateStore(store IntermediateStore) *Chain {
+	c.store = store
+	return c
+}
+
+// WithCheckpoints enables checkpointing at (item, step) granularity: after each step's
+// output for an item is saved to store, the pair is marked complete in checkpoints. A run
+// interrupted at any point resumes exactly where it stopped when run again with the same
+// stores, with only marked outputs reused. Outputs of processors are marked with their
+// provenance, so they are not reused once the input, prompt or model changes.
+func (c *Chain) WithCheckpoints(checkpoints data.CheckpointStore, store IntermediateStore) *Chain {
+	c.checkpoints = checkpoints
+	c.store = store
+	return c
+}
+
+// stepAt returns the step at index, wrapped to use the chain's intermediate store if set
+func (c *Chain) stepAt(index int) Step {
+	step := c.steps[index]
+	if c.store == nil {
+		return step
+	}
+	return &storedStep{
+		step:        step,
+		store:       c.store,
+		checkpoints: c.checkpoints,
+		key:         fmt.Sprintf("%02d-%s", index+1, step.GetName()),
+	}
+}
+
+// storedStep saves a step's outputs and skips items whose output is already saved
+type storedStep struct {
+	step        Step
+	store       IntermediateStore
+	checkpoints data.CheckpointStore
+	key         string
+}
+
+// GetName returns the name of the wrapped step
+func (s *storedStep) GetName() string {
+	return s.step.GetName()
+}
+
+// Process returns the saved output for the item, or runs the step and saves its output
+func (s *storedStep) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	if item.ID != "" {
+		saved, ok, err := s.load(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return saved, nil
+		}
+	}
+
+	result, err := s.step.Process(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ProcessBatch runs the step on the items without saved outputs only, keeping the batch order.
+// Items are processed individually so that every completed output is saved even if
+// another item in the batch fails.
+func (s *storedStep) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	parallel := data.NewProcessItemParallelProcessor(data.NewProcessItemSliceSource(items), len(items), data.DefaultWorkers)
+	defer parallel.Close()
+	return parallel.ProcessAll(ctx, s.Process)
+}
+
+// ProcessSource runs the step on the source's items without saved outputs, through the
+// step's own ProcessSource if it has one so its retry policy and batching apply, and
+// returns the outputs of all items in source order. Outputs completed before an error are
+// saved, so a re-run resumes after them.
+func (s *storedStep) ProcessSource(ctx context.Context, source data.ProcessItemSource, batchSize, workers int) ([]*data.ProcessItem, error) {
+	inner, ok := s.step.(sourceStep)
+	if !ok {
+		parallel := data.NewProcessItemParallelProcessor(source, batchSize, workers)
+		defer parallel.Close()
+		return parallel.ProcessAll(ctx, s.Process)
+	}
+
+	pending := &unsavedSource{source: source, step: s}
+	results, err := inner.ProcessSource(ctx, pending, batchSize, workers)
+	for _, result := range results {
+		if saveErr := s.save(ctx, result); saveErr != nil {
+			return nil, saveErr
+		}
+	}
+	if err != nil {
+		return results, err
+	}
+	return pending.merge(results), nil
+}
+
+// unsavedSource yields the items of a source whose step output isn't saved, and keeps
+// the saved outputs of the others with the source order
+type unsavedSource struct {
+	source data.ProcessItemSource
+	step   *storedStep
+	mu     sync.Mutex
+	// order holds, in source order, each item's saved output or nil if it was yielded
+	order []*data.ProcessItem
+	// yielded are the items yielded, in source order
+	yielded []*data.ProcessItem
+}
+
+// NextProcessItem implements the ProcessItemSource interface
+func (u *unsavedSource) NextProcessItem(ctx context.Context) (*data.ProcessItem, error) {
+	for {
+		item, err := u.source.NextProcessItem(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var saved *data.ProcessItem
+		if item.ID != "" {
+			output, ok, err := u.step.load(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				saved = output
+			}
+		}
+
+		u.mu.Lock()
+		u.order = append(u.order, saved)
+		if saved == nil {
+			u.yielded = append(u.yielded, item)
+		}
+		u.mu.Unlock()
+		if saved == nil {
+			return item, nil
+		}
+	}
+}
+
+// Close implements the ProcessItemSource interface
+func (u *unsavedSource) Close() error {
+	return u.source.Close()
+}
+
+// merge interleaves the step's results for the yielded items with the saved outputs, in
+// source order. Results are matched by position, or by ID if the step dropped items.
+func (u *unsavedSource) merge(results []*data.ProcessItem) []*data.ProcessItem {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var byID map[string]*data.ProcessItem
+	if len(results) != len(u.yielded) {
+		byID = make(map[string]*data.ProcessItem, len(results))
+		for _, result := range results {
+			byID[result.ID] = result
+		}
+	}
+
+	merged := make([]*data.ProcessItem, 0, len(u.order))
+	next := 0
+	for _, saved := range u.order {
+		if saved != nil {
+			merged = append(merged, saved)
+			continue
+		}
+		item := u.yielded[next]
+		next++
+		if byID == nil {
+			merged = append(merged, results[next-1])
+		} else if result, ok := byID[item.ID]; ok {
+			merged = append(merged, result)
+		}
+	}
+	return merged
+}
+
+// load returns the saved output for an item. With checkpoints, only outputs whose
+// (item, step) pair was marked complete are used. Outputs of steps with a provenance, such
+// as processors, are only used if they were produced from the same input, prompt and model.
+func (s *storedStep) load(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, bool, error) {
+	provenance := s.provenance(item)
+	if s.checkpoints != nil {
+		complete, err := s.checkpoints.IsComplete(ctx, s.checkpointKey(item.ID, provenance))
+		if err != nil || !complete {
+			return nil, false, err
+		}
+	}
+	saved, ok, err := s.store.Load(ctx, s.key, item.ID)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if provenance != "" && saved.Provenance != "" && saved.Provenance != provenance {
+		return nil, false, nil
+	}
+	return saved, true, nil
+}
+
+// save stores a step output if it can be identified and checkpoints it
+func (s *storedStep) save(ctx context.Context, result *data.ProcessItem) error {
+	if result == nil || result.ID == "" {
+		return nil
+	}
+	if err := s.store.Save(ctx, s.key, result); err != nil {
+		return fmt.Errorf("failed to save output of step '%s': %w", s.step.GetName(), err)
+	}
+	if s.checkpoints != nil {
+		var provenance string
+		if _, ok := s.step.(provenanceStep); ok {
+			provenance = result.Provenance
+		}
+		if err := s.checkpoints.MarkComplete(ctx, s.checkpointKey(result.ID, provenance)); err != nil {
+			return fmt.Errorf("failed to checkpoint step '%s': %w", s.step.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// checkpointKey identifies an (item, step) pair in the checkpoint store, along with the
+// provenance of the step's output if it has one
+func (s *storedStep) checkpointKey(id, provenance string) string {
+	if provenance == "" {
+		return id + "@" + s.key
+	}
+	return id + "@" + s.key + "#" + provenance
+}
+
+// provenanceStep is a step hashing the provenance of its outputs, such as a processor
+type provenanceStep interface {
+	Provenance(item *data.ProcessItem) string
+}
+
+// provenance returns the provenance of the step's output for an item, or "" if the step
+// has none
+func (s *storedStep) provenance(item *data.ProcessItem) string {
+	if step, ok := s.step.(provenanceStep); ok {
+		return step.Provenance(item)
+	}
+	return ""
+}
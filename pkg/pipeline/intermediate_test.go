@@ -0,0 +1,179 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// sourceRecorder is a step that records whether each item went through its own
+// ProcessSource or through Process, and counts the items ProcessSource received
+type sourceRecorder struct {
+	fromSource atomic.Int64
+}
+
+func (s *sourceRecorder) GetName() string {
+	return "recorder"
+}
+
+func (s *sourceRecorder) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	return s.record(item, "process")
+}
+
+func (s *sourceRecorder) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	results := make([]*data.ProcessItem, len(items))
+	for i, item := range items {
+		results[i], _ = s.record(item, "process")
+	}
+	return results, nil
+}
+
+func (s *sourceRecorder) ProcessSource(ctx context.Context, source data.ProcessItemSource, batchSize, workers int) ([]*data.ProcessItem, error) {
+	parallel := data.NewProcessItemParallelProcessor(source, batchSize, workers)
+	defer parallel.Close()
+	return parallel.ProcessAll(ctx, func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		s.fromSource.Add(1)
+		return s.record(item, "source")
+	})
+}
+
+func (s *sourceRecorder) record(item *data.ProcessItem, via string) (*data.ProcessItem, error) {
+	result, err := item.Clone()
+	if err != nil {
+		return nil, err
+	}
+	result.AddProcessingInfo(s.GetName(), map[string]interface{}{"via": via})
+	return result, nil
+}
+
+func TestStoredStepProcessSource(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewDirectoryStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Save the outputs of items 1 and 3 as if an earlier run completed them
+	for _, i := range []int{1, 3} {
+		saved := data.NewTextProcessItem(fmt.Sprintf("item-%d", i), "saved", nil)
+		saved.AddProcessingInfo("recorder", map[string]interface{}{"via": "store"})
+		if err := store.Save(ctx, "01-recorder", saved); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	step := &sourceRecorder{}
+	chain := NewChain("stored").AddStep(step).WithIntermediateStore(store)
+	results, err := chain.ProcessSource(ctx, textSource(5), 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The step's own ProcessSource runs the unsaved items, and results keep source order
+	want := []string{"source", "store", "source", "store", "source"}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(results))
+	}
+	for i, result := range results {
+		if id := fmt.Sprintf("item-%d", i); result.ID != id {
+			t.Errorf("result %d: expected %s, got %s", i, id, result.ID)
+		}
+		if via := result.ProcessingInfo["recorder"].(map[string]interface{})["via"]; via != want[i] {
+			t.Errorf("result %d: expected output via %s, got %v", i, want[i], via)
+		}
+	}
+	if n := step.fromSource.Load(); n != 3 {
+		t.Errorf("expected ProcessSource to receive the 3 unsaved items, got %d", n)
+	}
+
+	// A re-run finds every output saved
+	step = &sourceRecorder{}
+	chain = NewChain("stored").AddStep(step).WithIntermediateStore(store)
+	results, err = chain.ProcessSource(ctx, textSource(5), 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 5 || step.fromSource.Load() != 0 {
+		t.Errorf("expected 5 saved results without running the step, got %d results and %d runs", len(results), step.fromSource.Load())
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	tests := []struct {
+		name     string
+		failStep int
+	}{
+		{"failure in the first step", 0},
+		{"failure in a later step", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			checkpoints := data.NewMemoryCheckpointStore()
+			store, err := NewDirectoryStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// run builds a two-step chain counting the items each step processes, with
+			// item-2 failing in failStep when fail is set
+			run := func(fail bool) ([]*data.ProcessItem, [2]int64, error) {
+				var calls [2]atomic.Int64
+				chain := NewChain("resume").WithCheckpoints(checkpoints, store)
+				for i, name := range []string{"first", "second"} {
+					i := i
+					chain.AddStep(&funcStep{name: name, fn: func(_ context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+						calls[i].Add(1)
+						if fail && i == tt.failStep && item.ID == "item-2" {
+							return nil, fmt.Errorf("step %d failed", i)
+						}
+						result, err := item.Clone()
+						if err != nil {
+							return nil, err
+						}
+						result.AddProcessingInfo(name, map[string]interface{}{"done": true})
+						return result, nil
+					}})
+				}
+				results, err := chain.ProcessSource(ctx, textSource(5), 5, 1)
+				return results, [2]int64{calls[0].Load(), calls[1].Load()}, err
+			}
+
+			if _, _, err := run(true); err == nil {
+				t.Fatal("expected the first run to fail")
+			}
+			completed := checkpoints.Completed()
+			if completed == 0 {
+				t.Fatal("expected the first run to checkpoint completed steps")
+			}
+
+			results, calls, err := run(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 5 {
+				t.Fatalf("expected 5 results, got %d", len(results))
+			}
+			for i, result := range results {
+				if id := fmt.Sprintf("item-%d", i); result.ID != id {
+					t.Errorf("result %d: expected %s, got %s", i, id, result.ID)
+				}
+				if keys := infoKeys(result); len(keys) != 2 {
+					t.Errorf("result %d: expected the output of both steps, got %v", i, keys)
+				}
+			}
+
+			// The re-run only processes the (item, step) pairs the first run didn't complete
+			if rerun := calls[0] + calls[1]; rerun != int64(10-completed) {
+				t.Errorf("expected %d steps to run again, got %d", 10-completed, rerun)
+			}
+			if checkpoints.Completed() != 10 {
+				t.Errorf("expected all 10 (item, step) pairs checkpointed, got %d", checkpoints.Completed())
+			}
+		})
+	}
+}
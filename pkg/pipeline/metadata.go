@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// MetadataMode controls which metadata fields survive each chain step
+type MetadataMode string
+
+const (
+	// MetadataCopyAll keeps every metadata field (the default)
+	MetadataCopyAll MetadataMode = "copy_all"
+	// MetadataWhitelist keeps only the fields listed in MetadataPolicy.Keys
+	MetadataWhitelist MetadataMode = "whitelist"
+)
+
+// reservedMetadataKeys are always kept because the framework depends on them
+var reservedMetadataKeys = []string{"original_text", data.LineageKey, data.StateKey, data.KafkaMessageKey, ReviewKey}
+
+// MetadataPolicy describes how metadata flows from one chain step to the next
+type MetadataPolicy struct {
+	// Mode selects copy-all or whitelist behaviour
+	Mode MetadataMode
+	// Keys are the metadata fields kept in whitelist mode
+	Keys []string
+	// Derive, if set, is called after each step and its fields are merged into the metadata
+	Derive func(item *data.ProcessItem) map[string]interface{}
+}
+
+// apply enforces the policy on an item's metadata
+func (m MetadataPolicy) apply(item *data.ProcessItem) {
+	if m.Mode == MetadataWhitelist && item.Metadata != nil {
+		keep := make(map[string]bool, len(m.Keys)+len(reservedMetadataKeys))
+		for _, key := range m.Keys {
+			keep[key] = true
+		}
+		for _, key := range reservedMetadataKeys {
+			keep[key] = true
+		}
+		for key := range item.Metadata {
+			if !keep[key] {
+				delete(item.Metadata, key)
+			}
+		}
+	}
+
+	if m.Derive != nil {
+		derived := m.Derive(item)
+		if len(derived) > 0 && item.Metadata == nil {
+			item.Metadata = make(map[string]interface{})
+		}
+		for key, value := range derived {
+			item.Metadata[key] = value
+		}
+	}
+}
+
+// WithMetadataPolicy sets how metadata flows between the chain's steps
+func (c *Chain) WithMetadataPolicy(policy MetadataPolicy) *Chain {
+	c.metadataPolicy = &policy
+	return c
+}
+
+// WithLineage enables lineage tracking: after each step, the processor name, timings,
+// pipeline name, source and library version are recorded under the "lineage" metadata key
+func (c *Chain) WithLineage(source string) *Chain {
+	c.lineage = true
+	c.lineageSource = source
+	return c
+}
+
+// afterStep applies the chain's metadata policy and lineage tracking to the results of a step
+func (c *Chain) afterStep(procName string, started time.Time, results ...*data.ProcessItem) error {
+	if c.metadataPolicy == nil && !c.lineage {
+		return nil
+	}
+
+	completed := time.Now().UTC()
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if c.lineage {
+			step := data.LineageStep{
+				Processor:   procName,
+				Pipeline:    c.name,
+				StartedAt:   started.UTC(),
+				CompletedAt: completed,
+			}
+			if err := result.AddLineageStep(c.lineageSource, step); err != nil {
+				return err
+			}
+		}
+		if c.metadataPolicy != nil {
+			c.metadataPolicy.apply(result)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+func TestMetadataPolicyWhitelist(t *testing.T) {
+	item := data.NewTextProcessItem("1", "text", map[string]interface{}{
+		"customer_id":        "c-1",
+		"agent_notes":        "internal",
+		"original_text":      "text",
+		data.KafkaMessageKey: "transcripts:0:42",
+		data.StateKey:        map[string]interface{}{"language": "en"},
+	})
+
+	MetadataPolicy{Mode: MetadataWhitelist, Keys: []string{"customer_id"}}.apply(item)
+
+	// Reserved keys survive even though they aren't listed, so KafkaSink can still commit
+	for _, key := range []string{"customer_id", "original_text", data.KafkaMessageKey, data.StateKey} {
+		if _, ok := item.Metadata[key]; !ok {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+	if _, ok := item.Metadata["agent_notes"]; ok {
+		t.Error("expected agent_notes to be removed")
+	}
+}
@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// ParallelStep runs several steps concurrently on the same input and merges their
+// results into one item, each under its own ProcessingInfo entry
+type ParallelStep struct {
+	name  string
+	steps []Step
+}
+
+// Parallel creates a step that fans the input out to every given step at once instead
+// of chaining them. Results are combined with MergeProcessingInfo.
+func Parallel(steps ...Step) *ParallelStep {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = step.GetName()
+	}
+	return &ParallelStep{
+		name:  "parallel(" + strings.Join(names, ",") + ")",
+		steps: steps,
+	}
+}
+
+// WithName sets the name the step reports, e.g. for lineage
+func (p *ParallelStep) WithName(name string) *ParallelStep {
+	p.name = name
+	return p
+}
+
+// GetName returns the step name
+func (p *ParallelStep) GetName() string {
+	return p.name
+}
+
+// Process runs every step on the item concurrently and merges the results
+func (p *ParallelStep) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	outputs, err := p.run(ctx, func(ctx context.Context, step Step) (interface{}, error) {
+		return step.Process(ctx, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make(map[string]*data.ProcessItem, len(outputs))
+	for i, output := range outputs {
+		inputs[branchKey(i, p.steps[i])] = output.(*data.ProcessItem)
+	}
+	return MergeProcessingInfo(ctx, item, inputs)
+}
+
+// ProcessBatch runs every step over the whole batch concurrently and merges the results item by item
+func (p *ParallelStep) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	tracker := data.NewProgressTracker(ctx, len(items))
+	inner := data.WithoutProgress(ctx)
+
+	outputs, err := p.run(inner, func(ctx context.Context, step Step) (interface{}, error) {
+		return step.ProcessBatch(ctx, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*data.ProcessItem, len(items))
+	for j, item := range items {
+		inputs := make(map[string]*data.ProcessItem, len(outputs))
+		for i, output := range outputs {
+			batch := output.([]*data.ProcessItem)
+			if j < len(batch) && batch[j] != nil {
+				inputs[branchKey(i, p.steps[i])] = batch[j]
+			}
+		}
+
+		merged, err := MergeProcessingInfo(ctx, item, inputs)
+		tracker.Record(err)
+		if err != nil {
+			return nil, err
+		}
+		results[j] = merged
+	}
+
+	return results, nil
+}
+
+// run calls fn for every step concurrently, cancelling the others on the first error
+func (p *ParallelStep) run(ctx context.Context, fn func(ctx context.Context, step Step) (interface{}, error)) ([]interface{}, error) {
+	if len(p.steps) == 0 {
+		return nil, fmt.Errorf("parallel step has no steps")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		outputs  = make([]interface{}, len(p.steps))
+	)
+
+	for i, step := range p.steps {
+		wg.Add(1)
+		go func(i int, step Step) {
+			defer wg.Done()
+
+			output, err := fn(ctx, step)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("processor '%s' error: %w", step.GetName(), err)
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			outputs[i] = output
+		}(i, step)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return outputs, nil
+}
+
+// branchKey identifies a branch's output when merging, keeping branches with the same name apart
+func branchKey(index int, step Step) string {
+	return fmt.Sprintf("%03d:%s", index, step.GetName())
+}
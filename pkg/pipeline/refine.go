@@ -0,0 +1,205 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// Defaults used by Refine when RefineConfig leaves them unset. They match the
+// result structure of the built-in quality_reviewer processor.
+const (
+	DefaultRefineMaxIterations = 3
+	DefaultRefineScorePath     = "overall_quality.score"
+)
+
+// DefaultRefineCritiquePaths are the validator result fields fed back to the generator by default
+var DefaultRefineCritiquePaths = []string{"overall_quality.weaknesses", "improvements.suggestion"}
+
+// RefineConfig configures an iterative refinement loop
+type RefineConfig struct {
+	// Generator produces the candidate result
+	Generator Step
+	// Validator scores the candidate, e.g. the quality_reviewer processor
+	Validator Step
+	// Threshold is the score at which a candidate is accepted
+	Threshold float64
+	// MaxIterations bounds the number of generate/validate rounds (defaults to DefaultRefineMaxIterations)
+	MaxIterations int
+	// ScorePath locates the numeric score in the validator's result (defaults to DefaultRefineScorePath)
+	ScorePath string
+	// CritiquePaths locate the critique fed back to the generator (defaults to DefaultRefineCritiquePaths)
+	CritiquePaths []string
+}
+
+// RefineIteration records one generate/validate round
+type RefineIteration struct {
+	Iteration int         `json:"iteration"`
+	Score     float64     `json:"score"`
+	Critique  []string    `json:"critique,omitempty"`
+	Result    interface{} `json:"result"`
+}
+
+// refineStep runs a generator and validator in a loop until the score reaches the threshold
+type refineStep struct {
+	name   string
+	config RefineConfig
+}
+
+// Refine creates a step that runs the generator, scores its output with the validator and,
+// while the score is below the threshold, regenerates with the critique appended to the
+// input. It returns the best-scoring output, carrying the validator's result and the
+// iteration history under the step's own ProcessingInfo entry.
+func Refine(name string, config RefineConfig) Step {
+	if config.MaxIterations <= 0 {
+		config.MaxIterations = DefaultRefineMaxIterations
+	}
+	if config.ScorePath == "" {
+		config.ScorePath = DefaultRefineScorePath
+	}
+	if config.CritiquePaths == nil {
+		config.CritiquePaths = DefaultRefineCritiquePaths
+	}
+	return &refineStep{name: name, config: config}
+}
+
+// GetName returns the step name
+func (r *refineStep) GetName() string {
+	return r.name
+}
+
+// Process runs the refinement loop for one item
+func (r *refineStep) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	if r.config.Generator == nil || r.config.Validator == nil {
+		return nil, fmt.Errorf("refine step '%s' needs a generator and a validator", r.name)
+	}
+
+	baseText, err := refineBaseText(item)
+	if err != nil {
+		return nil, err
+	}
+
+	generatorName := r.config.Generator.GetName()
+	validatorName := r.config.Validator.GetName()
+
+	var (
+		history   []RefineIteration
+		best      *data.ProcessItem
+		bestScore float64
+		bestIndex int
+		input     = item
+	)
+
+	for i := 1; i <= r.config.MaxIterations; i++ {
+		generated, err := r.config.Generator.Process(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("refine '%s' iteration %d generator error: %w", r.name, i, err)
+		}
+
+		validated, err := r.config.Validator.Process(ctx, generated)
+		if err != nil {
+			return nil, fmt.Errorf("refine '%s' iteration %d validator error: %w", r.name, i, err)
+		}
+
+		score := 0.0
+		if values := ResultValues(validated, validatorName, r.config.ScorePath); len(values) > 0 {
+			score, _ = values[0].(float64)
+		}
+		var critique []string
+		for _, path := range r.config.CritiquePaths {
+			for _, value := range ResultValues(validated, validatorName, path) {
+				critique = append(critique, fmt.Sprint(value))
+			}
+		}
+
+		history = append(history, RefineIteration{
+			Iteration: i,
+			Score:     score,
+			Critique:  critique,
+			Result:    generated.ProcessingInfo[generatorName],
+		})
+
+		// The generated item is returned, with the validator's verdict attached
+		if best == nil || score > bestScore {
+			best = generated
+			best.AddProcessingInfo(validatorName, validated.ProcessingInfo[validatorName])
+			bestScore = score
+			bestIndex = i
+		}
+
+		if score >= r.config.Threshold {
+			break
+		}
+
+		input, err = refineFeedbackItem(item, baseText, generated.ProcessingInfo[generatorName], critique)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	best.AddProcessingInfo(r.name, map[string]interface{}{
+		"processor_type": "refine",
+		"iterations":     history,
+		"best_iteration": bestIndex,
+		"best_score":     bestScore,
+		"threshold":      r.config.Threshold,
+		"threshold_met":  bestScore >= r.config.Threshold,
+	})
+	return best, nil
+}
+
+// ProcessBatch runs the refinement loop for each item in parallel
+func (r *refineStep) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	parallel := data.NewProcessItemParallelProcessor(data.NewProcessItemSliceSource(items), len(items), data.DefaultWorkers)
+	defer parallel.Close()
+	return parallel.ProcessAll(ctx, r.Process)
+}
+
+// refineBaseText returns the text the generator originally worked from
+func refineBaseText(item *data.ProcessItem) (string, error) {
+	if text, ok := item.Metadata["original_text"].(string); ok {
+		return text, nil
+	}
+	if item.ContentType == data.ContentTypeJSON {
+		encoded, err := json.Marshal(item.Content)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	}
+	return item.GetTextForProcessing()
+}
+
+// refineFeedbackItem builds the generator input for the next iteration: the original text
+// followed by the previous attempt and the critique it received
+func refineFeedbackItem(item *data.ProcessItem, baseText string, previous interface{}, critique []string) (*data.ProcessItem, error) {
+	next, err := item.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(baseText)
+	if encoded, err := json.MarshalIndent(previous, "", "  "); err == nil && previous != nil {
+		fmt.Fprintf(&sb, "\n\n**Previous Attempt:**\n%s", encoded)
+	}
+	if len(critique) > 0 {
+		sb.WriteString("\n\n**Feedback on Previous Attempt (address every point):**")
+		for _, point := range critique {
+			fmt.Fprintf(&sb, "\n- %s", point)
+		}
+	}
+
+	next.Content = sb.String()
+	next.ContentType = data.ContentTypeText
+	if next.Metadata == nil {
+		next.Metadata = make(map[string]interface{})
+	}
+	// Keep the original text as the item's source rather than the feedback prompt
+	next.Metadata["original_text"] = baseText
+	next.SetState("critique", critique)
+	return next, nil
+}
@@ -0,0 +1,399 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// ReviewKey is the metadata key holding an item's ReviewTicket
+const ReviewKey = "review"
+
+// ReviewStatus is the state of a human review
+type ReviewStatus string
+
+const (
+	// ReviewPending means the item is paused waiting for a reviewer
+	ReviewPending ReviewStatus = "pending"
+	// ReviewApproved means a reviewer approved the item, possibly with corrections
+	ReviewApproved ReviewStatus = "approved"
+	// ReviewRejected means a reviewer rejected the item; it does not continue through the pipeline
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// ReviewTicket is attached to an item paused for human review. Its token identifies the
+// item in the review queue and is used to resume it.
+type ReviewTicket struct {
+	Token       string       `json:"token"`
+	Step        string       `json:"step"`
+	Status      ReviewStatus `json:"status"`
+	RequestedAt time.Time    `json:"requested_at"`
+	Reviewer    string       `json:"reviewer,omitempty"`
+	Comment     string       `json:"comment,omitempty"`
+	ReviewedAt  *time.Time   `json:"reviewed_at,omitempty"`
+}
+
+// ReviewDecision is a reviewer's verdict on a paused item
+type ReviewDecision struct {
+	// Approved resumes the item through the remaining steps; otherwise it is rejected
+	Approved bool `json:"approved"`
+	// Corrections replace earlier processors' results, keyed by processor name
+	Corrections map[string]interface{} `json:"corrections,omitempty"`
+	// Reviewer identifies who made the decision
+	Reviewer string `json:"reviewer,omitempty"`
+	// Comment is an optional note from the reviewer
+	Comment string `json:"comment,omitempty"`
+}
+
+// GetReviewTicket returns the review ticket attached to an item, if any
+func GetReviewTicket(item *data.ProcessItem) (*ReviewTicket, bool) {
+	value, ok := item.Metadata[ReviewKey]
+	if !ok || value == nil {
+		return nil, false
+	}
+	if ticket, ok := value.(*ReviewTicket); ok {
+		copied := *ticket
+		return &copied, true
+	}
+
+	// Items read back from JSON hold the ticket as a generic map
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var ticket ReviewTicket
+	if err := json.Unmarshal(encoded, &ticket); err != nil || ticket.Token == "" {
+		return nil, false
+	}
+	return &ticket, true
+}
+
+// PendingReview reports whether an item is paused waiting for a reviewer
+func PendingReview(item *data.ProcessItem) bool {
+	if item == nil {
+		return false
+	}
+	ticket, ok := GetReviewTicket(item)
+	return ok && ticket.Status == ReviewPending
+}
+
+// ReviewQueue receives items paused for human review
+type ReviewQueue interface {
+	// Submit adds a paused item, carrying its ReviewTicket, to the queue
+	Submit(ctx context.Context, item *data.ProcessItem) error
+}
+
+// ReviewStore is a ReviewQueue that paused items can be read back from, so that a
+// separate process can resume them with Chain.ResumeReview
+type ReviewStore interface {
+	ReviewQueue
+
+	// Get returns the paused item with the given token, if there is one
+	Get(ctx context.Context, token string) (*data.ProcessItem, bool, error)
+
+	// Remove deletes the item with the given token from the queue
+	Remove(ctx context.Context, token string) error
+}
+
+// SinkReviewQueue is a ReviewQueue writing paused items to a sink, e.g. a JSONL file
+// read by a review tool
+type SinkReviewQueue struct {
+	sink data.ProcessItemSink
+}
+
+// NewSinkReviewQueue creates a new review queue writing to sink
+func NewSinkReviewQueue(sink data.ProcessItemSink) *SinkReviewQueue {
+	return &SinkReviewQueue{sink: sink}
+}
+
+// Submit implements the ReviewQueue interface
+func (q *SinkReviewQueue) Submit(ctx context.Context, item *data.ProcessItem) error {
+	if err := q.sink.Write(ctx, []*data.ProcessItem{item}); err != nil {
+		return err
+	}
+	return q.sink.Flush(ctx)
+}
+
+// DirectoryReviewQueue is a ReviewStore keeping one JSON file per paused item under
+// dir/<token>.json
+type DirectoryReviewQueue struct {
+	dir string
+}
+
+// NewDirectoryReviewQueue creates a new review queue rooted at dir, creating it if needed
+func NewDirectoryReviewQueue(dir string) (*DirectoryReviewQueue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create review queue directory: %w", err)
+	}
+	return &DirectoryReviewQueue{dir: dir}, nil
+}
+
+// Submit implements the ReviewQueue interface
+func (q *DirectoryReviewQueue) Submit(_ context.Context, item *data.ProcessItem) error {
+	ticket, ok := GetReviewTicket(item)
+	if !ok {
+		return fmt.Errorf("item %s has no review ticket", item.ID)
+	}
+
+	encoded, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal item %s: %w", item.ID, err)
+	}
+
+	path := q.path(ticket.Token)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, encoded, 0o644); err != nil {
+		return fmt.Errorf("failed to write review item: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write review item: %w", err)
+	}
+	return nil
+}
+
+// Get implements the ReviewStore interface
+func (q *DirectoryReviewQueue) Get(_ context.Context, token string) (*data.ProcessItem, bool, error) {
+	encoded, err := os.ReadFile(q.path(token))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read review item: %w", err)
+	}
+
+	var item data.ProcessItem
+	if err := json.Unmarshal(encoded, &item); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal review item %s: %w", token, err)
+	}
+	return &item, true, nil
+}
+
+// Remove implements the ReviewStore interface
+func (q *DirectoryReviewQueue) Remove(_ context.Context, token string) error {
+	if err := os.Remove(q.path(token)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove review item: %w", err)
+	}
+	return nil
+}
+
+// Pending returns the tokens of all items waiting in the queue, sorted
+func (q *DirectoryReviewQueue) Pending() ([]string, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review queue: %w", err)
+	}
+
+	var tokens []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		token, err := url.PathUnescape(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	return tokens, nil
+}
+
+// path returns the file holding a paused item
+func (q *DirectoryReviewQueue) path(token string) string {
+	return filepath.Join(q.dir, url.PathEscape(token)+".json")
+}
+
+// reviewStep pauses items matching a predicate for human review
+type reviewStep struct {
+	name      string
+	predicate Predicate
+	queue     ReviewQueue
+}
+
+// Review creates a step that pauses items matching predicate, such as low-confidence or
+// non-compliant results, and submits them to queue with a ReviewTicket. Paused items skip
+// the chain's remaining steps until they are resumed with Chain.ResumeReview; other items
+// pass through unchanged.
+func Review(name string, predicate Predicate, queue ReviewQueue) Step {
+	return &reviewStep{name: name, predicate: predicate, queue: queue}
+}
+
+// GetName returns the step name
+func (r *reviewStep) GetName() string {
+	return r.name
+}
+
+// Process pauses the item if it needs review
+func (r *reviewStep) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	if !r.predicate(item) {
+		return item, nil
+	}
+
+	token, err := newReviewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	paused, err := item.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if paused.Metadata == nil {
+		paused.Metadata = make(map[string]interface{})
+	}
+	paused.Metadata[ReviewKey] = &ReviewTicket{
+		Token:       token,
+		Step:        r.name,
+		Status:      ReviewPending,
+		RequestedAt: time.Now().UTC(),
+	}
+
+	if err := r.queue.Submit(ctx, paused); err != nil {
+		return nil, fmt.Errorf("failed to submit item %s for review: %w", item.ID, err)
+	}
+	return paused, nil
+}
+
+// ProcessBatch checks each item in parallel
+func (r *reviewStep) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	parallel := data.NewProcessItemParallelProcessor(data.NewProcessItemSliceSource(items), len(items), data.DefaultWorkers)
+	defer parallel.Close()
+	return parallel.ProcessAll(ctx, r.Process)
+}
+
+// AddReview appends a step pausing items that match predicate for human review
+func (c *Chain) AddReview(name string, predicate Predicate, queue ReviewQueue) *Chain {
+	return c.AddStep(Review(name, predicate, queue))
+}
+
+// Resume applies a reviewer's decision to an item paused by one of the chain's review
+// steps. An approved item has its corrections applied and continues through the steps
+// after the review step; a rejected item is returned as is.
+func (c *Chain) Resume(ctx context.Context, item *data.ProcessItem, decision ReviewDecision) (*data.ProcessItem, error) {
+	ticket, ok := GetReviewTicket(item)
+	if !ok || ticket.Status != ReviewPending {
+		return nil, fmt.Errorf("item %s is not pending review", item.ID)
+	}
+
+	index := -1
+	for i, step := range c.steps {
+		if step.GetName() == ticket.Step {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("chain '%s' has no review step '%s'", c.name, ticket.Step)
+	}
+
+	reviewed, err := item.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if reviewed.ProcessingInfo == nil {
+		reviewed.ProcessingInfo = make(map[string]interface{})
+	}
+
+	corrected := make([]string, 0, len(decision.Corrections))
+	for name, result := range decision.Corrections {
+		reviewed.ProcessingInfo[name] = result
+		corrected = append(corrected, name)
+	}
+	sort.Strings(corrected)
+
+	now := time.Now().UTC()
+	ticket.Status = ReviewRejected
+	if decision.Approved {
+		ticket.Status = ReviewApproved
+	}
+	ticket.Reviewer = decision.Reviewer
+	ticket.Comment = decision.Comment
+	ticket.ReviewedAt = &now
+	reviewed.Metadata[ReviewKey] = ticket
+
+	reviewed.ProcessingInfo[ticket.Step] = map[string]interface{}{
+		"processor_type": "review",
+		"status":         ticket.Status,
+		"reviewer":       decision.Reviewer,
+		"comment":        decision.Comment,
+		"corrected":      corrected,
+	}
+
+	if !decision.Approved {
+		return reviewed, nil
+	}
+	return c.processFrom(ctx, reviewed, index+1)
+}
+
+// ResumeReview loads the item with the given token from store, resumes it with the
+// decision and removes it from the queue
+func (c *Chain) ResumeReview(ctx context.Context, store ReviewStore, token string, decision ReviewDecision) (*data.ProcessItem, error) {
+	item, ok, err := store.Get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no item pending review with token %s", token)
+	}
+
+	result, err := c.Resume(ctx, item, decision)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Remove(ctx, token); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// newReviewToken returns a random token identifying a paused item
+func newReviewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate review token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// processActive runs a step over the items that aren't paused for review, leaving paused
+// items in place. It returns the full batch along with the items the step produced.
+func processActive(ctx context.Context, step Step, items []*data.ProcessItem) ([]*data.ProcessItem, []*data.ProcessItem, error) {
+	var active []*data.ProcessItem
+	var positions []int
+	for i, item := range items {
+		if !PendingReview(item) {
+			active = append(active, item)
+			positions = append(positions, i)
+		}
+	}
+	if len(active) == len(items) {
+		results, err := step.ProcessBatch(ctx, items)
+		return results, results, err
+	}
+
+	results := make([]*data.ProcessItem, len(items))
+	copy(results, items)
+	if len(active) == 0 {
+		return results, nil, nil
+	}
+
+	processed, err := step.ProcessBatch(ctx, active)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, result := range processed {
+		results[positions[i]] = result
+	}
+	return results, processed, nil
+}
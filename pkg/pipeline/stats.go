@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// StepStats holds the metrics recorded for one step of a chain
+type StepStats struct {
+	// Name is the step name
+	Name string
+	// Items is the number of items the step produced
+	Items int
+	// Errors counts failed step calls plus items the step skipped with an error recorded
+	Errors int
+	// Duration is the wall-clock time spent in the step
+	Duration time.Duration
+	// Tokens is the token usage reported by the step's processors
+	Tokens int64
+	// Cost is the cost reported by the step's processors
+	Cost float64
+}
+
+// AverageLatency returns the mean time spent in the step per item produced
+func (s StepStats) AverageLatency() time.Duration {
+	if s.Items == 0 {
+		return 0
+	}
+	return s.Duration / time.Duration(s.Items)
+}
+
+// ChainStats holds per-step metrics for a chain, accumulated since it was created or last reset
+type ChainStats struct {
+	// Steps holds the metrics for each step, in chain order
+	Steps []StepStats
+}
+
+// TotalDuration returns the time spent across all steps
+func (s ChainStats) TotalDuration() time.Duration {
+	var total time.Duration
+	for _, step := range s.Steps {
+		total += step.Duration
+	}
+	return total
+}
+
+// TotalTokens returns the token usage across all steps
+func (s ChainStats) TotalTokens() int64 {
+	var total int64
+	for _, step := range s.Steps {
+		total += step.Tokens
+	}
+	return total
+}
+
+// Slowest returns the step with the most time spent, identifying the bottleneck
+func (s ChainStats) Slowest() (StepStats, bool) {
+	var slowest StepStats
+	found := false
+	for _, step := range s.Steps {
+		if !found || step.Duration > slowest.Duration {
+			slowest = step
+			found = true
+		}
+	}
+	return slowest, found
+}
+
+// ChainStats returns a snapshot of the chain's per-step metrics
+func (c *Chain) ChainStats() ChainStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	steps := make([]StepStats, len(c.steps))
+	for i, step := range c.steps {
+		if i < len(c.stats) {
+			steps[i] = c.stats[i]
+		}
+		steps[i].Name = step.GetName()
+	}
+	return ChainStats{Steps: steps}
+}
+
+// ResetStats clears the chain's metrics, e.g. before a new run
+func (c *Chain) ResetStats() {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	c.stats = nil
+}
+
+// recordStep adds the outcome of one step call to the chain's metrics. inputs and
+// results are positionally matched; usage is the growth in reported tokens and cost.
+func (c *Chain) recordStep(index int, started time.Time, inputs, results []*data.ProcessItem, err error) {
+	elapsed := time.Since(started)
+	name := c.steps[index].GetName()
+
+	var tokens int64
+	var cost float64
+	errors := 0
+	if err != nil {
+		errors++
+	}
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		resultTokens, resultCost := itemUsage(result)
+		if i < len(inputs) && inputs[i] != nil {
+			inputTokens, inputCost := itemUsage(inputs[i])
+			resultTokens -= inputTokens
+			resultCost -= inputCost
+		}
+		tokens += resultTokens
+		cost += resultCost
+
+		if info, ok := result.ProcessingInfo[name].(map[string]interface{}); ok {
+			if _, failed := info["error"]; failed {
+				errors++
+			}
+		}
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	for len(c.stats) <= index {
+		c.stats = append(c.stats, StepStats{})
+	}
+	stats := &c.stats[index]
+	stats.Items += len(results)
+	stats.Errors += errors
+	stats.Duration += elapsed
+	stats.Tokens += tokens
+	stats.Cost += cost
+}
+
+// itemUsage sums the token and cost figures recorded by every processor on an item
+func itemUsage(item *data.ProcessItem) (int64, float64) {
+	var tokens int64
+	var cost float64
+	for _, info := range item.ProcessingInfo {
+		infoTokens, infoCost := data.UsageFromInfo(info)
+		tokens += infoTokens
+		cost += infoCost
+	}
+	return tokens, cost
+}
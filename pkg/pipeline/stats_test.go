@@ -0,0 +1,37 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+func TestChainStats(t *testing.T) {
+	options := processor.NewDefaultOptions().WithTokenCost(1, 1)
+	chain := NewChain("stats", newSentiment(t, options))
+
+	if _, err := chain.ProcessSource(context.Background(), textSource(3), 2, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := chain.ChainStats()
+	if len(stats.Steps) != 1 {
+		t.Fatalf("expected stats for 1 step, got %d", len(stats.Steps))
+	}
+	step := stats.Steps[0]
+	if step.Name != "sentiment" || step.Items != 3 || step.Errors != 0 {
+		t.Errorf("unexpected step stats: %+v", step)
+	}
+	if step.Tokens <= 0 || step.Cost <= 0 {
+		t.Errorf("expected the step to report usage, got %d tokens and cost %g", step.Tokens, step.Cost)
+	}
+	if stats.TotalTokens() != step.Tokens {
+		t.Errorf("expected total tokens %d, got %d", step.Tokens, stats.TotalTokens())
+	}
+
+	chain.ResetStats()
+	if stats := chain.ChainStats(); stats.Steps[0].Items != 0 || stats.Steps[0].Tokens != 0 {
+		t.Errorf("expected reset stats, got %+v", stats.Steps[0])
+	}
+}
@@ -0,0 +1,187 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// Step is a single stage of a Chain. Every processor.Processor is a Step, as are
+// Chain and DAG themselves.
+type Step interface {
+	// GetName returns the name of the step
+	GetName() string
+
+	// Process processes a single item
+	Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error)
+
+	// ProcessBatch processes a batch of items
+	ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error)
+}
+
+// sourceStep is implemented by steps that can read directly from a source
+type sourceStep interface {
+	ProcessSource(ctx context.Context, source data.ProcessItemSource, batchSize, workers int) ([]*data.ProcessItem, error)
+}
+
+// Predicate decides whether a conditional step runs for an item
+type Predicate func(item *data.ProcessItem) bool
+
+// ResultEquals returns a Predicate that is true when the result recorded by a previous
+// processor has the given field value, e.g. ResultEquals("sentiment", "sentiment", "negative")
+func ResultEquals(processorName, field string, value interface{}) Predicate {
+	return func(item *data.ProcessItem) bool {
+		info, ok := item.ProcessingInfo[processorName].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		actual, ok := info[field]
+		return ok && fmt.Sprint(actual) == fmt.Sprint(value)
+	}
+}
+
+// ResultBelow returns a Predicate that is true when the number at a dot-separated path in
+// a previous processor's result is below threshold, e.g. ResultBelow("intent", "confidence", 0.7)
+// to catch low-confidence results
+func ResultBelow(processorName, path string, threshold float64) Predicate {
+	return func(item *data.ProcessItem) bool {
+		for _, value := range ResultValues(item, processorName, path) {
+			if number, ok := value.(float64); ok && number < threshold {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MetadataEquals returns a Predicate that is true when a metadata field has the given value
+func MetadataEquals(key string, value interface{}) Predicate {
+	return func(item *data.ProcessItem) bool {
+		actual, ok := item.Metadata[key]
+		return ok && fmt.Sprint(actual) == fmt.Sprint(value)
+	}
+}
+
+// conditionalStep runs a step only for items matching a predicate; other items pass through unchanged
+type conditionalStep struct {
+	predicate Predicate
+	step      Step
+}
+
+// GetName returns the name of the wrapped step
+func (s *conditionalStep) GetName() string {
+	return s.step.GetName()
+}
+
+// Process runs the wrapped step if the predicate matches
+func (s *conditionalStep) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	if !s.predicate(item) {
+		return item, nil
+	}
+	return s.step.Process(ctx, item)
+}
+
+// ProcessBatch runs the wrapped step on the matching items only, keeping the batch order
+func (s *conditionalStep) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	results := make([]*data.ProcessItem, len(items))
+	var matched []*data.ProcessItem
+	var positions []int
+	for i, item := range items {
+		if s.predicate(item) {
+			matched = append(matched, item)
+			positions = append(positions, i)
+		} else {
+			results[i] = item
+		}
+	}
+
+	if len(matched) > 0 {
+		processed, err := s.step.ProcessBatch(ctx, matched)
+		if err != nil {
+			return nil, err
+		}
+		for i, result := range processed {
+			results[positions[i]] = result
+		}
+	}
+
+	return results, nil
+}
+
+// Add appends a processor to the chain
+func (c *Chain) Add(proc processor.Processor) *Chain {
+	return c.AddStep(proc)
+}
+
+// AddStep appends any step, such as another Chain or a DAG, to the chain
+func (c *Chain) AddStep(step Step) *Chain {
+	c.steps = append(c.steps, step)
+	return c
+}
+
+// AddConditional appends a step that only runs for items where predicate is true.
+// Items that don't match pass through unchanged, saving the LLM call.
+func (c *Chain) AddConditional(predicate Predicate, step Step) *Chain {
+	return c.AddStep(&conditionalStep{predicate: predicate, step: step})
+}
+
+// ErrorPolicy controls what a step does when processing an item fails
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyFail stops the pipeline with the error (the default)
+	ErrorPolicyFail ErrorPolicy = "fail"
+	// ErrorPolicySkip passes the item on unchanged, recording the error under the step's ProcessingInfo entry
+	ErrorPolicySkip ErrorPolicy = "skip"
+)
+
+// WithErrorPolicy wraps a step so that its failures are handled according to policy
+func WithErrorPolicy(step Step, policy ErrorPolicy) Step {
+	if policy == ErrorPolicySkip {
+		return &skipOnErrorStep{step: step}
+	}
+	return step
+}
+
+// skipOnErrorStep records failures on the item instead of returning them
+type skipOnErrorStep struct {
+	step Step
+}
+
+// GetName returns the name of the wrapped step
+func (s *skipOnErrorStep) GetName() string {
+	return s.step.GetName()
+}
+
+// Process runs the wrapped step, recording any error on the item
+func (s *skipOnErrorStep) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	result, err := s.step.Process(ctx, item)
+	if err == nil {
+		return result, nil
+	}
+	// Cancellation still stops the pipeline
+	if ctx.Err() != nil {
+		return nil, err
+	}
+
+	failed, cloneErr := item.Clone()
+	if cloneErr != nil {
+		return nil, cloneErr
+	}
+	if failed.ProcessingInfo == nil {
+		failed.ProcessingInfo = make(map[string]interface{})
+	}
+	failed.ProcessingInfo[s.step.GetName()] = map[string]interface{}{
+		"error": err.Error(),
+	}
+	return failed, nil
+}
+
+// ProcessBatch processes items individually in parallel so one failure doesn't discard the batch
+func (s *skipOnErrorStep) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	parallel := data.NewProcessItemParallelProcessor(data.NewProcessItemSliceSource(items), len(items), data.DefaultWorkers)
+	defer parallel.Close()
+	return parallel.ProcessAll(ctx, s.Process)
+}
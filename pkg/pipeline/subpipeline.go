@@ -0,0 +1,135 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// SubPipelineFactory creates a reusable sub-pipeline using the given provider
+type SubPipelineFactory func(provider llm.Provider) (Pipeline, error)
+
+// Global sub-pipeline registry, mirroring the processor registry
+var (
+	subPipelineRegistry     = make(map[string]SubPipelineFactory)
+	subPipelineRegistryLock sync.RWMutex
+)
+
+// RegisterSubPipeline registers a sub-pipeline factory so that pipelines, including
+// config files, can use it as a step by name
+func RegisterSubPipeline(name string, factory SubPipelineFactory) {
+	subPipelineRegistryLock.Lock()
+	defer subPipelineRegistryLock.Unlock()
+	subPipelineRegistry[name] = factory
+}
+
+// CreateSubPipeline creates a registered sub-pipeline by name
+func CreateSubPipeline(name string, provider llm.Provider) (Pipeline, error) {
+	subPipelineRegistryLock.RLock()
+	factory, ok := subPipelineRegistry[name]
+	subPipelineRegistryLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("sub-pipeline not found: %s", name)
+	}
+	return factory(provider)
+}
+
+// ListSubPipelines returns the names of the registered sub-pipelines
+func ListSubPipelines() []string {
+	subPipelineRegistryLock.RLock()
+	defer subPipelineRegistryLock.RUnlock()
+	names := make([]string, 0, len(subPipelineRegistry))
+	for name := range subPipelineRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SubPipelineOptions control how a sub-pipeline runs inside another pipeline
+type SubPipelineOptions struct {
+	// OnError is the error policy for the sub-pipeline as a whole: "fail" (the default)
+	// or "skip", which passes the item on unchanged with the error recorded
+	OnError ErrorPolicy
+	// When runs the sub-pipeline only for items matching the predicate
+	When Predicate
+	// Namespace nests the results of the sub-pipeline's steps under its own ProcessingInfo
+	// entry instead of adding them alongside the outer pipeline's results, so the same
+	// sub-pipeline can be used more than once in a pipeline
+	Namespace bool
+}
+
+// subPipelineStep runs a Chain or DAG as a single step
+type subPipelineStep struct {
+	name      string
+	pipeline  Step
+	namespace bool
+}
+
+// SubPipeline creates a step that runs a Chain or DAG, such as a shared
+// normalize → redact → language-detect flow, as a single step of another pipeline
+func SubPipeline(name string, pipeline Step, options SubPipelineOptions) Step {
+	var step Step = &subPipelineStep{name: name, pipeline: pipeline, namespace: options.Namespace}
+	step = WithErrorPolicy(step, options.OnError)
+	if options.When != nil {
+		step = &conditionalStep{predicate: options.When, step: step}
+	}
+	return step
+}
+
+// AddSubPipeline appends a Chain or DAG to the chain as a single step
+func (c *Chain) AddSubPipeline(name string, pipeline Step, options SubPipelineOptions) *Chain {
+	return c.AddStep(SubPipeline(name, pipeline, options))
+}
+
+// GetName returns the step name
+func (s *subPipelineStep) GetName() string {
+	return s.name
+}
+
+// Process runs the sub-pipeline for one item
+func (s *subPipelineStep) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	result, err := s.pipeline.Process(ctx, item)
+	if err != nil {
+		return nil, fmt.Errorf("sub-pipeline '%s': %w", s.name, err)
+	}
+	return s.nest(item, result), nil
+}
+
+// ProcessBatch runs the sub-pipeline for a batch of items
+func (s *subPipelineStep) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	results, err := s.pipeline.ProcessBatch(ctx, items)
+	if err != nil {
+		return nil, fmt.Errorf("sub-pipeline '%s': %w", s.name, err)
+	}
+	for i, result := range results {
+		if i < len(items) {
+			results[i] = s.nest(items[i], result)
+		}
+	}
+	return results, nil
+}
+
+// nest moves the results the sub-pipeline added to an item under the step's own
+// ProcessingInfo entry when namespacing is enabled
+func (s *subPipelineStep) nest(input, result *data.ProcessItem) *data.ProcessItem {
+	if !s.namespace || result == nil {
+		return result
+	}
+
+	nested := make(map[string]interface{})
+	for name, info := range result.ProcessingInfo {
+		if _, existed := input.ProcessingInfo[name]; !existed {
+			nested[name] = info
+			delete(result.ProcessingInfo, name)
+		}
+	}
+	if len(nested) > 0 {
+		result.AddProcessingInfo(s.name, nested)
+	}
+	return result
+}
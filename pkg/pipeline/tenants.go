@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// TenantField is the metadata field naming the tenant an item belongs to
+const TenantField = "tenant"
+
+// TenantConfig declares the provider, with its API key and model, and the budget of a tenant
+type TenantConfig struct {
+	// Provider is the tenant's provider
+	Provider ProviderConfig `json:"provider" yaml:"provider"`
+	// Budget limits the tenant's usage
+	Budget llm.TenantBudget `json:"budget,omitempty" yaml:"budget,omitempty"`
+}
+
+// NewProviderPool creates the providers of the tenants the configs declare
+func NewProviderPool(tenants map[string]TenantConfig) (*llm.ProviderPool, error) {
+	names := make([]string, 0, len(tenants))
+	for name := range tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pool := llm.NewProviderPool()
+	for _, name := range names {
+		provider, err := tenants[name].Provider.NewProvider()
+		if err != nil {
+			return nil, fmt.Errorf("tenant '%s': %w", name, err)
+		}
+		pool.Add(name, provider, tenants[name].Budget)
+	}
+	return pool, nil
+}
+
+// ItemTenant returns the tenant named by an item's metadata, or "" if it names none
+func ItemTenant(item *data.ProcessItem) string {
+	tenant, _ := item.Metadata[TenantField].(string)
+	return tenant
+}
+
+// tenantStep runs a processor with the provider of each item's tenant
+type tenantStep struct {
+	name    string
+	pool    *llm.ProviderPool
+	options processor.Options
+
+	mu         sync.Mutex
+	processors map[string]processor.Processor
+}
+
+// NewTenantStep creates a step running a registered processor on each item with the
+// provider of the tenant its metadata names under TenantField. Items of unknown tenants,
+// or of none, fail, so no tenant's items run on another's API key.
+func NewTenantStep(name string, pool *llm.ProviderPool, options processor.Options) Step {
+	return &tenantStep{
+		name:       name,
+		pool:       pool,
+		options:    options,
+		processors: make(map[string]processor.Processor),
+	}
+}
+
+// GetName returns the processor name
+func (s *tenantStep) GetName() string {
+	return s.name
+}
+
+// Process runs the processor of the item's tenant
+func (s *tenantStep) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	proc, err := s.processor(ItemTenant(item))
+	if err != nil {
+		return nil, data.Permanent(fmt.Errorf("item '%s': %w", item.ID, err))
+	}
+	return proc.Process(ctx, item)
+}
+
+// ProcessBatch processes items individually in parallel, as they may belong to different tenants
+func (s *tenantStep) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	parallel := data.NewProcessItemParallelProcessor(data.NewProcessItemSliceSource(items), len(items), data.DefaultWorkers)
+	defer parallel.Close()
+	return parallel.ProcessAll(ctx, s.Process)
+}
+
+// processor returns the processor of a tenant, creating it on first use
+func (s *tenantStep) processor(tenant string) (processor.Processor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if proc, ok := s.processors[tenant]; ok {
+		return proc, nil
+	}
+
+	provider, err := s.pool.Provider(tenant)
+	if err != nil {
+		return nil, err
+	}
+	proc, err := processor.Create(s.name, provider, s.options)
+	if err != nil {
+		return nil, err
+	}
+	s.processors[tenant] = proc
+	return proc, nil
+}
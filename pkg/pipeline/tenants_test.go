@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+func TestTenantStep(t *testing.T) {
+	pool := llm.NewProviderPool().
+		Add("acme", llm.NewMockProviderWithResponse(`{"sentiment": "positive", "score": 0.1, "confidence": 0.9, "keywords": []}`), llm.TenantBudget{}).
+		Add("globex", llm.NewMockProviderWithResponse(`{"sentiment": "negative", "score": -0.2, "confidence": 0.9, "keywords": []}`), llm.TenantBudget{MaxTokens: 1})
+	config := &Config{Name: "tenants", Steps: []StepConfig{{Processor: "sentiment"}}}
+	p, err := config.BuildWithProviderPool(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tenant  interface{}
+		want    string
+		wantErr error
+	}{
+		{name: "acme", tenant: "acme", want: "positive"},
+		{name: "globex", tenant: "globex", want: "negative"},
+		{name: "budget used up", tenant: "globex", wantErr: llm.ErrBudgetExceeded},
+		{name: "unknown tenant", tenant: "initech", wantErr: llm.ErrUnknownTenant},
+		{name: "no tenant", wantErr: llm.ErrUnknownTenant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := data.NewTextProcessItem("1", "text", map[string]interface{}{TenantField: tt.tenant})
+			result, err := p.Process(context.Background(), item)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !data.IsPermanent(err) {
+					t.Fatalf("expected a permanent %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, _ := result.ProcessingInfo["sentiment"].(map[string]interface{})
+			if info["sentiment"] != tt.want {
+				t.Errorf("expected the tenant's sentiment %q, got %v", tt.want, info["sentiment"])
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// TransformFunc reshapes an item between chain steps. It receives a copy of the item,
+// so it may modify and return it directly.
+type TransformFunc func(item *data.ProcessItem) (*data.ProcessItem, error)
+
+// transformStep runs a TransformFunc as a chain step
+type transformStep struct {
+	name string
+	fn   TransformFunc
+}
+
+// Transform creates a named step that applies fn to each item without calling an LLM
+func Transform(name string, fn TransformFunc) Step {
+	return &transformStep{name: name, fn: fn}
+}
+
+// GetName returns the step name
+func (t *transformStep) GetName() string {
+	return t.name
+}
+
+// Process applies the transform to a copy of the item
+func (t *transformStep) Process(_ context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	input, err := item.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.fn(input)
+	if err != nil {
+		return nil, fmt.Errorf("transform '%s' error on item %s: %w", t.name, item.ID, err)
+	}
+	if result == nil {
+		return nil, fmt.Errorf("transform '%s' returned no item for %s", t.name, item.ID)
+	}
+	return result, nil
+}
+
+// ProcessBatch applies the transform to each item in turn
+func (t *transformStep) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	tracker := data.NewProgressTracker(ctx, len(items))
+	results := make([]*data.ProcessItem, len(items))
+	for i, item := range items {
+		result, err := t.Process(ctx, item)
+		tracker.Record(err)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// AddTransform appends a step that reshapes each item before the next processor,
+// e.g. to build the combined input a later processor expects
+func (c *Chain) AddTransform(fn TransformFunc) *Chain {
+	return c.AddStep(Transform(fmt.Sprintf("transform_%d", len(c.steps)+1), fn))
+}
+
+// ResultWithOriginalText returns a TransformFunc that replaces the item's content with the
+// result of a previous processor, as indented JSON, followed by the original text. This
+// is the input get_attributes expects after required_attributes, although ResultAsInput
+// passes the result to it without changing the content.
+func ResultWithOriginalText(processorName string) TransformFunc {
+	return func(item *data.ProcessItem) (*data.ProcessItem, error) {
+		result, ok := item.ProcessingInfo[processorName]
+		if !ok {
+			return nil, fmt.Errorf("no result from processor %s", processorName)
+		}
+		originalText, ok := item.Metadata["original_text"].(string)
+		if !ok {
+			return nil, fmt.Errorf("item has no original text")
+		}
+
+		encoded, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s result: %w", processorName, err)
+		}
+
+		item.Content = fmt.Sprintf("%s\n\n%s", encoded, originalText)
+		item.ContentType = data.ContentTypeText
+		return item, nil
+	}
+}
+
+// ResultAsInput returns a TransformFunc that copies a previous processor's result into the
+// metadata key a later processor reads as a structured prompt input (see
+// processor.PromptInput) and restores the original text as the content, e.g.
+// ResultAsInput("attributes", "required_attributes") before get_attributes. Under a
+// MetadataWhitelist policy, key must be listed to reach that step.
+func ResultAsInput(key, processorName string) TransformFunc {
+	return func(item *data.ProcessItem) (*data.ProcessItem, error) {
+		result, ok := item.ProcessingInfo[processorName]
+		if !ok {
+			return nil, fmt.Errorf("no result from processor %s", processorName)
+		}
+		if item.Metadata == nil {
+			item.Metadata = make(map[string]interface{})
+		}
+		item.Metadata[key] = result
+
+		if originalText, ok := item.Metadata["original_text"].(string); ok {
+			item.Content = originalText
+			item.ContentType = data.ContentTypeText
+		}
+		return item, nil
+	}
+}
+
+// CaptureState returns a TransformFunc that copies the value at path in a previous
+// processor's result into the item's state under key, e.g.
+// CaptureState("customer_sentiment", "sentiment", "sentiment"). A single value is stored
+// as is; several values (from arrays along the path) are stored as a slice.
+func CaptureState(key, processorName, path string) TransformFunc {
+	return func(item *data.ProcessItem) (*data.ProcessItem, error) {
+		values := ResultValues(item, processorName, path)
+		switch len(values) {
+		case 0:
+			return nil, fmt.Errorf("no value at %s in %s result", path, processorName)
+		case 1:
+			item.SetState(key, values[0])
+		default:
+			item.SetState(key, values)
+		}
+		return item, nil
+	}
+}
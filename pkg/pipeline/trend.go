@@ -0,0 +1,285 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// Trend bucket intervals
+const (
+	TrendDay   = "day"
+	TrendWeek  = "week"
+	TrendMonth = "month"
+)
+
+// TrendConfig holds the configuration of the trend aggregate functions
+type TrendConfig struct {
+	// TimeField is the metadata key holding each item's timestamp (defaults to "timestamp").
+	// Timestamps are time.Time values, RFC 3339 or YYYY-MM-DD strings, or Unix seconds;
+	// items without one are left out.
+	TimeField string
+	// Interval is the size of the time buckets: TrendDay, TrendWeek (the default, starting
+	// on Mondays) or TrendMonth. Buckets are in UTC.
+	Interval string
+	// Window is the number of buckets averaged by AverageTrend's moving average (defaults to 4)
+	Window int
+}
+
+// withDefaults returns the config with defaults applied
+func (c TrendConfig) withDefaults() TrendConfig {
+	if c.TimeField == "" {
+		c.TimeField = "timestamp"
+	}
+	if c.Interval == "" {
+		c.Interval = TrendWeek
+	}
+	if c.Window <= 0 {
+		c.Window = 4
+	}
+	return c
+}
+
+// CountBucket is the number of times each value occurred in one time bucket
+type CountBucket struct {
+	// Start is the start of the bucket
+	Start time.Time `json:"start"`
+	// Items is the number of items in the bucket
+	Items int `json:"items"`
+	// Counts are the occurrences of each value
+	Counts map[string]int `json:"counts"`
+}
+
+// AverageBucket is the average of a numeric value in one time bucket
+type AverageBucket struct {
+	// Start is the start of the bucket
+	Start time.Time `json:"start"`
+	// Items is the number of items with a value in the bucket
+	Items int `json:"items"`
+	// Average is the average value in the bucket, 0 if it has no items
+	Average float64 `json:"average"`
+	// MovingAverage is the average value over this bucket and the Window-1 before it
+	MovingAverage float64 `json:"moving_average"`
+}
+
+// CountTrend returns an AggregateFunc counting the values found at path in a processor's
+// results per time bucket, e.g. CountTrend("intent", "intents.label", config) for intent
+// volume per week. The result is a []CountBucket in time order, without gaps.
+func CountTrend(processorName, path string, config TrendConfig) AggregateFunc {
+	config = config.withDefaults()
+	return func(_ context.Context, items []*data.ProcessItem) (interface{}, error) {
+		buckets, err := bucketItems(items, config)
+		if err != nil {
+			return nil, err
+		}
+
+		result := make([]CountBucket, len(buckets))
+		for i, bucket := range buckets {
+			counts := make(map[string]int)
+			for _, item := range bucket.items {
+				for _, value := range ResultValues(item, processorName, path) {
+					counts[fmt.Sprint(value)]++
+				}
+			}
+			result[i] = CountBucket{Start: bucket.start, Items: len(bucket.items), Counts: counts}
+		}
+		return result, nil
+	}
+}
+
+// AverageTrend returns an AggregateFunc averaging the numeric values found at path in a
+// processor's results per time bucket, with a moving average over config.Window buckets,
+// e.g. AverageTrend("sentiment", "score", config). The result is an []AverageBucket in time
+// order, without gaps.
+func AverageTrend(processorName, path string, config TrendConfig) AggregateFunc {
+	config = config.withDefaults()
+	return func(_ context.Context, items []*data.ProcessItem) (interface{}, error) {
+		buckets, err := bucketItems(items, config)
+		if err != nil {
+			return nil, err
+		}
+
+		result := make([]AverageBucket, len(buckets))
+		sums := make([]float64, len(buckets))
+		for i, bucket := range buckets {
+			result[i].Start = bucket.start
+			for _, item := range bucket.items {
+				for _, value := range ResultValues(item, processorName, path) {
+					if number, ok := value.(float64); ok {
+						sums[i] += number
+						result[i].Items++
+					}
+				}
+			}
+			if result[i].Items > 0 {
+				result[i].Average = sums[i] / float64(result[i].Items)
+			}
+
+			// The moving average weighs buckets by their number of values
+			var sum float64
+			n := 0
+			for j := max(0, i-config.Window+1); j <= i; j++ {
+				sum += sums[j]
+				n += result[j].Items
+			}
+			if n > 0 {
+				result[i].MovingAverage = sum / float64(n)
+			}
+		}
+		return result, nil
+	}
+}
+
+// TrendReport is the result of LLMTrendNarrative
+type TrendReport struct {
+	// Series are the computed trend series by name
+	Series map[string]interface{} `json:"series"`
+	// Narrative is the LLM's description of notable shifts in the series
+	Narrative string `json:"narrative"`
+}
+
+// LLMTrendNarrative returns an AggregateFunc computing trend series, such as those of
+// CountTrend and AverageTrend, and asking an LLM to describe their notable shifts.
+// instructions describe what the narrative should cover. The result is a TrendReport.
+func LLMTrendNarrative(provider llm.Provider, instructions string, series map[string]AggregateFunc) AggregateFunc {
+	if instructions == "" {
+		instructions = "Describe the notable shifts in the series, such as spikes, drops and changes of direction, with the periods they happened in. Do not describe periods without notable change."
+	}
+
+	return func(ctx context.Context, items []*data.ProcessItem) (interface{}, error) {
+		names := make([]string, 0, len(series))
+		for name := range series {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		report := TrendReport{Series: make(map[string]interface{}, len(series))}
+		var sb strings.Builder
+		sb.WriteString("You are an expert analyst describing trends in the results of processing a collection of texts over time.\n\n")
+		sb.WriteString("**Instructions**\n")
+		sb.WriteString(instructions)
+		sb.WriteString("\n\n**Series**\n")
+		for _, name := range names {
+			value, err := series[name](ctx, items)
+			if err != nil {
+				return nil, fmt.Errorf("series '%s' error: %w", name, err)
+			}
+			report.Series[name] = value
+
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode series '%s': %w", name, err)
+			}
+			fmt.Fprintf(&sb, "- %s: %s\n", name, encoded)
+		}
+
+		narrative, err := provider.Generate(ctx, sb.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate trend narrative: %w", err)
+		}
+		report.Narrative = strings.TrimSpace(narrative)
+		return report, nil
+	}
+}
+
+// timeBucket is the items of one time bucket
+type timeBucket struct {
+	start time.Time
+	items []*data.ProcessItem
+}
+
+// bucketItems groups items with a timestamp into consecutive time buckets, from the bucket
+// of the earliest item to the bucket of the latest
+func bucketItems(items []*data.ProcessItem, config TrendConfig) ([]timeBucket, error) {
+	byStart := make(map[time.Time][]*data.ProcessItem)
+	var first, last time.Time
+	for _, item := range items {
+		timestamp, ok, err := itemTime(item, config.TimeField)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		start, err := bucketStart(timestamp, config.Interval)
+		if err != nil {
+			return nil, err
+		}
+		if len(byStart) == 0 || start.Before(first) {
+			first = start
+		}
+		if len(byStart) == 0 || start.After(last) {
+			last = start
+		}
+		byStart[start] = append(byStart[start], item)
+	}
+	if len(byStart) == 0 {
+		return nil, nil
+	}
+
+	var buckets []timeBucket
+	for start := first; !start.After(last); start = nextBucket(start, config.Interval) {
+		buckets = append(buckets, timeBucket{start: start, items: byStart[start]})
+	}
+	return buckets, nil
+}
+
+// itemTime returns the timestamp in an item's metadata field
+func itemTime(item *data.ProcessItem, field string) (time.Time, bool, error) {
+	switch value := item.Metadata[field].(type) {
+	case nil:
+		return time.Time{}, false, nil
+	case time.Time:
+		return value, true, nil
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, true, nil
+			}
+		}
+		return time.Time{}, false, fmt.Errorf("item %s: invalid timestamp %q in %s", item.ID, value, field)
+	case float64:
+		return time.Unix(int64(value), 0), true, nil
+	case int64:
+		return time.Unix(value, 0), true, nil
+	case int:
+		return time.Unix(int64(value), 0), true, nil
+	default:
+		return time.Time{}, false, fmt.Errorf("item %s: unsupported timestamp type %T in %s", item.ID, value, field)
+	}
+}
+
+// bucketStart returns the start of the bucket holding t
+func bucketStart(t time.Time, interval string) (time.Time, error) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case TrendDay:
+		return day, nil
+	case TrendWeek:
+		// Weeks start on Monday
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7), nil
+	case TrendMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown trend interval: %s", interval)
+	}
+}
+
+// nextBucket returns the start of the bucket after the one starting at start
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case TrendDay:
+		return start.AddDate(0, 0, 1)
+	case TrendWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
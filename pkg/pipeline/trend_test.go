@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// trendItem returns an item with a sentiment and intent result at timestamp
+func trendItem(id string, timestamp interface{}, score float64, intent string) *data.ProcessItem {
+	item := data.NewTextProcessItem(id, "text", map[string]interface{}{"timestamp": timestamp})
+	item.AddProcessingInfo("sentiment", map[string]interface{}{"score": score})
+	item.AddProcessingInfo("intent", map[string]interface{}{"intents": []interface{}{map[string]interface{}{"label": intent}}})
+	return item
+}
+
+func TestTrends(t *testing.T) {
+	// 2024-01-01 is a Monday; the week of 2024-01-08 has no items
+	items := []*data.ProcessItem{
+		trendItem("1", "2024-01-01", 0.5, "billing"),
+		trendItem("2", "2024-01-03T10:00:00Z", -0.5, "billing"),
+		trendItem("3", time.Date(2024, 1, 7, 23, 0, 0, 0, time.UTC), 0.3, "cancel"),
+		trendItem("4", float64(time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC).Unix()), -0.8, "cancel"),
+		data.NewTextProcessItem("no-timestamp", "text", nil),
+	}
+	week := func(day int) time.Time { return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	counts, err := CountTrend("intent", "intents.label", TrendConfig{})(ctx, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCounts := []CountBucket{
+		{Start: week(1), Items: 3, Counts: map[string]int{"billing": 2, "cancel": 1}},
+		{Start: week(8), Items: 0, Counts: map[string]int{}},
+		{Start: week(15), Items: 1, Counts: map[string]int{"cancel": 1}},
+	}
+	gotCounts := counts.([]CountBucket)
+	if len(gotCounts) != len(wantCounts) {
+		t.Fatalf("expected %d buckets, got %+v", len(wantCounts), gotCounts)
+	}
+	for i, want := range wantCounts {
+		got := gotCounts[i]
+		if !got.Start.Equal(want.Start) || got.Items != want.Items || len(got.Counts) != len(want.Counts) {
+			t.Errorf("bucket %d: expected %+v, got %+v", i, want, got)
+			continue
+		}
+		for value, count := range want.Counts {
+			if got.Counts[value] != count {
+				t.Errorf("bucket %d: expected %d %s, got %d", i, count, value, got.Counts[value])
+			}
+		}
+	}
+
+	averages, err := AverageTrend("sentiment", "score", TrendConfig{Window: 2})(ctx, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAverages := []AverageBucket{
+		{Start: week(1), Items: 3, Average: 0.1, MovingAverage: 0.1},
+		{Start: week(8), Items: 0, Average: 0, MovingAverage: 0.1},
+		{Start: week(15), Items: 1, Average: -0.8, MovingAverage: -0.8},
+	}
+	gotAverages := averages.([]AverageBucket)
+	if len(gotAverages) != len(wantAverages) {
+		t.Fatalf("expected %d buckets, got %+v", len(wantAverages), gotAverages)
+	}
+	for i, want := range wantAverages {
+		got := gotAverages[i]
+		if !got.Start.Equal(want.Start) || got.Items != want.Items ||
+			!approxEqual(got.Average, want.Average) || !approxEqual(got.MovingAverage, want.MovingAverage) {
+			t.Errorf("bucket %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	// Daily and monthly buckets
+	daily, err := CountTrend("intent", "intents.label", TrendConfig{Interval: TrendDay})(ctx, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(daily.([]CountBucket)); n != 16 {
+		t.Errorf("expected 16 daily buckets, got %d", n)
+	}
+	monthly, err := CountTrend("intent", "intents.label", TrendConfig{Interval: TrendMonth})(ctx, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buckets := monthly.([]CountBucket); len(buckets) != 1 || buckets[0].Items != 4 {
+		t.Errorf("expected one monthly bucket of 4 items, got %+v", buckets)
+	}
+
+	// Invalid timestamps and intervals are errors
+	if _, err := CountTrend("intent", "intents.label", TrendConfig{})(ctx, []*data.ProcessItem{trendItem("bad", "yesterday", 0, "billing")}); err == nil {
+		t.Error("expected an error for an invalid timestamp")
+	}
+	if _, err := CountTrend("intent", "intents.label", TrendConfig{Interval: "hour"})(ctx, items); err == nil {
+		t.Error("expected an error for an unknown interval")
+	}
+}
+
+func TestLLMTrendNarrative(t *testing.T) {
+	provider := llm.NewMockProviderWithResponse("  Cancellations rose in the third week.  ")
+	narrative := LLMTrendNarrative(provider, "", map[string]AggregateFunc{
+		"intent_volume": CountTrend("intent", "intents.label", TrendConfig{}),
+	})
+
+	value, err := narrative(context.Background(), []*data.ProcessItem{trendItem("1", "2024-01-01", 0.5, "cancel")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := value.(TrendReport)
+	if report.Narrative != "Cancellations rose in the third week." {
+		t.Errorf("unexpected narrative %q", report.Narrative)
+	}
+	if _, ok := report.Series["intent_volume"].([]CountBucket); !ok {
+		t.Errorf("expected the intent_volume series in the report, got %+v", report.Series)
+	}
+	if prompts := provider.Prompts(); len(prompts) != 1 || !strings.Contains(prompts[0], `- intent_volume: [{"start":"2024-01-01T00:00:00Z"`) {
+		t.Errorf("expected the series in the prompt, got %v", prompts)
+	}
+}
+
+// approxEqual reports whether two floats are equal within rounding
+func approxEqual(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
@@ -3,9 +3,12 @@ package processor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/eisenzopf/agentic-text/pkg/data"
 	"github.com/eisenzopf/agentic-text/pkg/llm"
@@ -20,6 +23,23 @@ type BaseProcessor struct {
 	promptGenerator PromptGenerator
 	responseHandler ResponseHandler
 	options         Options
+	// redactor redacts debug output if the options configure redaction; redactErr is the
+	// error compiling its patterns, returned by Process
+	redactor  *Redactor
+	redactErr error
+	// structuredOutput is the structured output asked of the provider, if negotiated
+	structuredOutput llm.StructuredOutput
+	// routes are the models items are routed to, if the options configure routing
+	routes []routeClient
+	// calls holds a slot per LLM call in progress, if the options limit concurrency
+	calls chan struct{}
+	// version is the schema version of the processor's results, or 0 if unversioned
+	version int
+	// promptVersion and model identify the prompt and model in provenance hashes
+	promptVersion string
+	model         string
+	// compute computes results without an LLM, for processors created with NewComputedProcessor
+	compute ComputeFunc
 }
 
 // NewBaseProcessor creates a new base processor
@@ -31,7 +51,7 @@ func NewBaseProcessor(name string, contentTypes []string, llmClient llm.Client,
 		contentTypes = []string{"text"}
 	}
 
-	return &BaseProcessor{
+	p := &BaseProcessor{
 		name:            name,
 		contentTypes:    contentTypes,
 		llmClient:       llmClient,
@@ -40,6 +60,26 @@ func NewBaseProcessor(name string, contentTypes []string, llmClient llm.Client,
 		responseHandler: responseHandler,
 		options:         options,
 	}
+	if options.Redaction != nil {
+		p.redactor, p.redactErr = NewRedactor(*options.Redaction)
+	}
+	if options.MaxConcurrency > 0 {
+		p.calls = make(chan struct{}, options.MaxConcurrency)
+	}
+	return p
+}
+
+// complete makes an LLM call, waiting for a free slot first if the options limit concurrency
+func (p *BaseProcessor) complete(ctx context.Context, client llm.Client, prompt string) (interface{}, error) {
+	if p.calls != nil {
+		select {
+		case p.calls <- struct{}{}:
+			defer func() { <-p.calls }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return client.Complete(ctx, prompt, p.options.LLMOptions)
 }
 
 // GetName returns the processor name
@@ -52,8 +92,203 @@ func (p *BaseProcessor) GetSupportedContentTypes() []string {
 	return p.contentTypes
 }
 
-// Process processes a ProcessItem
+// Process processes a ProcessItem. If ctx carries a data.RunRecorder, the item's outcome,
+// latency and estimated token usage are recorded in it.
 func (p *BaseProcessor) Process(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	recorder := data.RunRecorderFromContext(ctx)
+	if recorder == nil {
+		return p.process(ctx, item, nil)
+	}
+
+	var usage callUsage
+	start := time.Now()
+	result, err := p.process(ctx, item, &usage)
+	recorder.Record(p.name, data.ItemOutcome{
+		ItemID:       item.ID,
+		Latency:      time.Since(start),
+		InputTokens:  usage.inputTokens,
+		OutputTokens: usage.outputTokens,
+		Err:          err,
+	})
+	return result, err
+}
+
+// callUsage is the estimated token usage of a processor's LLM call and the route it took
+type callUsage struct {
+	inputTokens  int64
+	outputTokens int64
+	route        string
+}
+
+// recordUsage records an LLM call's estimated tokens and cost in a result's processing
+// info, where pipeline budgets, stats and sinks read them
+func (p *BaseProcessor) recordUsage(result *data.ProcessItem, inputTokens, outputTokens int64) {
+	info, ok := result.ProcessingInfo[p.name].(map[string]interface{})
+	if !ok {
+		return
+	}
+	info["tokens"] = inputTokens + outputTokens
+	info["cost"] = llm.EstimateCost(inputTokens, outputTokens, p.options.InputTokenCost, p.options.OutputTokenCost)
+}
+
+// process processes a ProcessItem, recording the LLM call's usage in usage if it is set,
+// records the route the item took, the result's schema version and the call's usage in its
+// processing info, sets its provenance and redacts the result's debug output if the
+// options configure redaction. Results are answered from and stored in the options'
+// result cache, if set.
+func (p *BaseProcessor) process(ctx context.Context, item *data.ProcessItem, usage *callUsage) (*data.ProcessItem, error) {
+	if p.redactErr != nil {
+		return nil, p.redactErr
+	}
+	if usage == nil {
+		usage = &callUsage{}
+	}
+	provenance := p.Provenance(item)
+	if p.options.ResultCache != nil {
+		if result, ok := p.cachedResult(item, provenance); ok {
+			return result, nil
+		}
+	}
+	result, err := p.processItem(ctx, item, usage)
+	if err != nil {
+		return result, err
+	}
+	result.Provenance = provenance
+	if info, ok := result.ProcessingInfo[p.name].(map[string]interface{}); ok {
+		if usage.route != "" {
+			info["route"] = usage.route
+		}
+		if p.version > 0 {
+			info[VersionField] = p.version
+		}
+	}
+	if usage.inputTokens > 0 || usage.outputTokens > 0 {
+		p.recordUsage(result, usage.inputTokens, usage.outputTokens)
+	}
+	if p.redactor != nil {
+		p.redactDebug(result)
+	}
+	if p.options.ResultCache != nil {
+		p.cacheResult(result)
+	}
+	return result, nil
+}
+
+// redactDebug redacts the debug output stored in the result's content and ProcessingInfo
+func (p *BaseProcessor) redactDebug(result *data.ProcessItem) {
+	for _, value := range []interface{}{result.Content, result.ProcessingInfo[p.name]} {
+		if fields, ok := value.(map[string]interface{}); ok {
+			if debug, ok := fields["debug"]; ok {
+				fields["debug"] = p.redactor.RedactValue(debug)
+			}
+		}
+	}
+}
+
+// redactPrompt redacts a prompt for debug output if the options configure redaction
+func (p *BaseProcessor) redactPrompt(prompt string) string {
+	if p.redactor == nil {
+		return prompt
+	}
+	return p.redactor.Redact(prompt)
+}
+
+// redactResponse redacts a raw response for debug output if the options configure redaction
+func (p *BaseProcessor) redactResponse(response interface{}) interface{} {
+	if p.redactor == nil {
+		return response
+	}
+	return p.redactor.RedactValue(response)
+}
+
+// processItem processes a ProcessItem, recording the LLM call's usage in usage
+func (p *BaseProcessor) processItem(ctx context.Context, item *data.ProcessItem, usage *callUsage) (*data.ProcessItem, error) {
+	prepared, err := p.prepare(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	if p.compute != nil {
+		return p.computeItem(prepared)
+	}
+
+	// Add processing info with the proper processor type for non-LLM processing
+	if p.llmClient == nil {
+		prepared.result.AddProcessingInfo(p.name, map[string]interface{}{
+			"processor_type": p.name,
+		})
+		return p.finish(prepared), nil
+	}
+
+	// Generate prompt if needed, within the prompt budget if one is set
+	prompt := prepared.text
+	var trim *promptTrim
+	if p.promptGenerator != nil {
+		promptCtx := prepared.ctx
+		if p.options.PromptBudget != nil {
+			trim = &promptTrim{}
+			promptCtx = withPromptBudget(promptCtx, *p.options.PromptBudget, trim)
+		}
+		prompt, err = p.promptGenerator.GeneratePrompt(promptCtx, prepared.text)
+		if err != nil {
+			return nil, err
+		}
+	}
+	trimInfo := trim.info()
+	if trimInfo != nil && p.options.GetDebugEnabled() {
+		fmt.Printf("DEBUG - Prompt over budget: dropped sections %v, truncated input %t\n", trimInfo["dropped_sections"], trimInfo["truncated_input"])
+	}
+
+	// Print debug information if enabled
+	if p.options.GetDebugEnabled() {
+		DebugLLMInteraction(p.redactPrompt(prompt), "") // Print the prompt before calling LLM
+	}
+
+	// Route the item to one of several models if routing is configured
+	client := p.llmClient
+	if len(p.routes) > 0 {
+		route, err := p.route(prepared.ctx, prepared.text)
+		if err != nil {
+			return nil, err
+		}
+		client = route.client
+		usage.route = route.Name
+	}
+
+	// Call LLM, naming the processor and item for interaction logs
+	llmResponse, err := p.complete(p.callContext(prepared.ctx, item.ID), client, prompt)
+	usage.inputTokens = llm.EstimateTokens(prompt)
+	usage.outputTokens = estimateResponseTokens(llmResponse)
+	if errors.Is(err, llm.ErrContextLength) {
+		return p.processChunked(prepared, client, usage, err)
+	}
+	if errors.Is(err, llm.ErrBudgetExceeded) {
+		return nil, data.Permanent(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.handleResponse(prepared, prompt, llmResponse)
+	if err == nil && trimInfo != nil {
+		if info, ok := result.ProcessingInfo[p.name].(map[string]interface{}); ok {
+			info["prompt_trim"] = trimInfo
+		}
+	}
+	return result, err
+}
+
+// preparedItem is an item ready for its LLM call
+type preparedItem struct {
+	// ctx is the context of the item's calls, carrying its state
+	ctx context.Context
+	// result is the clone of the item that becomes the result
+	result *data.ProcessItem
+	// text is the text to process, after pre-processing
+	text string
+}
+
+// prepare checks an item's content type, clones it and extracts and pre-processes its text
+func (p *BaseProcessor) prepare(ctx context.Context, item *data.ProcessItem) (preparedItem, error) {
 	// Validate content type
 	contentTypeSupported := false
 	for _, ct := range p.contentTypes {
@@ -64,13 +299,13 @@ func (p *BaseProcessor) Process(ctx context.Context, item *data.ProcessItem) (*d
 	}
 
 	if !contentTypeSupported {
-		return nil, fmt.Errorf("unsupported content type: %s", item.ContentType)
+		return preparedItem{}, data.Permanent(fmt.Errorf("unsupported content type: %s", item.ContentType))
 	}
 
 	// Clone the item to avoid modifying the original
 	result, err := item.Clone()
 	if err != nil {
-		return nil, err
+		return preparedItem{}, err
 	}
 
 	// Get text content based on the content type
@@ -80,16 +315,18 @@ func (p *BaseProcessor) Process(ctx context.Context, item *data.ProcessItem) (*d
 		// Get text content directly
 		textContent, err = item.GetTextContent()
 		if err != nil {
-			return nil, err
+			return preparedItem{}, err
 		}
 	} else if item.ContentType == "json" {
 		// For JSON content, either:
 		// 1. Use "text" field if available in the JSON
 		// 2. Use "response" field if available
 		// 3. Or convert the entire JSON to text as fallback
-		jsonContent, ok := item.Content.(map[string]interface{})
+		// The clone's content is used because a previous processor may have left its
+		// result struct in the item's content, which the clone has decoded into a map
+		jsonContent, ok := result.Content.(map[string]interface{})
 		if !ok {
-			return nil, fmt.Errorf("invalid JSON content format")
+			return preparedItem{}, fmt.Errorf("invalid JSON content format")
 		}
 
 		// Try to extract text from the JSON
@@ -101,10 +338,16 @@ func (p *BaseProcessor) Process(ctx context.Context, item *data.ProcessItem) (*d
 			// Try to get original text from metadata if available
 			textContent = originalText
 		} else {
-			// Use the first text field we can find
+			// Use the first text field we can find, in key order so the prompt is the
+			// same on every run
 			foundText := false
-			for _, value := range jsonContent {
-				if text, ok := value.(string); ok {
+			keys := make([]string, 0, len(jsonContent))
+			for key := range jsonContent {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if text, ok := jsonContent[key].(string); ok {
 					textContent = text
 					foundText = true
 					break
@@ -115,185 +358,179 @@ func (p *BaseProcessor) Process(ctx context.Context, item *data.ProcessItem) (*d
 			if !foundText {
 				jsonBytes, err := json.Marshal(jsonContent)
 				if err != nil {
-					return nil, fmt.Errorf("failed to convert JSON to text: %w", err)
+					return preparedItem{}, fmt.Errorf("failed to convert JSON to text: %w", err)
 				}
 				textContent = string(jsonBytes)
 			}
 		}
+	} else {
+		// Rich content types (html, markdown, pdf_text, transcript) are rendered as plain text
+		textContent, err = item.GetTextForProcessing()
+		if err != nil {
+			return preparedItem{}, err
+		}
 	}
 
-	// Run LLM processing if available
-	if p.llmClient != nil {
-		// Check if debug is enabled in options
-		debugEnabled := false
-		if p.options.LLMOptions != nil {
-			if debug, ok := p.options.LLMOptions["debug"].(bool); ok {
-				debugEnabled = debug
-			}
-		}
+	// Make the item's state and structured inputs available to prompt generators
+	if state := item.State(); state != nil {
+		ctx = data.WithItemState(ctx, state)
+	}
+	ctx = withPromptInputs(ctx, result, p.options.Inputs)
 
-		// Pre-process if needed
-		if p.preProcessor != nil {
-			textContent, err = p.preProcessor.PreProcess(ctx, textContent)
-			if err != nil {
-				return nil, err
-			}
+	// Pre-process if needed
+	if p.llmClient != nil && p.preProcessor != nil {
+		textContent, err = p.preProcessor.PreProcess(ctx, textContent)
+		if err != nil {
+			return preparedItem{}, err
 		}
+	}
 
-		// Generate prompt if needed
-		prompt := textContent
-		if p.promptGenerator != nil {
-			prompt, err = p.promptGenerator.GeneratePrompt(ctx, textContent)
-			if err != nil {
-				return nil, err
-			}
-		}
+	return preparedItem{ctx: ctx, result: result, text: textContent}, nil
+}
+
+// callContext returns the context of an LLM call, naming the processor and item for
+// interaction logs and asking for native structured output if it was negotiated
+func (p *BaseProcessor) callContext(ctx context.Context, itemID string) context.Context {
+	ctx = llm.WithInteractionSource(ctx, p.name, itemID)
+	if p.structuredOutput.Mode != "" && p.structuredOutput.Mode != llm.StructuredOutputPrompt {
+		ctx = llm.WithStructuredOutput(ctx, p.structuredOutput)
+	}
+	return ctx
+}
+
+// estimateResponseTokens estimates the tokens of an LLM response
+func estimateResponseTokens(response interface{}) int64 {
+	if text, ok := response.(string); ok {
+		return llm.EstimateTokens(text)
+	}
+	if encoded, err := json.Marshal(response); err == nil && response != nil {
+		return llm.EstimateTokens(string(encoded))
+	}
+	return 0
+}
+
+// handleResponse turns the LLM's response to a prepared item's prompt into its result
+func (p *BaseProcessor) handleResponse(prepared preparedItem, prompt string, llmResponse interface{}) (*data.ProcessItem, error) {
+	ctx, result, textContent := prepared.ctx, prepared.result, prepared.text
+	// Computed results have no prompt or raw response to debug
+	debugEnabled := p.options.GetDebugEnabled() && p.compute == nil
+
+	// Print debug information if enabled
+	if debugEnabled {
+		DebugLLMInteraction(p.redactPrompt(prompt), p.redactResponse(llmResponse)) // Print full interaction
+	}
 
-		// Print debug information if enabled
-		if debugEnabled {
-			DebugLLMInteraction(prompt, "") // Print the prompt before calling LLM
+	// Store debug info in a map if debug is enabled
+	var debugInfo map[string]interface{}
+	if debugEnabled {
+		debugInfo = map[string]interface{}{
+			"prompt":       prompt,
+			"raw_response": llmResponse,
 		}
+	}
 
-		// Call LLM
-		llmResponse, err := p.llmClient.Complete(ctx, prompt, p.options.LLMOptions)
+	// Handle response
+	if p.responseHandler != nil {
+		processedContent, err := p.responseHandler.HandleResponse(ctx, textContent, llmResponse)
 		if err != nil {
 			return nil, err
 		}
 
-		// Print debug information if enabled
-		if debugEnabled {
-			DebugLLMInteraction(prompt, llmResponse) // Print full interaction
-		}
-
-		// Store debug info in a map if debug is enabled
-		var debugInfo map[string]interface{}
-		if debugEnabled {
-			debugInfo = map[string]interface{}{
-				"prompt":       prompt,
-				"raw_response": llmResponse,
+		// Add debug info to processed content if available
+		if debugEnabled && debugInfo != nil {
+			// If the result is a map, add debug info directly
+			if contentMap, ok := processedContent.(map[string]interface{}); ok {
+				contentMap["debug"] = debugInfo
+				processedContent = contentMap
+			} else {
+				// For struct responses, we'll handle debug in a different way below
 			}
 		}
 
-		// Handle response
-		if p.responseHandler != nil {
-			processedContent, err := p.responseHandler.HandleResponse(ctx, textContent, llmResponse)
-			if err != nil {
-				return nil, err
-			}
+		// Update the content with the processed result
+		result.Content = processedContent
 
-			// Add debug info to processed content if available
-			if debugEnabled && debugInfo != nil {
-				// If the result is a map, add debug info directly
-				if contentMap, ok := processedContent.(map[string]interface{}); ok {
-					contentMap["debug"] = debugInfo
-					processedContent = contentMap
-				} else {
-					// For struct responses, we'll handle debug in a different way below
-				}
-			}
-
-			// Update the content with the processed result
-			result.Content = processedContent
+		// If content is a string, keep content type as text
+		// otherwise change to the appropriate type
+		if _, ok := processedContent.(string); !ok {
+			result.ContentType = "json"
+		} else {
+			result.ContentType = "text"
+		}
 
-			// If content is a string, keep content type as text
-			// otherwise change to the appropriate type
-			if _, ok := processedContent.(string); !ok {
-				result.ContentType = "json"
-			} else {
-				result.ContentType = "text"
+		// Add processing info, checking if processor_type already exists in the response
+		if contentMap, ok := processedContent.(map[string]interface{}); ok && contentMap["processor_type"] != nil {
+			// Use the processor_type from the response, in a copy so the usage recorded in
+			// the processing info doesn't leak into the content
+			info := make(map[string]interface{}, len(contentMap))
+			for key, value := range contentMap {
+				info[key] = value
 			}
-
-			// Add processing info, checking if processor_type already exists in the response
-			if contentMap, ok := processedContent.(map[string]interface{}); ok && contentMap["processor_type"] != nil {
-				// Use the processor_type from the response
-				result.AddProcessingInfo(p.name, processedContent)
-			} else {
-				// For struct responses, convert to map first
-				// This handles cases like SentimentResult, IntentResult, etc.
-				if reflect.TypeOf(processedContent) != nil && reflect.TypeOf(processedContent).Kind() == reflect.Ptr {
-					// Use reflection to convert struct to map
-					val := reflect.ValueOf(processedContent).Elem()
-					if val.Kind() == reflect.Struct {
-						structMap := make(map[string]interface{})
-						structType := val.Type()
-
-						// First see if struct has a ProcessorType field
-						var hasProcessorType bool
-						var processorTypeValue string
-
-						// Check each field in the struct
-						for i := 0; i < val.NumField(); i++ {
-							field := structType.Field(i)
-
-							// Get the field's JSON tag
-							tag := field.Tag.Get("json")
-							if tag == "" {
-								tag = strings.ToLower(field.Name)
-							} else {
-								tag = strings.Split(tag, ",")[0]
-							}
-
-							// Skip if the tag is "-" (meaning don't include in JSON)
-							if tag == "-" {
-								continue
-							}
-
-							// Get the field value
-							fieldValue := val.Field(i).Interface()
-							structMap[tag] = fieldValue
-
-							// Check if this is the processor_type field
-							if tag == "processor_type" {
-								hasProcessorType = true
-								if strValue, ok := fieldValue.(string); ok {
-									processorTypeValue = strValue
-								}
-							}
+			result.AddProcessingInfo(p.name, info)
+		} else {
+			// For struct responses, convert to map first
+			// This handles cases like SentimentResult, IntentResult, etc.
+			if reflect.TypeOf(processedContent) != nil && reflect.TypeOf(processedContent).Kind() == reflect.Ptr {
+				// Use reflection to convert struct to map
+				val := reflect.ValueOf(processedContent).Elem()
+				if val.Kind() == reflect.Struct {
+					structMap := make(map[string]interface{})
+					structType := val.Type()
+
+					// First see if struct has a ProcessorType field
+					var hasProcessorType bool
+					var processorTypeValue string
+
+					// Check each field in the struct
+					for i := 0; i < val.NumField(); i++ {
+						field := structType.Field(i)
+
+						// Get the field's JSON tag
+						tag := field.Tag.Get("json")
+						if tag == "" {
+							tag = strings.ToLower(field.Name)
+						} else {
+							tag = strings.Split(tag, ",")[0]
 						}
 
-						// Add debug info to the struct map if enabled
-						if debugEnabled && debugInfo != nil {
-							structMap["debug"] = debugInfo
+						// Skip if the tag is "-" (meaning don't include in JSON)
+						if tag == "-" {
+							continue
 						}
 
-						// If the struct has a processor_type, use it
-						if hasProcessorType && processorTypeValue != "" {
-							result.AddProcessingInfo(p.name, structMap)
-							result.Content = processedContent // Keep the original content
-						} else {
-							// Add the processor type to the map
-							structMap["processor_type"] = p.name
-							result.AddProcessingInfo(p.name, structMap)
+						// Get the field value
+						fieldValue := val.Field(i).Interface()
+						structMap[tag] = fieldValue
+
+						// Check if this is the processor_type field
+						if tag == "processor_type" {
+							hasProcessorType = true
+							if strValue, ok := fieldValue.(string); ok {
+								processorTypeValue = strValue
+							}
 						}
+					}
 
-						return result, nil
+					// Add debug info to the struct map if enabled
+					if debugEnabled && debugInfo != nil {
+						structMap["debug"] = debugInfo
 					}
-				}
 
-				// If not a struct or conversion failed, use the default processor_type
-				processingInfo := map[string]interface{}{
-					"processor_type": p.name,
-				}
+					// If the struct has a processor_type, use it
+					if hasProcessorType && processorTypeValue != "" {
+						result.AddProcessingInfo(p.name, structMap)
+						result.Content = processedContent // Keep the original content
+					} else {
+						// Add the processor type to the map
+						structMap["processor_type"] = p.name
+						result.AddProcessingInfo(p.name, structMap)
+					}
 
-				// Add debug info if enabled
-				if debugEnabled && debugInfo != nil {
-					processingInfo["debug"] = debugInfo
+					return p.finish(prepared), nil
 				}
-
-				result.AddProcessingInfo(p.name, processingInfo)
-			}
-		} else {
-			// Default behavior: replace content with LLM response
-			result.Content = llmResponse
-
-			// If response is a string, assume it's text
-			if _, ok := llmResponse.(string); ok {
-				result.ContentType = "text"
-			} else {
-				result.ContentType = "json"
 			}
 
-			// Add processing info with the proper processor type for non-LLM processing
+			// If not a struct or conversion failed, use the default processor_type
 			processingInfo := map[string]interface{}{
 				"processor_type": p.name,
 			}
@@ -306,29 +543,57 @@ func (p *BaseProcessor) Process(ctx context.Context, item *data.ProcessItem) (*d
 			result.AddProcessingInfo(p.name, processingInfo)
 		}
 	} else {
+		// Default behavior: replace content with LLM response
+		result.Content = llmResponse
+
+		// If response is a string, assume it's text
+		if _, ok := llmResponse.(string); ok {
+			result.ContentType = "text"
+		} else {
+			result.ContentType = "json"
+		}
+
 		// Add processing info with the proper processor type for non-LLM processing
-		result.AddProcessingInfo(p.name, map[string]interface{}{
+		processingInfo := map[string]interface{}{
 			"processor_type": p.name,
-		})
+		}
+
+		// Add debug info if enabled
+		if debugEnabled && debugInfo != nil {
+			processingInfo["debug"] = debugInfo
+		}
+
+		result.AddProcessingInfo(p.name, processingInfo)
 	}
 
-	// Store original text in metadata if not already present
+	return p.finish(prepared), nil
+}
+
+// finish stores the item's original text in the result's metadata if not already present
+func (p *BaseProcessor) finish(prepared preparedItem) *data.ProcessItem {
+	result := prepared.result
 	if _, exists := result.Metadata["original_text"]; !exists {
 		if result.Metadata == nil {
 			result.Metadata = make(map[string]interface{})
 		}
-		result.Metadata["original_text"] = textContent
+		result.Metadata["original_text"] = prepared.text
 	}
-
-	return result, nil
+	return result
 }
 
-// ProcessBatch processes a batch of items
+// ProcessBatch processes a batch of items. With packing, items are processed one pack at
+// a time and items that can't be packed use the configured retry policy.
 func (p *BaseProcessor) ProcessBatch(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	if p.options.Packing != nil {
+		return p.processSourcePacked(ctx, data.NewProcessItemSliceSource(items), 1)
+	}
+
 	results := make([]*data.ProcessItem, len(items))
+	tracker := data.NewProgressTracker(ctx, len(items))
 
 	for i, item := range items {
 		result, err := p.Process(ctx, item)
+		tracker.Record(err)
 		if err != nil {
 			return nil, err
 		}
@@ -338,10 +603,77 @@ func (p *BaseProcessor) ProcessBatch(ctx context.Context, items []*data.ProcessI
 	return results, nil
 }
 
-// ProcessSource processes all items from a source
+// ProcessSource processes all items from a source. With packing, workers process whole
+// packs and batchSize is unused.
 func (p *BaseProcessor) ProcessSource(ctx context.Context, source data.ProcessItemSource, batchSize, workers int) ([]*data.ProcessItem, error) {
-	processor := data.NewProcessItemParallelProcessor(source, batchSize, workers)
+	if p.options.Packing != nil {
+		return p.processSourcePacked(ctx, source, workers)
+	}
+
+	processor := p.parallelProcessor(source, batchSize, workers)
 	defer processor.Close()
 
-	return processor.ProcessAll(ctx, p.Process)
+	return processor.ProcessAll(ctx, p.processWithRetry)
+}
+
+// parallelProcessor creates a parallel processor for a source with the options' priority
+// and result order
+func (p *BaseProcessor) parallelProcessor(source data.ProcessItemSource, batchSize, workers int) *data.ProcessItemParallelProcessor {
+	return data.NewProcessItemParallelProcessorWithConfig(source, data.ParallelConfig{
+		BatchSize:  batchSize,
+		MaxWorkers: workers,
+		Priority:   p.options.Priority,
+		Order:      p.options.ResultOrder,
+	})
+}
+
+// ProcessSourceWithReport processes all items from a source like ProcessSource and returns
+// a report of the run. The report is returned even if the run fails, covering the items
+// processed until then.
+func (p *BaseProcessor) ProcessSourceWithReport(ctx context.Context, source data.ProcessItemSource, batchSize, workers int, config data.ReportConfig) ([]*data.ProcessItem, *data.RunReport, error) {
+	recorder := data.NewRunRecorder(p.name, config)
+	results, err := p.ProcessSource(data.WithRunRecorder(ctx, recorder), source, batchSize, workers)
+	return results, recorder.Report(len(results), err), err
+}
+
+// processWithRetry processes an item, retrying according to the configured retry policy.
+// On success the number of attempts is recorded in the item's processing info.
+func (p *BaseProcessor) processWithRetry(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+	if p.options.Retry == nil {
+		return p.Process(ctx, item)
+	}
+
+	var result *data.ProcessItem
+	attempts, err := p.options.Retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = p.Process(ctx, item)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("item '%s' failed after %d attempt(s): %w", item.ID, attempts, err)
+	}
+
+	if info, ok := result.ProcessingInfo[p.name].(map[string]interface{}); ok {
+		info["attempts"] = attempts
+	}
+
+	return result, nil
+}
+
+// ProcessSourceStream processes items from a source as they arrive and streams the
+// results over a channel instead of collecting them into a slice
+func (p *BaseProcessor) ProcessSourceStream(ctx context.Context, source data.ProcessItemSource, workers int) <-chan data.ProcessResult {
+	return p.parallelProcessor(source, 0, workers).ProcessStream(ctx, p.processWithRetry)
+}
+
+// ProcessSourceToSink processes all items from a source and writes the results to a sink
+// in batches of batchSize as they are produced. Processing stops at the first error; if ctx
+// is cancelled, the results so far are written and ctx.Err() is returned. The sink is
+// flushed but not closed.
+func (p *BaseProcessor) ProcessSourceToSink(ctx context.Context, source data.ProcessItemSource, sink data.ProcessItemSink, batchSize, workers int) error {
+	// Cancel outstanding work if we stop early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return data.WriteStream(ctx, p.ProcessSourceStream(ctx, source, workers), sink, batchSize)
 }
@@ -2,8 +2,12 @@ package processor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
 )
 
 // ProcessorBuilder provides a fluent interface for creating processors
@@ -14,10 +18,29 @@ type ProcessorBuilder struct {
 	role            string
 	objective       string
 	instructions    []string
-	customSections  map[string]string
+	customSections  []promptSection
+	examples        []PromptExample
+	stateKeys       []string
+	inputs          []promptInput
+	localizations   map[string]PromptLocalization
 	customPromptGen PromptGenerator
 	customInit      func(*GenericProcessor) error
 	validateStruct  bool
+	resultValidator ResultValidator
+	llmOptions      map[string]interface{}
+	// transforms post-process mapped results, in the order they were added
+	transforms []ResultTransform
+	// preferredModels are the models the processor works best with, in order of preference
+	preferredModels []string
+	// notApplicable is the prompt section allowing a not applicable result, if allowed
+	notApplicable string
+	// sanitize is the default sanitization of results, if any
+	sanitize *SanitizeConfig
+	// version is the schema version of results and migrations upgrade older results to it
+	version    int
+	migrations map[int]Migration
+	// promptVersion identifies the prompt in provenance hashes, if set
+	promptVersion string
 }
 
 // NewBuilder creates a new processor builder
@@ -25,8 +48,7 @@ func NewBuilder(name string) *ProcessorBuilder {
 	return &ProcessorBuilder{
 		name:           name,
 		contentTypes:   []string{"text"}, // sensible default
-		customSections: make(map[string]string),
-		validateStruct: false, // sensible default
+		validateStruct: false,            // sensible default
 	}
 }
 
@@ -36,6 +58,13 @@ func (b *ProcessorBuilder) WithStruct(resultStruct interface{}) *ProcessorBuilde
 	return b
 }
 
+// WithSchema sets a JSON Schema as the result structure, in place of a struct; results are
+// then maps conforming to it
+func (b *ProcessorBuilder) WithSchema(schema *Schema) *ProcessorBuilder {
+	b.resultStruct = schema
+	return b
+}
+
 // WithContentTypes sets supported content types
 func (b *ProcessorBuilder) WithContentTypes(types ...string) *ProcessorBuilder {
 	b.contentTypes = types
@@ -60,9 +89,62 @@ func (b *ProcessorBuilder) WithInstructions(instructions ...string) *ProcessorBu
 	return b
 }
 
-// WithCustomSection adds a custom section to the prompt
+// WithCustomSection adds a custom section to the prompt. Sections appear in the order they
+// are added; adding a section again replaces its content in place.
 func (b *ProcessorBuilder) WithCustomSection(name, content string) *ProcessorBuilder {
-	b.customSections[name] = content
+	for i, section := range b.customSections {
+		if section.name == name {
+			b.customSections[i].content = content
+			return b
+		}
+	}
+	b.customSections = append(b.customSections, promptSection{name: name, content: content})
+	return b
+}
+
+// WithStandardSection adds a shared section of the standard prompt section library, such
+// as SectionNoHallucination, in the order sections are added. Its content is looked up when
+// prompts are generated, so RegisterStandardSection updates every processor using it.
+// Builder prompts always end with SectionJSONOnly.
+func (b *ProcessorBuilder) WithStandardSection(name string) *ProcessorBuilder {
+	for _, section := range b.customSections {
+		if section.standard == name {
+			return b
+		}
+	}
+	b.customSections = append(b.customSections, promptSection{standard: name})
+	return b
+}
+
+// WithExamples adds few-shot examples of input texts and their results to the prompt
+func (b *ProcessorBuilder) WithExamples(examples ...PromptExample) *ProcessorBuilder {
+	b.examples = examples
+	return b
+}
+
+// WithLocalization adds a variant of the prompt for inputs in a language, such as "es" or
+// "pt-BR", with instructions and examples in that language. The variant is selected by the
+// item's language, see PromptLanguage; a regional tag falls back to its language's variant,
+// and a language without one to the default prompt.
+func (b *ProcessorBuilder) WithLocalization(language string, localization PromptLocalization) *ProcessorBuilder {
+	if b.localizations == nil {
+		b.localizations = make(map[string]PromptLocalization)
+	}
+	b.localizations[normalizeLanguage(language)] = localization
+	return b
+}
+
+// WithStateKeys includes the listed values from the item's state (set by earlier
+// pipeline steps) in a Context section of the prompt, when present
+func (b *ProcessorBuilder) WithStateKeys(keys ...string) *ProcessorBuilder {
+	b.stateKeys = keys
+	return b
+}
+
+// WithInput includes the item's structured input key, see PromptInput, in a prompt section
+// named title, when present. format renders the value; nil uses FormatInput.
+func (b *ProcessorBuilder) WithInput(key, title string, format InputFormatter) *ProcessorBuilder {
+	b.inputs = append(b.inputs, promptInput{key: key, title: title, format: format})
 	return b
 }
 
@@ -84,35 +166,250 @@ func (b *ProcessorBuilder) WithValidation() *ProcessorBuilder {
 	return b
 }
 
+// WithResultValidator checks each mapped result before it is returned, e.g. to enforce
+// constraints that depend on the item's structured inputs
+func (b *ProcessorBuilder) WithResultValidator(validate ResultValidator) *ProcessorBuilder {
+	b.resultValidator = validate
+	return b
+}
+
+// WithResultTransform post-processes each mapped result, after sanitization and before the
+// result validator, e.g. to normalize label casing or compute derived fields. Transforms run
+// in the order they are added. Use Transform for a function of the result struct:
+//
+//	WithResultTransform(processor.Transform(func(r *MatchResult) (*MatchResult, error) { ... }))
+func (b *ProcessorBuilder) WithResultTransform(transform ResultTransform) *ProcessorBuilder {
+	b.transforms = append(b.transforms, transform)
+	return b
+}
+
+// WithNotApplicable lets the processor abstain with a not applicable result, holding
+// not_applicable: true and a reason, when condition holds, e.g. "the text makes no
+// purchase decision", rather than forcing default values into its result. An empty
+// condition abstains when the text doesn't contain what the processor analyzes. See
+// NotApplicable; custom prompts must ask for such results themselves.
+func (b *ProcessorBuilder) WithNotApplicable(condition string) *ProcessorBuilder {
+	b.notApplicable = notApplicableInstruction(condition)
+	return b
+}
+
+// WithSanitize sanitizes the processor's results after they are mapped, unless the options
+// the processor is created with set their own sanitization. See SanitizeConfig.
+func (b *ProcessorBuilder) WithSanitize(config SanitizeConfig) *ProcessorBuilder {
+	b.sanitize = &config
+	return b
+}
+
+// WithVersion sets the schema version of the processor's results, recorded under
+// VersionField in each result's processing info. Bump it when the result struct changes,
+// adding a migration for results stored by the previous version.
+func (b *ProcessorBuilder) WithVersion(version int) *ProcessorBuilder {
+	b.version = version
+	return b
+}
+
+// WithPromptVersion identifies the processor's prompt in the provenance hashes of its
+// results, in place of a hash of the prompt itself, so changes that don't affect results,
+// such as rewording, keep cached results and checkpoints valid
+func (b *ProcessorBuilder) WithPromptVersion(version string) *ProcessorBuilder {
+	b.promptVersion = version
+	return b
+}
+
+// WithMigration adds the migration upgrading stored results from schema version from to
+// the next, applied by Migrate and MigrateItem once the processor is registered
+func (b *ProcessorBuilder) WithMigration(from int, migration Migration) *ProcessorBuilder {
+	if b.migrations == nil {
+		b.migrations = make(map[int]Migration)
+	}
+	b.migrations[from] = migration
+	return b
+}
+
+// WithMaxOutputTokens limits the length of the processor's responses, so a verbose processor
+// can't exhaust a token budget. It sets the "max_output_tokens" LLM option unless the
+// options the processor is created with set it.
+func (b *ProcessorBuilder) WithMaxOutputTokens(tokens int) *ProcessorBuilder {
+	return b.withLLMOption("max_output_tokens", tokens)
+}
+
+// WithStopSequences ends the processor's responses at any of the sequences. It sets the
+// "stop_sequences" LLM option unless the options the processor is created with set it.
+func (b *ProcessorBuilder) WithStopSequences(sequences ...string) *ProcessorBuilder {
+	return b.withLLMOption("stop_sequences", sequences)
+}
+
+// WithTemperature sets the sampling temperature the processor works best with, e.g. 0 for
+// consistent classifications. It sets the "temperature" LLM option unless the options the
+// processor is created with set it.
+func (b *ProcessorBuilder) WithTemperature(temperature float64) *ProcessorBuilder {
+	return b.withLLMOption("temperature", temperature)
+}
+
+// WithPreferredModels names the models the processor works best with, in order of
+// preference, e.g. "gemini-2.0-flash", "gpt-4o-mini". The processor uses the first one its
+// provider serves (see llm.ServesModel), unless the provider's config chose a model other
+// than its type's default or the options configure routing.
+func (b *ProcessorBuilder) WithPreferredModels(models ...string) *ProcessorBuilder {
+	b.preferredModels = models
+	return b
+}
+
+// withLLMOption sets a default LLM option of the processor
+func (b *ProcessorBuilder) withLLMOption(key string, value interface{}) *ProcessorBuilder {
+	if b.llmOptions == nil {
+		b.llmOptions = make(map[string]interface{})
+	}
+	b.llmOptions[key] = value
+	return b
+}
+
 // Register creates and registers the processor
 func (b *ProcessorBuilder) Register() {
 	if b.resultStruct == nil {
 		panic(fmt.Sprintf("processor %s: result struct is required", b.name))
 	}
+	if err := b.checkSections(); err != nil {
+		panic(err.Error())
+	}
 
-	var promptGen PromptGenerator
-	if b.customPromptGen != nil {
-		// Use custom prompt generator
-		promptGen = b.customPromptGen
-	} else {
-		// Create auto-generated prompt generator
-		promptGen = &BuilderPromptGenerator{
-			resultStruct:   b.resultStruct,
-			role:           b.role,
-			objective:      b.objective,
-			instructions:   b.instructions,
-			customSections: b.customSections,
+	registerDescription(Description{
+		Name:            b.name,
+		ContentTypes:    b.contentTypes,
+		ResultSchema:    JSONSchema(b.resultStruct),
+		Version:         b.version,
+		Temperature:     b.temperature(),
+		PreferredModels: b.preferredModels,
+	})
+	if b.version > 0 {
+		RegisterVersion(b.name, b.version, b.migrations)
+	}
+	Register(b.name, b.factory())
+}
+
+// Build creates the processor without registering it, e.g. to try a variant of a
+// registered processor's prompt
+func (b *ProcessorBuilder) Build(provider llm.Provider, options Options) (Processor, error) {
+	if b.resultStruct == nil {
+		return nil, fmt.Errorf("processor %s: result struct is required", b.name)
+	}
+	if err := b.checkSections(); err != nil {
+		return nil, err
+	}
+
+	return b.factory()(provider, options)
+}
+
+// checkSections checks that the standard sections the builder references exist
+func (b *ProcessorBuilder) checkSections() error {
+	for _, section := range b.customSections {
+		if section.standard == "" {
+			continue
+		}
+		if _, err := standardSectionText(section.standard); err != nil {
+			return fmt.Errorf("processor %s: %w", b.name, err)
+		}
+	}
+	return nil
+}
+
+// factory returns the factory of the builder's processor
+func (b *ProcessorBuilder) factory() FactoryFunc {
+	factory := newGenericFactory(b.name, b.contentTypes, b.resultStruct, b.promptGenerator(), b.customInit, b.validateStruct, b.transforms, b.resultValidator)
+	if len(b.llmOptions) == 0 && b.notApplicable == "" && b.sanitize == nil && b.version == 0 && b.promptVersion == "" && len(b.preferredModels) == 0 {
+		return factory
+	}
+
+	defaults, notApplicable, sanitize := b.llmOptions, b.notApplicable != "", b.sanitize
+	version, promptVersion, preferredModels := b.version, b.promptVersion, b.preferredModels
+	return func(provider llm.Provider, options Options) (Processor, error) {
+		for key, value := range defaults {
+			if _, ok := options.LLMOptions[key]; !ok {
+				options = options.WithLLMOption(key, value)
+			}
+		}
+		if len(preferredModels) > 0 && options.Routing == nil {
+			var err error
+			if provider, err = preferredProvider(provider, preferredModels); err != nil {
+				return nil, err
+			}
+		}
+		if sanitize != nil && options.Sanitize == nil {
+			options = options.WithSanitize(*sanitize)
 		}
+		p, err := factory(provider, options)
+		if err != nil {
+			return nil, err
+		}
+		if generic, ok := p.(*GenericProcessor); ok {
+			generic.version = version
+			if promptVersion != "" {
+				generic.promptVersion = promptVersion
+			}
+			if notApplicable {
+				allowNotApplicable(generic)
+			}
+		}
+		return p, nil
+	}
+}
+
+// temperature returns the temperature set with WithTemperature, if any
+func (b *ProcessorBuilder) temperature() *float64 {
+	if temperature, ok := b.llmOptions["temperature"].(float64); ok {
+		return &temperature
+	}
+	return nil
+}
+
+// preferredProvider returns the provider switched to the first of the preferred models it
+// serves, unless its config chose a model other than its type's default
+func preferredProvider(provider llm.Provider, models []string) (llm.Provider, error) {
+	if provider == nil {
+		return nil, nil
 	}
+	providerType, model := provider.GetType(), provider.GetConfig().Model
+	if model != "" && model != llm.DefaultModel(providerType) {
+		return provider, nil
+	}
+	for _, preferred := range models {
+		if llm.ServesModel(providerType, preferred) {
+			switched, err := llm.WithModel(provider, preferred)
+			if err != nil {
+				return nil, fmt.Errorf("failed to switch to preferred model %s: %w", preferred, err)
+			}
+			return switched, nil
+		}
+	}
+	return provider, nil
+}
 
-	RegisterGenericProcessor(
-		b.name,
-		b.contentTypes,
-		b.resultStruct,
-		promptGen,
-		b.customInit,
-		b.validateStruct,
-	)
+// promptGenerator returns the custom prompt generator, or else one generating the prompt
+// from the builder's sections
+func (b *ProcessorBuilder) promptGenerator() PromptGenerator {
+	if b.customPromptGen != nil {
+		return b.customPromptGen
+	}
+	return &BuilderPromptGenerator{
+		resultStruct:   b.resultStruct,
+		role:           b.role,
+		objective:      b.objective,
+		instructions:   b.instructions,
+		customSections: b.customSections,
+		examples:       b.examples,
+		stateKeys:      b.stateKeys,
+		inputs:         b.inputs,
+		localizations:  b.localizations,
+		notApplicable:  b.notApplicable,
+	}
+}
+
+// promptSection is a custom section of a builder prompt, or a reference to a standard one
+type promptSection struct {
+	name    string
+	content string
+	// standard is the name of the standard section, if the section references one
+	standard string
 }
 
 // BuilderPromptGenerator generates prompts based on builder configuration
@@ -121,11 +418,37 @@ type BuilderPromptGenerator struct {
 	role           string
 	objective      string
 	instructions   []string
-	customSections map[string]string
+	customSections []promptSection
+	examples       []PromptExample
+	stateKeys      []string
+	inputs         []promptInput
+	localizations  map[string]PromptLocalization
+	notApplicable  string
 }
 
-// GeneratePrompt implements PromptGenerator interface
+// GeneratePrompt implements PromptGenerator interface. Under a PromptBudget (see
+// Options.WithPromptBudget), a prompt over budget is tightened to fit it.
 func (p *BuilderPromptGenerator) GeneratePrompt(ctx context.Context, text string) (string, error) {
+	// Use the variant of the prompt for the item's language, if any
+	if len(p.localizations) > 0 {
+		if localization, ok := localizationFor(p.localizations, PromptLanguage(ctx)); ok {
+			p = p.localize(localization)
+		}
+	}
+
+	prompt, err := p.render(ctx, text, nil)
+	if err != nil {
+		return "", err
+	}
+	if state, ok := promptBudgetFromContext(ctx); ok && llm.EstimateTokens(prompt) > state.budget.tokens() {
+		return p.tighten(ctx, text, state)
+	}
+	return prompt, nil
+}
+
+// render generates the prompt for a text without the optional sections in skip, keyed as
+// by optionalSections
+func (p *BuilderPromptGenerator) render(ctx context.Context, text string, skip map[string]bool) (string, error) {
 	// Generate example JSON from the result struct
 	jsonExample := GenerateJSONExample(p.resultStruct)
 
@@ -141,6 +464,22 @@ func (p *BuilderPromptGenerator) GeneratePrompt(ctx context.Context, text string
 		promptParts = append(promptParts, fmt.Sprintf("**Objective:** %s", p.objective))
 	}
 
+	// Add values shared by earlier pipeline steps
+	if contextText := formatStateSection(ctx, p.stateKeys); contextText != "" && !skip["context"] {
+		promptParts = append(promptParts, fmt.Sprintf("**Context:**\n%s", contextText))
+	}
+
+	// Add structured inputs such as definitions to follow
+	for _, input := range p.inputs {
+		section, err := input.render(ctx)
+		if err != nil {
+			return "", err
+		}
+		if section != "" {
+			promptParts = append(promptParts, section)
+		}
+	}
+
 	// Add input text
 	promptParts = append(promptParts, fmt.Sprintf("**Input Text:**\n%s", text))
 
@@ -154,15 +493,76 @@ func (p *BuilderPromptGenerator) GeneratePrompt(ctx context.Context, text string
 	}
 
 	// Add custom sections
-	for name, content := range p.customSections {
-		promptParts = append(promptParts, fmt.Sprintf("**%s:**\n%s", name, content))
+	for _, section := range p.customSections {
+		if section.standard == SectionJSONOnly {
+			continue
+		}
+		if section.standard != "" {
+			if skip["standard:"+section.standard] {
+				continue
+			}
+			sectionText, err := standardSectionText(section.standard)
+			if err != nil {
+				return "", err
+			}
+			promptParts = append(promptParts, sectionText)
+			continue
+		}
+		if skip["custom:"+section.name] {
+			continue
+		}
+		promptParts = append(promptParts, fmt.Sprintf("**%s:**\n%s", section.name, section.content))
+	}
+
+	// Add few-shot examples
+	if len(p.examples) > 0 && !skip["examples"] {
+		examples, err := formatExamples(p.examples)
+		if err != nil {
+			return "", err
+		}
+		promptParts = append(promptParts, fmt.Sprintf("**Examples:**\n%s", examples))
 	}
 
 	// Always add JSON structure requirement
 	promptParts = append(promptParts, fmt.Sprintf("**Required JSON Output Structure:**\n%s", jsonExample))
 
+	// Add the alternative result of processors that may abstain
+	if p.notApplicable != "" {
+		promptParts = append(promptParts, fmt.Sprintf("**Not Applicable:**\n%s", p.notApplicable))
+	}
+
 	// Always add critical JSON-only instruction
-	promptParts = append(promptParts, "*** IMPORTANT: Your ENTIRE response must be a single JSON object, without ANY additional text, explanation, or markdown formatting. ***")
+	jsonOnly, err := standardSectionText(SectionJSONOnly)
+	if err != nil {
+		return "", err
+	}
+	promptParts = append(promptParts, jsonOnly)
 
 	return strings.Join(promptParts, "\n\n"), nil
 }
+
+// formatStateSection renders the requested item state values as "key: value" lines
+func formatStateSection(ctx context.Context, keys []string) string {
+	state := data.ItemStateFromContext(ctx)
+	if len(keys) == 0 || state == nil {
+		return ""
+	}
+
+	var lines []string
+	for _, key := range keys {
+		value, ok := state[key]
+		if !ok {
+			continue
+		}
+		if text, ok := value.(string); ok {
+			lines = append(lines, fmt.Sprintf("%s: %s", key, text))
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", key, encoded))
+	}
+	return strings.Join(lines, "\n")
+}
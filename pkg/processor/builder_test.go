@@ -0,0 +1,406 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// limitResult is the result struct of TestGenerationLimits
+type limitResult struct {
+	Summary       string `json:"summary"`
+	ProcessorType string `json:"processor_type"`
+}
+
+func TestGenerationLimits(t *testing.T) {
+	builder := NewBuilder("limited").
+		WithStruct(&limitResult{}).
+		WithStopSequences("<END>").
+		WithMaxOutputTokens(100)
+	response := `{"summary": "short"}<END> and a rambling tail`
+
+	tests := []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{name: "builder limits", options: NewDefaultOptions(), want: `{"summary": "short"}`},
+		{name: "options override", options: NewDefaultOptions().WithLLMOption("stop_sequences", []interface{}{"}"}), want: `{"summary": "short"`},
+		{name: "token limit", options: NewDefaultOptions().WithLLMOption("max_output_tokens", 3), want: `{"summary": `},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := llm.NewMockProviderWithResponse(response)
+			var streamed string
+			ctx := llm.WithStream(context.Background(), func(chunk string) { streamed += chunk })
+
+			proc, err := builder.Build(provider, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := proc.Process(ctx, data.NewTextProcessItem("1", "text", nil)); err != nil {
+				t.Fatal(err)
+			}
+			if streamed != tt.want {
+				t.Errorf("expected response %q, got %q", tt.want, streamed)
+			}
+		})
+	}
+}
+
+func TestNotApplicable(t *testing.T) {
+	validated := 0
+	builder := NewBuilder("abstaining").
+		WithStruct(&limitResult{}).
+		WithNotApplicable("the text is empty").
+		WithResultValidator(func(_ context.Context, _ interface{}) error {
+			validated++
+			return nil
+		})
+
+	tests := []struct {
+		name          string
+		response      string
+		wantReason    string
+		notApplicable bool
+	}{
+		{name: "applicable", response: `{"summary": "A refund request"}`},
+		{name: "abstained", response: "```json\n{\"not_applicable\": true, \"reason\": \"nothing to summarize\"}\n```", notApplicable: true, wantReason: "nothing to summarize"},
+		{name: "explicitly applicable", response: `{"summary": "A refund request", "not_applicable": false}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validated = 0
+			provider := llm.NewMockProviderWithResponse(tt.response)
+			proc, err := builder.Build(provider, NewDefaultOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			item, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "text", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			notApplicable, reason := ItemNotApplicable(item, "abstaining")
+			if notApplicable != tt.notApplicable || reason != tt.wantReason {
+				t.Errorf("expected not applicable %t (%q), got %t (%q)", tt.notApplicable, tt.wantReason, notApplicable, reason)
+			}
+			if wantValidated := map[bool]int{false: 1, true: 0}[tt.notApplicable]; validated != wantValidated {
+				t.Errorf("expected %d validations, got %d", wantValidated, validated)
+			}
+			if prompt := provider.Prompts()[0]; !strings.Contains(prompt, "If the text is empty, do not guess") {
+				t.Errorf("prompt doesn't allow a not applicable result:\n%s", prompt)
+			}
+		})
+	}
+}
+
+func TestStandardSections(t *testing.T) {
+	RegisterStandardSection("test_tone", StandardSection{Title: "Tone", Content: "Be neutral."})
+	builder := NewBuilder("standardized").
+		WithStruct(&limitResult{}).
+		WithStandardSection(SectionNoHallucination).
+		WithStandardSection("test_tone")
+	provider := llm.NewMockProviderWithResponse(`{"summary": "short"}`)
+	proc, err := builder.Build(provider, NewDefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates of a section reach processors created before them
+	RegisterStandardSection("test_tone", StandardSection{Title: "Tone", Content: "Be formal."})
+	if _, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "text", nil)); err != nil {
+		t.Fatal(err)
+	}
+	prompt := provider.Prompts()[0]
+	accuracy, _ := standardSectionText(SectionNoHallucination)
+	jsonOnly, _ := standardSectionText(SectionJSONOnly)
+	for _, want := range []string{accuracy, "**Tone:**\nBe formal.", jsonOnly} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Index(prompt, accuracy) > strings.Index(prompt, "**Tone:**") || !strings.HasSuffix(prompt, jsonOnly) {
+		t.Errorf("sections out of order:\n%s", prompt)
+	}
+
+	if _, err := NewBuilder("unknown").WithStruct(&limitResult{}).WithStandardSection("missing").Build(provider, NewDefaultOptions()); err == nil {
+		t.Error("expected an error for an unknown standard section")
+	}
+}
+
+func TestLocalization(t *testing.T) {
+	builder := NewBuilder("localized").
+		WithStruct(&limitResult{}).
+		WithObjective("Summarize the text").
+		WithCustomSection("Style", "Be brief.").
+		WithExamples(PromptExample{Input: "I want a refund", Output: map[string]string{"summary": "Refund request"}}).
+		WithLocalization("es", PromptLocalization{
+			Objective: "Resume el texto",
+			Sections:  map[string]string{"Style": "Sé breve."},
+			Examples:  []PromptExample{{Input: "Quiero un reembolso", Output: map[string]string{"summary": "Solicitud de reembolso"}}},
+		}).
+		WithLocalization("pt", PromptLocalization{Objective: "Resuma o texto"})
+
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		state    string
+		options  Options
+		want     []string
+	}{
+		{name: "default", options: NewDefaultOptions(), want: []string{"Summarize the text", "Be brief.", `Input: I want a refund`}},
+		{name: "metadata", metadata: map[string]interface{}{LanguageInput: "ES"}, options: NewDefaultOptions(), want: []string{"Resume el texto", "Sé breve.", `"summary":"Solicitud de reembolso"`}},
+		{name: "detected", state: "pt_BR", options: NewDefaultOptions(), want: []string{"Resuma o texto", "Be brief.", `Input: I want a refund`}},
+		{name: "configured", options: NewDefaultOptions().WithInput(LanguageInput, "es"), want: []string{"Resume el texto"}},
+		{name: "item over configured", metadata: map[string]interface{}{LanguageInput: "pt"}, options: NewDefaultOptions().WithInput(LanguageInput, "es"), want: []string{"Resuma o texto"}},
+		{name: "fallback", metadata: map[string]interface{}{LanguageInput: "fr"}, options: NewDefaultOptions(), want: []string{"Summarize the text"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := llm.NewMockProviderWithResponse(`{"summary": "short"}`)
+			proc, err := builder.Build(provider, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			item := data.NewTextProcessItem("1", "text", tt.metadata)
+			if tt.state != "" {
+				item.SetState(LanguageInput, tt.state)
+			}
+			if _, err := proc.Process(context.Background(), item); err != nil {
+				t.Fatal(err)
+			}
+			prompt := provider.Prompts()[0]
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt lacks %q:\n%s", want, prompt)
+				}
+			}
+		})
+	}
+}
+
+// settingsProvider records the temperature of each call, or -1 for the provider's own
+type settingsProvider struct {
+	*llm.MockProvider
+	temperatures []float64
+}
+
+// Generate implements llm.Provider
+func (p *settingsProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	p.record(ctx)
+	return p.MockProvider.Generate(ctx, prompt)
+}
+
+// GenerateJSON implements llm.Provider
+func (p *settingsProvider) GenerateJSON(ctx context.Context, prompt string, responseStruct interface{}) error {
+	p.record(ctx)
+	return p.MockProvider.GenerateJSON(ctx, prompt, responseStruct)
+}
+
+func (p *settingsProvider) record(ctx context.Context) {
+	temperature := -1.0
+	if limits, ok := llm.GenerationLimitsFromContext(ctx); ok && limits.Temperature != nil {
+		temperature = *limits.Temperature
+	}
+	p.temperatures = append(p.temperatures, temperature)
+}
+
+func TestGenerationSettings(t *testing.T) {
+	builder := NewBuilder("classifier").
+		WithStruct(&limitResult{}).
+		WithTemperature(0).
+		WithPreferredModels("gemini-2.0-flash", "gpt-4o-mini")
+
+	t.Run("temperature", func(t *testing.T) {
+		for _, tt := range []struct {
+			options Options
+			want    float64
+		}{
+			{options: NewDefaultOptions(), want: 0},
+			{options: NewDefaultOptions().WithLLMOption("temperature", 0.7), want: 0.7},
+		} {
+			provider := &settingsProvider{MockProvider: llm.NewMockProviderWithResponse(`{"summary": "short"}`)}
+			proc, err := builder.Build(provider, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "text", nil)); err != nil {
+				t.Fatal(err)
+			}
+			if len(provider.temperatures) != 1 || provider.temperatures[0] != tt.want {
+				t.Errorf("expected temperature %g, got %v", tt.want, provider.temperatures)
+			}
+		}
+	})
+
+	t.Run("preferred models", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			model   string
+			options Options
+			want    string
+		}{
+			{name: "default model", options: NewDefaultOptions(), want: "openai/gpt-4o-mini"},
+			{name: "chosen model", model: "gpt-4.1", options: NewDefaultOptions(), want: "openai/gpt-4.1"},
+			{name: "routing", options: NewDefaultOptions().WithRouting(RoutingConfig{Routes: []ModelRoute{{}}}), want: "openai/gpt-4"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				provider, err := llm.NewOpenAIProvider(llm.Config{APIKey: "test", Model: tt.model})
+				if err != nil {
+					t.Fatal(err)
+				}
+				proc, err := builder.Build(provider, tt.options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if model := proc.(*GenericProcessor).model; model != tt.want {
+					t.Errorf("expected model %s, got %s", tt.want, model)
+				}
+			})
+		}
+	})
+}
+
+func TestPromptBudget(t *testing.T) {
+	builder := NewBuilder("budgeted").
+		WithStruct(&limitResult{}).
+		WithObjective("Summarize the text").
+		WithCustomSection("Tone", strings.Repeat("Stay neutral. ", 100)).
+		WithStandardSection(SectionNoHallucination).
+		WithCustomSection("Guidance", strings.Repeat("Be brief. ", 100)).
+		WithExamples(PromptExample{Input: "I want a refund", Output: map[string]string{"summary": "Refund request"}})
+	generator := builder.promptGenerator().(*BuilderPromptGenerator)
+	text := strings.Repeat("The customer asks for a refund. ", 50)
+
+	full, err := generator.GeneratePrompt(context.Background(), text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allDropped := map[string]bool{"custom:Tone": true, "custom:Guidance": true, "examples": true, "standard:" + SectionNoHallucination: true}
+	bare, err := generator.render(context.Background(), "", allDropped)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		tokens        int64
+		wantDropped   []string
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{name: "within budget", tokens: llm.EstimateTokens(full)},
+		{name: "custom section dropped", tokens: llm.EstimateTokens(full) - 100, wantDropped: []string{"Guidance"}},
+		{name: "input truncated", tokens: llm.EstimateTokens(bare) + 100, wantDropped: []string{"Guidance", "Tone", "Examples", SectionNoHallucination}, wantTruncated: true},
+		{name: "no room for input", tokens: llm.EstimateTokens(bare), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trim := &promptTrim{}
+			ctx := withPromptBudget(context.Background(), PromptBudget{ContextTokens: int(tt.tokens), Fraction: 1}, trim)
+			prompt, err := generator.GeneratePrompt(ctx, text)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tokens := llm.EstimateTokens(prompt); tokens > tt.tokens {
+				t.Errorf("prompt of %d tokens exceeds the budget of %d", tokens, tt.tokens)
+			}
+			if !reflect.DeepEqual(trim.dropped, tt.wantDropped) || trim.truncated != tt.wantTruncated {
+				t.Errorf("expected dropped %v and truncated %t, got %v and %t", tt.wantDropped, tt.wantTruncated, trim.dropped, trim.truncated)
+			}
+			if truncated := strings.Contains(prompt, truncationMarker); truncated != tt.wantTruncated {
+				t.Errorf("expected truncated input %t:\n%s", tt.wantTruncated, prompt)
+			}
+		})
+	}
+
+	// The trim is recorded in the result's processing info
+	provider := llm.NewMockProviderWithResponse(`{"summary": "short"}`)
+	options := NewDefaultOptions().WithPromptBudget(PromptBudget{ContextTokens: int(llm.EstimateTokens(full)) - 100, Fraction: 1})
+	proc, err := builder.Build(provider, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := proc.Process(context.Background(), data.NewTextProcessItem("1", text, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := item.ProcessingInfo["budgeted"].(map[string]interface{})
+	want := map[string]interface{}{"dropped_sections": []string{"Guidance"}, "truncated_input": false}
+	if !reflect.DeepEqual(info["prompt_trim"], want) {
+		t.Errorf("expected prompt trim %v, got %v", want, info["prompt_trim"])
+	}
+}
+
+func TestResultTransform(t *testing.T) {
+	var validated string
+	builder := NewBuilder("transformed").
+		WithStruct(&limitResult{}).
+		WithResultTransform(Transform(func(result *limitResult) (*limitResult, error) {
+			if result.Summary == "" {
+				return nil, errors.New("empty summary")
+			}
+			result.Summary = strings.ToLower(strings.TrimSpace(result.Summary))
+			return result, nil
+		})).
+		WithResultTransform(Transform(func(result *limitResult) (*limitResult, error) {
+			return &limitResult{Summary: strings.TrimSuffix(result.Summary, "."), ProcessorType: result.ProcessorType}, nil
+		})).
+		WithResultValidator(func(_ context.Context, result interface{}) error {
+			validated = result.(*limitResult).Summary
+			return nil
+		})
+
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantErr  bool
+	}{
+		{name: "transformed in order", response: `{"summary": "  A Refund Request. "}`, want: "a refund request"},
+		{name: "transform error", response: `{"summary": ""}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validated = ""
+			proc, err := builder.Build(llm.NewMockProviderWithResponse(tt.response), NewDefaultOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			item, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "text", nil))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := item.ProcessingInfo["transformed"].(map[string]interface{})["summary"]; got != tt.want {
+				t.Errorf("expected summary %q, got %q", tt.want, got)
+			}
+			if validated != tt.want {
+				t.Errorf("expected the transformed summary %q to be validated, got %q", tt.want, validated)
+			}
+		})
+	}
+}
@@ -40,7 +40,7 @@ type MatchSummary struct {
 	TotalMatched int `json:"total_matched"`
 	// TotalMissing is the number of attributes with no suitable match
 	TotalMissing int `json:"total_missing"`
-	// MatchRate is the percentage of required attributes that were matched
+	// MatchRate is the fraction of required attributes that were matched
 	MatchRate float64 `json:"match_rate"`
 	// AverageConfidence is the average confidence score of all matches
 	AverageConfidence float64 `json:"average_confidence"`
@@ -120,5 +120,27 @@ Quality Assessment:
 - Good (70-89% match rate): Usable with minor gaps
 - Fair (50-69% match rate): Significant gaps requiring attention
 - Poor (<50% match rate): Major restructuring needed`).
+		WithResultTransform(processor.Transform(summarizeMatches)).
 		Register()
 }
+
+// summarizeMatches derives the counts, match rate and average confidence of the match
+// summary from the matches and missing attributes, rather than trusting the model's arithmetic
+func summarizeMatches(result *AttributeMatchResult) (*AttributeMatchResult, error) {
+	summary := &result.MatchSummary
+	summary.TotalMatched = len(result.Matches)
+	summary.TotalMissing = len(result.MissingAttributes)
+	summary.TotalRequired = summary.TotalMatched + summary.TotalMissing
+	summary.MatchRate, summary.AverageConfidence = 0, 0
+	if summary.TotalRequired > 0 {
+		summary.MatchRate = round(float64(summary.TotalMatched)/float64(summary.TotalRequired), 2)
+	}
+	if summary.TotalMatched > 0 {
+		var total float64
+		for _, match := range result.Matches {
+			total += match.Confidence
+		}
+		summary.AverageConfidence = round(total/float64(summary.TotalMatched), 2)
+	}
+	return result, nil
+}
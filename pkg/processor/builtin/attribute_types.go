@@ -0,0 +1,218 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// Attribute types an AttributeDefinition may declare
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeDate    = "date"
+	AttributeEnum    = "enum"
+	AttributeBoolean = "boolean"
+)
+
+// DateLayout is the layout of the date values of typed attributes
+const DateLayout = "2006-01-02"
+
+// attributeTypeAliases maps the type names LLMs and schemas commonly use to attribute types
+var attributeTypeAliases = map[string]string{
+	"":          AttributeString,
+	"text":      AttributeString,
+	"str":       AttributeString,
+	"integer":   AttributeNumber,
+	"int":       AttributeNumber,
+	"float":     AttributeNumber,
+	"decimal":   AttributeNumber,
+	"numeric":   AttributeNumber,
+	"bool":      AttributeBoolean,
+	"datetime":  AttributeDate,
+	"timestamp": AttributeDate,
+	"category":  AttributeEnum,
+}
+
+// dateLayouts are the layouts date values are parsed with
+var dateLayouts = []string{
+	DateLayout,
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006/01/02",
+	"01/02/2006",
+	"1/2/2006",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"02.01.2006",
+}
+
+// nullValues are the values extracted for attributes the text doesn't state
+var nullValues = map[string]bool{"": true, "unknown": true, "n/a": true, "na": true, "none": true, "null": true}
+
+// TypeViolation reports an extracted value that doesn't conform to its attribute's type
+type TypeViolation struct {
+	// FieldName is the attribute's field name
+	FieldName string `json:"field_name"`
+	// Value is the value extracted
+	Value string `json:"value"`
+	// Type is the attribute's declared type
+	Type string `json:"type"`
+	// Message explains the violation
+	Message string `json:"message"`
+}
+
+// AttributeType returns the definition's type, with common aliases such as "integer"
+// resolved; unknown types are strings
+func (d AttributeDefinition) AttributeType() string {
+	name := strings.ToLower(strings.TrimSpace(d.Type))
+	if alias, ok := attributeTypeAliases[name]; ok {
+		return alias
+	}
+	switch name {
+	case AttributeNumber, AttributeDate, AttributeEnum, AttributeBoolean:
+		return name
+	}
+	return AttributeString
+}
+
+// Coerce converts an extracted value to the definition's type: numbers are stripped of
+// currency symbols, percent signs and thousands separators, dates are written as
+// DateLayout, booleans as "true" or "false" and allowed values as declared. It returns
+// "" for values the text doesn't state, such as "unknown".
+func (d AttributeDefinition) Coerce(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if nullValues[strings.ToLower(value)] {
+		return "", nil
+	}
+
+	switch d.AttributeType() {
+	case AttributeNumber:
+		number, err := strconv.ParseFloat(strings.NewReplacer(",", "", "$", "", "€", "", "£", "", "%", "", " ", "").Replace(value), 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case AttributeBoolean:
+		switch strings.ToLower(value) {
+		case "true", "yes", "y", "1":
+			return "true", nil
+		case "false", "no", "n", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%q is not a boolean", value)
+	case AttributeDate:
+		for _, layout := range dateLayouts {
+			if date, err := time.Parse(layout, value); err == nil {
+				return date.Format(DateLayout), nil
+			}
+		}
+		return "", fmt.Errorf("%q is not a date", value)
+	}
+
+	if len(d.AllowedValues) == 0 {
+		if d.AttributeType() == AttributeEnum {
+			return "", fmt.Errorf("enum attribute declares no allowed values")
+		}
+		return value, nil
+	}
+	for _, allowed := range d.AllowedValues {
+		if strings.EqualFold(normalizeEnumValue(allowed), normalizeEnumValue(value)) {
+			return allowed, nil
+		}
+	}
+	return "", fmt.Errorf("%q is not one of %s", value, strings.Join(d.AllowedValues, ", "))
+}
+
+// TypedValue returns a coerced value as the Go value of the definition's type: a float64
+// for numbers, a bool for booleans, a time.Time for dates and a string otherwise, or nil
+// for "", so it can be loaded into a typed column
+func (d AttributeDefinition) TypedValue(value string) (interface{}, error) {
+	if value == "" {
+		return nil, nil
+	}
+	switch d.AttributeType() {
+	case AttributeNumber:
+		return strconv.ParseFloat(value, 64)
+	case AttributeBoolean:
+		return strconv.ParseBool(value)
+	case AttributeDate:
+		return time.Parse(DateLayout, value)
+	default:
+		return value, nil
+	}
+}
+
+// normalizeEnumValue makes enum values spelled with spaces, hyphens or underscores compare equal
+func normalizeEnumValue(value string) string {
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.TrimSpace(value))
+}
+
+// typeHint describes a definition's type and allowed values for the prompt, or "" for
+// untyped strings
+func (d AttributeDefinition) typeHint() string {
+	switch d.AttributeType() {
+	case AttributeNumber:
+		return "number"
+	case AttributeBoolean:
+		return "boolean: true or false"
+	case AttributeDate:
+		return "date: YYYY-MM-DD"
+	}
+	if len(d.AllowedValues) > 0 {
+		return "one of: " + strings.Join(d.AllowedValues, ", ")
+	}
+	return ""
+}
+
+// validateAttributeTypes coerces the extracted values to the types the attribute
+// definitions declare, replacing values that don't conform with "" and reporting them as
+// violations. Attributes without a definition are kept as extracted.
+func validateAttributeTypes(ctx context.Context, result interface{}) error {
+	attributes, ok := result.(*AttributeResult)
+	if !ok {
+		return nil
+	}
+	// Only the violations found here are reported
+	attributes.Violations = nil
+	value, ok := processor.PromptInput(ctx, AttributesInput)
+	if !ok {
+		return nil
+	}
+	definitions, err := attributeDefinitions(value)
+	if err != nil {
+		// Text definitions declare no types
+		return nil
+	}
+
+	byName := make(map[string]AttributeDefinition, len(definitions))
+	for _, definition := range definitions {
+		byName[strings.ToLower(definition.FieldName)] = definition
+	}
+	for i, attribute := range attributes.Attributes {
+		definition, ok := byName[strings.ToLower(attribute.FieldName)]
+		if !ok {
+			continue
+		}
+		attribute.FieldName = definition.FieldName
+		attribute.Type = definition.AttributeType()
+		coerced, err := definition.Coerce(attribute.Value)
+		if err != nil {
+			attributes.Violations = append(attributes.Violations, TypeViolation{
+				FieldName: definition.FieldName,
+				Value:     attribute.Value,
+				Type:      attribute.Type,
+				Message:   err.Error(),
+			})
+		}
+		attribute.Value = coerced
+		attributes.Attributes[i] = attribute
+	}
+	return nil
+}
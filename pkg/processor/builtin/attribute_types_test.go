@@ -0,0 +1,75 @@
+package builtin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+func TestAttributeTypes(t *testing.T) {
+	definitions := []AttributeDefinition{
+		{FieldName: "order_total", Type: "number"},
+		{FieldName: "quantity", Type: "integer"},
+		{FieldName: "order_date", Type: "date"},
+		{FieldName: "issue_type", Type: "enum", AllowedValues: []string{"billing", "late_delivery"}},
+		{FieldName: "repeat_contact", Type: "boolean"},
+		{FieldName: "summary"},
+	}
+
+	tests := []struct {
+		name           string
+		field          string
+		value          string
+		want           string
+		wantViolations int
+	}{
+		{name: "currency", field: "order_total", value: "$1,249.50", want: "1249.5"},
+		{name: "number alias", field: "quantity", value: "3", want: "3"},
+		{name: "not a number", field: "order_total", value: "about forty", want: "", wantViolations: 1},
+		{name: "written date", field: "order_date", value: "March 4, 2024", want: "2024-03-04"},
+		{name: "not a date", field: "order_date", value: "last week", want: "", wantViolations: 1},
+		{name: "enum spelling", field: "issue_type", value: "Late Delivery", want: "late_delivery"},
+		{name: "not allowed", field: "issue_type", value: "refund", want: "", wantViolations: 1},
+		{name: "boolean", field: "repeat_contact", value: "Yes", want: "true"},
+		{name: "not stated", field: "repeat_contact", value: "unknown", want: ""},
+		{name: "untyped", field: "summary", value: " Late parcel ", want: "Late parcel"},
+		{name: "undeclared", field: "store", value: " Main St ", want: " Main St "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := `{"attributes": [{"field_name": "` + tt.field + `", "value": "` + tt.value + `", "confidence": 0.8}]}`
+			proc, err := processor.Create("get_attributes", llm.NewMockProviderWithResponse(response), processor.NewDefaultOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			item := data.NewTextProcessItem("1", "text", map[string]interface{}{AttributesInput: definitions})
+			result, err := proc.Process(context.Background(), item)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := result.Content.(*AttributeResult)
+			if len(got.Attributes) != 1 || got.Attributes[0].Value != tt.want {
+				t.Fatalf("expected value %q, got %+v", tt.want, got.Attributes)
+			}
+			if len(got.Violations) != tt.wantViolations {
+				t.Errorf("expected %d violations, got %+v", tt.wantViolations, got.Violations)
+			}
+		})
+	}
+
+	t.Run("typed values", func(t *testing.T) {
+		typed, err := definitions[1].TypedValue("3")
+		if err != nil || !reflect.DeepEqual(typed, 3.0) {
+			t.Errorf("expected 3.0, got %v, %v", typed, err)
+		}
+		if typed, err := definitions[4].TypedValue(""); typed != nil || err != nil {
+			t.Errorf("expected nil for an empty value, got %v, %v", typed, err)
+		}
+	})
+}
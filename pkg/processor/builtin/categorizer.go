@@ -82,5 +82,6 @@ Ensure:
 - Balanced consolidation (neither too granular nor too broad)
 - Preservation of important semantic distinctions
 - Business-relevant categorizations that enable action`).
+		WithTemperature(0).
 		Register()
 }
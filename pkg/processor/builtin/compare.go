@@ -0,0 +1,68 @@
+package builtin
+
+import (
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// ReferenceInput is the structured input holding the text compare compares the item's text
+// with, e.g. a second transcript or a policy: a field of JSON content, a metadata key or an
+// Options.Inputs key
+const ReferenceInput = "reference"
+
+// ComparisonPoint is a point on which the two texts agree or contradict each other
+type ComparisonPoint struct {
+	// Topic is what the point is about
+	Topic string `json:"topic"`
+	// Description explains how the texts agree or differ on the topic
+	Description string `json:"description"`
+	// InputQuote is the passage of the input text about the topic, quoted verbatim
+	InputQuote string `json:"input_quote,omitempty"`
+	// ReferenceQuote is the passage of the reference text about the topic, quoted verbatim
+	ReferenceQuote string `json:"reference_quote,omitempty"`
+}
+
+// MissingElement is an element only one of the texts contains
+type MissingElement struct {
+	// Element describes what is missing
+	Element string `json:"element"`
+	// MissingFrom is the text lacking the element: "input" or "reference"
+	MissingFrom string `json:"missing_from"`
+}
+
+// ComparisonResult is a structured diff of two texts
+type ComparisonResult struct {
+	// Agreements are the points both texts agree on
+	Agreements []ComparisonPoint `json:"agreements,omitempty"`
+	// Contradictions are the points the texts contradict each other on
+	Contradictions []ComparisonPoint `json:"contradictions,omitempty"`
+	// Missing are the elements only one of the texts contains
+	Missing []MissingElement `json:"missing,omitempty"`
+	// SimilarityScore is how similar the texts are in substance (0.0 to 1.0)
+	SimilarityScore float64 `json:"similarity_score" default:"0.0"`
+	// Summary sums up the differences in one or two sentences
+	Summary string `json:"summary"`
+	// ProcessorType is the type of processor that generated this result
+	ProcessorType string `json:"processor_type"`
+}
+
+// Register the processor with the registry
+func init() {
+	processor.NewBuilder("compare").
+		WithStruct(&ComparisonResult{}).
+		WithContentTypes("text", "json", "html", "markdown", "pdf_text", "transcript").
+		WithRole("You are an expert analyst comparing two texts that ONLY outputs valid JSON").
+		WithObjective("Compare the Input Text with the Reference Text, e.g. two transcripts of the same case or a response draft and the policy it must follow, and produce a structured diff of their substance").
+		WithInput(ReferenceInput, "Reference Text", nil).
+		WithInstructions(
+			"Carefully read the Input Text and the Reference Text; if no Reference Text is given, compare the two texts contained in the Input Text, treating the first as the input and the second as the reference",
+			"List the points both texts agree on in 'agreements'",
+			"List the points on which the texts contradict each other in 'contradictions'",
+			"List the elements only one text contains in 'missing', with 'missing_from' set to the text lacking it: 'input' or 'reference'",
+			"For agreements and contradictions, quote the relevant passages of each text word for word in 'input_quote' and 'reference_quote'",
+			"Compare substance, not wording: rephrasings of the same fact are agreements",
+			"Assign a 'similarity_score' from 0.0 (unrelated or fully contradictory) to 1.0 (the same substance)",
+			"Sum up the differences in one or two sentences in 'summary'",
+			"Format your entire output as a single, valid JSON object conforming to the structure below",
+		).
+		Register()
+}
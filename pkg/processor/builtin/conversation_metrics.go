@@ -0,0 +1,317 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// CustomerSpeakerInput is the structured input naming the customer's speaker label, e.g.
+// "Speaker 2", for conversation_metrics. Without it, the first speaker labeled customer,
+// caller, client or user is the customer.
+const CustomerSpeakerInput = "customer_speaker"
+
+// SilenceThresholdInput is the structured input holding the shortest pause, in seconds,
+// conversation_metrics reports as a silence gap
+const SilenceThresholdInput = "silence_threshold"
+
+// DefaultSilenceThreshold is the shortest silence gap reported without a SilenceThresholdInput
+const DefaultSilenceThreshold = 3.0
+
+// SpeakerMetrics are the talk-time and interaction metrics of one speaker
+type SpeakerMetrics struct {
+	Speaker string `json:"speaker"`
+	// Turns is the number of the speaker's turns; consecutive utterances are one turn
+	Turns int `json:"turns"`
+	Words int `json:"words"`
+	// TalkTime is the speaker's total speaking time in seconds, 0 without timestamps
+	TalkTime float64 `json:"talk_time_seconds"`
+	// TalkRatio is the speaker's share of the talk time, or of the words without timestamps
+	TalkRatio          float64 `json:"talk_ratio"`
+	AverageTurnWords   float64 `json:"average_turn_words"`
+	AverageTurnSeconds float64 `json:"average_turn_seconds"`
+	// Interruptions is the number of times the speaker cut another speaker off
+	Interruptions int `json:"interruptions"`
+}
+
+// Monologue is a single uninterrupted turn
+type Monologue struct {
+	Speaker string `json:"speaker"`
+	// Turn is the 1-based position of the turn in the conversation
+	Turn    int     `json:"turn"`
+	Words   int     `json:"words"`
+	Seconds float64 `json:"seconds"`
+}
+
+// SilenceGap is a pause in which nobody speaks
+type SilenceGap struct {
+	// Start is the offset of the pause from the start of the audio, in seconds
+	Start   float64 `json:"start"`
+	Seconds float64 `json:"seconds"`
+	// After is the speaker who spoke last before the pause
+	After string `json:"after"`
+}
+
+// ConversationMetricsResult contains objective talk-time and interaction metrics of a
+// conversation, computed without an LLM
+type ConversationMetricsResult struct {
+	// Speakers are the metrics of each speaker, in order of first appearance
+	Speakers      []SpeakerMetrics `json:"speakers"`
+	Turns         int              `json:"turns"`
+	Interruptions int              `json:"interruptions"`
+	// HasTimestamps reports whether timing metrics were computed, which needs transcript content
+	HasTimestamps bool    `json:"has_timestamps"`
+	Duration      float64 `json:"duration_seconds"`
+	// CustomerSpeaker is the speaker identified as the customer, if any
+	CustomerSpeaker string `json:"customer_speaker,omitempty"`
+	// LongestCustomerMonologue is the customer's longest turn, by time or else by words
+	LongestCustomerMonologue *Monologue `json:"longest_customer_monologue,omitempty"`
+	// SilenceGaps are the pauses of at least the silence threshold, with timestamps only
+	SilenceGaps    []SilenceGap `json:"silence_gaps"`
+	TotalSilence   float64      `json:"total_silence_seconds"`
+	LongestSilence float64      `json:"longest_silence_seconds"`
+	// ProcessorType is the type of processor that generated this result
+	ProcessorType string `json:"processor_type"`
+}
+
+// utterance is a segment of a conversation attributed to a speaker
+type utterance struct {
+	speaker    string
+	text       string
+	start, end float64
+}
+
+// turn is a run of consecutive utterances of one speaker
+type turn struct {
+	speaker    string
+	words      int
+	start, end float64
+	// talk is the speaking time of the turn's utterances, excluding pauses between them
+	talk        float64
+	interrupted bool
+	// cutOff reports whether the turn's text trails off as if interrupted
+	cutOff bool
+}
+
+// speakerLinePattern matches a "Speaker: text" line, optionally prefixed with a [hh:mm:ss]
+// timestamp as rendered for transcripts
+var speakerLinePattern = regexp.MustCompile(`^\s*(?:\[[\d:.]+\]\s*)?([\p{L}\p{N}][\p{L}\p{N} _.'-]{0,39}):\s*(.*)$`)
+
+// customerLabelPattern matches speaker labels identifying the customer
+var customerLabelPattern = regexp.MustCompile(`(?i)customer|caller|client|user`)
+
+// parseConversation splits a "Speaker: text" conversation into utterances; lines without
+// a speaker continue the previous utterance
+func parseConversation(text string) []utterance {
+	var utterances []utterance
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if match := speakerLinePattern.FindStringSubmatch(line); match != nil {
+			utterances = append(utterances, utterance{speaker: strings.TrimSpace(match[1]), text: match[2]})
+			continue
+		}
+		if len(utterances) == 0 {
+			utterances = append(utterances, utterance{speaker: "unknown"})
+		}
+		last := &utterances[len(utterances)-1]
+		last.text = strings.TrimSpace(last.text + " " + line)
+	}
+	return utterances
+}
+
+// transcriptUtterances returns a transcript's segments as utterances, and whether they are timed
+func transcriptUtterances(transcript *data.Transcript) ([]utterance, bool) {
+	utterances := make([]utterance, 0, len(transcript.Segments))
+	timed := false
+	for _, segment := range transcript.Segments {
+		speaker := strings.TrimSpace(segment.Speaker)
+		if speaker == "" {
+			speaker = "unknown"
+		}
+		if segment.End > segment.Start {
+			timed = true
+		}
+		utterances = append(utterances, utterance{speaker: speaker, text: segment.Text, start: segment.Start, end: segment.End})
+	}
+	return utterances, timed
+}
+
+// trailsOff reports whether an utterance ends mid-sentence, as transcribers mark cut-off speech
+func trailsOff(text string) bool {
+	text = strings.TrimSpace(text)
+	for _, suffix := range []string{"-", "—", "–", "...", "…"} {
+		if strings.HasSuffix(text, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// inputFloat returns a numeric structured input, or fallback if it's absent
+func inputFloat(ctx context.Context, key string, fallback float64) (float64, error) {
+	value, ok := processor.PromptInput(ctx, key)
+	if !ok {
+		return fallback, nil
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s input %q: %w", key, v, err)
+		}
+		return number, nil
+	}
+	return 0, fmt.Errorf("invalid %s input: expected a number, got %T", key, value)
+}
+
+// computeConversationMetrics computes the metrics of a transcript or "Speaker: text" conversation
+func computeConversationMetrics(ctx context.Context, item *data.ProcessItem, text string) (interface{}, error) {
+	threshold, err := inputFloat(ctx, SilenceThresholdInput, DefaultSilenceThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	var utterances []utterance
+	timed := false
+	if item.ContentType == data.ContentTypeTranscript {
+		transcript, err := item.GetTranscriptContent()
+		if err != nil {
+			return nil, err
+		}
+		utterances, timed = transcriptUtterances(transcript)
+	} else {
+		utterances = parseConversation(text)
+	}
+
+	result := &ConversationMetricsResult{HasTimestamps: timed, SilenceGaps: []SilenceGap{}, Speakers: []SpeakerMetrics{}}
+	if len(utterances) == 0 {
+		return result, nil
+	}
+
+	// Merge utterances into turns, noting interruptions and silence between utterances
+	var turns []turn
+	end := utterances[0].start
+	for i, u := range utterances {
+		if timed && i > 0 {
+			if gap := u.start - end; gap >= threshold && gap > 0 {
+				result.SilenceGaps = append(result.SilenceGaps, SilenceGap{Start: round(end, 2), Seconds: round(gap, 2), After: utterances[i-1].speaker})
+				result.TotalSilence += gap
+				result.LongestSilence = math.Max(result.LongestSilence, gap)
+			}
+		}
+		words := len(strings.Fields(u.text))
+		if len(turns) > 0 && turns[len(turns)-1].speaker == u.speaker {
+			last := &turns[len(turns)-1]
+			last.words += words
+			last.talk += u.end - u.start
+			last.end = math.Max(last.end, u.end)
+			last.cutOff = trailsOff(u.text)
+		} else {
+			t := turn{speaker: u.speaker, words: words, start: u.start, end: u.end, talk: u.end - u.start, cutOff: trailsOff(u.text)}
+			if len(turns) > 0 {
+				previous := turns[len(turns)-1]
+				t.interrupted = previous.cutOff || (timed && u.start < previous.end)
+			}
+			turns = append(turns, t)
+		}
+		end = math.Max(end, u.end)
+	}
+	if timed {
+		result.Duration = round(end-utterances[0].start, 2)
+	}
+
+	// Aggregate turns per speaker
+	index := make(map[string]int)
+	var totalWords int
+	var totalTime float64
+	for _, t := range turns {
+		i, ok := index[t.speaker]
+		if !ok {
+			i = len(result.Speakers)
+			index[t.speaker] = i
+			result.Speakers = append(result.Speakers, SpeakerMetrics{Speaker: t.speaker})
+		}
+		speaker := &result.Speakers[i]
+		speaker.Turns++
+		speaker.Words += t.words
+		speaker.TalkTime += t.talk
+		if t.interrupted {
+			speaker.Interruptions++
+			result.Interruptions++
+		}
+		totalWords += t.words
+		totalTime += t.talk
+	}
+	result.Turns = len(turns)
+	for i := range result.Speakers {
+		speaker := &result.Speakers[i]
+		speaker.AverageTurnWords = round(float64(speaker.Words)/float64(speaker.Turns), 2)
+		speaker.AverageTurnSeconds = round(speaker.TalkTime/float64(speaker.Turns), 2)
+		if timed && totalTime > 0 {
+			speaker.TalkRatio = round(speaker.TalkTime/totalTime, 3)
+		} else if totalWords > 0 {
+			speaker.TalkRatio = round(float64(speaker.Words)/float64(totalWords), 3)
+		}
+		speaker.TalkTime = round(speaker.TalkTime, 2)
+	}
+	result.TotalSilence = round(result.TotalSilence, 2)
+	result.LongestSilence = round(result.LongestSilence, 2)
+
+	// Find the customer's longest monologue
+	result.CustomerSpeaker = customerSpeaker(ctx, result.Speakers)
+	for i, t := range turns {
+		if t.speaker != result.CustomerSpeaker || result.CustomerSpeaker == "" {
+			continue
+		}
+		monologue := &Monologue{Speaker: t.speaker, Turn: i + 1, Words: t.words, Seconds: round(t.talk, 2)}
+		longest := result.LongestCustomerMonologue
+		if longest == nil || (timed && monologue.Seconds > longest.Seconds) || (!timed && monologue.Words > longest.Words) {
+			result.LongestCustomerMonologue = monologue
+		}
+	}
+	return result, nil
+}
+
+// customerSpeaker returns the customer's speaker label: the CustomerSpeakerInput if it
+// names a speaker, else the first speaker labeled as a customer, else ""
+func customerSpeaker(ctx context.Context, speakers []SpeakerMetrics) string {
+	if value, ok := processor.PromptInput(ctx, CustomerSpeakerInput); ok {
+		if name, ok := value.(string); ok {
+			for _, speaker := range speakers {
+				if strings.EqualFold(speaker.Speaker, strings.TrimSpace(name)) {
+					return speaker.Speaker
+				}
+			}
+		}
+		return ""
+	}
+	for _, speaker := range speakers {
+		if customerLabelPattern.MatchString(speaker.Speaker) {
+			return speaker.Speaker
+		}
+	}
+	return ""
+}
+
+// round rounds x to the given number of decimal places
+func round(x float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(x*scale) / scale
+}
+
+func init() {
+	processor.RegisterComputedProcessor("conversation_metrics", []string{"transcript", "text"},
+		&ConversationMetricsResult{}, computeConversationMetrics)
+}
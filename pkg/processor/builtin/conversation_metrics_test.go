@@ -0,0 +1,89 @@
+package builtin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+func TestConversationMetrics(t *testing.T) {
+	transcript := []data.TranscriptSegment{
+		{Start: 0, End: 4, Speaker: "Agent", Text: "Thanks for calling, how can I help?"},
+		{Start: 4.5, End: 14.5, Speaker: "Caller", Text: "My internet keeps dropping every evening and"},
+		{Start: 14.5, End: 20.5, Speaker: "Caller", Text: "this is the third time I'm calling about it."},
+		{Start: 20, End: 22, Speaker: "Agent", Text: "I'm sorry, let me check."},
+		{Start: 30, End: 34, Speaker: "Agent", Text: "I found an outage in your area."},
+	}
+
+	tests := []struct {
+		name string
+		item *data.ProcessItem
+		want ConversationMetricsResult
+	}{
+		{
+			name: "transcript",
+			item: data.NewTranscriptProcessItem("1", transcript, nil),
+			want: ConversationMetricsResult{
+				Speakers: []SpeakerMetrics{
+					{Speaker: "Agent", Turns: 2, Words: 19, TalkTime: 10, TalkRatio: 0.385, AverageTurnWords: 9.5, AverageTurnSeconds: 5, Interruptions: 1},
+					{Speaker: "Caller", Turns: 1, Words: 16, TalkTime: 16, TalkRatio: 0.615, AverageTurnWords: 16, AverageTurnSeconds: 16},
+				},
+				Turns:                    3,
+				Interruptions:            1,
+				HasTimestamps:            true,
+				Duration:                 34,
+				CustomerSpeaker:          "Caller",
+				LongestCustomerMonologue: &Monologue{Speaker: "Caller", Turn: 2, Words: 16, Seconds: 16},
+				SilenceGaps:              []SilenceGap{{Start: 22, Seconds: 8, After: "Agent"}},
+				TotalSilence:             8,
+				LongestSilence:           8,
+			},
+		},
+		{
+			name: "text",
+			item: data.NewTextProcessItem("2", "Agent: How can I help?\n"+
+				"Customer: I was charged twice and—\n"+
+				"Agent: Let me look at your account.\n"+
+				"Customer: Thanks.\nIt's the second time this happened.",
+				map[string]interface{}{CustomerSpeakerInput: "customer"}),
+			want: ConversationMetricsResult{
+				Speakers: []SpeakerMetrics{
+					{Speaker: "Agent", Turns: 2, Words: 10, TalkRatio: 0.455, AverageTurnWords: 5, Interruptions: 1},
+					{Speaker: "Customer", Turns: 2, Words: 12, TalkRatio: 0.545, AverageTurnWords: 6},
+				},
+				Turns:                    4,
+				Interruptions:            1,
+				CustomerSpeaker:          "Customer",
+				LongestCustomerMonologue: &Monologue{Speaker: "Customer", Turn: 4, Words: 7},
+				SilenceGaps:              []SilenceGap{},
+			},
+		},
+	}
+
+	proc, err := processor.Create("conversation_metrics", nil, processor.NewDefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := proc.Process(context.Background(), tt.item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			metrics := result.Content.(*ConversationMetricsResult)
+			if !reflect.DeepEqual(*metrics, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, *metrics)
+			}
+			if info := result.ProcessingInfo["conversation_metrics"].(map[string]interface{}); info["processor_type"] != "conversation_metrics" {
+				t.Errorf("expected the processor type in the processing info, got %v", info)
+			}
+		})
+	}
+
+	if !processor.IsComputed("conversation_metrics") || processor.IsComputed("sentiment") {
+		t.Error("expected only conversation_metrics to be computed")
+	}
+}
@@ -1,9 +1,18 @@
 package builtin
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
 	"github.com/eisenzopf/agentic-text/pkg/processor"
 )
 
+// QuestionsInput is the structured input holding the research questions data_analyzer
+// answers: a field of JSON content, a metadata key or an Options.Inputs key. It accepts text,
+// a list of questions or a question_generator result.
+const QuestionsInput = "questions"
+
 // AnalysisAnswer represents a single answer to a research question
 type AnalysisAnswer struct {
 	// Question is the research question being answered
@@ -16,6 +25,10 @@ type AnalysisAnswer struct {
 	Confidence string `json:"confidence"`
 	// SupportingData provides evidence from the data that supports this answer
 	SupportingData string `json:"supporting_data"`
+	// Evidence are the passages of the data supporting the answer, quoted verbatim
+	Evidence []string `json:"evidence,omitempty"`
+	// DataGaps are the data missing to answer this question fully
+	DataGaps []string `json:"data_gaps,omitempty"`
 }
 
 // Pattern represents an identified pattern in the data
@@ -44,6 +57,74 @@ type DataAnalysisResult struct {
 	ProcessorType string `json:"processor_type"`
 }
 
+// formatQuestions renders the research questions as a numbered list; text is used as is
+func formatQuestions(value interface{}) (string, error) {
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	var questions []string
+	if json.Unmarshal(encoded, &questions) != nil {
+		var generated []ResearchQuestion
+		var result QuestionGenerationResult
+		if json.Unmarshal(encoded, &generated) != nil {
+			if json.Unmarshal(encoded, &result) != nil || result.Questions == nil {
+				return "", fmt.Errorf("questions must be text, a list of questions or a question_generator result")
+			}
+			generated = result.Questions
+		}
+		for _, question := range generated {
+			questions = append(questions, question.Question)
+		}
+	}
+
+	var lines []string
+	for _, question := range questions {
+		if question = strings.TrimSpace(question); question != "" {
+			lines = append(lines, fmt.Sprintf("%d. %s", len(lines)+1, question))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatExtractedAttributes renders attributes extracted by get_attributes as one line per
+// attribute; text is used as is
+func formatExtractedAttributes(value interface{}) (string, error) {
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	var attributes []Attribute
+	if json.Unmarshal(encoded, &attributes) != nil {
+		var result AttributeResult
+		if json.Unmarshal(encoded, &result) != nil {
+			return "", fmt.Errorf("attributes must be a list of extracted attributes or a get_attributes result")
+		}
+		attributes = result.Attributes
+	}
+
+	var lines []string
+	for _, attribute := range attributes {
+		if attribute.FieldName == "" {
+			continue
+		}
+		line := fmt.Sprintf("- %s: %s", attribute.FieldName, attribute.Value)
+		if attribute.Confidence > 0 {
+			line += fmt.Sprintf(" (confidence %.2f)", attribute.Confidence)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
 // Register the processor with the registry
 func init() {
 	processor.NewBuilder("data_analyzer").
@@ -51,12 +132,16 @@ func init() {
 		WithContentTypes("text", "json").
 		WithRole("You are an expert data analyst specializing in contact center analytics and customer service research").
 		WithObjective("Analyze customer service data to answer research questions, identify patterns, and provide actionable insights with supporting evidence").
+		WithInput(QuestionsInput, "Research Questions", formatQuestions).
+		WithInput(AttributesInput, "Extracted Attributes", formatExtractedAttributes).
 		WithInstructions(
-			"Analyze the provided data against the research questions",
+			"Analyze the provided data, including any Extracted Attributes, against the research questions",
+			"Answer each question listed under Research Questions separately and in order; if none are listed, answer the questions found in the Input Text",
 			"Provide specific, detailed answers citing the data as evidence",
+			"Quote the passages of the data supporting each answer word for word in its 'evidence', without paraphrasing",
 			"Identify key quantifiable metrics that support each answer",
 			"Assess confidence levels (High/Medium/Low) based on data quality and sample size",
-			"Identify any data gaps or limitations that affect the analysis",
+			"List the data missing to answer each question fully in its 'data_gaps', and gaps affecting the whole analysis in the top-level 'data_gaps'",
 			"Look for patterns and trends in the data that provide additional insights",
 			"Ensure all answers are supported by concrete evidence from the dataset",
 		).
@@ -6,8 +6,14 @@
 //
 // Available processors:
 // - sentiment: Analyzes the sentiment of text, returning sentiment type, score, confidence, and keywords
+// - aspect_sentiment: Analyzes the sentiment toward each aspect, such as price or support quality, with quotes
+// - compare: Compares two texts, listing agreements, contradictions and missing elements with a similarity score
+// - tags: Assigns every applicable tag from a tag set supplied at runtime
 // - intent: Identifies the primary intent in customer service conversations
 // - keyword_extraction: Extracts important keywords from text with relevance scores and categories
 // - required_attributes: Identifies data attributes required to answer a set of questions
 // - get_attributes: Extracts attribute values from text based on the identified attributes
+// - outcome_prediction: Predicts the likely next events of a case with probabilities and supporting signals
+// - data_analyzer: Answers research questions with evidence, confidence and data gaps per question
+// - conversation_metrics: Computes talk ratios, turns, interruptions and silence gaps without an LLM
 package builtin
@@ -0,0 +1,16 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/processor/processortest"
+)
+
+// TestRecordedResponses replays the recorded provider responses in testdata/fixtures
+// through every builtin processor and compares the results with the expected ones. After an
+// intended change to response handling, update the expected results with:
+//
+//	go test ./pkg/processor/builtin -run TestRecordedResponses -update-results
+func TestRecordedResponses(t *testing.T) {
+	processortest.ReplayFixtures(t, processortest.ReplayConfig{})
+}
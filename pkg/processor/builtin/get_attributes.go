@@ -1,13 +1,26 @@
 package builtin
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/eisenzopf/agentic-text/pkg/processor"
 )
 
+// AttributesInput is the structured input holding the attribute definitions to extract: a
+// field of JSON content, a metadata key or an Options.Inputs key. It accepts a list of
+// AttributeDefinition, a required_attributes result, a list of field names or their JSON.
+const AttributesInput = "attributes"
+
 // AttributeResult contains the extracted attributes
 type AttributeResult struct {
 	// Attributes is an array of extracted attributes
 	Attributes []Attribute `json:"attributes,omitempty"`
+	// Violations are the extracted values that didn't conform to their attribute's type,
+	// whose values were cleared
+	Violations []TypeViolation `json:"violations,omitempty" prompt:"-"`
 	// ProcessorType is the type of processor that generated this result
 	ProcessorType string `json:"processor_type"`
 }
@@ -54,6 +67,77 @@ type Attribute struct {
 	Confidence float64 `json:"confidence"`
 	// Explanation provides context for this specific attribute
 	Explanation string `json:"explanation"`
+	// Type is the type the attribute's definition declares, if it has one
+	Type string `json:"type,omitempty" prompt:"-"`
+}
+
+// attributeDefinitions parses the attribute definitions to extract from the attributes
+// input; text that isn't JSON is returned as an error
+func attributeDefinitions(value interface{}) ([]AttributeDefinition, error) {
+	raw, ok := value.(string)
+	if !ok {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		raw = string(encoded)
+	} else if !json.Valid([]byte(raw)) {
+		return nil, errTextDefinitions
+	}
+
+	var definitions []AttributeDefinition
+	if err := json.Unmarshal([]byte(raw), &definitions); err != nil {
+		var result RequiredAttributesResult
+		var names []string
+		if json.Unmarshal([]byte(raw), &result) == nil && result.Attributes != nil {
+			definitions = result.Attributes
+		} else if json.Unmarshal([]byte(raw), &names) == nil {
+			for _, name := range names {
+				definitions = append(definitions, AttributeDefinition{FieldName: name})
+			}
+		} else {
+			return nil, fmt.Errorf("attribute definitions must be a list of definitions or field names, or a required_attributes result")
+		}
+	}
+
+	kept := definitions[:0]
+	for _, definition := range definitions {
+		if definition.FieldName != "" {
+			kept = append(kept, definition)
+		}
+	}
+	return kept, nil
+}
+
+// errTextDefinitions is returned by attributeDefinitions for definitions given as text
+var errTextDefinitions = errors.New("attribute definitions are text")
+
+// formatAttributeDefinitions renders the attribute definitions to extract as one line per
+// attribute, with its type; text that isn't JSON is used as is
+func formatAttributeDefinitions(value interface{}) (string, error) {
+	definitions, err := attributeDefinitions(value)
+	if errors.Is(err, errTextDefinitions) {
+		return value.(string), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, definition := range definitions {
+		line := "- " + definition.FieldName
+		if definition.Title != "" {
+			line += fmt.Sprintf(" (%s)", definition.Title)
+		}
+		if definition.Description != "" {
+			line += ": " + definition.Description
+		}
+		if hint := definition.typeHint(); hint != "" {
+			line += fmt.Sprintf(" [%s]", hint)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
 }
 
 // Register the processor with the registry
@@ -63,14 +147,17 @@ func init() {
 		WithContentTypes("text", "json").
 		WithRole("You are an expert at extracting structured information from text").
 		WithObjective("Analyze the provided text and extract relevant attributes and their values").
+		WithInput(AttributesInput, "Required Attributes", formatAttributeDefinitions).
 		WithInstructions(
 			"Carefully read and interpret the Input Text",
-			"If the input appears to be JSON containing required attributes, use those as a guide to extract values",
-			"Extract any relevant attributes and their values based on the required structure",
+			"If Required Attributes are listed, extract a value for each of them using its field name, and no other attributes",
+			"Otherwise, extract any relevant attributes and their values, using required attributes given as JSON in the input as a guide",
 			"For each attribute, provide a field name (in snake_case), the extracted value, a confidence score (0.0 to 1.0), and a brief explanation",
+			"Give each value in the form shown in brackets after its required attribute, if any, and leave the value empty if the text doesn't state it",
 			"Assign an overall confidence score for the extraction",
 			"Provide a brief overall explanation of how the attributes were determined",
 			"Format your entire output as a single, valid JSON object",
 		).
+		WithResultValidator(validateAttributeTypes).
 		Register()
 }
@@ -7,11 +7,15 @@ import (
 // IntentItem represents a single identified customer intent.
 type IntentItem struct {
 	// LabelName is a natural language label describing the customer's intent (title case, 2-3 words)
-	LabelName string `json:"label_name" default:"Unclear Intent"`
+	LabelName string `json:"label_name" default:"Unclear Intent" example:"Billing Dispute|Cancel Subscription"`
 	// Label is a machine-readable version of LabelName (snake_case)
-	Label string `json:"label" default:"unclear_intent"`
+	Label string `json:"label" default:"unclear_intent" example:"billing_dispute|cancel_subscription"`
 	// Description is a concise description of the customer's intent (1-2 sentences)
-	Description string `json:"description" default:"The conversation transcript is unclear or does not contain a discernible customer service request."`
+	Description string `json:"description" default:"The conversation transcript is unclear or does not contain a discernible customer service request." example:"The customer is disputing a duplicate charge on their latest bill.|The customer wants to cancel their subscription at the end of the billing period."`
+	// Confidence is how clearly the conversation shows the intent (0.0 to 1.0)
+	Confidence float64 `json:"confidence" default:"0.0" example:"0.9|0.6"`
+	// Evidence are the customer utterances supporting the intent, quoted verbatim
+	Evidence []string `json:"evidence,omitempty" example:"I was charged twice this month|I want to cancel"`
 }
 
 // IntentResult contains a list of identified customer intents.
@@ -26,7 +30,8 @@ type IntentResult struct {
 func init() {
 	processor.NewBuilder("intent").
 		WithStruct(&IntentResult{}).
-		WithContentTypes("text", "json").
+		WithContentTypes("text", "json", "transcript").
+		WithNotApplicable("the text contains no customer request at all, such as a wrong number, a test message or an automated notice").
 		WithRole("You are a helpful AI assistant specializing in classifying customer service conversations").
 		WithObjective("Analyze a provided conversation transcript and identify *all* distinct customer intents expressed").
 		WithInstructions(
@@ -35,11 +40,14 @@ func init() {
 			"Keep the 'label_name' to 2-3 words (Title Case) and the 'description' brief and to the point (1-2 sentences)",
 			"Be as specific as possible in the description for each intent",
 			"Don't just say 'billing issue.' Say 'The customer is disputing a charge on their latest bill.'",
+			"Give each intent a 'confidence' from 0.0 to 1.0: high when the customer states it explicitly, lower when it is only implied",
+			"Quote the customer utterance(s) supporting each intent word for word in 'evidence', without paraphrasing",
 			"Do not hallucinate information. Base the classification solely on the provided transcript",
 		).
 		WithCustomSection("Important Constraints", `
 - Do not respond in a conversational manner
 - Your entire response should be only the requested JSON
 - If the input appears to be in JSON format, focus on the text content and ignore the JSON structure`).
+		WithTemperature(0).
 		Register()
 }
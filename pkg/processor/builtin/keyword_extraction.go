@@ -26,6 +26,7 @@ type KeywordResult struct {
 func init() {
 	processor.NewBuilder("keyword_extraction").
 		WithStruct(&KeywordResult{}).
+		WithContentTypes("text", "html", "markdown", "pdf_text", "transcript").
 		WithRole("You are an expert at extracting important keywords from text").
 		WithObjective("Analyze the provided text and extract the most meaningful keywords").
 		WithInstructions(
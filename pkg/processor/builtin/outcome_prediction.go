@@ -0,0 +1,94 @@
+package builtin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// OutcomesInput is the structured input listing the outcomes outcome_prediction considers,
+// as a list or a comma-separated string: a field of JSON content, a metadata key or an
+// Options.Inputs key
+const OutcomesInput = "outcomes"
+
+// defaultOutcomes are the outcomes outcome_prediction considers when none are given
+var defaultOutcomes = []string{"callback", "escalation", "chargeback", "cancellation", "resolved"}
+
+// OutcomeSignal is a sign in the text pointing to an outcome
+type OutcomeSignal struct {
+	// Signal describes the sign, e.g. "customer threatens to dispute the charge"
+	Signal string `json:"signal"`
+	// Quote is the passage showing the sign, quoted verbatim
+	Quote string `json:"quote,omitempty"`
+}
+
+// OutcomePrediction is a likely next event for the case
+type OutcomePrediction struct {
+	// Outcome is a machine-readable label of the event (snake_case), e.g. chargeback
+	Outcome string `json:"outcome"`
+	// Description explains the predicted event in one sentence
+	Description string `json:"description"`
+	// Probability is the likelihood of the event (0.0 to 1.0)
+	Probability float64 `json:"probability" default:"0.0"`
+	// Timeframe is when the event is expected, e.g. "within 7 days", if the text suggests one
+	Timeframe string `json:"timeframe,omitempty"`
+	// Signals are the signs in the text supporting the prediction
+	Signals []OutcomeSignal `json:"signals,omitempty"`
+	// RecommendedAction is what could be done now to prevent or prepare for the event
+	RecommendedAction string `json:"recommended_action,omitempty"`
+}
+
+// OutcomePredictionResult contains the predicted next events of a case, most likely first
+type OutcomePredictionResult struct {
+	// Predictions are the likely next events, most likely first
+	Predictions []OutcomePrediction `json:"predictions"`
+	// ProcessorType is the type of processor that generated this result
+	ProcessorType string `json:"processor_type"`
+}
+
+// formatOutcomes renders the outcomes to consider as a bulleted list
+func formatOutcomes(value interface{}) (string, error) {
+	var outcomes []string
+	switch v := value.(type) {
+	case string:
+		outcomes = strings.Split(v, ",")
+	case []string:
+		outcomes = v
+	case []interface{}:
+		for _, outcome := range v {
+			outcomes = append(outcomes, fmt.Sprint(outcome))
+		}
+	default:
+		return "", fmt.Errorf("outcomes must be a list or a comma-separated string")
+	}
+
+	var lines []string
+	for _, outcome := range outcomes {
+		if outcome = strings.TrimSpace(outcome); outcome != "" {
+			lines = append(lines, "- "+outcome)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Register the processor with the registry
+func init() {
+	processor.NewBuilder("outcome_prediction").
+		WithStruct(&OutcomePredictionResult{}).
+		WithContentTypes("text", "json", "transcript").
+		WithRole("You are an expert customer service analyst predicting how cases develop that ONLY outputs valid JSON").
+		WithObjective("Predict the likely next events for the case described in the provided text, such as the customer calling back, a dispute escalating to a chargeback or a cancellation completing, so they can be acted on before they happen").
+		WithInput(OutcomesInput, "Outcomes", formatOutcomes).
+		WithInstructions(
+			"Carefully read and interpret the Input Text",
+			fmt.Sprintf("Consider the outcomes listed under Outcomes; if none are listed, consider %s", strings.Join(defaultOutcomes, ", ")),
+			"Only predict outcomes the text gives signals for, most likely first; return an empty list if there are none",
+			"Give each prediction a 'probability' from 0.0 to 1.0, calibrated so that predictions at 0.7 come true about 70% of the time",
+			"Support each prediction with the signals in the text, quoting the relevant passage word for word in 'quote'",
+			"Give a 'timeframe' only when the text suggests one",
+			"Suggest a 'recommended_action' that could prevent an unwanted outcome or prepare for it",
+			"Format your entire output as a single, valid JSON object conforming to the structure below",
+		).
+		Register()
+}
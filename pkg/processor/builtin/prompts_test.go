@@ -0,0 +1,82 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/processor/processortest"
+)
+
+// TestPromptSnapshots fails when a builtin processor's prompt changes. After an intended
+// change, update the golden files with:
+//
+//	go test ./pkg/processor/builtin -run TestPromptSnapshots -update-prompts
+func TestPromptSnapshots(t *testing.T) {
+	processortest.GoldenPrompts(t, processortest.GoldenConfig{})
+}
+
+// TestAttributeDefinitionPrompts snapshots the prompt of get_attributes given the result
+// of required_attributes as a structured input
+func TestAttributeDefinitionPrompts(t *testing.T) {
+	processortest.GoldenPrompts(t, processortest.GoldenConfig{
+		Processors: []string{"get_attributes"},
+		Inputs: []processortest.Input{{
+			Name: "definitions",
+			Text: processortest.CanonicalInputs[2].Text,
+			Metadata: map[string]interface{}{
+				AttributesInput: RequiredAttributesResult{Attributes: []AttributeDefinition{
+					{FieldName: "issue_type", Title: "Issue Type", Description: "The kind of problem the customer reports",
+						Type: AttributeEnum, AllowedValues: []string{"billing", "technical", "account"}},
+					{FieldName: "repeat_contact", Title: "Repeat Contact", Description: "Whether the customer called about the issue before",
+						Type: AttributeBoolean},
+				}},
+			},
+		}},
+	})
+}
+
+// TestResearchQuestionPrompts snapshots the prompt of data_analyzer given research questions
+// and the attributes extracted by get_attributes as structured inputs
+func TestResearchQuestionPrompts(t *testing.T) {
+	processortest.GoldenPrompts(t, processortest.GoldenConfig{
+		Processors: []string{"data_analyzer"},
+		Inputs: []processortest.Input{{
+			Name: "questions",
+			Text: processortest.CanonicalInputs[2].Text,
+			Metadata: map[string]interface{}{
+				QuestionsInput: []string{"Why did the customer call?", "Was the issue resolved on the first contact?"},
+				AttributesInput: AttributeResult{Attributes: []Attribute{
+					{FieldName: "issue_type", Value: "billing", Confidence: 0.9},
+					{FieldName: "repeat_contact", Value: "yes", Confidence: 0.7},
+				}},
+			},
+		}},
+	})
+}
+
+// TestReferencePrompts snapshots the prompt of compare given the reference text as a
+// structured input
+func TestReferencePrompts(t *testing.T) {
+	processortest.GoldenPrompts(t, processortest.GoldenConfig{
+		Processors: []string{"compare"},
+		Inputs: []processortest.Input{{
+			Name:     "reference",
+			Text:     "Thanks for reaching out. I've refunded your order in full, and you can keep the item.",
+			Metadata: map[string]interface{}{ReferenceInput: "Orders may be refunded in full once the item is returned."},
+		}},
+	})
+}
+
+// TestTagPrompts snapshots the prompt of tags given the allowed tags as a structured input
+func TestTagPrompts(t *testing.T) {
+	processortest.GoldenPrompts(t, processortest.GoldenConfig{
+		Processors: []string{"tags"},
+		Inputs: []processortest.Input{{
+			Name: "allowed",
+			Text: processortest.CanonicalInputs[1].Text,
+			Metadata: map[string]interface{}{TagsInput: []TagDefinition{
+				{Tag: "billing_error", Description: "The customer was charged incorrectly"},
+				{Tag: "refund_request"},
+			}},
+		}},
+	})
+}
@@ -45,6 +45,7 @@ func init() {
 	processor.NewBuilder("recommendation_engine").
 		WithStruct(&RecommendationResult{}).
 		WithContentTypes("text", "json").
+		WithMaxOutputTokens(4096).
 		WithRole("You are an expert business consultant specializing in contact center operations, customer service optimization, and organizational improvement").
 		WithObjective("Generate specific, actionable recommendations based on data analysis that will improve business outcomes, customer satisfaction, and operational efficiency").
 		WithInstructions(
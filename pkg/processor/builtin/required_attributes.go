@@ -10,6 +10,11 @@ type AttributeDefinition struct {
 	Title       string `json:"title" default:"Unknown"`                                                         // Human readable title
 	Description string `json:"description" default:"Unable to determine required attributes from the response"` // Detailed description of the attribute
 	Rationale   string `json:"rationale" default:"The response did not contain valid attribute definitions"`    // Why this attribute is needed
+	// Type is the type of the attribute's values: string, number, date, enum or boolean
+	// (defaults to string); get_attributes coerces extracted values to it
+	Type string `json:"type,omitempty"`
+	// AllowedValues are the values an enum or string attribute may take
+	AllowedValues []string `json:"allowed_values,omitempty"`
 }
 
 // RequiredAttributesResult contains the required attributes results
@@ -34,6 +39,7 @@ func init() {
 			"Provide a human-readable title for each attribute",
 			"Give a clear description of what the attribute represents",
 			"Explain the rationale for why this attribute is needed",
+			"Declare the type of each attribute's values as string, number, date, enum or boolean, and list the allowed values of enum attributes",
 			"Format your entire output as a single, valid JSON object conforming to the structure below",
 		).
 		Register()
@@ -1,14 +1,22 @@
 package builtin
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
 	"github.com/eisenzopf/agentic-text/pkg/processor"
 )
 
 // SentimentResult contains the sentiment analysis results
 type SentimentResult struct {
-	// Sentiment is the overall sentiment (positive, negative, neutral)
+	// Sentiment is the overall sentiment, one of the labels of the scale (positive, negative,
+	// neutral by default)
 	Sentiment string `json:"sentiment" default:"unknown"`
-	// Score is the sentiment score (-1.0 to 1.0)
+	// Score is the sentiment score in the range of the scale (-1.0 to 1.0 by default)
 	Score float64 `json:"score" default:"0.0"`
 	// Confidence is the confidence level (0.0 to 1.0)
 	Confidence float64 `json:"confidence" default:"0.0"`
@@ -18,19 +26,179 @@ type SentimentResult struct {
 	ProcessorType string `json:"processor_type"`
 }
 
-// Register the processor with the registry
+// SentimentScaleInput is the structured input naming the scale sentiment reports on, one of
+// SentimentScales: a field of JSON content, a metadata key or an Options.Inputs key
+const SentimentScaleInput = "sentiment_scale"
+
+// DefaultSentimentScale is the scale used when no scale is given
+const DefaultSentimentScale = "three_class"
+
+// SentimentScale is a scale the sentiment processor reports on, so results match the schema
+// downstream systems already use
+type SentimentScale struct {
+	// Labels are the sentiment values allowed, from most negative to most positive
+	Labels []string
+	// MinScore and MaxScore bound the score, from most negative to most positive
+	MinScore float64
+	MaxScore float64
+}
+
+// SentimentScales are the scales sentiment can report on, by name: binary and three_class
+// labels with a score from -1 to 1, or a five_star rating. Add to them before creating
+// processors to support another scale.
+var SentimentScales = map[string]SentimentScale{
+	"binary":      {Labels: []string{"negative", "positive"}, MinScore: -1, MaxScore: 1},
+	"three_class": {Labels: []string{"negative", "neutral", "positive"}, MinScore: -1, MaxScore: 1},
+	"five_star":   {Labels: []string{"1", "2", "3", "4", "5"}, MinScore: 1, MaxScore: 5},
+}
+
+// sentimentScale returns the scale named by the item's sentiment_scale input
+func sentimentScale(value interface{}) (SentimentScale, error) {
+	name, ok := value.(string)
+	if !ok {
+		return SentimentScale{}, fmt.Errorf("sentiment scale must be a string")
+	}
+	scale, ok := SentimentScales[name]
+	if !ok {
+		names := make([]string, 0, len(SentimentScales))
+		for name := range SentimentScales {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return SentimentScale{}, fmt.Errorf("unknown sentiment scale %q (expected one of %s)", name, strings.Join(names, ", "))
+	}
+	return scale, nil
+}
+
+// formatSentimentScale renders the labels and score range of the scale to use
+func formatSentimentScale(value interface{}) (string, error) {
+	scale, err := sentimentScale(value)
+	if err != nil {
+		return "", err
+	}
+	labels := make([]string, len(scale.Labels))
+	for i, label := range scale.Labels {
+		labels[i] = "'" + label + "'"
+	}
+	return fmt.Sprintf("Sentiment labels, from most negative to most positive: %s\nScore range: %g (most negative) to %g (most positive)",
+		strings.Join(labels, ", "), scale.MinScore, scale.MaxScore), nil
+}
+
+// validateSentimentScale replaces a sentiment outside the item's scale with "unknown" and
+// clamps the score to the scale's range
+func validateSentimentScale(ctx context.Context, result interface{}) error {
+	sentiment, ok := result.(*SentimentResult)
+	if !ok {
+		return nil
+	}
+	scale := SentimentScales[DefaultSentimentScale]
+	if value, ok := processor.PromptInput(ctx, SentimentScaleInput); ok {
+		var err error
+		if scale, err = sentimentScale(value); err != nil {
+			return err
+		}
+	}
+
+	label := strings.ToLower(strings.TrimSpace(sentiment.Sentiment))
+	if slices.Contains(scale.Labels, label) {
+		sentiment.Sentiment = label
+	} else {
+		sentiment.Sentiment = "unknown"
+	}
+	sentiment.Score = math.Max(scale.MinScore, math.Min(scale.MaxScore, sentiment.Score))
+	return nil
+}
+
+// AspectsInput is the structured input naming the aspects aspect_sentiment rates, as a list
+// or a comma-separated string: a field of JSON content, a metadata key or an Options.Inputs key
+const AspectsInput = "aspects"
+
+// defaultSentimentAspects are the aspects aspect_sentiment rates when no aspects are given
+var defaultSentimentAspects = []string{"price", "support quality", "product", "billing"}
+
+// AspectSentiment is the sentiment toward one aspect of the text
+type AspectSentiment struct {
+	// Aspect is the aspect, e.g. price or support quality
+	Aspect string `json:"aspect"`
+	// Sentiment is the sentiment toward the aspect (positive, negative, neutral)
+	Sentiment string `json:"sentiment" default:"unknown"`
+	// Score is the sentiment score toward the aspect (-1.0 to 1.0)
+	Score float64 `json:"score" default:"0.0"`
+	// Confidence is the confidence level (0.0 to 1.0)
+	Confidence float64 `json:"confidence" default:"0.0"`
+	// Quotes are the passages about the aspect, quoted verbatim
+	Quotes []string `json:"quotes,omitempty"`
+}
+
+// AspectSentimentResult contains the sentiment toward each aspect the text discusses
+type AspectSentimentResult struct {
+	// Aspects are the aspects the text discusses, each with its own sentiment
+	Aspects []AspectSentiment `json:"aspects"`
+	// ProcessorType is the type of processor that generated this result
+	ProcessorType string `json:"processor_type"`
+}
+
+// formatAspects renders the aspects to rate as a bulleted list
+func formatAspects(value interface{}) (string, error) {
+	var aspects []string
+	switch v := value.(type) {
+	case string:
+		aspects = strings.Split(v, ",")
+	case []string:
+		aspects = v
+	case []interface{}:
+		for _, aspect := range v {
+			aspects = append(aspects, fmt.Sprint(aspect))
+		}
+	default:
+		return "", fmt.Errorf("aspects must be a list or a comma-separated string")
+	}
+
+	var lines []string
+	for _, aspect := range aspects {
+		if aspect = strings.TrimSpace(aspect); aspect != "" {
+			lines = append(lines, "- "+aspect)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Register the processors with the registry
 func init() {
 	processor.NewBuilder("sentiment").
 		WithStruct(&SentimentResult{}).
+		WithContentTypes("text", "html", "markdown", "pdf_text", "transcript").
 		WithRole("You are an expert sentiment analysis tool that ONLY outputs valid JSON").
 		WithObjective("Analyze the sentiment expressed in the provided text accurately and objectively. Consider the overall tone, specific word choices, context, and potential nuances like sarcasm or mixed feelings").
+		WithInput(SentimentScaleInput, "Scale", formatSentimentScale).
 		WithInstructions(
 			"Carefully read and interpret the Input Text",
-			"Determine the primary sentiment: 'positive', 'negative', or 'neutral'",
-			"Assign a precise sentiment score between -1.0 (most negative) and 1.0 (most positive)",
+			"Determine the primary sentiment: 'positive', 'negative', or 'neutral', unless the Scale section lists other labels",
+			"Assign a precise sentiment score between -1.0 (most negative) and 1.0 (most positive), unless the Scale section gives another range",
 			"Assess your confidence in the analysis on a scale of 0.0 to 1.0",
 			"Extract up to 5 keywords or short phrases most representative of the sentiment",
 			"Format your entire output as a single, valid JSON object conforming to the structure below",
 		).
+		WithResultValidator(validateSentimentScale).
+		WithTemperature(0).
+		Register()
+
+	processor.NewBuilder("aspect_sentiment").
+		WithStruct(&AspectSentimentResult{}).
+		WithContentTypes("text", "json", "html", "markdown", "pdf_text", "transcript").
+		WithRole("You are an expert aspect-based sentiment analysis tool that ONLY outputs valid JSON").
+		WithObjective("Analyze the sentiment expressed toward each aspect of the product or service discussed in the provided text, instead of one overall sentiment").
+		WithInput(AspectsInput, "Aspects", formatAspects).
+		WithInstructions(
+			"Carefully read and interpret the Input Text",
+			fmt.Sprintf("Rate the aspects listed under Aspects; if none are listed, rate %s", strings.Join(defaultSentimentAspects, ", ")),
+			"Only include aspects the text actually discusses; omit the others rather than guessing",
+			"For each aspect, determine its sentiment: 'positive', 'negative', or 'neutral'",
+			"Assign each aspect a precise sentiment score between -1.0 (most negative) and 1.0 (most positive)",
+			"Assess your confidence in each aspect's analysis on a scale of 0.0 to 1.0",
+			"Quote the passages about each aspect word for word in 'quotes', without paraphrasing",
+			"Format your entire output as a single, valid JSON object conforming to the structure below",
+		).
+		WithTemperature(0).
 		Register()
 }
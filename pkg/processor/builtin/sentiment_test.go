@@ -0,0 +1,57 @@
+package builtin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+func TestSentimentScales(t *testing.T) {
+	tests := []struct {
+		name          string
+		scale         interface{}
+		response      string
+		wantSentiment string
+		wantScore     float64
+		wantErr       bool
+	}{
+		{name: "default scale", response: `{"sentiment": "Positive", "score": 0.8}`, wantSentiment: "positive", wantScore: 0.8},
+		{name: "label outside the default scale", response: `{"sentiment": "4", "score": 4}`, wantSentiment: "unknown", wantScore: 1},
+		{name: "binary", scale: "binary", response: `{"sentiment": "negative", "score": -0.6}`, wantSentiment: "negative", wantScore: -0.6},
+		{name: "neutral outside binary", scale: "binary", response: `{"sentiment": "neutral", "score": 0}`, wantSentiment: "unknown", wantScore: 0},
+		{name: "five star", scale: "five_star", response: `{"sentiment": "4", "score": 4}`, wantSentiment: "4", wantScore: 4},
+		{name: "score clamped to five star", scale: "five_star", response: `{"sentiment": "positive", "score": 0.8}`, wantSentiment: "unknown", wantScore: 1},
+		{name: "unknown scale", scale: "ten_point", response: `{"sentiment": "positive", "score": 0.8}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := processor.NewDefaultOptions()
+			if tt.scale != nil {
+				options = options.WithInput(SentimentScaleInput, tt.scale)
+			}
+			proc, err := processor.Create("sentiment", llm.NewMockProviderWithResponse(tt.response), options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "The support was great", nil))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := result.ProcessingInfo["sentiment"].(map[string]interface{})
+			if info["sentiment"] != tt.wantSentiment || info["score"] != tt.wantScore {
+				t.Errorf("expected %s with score %g, got %v with score %v", tt.wantSentiment, tt.wantScore, info["sentiment"], info["score"])
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ type SpeechActResult struct {
 func init() {
 	processor.NewBuilder("speech_act").
 		WithStruct(&SpeechActResult{}).
+		WithContentTypes("text", "transcript").
 		WithRole("You are an expert at identifying distinct speech acts within a text").
 		WithObjective("Analyze the provided text and identify all distinct speech acts (like questions, requests, statements, greetings, etc.). For each identified speech act, provide its category, complexity, and relevant keywords").
 		WithInstructions(
@@ -36,5 +37,6 @@ func init() {
 			"Ensure the 'keywords' field for each speech act is a JSON array of strings",
 			"If no relevant keywords are found for a specific speech act, use an empty array []",
 		).
+		WithTemperature(0).
 		Register()
 }
@@ -0,0 +1,170 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// TagsInput is the structured input holding the tags the tags processor may assign: a field
+// of JSON content, a metadata key or an Options.Inputs key. It accepts a comma-separated
+// string, a list of tags, a list of {"tag", "description"} objects or a map of tags to
+// descriptions.
+const TagsInput = "tags"
+
+// TagMatch is a tag that applies to the text
+type TagMatch struct {
+	// Tag is the tag, spelled as in the allowed tags
+	Tag string `json:"tag"`
+	// Confidence is how clearly the tag applies (0.0 to 1.0)
+	Confidence float64 `json:"confidence" default:"0.0"`
+	// Evidence is the passage showing the tag applies, quoted verbatim
+	Evidence string `json:"evidence,omitempty"`
+}
+
+// TagsResult contains the tags that apply to the text
+type TagsResult struct {
+	// Tags are the tags that apply, most confident first
+	Tags []TagMatch `json:"tags"`
+	// ProcessorType is the type of processor that generated this result
+	ProcessorType string `json:"processor_type"`
+}
+
+// TagDefinition is a tag the tags processor may assign
+type TagDefinition struct {
+	// Tag is the tag itself
+	Tag string `json:"tag"`
+	// Description explains when the tag applies
+	Description string `json:"description,omitempty"`
+}
+
+// tagDefinitions parses the allowed tags from the tags input
+func tagDefinitions(value interface{}) ([]TagDefinition, error) {
+	var definitions []TagDefinition
+	switch v := value.(type) {
+	case string:
+		for _, tag := range strings.Split(v, ",") {
+			definitions = append(definitions, TagDefinition{Tag: tag})
+		}
+	case []string:
+		for _, tag := range v {
+			definitions = append(definitions, TagDefinition{Tag: tag})
+		}
+	case map[string]string:
+		for tag, description := range v {
+			definitions = append(definitions, TagDefinition{Tag: tag, Description: description})
+		}
+	case map[string]interface{}:
+		for tag, description := range v {
+			definitions = append(definitions, TagDefinition{Tag: tag, Description: fmt.Sprint(description)})
+		}
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		var tags []string
+		if json.Unmarshal(encoded, &tags) == nil {
+			return tagDefinitions(tags)
+		}
+		if err := json.Unmarshal(encoded, &definitions); err != nil {
+			return nil, fmt.Errorf("tags must be a list of tags or tag definitions, or a map of tags to descriptions")
+		}
+	}
+
+	kept := definitions[:0]
+	for _, definition := range definitions {
+		if definition.Tag = strings.TrimSpace(definition.Tag); definition.Tag != "" {
+			kept = append(kept, definition)
+		}
+	}
+	// Maps have no order; sort them so the prompt is the same on every run
+	if _, ok := value.(map[string]string); ok {
+		sort.Slice(kept, func(i, j int) bool { return kept[i].Tag < kept[j].Tag })
+	} else if _, ok := value.(map[string]interface{}); ok {
+		sort.Slice(kept, func(i, j int) bool { return kept[i].Tag < kept[j].Tag })
+	}
+	return kept, nil
+}
+
+// formatTags renders the allowed tags as a bulleted list with their descriptions
+func formatTags(value interface{}) (string, error) {
+	definitions, err := tagDefinitions(value)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, len(definitions))
+	for i, definition := range definitions {
+		lines[i] = "- " + definition.Tag
+		if definition.Description != "" {
+			lines[i] += ": " + definition.Description
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// validateTags keeps the tags allowed for the item, spelled as given, and drops duplicates.
+// Without allowed tags, any tag is kept.
+func validateTags(ctx context.Context, result interface{}) error {
+	tags, ok := result.(*TagsResult)
+	if !ok {
+		return nil
+	}
+
+	var allowed map[string]string
+	if value, ok := processor.PromptInput(ctx, TagsInput); ok {
+		definitions, err := tagDefinitions(value)
+		if err != nil {
+			return err
+		}
+		allowed = make(map[string]string, len(definitions))
+		for _, definition := range definitions {
+			allowed[strings.ToLower(definition.Tag)] = definition.Tag
+		}
+	}
+
+	seen := make(map[string]bool, len(tags.Tags))
+	kept := make([]TagMatch, 0, len(tags.Tags))
+	for _, match := range tags.Tags {
+		key := strings.ToLower(strings.TrimSpace(match.Tag))
+		if allowed != nil {
+			tag, ok := allowed[key]
+			if !ok {
+				continue
+			}
+			match.Tag = tag
+		}
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, match)
+	}
+	tags.Tags = kept
+	return nil
+}
+
+// Register the processor with the registry
+func init() {
+	processor.NewBuilder("tags").
+		WithStruct(&TagsResult{}).
+		WithContentTypes("text", "json", "html", "markdown", "pdf_text", "transcript").
+		WithRole("You are an expert text classifier that assigns every applicable tag and ONLY outputs valid JSON").
+		WithObjective("Assign to the provided text every tag that applies to it, from the tags allowed for it").
+		WithInput(TagsInput, "Allowed Tags", formatTags).
+		WithInstructions(
+			"Carefully read and interpret the Input Text",
+			"Assign every tag listed under Allowed Tags that applies to the text, spelled exactly as listed, and no other tags; if none are listed, assign up to 5 short snake_case tags of your own",
+			"Assign no tags if none apply; do not force a tag that only loosely fits",
+			"Give each tag a 'confidence' from 0.0 to 1.0 and list the tags most confident first",
+			"Quote the passage showing each tag applies word for word in 'evidence'",
+			"Format your entire output as a single, valid JSON object conforming to the structure below",
+		).
+		WithResultValidator(validateTags).
+		WithTemperature(0).
+		Register()
+}
@@ -0,0 +1,60 @@
+package builtin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+func TestTagsValidation(t *testing.T) {
+	response := `{"tags": [
+		{"tag": "Billing_Error", "confidence": 0.9},
+		{"tag": "refund_request", "confidence": 0.7},
+		{"tag": "billing_error", "confidence": 0.5},
+		{"tag": "made_up", "confidence": 0.4}
+	]}`
+
+	tests := []struct {
+		name     string
+		allowed  interface{}
+		wantTags []string
+	}{
+		{name: "list", allowed: []string{"billing_error", "refund_request"}, wantTags: []string{"billing_error", "refund_request"}},
+		{name: "comma-separated", allowed: "billing_error, outage", wantTags: []string{"billing_error"}},
+		{name: "definitions", allowed: []interface{}{
+			map[string]interface{}{"tag": "refund_request", "description": "The customer asks for money back"},
+		}, wantTags: []string{"refund_request"}},
+		{name: "map of descriptions", allowed: map[string]interface{}{"made_up": "Anything else"}, wantTags: []string{"made_up"}},
+		{name: "no allowed tags", wantTags: []string{"Billing_Error", "refund_request", "made_up"}},
+	}
+
+	proc, err := processor.Create("tags", llm.NewMockProviderWithResponse(response), processor.NewDefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var metadata map[string]interface{}
+			if tt.allowed != nil {
+				metadata = map[string]interface{}{TagsInput: tt.allowed}
+			}
+			result, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "I was charged twice", metadata))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tags := result.ProcessingInfo["tags"].(map[string]interface{})["tags"].([]TagMatch)
+			if len(tags) != len(tt.wantTags) {
+				t.Fatalf("expected tags %v, got %+v", tt.wantTags, tags)
+			}
+			for i, tag := range tt.wantTags {
+				if tags[i].Tag != tag {
+					t.Errorf("tag %d: expected %s, got %s", i, tag, tags[i].Tag)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// ChunkingConfig configures the downshift of items whose prompt the provider rejects for
+// exceeding the model's context length: the text is split into chunks processed one call
+// each, and the chunks' responses are merged into a single result
+type ChunkingConfig struct {
+	// MaxTokens is the most estimated tokens of text per chunk, at four characters per
+	// token (defaults to 2000)
+	MaxTokens int `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+}
+
+// withDefaults returns the config with defaults applied
+func (c ChunkingConfig) withDefaults() ChunkingConfig {
+	if c.MaxTokens <= 0 {
+		c.MaxTokens = 2000
+	}
+	return c
+}
+
+// processChunked processes an item whose prompt exceeded the context length in chunks, if
+// the options enable chunking, recording the downshift in its processing info. Otherwise,
+// or if the text can't be split, the item fails with cause, which retries can't fix.
+func (p *BaseProcessor) processChunked(prepared preparedItem, client llm.Client, usage *callUsage, cause error) (*data.ProcessItem, error) {
+	if p.options.Chunking == nil {
+		return nil, data.Permanent(cause)
+	}
+	config := p.options.Chunking.withDefaults()
+	chunks := splitChunks(prepared.text, config.MaxTokens)
+	if len(chunks) < 2 {
+		return nil, data.Permanent(cause)
+	}
+
+	// The rejected call is not charged
+	usage.inputTokens, usage.outputTokens = 0, 0
+	parser := &BaseResponseHandler{
+		ProcessorType:    p.name,
+		DefaultResponder: func() interface{} { return map[string]interface{}{} },
+	}
+	prompts := make([]string, 0, len(chunks))
+	responses := make([]interface{}, 0, len(chunks))
+	for i, chunk := range chunks {
+		prompt := chunk
+		if p.promptGenerator != nil {
+			var err error
+			prompt, err = p.promptGenerator.GeneratePrompt(prepared.ctx, chunk)
+			if err != nil {
+				return nil, err
+			}
+		}
+		prompts = append(prompts, prompt)
+
+		response, err := p.complete(p.callContext(prepared.ctx, prepared.result.ID), client, prompt)
+		usage.inputTokens += llm.EstimateTokens(prompt)
+		usage.outputTokens += estimateResponseTokens(response)
+		if errors.Is(err, llm.ErrContextLength) {
+			return nil, data.Permanent(fmt.Errorf("chunk %d of %d: %w", i+1, len(chunks), err))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Chunks whose response isn't JSON add nothing to the result
+		fields, validJSON, _ := parser.ParseLLMResponse(response)
+		if !validJSON {
+			continue
+		}
+		delete(fields, "processor_type")
+		delete(fields, "debug")
+		responses = append(responses, fields)
+	}
+
+	merged, _ := mergeChunkValues(responses).(map[string]interface{})
+	if merged == nil {
+		merged = make(map[string]interface{})
+	}
+	result, err := p.handleResponse(prepared, strings.Join(prompts, "\n\n"), merged)
+	if err != nil {
+		return nil, err
+	}
+	if info, ok := result.ProcessingInfo[p.name].(map[string]interface{}); ok {
+		info["downshift"] = "chunking"
+		info["chunks"] = len(chunks)
+	}
+	return result, nil
+}
+
+// mergeChunkValues merges the values the chunks of a text gave a field: objects field by
+// field, lists concatenated without duplicates, numbers averaged, booleans true if any is,
+// and strings to the most common one, the earliest on ties
+func mergeChunkValues(values []interface{}) interface{} {
+	present := values[:0:0]
+	for _, value := range values {
+		if value != nil {
+			present = append(present, value)
+		}
+	}
+	if len(present) == 0 {
+		return nil
+	}
+
+	switch present[0].(type) {
+	case map[string]interface{}:
+		byField := make(map[string][]interface{})
+		for _, value := range present {
+			if object, ok := value.(map[string]interface{}); ok {
+				for field, fieldValue := range object {
+					byField[field] = append(byField[field], fieldValue)
+				}
+			}
+		}
+		merged := make(map[string]interface{}, len(byField))
+		for field, fieldValues := range byField {
+			merged[field] = mergeChunkValues(fieldValues)
+		}
+		return merged
+	case []interface{}:
+		var merged []interface{}
+		for _, value := range present {
+			list, _ := value.([]interface{})
+			for _, element := range list {
+				if !containsValue(merged, element) {
+					merged = append(merged, element)
+				}
+			}
+		}
+		return merged
+	case float64:
+		var sum float64
+		n := 0
+		for _, value := range present {
+			if number, ok := value.(float64); ok {
+				sum += number
+				n++
+			}
+		}
+		return sum / float64(n)
+	case bool:
+		for _, value := range present {
+			if value == true {
+				return true
+			}
+		}
+		return false
+	case string:
+		counts := make(map[string]int)
+		var order []string
+		for _, value := range present {
+			if text, ok := value.(string); ok && text != "" {
+				if counts[text] == 0 {
+					order = append(order, text)
+				}
+				counts[text]++
+			}
+		}
+		if len(order) == 0 {
+			return ""
+		}
+		sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+		return order[0]
+	default:
+		return present[0]
+	}
+}
+
+// containsValue reports whether list holds a value deeply equal to value
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, element := range list {
+		if reflect.DeepEqual(element, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitChunks splits text into chunks of at most maxTokens estimated tokens, between lines
+// where possible, then between sentences, and otherwise within them
+func splitChunks(text string, maxTokens int) []string {
+	maxChars := maxTokens * 4
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+	}
+
+	for _, piece := range splitPieces(text, maxChars) {
+		if current.Len() > 0 && current.Len()+len(piece) > maxChars {
+			flush()
+		}
+		current.WriteString(piece)
+	}
+	flush()
+	return chunks
+}
+
+// splitPieces splits text into lines, sentences of lines too long for a chunk, and runs of
+// at most maxChars bytes of sentences still too long, keeping the separators
+func splitPieces(text string, maxChars int) []string {
+	var pieces []string
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if len(line) <= maxChars {
+			pieces = append(pieces, line)
+			continue
+		}
+		for _, sentence := range strings.SplitAfter(line, ". ") {
+			for len(sentence) > maxChars {
+				// Cut at a character boundary
+				cut := maxChars
+				for cut > 0 && !isCharStart(sentence[cut]) {
+					cut--
+				}
+				if cut == 0 {
+					cut = maxChars
+				}
+				pieces = append(pieces, sentence[:cut])
+				sentence = sentence[cut:]
+			}
+			pieces = append(pieces, sentence)
+		}
+	}
+	return pieces
+}
+
+// isCharStart reports whether a byte starts a UTF-8 encoded character
+func isCharStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
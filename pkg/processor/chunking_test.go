@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// chunkResult is the result struct of TestChunking
+type chunkResult struct {
+	Topics        []string `json:"topics"`
+	Score         float64  `json:"score"`
+	Escalate      bool     `json:"escalate"`
+	ProcessorType string   `json:"processor_type"`
+}
+
+func TestChunking(t *testing.T) {
+	// Each line is about 300 tokens, so the whole text only fits the context in chunks
+	text := "alpha " + strings.Repeat("billing ", 150) + "\nbeta " + strings.Repeat("outage ", 171)
+	newProvider := func() *llm.MockProvider {
+		return llm.NewMockProviderWithResponse(`{}`).
+			WithContextLimit(500).
+			WithResponse("alpha", `{"topics": ["billing"], "score": 0.2}`).
+			WithResponse("beta", `{"topics": ["outage", "billing"], "score": 0.6, "escalate": true}`)
+	}
+	builder := NewBuilder("chunked").WithStruct(&chunkResult{})
+
+	t.Run("downshift", func(t *testing.T) {
+		provider := newProvider()
+		proc, err := builder.Build(provider, NewDefaultOptions().WithChunking(ChunkingConfig{MaxTokens: 350}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		item, err := proc.Process(context.Background(), data.NewTextProcessItem("1", text, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := item.Content.(*chunkResult)
+		if want := []string{"billing", "outage"}; !reflect.DeepEqual(result.Topics, want) {
+			t.Errorf("expected topics %v, got %v", want, result.Topics)
+		}
+		if result.Score != 0.4 || !result.Escalate {
+			t.Errorf("expected score 0.4 and escalation, got %+v", result)
+		}
+		info := item.ProcessingInfo["chunked"].(map[string]interface{})
+		if info["downshift"] != "chunking" || info["chunks"] != 2 {
+			t.Errorf("expected the downshift to be recorded, got %v", info)
+		}
+		// The rejected prompt, then one per chunk
+		if prompts := provider.Prompts(); len(prompts) != 3 {
+			t.Errorf("expected 3 calls, got %d", len(prompts))
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		proc, err := builder.Build(newProvider(), NewDefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = proc.Process(context.Background(), data.NewTextProcessItem("1", text, nil))
+		if !errors.Is(err, llm.ErrContextLength) || !data.IsPermanent(err) {
+			t.Errorf("expected a permanent context length error, got %v", err)
+		}
+	})
+}
+
+func TestSplitChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		want      []string
+	}{
+		{name: "fits", text: "one line", maxTokens: 10, want: []string{"one line"}},
+		{name: "lines", text: "first line\nsecond line\nthird line", maxTokens: 6, want: []string{"first line\nsecond line", "third line"}},
+		{name: "sentences", text: "One sentence here. Another one here.", maxTokens: 5, want: []string{"One sentence here.", "Another one here."}},
+		{name: "long words", text: strings.Repeat("é", 10), maxTokens: 2, want: []string{strings.Repeat("é", 4), strings.Repeat("é", 4), strings.Repeat("é", 2)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitChunks(tt.text, tt.maxTokens); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
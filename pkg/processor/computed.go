@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// ComputeFunc computes a processor's result from an item without an LLM call. text is the
+// item's text, as it would be prompted; ctx carries the item's state and structured inputs,
+// readable with PromptInput. The result is typically a pointer to a result struct.
+type ComputeFunc func(ctx context.Context, item *data.ProcessItem, text string) (interface{}, error)
+
+// computedProcessors holds the names of the processors registered with RegisterComputedProcessor
+var computedProcessors = make(map[string]bool)
+
+// RegisterComputedProcessor registers a deterministic processor whose result is computed
+// from the item rather than asked of an LLM, such as conversation metrics. It never calls
+// its provider, which may be nil, and its results otherwise behave like those of an LLM
+// processor: they are stored in the item's processing info, cached and versioned.
+func RegisterComputedProcessor(name string, contentTypes []string, resultStruct interface{}, compute ComputeFunc) {
+	registerDescription(Description{
+		Name:         name,
+		ContentTypes: contentTypes,
+		ResultSchema: JSONSchema(resultStruct),
+	})
+	globalRegistryLock.Lock()
+	computedProcessors[name] = true
+	globalRegistryLock.Unlock()
+
+	Register(name, func(provider llm.Provider, options Options) (Processor, error) {
+		return NewComputedProcessor(name, contentTypes, compute, options), nil
+	})
+}
+
+// NewComputedProcessor creates a processor computing its results with compute. Options
+// configuring LLM calls, such as packing, routing and chunking, are ignored.
+func NewComputedProcessor(name string, contentTypes []string, compute ComputeFunc, options Options) *BaseProcessor {
+	options.Packing, options.Routing, options.Chunking = nil, nil, nil
+	p := NewBaseProcessor(name, contentTypes, nil, nil, nil, computedHandler{}, options)
+	p.compute = compute
+	p.promptVersion = "computed"
+	return p
+}
+
+// IsComputed reports whether a registered processor computes its results without an LLM
+func IsComputed(name string) bool {
+	globalRegistryLock.RLock()
+	defer globalRegistryLock.RUnlock()
+	return computedProcessors[name]
+}
+
+// computedHandler passes a computed result through as the processor's response
+type computedHandler struct{}
+
+// HandleResponse implements ResponseHandler
+func (computedHandler) HandleResponse(ctx context.Context, text string, responseData interface{}) (interface{}, error) {
+	return responseData, nil
+}
+
+// computeItem computes a prepared item's result. Errors are permanent, since computing
+// again gives the same result.
+func (p *BaseProcessor) computeItem(prepared preparedItem) (*data.ProcessItem, error) {
+	computed, err := p.compute(prepared.ctx, prepared.result, prepared.text)
+	if err != nil {
+		return nil, data.Permanent(fmt.Errorf("failed to compute %s: %w", p.name, err))
+	}
+	return p.handleResponse(prepared, "", computed)
+}
@@ -0,0 +1,336 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// Definition declares a processor in a YAML or JSON file instead of Go code, for
+// processors that need nothing beyond a result struct and a builder prompt
+type Definition struct {
+	// Name is the processor's registered name
+	Name string `json:"name" yaml:"name"`
+	// ContentTypes are the content types the processor accepts (defaults to text)
+	ContentTypes []string `json:"content_types,omitempty" yaml:"content_types,omitempty"`
+	// Role, Objective and Instructions are the prompt sections, as in ProcessorBuilder
+	Role         string   `json:"role,omitempty" yaml:"role,omitempty"`
+	Objective    string   `json:"objective,omitempty" yaml:"objective,omitempty"`
+	Instructions []string `json:"instructions,omitempty" yaml:"instructions,omitempty"`
+	// StateKeys are item state values from earlier pipeline steps to include in the prompt
+	StateKeys []string `json:"state_keys,omitempty" yaml:"state_keys,omitempty"`
+	// Inputs are structured item inputs to include in the prompt, see PromptInput
+	Inputs []InputDefinition `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	// Fields are the fields of the result
+	Fields []FieldDefinition `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// Schema is a JSON Schema of the result, in place of Fields; results are then maps
+	// conforming to it, see Schema
+	Schema map[string]interface{} `json:"schema,omitempty" yaml:"schema,omitempty"`
+	// Validate enables validation of the response against the fields
+	Validate bool `json:"validate,omitempty" yaml:"validate,omitempty"`
+	// Examples are few-shot examples of the prompt, see ProcessorBuilder.WithExamples
+	Examples []PromptExample `json:"examples,omitempty" yaml:"examples,omitempty"`
+	// Localizations are variants of the prompt by input language, see
+	// ProcessorBuilder.WithLocalization
+	Localizations map[string]PromptLocalization `json:"localizations,omitempty" yaml:"localizations,omitempty"`
+	// StandardSections are standard prompt sections to include by name, such as
+	// no_hallucination, see ProcessorBuilder.WithStandardSection
+	StandardSections []string `json:"standard_sections,omitempty" yaml:"standard_sections,omitempty"`
+	// NotApplicable lets the processor abstain when the condition it describes holds, see
+	// ProcessorBuilder.WithNotApplicable
+	NotApplicable string `json:"not_applicable,omitempty" yaml:"not_applicable,omitempty"`
+	// MaxOutputTokens and StopSequences bound the responses, as in ProcessorBuilder
+	MaxOutputTokens int      `json:"max_output_tokens,omitempty" yaml:"max_output_tokens,omitempty"`
+	StopSequences   []string `json:"stop_sequences,omitempty" yaml:"stop_sequences,omitempty"`
+	// Temperature and PreferredModels are the recommended generation settings, as in
+	// ProcessorBuilder
+	Temperature     *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	PreferredModels []string `json:"preferred_models,omitempty" yaml:"preferred_models,omitempty"`
+	// Sanitize sanitizes the results after they are mapped, see SanitizeConfig
+	Sanitize *SanitizeConfig `json:"sanitize,omitempty" yaml:"sanitize,omitempty"`
+	// Version is the schema version of the results, see ProcessorBuilder.WithVersion.
+	// Migrations of older results are registered in code, after the definition, with
+	// RegisterVersion.
+	Version int `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// FieldDefinition declares a field of a Definition's result
+type FieldDefinition struct {
+	// Name is the field's JSON name, in snake_case
+	Name string `json:"name" yaml:"name"`
+	// Type is string, number, integer or boolean (defaults to string)
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// List makes the field a list of Type
+	List bool `json:"list,omitempty" yaml:"list,omitempty"`
+	// Description tells the LLM what the field holds
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Default is the value used when the response lacks the field
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// InputDefinition declares a structured prompt input of a Definition
+type InputDefinition struct {
+	// Key is the JSON content field, metadata key or Options.Inputs key holding the input
+	Key string `json:"key" yaml:"key"`
+	// Title names the input's prompt section (defaults to Key)
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+}
+
+// fieldNamePattern matches the field names a Definition accepts
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// fieldTypes are the Go types of the field types a Definition accepts
+var fieldTypes = map[string]reflect.Type{
+	"string":  reflect.TypeOf(""),
+	"number":  reflect.TypeOf(float64(0)),
+	"integer": reflect.TypeOf(0),
+	"boolean": reflect.TypeOf(false),
+}
+
+// LoadDefinition reads a processor definition from a YAML (.yaml, .yml) or JSON (.json) file
+func LoadDefinition(path string) (*Definition, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processor definition: %w", err)
+	}
+
+	var definition Definition
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(raw, &definition)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &definition)
+	default:
+		return nil, fmt.Errorf("unsupported processor definition format: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse processor definition %s: %w", path, err)
+	}
+	return &definition, nil
+}
+
+// RegisterDefinitionFile loads a processor definition from a file and registers it
+func RegisterDefinitionFile(path string) error {
+	definition, err := LoadDefinition(path)
+	if err != nil {
+		return err
+	}
+	return definition.Register()
+}
+
+// Register registers the defined processor, with a result struct built from its fields
+func (d *Definition) Register() error {
+	builder, err := d.builder()
+	if err != nil {
+		return err
+	}
+	builder.Register()
+	return nil
+}
+
+// Create creates the defined processor without registering it, e.g. to evaluate a revision
+// of its prompt
+func (d *Definition) Create(provider llm.Provider, options Options) (Processor, error) {
+	builder, err := d.builder()
+	if err != nil {
+		return nil, err
+	}
+	return builder.Build(provider, options)
+}
+
+// Save writes the definition to a YAML (.yaml, .yml) or JSON (.json) file
+func (d *Definition) Save(path string) error {
+	var (
+		raw []byte
+		err error
+	)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		raw, err = json.MarshalIndent(d, "", "  ")
+	case ".yaml", ".yml":
+		raw, err = yaml.Marshal(d)
+	default:
+		return fmt.Errorf("unsupported processor definition format: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode processor definition: %w", err)
+	}
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write processor definition: %w", err)
+	}
+	return nil
+}
+
+// builder returns the builder of the defined processor, with a result struct built from
+// its fields
+func (d *Definition) builder() (*ProcessorBuilder, error) {
+	resultStruct, err := d.resultStruct()
+	if err != nil {
+		return nil, err
+	}
+
+	builder := NewBuilder(d.Name).
+		WithStruct(resultStruct).
+		WithRole(d.Role).
+		WithObjective(d.Objective).
+		WithInstructions(d.Instructions...).
+		WithStateKeys(d.StateKeys...)
+	for _, input := range d.Inputs {
+		if input.Key == "" {
+			return nil, fmt.Errorf("processor %s: input key is required", d.Name)
+		}
+		title := input.Title
+		if title == "" {
+			title = input.Key
+		}
+		builder.WithInput(input.Key, title, nil)
+	}
+	if len(d.ContentTypes) > 0 {
+		builder.WithContentTypes(d.ContentTypes...)
+	}
+	if d.Validate {
+		builder.WithValidation()
+	}
+	if d.NotApplicable != "" {
+		builder.WithNotApplicable(d.NotApplicable)
+	}
+	if d.MaxOutputTokens > 0 {
+		builder.WithMaxOutputTokens(d.MaxOutputTokens)
+	}
+	if len(d.StopSequences) > 0 {
+		builder.WithStopSequences(d.StopSequences...)
+	}
+	if d.Temperature != nil {
+		builder.WithTemperature(*d.Temperature)
+	}
+	if len(d.PreferredModels) > 0 {
+		builder.WithPreferredModels(d.PreferredModels...)
+	}
+	if d.Sanitize != nil {
+		if err := d.Sanitize.validate(); err != nil {
+			return nil, fmt.Errorf("processor %s: %w", d.Name, err)
+		}
+		builder.WithSanitize(*d.Sanitize)
+	}
+	if d.Version < 0 {
+		return nil, fmt.Errorf("processor %s: invalid version %d", d.Name, d.Version)
+	}
+	builder.WithVersion(d.Version)
+
+	var descriptions []string
+	for _, field := range d.Fields {
+		if field.Description != "" {
+			descriptions = append(descriptions, fmt.Sprintf("- %s: %s", field.Name, field.Description))
+		}
+	}
+	if schema, ok := resultStruct.(*Schema); ok {
+		properties := schema.definition["properties"].(map[string]interface{})
+		for _, name := range sortedKeys(properties) {
+			property, _ := properties[name].(map[string]interface{})
+			if description, _ := property["description"].(string); description != "" {
+				descriptions = append(descriptions, fmt.Sprintf("- %s: %s", name, description))
+			}
+		}
+	}
+	if len(descriptions) > 0 {
+		builder.WithCustomSection("Output Fields", strings.Join(descriptions, "\n"))
+	}
+	if len(d.Examples) > 0 {
+		builder.WithExamples(d.Examples...)
+	}
+	for language, localization := range d.Localizations {
+		builder.WithLocalization(language, localization)
+	}
+	for _, name := range d.StandardSections {
+		if _, ok := LookupStandardSection(name); !ok {
+			return nil, fmt.Errorf("processor %s: unknown standard prompt section %q", d.Name, name)
+		}
+		builder.WithStandardSection(name)
+	}
+	return builder, nil
+}
+
+// resultStruct returns a pointer to a new struct with a field per field definition,
+// followed by the processor_type field every result has, or the definition's Schema
+func (d *Definition) resultStruct() (interface{}, error) {
+	if d.Name == "" {
+		return nil, fmt.Errorf("processor definition: name is required")
+	}
+	if d.Schema != nil {
+		if len(d.Fields) > 0 {
+			return nil, fmt.Errorf("processor %s: declare either fields or a schema, not both", d.Name)
+		}
+		schema, err := NewSchema(d.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("processor %s: %w", d.Name, err)
+		}
+		return schema, nil
+	}
+	if len(d.Fields) == 0 {
+		return nil, fmt.Errorf("processor %s: at least one field is required", d.Name)
+	}
+
+	fields := make([]reflect.StructField, 0, len(d.Fields)+1)
+	seen := make(map[string]bool, len(d.Fields))
+	for _, field := range d.Fields {
+		if !fieldNamePattern.MatchString(field.Name) {
+			return nil, fmt.Errorf("processor %s: invalid field name %q; use snake_case", d.Name, field.Name)
+		}
+		// Names such as "a_b" and "a__b" would make the same Go field
+		goName := exportedName(field.Name)
+		if seen[goName] || goName == "ProcessorType" {
+			return nil, fmt.Errorf("processor %s: duplicate field %s", d.Name, field.Name)
+		}
+		seen[goName] = true
+
+		fieldType := field.Type
+		if fieldType == "" {
+			fieldType = "string"
+		}
+		goType, ok := fieldTypes[fieldType]
+		if !ok {
+			return nil, fmt.Errorf("processor %s: field %s has unknown type %s", d.Name, field.Name, field.Type)
+		}
+
+		tag := fmt.Sprintf(`json:"%s"`, field.Name)
+		if field.List {
+			goType = reflect.SliceOf(goType)
+			tag = fmt.Sprintf(`json:"%s,omitempty"`, field.Name)
+		}
+		if field.Default != "" {
+			tag += fmt.Sprintf(" default:%q", field.Default)
+		}
+		fields = append(fields, reflect.StructField{
+			Name: goName,
+			Type: goType,
+			Tag:  reflect.StructTag(tag),
+		})
+	}
+	fields = append(fields, reflect.StructField{
+		Name: "ProcessorType",
+		Type: reflect.TypeOf(""),
+		Tag:  `json:"processor_type"`,
+	})
+
+	return reflect.New(reflect.StructOf(fields)).Interface(), nil
+}
+
+// exportedName converts a snake_case name to an exported Go identifier, e.g. "key_phrases"
+// to "KeyPhrases"
+func exportedName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
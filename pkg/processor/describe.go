@@ -0,0 +1,134 @@
+package processor
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Description describes a registered processor for documentation and API clients
+type Description struct {
+	// Name is the processor's registered name
+	Name string `json:"name"`
+	// ContentTypes are the content types the processor accepts
+	ContentTypes []string `json:"content_types,omitempty"`
+	// ResultSchema is a JSON Schema of the processor's result, or nil if it is unknown
+	ResultSchema map[string]interface{} `json:"result_schema,omitempty"`
+	// Version is the schema version of the processor's results, or 0 if it is unversioned
+	Version int `json:"version,omitempty"`
+	// Temperature is the sampling temperature the processor recommends, if any
+	Temperature *float64 `json:"temperature,omitempty"`
+	// PreferredModels are the models the processor works best with, in order of preference
+	PreferredModels []string `json:"preferred_models,omitempty"`
+}
+
+// descriptions holds the descriptions of processors registered with a result struct
+var descriptions = make(map[string]Description)
+
+// registerDescription records a processor's description
+func registerDescription(description Description) {
+	globalRegistryLock.Lock()
+	defer globalRegistryLock.Unlock()
+	descriptions[description.Name] = description
+}
+
+// Describe returns the description of a registered processor. Processors registered with
+// Register rather than RegisterGenericProcessor have no content types or result schema.
+func Describe(name string) (Description, error) {
+	globalRegistryLock.RLock()
+	defer globalRegistryLock.RUnlock()
+	if _, ok := globalRegistry[name]; !ok {
+		return Description{}, fmt.Errorf("processor not found: %s", name)
+	}
+	if description, ok := descriptions[name]; ok {
+		return description, nil
+	}
+	return Description{Name: name}, nil
+}
+
+// JSONSchema returns a JSON Schema describing the JSON encoding of a value's type. Struct
+// fields without omitempty are required, and the enum tag of string fields and string slices
+// lists their allowed values. For a *Schema it returns the schema itself.
+func JSONSchema(value interface{}) map[string]interface{} {
+	if schema, ok := value.(*Schema); ok {
+		return schema.Map()
+	}
+	return typeSchema(reflect.TypeOf(value))
+}
+
+// typeSchema returns the JSON Schema of a type
+func typeSchema(t reflect.Type) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			property := typeSchema(field.Type)
+			if enum := tagValues(field.Tag.Get("enum"), ","); len(enum) > 0 {
+				addEnum(property, enum)
+			}
+			properties[name] = property
+			if !strings.Contains(options, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		// Interfaces can hold any value
+		return map[string]interface{}{}
+	}
+}
+
+// addEnum restricts a string schema, or the items of an array schema, to the enum values
+func addEnum(schema map[string]interface{}, enum []string) {
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		schema = items
+	}
+	if schema["type"] != "string" {
+		return
+	}
+	values := make([]interface{}, len(enum))
+	for i, value := range enum {
+		values[i] = value
+	}
+	schema["enum"] = values
+}
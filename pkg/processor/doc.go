@@ -18,10 +18,14 @@ Core components:
 2. Base Processors (base_processor.go):
   - BaseProcessor: Provides core implementation of the Processor interface
   - Handles common operations like content extraction and LLM calling
+  - ProcessSourceWithReport: Processes a source and summarizes the run in a data.RunReport
 
 3. Generic Processors (generic_processor.go):
   - GenericProcessor: Extends BaseProcessor with standard response handling
   - RegisterGenericProcessor: Helper for registering processors
+  - Negotiates native structured output from the provider's capabilities
+  - ResultValidator / ResultTransform: Check and post-process mapped results; Transform adapts
+    a typed function of the result struct
 
 4. Response Handling (response_handler.go):
   - BaseResponseHandler: Provides common response handling functionality
@@ -30,13 +34,64 @@ Core components:
 5. Utilities:
   - JSON utilities (json_utils.go): Tools for working with JSON data
   - Validation (validation.go): Functions for validating LLM responses
+  - Redaction (redact.go): Redactor and RedactionConfig for removing sensitive values from debug output
+  - Routing (routing.go): RoutingConfig and Complexity for sending items to cheaper or stronger models
+  - Packing (packing.go): PackingConfig for processing several short items per LLM call
+  - Chunking (chunking.go): ChunkingConfig for processing texts too long for the context in chunks
+  - Inputs (inputs.go): PromptInput and FormatInput for structured inputs rendered into builder prompts
 
 6. Registry (registry.go):
   - Register: Registers processor factories
   - Create: Creates processors by name
 
+7. Descriptions (describe.go):
+  - Describe: Content types and result JSON Schema of a registered processor
+  - JSONSchema: JSON Schema of a Go type, as used for result structs
+
+8. Definitions (definition.go):
+  - Definition: A processor declared in a YAML or JSON file
+  - RegisterDefinitionFile: Loads and registers a definition
+
+9. Schemas (schema.go):
+  - Schema: A JSON Schema used in place of a result struct, with map results
+
+10. Not applicable results (not_applicable.go):
+  - NotApplicable / ItemNotApplicable: Detect results of processors that abstained
+
+11. Sanitization (sanitize.go):
+  - SanitizeConfig: Trimming, control character removal, label casing, number clamping and
+    list de-duplication of mapped results
+
+12. Versioning (versioning.go):
+  - RegisterVersion / Migrate / MigrateItem: Schema versions of results and migrations
+    upgrading results stored by older versions
+
+13. Provenance (provenance.go):
+  - BaseProcessor.Provenance: Hash of an item's input, processor, prompt version and model,
+    keying the result cache set with Options.WithResultCache
+
+14. Computed processors (computed.go):
+  - RegisterComputedProcessor / NewComputedProcessor: Deterministic processors computing
+    results without an LLM, such as conversation metrics
+
+15. Standard prompt sections (sections.go):
+  - RegisterStandardSection / LookupStandardSection: Shared prompt clauses, such as the
+    anti-hallucination clause, that builders include by name with WithStandardSection
+
+16. Localized prompts (localization.go):
+  - PromptLocalization / PromptLanguage: Variants of builder prompts, with instructions and
+    few-shot examples, selected by the detected or configured language of the input
+
+17. Prompt budgets (prompt_budget.go):
+  - PromptBudget: Bounds builder prompts to a share of the context window, dropping
+    optional sections before truncating the input text
+
+The processortest subpackage snapshots the prompts of registered LLM processors in golden
+files and replays recorded provider responses through them, for tests.
+
 To create a custom processor, implement the required interfaces and register
 your processor factory using Register() or use the RegisterGenericProcessor()
-helper function for common cases.
+helper function for common cases. Processors that only need a result struct and
+a prompt can also be declared in a file and registered with RegisterDefinitionFile().
 */
 package processor
@@ -2,6 +2,7 @@ package processor
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"strings"
 
@@ -18,6 +19,72 @@ type GenericProcessor struct {
 	responseHandler ResponseHandler
 }
 
+// ResultValidator checks a processor's mapped result before it is returned, and may correct
+// it in place. The result is a pointer to the processor's result struct, or a map of defaults
+// if the response wasn't JSON. ctx carries the item's structured inputs (see PromptInput). An
+// error fails the item.
+type ResultValidator func(ctx context.Context, result interface{}) error
+
+// validatingHandler applies a ResultValidator to the results of a response handler
+type validatingHandler struct {
+	handler  ResponseHandler
+	validate ResultValidator
+}
+
+// HandleResponse implements the ResponseHandler interface
+func (h validatingHandler) HandleResponse(ctx context.Context, text string, responseData interface{}) (interface{}, error) {
+	result, err := h.handler.HandleResponse(ctx, text, responseData)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.validate(ctx, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ResultTransform post-processes a processor's mapped result deterministically, e.g. to
+// normalize label casing or compute derived fields, and returns the result to use in its
+// place. It receives the result as a ResultValidator does; see Transform for transforms of
+// the result struct. An error fails the item.
+type ResultTransform func(ctx context.Context, result interface{}) (interface{}, error)
+
+// Transform adapts a transform of the processor's result type T, such as a pointer to its
+// result struct, to a ResultTransform. Results of other types, such as the defaults of a
+// response that wasn't JSON, are passed through unchanged.
+func Transform[T any](transform func(result T) (T, error)) ResultTransform {
+	return func(_ context.Context, result interface{}) (interface{}, error) {
+		typed, ok := result.(T)
+		if !ok {
+			return result, nil
+		}
+		return transform(typed)
+	}
+}
+
+// transformingHandler applies ResultTransforms, in order, to the results of a response handler
+type transformingHandler struct {
+	handler    ResponseHandler
+	transforms []ResultTransform
+}
+
+// HandleResponse implements the ResponseHandler interface
+func (h transformingHandler) HandleResponse(ctx context.Context, text string, responseData interface{}) (interface{}, error) {
+	result, err := h.handler.HandleResponse(ctx, text, responseData)
+	if err != nil {
+		return nil, err
+	}
+	for _, transform := range h.transforms {
+		if result, err = transform(ctx, result); err != nil {
+			return nil, fmt.Errorf("failed to transform result: %w", err)
+		}
+		if value := reflect.ValueOf(result); !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+			return nil, fmt.Errorf("failed to transform result: transform returned no result")
+		}
+	}
+	return result, nil
+}
+
 // HandleResponse implements ResponseHandler interface - handles the LLM response
 func (p *GenericProcessor) HandleResponse(ctx context.Context, text string, responseData interface{}) (interface{}, error) {
 	// The response handler is now set directly in RegisterGenericProcessor
@@ -31,7 +98,57 @@ func (p *GenericProcessor) HandleResponse(ctx context.Context, text string, resp
 	return handler.AutoProcessResponse(ctx, text, responseData)
 }
 
-// RegisterGenericProcessor creates and registers a processor with standard behavior
+// StructuredOutputMode returns how the processor asks its provider for results, as
+// negotiated from the provider's capabilities when the processor was created
+func (p *GenericProcessor) StructuredOutputMode() llm.StructuredOutputMode {
+	return p.structuredOutput.Mode
+}
+
+// negotiateStructuredOutput chooses how a processor asks for its result: with the most
+// constrained mode the provider's capabilities allow, or in the prompt only if the provider
+// supports none, the "structured_output" LLM option is "prompt" or "json_output" is false
+func negotiateStructuredOutput(name string, resultStruct interface{}, provider llm.Provider, options Options) llm.StructuredOutput {
+	output := llm.StructuredOutput{Mode: llm.StructuredOutputPrompt, Name: name}
+	if provider == nil {
+		return output
+	}
+	if mode, _ := options.LLMOptions["structured_output"].(string); mode == string(llm.StructuredOutputPrompt) {
+		return output
+	}
+	if jsonOutput, ok := options.LLMOptions["json_output"].(bool); ok && !jsonOutput {
+		return output
+	}
+	output.Mode = llm.CapabilitiesOf(provider).StructuredOutputMode()
+	output.Schema = responseSchema(resultStruct)
+	return output
+}
+
+// responseSchema returns the JSON Schema of the response wanted from the LLM: the result
+// struct's, without the fields the framework fills in
+func responseSchema(resultStruct interface{}) map[string]interface{} {
+	schema := JSONSchema(resultStruct)
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		delete(properties, "processor_type")
+		delete(properties, "debug")
+	}
+	if required, ok := schema["required"].([]string); ok {
+		kept := required[:0]
+		for _, name := range required {
+			if name != "processor_type" && name != "debug" {
+				kept = append(kept, name)
+			}
+		}
+		if len(kept) == 0 {
+			delete(schema, "required")
+		} else {
+			schema["required"] = kept
+		}
+	}
+	return schema
+}
+
+// RegisterGenericProcessor creates and registers a processor with standard behavior. The
+// result struct may be a *Schema, for processors whose results are only known at runtime.
 func RegisterGenericProcessor(
 	name string,
 	contentTypes []string,
@@ -40,8 +157,28 @@ func RegisterGenericProcessor(
 	customInit func(*GenericProcessor) error,
 	validateStructure bool,
 ) {
+	registerDescription(Description{
+		Name:         name,
+		ContentTypes: contentTypes,
+		ResultSchema: JSONSchema(resultStruct),
+	})
+
 	// Register the processor creator function
-	Register(name, func(provider llm.Provider, options Options) (Processor, error) {
+	Register(name, newGenericFactory(name, contentTypes, resultStruct, promptGenerator, customInit, validateStructure, nil, nil))
+}
+
+// newGenericFactory returns the factory of a processor with standard behavior
+func newGenericFactory(
+	name string,
+	contentTypes []string,
+	resultStruct interface{},
+	promptGenerator PromptGenerator,
+	customInit func(*GenericProcessor) error,
+	validateStructure bool,
+	transforms []ResultTransform,
+	validateResult ResultValidator,
+) FactoryFunc {
+	return func(provider llm.Provider, options Options) (Processor, error) {
 		// Create a new generic processor
 		p := &GenericProcessor{
 			ResultStruct: resultStruct,
@@ -50,52 +187,45 @@ func RegisterGenericProcessor(
 		// Create client from provider
 		client := llm.NewProviderClient(provider)
 
-		// Create response handler with dynamic validators if needed
-		responseHandler := &BaseResponseHandler{
-			ProcessorType:     name,
-			ResultStruct:      resultStruct,
-			Fields:            make(map[string]FieldMapper),
-			DynamicValidators: make(map[string]func(interface{}) interface{}),
-			validateStructure: validateStructure,
+		// Results of a JSON Schema are maps conforming to it
+		if schema, ok := resultStruct.(*Schema); ok {
+			p.responseHandler = newSchemaResponseHandler(name, schema, validateStructure)
+		} else {
+			p.responseHandler = newStructResponseHandler(name, resultStruct, validateStructure)
 		}
-
-		// Set the default responder
-		responseHandler.DefaultResponder = func() interface{} {
-			return responseHandler.createDefaultResponse()
-		}
-
-		// Configure fields based on the result struct
-		responseHandler.configureFieldsFromStruct()
-
-		// Apply processor-specific defaults
-		responseHandler.applyProcessorDefaults()
-
-		// Check for custom field validators (ValidateFieldName methods)
-		// These run *after* the main structure validation (if enabled and passed)
-		// Iterate over fields defined in the handler (which come from ResultStruct)
-		for fieldName := range responseHandler.Fields {
-			// Build the expected custom validator method name: "Validate" + Title case field name
-			methodName := "Validate" + strings.Title(fieldName)
-			validatorMethod := reflect.ValueOf(resultStruct).MethodByName(methodName)
-
-			// If a custom validator method exists, add it to DynamicValidators
-			if validatorMethod.IsValid() {
-				// Call the validator method to get transform function
-				results := validatorMethod.Call(nil)
-				if len(results) > 0 {
-					if transformFn, ok := results[0].Interface().(func(interface{}) interface{}); ok {
-						// Add custom validator/transformer to DynamicValidators
-						responseHandler.DynamicValidators[fieldName] = transformFn
-					}
-				}
+		if options.Sanitize != nil {
+			if err := options.Sanitize.validate(); err != nil {
+				return nil, fmt.Errorf("invalid sanitize config for %s: %w", name, err)
 			}
+			p.responseHandler = sanitizingHandler{handler: p.responseHandler, config: *options.Sanitize}
+		}
+		if len(transforms) > 0 {
+			p.responseHandler = transformingHandler{handler: p.responseHandler, transforms: transforms}
+		}
+		if validateResult != nil {
+			p.responseHandler = validatingHandler{handler: p.responseHandler, validate: validateResult}
 		}
 
-		// Override the generic HandleResponse method to use our configured handler
-		p.responseHandler = responseHandler
+		// Ask for the result natively if the provider supports structured output
+		output := negotiateStructuredOutput(name, resultStruct, provider, options)
+		if output.Mode != llm.StructuredOutputPrompt {
+			options = options.WithLLMOption("json_output", true)
+		}
 
 		// Create and embed base processor with the appropriate content types
 		base := NewBaseProcessor(name, contentTypes, client, nil, promptGenerator, p.responseHandler, options)
+		base.structuredOutput = output
+		base.promptVersion = promptFingerprint(promptGenerator)
+		if provider != nil {
+			base.model = fmt.Sprintf("%s/%s", provider.GetType(), provider.GetConfig().Model)
+		}
+		if options.Routing != nil {
+			routes, err := newRouteClients(provider, *options.Routing)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure routing for %s: %w", name, err)
+			}
+			base.routes = routes
+		}
 		p.BaseProcessor = *base
 
 		// Call custom initializer if provided
@@ -106,5 +236,51 @@ func RegisterGenericProcessor(
 		}
 
 		return p, nil
-	})
+	}
+}
+
+// newStructResponseHandler creates the response handler of a processor with a result struct
+func newStructResponseHandler(name string, resultStruct interface{}, validateStructure bool) *BaseResponseHandler {
+	// Create response handler with dynamic validators if needed
+	responseHandler := &BaseResponseHandler{
+		ProcessorType:     name,
+		ResultStruct:      resultStruct,
+		Fields:            make(map[string]FieldMapper),
+		DynamicValidators: make(map[string]func(interface{}) interface{}),
+		validateStructure: validateStructure,
+	}
+
+	// Set the default responder
+	responseHandler.DefaultResponder = func() interface{} {
+		return responseHandler.createDefaultResponse()
+	}
+
+	// Configure fields based on the result struct
+	responseHandler.configureFieldsFromStruct()
+
+	// Apply processor-specific defaults
+	responseHandler.applyProcessorDefaults()
+
+	// Check for custom field validators (ValidateFieldName methods)
+	// These run *after* the main structure validation (if enabled and passed)
+	// Iterate over fields defined in the handler (which come from ResultStruct)
+	for fieldName := range responseHandler.Fields {
+		// Build the expected custom validator method name: "Validate" + Title case field name
+		methodName := "Validate" + strings.Title(fieldName)
+		validatorMethod := reflect.ValueOf(resultStruct).MethodByName(methodName)
+
+		// If a custom validator method exists, add it to DynamicValidators
+		if validatorMethod.IsValid() {
+			// Call the validator method to get transform function
+			results := validatorMethod.Call(nil)
+			if len(results) > 0 {
+				if transformFn, ok := results[0].Interface().(func(interface{}) interface{}); ok {
+					// Add custom validator/transformer to DynamicValidators
+					responseHandler.DynamicValidators[fieldName] = transformFn
+				}
+			}
+		}
+	}
+
+	return responseHandler
 }
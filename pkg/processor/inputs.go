@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// promptInputsKey is the context key of the item's prompt input sources
+type promptInputsKey struct{}
+
+// promptInputs are the places an item's structured prompt inputs are looked up, in order
+type promptInputs struct {
+	content  map[string]interface{}
+	metadata map[string]interface{}
+	options  map[string]interface{}
+}
+
+// withPromptInputs makes an item's structured inputs, and the processor's default inputs,
+// available to prompt generators
+func withPromptInputs(ctx context.Context, item *data.ProcessItem, options map[string]interface{}) context.Context {
+	content, _ := item.Content.(map[string]interface{})
+	return context.WithValue(ctx, promptInputsKey{}, promptInputs{
+		content:  content,
+		metadata: item.Metadata,
+		options:  options,
+	})
+}
+
+// PromptInput returns the structured input key of the item being prompted for, e.g. a list
+// of definitions the prompt should follow. It is taken from the field of the item's JSON
+// content, else its metadata key, else the processor's Options.Inputs.
+func PromptInput(ctx context.Context, key string) (interface{}, bool) {
+	inputs, ok := ctx.Value(promptInputsKey{}).(promptInputs)
+	if !ok {
+		return nil, false
+	}
+	for _, source := range []map[string]interface{}{inputs.content, inputs.metadata, inputs.options} {
+		if value, ok := source[key]; ok && value != nil {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// InputFormatter renders a structured prompt input as the text of its prompt section
+type InputFormatter func(value interface{}) (string, error)
+
+// promptInput is a structured input of a builder prompt
+type promptInput struct {
+	key    string
+	title  string
+	format InputFormatter
+}
+
+// render returns the input's prompt section, or "" if the item has no such input
+func (i promptInput) render(ctx context.Context) (string, error) {
+	value, ok := PromptInput(ctx, i.key)
+	if !ok {
+		return "", nil
+	}
+	format := i.format
+	if format == nil {
+		format = FormatInput
+	}
+	text, err := format(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to format input %s: %w", i.key, err)
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", nil
+	}
+	return fmt.Sprintf("**%s:**\n%s", i.title, text), nil
+}
+
+// FormatInput is the default InputFormatter: strings are used as is and other values are
+// rendered as indented JSON
+func FormatInput(value interface{}) (string, error) {
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	encoded, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
 )
 
 // Options holds common configuration for processors
@@ -14,6 +15,47 @@ type Options struct {
 	LLMOptions map[string]interface{}
 	// PostProcessOptions holds options for post-processing
 	PostProcessOptions map[string]interface{}
+	// Inputs are default structured prompt inputs, for processors that declare them, e.g.
+	// the attribute definitions get_attributes extracts. An item's own inputs, in its JSON
+	// content or metadata, take precedence.
+	Inputs map[string]interface{}
+	// Retry, if set, retries failed items in ProcessSource and its streaming variants
+	Retry *data.RetryPolicy
+	// Redaction, if set, redacts the prompts and raw responses stored as debug output
+	Redaction *RedactionConfig
+	// ValidateProvider makes Create verify the provider's API key and model before creating
+	// the processor
+	ValidateProvider bool
+	// Routing, if set, sends each item to one of several models by size or complexity
+	Routing *RoutingConfig
+	// Packing, if set, processes several short items per LLM call in ProcessBatch and
+	// ProcessSource
+	Packing *PackingConfig
+	// InputTokenCost and OutputTokenCost are prices per million tokens, used to estimate the
+	// cost recorded with each result's estimated token usage
+	InputTokenCost  float64
+	OutputTokenCost float64
+	// Chunking, if set, processes items whose prompt exceeds the model's context length in
+	// chunks instead of failing them
+	Chunking *ChunkingConfig
+	// MaxConcurrency, if positive, is the most LLM calls the processor instance makes at
+	// once, across every goroutine using it, for providers or models that tolerate less
+	// parallelism than the workers processing items
+	MaxConcurrency int
+	// Priority, if set, ranks items in ProcessSource and its streaming variants, starting
+	// those of higher priority first. See data.PriorityFromMetadata.
+	Priority func(item *data.ProcessItem) int
+	// ResultOrder is the order ProcessSourceStream and ProcessSourceToSink deliver results
+	// in (defaults to data.OrderCompletion)
+	ResultOrder data.ResultOrder
+	// Sanitize, if set, sanitizes results after they are mapped, before any result validator
+	Sanitize *SanitizeConfig
+	// ResultCache, if set, stores results by their provenance hash, so re-runs return the
+	// result of an input already processed with the same prompt and model without an LLM call
+	ResultCache llm.Cache
+	// PromptBudget, if set, bounds builder prompts to a share of the model's context window,
+	// dropping optional sections and truncating the input text of prompts over budget
+	PromptBudget *PromptBudget
 }
 
 // TextPreProcessor defines the interface for pre-processing text
@@ -47,4 +89,13 @@ type Processor interface {
 
 	// ProcessSource processes all items from a source
 	ProcessSource(ctx context.Context, source data.ProcessItemSource, batchSize, workers int) ([]*data.ProcessItem, error)
+
+	// ProcessSourceStream processes items from a source and streams results over a channel
+	ProcessSourceStream(ctx context.Context, source data.ProcessItemSource, workers int) <-chan data.ProcessResult
+
+	// ProcessSourceToSink processes all items from a source and writes results to a sink as they are produced
+	ProcessSourceToSink(ctx context.Context, source data.ProcessItemSource, sink data.ProcessItemSink, batchSize, workers int) error
+
+	// ProcessSourceWithReport processes all items from a source and reports counts, errors, latencies and usage
+	ProcessSourceWithReport(ctx context.Context, source data.ProcessItemSource, batchSize, workers int, config data.ReportConfig) ([]*data.ProcessItem, *data.RunReport, error)
 }
@@ -6,16 +6,25 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // GenerateJSONExample generates a sample JSON structure from a struct
-// This is useful for creating example JSON in LLM prompts
+// This is useful for creating example JSON in LLM prompts. Fields tagged prompt:"-" are
+// left empty, so omitempty fields the processor fills in itself are left out. Sample values
+// come from a field's example tag, else its default tag, else its enum tag (comma-separated
+// allowed values), else its type; slices get several elements, which cycle through the
+// "|"-separated values of an example tag and through the enum values.
 func GenerateJSONExample(structType interface{}) string {
+	if schema, ok := structType.(*Schema); ok {
+		return schema.Example()
+	}
+
 	// Create a sample instance of the struct
 	val := reflect.ValueOf(structType).Elem()
 	sampleStruct := reflect.New(val.Type()).Interface()
 
-	// Use reflection to populate the struct with sample values based on default tags
+	// Use reflection to populate the struct with sample values based on its tags
 	populateSampleValues(reflect.ValueOf(sampleStruct).Elem())
 
 	// Marshal to a map first so we can exclude processor_type
@@ -42,8 +51,75 @@ func GenerateJSONExample(structType interface{}) string {
 	return string(prettyBytes)
 }
 
+// sampleSliceLength is the number of elements of sample slices, so the example shows that
+// lists hold several items; example tags listing more values get up to maxSampleSliceLength
+const (
+	sampleSliceLength    = 2
+	maxSampleSliceLength = 3
+)
+
+// maxSampleDepth bounds the nesting of sample values, for recursive types
+const maxSampleDepth = 8
+
+// sampleTime is the sample value of time fields without an example tag
+var sampleTime = time.Date(2024, 3, 15, 14, 30, 0, 0, time.UTC)
+
+// sampleTags are the tags of a struct field that shape its sample values
+type sampleTags struct {
+	name         string
+	defaultValue string
+	comment      string
+	examples     []string
+	enum         []string
+}
+
+// fieldSampleTags returns the sample tags of a struct field named name
+func fieldSampleTags(field reflect.StructField, name string) sampleTags {
+	tags := sampleTags{
+		name:         name,
+		defaultValue: field.Tag.Get("default"),
+		comment:      field.Tag.Get("comment"),
+		enum:         tagValues(field.Tag.Get("enum"), ","),
+	}
+	if example, ok := field.Tag.Lookup("example"); ok {
+		tags.examples = tagValues(example, "|")
+	}
+	return tags
+}
+
+// tagValues splits a tag into its trimmed, non-empty values
+func tagValues(tag, separator string) []string {
+	var values []string
+	for _, value := range strings.Split(tag, separator) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// literal returns the tag value of the index-th sample of a field: an example, else the
+// default for the first sample, else an enum value
+func (t sampleTags) literal(index int) (string, bool) {
+	switch {
+	case len(t.examples) > 0:
+		return t.examples[index%len(t.examples)], true
+	case t.defaultValue != "" && (index == 0 || len(t.enum) == 0):
+		return t.defaultValue, true
+	case len(t.enum) > 0:
+		return t.enum[index%len(t.enum)], true
+	}
+	return "", false
+}
+
 // populateSampleValues populates a struct value with sample data
 func populateSampleValues(value reflect.Value) {
+	populateSampleStruct(value, 0, 0)
+}
+
+// populateSampleStruct populates the fields of a struct, the index-th element of a sample
+// slice, with sample data
+func populateSampleStruct(value reflect.Value, index, depth int) {
 	// Get the struct type
 	typ := value.Type()
 
@@ -62,6 +138,9 @@ func populateSampleValues(value reflect.Value) {
 		if tag == "-" {
 			continue // Skip fields with json:"-"
 		}
+		if fieldType.Tag.Get("prompt") == "-" {
+			continue // Skip fields the LLM doesn't fill in
+		}
 
 		// Get field name from JSON tag or struct field name
 		fieldName := fieldType.Name
@@ -72,109 +151,105 @@ func populateSampleValues(value reflect.Value) {
 			}
 		}
 
-		// Check for default tag value
-		defaultValue := fieldType.Tag.Get("default")
-
-		// Generate sample value based on field type
-		switch field.Kind() {
-		case reflect.String:
-			if defaultValue != "" {
-				// Use the default value if provided
-				field.SetString(defaultValue)
-			} else {
-				// Look for example comments in the field
-				comment := fieldType.Tag.Get("comment")
-				if comment != "" {
-					field.SetString(comment)
-				} else {
-					// Use field name as sample
-					field.SetString("Example " + fieldName)
-				}
-			}
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			if defaultValue != "" {
-				// Convert default string to int
-				if intVal, err := strconv.ParseInt(defaultValue, 10, 64); err == nil {
-					field.SetInt(intVal)
-				} else {
-					field.SetInt(42) // Fallback
-				}
-			} else {
-				field.SetInt(42)
-			}
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			if defaultValue != "" {
-				// Convert default string to uint
-				if uintVal, err := strconv.ParseUint(defaultValue, 10, 64); err == nil {
-					field.SetUint(uintVal)
-				} else {
-					field.SetUint(42) // Fallback
-				}
-			} else {
-				field.SetUint(42)
-			}
-		case reflect.Float32, reflect.Float64:
-			if defaultValue != "" {
-				// Convert default string to float
-				if floatVal, err := strconv.ParseFloat(defaultValue, 64); err == nil {
-					field.SetFloat(floatVal)
-				} else {
-					field.SetFloat(42.5) // Fallback
-				}
-			} else {
-				field.SetFloat(42.5)
-			}
-		case reflect.Bool:
-			if defaultValue != "" {
-				// Convert default string to bool
-				if boolVal, err := strconv.ParseBool(defaultValue); err == nil {
-					field.SetBool(boolVal)
-				} else {
-					field.SetBool(true) // Fallback
-				}
-			} else {
-				field.SetBool(true)
-			}
-		case reflect.Slice:
-			// Create a sample slice with one element
-			sliceType := field.Type().Elem()
-			sampleSlice := reflect.MakeSlice(field.Type(), 1, 1)
-
-			// If it's a slice of struct, populate the struct with defaults too
-			if sliceType.Kind() == reflect.Struct {
-				populateSampleValues(sampleSlice.Index(0))
-			} else if sliceType.Kind() == reflect.String {
-				// For strings, use field name in example
-				if defaultValue != "" {
-					sampleSlice.Index(0).SetString(defaultValue)
-				} else {
-					sampleSlice.Index(0).SetString("Sample " + fieldName + " string")
-				}
-			} else if sliceType.Kind() == reflect.Int {
-				if defaultValue != "" {
-					// Try to parse default as int
-					if intVal, err := strconv.ParseInt(defaultValue, 10, 64); err == nil {
-						sampleSlice.Index(0).SetInt(intVal)
-					} else {
-						sampleSlice.Index(0).SetInt(42)
-					}
-				} else {
-					sampleSlice.Index(0).SetInt(42)
-				}
-			}
+		populateSampleValue(field, fieldSampleTags(fieldType, fieldName), index, depth+1)
+	}
+}
 
-			field.Set(sampleSlice)
-		case reflect.Struct:
-			populateSampleValues(field)
-		case reflect.Ptr:
-			// Create a new instance of the pointed-to type and set it
-			if field.IsNil() {
-				newVal := reflect.New(field.Type().Elem())
-				field.Set(newVal)
-				populateSampleValues(newVal.Elem())
+// populateSampleValue sets a value to the index-th sample of a field with tags
+func populateSampleValue(value reflect.Value, tags sampleTags, index, depth int) {
+	if depth > maxSampleDepth {
+		return
+	}
+	literal, hasLiteral := tags.literal(index)
+
+	if value.Type() == reflect.TypeOf(time.Time{}) {
+		sample := sampleTime
+		if hasLiteral {
+			if parsed, err := parseSampleTime(literal); err == nil {
+				sample = parsed
 			}
 		}
+		value.Set(reflect.ValueOf(sample))
+		return
+	}
+
+	// Generate sample value based on field type
+	switch value.Kind() {
+	case reflect.String:
+		switch {
+		case hasLiteral:
+			value.SetString(literal)
+		case tags.comment != "" && index == 0:
+			value.SetString(tags.comment)
+		case index == 0:
+			value.SetString("Example " + tags.name)
+		default:
+			value.SetString(fmt.Sprintf("Example %s %d", tags.name, index+1))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(42)
+		if intVal, err := strconv.ParseInt(literal, 10, 64); hasLiteral && err == nil {
+			value.SetInt(intVal)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value.SetUint(42)
+		if uintVal, err := strconv.ParseUint(literal, 10, 64); hasLiteral && err == nil {
+			value.SetUint(uintVal)
+		}
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(42.5)
+		if floatVal, err := strconv.ParseFloat(literal, 64); hasLiteral && err == nil {
+			value.SetFloat(floatVal)
+		}
+	case reflect.Bool:
+		value.SetBool(true)
+		if boolVal, err := strconv.ParseBool(literal); hasLiteral && err == nil {
+			value.SetBool(boolVal)
+		}
+	case reflect.Slice:
+		// Byte slices are encoded as base64 text rather than lists
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		length := sampleSliceLength
+		if len(tags.examples) > length {
+			length = min(len(tags.examples), maxSampleSliceLength)
+		}
+		sample := reflect.MakeSlice(value.Type(), length, length)
+		for i := 0; i < length; i++ {
+			populateSampleValue(sample.Index(i), tags, i, depth+1)
+		}
+		value.Set(sample)
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			populateSampleValue(value.Index(i), tags, i, depth+1)
+		}
+	case reflect.Map:
+		// One entry shows the shape of the keys and values
+		key := reflect.New(value.Type().Key()).Elem()
+		populateSampleValue(key, sampleTags{name: tags.name + " key"}, 0, depth+1)
+		element := reflect.New(value.Type().Elem()).Elem()
+		populateSampleValue(element, tags, 0, depth+1)
+		sample := reflect.MakeMap(value.Type())
+		sample.SetMapIndex(key, element)
+		value.Set(sample)
+	case reflect.Struct:
+		populateSampleStruct(value, index, depth)
+	case reflect.Ptr:
+		// Create a new instance of the pointed-to type and set it
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		populateSampleValue(value.Elem(), tags, index, depth+1)
+	}
+}
+
+// parseSampleTime parses the example of a time field as RFC 3339 or a date
+func parseSampleTime(text string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, text); err == nil {
+		return parsed, nil
 	}
+	return time.Parse("2006-01-02", text)
 }
 
 // GetStringValue safely gets a string value from an interface map
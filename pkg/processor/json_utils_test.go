@@ -0,0 +1,66 @@
+package processor
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sampleOwner is a nested struct of sampleResult
+type sampleOwner struct {
+	Team string `json:"team" example:"billing_ops|network"`
+}
+
+// sampleResult is the result struct of TestGenerateJSONExample
+type sampleResult struct {
+	Sentiment     string             `json:"sentiment" enum:"positive,negative,neutral"`
+	Topics        []string           `json:"topics" enum:"billing,outage,other"`
+	Quotes        []string           `json:"quotes" example:"I was charged twice|Please fix it|Thanks"`
+	Keywords      []string           `json:"keywords"`
+	Amount        float64            `json:"amount" example:"19.99"`
+	Opened        time.Time          `json:"opened" example:"2024-05-01"`
+	Scores        map[string]float64 `json:"scores" default:"0.5"`
+	Owner         **sampleOwner      `json:"owner"`
+	Owners        []*sampleOwner     `json:"owners"`
+	Internal      string             `json:"internal,omitempty" prompt:"-"`
+	ProcessorType string             `json:"processor_type"`
+}
+
+func TestGenerateJSONExample(t *testing.T) {
+	var example map[string]interface{}
+	if err := json.Unmarshal([]byte(GenerateJSONExample(&sampleResult{})), &example); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		field string
+		want  interface{}
+	}{
+		{field: "sentiment", want: "positive"},
+		{field: "topics", want: []interface{}{"billing", "outage"}},
+		{field: "quotes", want: []interface{}{"I was charged twice", "Please fix it", "Thanks"}},
+		{field: "keywords", want: []interface{}{"Example keywords", "Example keywords 2"}},
+		{field: "amount", want: 19.99},
+		{field: "opened", want: "2024-05-01T00:00:00Z"},
+		{field: "scores", want: map[string]interface{}{"Example scores key": 0.5}},
+		{field: "owner", want: map[string]interface{}{"team": "billing_ops"}},
+		{field: "owners", want: []interface{}{map[string]interface{}{"team": "billing_ops"}, map[string]interface{}{"team": "network"}}},
+		{field: "internal"},
+		{field: "processor_type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if got := example[tt.field]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %s %v, got %v", tt.field, tt.want, got)
+			}
+		})
+	}
+
+	// The allowed values also constrain native structured output
+	properties := JSONSchema(&sampleResult{})["properties"].(map[string]interface{})
+	topics := properties["topics"].(map[string]interface{})["items"].(map[string]interface{})
+	if want := []interface{}{"billing", "outage", "other"}; !reflect.DeepEqual(topics["enum"], want) {
+		t.Errorf("expected topics enum %v, got %v", want, topics["enum"])
+	}
+}
@@ -0,0 +1,126 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// LanguageInput is the structured input and item state key holding the language of an
+// item's text, as a language tag such as "es" or "pt-BR", that selects a localized prompt
+const LanguageInput = "language"
+
+// PromptLocalization is a variant of a builder prompt for inputs in one language. Fields left
+// empty keep the default prompt's.
+type PromptLocalization struct {
+	Role         string   `json:"role,omitempty" yaml:"role,omitempty"`
+	Objective    string   `json:"objective,omitempty" yaml:"objective,omitempty"`
+	Instructions []string `json:"instructions,omitempty" yaml:"instructions,omitempty"`
+	// Sections replace the content of the custom sections with the same name
+	Sections map[string]string `json:"sections,omitempty" yaml:"sections,omitempty"`
+	// Examples replace the default prompt's few-shot examples
+	Examples []PromptExample `json:"examples,omitempty" yaml:"examples,omitempty"`
+}
+
+// PromptExample is a few-shot example of a builder prompt: an input text and its result
+type PromptExample struct {
+	Input string `json:"input" yaml:"input"`
+	// Output is the expected result, rendered as JSON
+	Output interface{} `json:"output" yaml:"output"`
+}
+
+// PromptLanguage returns the language of the item being prompted for, normalized to a
+// lowercase tag: the LanguageInput of the item's JSON content or metadata, else its item
+// state value set by an earlier step such as language detection, else the processor's
+// Options.Inputs. It returns "" if the language is unknown.
+func PromptLanguage(ctx context.Context) string {
+	inputs, _ := ctx.Value(promptInputsKey{}).(promptInputs)
+	sources := []map[string]interface{}{inputs.content, inputs.metadata, data.ItemStateFromContext(ctx), inputs.options}
+	for _, source := range sources {
+		if language, ok := source[LanguageInput].(string); ok && strings.TrimSpace(language) != "" {
+			return normalizeLanguage(language)
+		}
+	}
+	return ""
+}
+
+// normalizeLanguage returns a language tag in lowercase with hyphens, e.g. "pt_BR" as "pt-br"
+func normalizeLanguage(language string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(language)), "_", "-")
+}
+
+// localizationFor returns the localization of a language, falling back from a regional tag
+// such as "pt-br" to its language "pt"
+func localizationFor(localizations map[string]PromptLocalization, language string) (PromptLocalization, bool) {
+	for language != "" {
+		if localization, ok := localizations[language]; ok {
+			return localization, true
+		}
+		i := strings.LastIndex(language, "-")
+		if i < 0 {
+			break
+		}
+		language = language[:i]
+	}
+	return PromptLocalization{}, false
+}
+
+// localize returns a copy of the generator with the localization's sections in place of
+// the default ones
+func (p *BuilderPromptGenerator) localize(localization PromptLocalization) *BuilderPromptGenerator {
+	localized := *p
+	if localization.Role != "" {
+		localized.role = localization.Role
+	}
+	if localization.Objective != "" {
+		localized.objective = localization.Objective
+	}
+	if len(localization.Instructions) > 0 {
+		localized.instructions = localization.Instructions
+	}
+	if len(localization.Examples) > 0 {
+		localized.examples = localization.Examples
+	}
+	if len(localization.Sections) > 0 {
+		localized.customSections = make([]promptSection, len(p.customSections))
+		for i, section := range p.customSections {
+			if content, ok := localization.Sections[section.name]; ok && section.standard == "" {
+				section.content = content
+			}
+			localized.customSections[i] = section
+		}
+	}
+	return &localized
+}
+
+// languages returns the languages the generator has localizations of, in order
+func (p *BuilderPromptGenerator) languages() []string {
+	languages := make([]string, 0, len(p.localizations))
+	for language := range p.localizations {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// withLanguage returns a context prompting for an item in a language
+func withLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, promptInputsKey{}, promptInputs{options: map[string]interface{}{LanguageInput: language}})
+}
+
+// formatExamples renders few-shot examples as the text of the Examples section
+func formatExamples(examples []PromptExample) (string, error) {
+	parts := make([]string, 0, len(examples))
+	for i, example := range examples {
+		output, err := json.Marshal(example.Output)
+		if err != nil {
+			return "", fmt.Errorf("failed to format example %d: %w", i+1, err)
+		}
+		parts = append(parts, fmt.Sprintf("Example %d:\nInput: %s\nOutput: %s", i+1, example.Input, output))
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
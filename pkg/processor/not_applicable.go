@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// Fields of a not applicable result
+const (
+	// NotApplicableField is true in the result of a processor that abstained because the
+	// input doesn't contain what it analyzes
+	NotApplicableField = "not_applicable"
+	// NotApplicableReasonField holds why the processor abstained
+	NotApplicableReasonField = "reason"
+)
+
+// defaultNotApplicableCondition is the abstention condition of WithNotApplicable("")
+const defaultNotApplicableCondition = "the input text does not contain what you are asked to analyze"
+
+// NotApplicable reports whether a result, such as an item's processing info for a
+// processor, is a not applicable result, and the reason given
+func NotApplicable(result interface{}) (bool, string) {
+	var fields map[string]interface{}
+	switch r := result.(type) {
+	case map[string]interface{}:
+		fields = r
+	case nil:
+		return false, ""
+	default:
+		// Typed results never abstain, but may come back as maps after a JSON round-trip
+		encoded, err := json.Marshal(result)
+		if err != nil || json.Unmarshal(encoded, &fields) != nil {
+			return false, ""
+		}
+	}
+	if notApplicable, _ := fields[NotApplicableField].(bool); !notApplicable {
+		return false, ""
+	}
+	reason, _ := fields[NotApplicableReasonField].(string)
+	return true, reason
+}
+
+// ItemNotApplicable reports whether an item's result for a processor is not applicable,
+// and the reason given
+func ItemNotApplicable(item *data.ProcessItem, processorName string) (bool, string) {
+	return NotApplicable(item.ProcessingInfo[processorName])
+}
+
+// notApplicableInstruction returns the prompt section asking for a not applicable result
+// under a condition
+func notApplicableInstruction(condition string) string {
+	if condition == "" {
+		condition = defaultNotApplicableCondition
+	}
+	return fmt.Sprintf(`If %s, do not guess or fill in default values. Respond only with:
+{"%s": true, "%s": "<why the text does not apply>"}`, condition, NotApplicableField, NotApplicableReasonField)
+}
+
+// notApplicableHandler returns not applicable responses as such, and hands the others to
+// the processor's response handler
+type notApplicableHandler struct {
+	handler ResponseHandler
+	parser  *BaseResponseHandler
+}
+
+// HandleResponse implements the ResponseHandler interface
+func (h notApplicableHandler) HandleResponse(ctx context.Context, text string, responseData interface{}) (interface{}, error) {
+	if fields, validJSON, _ := h.parser.ParseLLMResponse(responseData); validJSON {
+		if notApplicable, reason := NotApplicable(fields); notApplicable {
+			return map[string]interface{}{
+				NotApplicableField:       true,
+				NotApplicableReasonField: reason,
+				"processor_type":         h.parser.ProcessorType,
+			}, nil
+		}
+	}
+	return h.handler.HandleResponse(ctx, text, responseData)
+}
+
+// allowNotApplicable lets a processor return not applicable results: its responses are
+// checked for one first, and its response schema admits one
+func allowNotApplicable(p *GenericProcessor) {
+	handler := notApplicableHandler{
+		handler: p.responseHandler,
+		parser: &BaseResponseHandler{
+			ProcessorType:    p.name,
+			DefaultResponder: func() interface{} { return map[string]interface{}{} },
+		},
+	}
+	p.responseHandler = handler
+	p.BaseProcessor.responseHandler = handler
+
+	if properties, ok := p.structuredOutput.Schema["properties"].(map[string]interface{}); ok {
+		properties[NotApplicableField] = map[string]interface{}{"type": "boolean"}
+		properties[NotApplicableReasonField] = map[string]interface{}{"type": "string"}
+	}
+}
@@ -1,11 +1,17 @@
 package processor
 
+import (
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
 // NewDefaultOptions creates a new Options instance with default settings
 func NewDefaultOptions() Options {
 	return Options{
 		PreProcessOptions:  make(map[string]interface{}),
 		LLMOptions:         make(map[string]interface{}),
 		PostProcessOptions: make(map[string]interface{}),
+		Inputs:             make(map[string]interface{}),
 	}
 }
 
@@ -28,6 +34,75 @@ func (o Options) Clone() Options {
 		result.PostProcessOptions[k] = v
 	}
 
+	// Copy prompt inputs
+	for k, v := range o.Inputs {
+		result.Inputs[k] = v
+	}
+
+	// Copy retry policy
+	if o.Retry != nil {
+		retry := *o.Retry
+		result.Retry = &retry
+	}
+
+	// Copy redaction config
+	if o.Redaction != nil {
+		redaction := *o.Redaction
+		if o.Redaction.Patterns != nil {
+			redaction.Patterns = make(map[string]string, len(o.Redaction.Patterns))
+			for name, pattern := range o.Redaction.Patterns {
+				redaction.Patterns[name] = pattern
+			}
+		}
+		result.Redaction = &redaction
+	}
+
+	result.ValidateProvider = o.ValidateProvider
+	result.InputTokenCost = o.InputTokenCost
+	result.OutputTokenCost = o.OutputTokenCost
+	result.MaxConcurrency = o.MaxConcurrency
+	result.Priority = o.Priority
+	result.ResultOrder = o.ResultOrder
+	result.ResultCache = o.ResultCache
+
+	// Copy routing config
+	if o.Routing != nil {
+		routing := *o.Routing
+		routing.Routes = append([]ModelRoute(nil), o.Routing.Routes...)
+		result.Routing = &routing
+	}
+
+	// Copy packing config
+	if o.Packing != nil {
+		packing := *o.Packing
+		result.Packing = &packing
+	}
+
+	// Copy sanitize config
+	if o.Sanitize != nil {
+		sanitize := *o.Sanitize
+		sanitize.LabelFields = append([]string(nil), o.Sanitize.LabelFields...)
+		if o.Sanitize.Ranges != nil {
+			sanitize.Ranges = make(map[string]Range, len(o.Sanitize.Ranges))
+			for field, bounds := range o.Sanitize.Ranges {
+				sanitize.Ranges[field] = bounds
+			}
+		}
+		result.Sanitize = &sanitize
+	}
+
+	// Copy chunking config
+	if o.Chunking != nil {
+		chunking := *o.Chunking
+		result.Chunking = &chunking
+	}
+
+	// Copy prompt budget
+	if o.PromptBudget != nil {
+		budget := *o.PromptBudget
+		result.PromptBudget = &budget
+	}
+
 	return result
 }
 
@@ -52,6 +127,14 @@ func (o Options) WithPostProcessOption(key string, value interface{}) Options {
 	return result
 }
 
+// WithInput sets a default structured prompt input, e.g. the attribute definitions for
+// get_attributes, and returns the updated Options
+func (o Options) WithInput(key string, value interface{}) Options {
+	result := o.Clone()
+	result.Inputs[key] = value
+	return result
+}
+
 // WithDebug sets the debug mode for the processor
 func (o Options) WithDebug(debug bool) Options {
 	result := o.Clone()
@@ -59,6 +142,110 @@ func (o Options) WithDebug(debug bool) Options {
 	return result
 }
 
+// WithRetryPolicy sets the item-level retry policy used by ProcessSource
+func (o Options) WithRetryPolicy(policy data.RetryPolicy) Options {
+	result := o.Clone()
+	result.Retry = &policy
+	return result
+}
+
+// WithRedaction redacts the prompts and raw responses stored as debug output, so results
+// can be persisted and shared
+func (o Options) WithRedaction(config RedactionConfig) Options {
+	result := o.Clone()
+	result.Redaction = &config
+	return result
+}
+
+// WithProviderValidation makes Create verify the provider's API key and model with a cheap
+// check, so misconfiguration fails immediately instead of mid-batch
+func (o Options) WithProviderValidation() Options {
+	result := o.Clone()
+	result.ValidateProvider = true
+	return result
+}
+
+// WithRouting sends each item to one of several models of the provider, e.g. short inputs to
+// a cheap model and long or complex ones to a stronger model
+func (o Options) WithRouting(config RoutingConfig) Options {
+	result := o.Clone()
+	result.Routing = &config
+	return result
+}
+
+// WithPacking packs several short items into each LLM call, e.g. tweets or survey answers,
+// and splits the response back into per-item results
+func (o Options) WithPacking(config PackingConfig) Options {
+	result := o.Clone()
+	result.Packing = &config
+	return result
+}
+
+// WithTokenCost sets the prices per million prompt and response tokens, used to estimate
+// the cost recorded in each result's processing info
+func (o Options) WithTokenCost(inputTokenCost, outputTokenCost float64) Options {
+	result := o.Clone()
+	result.InputTokenCost = inputTokenCost
+	result.OutputTokenCost = outputTokenCost
+	return result
+}
+
+// WithChunking processes items whose prompt the provider rejects for exceeding the model's
+// context length in chunks, merging the chunks' results
+func (o Options) WithChunking(config ChunkingConfig) Options {
+	result := o.Clone()
+	result.Chunking = &config
+	return result
+}
+
+// WithPromptBudget bounds builder prompts to a share of the model's context window. Prompts
+// over budget drop optional sections, such as custom guidance, before the input text is
+// truncated; the dropped sections are recorded in the result's processing info.
+func (o Options) WithPromptBudget(budget PromptBudget) Options {
+	result := o.Clone()
+	result.PromptBudget = &budget
+	return result
+}
+
+// WithSanitize sanitizes results after they are mapped, e.g. trimming whitespace and
+// normalizing the casing of labels
+func (o Options) WithSanitize(config SanitizeConfig) Options {
+	result := o.Clone()
+	result.Sanitize = &config
+	return result
+}
+
+// WithResultCache stores results in cache by their provenance hash and answers items
+// already processed with the same prompt and model from it
+func (o Options) WithResultCache(cache llm.Cache) Options {
+	result := o.Clone()
+	result.ResultCache = cache
+	return result
+}
+
+// WithMaxConcurrency limits the processor to n LLM calls at once
+func (o Options) WithMaxConcurrency(n int) Options {
+	result := o.Clone()
+	result.MaxConcurrency = n
+	return result
+}
+
+// WithPriority starts items of higher priority first in ProcessSource and its streaming
+// variants, e.g. data.PriorityFromMetadata("priority")
+func (o Options) WithPriority(priority func(item *data.ProcessItem) int) Options {
+	result := o.Clone()
+	result.Priority = priority
+	return result
+}
+
+// WithResultOrder sets the order ProcessSourceStream and ProcessSourceToSink deliver
+// results in, e.g. data.OrderSource for sinks whose consumers expect the source's order
+func (o Options) WithResultOrder(order data.ResultOrder) Options {
+	result := o.Clone()
+	result.ResultOrder = order
+	return result
+}
+
 // GetDebugEnabled returns whether debug mode is enabled
 func (o Options) GetDebugEnabled() bool {
 	if o.LLMOptions == nil {
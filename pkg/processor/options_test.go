@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// concurrencyProvider records the most calls in progress at once
+type concurrencyProvider struct {
+	*llm.MockProvider
+	mu      sync.Mutex
+	current int
+	peak    int
+}
+
+// Generate implements the llm.Provider interface
+func (p *concurrencyProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	p.mu.Lock()
+	p.current++
+	p.peak = max(p.peak, p.current)
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.current--
+		p.mu.Unlock()
+	}()
+	return p.MockProvider.Generate(ctx, prompt)
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		wantPeak       int
+	}{
+		{name: "limited", maxConcurrency: 2, wantPeak: 2},
+		{name: "single", maxConcurrency: 1, wantPeak: 1},
+		{name: "unlimited", wantPeak: 8},
+	}
+
+	builder := NewBuilder("throttled").WithStruct(&limitResult{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &concurrencyProvider{
+				MockProvider: llm.NewMockProviderWithResponse(`{"summary": "ok"}`).WithLatency(20 * time.Millisecond),
+			}
+			proc, err := builder.Build(provider, NewDefaultOptions().WithMaxConcurrency(tt.maxConcurrency))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Workers of ProcessSource are capped to the CPUs, so goroutines share the processor
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 2; j++ {
+						if _, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "text", nil)); err != nil {
+							t.Error(err)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			if provider.peak != tt.wantPeak {
+				t.Errorf("expected at most %d calls at once, got %d", tt.wantPeak, provider.peak)
+			}
+		})
+	}
+}
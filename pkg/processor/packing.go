@@ -0,0 +1,367 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+const (
+	// DefaultPackItems is the default number of items packed into one LLM call
+	DefaultPackItems = 10
+	// DefaultPackItemTokens is the default longest input, in estimated tokens, that is packed
+	DefaultPackItemTokens = 200
+)
+
+// PackingConfig packs several short items into one LLM call, which cuts the request
+// overhead and cost of very short inputs such as tweets or survey answers by up to the
+// pack size
+type PackingConfig struct {
+	// MaxItems is the largest number of items per call (defaults to DefaultPackItems)
+	MaxItems int `json:"max_items,omitempty" yaml:"max_items,omitempty"`
+	// MaxItemTokens is the longest input, in estimated tokens, that is packed (defaults to
+	// DefaultPackItemTokens). Longer items are processed on their own.
+	MaxItemTokens int `json:"max_item_tokens,omitempty" yaml:"max_item_tokens,omitempty"`
+}
+
+// packSize returns the number of items per pack
+func (c PackingConfig) packSize() int {
+	if c.MaxItems <= 0 {
+		return DefaultPackItems
+	}
+	return c.MaxItems
+}
+
+// maxItemTokens returns the longest input that is packed
+func (c PackingConfig) maxItemTokens() int64 {
+	if c.MaxItemTokens <= 0 {
+		return DefaultPackItemTokens
+	}
+	return int64(c.MaxItemTokens)
+}
+
+// packMarker stands in for an item's text when generating the prompt a pack is built from
+const packMarker = "<<<AGENTIC_TEXT_PACKED_INPUT>>>"
+
+// packIndexField is the field of each packed result naming the input it belongs to
+const packIndexField = "input_index"
+
+// packInstruction is appended to packed prompts; it is formatted with the number of inputs
+const packInstruction = `
+
+**Packed Input:** The input above consists of %d separate texts, numbered [1] to [%[1]d].
+Analyze each text on its own. Respond with a JSON array of %[1]d objects, one per text
+in the same order, each with an "` + packIndexField + `" field holding the text's number
+and the fields of the response format described above.`
+
+// packMember is an item of a pack, prepared for the packed call
+type packMember struct {
+	// index is the item's position in the pack
+	index    int
+	prepared preparedItem
+}
+
+// processPack processes items with as few LLM calls as possible: items that are short
+// enough and share the same prompt are packed into one call and the response is split
+// back into their results. Items that can't be packed, or whose packed result is missing
+// or invalid, are processed on their own with the configured retry policy.
+func (p *BaseProcessor) processPack(ctx context.Context, items []*data.ProcessItem) ([]*data.ProcessItem, error) {
+	if p.redactErr != nil {
+		return nil, p.redactErr
+	}
+
+	results := make([]*data.ProcessItem, len(items))
+	if members, template := p.packMembers(ctx, items); len(members) > 1 {
+		// A failed packed call leaves its items to be processed on their own
+		packed, _ := p.callPack(ctx, items, members, template)
+		for i, result := range packed {
+			if result != nil {
+				results[members[i].index] = result
+			}
+		}
+	}
+
+	for i, item := range items {
+		if results[i] != nil {
+			continue
+		}
+		result, err := p.processWithRetry(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// packMembers returns the items that can be packed into one call and the prompt template
+// they share: short items whose prompt, generated with a marker for their text, is the same
+// as the first such item's
+func (p *BaseProcessor) packMembers(ctx context.Context, items []*data.ProcessItem) ([]packMember, string) {
+	if p.llmClient == nil || p.promptGenerator == nil {
+		return nil, ""
+	}
+
+	var (
+		members  []packMember
+		template string
+	)
+	for i, item := range items {
+		// Items that fail to prepare fail again, with their error, on their own
+		prepared, err := p.prepare(ctx, item)
+		if err != nil || llm.EstimateTokens(prepared.text) > p.options.Packing.maxItemTokens() {
+			continue
+		}
+		prompt, err := p.promptGenerator.GeneratePrompt(prepared.ctx, packMarker)
+		if err != nil || strings.Count(prompt, packMarker) != 1 {
+			continue
+		}
+		if template == "" {
+			template = prompt
+		} else if prompt != template {
+			continue
+		}
+		members = append(members, packMember{index: i, prepared: prepared})
+	}
+	return members, template
+}
+
+// callPack makes the packed call of a pack's members and returns their results, nil for
+// members whose result is missing or invalid
+func (p *BaseProcessor) callPack(ctx context.Context, items []*data.ProcessItem, members []packMember, template string) ([]*data.ProcessItem, error) {
+	var (
+		inputs strings.Builder
+		texts  = make([]string, len(members))
+		ids    = make([]string, len(members))
+	)
+	for i, member := range members {
+		fmt.Fprintf(&inputs, "[%d] %s\n\n", i+1, member.prepared.text)
+		texts[i] = member.prepared.text
+		ids[i] = items[member.index].ID
+	}
+	prompt := strings.Replace(template, packMarker, strings.TrimSpace(inputs.String()), 1) +
+		fmt.Sprintf(packInstruction, len(members))
+
+	if p.options.GetDebugEnabled() {
+		DebugLLMInteraction(p.redactPrompt(prompt), "")
+	}
+
+	// Route the pack by all of its texts if routing is configured
+	client, routeName := p.llmClient, ""
+	if len(p.routes) > 0 {
+		route, err := p.route(ctx, strings.Join(texts, "\n"))
+		if err != nil {
+			return nil, err
+		}
+		client, routeName = route.client, route.Name
+	}
+
+	// The response is an array, so native structured output is limited to JSON mode
+	callCtx := llm.WithInteractionSource(ctx, p.name, strings.Join(ids, ","))
+	if p.structuredOutput.Mode != "" && p.structuredOutput.Mode != llm.StructuredOutputPrompt {
+		callCtx = llm.WithStructuredOutput(callCtx, llm.StructuredOutput{Mode: llm.StructuredOutputJSONMode, Name: p.name})
+	}
+
+	start := time.Now()
+	response, err := p.complete(callCtx, client, prompt)
+	latency := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := splitPackedResponse(response, len(members))
+	if err != nil {
+		return nil, err
+	}
+
+	// Each item is charged an equal share of the call
+	inputTokens := llm.EstimateTokens(prompt) / int64(len(members))
+	outputTokens := estimateResponseTokens(response) / int64(len(members))
+	recorder := data.RunRecorderFromContext(ctx)
+
+	results := make([]*data.ProcessItem, len(members))
+	for i, member := range members {
+		if elements[i] == nil {
+			continue
+		}
+		result, err := p.handleResponse(member.prepared, prompt, elements[i])
+		if err != nil {
+			continue
+		}
+		if info, ok := result.ProcessingInfo[p.name].(map[string]interface{}); ok {
+			info["packed"] = len(members)
+			if routeName != "" {
+				info["route"] = routeName
+			}
+		}
+		p.recordUsage(result, inputTokens, outputTokens)
+		if p.redactor != nil {
+			p.redactDebug(result)
+		}
+		recorder.Record(p.name, data.ItemOutcome{
+			ItemID:       ids[i],
+			Latency:      latency,
+			InputTokens:  inputTokens,
+			OutputTokens: outputTokens,
+		})
+		results[i] = result
+	}
+	return results, nil
+}
+
+// splitPackedResponse splits the response to a packed prompt into the responses to its n
+// inputs, by their input_index or else their position. Inputs without a response are nil.
+func splitPackedResponse(response interface{}, n int) ([]interface{}, error) {
+	var elements []interface{}
+	switch r := response.(type) {
+	case []interface{}:
+		elements = r
+	case map[string]interface{}:
+		// JSON modes that only allow objects wrap the array in one
+		for _, value := range r {
+			if array, ok := value.([]interface{}); ok {
+				elements = array
+				break
+			}
+		}
+	case string:
+		start, end := strings.Index(r, "["), strings.LastIndex(r, "]")
+		if start < 0 || end < start {
+			return nil, fmt.Errorf("packed response is not a JSON array")
+		}
+		if err := json.Unmarshal([]byte(r[start:end+1]), &elements); err != nil {
+			return nil, fmt.Errorf("failed to parse packed response: %w", err)
+		}
+	}
+	if elements == nil {
+		return nil, fmt.Errorf("packed response is not a JSON array")
+	}
+
+	split := make([]interface{}, n)
+	for position, element := range elements {
+		fields, ok := element.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		index := position + 1
+		if value, ok := fields[packIndexField].(float64); ok {
+			index = int(value)
+		}
+		delete(fields, packIndexField)
+		if index >= 1 && index <= n && split[index-1] == nil {
+			split[index-1] = fields
+		}
+	}
+	return split, nil
+}
+
+// processSourcePacked processes all items from a source in packs, with up to workers packs
+// in flight at once (defaults to data.DefaultWorkers, capped at the CPU count). Results
+// keep the source order and processing stops at the first error. If ctx is cancelled, the
+// results of the packs completed until then are returned with ctx.Err().
+func (p *BaseProcessor) processSourcePacked(ctx context.Context, source data.ProcessItemSource, workers int) ([]*data.ProcessItem, error) {
+	if workers <= 0 {
+		workers = data.DefaultWorkers
+	}
+	workers = min(workers, runtime.NumCPU())
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type pack struct {
+		index int
+		items []*data.ProcessItem
+	}
+
+	var (
+		mu       sync.Mutex
+		packed   [][]*data.ProcessItem
+		firstErr error
+		errIndex int
+	)
+	fail := func(index int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Keep the error of the earliest pack, mirroring sequential processing
+		if firstErr == nil || index < errIndex {
+			firstErr, errIndex = err, index
+		}
+		cancel()
+	}
+
+	batcher := data.NewProcessItemBatchProcessor(source, p.options.Packing.packSize())
+	defer batcher.Close()
+	tracker := data.NewProgressTracker(runCtx, data.SourceLen(source))
+	packs := make(chan pack)
+
+	go func() {
+		defer close(packs)
+		for index := 0; ; index++ {
+			items, err := batcher.NextBatch(runCtx)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				// Source errors sort after any item error
+				if runCtx.Err() == nil {
+					fail(math.MaxInt, err)
+				}
+				return
+			}
+			select {
+			case packs <- pack{index: index, items: items}:
+			case <-runCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next := range packs {
+				if runCtx.Err() != nil {
+					continue
+				}
+				results, err := p.processPack(runCtx, next.items)
+				for range next.items {
+					tracker.Record(err)
+				}
+				if err != nil {
+					fail(next.index, err)
+					continue
+				}
+				mu.Lock()
+				for len(packed) <= next.index {
+					packed = append(packed, nil)
+				}
+				packed[next.index] = results
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var results []*data.ProcessItem
+	for _, pack := range packed {
+		results = append(results, pack...)
+	}
+	// Like data.ProcessItemParallelProcessor, a cancelled run returns the completed packs
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
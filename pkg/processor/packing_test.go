@@ -0,0 +1,224 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// packResult is the result struct of the packing tests
+type packResult struct {
+	Label         string `json:"label"`
+	ProcessorType string `json:"processor_type"`
+}
+
+// packFailingProvider answers like its mock provider but fails packed prompts
+type packFailingProvider struct {
+	*llm.MockProvider
+}
+
+func (p packFailingProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	if strings.Contains(prompt, "Packed Input") {
+		return "", errors.New("provider unavailable")
+	}
+	return p.MockProvider.Generate(ctx, prompt)
+}
+
+func (p packFailingProvider) GenerateJSON(ctx context.Context, prompt string, responseStruct interface{}) error {
+	if strings.Contains(prompt, "Packed Input") {
+		return errors.New("provider unavailable")
+	}
+	return p.MockProvider.GenerateJSON(ctx, prompt, responseStruct)
+}
+
+func TestPacking(t *testing.T) {
+	texts := []string{"first", "second", "third"}
+	tests := []struct {
+		name string
+		// packed is the response to packed prompts; other prompts are answered with "single"
+		packed        string
+		failPacked    bool
+		maxItemTokens int
+		wantLabels    []string
+		wantPacked    []bool
+		wantCalls     int
+	}{
+		{
+			name:       "in order",
+			packed:     `[{"input_index": 1, "label": "a"}, {"input_index": 2, "label": "b"}, {"input_index": 3, "label": "c"}]`,
+			wantLabels: []string{"a", "b", "c"},
+			wantPacked: []bool{true, true, true},
+			wantCalls:  1,
+		},
+		{
+			name:       "out of order indexes",
+			packed:     `[{"input_index": 3, "label": "c"}, {"input_index": 1, "label": "a"}, {"input_index": 2, "label": "b"}]`,
+			wantLabels: []string{"a", "b", "c"},
+			wantPacked: []bool{true, true, true},
+			wantCalls:  1,
+		},
+		{
+			name:       "positions without indexes",
+			packed:     `[{"label": "a"}, {"label": "b"}, {"label": "c"}]`,
+			wantLabels: []string{"a", "b", "c"},
+			wantPacked: []bool{true, true, true},
+			wantCalls:  1,
+		},
+		{
+			name:       "missing element",
+			packed:     `[{"input_index": 1, "label": "a"}, {"input_index": 3, "label": "c"}]`,
+			wantLabels: []string{"a", "single", "c"},
+			wantPacked: []bool{true, false, true},
+			wantCalls:  2,
+		},
+		{
+			name:       "wrapped array",
+			packed:     `{"results": [{"input_index": 1, "label": "a"}, {"input_index": 2, "label": "b"}, {"input_index": 3, "label": "c"}]}`,
+			wantLabels: []string{"a", "b", "c"},
+			wantPacked: []bool{true, true, true},
+			wantCalls:  1,
+		},
+		{
+			name:       "invalid packed response",
+			packed:     `Sorry, I can only analyze one text at a time.`,
+			wantLabels: []string{"single", "single", "single"},
+			wantPacked: []bool{false, false, false},
+			wantCalls:  4,
+		},
+		{
+			name:       "failed packed call",
+			failPacked: true,
+			wantLabels: []string{"single", "single", "single"},
+			wantPacked: []bool{false, false, false},
+			wantCalls:  4,
+		},
+		{
+			// Only "first" and "third" are short enough to pack
+			name:          "long item on its own",
+			packed:        `[{"input_index": 1, "label": "a"}, {"input_index": 2, "label": "c"}]`,
+			maxItemTokens: 3,
+			wantLabels:    []string{"a", "single", "c"},
+			wantPacked:    []bool{true, false, true},
+			wantCalls:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := llm.NewMockProviderWithResponse(`{"label": "single"}`)
+			var provider llm.Provider = mock
+			if tt.failPacked {
+				provider = packFailingProvider{MockProvider: mock}
+			} else {
+				mock.WithResponse("Packed Input", tt.packed)
+			}
+			options := NewDefaultOptions().WithPacking(PackingConfig{MaxItemTokens: tt.maxItemTokens})
+			proc, err := NewBuilder("packed").WithStruct(&packResult{}).Build(provider, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			items := make([]*data.ProcessItem, len(texts))
+			for i, text := range texts {
+				if tt.maxItemTokens > 0 && i == 1 {
+					text = strings.Repeat(text+" ", 10)
+				}
+				items[i] = data.NewTextProcessItem(text, text, nil)
+			}
+			results, err := proc.ProcessBatch(context.Background(), items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(texts) {
+				t.Fatalf("expected %d results, got %d", len(texts), len(results))
+			}
+
+			for i, result := range results {
+				if result.ID != items[i].ID {
+					t.Errorf("result %d: expected item %s, got %s", i, items[i].ID, result.ID)
+				}
+				if label := result.Content.(*packResult).Label; label != tt.wantLabels[i] {
+					t.Errorf("result %d: expected label %s, got %s", i, tt.wantLabels[i], label)
+				}
+				info := result.ProcessingInfo["packed"].(map[string]interface{})
+				if _, packed := info["packed"]; packed != tt.wantPacked[i] {
+					t.Errorf("result %d: expected packed %t, got info %v", i, tt.wantPacked[i], info)
+				}
+			}
+			// The failing provider doesn't record the packed call
+			calls := len(mock.Prompts())
+			if tt.failPacked {
+				calls++
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestSplitPackedResponse(t *testing.T) {
+	element := func(label string) map[string]interface{} { return map[string]interface{}{"label": label} }
+	tests := []struct {
+		name     string
+		response interface{}
+		want     []interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "array",
+			response: []interface{}{map[string]interface{}{"input_index": 2.0, "label": "b"}, map[string]interface{}{"input_index": 1.0, "label": "a"}},
+			want:     []interface{}{element("a"), element("b")},
+		},
+		{
+			name:     "wrapped array",
+			response: map[string]interface{}{"results": []interface{}{map[string]interface{}{"label": "a"}, map[string]interface{}{"label": "b"}}},
+			want:     []interface{}{element("a"), element("b")},
+		},
+		{
+			name:     "array in text",
+			response: "Here you go:\n```json\n[{\"input_index\": 1, \"label\": \"a\"}, {\"input_index\": 2, \"label\": \"b\"}]\n```",
+			want:     []interface{}{element("a"), element("b")},
+		},
+		{
+			name: "first of duplicate indexes",
+			response: []interface{}{
+				map[string]interface{}{"input_index": 1.0, "label": "a"},
+				map[string]interface{}{"input_index": 1.0, "label": "again"},
+				map[string]interface{}{"input_index": 2.0, "label": "b"},
+			},
+			want: []interface{}{element("a"), element("b")},
+		},
+		{
+			name:     "index out of range and non-objects are skipped",
+			response: []interface{}{map[string]interface{}{"input_index": 5.0, "label": "x"}, "b", map[string]interface{}{"input_index": 1.0, "label": "a"}},
+			want:     []interface{}{element("a"), nil},
+		},
+		{name: "object without array", response: map[string]interface{}{"label": "a"}, wantErr: true},
+		{name: "text without array", response: "no array here", wantErr: true},
+		{name: "invalid array", response: "[{\"label\": }]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitPackedResponse(tt.response, 2)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
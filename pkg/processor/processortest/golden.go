@@ -0,0 +1,220 @@
+// Package processortest provides helpers for testing processors: golden-file snapshots of
+// the prompts they send, and offline replays of recorded provider responses.
+package processortest
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// update rewrites the golden files instead of comparing prompts with them
+var update = flag.Bool("update-prompts", false, "rewrite golden prompt files instead of comparing with them")
+
+// Input is an input rendered into the prompts of the processors under test
+type Input struct {
+	// Name names the input's golden files, e.g. "short"
+	Name string
+	Text string
+	// State is the item state set by earlier pipeline steps, for processors reading it
+	State map[string]interface{}
+	// Metadata is the item's metadata, e.g. structured inputs of processors reading them
+	Metadata map[string]interface{}
+}
+
+// CanonicalInputs are the inputs GoldenPrompts renders by default
+var CanonicalInputs = []Input{
+	{
+		Name: "short",
+		Text: "I love this product!",
+	},
+	{
+		Name: "complaint",
+		Text: "I was charged twice for my subscription this month and nobody has answered my " +
+			"emails for a week. Please refund the extra charge and tell me why this happened.",
+		State: map[string]interface{}{"language": "en", "customer_tier": "gold"},
+	},
+	{
+		Name: "conversation",
+		Text: "Agent: Thanks for calling, how can I help?\n" +
+			"Customer: My internet keeps dropping every evening.\n" +
+			"Agent: I'm sorry to hear that. Let me run a line test.\n" +
+			"Customer: Okay, but this is the third time I'm calling about it.",
+	},
+}
+
+// separator separates the prompts of a processor that calls its provider more than once
+const separator = "\n\n----- next prompt -----\n\n"
+
+// RenderPrompts returns the prompts a registered processor sends to its provider when
+// processing an item. The processor runs against a mock provider answering "{}", so
+// pre-processing and state handling are included; an error processing that response is
+// ignored once the prompts are sent.
+func RenderPrompts(ctx context.Context, name string, item *data.ProcessItem, options processor.Options) ([]string, error) {
+	provider := llm.NewMockProviderWithResponse("{}")
+	proc, err := processor.Create(name, provider, options)
+	if err != nil {
+		return nil, err
+	}
+	_, err = proc.Process(ctx, item)
+	prompts := provider.Prompts()
+	if len(prompts) == 0 {
+		if err != nil {
+			return nil, fmt.Errorf("processor %s sent no prompt: %w", name, err)
+		}
+		return nil, fmt.Errorf("processor %s sent no prompt", name)
+	}
+	return prompts, nil
+}
+
+// GoldenConfig holds the configuration of GoldenPrompts
+type GoldenConfig struct {
+	// Dir is the directory of the golden files (defaults to "testdata/prompts")
+	Dir string
+	// Processors are the processors to render (defaults to every registered processor calling an LLM)
+	Processors []string
+	// Inputs are the inputs to render (defaults to CanonicalInputs)
+	Inputs []Input
+	// Options are the options the processors are created with
+	Options processor.Options
+}
+
+// GoldenPrompts renders the prompt of every processor for every input and compares it with
+// the golden file <Dir>/<processor>/<input>.txt, failing with a diff when they differ. Run
+// the test with -update-prompts to write the golden files after an intended change.
+func GoldenPrompts(t *testing.T, config GoldenConfig) {
+	t.Helper()
+	if config.Dir == "" {
+		config.Dir = filepath.Join("testdata", "prompts")
+	}
+	if config.Processors == nil {
+		config.Processors = llmProcessors()
+	}
+	if config.Inputs == nil {
+		config.Inputs = CanonicalInputs
+	}
+
+	for _, name := range config.Processors {
+		for _, input := range config.Inputs {
+			t.Run(name+"/"+input.Name, func(t *testing.T) {
+				item := data.NewTextProcessItem(input.Name, input.Text, input.Metadata)
+				for key, value := range input.State {
+					item.SetState(key, value)
+				}
+				prompts, err := RenderPrompts(context.Background(), name, item, config.Options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := strings.Join(prompts, separator) + "\n"
+				path := filepath.Join(config.Dir, name, input.Name+".txt")
+
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+
+				want, err := os.ReadFile(path)
+				if os.IsNotExist(err) {
+					t.Fatalf("golden file %s doesn't exist; run the test with -update-prompts to create it", path)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(want) != got {
+					t.Errorf("prompt differs from %s (- golden, + current); run the test with -update-prompts if the change is intended:\n%s",
+						path, diffLines(string(want), got))
+				}
+			})
+		}
+	}
+}
+
+// diffLines returns the lines removed from want ("-") and added in got ("+"), with the
+// unchanged lines next to them for context
+func diffLines(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j]})
+			j++
+		}
+	}
+
+	// Only changes and the two lines around them are shown
+	const contextLines = 2
+	var out strings.Builder
+	last := -1
+	for k, l := range lines {
+		near := false
+		for d := max(0, k-contextLines); d <= min(len(lines)-1, k+contextLines); d++ {
+			if lines[d].op != ' ' {
+				near = true
+				break
+			}
+		}
+		if !near {
+			continue
+		}
+		if last >= 0 && k > last+1 {
+			out.WriteString("...\n")
+		}
+		fmt.Fprintf(&out, "%c %s\n", l.op, l.text)
+		last = k
+	}
+	return out.String()
+}
+
+// llmProcessors returns the registered processors calling an LLM, which have prompts and
+// responses to test
+func llmProcessors() []string {
+	var names []string
+	for _, name := range processor.ListProcessors() {
+		if !processor.IsComputed(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
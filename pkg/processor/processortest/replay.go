@@ -0,0 +1,228 @@
+package processortest
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// updateResults rewrites the expected results of fixtures instead of comparing with them
+var updateResults = flag.Bool("update-results", false, "rewrite the expected results of recorded fixtures instead of comparing with them")
+
+// Fixture files, in <dir>/<processor>/<case>/
+const (
+	inputFile    = "input.txt"
+	responseFile = "response.txt"
+	resultFile   = "result.json"
+)
+
+// ReplayConfig holds the configuration of ReplayFixtures
+type ReplayConfig struct {
+	// Dir is the directory of the fixtures (defaults to "testdata/fixtures")
+	Dir string
+	// Processors are the processors to replay (defaults to every registered processor calling an LLM).
+	// Each must have at least one fixture.
+	Processors []string
+	// Options are the options the processors are created with
+	Options processor.Options
+}
+
+// ReplayFixtures runs every processor on the inputs of its recorded fixtures, answering with
+// the recorded provider responses, and compares each result with the fixture's expected
+// result. This exercises the whole Process path (prompt, response parsing, field mapping and
+// ProcessingInfo) offline. Run the test with -update-results to rewrite the expected results
+// after an intended change.
+//
+// A fixture is a directory <Dir>/<processor>/<case> holding input.txt (the text processed),
+// response.txt (the raw provider response) and result.json (the processor's expected
+// result), as written by RecordFixture.
+func ReplayFixtures(t *testing.T, config ReplayConfig) {
+	t.Helper()
+	if config.Dir == "" {
+		config.Dir = filepath.Join("testdata", "fixtures")
+	}
+	if config.Processors == nil {
+		config.Processors = llmProcessors()
+	}
+
+	for _, name := range config.Processors {
+		entries, err := os.ReadDir(filepath.Join(config.Dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		var cases []string
+		for _, entry := range entries {
+			if entry.IsDir() {
+				cases = append(cases, entry.Name())
+			}
+		}
+		if len(cases) == 0 {
+			t.Errorf("processor %s has no fixtures in %s; record one with processortest.RecordFixture", name, filepath.Join(config.Dir, name))
+			continue
+		}
+
+		for _, caseName := range cases {
+			t.Run(name+"/"+caseName, func(t *testing.T) {
+				replayFixture(t, filepath.Join(config.Dir, name, caseName), name, config.Options)
+			})
+		}
+	}
+}
+
+// replayFixture replays one fixture
+func replayFixture(t *testing.T, dir, name string, options processor.Options) {
+	input, err := os.ReadFile(filepath.Join(dir, inputFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := os.ReadFile(filepath.Join(dir, responseFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proc, err := processor.Create(name, llm.NewMockProviderWithResponse(string(response)), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := proc.Process(context.Background(), data.NewTextProcessItem(filepath.Base(dir), trimInput(input), nil))
+	if err != nil {
+		t.Fatalf("failed to process the recorded response: %v", err)
+	}
+	got, err := encodeResult(item.ProcessingInfo[name])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, resultFile)
+	if *updateResults {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("expected result %s doesn't exist; run the test with -update-results to create it", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Results are compared as JSON values so formatting of the file doesn't matter
+	var wantValue, gotValue interface{}
+	if err := json.Unmarshal(want, &wantValue); err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(wantValue, gotValue) {
+		t.Errorf("result differs from %s (- expected, + current); run the test with -update-results if the change is intended:\n%s",
+			path, diffLines(string(want), string(got)))
+	}
+}
+
+// RecordFixture processes an input with a registered processor and a real provider, and
+// writes the input, the raw provider response and the result as a fixture in
+// <dir>/<processor>/<caseName> for ReplayFixtures
+func RecordFixture(ctx context.Context, provider llm.Provider, dir, name, caseName, input string, options processor.Options) error {
+	recorder := &recordingProvider{Provider: provider}
+	proc, err := processor.Create(name, recorder, options)
+	if err != nil {
+		return err
+	}
+	item, err := proc.Process(ctx, data.NewTextProcessItem(caseName, input, nil))
+	if err != nil {
+		return fmt.Errorf("failed to process input: %w", err)
+	}
+
+	responses := recorder.responses()
+	if len(responses) != 1 {
+		return fmt.Errorf("processor %s made %d text requests; fixtures record exactly one", name, len(responses))
+	}
+	result, err := encodeResult(item.ProcessingInfo[name])
+	if err != nil {
+		return err
+	}
+
+	caseDir := filepath.Join(dir, name, caseName)
+	if err := os.MkdirAll(caseDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	files := map[string][]byte{
+		inputFile:    []byte(input + "\n"),
+		responseFile: []byte(responses[0]),
+		resultFile:   result,
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(caseDir, file), content, 0o644); err != nil {
+			return fmt.Errorf("failed to write fixture: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordingProvider records the text responses of the provider it wraps
+type recordingProvider struct {
+	llm.Provider
+
+	mu       sync.Mutex
+	recorded []string
+}
+
+// Generate implements llm.Provider
+func (p *recordingProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	response, err := p.Provider.Generate(ctx, prompt)
+	if err == nil {
+		p.mu.Lock()
+		p.recorded = append(p.recorded, response)
+		p.mu.Unlock()
+	}
+	return response, err
+}
+
+// responses returns the recorded responses
+func (p *recordingProvider) responses() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.recorded...)
+}
+
+// usageKeys are the processing info keys holding estimated usage, which changes with every
+// prompt edit and is left out of stored results
+var usageKeys = []string{"tokens", "cost"}
+
+// encodeResult encodes a result without its usage as indented JSON, as stored in result.json
+func encodeResult(result interface{}) ([]byte, error) {
+	if info, ok := result.(map[string]interface{}); ok {
+		stripped := make(map[string]interface{}, len(info))
+		for key, value := range info {
+			stripped[key] = value
+		}
+		for _, key := range usageKeys {
+			delete(stripped, key)
+		}
+		result = stripped
+	}
+	encoded, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return append(encoded, '\n'), nil
+}
+
+// trimInput removes the line ending that editors add to the end of input.txt
+func trimInput(input []byte) string {
+	return strings.TrimSuffix(strings.TrimSuffix(string(input), "\n"), "\r")
+}
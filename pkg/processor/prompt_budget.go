@@ -0,0 +1,148 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// DefaultPromptFraction is the share of the context window builder prompts may fill when a
+// PromptBudget doesn't set one, leaving the rest for the response
+const DefaultPromptFraction = 0.75
+
+// truncationMarker ends input text truncated to fit a prompt budget
+const truncationMarker = " [truncated]"
+
+// PromptBudget bounds builder prompts to a share of the model's context window. A prompt over
+// budget is tightened by dropping optional sections, in order: custom sections, last added
+// first, then few-shot examples, then standard sections, last added first, then the Context
+// section of item state. If it is still over budget, the input text is truncated.
+type PromptBudget struct {
+	// ContextTokens is the model's context window in tokens
+	ContextTokens int `json:"context_tokens" yaml:"context_tokens"`
+	// Fraction is the share of the context window the prompt may fill (defaults to
+	// DefaultPromptFraction)
+	Fraction float64 `json:"fraction,omitempty" yaml:"fraction,omitempty"`
+}
+
+// tokens returns the most estimated tokens a prompt may have
+func (b PromptBudget) tokens() int64 {
+	fraction := b.Fraction
+	if fraction <= 0 || fraction > 1 {
+		fraction = DefaultPromptFraction
+	}
+	return int64(float64(b.ContextTokens) * fraction)
+}
+
+// promptTrim records how a prompt was tightened to fit its budget
+type promptTrim struct {
+	// dropped are the optional sections left out, in the order they were dropped
+	dropped []string
+	// truncated reports whether the input text was truncated
+	truncated bool
+}
+
+// info returns the trim as processing info, or nil if the prompt wasn't tightened
+func (t *promptTrim) info() map[string]interface{} {
+	if t == nil || (len(t.dropped) == 0 && !t.truncated) {
+		return nil
+	}
+	return map[string]interface{}{
+		"dropped_sections": append([]string{}, t.dropped...),
+		"truncated_input":  t.truncated,
+	}
+}
+
+// promptBudgetKey is the context key of the prompt budget of the item being prompted for
+type promptBudgetKey struct{}
+
+// promptBudgetState is a prompt budget and the record of how the prompt was tightened
+type promptBudgetState struct {
+	budget PromptBudget
+	trim   *promptTrim
+}
+
+// withPromptBudget returns a context bounding builder prompts to budget and recording in
+// trim how they were tightened
+func withPromptBudget(ctx context.Context, budget PromptBudget, trim *promptTrim) context.Context {
+	return context.WithValue(ctx, promptBudgetKey{}, promptBudgetState{budget: budget, trim: trim})
+}
+
+// promptBudgetFromContext returns the prompt budget set with withPromptBudget, if any
+func promptBudgetFromContext(ctx context.Context) (promptBudgetState, bool) {
+	state, ok := ctx.Value(promptBudgetKey{}).(promptBudgetState)
+	return state, ok && state.budget.ContextTokens > 0
+}
+
+// optionalSections returns the keys of the prompt's optional sections in the order they are
+// dropped, with their names
+func (p *BuilderPromptGenerator) optionalSections(ctx context.Context) (keys, names []string) {
+	for i := len(p.customSections) - 1; i >= 0; i-- {
+		if section := p.customSections[i]; section.standard == "" {
+			keys, names = append(keys, "custom:"+section.name), append(names, section.name)
+		}
+	}
+	if len(p.examples) > 0 {
+		keys, names = append(keys, "examples"), append(names, "Examples")
+	}
+	for i := len(p.customSections) - 1; i >= 0; i-- {
+		if section := p.customSections[i]; section.standard != "" && section.standard != SectionJSONOnly {
+			keys, names = append(keys, "standard:"+section.standard), append(names, section.standard)
+		}
+	}
+	if formatStateSection(ctx, p.stateKeys) != "" {
+		keys, names = append(keys, "context"), append(names, "Context")
+	}
+	return keys, names
+}
+
+// tighten fits a prompt over its budget by dropping optional sections and then truncating
+// the input text, recording what it did
+func (p *BuilderPromptGenerator) tighten(ctx context.Context, text string, state promptBudgetState) (string, error) {
+	limit := state.budget.tokens()
+	trim := state.trim
+	if trim == nil {
+		trim = &promptTrim{}
+	}
+
+	skip := make(map[string]bool)
+	keys, names := p.optionalSections(ctx)
+	for i, key := range keys {
+		skip[key] = true
+		trim.dropped = append(trim.dropped, names[i])
+		prompt, err := p.render(ctx, text, skip)
+		if err != nil {
+			return "", err
+		}
+		if llm.EstimateTokens(prompt) <= limit {
+			return prompt, nil
+		}
+	}
+
+	// Truncate the input text to the room the rest of the prompt leaves
+	empty, err := p.render(ctx, "", skip)
+	if err != nil {
+		return "", err
+	}
+	room := (limit-llm.EstimateTokens(empty))*llm.CharsPerToken - int64(len(truncationMarker))
+	if room <= 0 {
+		return "", data.Permanent(fmt.Errorf("prompt exceeds its budget of %d tokens even without the input text", limit))
+	}
+	trim.truncated = true
+	return p.render(ctx, truncateText(text, int(room))+truncationMarker, skip)
+}
+
+// truncateText cuts text to at most n bytes, at a character boundary
+func truncateText(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return strings.TrimSpace(text[:n])
+}
@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// Provenance returns the provenance hash of processing an item with the processor: a hash
+// of the item's content and provenance, the processor's name, its prompt version and its
+// model. See data.ProcessItem.ProvenanceHash.
+func (p *BaseProcessor) Provenance(item *data.ProcessItem) string {
+	return item.ProvenanceHash(p.name, p.promptVersion, p.model)
+}
+
+// promptFingerprint identifies a prompt generator's prompt by a hash of the prompt it
+// generates for an empty text, and those of its localizations, or "" if it can't generate
+// one without an item
+func promptFingerprint(generator PromptGenerator) string {
+	if generator == nil {
+		return ""
+	}
+	prompt, err := generator.GeneratePrompt(context.Background(), "")
+	if err != nil {
+		return ""
+	}
+	if localized, ok := generator.(*BuilderPromptGenerator); ok {
+		for _, language := range localized.languages() {
+			variant, err := generator.GeneratePrompt(withLanguage(context.Background(), language), "")
+			if err != nil {
+				return ""
+			}
+			prompt += "\x00" + variant
+		}
+	}
+	hash := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(hash[:8])
+}
+
+// cachedResult returns the result the options' result cache holds for an item's
+// provenance, with the item's ID, metadata and earlier processing info
+func (p *BaseProcessor) cachedResult(item *data.ProcessItem, provenance string) (*data.ProcessItem, bool) {
+	value, ok := p.options.ResultCache.Get(provenance)
+	if !ok {
+		return nil, false
+	}
+	var cached data.ProcessItem
+	if err := json.Unmarshal(value, &cached); err != nil {
+		return nil, false
+	}
+	result, err := item.Clone()
+	if err != nil {
+		return nil, false
+	}
+
+	result.Content = cached.Content
+	result.ContentType = cached.ContentType
+	result.Provenance = provenance
+	info, _ := cached.ProcessingInfo[p.name].(map[string]interface{})
+	if info == nil {
+		info = make(map[string]interface{})
+	}
+	// The cached call's usage isn't incurred again
+	delete(info, "tokens")
+	delete(info, "cost")
+	info["cached"] = true
+	result.AddProcessingInfo(p.name, info)
+	return result, true
+}
+
+// cacheResult stores a result in the options' result cache under its provenance. Failures
+// to store it are ignored.
+func (p *BaseProcessor) cacheResult(result *data.ProcessItem) {
+	cached := &data.ProcessItem{
+		Content:        result.Content,
+		ContentType:    result.ContentType,
+		ProcessingInfo: map[string]interface{}{p.name: result.ProcessingInfo[p.name]},
+	}
+	if value, err := json.Marshal(cached); err == nil {
+		_ = p.options.ResultCache.Set(result.Provenance, value)
+	}
+}
@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// countingProvider counts its calls
+type countingProvider struct {
+	*llm.MockProvider
+	mu    sync.Mutex
+	calls int
+}
+
+// Generate implements the llm.Provider interface
+func (p *countingProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+	return p.MockProvider.Generate(ctx, prompt)
+}
+
+// memoryCache is an llm.Cache held in memory
+type memoryCache map[string][]byte
+
+// Get implements llm.Cache
+func (c memoryCache) Get(key string) ([]byte, bool) {
+	value, ok := c[key]
+	return value, ok
+}
+
+// Set implements llm.Cache
+func (c memoryCache) Set(key string, value []byte) error {
+	c[key] = value
+	return nil
+}
+
+func TestResultCache(t *testing.T) {
+	builder := NewBuilder("cached").WithStruct(&limitResult{})
+	brief := NewBuilder("cached").WithStruct(&limitResult{}).WithInstructions("Be brief")
+	tests := []struct {
+		name          string
+		first, second *ProcessorBuilder
+		text          string
+		wantCalls     int
+	}{
+		{name: "same input", first: builder, second: builder, text: "text", wantCalls: 1},
+		{name: "changed text", first: builder, second: builder, text: "edited text", wantCalls: 2},
+		{name: "changed prompt", first: builder, second: brief, text: "text", wantCalls: 2},
+		{
+			name:      "same prompt version",
+			first:     NewBuilder("cached").WithStruct(&limitResult{}).WithPromptVersion("v1"),
+			second:    NewBuilder("cached").WithStruct(&limitResult{}).WithInstructions("Be brief").WithPromptVersion("v1"),
+			text:      "text",
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &countingProvider{MockProvider: llm.NewMockProviderWithResponse(`{"summary": "ok"}`)}
+			options := NewDefaultOptions().WithResultCache(memoryCache{})
+
+			var results []*data.ProcessItem
+			for i, b := range []*ProcessorBuilder{tt.first, tt.second} {
+				text := "text"
+				if i == 1 {
+					text = tt.text
+				}
+				proc, err := b.Build(provider, options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				result, err := proc.Process(context.Background(), data.NewTextProcessItem("1", text, nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				results = append(results, result)
+			}
+
+			if provider.calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, provider.calls)
+			}
+			if same := results[0].Provenance == results[1].Provenance; same != (tt.wantCalls == 1) {
+				t.Errorf("expected equal provenance %v, got %q and %q", tt.wantCalls == 1, results[0].Provenance, results[1].Provenance)
+			}
+			info := results[1].ProcessingInfo["cached"].(map[string]interface{})
+			if cached := info["cached"] == true; cached != (tt.wantCalls == 1) {
+				t.Errorf("expected cached %v, got %v", tt.wantCalls == 1, info)
+			}
+		})
+	}
+}
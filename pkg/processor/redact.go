@@ -0,0 +1,193 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// RedactionConfig selects what is redacted from debug output, i.e. the prompts and raw
+// responses debug mode stores under "debug" in ProcessingInfo
+type RedactionConfig struct {
+	// APIKeys redacts API keys, bearer tokens and values assigned to keys, secrets, tokens
+	// and passwords
+	APIKeys bool `json:"api_keys,omitempty" yaml:"api_keys,omitempty"`
+	// Emails redacts email addresses
+	Emails bool `json:"emails,omitempty" yaml:"emails,omitempty"`
+	// AccountNumbers redacts card, bank account and IBAN numbers, i.e. IBANs and runs of 12
+	// to 19 digits optionally separated by spaces or dashes. Shorter runs, such as dates,
+	// times and order numbers, are kept.
+	AccountNumbers bool `json:"account_numbers,omitempty" yaml:"account_numbers,omitempty"`
+	// PhoneNumbers redacts international numbers starting with + and North American numbers
+	// such as (555) 123-4567
+	PhoneNumbers bool `json:"phone_numbers,omitempty" yaml:"phone_numbers,omitempty"`
+	// Patterns are custom regular expressions by name; matches are replaced with
+	// [REDACTED:name]. If a pattern has a group named "redact", only that group is replaced.
+	Patterns map[string]string `json:"patterns,omitempty" yaml:"patterns,omitempty"`
+}
+
+// DefaultRedaction redacts API keys, email addresses and account numbers
+func DefaultRedaction() RedactionConfig {
+	return RedactionConfig{APIKeys: true, Emails: true, AccountNumbers: true}
+}
+
+// builtinRedactions are the patterns of the RedactionConfig switches
+var builtinRedactions = struct {
+	apiKeys, emails, accountNumbers, phoneNumbers []string
+}{
+	apiKeys: []string{
+		`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{16,}`,
+		`\bAIza[0-9A-Za-z_-]{35}\b`,
+		`\bgsk_[A-Za-z0-9]{20,}\b`,
+		`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+		`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+		`(?i)\bbearer\s+(?P<redact>[A-Za-z0-9._~+/-]{16,}=*)`,
+		`(?i)\b(?:api[_-]?key|secret|token|password|passwd)["']?\s*[:=]\s*["']?(?P<redact>[^\s"',;]{6,})`,
+	},
+	emails: []string{
+		`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	},
+	accountNumbers: []string{
+		`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`,
+		`\b\d(?:[ -]?\d){11,18}\b`,
+	},
+	phoneNumbers: []string{
+		`\+\d{1,3}[ .-]?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}(?:[ .-]?\d{2,4}){1,4}\b`,
+		`(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]\d{4}\b`,
+	},
+}
+
+// redaction is a compiled pattern and what replaces its matches
+type redaction struct {
+	pattern     *regexp.Regexp
+	replacement string
+	// group is the index of the "redact" group, or 0 to replace the whole match
+	group int
+}
+
+// Redactor removes sensitive values from debug output
+type Redactor struct {
+	redactions []redaction
+}
+
+// NewRedactor compiles the patterns of a redaction config
+func NewRedactor(config RedactionConfig) (*Redactor, error) {
+	r := &Redactor{}
+	add := func(name string, patterns ...string) error {
+		for _, pattern := range patterns {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("failed to compile redaction pattern %s: %w", name, err)
+			}
+			group := compiled.SubexpIndex("redact")
+			r.redactions = append(r.redactions, redaction{
+				pattern:     compiled,
+				replacement: "[REDACTED:" + name + "]",
+				group:       max(group, 0),
+			})
+		}
+		return nil
+	}
+
+	if config.APIKeys {
+		if err := add("api_key", builtinRedactions.apiKeys...); err != nil {
+			return nil, err
+		}
+	}
+	if config.Emails {
+		if err := add("email", builtinRedactions.emails...); err != nil {
+			return nil, err
+		}
+	}
+	// Phone numbers come before account numbers, which would match the digits of long
+	// international numbers. Phone patterns need a + or a 3-3-4 grouping, so they don't
+	// match card numbers.
+	if config.PhoneNumbers {
+		if err := add("phone", builtinRedactions.phoneNumbers...); err != nil {
+			return nil, err
+		}
+	}
+	if config.AccountNumbers {
+		if err := add("account_number", builtinRedactions.accountNumbers...); err != nil {
+			return nil, err
+		}
+	}
+	// Custom patterns apply in name order so redaction doesn't depend on map order
+	names := make([]string, 0, len(config.Patterns))
+	for name := range config.Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := add(name, config.Patterns[name]); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Redact replaces the sensitive values in text
+func (r *Redactor) Redact(text string) string {
+	for _, redaction := range r.redactions {
+		if redaction.group == 0 {
+			text = redaction.pattern.ReplaceAllLiteralString(text, redaction.replacement)
+			continue
+		}
+		text = replaceGroup(redaction.pattern, text, redaction.group, redaction.replacement)
+	}
+	return text
+}
+
+// replaceGroup replaces one group of every match of pattern in text
+func replaceGroup(pattern *regexp.Regexp, text string, group int, replacement string) string {
+	matches := pattern.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text
+	}
+	var redacted []byte
+	last := 0
+	for _, match := range matches {
+		start, end := match[2*group], match[2*group+1]
+		if start < 0 {
+			continue
+		}
+		redacted = append(redacted, text[last:start]...)
+		redacted = append(redacted, replacement...)
+		last = end
+	}
+	return string(append(redacted, text[last:]...))
+}
+
+// RedactValue returns a copy of a decoded JSON value, such as a raw response, with the
+// sensitive values of its strings replaced. Other values are converted to JSON values first.
+func (r *Redactor) RedactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, float64, int, int64:
+		return v
+	case string:
+		return r.Redact(v)
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			redacted[key] = r.RedactValue(field)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, element := range v {
+			redacted[i] = r.RedactValue(element)
+		}
+		return redacted
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return r.Redact(fmt.Sprintf("%+v", v))
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			return r.Redact(string(encoded))
+		}
+		return r.RedactValue(decoded)
+	}
+}
@@ -1,8 +1,10 @@
 package processor
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/eisenzopf/agentic-text/pkg/llm"
 )
@@ -23,7 +25,12 @@ func Register(name string, factory FactoryFunc) {
 	globalRegistry[name] = factory
 }
 
-// Create creates a processor by name
+// ValidationTimeout limits the provider validation Create performs when the options ask for it
+var ValidationTimeout = 15 * time.Second
+
+// Create creates a processor by name. If the options enable provider validation, the
+// provider's API key and model are verified with llm.Validate first, so misconfiguration
+// fails here rather than on the first item processed.
 func Create(name string, provider llm.Provider, options Options) (Processor, error) {
 	globalRegistryLock.RLock()
 	factory, ok := globalRegistry[name]
@@ -31,6 +38,13 @@ func Create(name string, provider llm.Provider, options Options) (Processor, err
 	if !ok {
 		return nil, fmt.Errorf("processor not found: %s", name)
 	}
+	if options.ValidateProvider && provider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), ValidationTimeout)
+		defer cancel()
+		if err := llm.Validate(ctx, provider); err != nil {
+			return nil, fmt.Errorf("failed to create processor %s: %w", name, err)
+		}
+	}
 	return factory(provider, options)
 }
 
@@ -395,6 +395,14 @@ func (h *BaseResponseHandler) mapValueToField(value interface{}, field reflect.V
 			field.SetBool(b)
 			return true
 		}
+	case reflect.Struct:
+		// Nested objects are decoded through their JSON form
+		if fields, ok := value.(map[string]interface{}); ok && field.CanAddr() {
+			encoded, err := json.Marshal(fields)
+			if err == nil && json.Unmarshal(encoded, field.Addr().Interface()) == nil {
+				return true
+			}
+		}
 	}
 
 	return false
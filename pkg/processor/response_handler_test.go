@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type nestedScore struct {
+	Value      float64 `json:"value"`
+	Confidence float64 `json:"confidence"`
+}
+
+type nestedResult struct {
+	Label         string      `json:"label"`
+	Score         nestedScore `json:"score"`
+	ProcessorType string      `json:"processor_type"`
+}
+
+func TestHandleResponseNestedStruct(t *testing.T) {
+	tests := []struct {
+		name     string
+		response map[string]interface{}
+		want     nestedResult
+	}{
+		{
+			name: "nested object",
+			response: map[string]interface{}{
+				"label": "positive",
+				"score": map[string]interface{}{"value": 0.8, "confidence": 0.9},
+			},
+			want: nestedResult{Label: "positive", Score: nestedScore{Value: 0.8, Confidence: 0.9}, ProcessorType: "nested"},
+		},
+		{
+			name: "partial nested object",
+			response: map[string]interface{}{
+				"label": "negative",
+				"score": map[string]interface{}{"value": -0.5},
+			},
+			want: nestedResult{Label: "negative", Score: nestedScore{Value: -0.5}, ProcessorType: "nested"},
+		},
+		{
+			name: "non-object value leaves the field zero",
+			response: map[string]interface{}{
+				"label": "neutral",
+				"score": "high",
+			},
+			want: nestedResult{Label: "neutral", ProcessorType: "nested"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewResponseHandler("nested", &nestedResult{})
+			result, err := handler.HandleResponse(context.Background(), "text", tt.response)
+			if err != nil {
+				t.Fatalf("HandleResponse: %v", err)
+			}
+			got, ok := result.(*nestedResult)
+			if !ok {
+				t.Fatalf("result is %T, want *nestedResult", result)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("result = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
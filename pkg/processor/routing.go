@@ -0,0 +1,148 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// RoutingConfig sends each item to one of several models of the processor's provider, so
+// short or simple inputs can use a cheap model and long or complex ones a stronger model
+type RoutingConfig struct {
+	// Routes are the models, usually from cheapest to strongest. An item goes to the first
+	// route whose limits it is within, or to the last route if none.
+	Routes []ModelRoute `json:"routes" yaml:"routes"`
+	// Classify, if set, chooses the route by name instead of the limits, e.g. with a quick
+	// call to a cheap model. It receives the item's text after pre-processing.
+	Classify func(ctx context.Context, text string) (string, error) `json:"-" yaml:"-"`
+}
+
+// ModelRoute is a model items can be routed to
+type ModelRoute struct {
+	// Name identifies the route for Classify and in processing info (defaults to the model)
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Model is the model of the processor's provider to use (defaults to the provider's own)
+	Model string `json:"model,omitempty" yaml:"model,omitempty"`
+	// MaxTokens is the largest input, in estimated tokens, the route takes (0 for no limit)
+	MaxTokens int `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	// MaxComplexity is the highest Complexity score the route takes (0 for no limit)
+	MaxComplexity float64 `json:"max_complexity,omitempty" yaml:"max_complexity,omitempty"`
+}
+
+// routeClient is a route and the client calling its model
+type routeClient struct {
+	ModelRoute
+	client llm.Client
+}
+
+// newRouteClients creates a client for every route of a routing config
+func newRouteClients(provider llm.Provider, routing RoutingConfig) ([]routeClient, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("routing needs a provider")
+	}
+	if len(routing.Routes) == 0 {
+		return nil, fmt.Errorf("routing needs at least one route")
+	}
+	routes := make([]routeClient, len(routing.Routes))
+	names := make(map[string]bool, len(routing.Routes))
+	for i, route := range routing.Routes {
+		model, err := llm.WithModel(provider, route.Model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create route %d: %w", i+1, err)
+		}
+		if route.Name == "" {
+			route.Name = model.GetConfig().Model
+		}
+		if names[route.Name] {
+			return nil, fmt.Errorf("duplicate route %s", route.Name)
+		}
+		names[route.Name] = true
+		routes[i] = routeClient{ModelRoute: route, client: llm.NewProviderClient(model)}
+	}
+	return routes, nil
+}
+
+// route chooses the route of an item's text
+func (p *BaseProcessor) route(ctx context.Context, text string) (routeClient, error) {
+	if classify := p.options.Routing.Classify; classify != nil {
+		name, err := classify(ctx, text)
+		if err != nil {
+			return routeClient{}, fmt.Errorf("failed to classify item for routing: %w", err)
+		}
+		for _, route := range p.routes {
+			if route.Name == name {
+				return route, nil
+			}
+		}
+		return routeClient{}, fmt.Errorf("no route named %s", name)
+	}
+
+	tokens := int(llm.EstimateTokens(text))
+	complexity := -1.0
+	for _, route := range p.routes {
+		if route.MaxTokens > 0 && tokens > route.MaxTokens {
+			continue
+		}
+		if route.MaxComplexity > 0 {
+			// The score is only computed if a route needs it
+			if complexity < 0 {
+				complexity = Complexity(text)
+			}
+			if complexity > route.MaxComplexity {
+				continue
+			}
+		}
+		return route, nil
+	}
+	return p.routes[len(p.routes)-1], nil
+}
+
+// complexityMarkers are words that signal reasoning, contrast or conditions
+var complexityMarkers = map[string]bool{
+	"although": true, "however": true, "but": true, "unless": true, "whereas": true,
+	"because": true, "therefore": true, "if": true, "except": true, "despite": true,
+	"though": true, "while": true, "otherwise": true, "meanwhile": true, "nevertheless": true,
+}
+
+// Complexity is a quick heuristic score of how hard a text is to analyze, from 0 (short
+// and simple) to 1. It averages four signals, each capped at 1: the length (1,000 estimated
+// tokens or more scores 1), the mean sentence length (40 words), the share of long words (a
+// third of words with nine letters or more) and the density of words signaling contrast,
+// reasoning or conditions, plus questions (5 per 100 words).
+func Complexity(text string) float64 {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	if len(words) == 0 {
+		return 0
+	}
+
+	sentences := strings.FieldsFunc(text, func(r rune) bool { return r == '.' || r == '!' || r == '?' || r == '\n' })
+	nonEmpty := 0
+	for _, sentence := range sentences {
+		if strings.TrimSpace(sentence) != "" {
+			nonEmpty++
+		}
+	}
+
+	long, markers := 0, strings.Count(text, "?")
+	for _, word := range words {
+		if len([]rune(word)) >= 9 {
+			long++
+		}
+		if complexityMarkers[strings.ToLower(word)] {
+			markers++
+		}
+	}
+
+	count := float64(len(words))
+	length := float64(llm.EstimateTokens(text)) / 1000
+	sentenceLength := count / float64(max(nonEmpty, 1)) / 40
+	vocabulary := float64(long) / count * 3
+	density := float64(markers) / count * 100 / 5
+	return (math.Min(length, 1) + math.Min(sentenceLength, 1) + math.Min(vocabulary, 1) + math.Min(density, 1)) / 4
+}
@@ -0,0 +1,189 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// routeResult is the result struct of the routing tests
+type routeResult struct {
+	Label         string `json:"label"`
+	ProcessorType string `json:"processor_type"`
+}
+
+// newRoutedProcessor builds a processor routing items over a mock provider, whose models
+// all answer with the same label
+func newRoutedProcessor(t *testing.T, routing RoutingConfig) Processor {
+	t.Helper()
+	provider, err := llm.NewMockProvider(llm.Config{Options: map[string]interface{}{"response": `{"label": "ok"}`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proc, err := NewBuilder("routed").WithStruct(&routeResult{}).Build(provider, NewDefaultOptions().WithRouting(routing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proc
+}
+
+func TestRouting(t *testing.T) {
+	// simple is long but plain; hard has long words and contrast markers in every sentence
+	simple := strings.Repeat("Thanks a lot. ", 10)
+	hard := strings.Repeat("However, the subscription renewal failed although I cancelled it because of misconfigured billing? ", 3)
+
+	tests := []struct {
+		name      string
+		routes    []ModelRoute
+		text      string
+		wantRoute string
+	}{
+		{
+			name:      "within token limit",
+			routes:    []ModelRoute{{Name: "small", Model: "small", MaxTokens: 10}, {Name: "large", Model: "large"}},
+			text:      "Thanks!",
+			wantRoute: "small",
+		},
+		{
+			name:      "over token limit",
+			routes:    []ModelRoute{{Name: "small", Model: "small", MaxTokens: 10}, {Name: "large", Model: "large"}},
+			text:      simple,
+			wantRoute: "large",
+		},
+		{
+			name:      "within complexity limit",
+			routes:    []ModelRoute{{Name: "small", Model: "small", MaxTokens: 10}, {Name: "medium", Model: "medium", MaxComplexity: 0.3}, {Name: "large", Model: "large"}},
+			text:      simple,
+			wantRoute: "medium",
+		},
+		{
+			name:      "over complexity limit",
+			routes:    []ModelRoute{{Name: "small", Model: "small", MaxTokens: 10}, {Name: "medium", Model: "medium", MaxComplexity: 0.3}, {Name: "large", Model: "large"}},
+			text:      hard,
+			wantRoute: "large",
+		},
+		{
+			name:      "both limits",
+			routes:    []ModelRoute{{Name: "small", Model: "small", MaxTokens: 1000, MaxComplexity: 0.3}, {Name: "large", Model: "large"}},
+			text:      hard,
+			wantRoute: "large",
+		},
+		{
+			name:      "last route when none fits",
+			routes:    []ModelRoute{{Name: "small", Model: "small", MaxTokens: 1}, {Name: "medium", Model: "medium", MaxTokens: 2}},
+			text:      simple,
+			wantRoute: "medium",
+		},
+		{
+			name:      "name defaults to model",
+			routes:    []ModelRoute{{Model: "small"}},
+			text:      "Thanks!",
+			wantRoute: "small",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc := newRoutedProcessor(t, RoutingConfig{Routes: tt.routes})
+			item, err := proc.Process(context.Background(), data.NewTextProcessItem("1", tt.text, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := item.ProcessingInfo["routed"].(map[string]interface{})
+			if info["route"] != tt.wantRoute {
+				t.Errorf("expected route %s, got %v", tt.wantRoute, info["route"])
+			}
+		})
+	}
+}
+
+func TestRoutingClassify(t *testing.T) {
+	routes := []ModelRoute{{Name: "cheap", Model: "small"}, {Name: "strong", Model: "large"}}
+	tests := []struct {
+		name      string
+		classify  func(ctx context.Context, text string) (string, error)
+		wantRoute string
+		wantErr   string
+	}{
+		{
+			name:      "chooses route by name",
+			classify:  func(context.Context, string) (string, error) { return "strong", nil },
+			wantRoute: "strong",
+		},
+		{
+			name:     "unknown name",
+			classify: func(context.Context, string) (string, error) { return "medium", nil },
+			wantErr:  "no route named medium",
+		},
+		{
+			name:     "classifier error",
+			classify: func(context.Context, string) (string, error) { return "", errors.New("classifier down") },
+			wantErr:  "failed to classify item for routing: classifier down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc := newRoutedProcessor(t, RoutingConfig{Routes: routes, Classify: tt.classify})
+			item, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "Thanks!", nil))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if route := item.ProcessingInfo["routed"].(map[string]interface{})["route"]; route != tt.wantRoute {
+				t.Errorf("expected route %s, got %v", tt.wantRoute, route)
+			}
+		})
+	}
+}
+
+func TestRoutingConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		routes  []ModelRoute
+		wantErr string
+	}{
+		{name: "no routes", wantErr: "routing needs at least one route"},
+		{name: "duplicate names", routes: []ModelRoute{{Name: "a", Model: "small"}, {Name: "a", Model: "large"}}, wantErr: "duplicate route a"},
+		{name: "duplicate default names", routes: []ModelRoute{{Model: "small"}, {Model: "small"}}, wantErr: "duplicate route small"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := NewDefaultOptions().WithRouting(RoutingConfig{Routes: tt.routes})
+			_, err := NewBuilder("routed").WithStruct(&routeResult{}).Build(llm.NewMockProviderWithResponse(`{}`), options)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestComplexity(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		min, max float64
+	}{
+		{name: "empty", text: "", min: 0, max: 0},
+		{name: "short and plain", text: "Thanks a lot.", min: 0, max: 0.1},
+		{name: "contrast and long words", text: "However, the subscription renewal failed although I cancelled it because of misconfigured billing?", min: 0.4, max: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Complexity(tt.text); got < tt.min || got > tt.max {
+				t.Errorf("expected a complexity from %v to %v, got %v", tt.min, tt.max, got)
+			}
+		})
+	}
+}
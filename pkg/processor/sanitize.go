@@ -0,0 +1,216 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// SanitizeConfig configures the sanitization of a processor's results after they are
+// mapped, so downstream systems receive consistent values. Fields are named by their JSON
+// names, with dots separating nested fields, e.g. "entities.type"; lists along a path are
+// sanitized element by element.
+type SanitizeConfig struct {
+	// TrimSpace trims leading and trailing whitespace from every string
+	TrimSpace bool `json:"trim_space,omitempty" yaml:"trim_space,omitempty"`
+	// StripControl removes control characters other than newlines and tabs from every string
+	StripControl bool `json:"strip_control,omitempty" yaml:"strip_control,omitempty"`
+	// LabelCase normalizes the casing of the LabelFields: "lower", "upper" or "title"
+	LabelCase string `json:"label_case,omitempty" yaml:"label_case,omitempty"`
+	// LabelFields are the fields holding labels, as strings or lists of strings
+	LabelFields []string `json:"label_fields,omitempty" yaml:"label_fields,omitempty"`
+	// Ranges clamp number fields to a range
+	Ranges map[string]Range `json:"ranges,omitempty" yaml:"ranges,omitempty"`
+	// DedupeLists removes duplicate strings from every list of strings, ignoring case and
+	// surrounding whitespace and keeping the first
+	DedupeLists bool `json:"dedupe_lists,omitempty" yaml:"dedupe_lists,omitempty"`
+}
+
+// Range bounds a number; a nil bound leaves that side open
+type Range struct {
+	Min *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// validate checks the config's label case
+func (c SanitizeConfig) validate() error {
+	switch c.LabelCase {
+	case "", "lower", "upper", "title":
+		return nil
+	default:
+		return fmt.Errorf("invalid label case %q: use lower, upper or title", c.LabelCase)
+	}
+}
+
+// Sanitize returns a sanitized copy of a result value, such as a result map
+func (c SanitizeConfig) Sanitize(value interface{}) interface{} {
+	value = c.sanitizeValue(value)
+	if c.LabelCase != "" {
+		for _, field := range c.LabelFields {
+			value = applyAtPath(value, strings.Split(field, "."), c.caseLabel)
+		}
+	}
+	for field, bounds := range c.Ranges {
+		value = applyAtPath(value, strings.Split(field, "."), bounds.clamp)
+	}
+	return value
+}
+
+// sanitizeValue applies the whitespace, control character and duplicate rules to a value
+// and everything it contains
+func (c SanitizeConfig) sanitizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if c.StripControl {
+			v = strings.Map(func(r rune) rune {
+				if unicode.IsControl(r) && r != '\n' && r != '\t' {
+					return -1
+				}
+				return r
+			}, v)
+		}
+		if c.TrimSpace {
+			v = strings.TrimSpace(v)
+		}
+		return v
+	case map[string]interface{}:
+		sanitized := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			sanitized[key] = c.sanitizeValue(element)
+		}
+		return sanitized
+	case []interface{}:
+		sanitized := make([]interface{}, 0, len(v))
+		seen := make(map[string]bool)
+		for _, element := range v {
+			element = c.sanitizeValue(element)
+			if text, ok := element.(string); ok && c.DedupeLists {
+				key := strings.ToLower(strings.TrimSpace(text))
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			sanitized = append(sanitized, element)
+		}
+		return sanitized
+	default:
+		return value
+	}
+}
+
+// caseLabel normalizes the casing of a label or list of labels
+func (c SanitizeConfig) caseLabel(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		switch c.LabelCase {
+		case "lower":
+			return strings.ToLower(v)
+		case "upper":
+			return strings.ToUpper(v)
+		default:
+			return titleCase(v)
+		}
+	case []interface{}:
+		cased := make([]interface{}, len(v))
+		for i, element := range v {
+			cased[i] = c.caseLabel(element)
+		}
+		return cased
+	default:
+		return value
+	}
+}
+
+// clamp limits a number to the range; other values are returned unchanged
+func (r Range) clamp(value interface{}) interface{} {
+	number, ok := value.(float64)
+	if !ok {
+		return value
+	}
+	if r.Min != nil {
+		number = math.Max(number, *r.Min)
+	}
+	if r.Max != nil {
+		number = math.Min(number, *r.Max)
+	}
+	return number
+}
+
+// applyAtPath replaces the values at a path of field names with fn's result, descending
+// into the elements of lists along the way
+func applyAtPath(value interface{}, path []string, fn func(interface{}) interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for i, element := range v {
+			v[i] = applyAtPath(element, path, fn)
+		}
+		return v
+	case map[string]interface{}:
+		field, ok := v[path[0]]
+		if !ok {
+			return v
+		}
+		if len(path) == 1 {
+			v[path[0]] = fn(field)
+		} else {
+			v[path[0]] = applyAtPath(field, path[1:], fn)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// titleCase upper-cases the first letter of each word and lower-cases the rest
+func titleCase(s string) string {
+	runes := []rune(strings.ToLower(s))
+	for i, r := range runes {
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && !unicode.IsDigit(runes[i-1]) && runes[i-1] != '\'' {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+// sanitizingHandler sanitizes the results of a response handler
+type sanitizingHandler struct {
+	handler ResponseHandler
+	config  SanitizeConfig
+}
+
+// HandleResponse implements the ResponseHandler interface. Struct results are sanitized
+// through their JSON form and updated in place.
+func (h sanitizingHandler) HandleResponse(ctx context.Context, text string, responseData interface{}) (interface{}, error) {
+	result, err := h.handler.HandleResponse(ctx, text, responseData)
+	if err != nil {
+		return nil, err
+	}
+	if fields, ok := result.(map[string]interface{}); ok {
+		return h.config.Sanitize(fields), nil
+	}
+	if reflect.ValueOf(result).Kind() != reflect.Ptr {
+		return result, nil
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sanitize result: %w", err)
+	}
+	var fields interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("failed to sanitize result: %w", err)
+	}
+	sanitized, err := json.Marshal(h.config.Sanitize(fields))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sanitize result: %w", err)
+	}
+	if err := json.Unmarshal(sanitized, result); err != nil {
+		return nil, fmt.Errorf("failed to sanitize result: %w", err)
+	}
+	return result, nil
+}
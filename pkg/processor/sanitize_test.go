@@ -0,0 +1,67 @@
+package processor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// sanitizeResult is the result struct of TestSanitize
+type sanitizeResult struct {
+	Label         string   `json:"label"`
+	Keywords      []string `json:"keywords"`
+	Score         float64  `json:"score"`
+	Note          string   `json:"note"`
+	ProcessorType string   `json:"processor_type"`
+}
+
+func TestSanitize(t *testing.T) {
+	max := 1.0
+	builder := NewBuilder("sanitized").
+		WithStruct(&sanitizeResult{}).
+		WithSanitize(SanitizeConfig{
+			TrimSpace:    true,
+			StripControl: true,
+			LabelCase:    "lower",
+			LabelFields:  []string{"label"},
+			Ranges:       map[string]Range{"score": {Max: &max}},
+			DedupeLists:  true,
+		})
+	response := `{"label": " Billing\u0007 ", "keywords": ["Refund", " refund", "invoice "], "score": 1.7, "note": "  Card\u0000 declined  "}`
+
+	tests := []struct {
+		name    string
+		options Options
+		want    sanitizeResult
+	}{
+		{
+			name:    "builder sanitization",
+			options: NewDefaultOptions(),
+			want:    sanitizeResult{Label: "billing", Keywords: []string{"Refund", "invoice"}, Score: 1, Note: "Card declined", ProcessorType: "sanitized"},
+		},
+		{
+			name:    "options override",
+			options: NewDefaultOptions().WithSanitize(SanitizeConfig{LabelCase: "title", LabelFields: []string{"label", "keywords"}}),
+			want:    sanitizeResult{Label: " Billing\u0007 ", Keywords: []string{"Refund", " Refund", "Invoice "}, Score: 1.7, Note: "  Card\u0000 declined  ", ProcessorType: "sanitized"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc, err := builder.Build(llm.NewMockProviderWithResponse(response), tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			item, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "text", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := item.Content.(*sanitizeResult); got == nil || !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, item.Content)
+			}
+		})
+	}
+}
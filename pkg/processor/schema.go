@@ -0,0 +1,423 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema is a JSON Schema describing a processor's result, for processors defined at runtime
+// instead of with a Go result struct. A *Schema can be passed wherever a result struct is
+// expected: RegisterGenericProcessor, ProcessorBuilder.WithStruct or a Definition's schema.
+// Results are maps conforming to the schema.
+//
+// The supported keywords are type (object, array, string, number, integer, boolean),
+// properties, required, additionalProperties (false only), items, enum, minimum, maximum,
+// default and description.
+type Schema struct {
+	definition map[string]interface{}
+}
+
+// NewSchema creates a schema from a JSON Schema object, which must describe an object
+func NewSchema(definition map[string]interface{}) (*Schema, error) {
+	// Round-trip through JSON so that values decoded from YAML, such as ints and []string,
+	// compare with those of JSON responses
+	raw, err := json.Marshal(definition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid result schema: %w", err)
+	}
+	definition = nil
+	if err := json.Unmarshal(raw, &definition); err != nil {
+		return nil, fmt.Errorf("invalid result schema: %w", err)
+	}
+
+	if schemaType(definition) != "object" {
+		return nil, fmt.Errorf("result schema must have type object")
+	}
+	if _, ok := definition["properties"].(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("result schema must have properties")
+	}
+	if err := checkSchema(definition, ""); err != nil {
+		return nil, err
+	}
+	return &Schema{definition: definition}, nil
+}
+
+// ParseSchema creates a schema from its JSON encoding
+func ParseSchema(raw []byte) (*Schema, error) {
+	var definition map[string]interface{}
+	if err := json.Unmarshal(raw, &definition); err != nil {
+		return nil, fmt.Errorf("invalid result schema: %w", err)
+	}
+	return NewSchema(definition)
+}
+
+// checkSchema rejects types the schema's results can't be conformed to
+func checkSchema(schema map[string]interface{}, path string) error {
+	switch t := schemaType(schema); t {
+	case "", "string", "number", "integer", "boolean":
+	case "object":
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range properties {
+			propertySchema, ok := property.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("result schema: property %s must be a schema", joinPath(path, name))
+			}
+			if err := checkSchema(propertySchema, joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+	case "array":
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			return checkSchema(items, path+"[]")
+		}
+	default:
+		return fmt.Errorf("result schema: %s has unsupported type %v", pathName(path), schema["type"])
+	}
+	return nil
+}
+
+// Map returns the JSON Schema of the processor's results, including processor_type
+func (s *Schema) Map() map[string]interface{} {
+	schema := copySchema(s.definition)
+	properties := copySchema(schema["properties"].(map[string]interface{}))
+	properties["processor_type"] = map[string]interface{}{"type": "string"}
+	schema["properties"] = properties
+	return schema
+}
+
+// copySchema returns a shallow copy of a schema object
+func copySchema(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema)+1)
+	for key, value := range schema {
+		result[key] = value
+	}
+	return result
+}
+
+// Example returns a sample result as indented JSON, for prompts. Arrays get several
+// elements, which cycle through the enum values of their items.
+func (s *Schema) Example() string {
+	encoded, err := json.MarshalIndent(exampleValue(s.definition, "", 0), "", "  ")
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
+}
+
+// exampleValue returns the index-th sample value of a schema, index being the position of
+// the value in a sample array
+func exampleValue(schema map[string]interface{}, name string, index int) interface{} {
+	enum, _ := schema["enum"].([]interface{})
+	if value, ok := schema["default"]; ok && (index == 0 || len(enum) == 0) {
+		return value
+	}
+	if len(enum) > 0 {
+		return enum[index%len(enum)]
+	}
+	switch schemaType(schema) {
+	case "object":
+		properties, _ := schema["properties"].(map[string]interface{})
+		result := make(map[string]interface{}, len(properties))
+		for property, propertySchema := range properties {
+			if propertySchema, ok := propertySchema.(map[string]interface{}); ok {
+				result[property] = exampleValue(propertySchema, property, index)
+			}
+		}
+		return result
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		values := make([]interface{}, sampleSliceLength)
+		for i := range values {
+			values[i] = exampleValue(items, name, i)
+		}
+		return values
+	case "number":
+		return 0.0
+	case "integer":
+		return 42
+	case "boolean":
+		return false
+	default:
+		if index > 0 {
+			return fmt.Sprintf("Example %s %d", name, index+1)
+		}
+		return "Example " + name
+	}
+}
+
+// Defaults returns the result used when a response isn't JSON: the defaults of the schema's
+// required properties and of the properties declaring one
+func (s *Schema) Defaults() map[string]interface{} {
+	result, _ := s.Conform(map[string]interface{}{})
+	return result
+}
+
+// Conform returns a copy of value conforming to the schema, and the schema violations
+// corrected on the way. Values of the wrong type are converted when possible, such as
+// numbers given as strings; otherwise they are replaced by the property's default, or
+// dropped. Values outside an enum are replaced the same way, numbers are clamped to their
+// minimum and maximum, properties not allowed by additionalProperties: false are dropped,
+// and missing required properties get their default or zero value.
+func (s *Schema) Conform(value map[string]interface{}) (map[string]interface{}, []string) {
+	var violations []string
+	result, _ := conformValue(s.definition, value, "", &violations)
+	conformed, _ := result.(map[string]interface{})
+	if conformed == nil {
+		conformed = make(map[string]interface{})
+	}
+	return conformed, violations
+}
+
+// conformValue conforms a value to a schema, reporting whether the result is usable
+func conformValue(schema map[string]interface{}, value interface{}, path string, violations *[]string) (interface{}, bool) {
+	violate := func(format string, args ...interface{}) {
+		*violations = append(*violations, pathName(path)+": "+fmt.Sprintf(format, args...))
+	}
+
+	result := value
+	switch schemaType(schema) {
+	case "object":
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			violate("expected an object, got %T", value)
+			return nil, false
+		}
+		result = conformObject(schema, object, path, violations)
+	case "array":
+		list, isList := value.([]interface{})
+		if !isList {
+			violate("expected an array, got %T", value)
+			return nil, false
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		conformed := make([]interface{}, 0, len(list))
+		for i, item := range list {
+			if item, ok := conformValue(items, item, fmt.Sprintf("%s[%d]", path, i), violations); ok {
+				conformed = append(conformed, item)
+			}
+		}
+		result = conformed
+	case "string":
+		switch v := value.(type) {
+		case string:
+		case float64, bool:
+			violate("expected a string, got %v", v)
+			result = fmt.Sprint(v)
+		default:
+			violate("expected a string, got %T", value)
+			return nil, false
+		}
+	case "number", "integer":
+		number, isNumber := value.(float64)
+		if text, isText := value.(string); isText {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+			if err != nil {
+				violate("expected a number, got %q", text)
+				return nil, false
+			}
+			violate("expected a number, got %q", text)
+			number, isNumber = parsed, true
+		}
+		if !isNumber {
+			violate("expected a number, got %T", value)
+			return nil, false
+		}
+		if schemaType(schema) == "integer" && number != math.Trunc(number) {
+			violate("expected an integer, got %v", number)
+			number = math.Round(number)
+		}
+		if minimum, ok := schema["minimum"].(float64); ok && number < minimum {
+			violate("%v is below the minimum %v", number, minimum)
+			number = minimum
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && number > maximum {
+			violate("%v is above the maximum %v", number, maximum)
+			number = maximum
+		}
+		result = number
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+		case string:
+			parsed, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				violate("expected a boolean, got %q", v)
+				return nil, false
+			}
+			violate("expected a boolean, got %q", v)
+			result = parsed
+		default:
+			violate("expected a boolean, got %T", value)
+			return nil, false
+		}
+	}
+
+	if enum, isEnum := schema["enum"].([]interface{}); isEnum && !inEnum(enum, result) {
+		violate("%v is not one of %v", result, enum)
+		return nil, false
+	}
+	return result, true
+}
+
+// conformObject conforms the properties of an object
+func conformObject(schema map[string]interface{}, object map[string]interface{}, path string, violations *[]string) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	list, _ := schema["required"].([]interface{})
+	for _, name := range list {
+		required[fmt.Sprint(name)] = true
+	}
+
+	result := make(map[string]interface{}, len(properties))
+	for _, name := range sortedKeys(object) {
+		value := object[name]
+		propertySchema, known := properties[name].(map[string]interface{})
+		if !known {
+			if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				*violations = append(*violations, joinPath(path, name)+": property not allowed")
+				continue
+			}
+			result[name] = value
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		if conformed, ok := conformValue(propertySchema, value, joinPath(path, name), violations); ok {
+			result[name] = conformed
+		}
+	}
+
+	// Properties missing or dropped above get their default, or their zero value if required
+	for _, name := range sortedKeys(properties) {
+		if _, ok := result[name]; ok {
+			continue
+		}
+		propertySchema, _ := properties[name].(map[string]interface{})
+		if value, ok := propertySchema["default"]; ok {
+			result[name] = value
+			continue
+		}
+		if required[name] {
+			if _, given := object[name]; !given {
+				*violations = append(*violations, joinPath(path, name)+": required property missing")
+			}
+			result[name] = zeroValue(propertySchema, joinPath(path, name), violations)
+		}
+	}
+	return result
+}
+
+// zeroValue returns the zero value of a schema's type
+func zeroValue(schema map[string]interface{}, path string, violations *[]string) interface{} {
+	switch schemaType(schema) {
+	case "object":
+		return conformObject(schema, map[string]interface{}{}, path, violations)
+	case "array":
+		return []interface{}{}
+	case "string":
+		return ""
+	case "number", "integer":
+		return 0.0
+	case "boolean":
+		return false
+	default:
+		return nil
+	}
+}
+
+// sortedKeys returns the keys of a map in order, so violations are reported consistently
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// inEnum reports whether value is one of the enum's values
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaType returns the type of a schema, or "" if it has none
+func schemaType(schema map[string]interface{}) string {
+	t, _ := schema["type"].(string)
+	return t
+}
+
+// joinPath appends a property name to a path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// pathName names a path in violations, the root being the result
+func pathName(path string) string {
+	if path == "" {
+		return "result"
+	}
+	return path
+}
+
+// schemaResponseHandler turns responses into maps conforming to a Schema
+type schemaResponseHandler struct {
+	parser   *BaseResponseHandler
+	schema   *Schema
+	validate bool
+}
+
+// newSchemaResponseHandler creates the response handler of a processor with a Schema. With
+// validate, responses violating the schema are replaced by the schema's defaults.
+func newSchemaResponseHandler(name string, schema *Schema, validate bool) *schemaResponseHandler {
+	h := &schemaResponseHandler{schema: schema, validate: validate}
+	h.parser = &BaseResponseHandler{ProcessorType: name}
+	h.parser.DefaultResponder = func() interface{} {
+		return h.defaults()
+	}
+	return h
+}
+
+// defaults returns the schema's defaults with the processor type
+func (h *schemaResponseHandler) defaults() map[string]interface{} {
+	defaults := h.schema.Defaults()
+	defaults["processor_type"] = h.parser.ProcessorType
+	return defaults
+}
+
+// HandleResponse implements the ResponseHandler interface
+func (h *schemaResponseHandler) HandleResponse(_ context.Context, _ string, responseData interface{}) (interface{}, error) {
+	data, validJSON, debugInfo := h.parser.ParseLLMResponse(responseData)
+	if data == nil {
+		return nil, fmt.Errorf("failed to parse response data")
+	}
+	if !validJSON {
+		return data, nil
+	}
+
+	delete(data, "processor_type")
+	delete(data, "debug")
+	result, violations := h.schema.Conform(data)
+	if h.validate && len(violations) > 0 {
+		result = h.defaults()
+	}
+	result["processor_type"] = h.parser.ProcessorType
+	if debugInfo != nil {
+		result["debug"] = debugInfo
+	}
+	return result, nil
+}
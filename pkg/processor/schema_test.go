@@ -0,0 +1,125 @@
+package processor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// ticketSchema is the schema of the tests, as a YAML definition would declare it
+var ticketSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"topic":      map[string]interface{}{"type": "string", "enum": []interface{}{"billing", "outage", "other"}, "default": "other"},
+		"urgency":    map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 5},
+		"refund":     map[string]interface{}{"type": "boolean"},
+		"products":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"escalation": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"team": map[string]interface{}{"type": "string"}}},
+	},
+	"required":             []string{"topic", "urgency", "products"},
+	"additionalProperties": false,
+}
+
+func TestSchemaConform(t *testing.T) {
+	schema, err := NewSchema(ticketSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		value          map[string]interface{}
+		want           map[string]interface{}
+		wantViolations int
+	}{
+		{
+			name:  "conforming",
+			value: map[string]interface{}{"topic": "billing", "urgency": 3.0, "products": []interface{}{"router"}, "refund": true},
+			want:  map[string]interface{}{"topic": "billing", "urgency": 3.0, "products": []interface{}{"router"}, "refund": true},
+		},
+		{
+			name:           "coerced",
+			value:          map[string]interface{}{"topic": "billing", "urgency": "9", "products": []interface{}{"router", 2.0}, "refund": "true"},
+			want:           map[string]interface{}{"topic": "billing", "urgency": 5.0, "products": []interface{}{"router", "2"}, "refund": true},
+			wantViolations: 4,
+		},
+		{
+			name:           "invalid values replaced",
+			value:          map[string]interface{}{"topic": "shipping", "urgency": 2.0, "products": "router", "mood": "angry"},
+			want:           map[string]interface{}{"topic": "other", "urgency": 2.0, "products": []interface{}{}},
+			wantViolations: 3,
+		},
+		{
+			name:           "missing required",
+			value:          map[string]interface{}{"escalation": map[string]interface{}{"team": "tier 2"}},
+			want:           map[string]interface{}{"topic": "other", "urgency": 0.0, "products": []interface{}{}, "escalation": map[string]interface{}{"team": "tier 2"}},
+			wantViolations: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, violations := schema.Conform(tt.value)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if len(violations) != tt.wantViolations {
+				t.Errorf("expected %d violations, got %v", tt.wantViolations, violations)
+			}
+		})
+	}
+}
+
+func TestSchemaProcessor(t *testing.T) {
+	definition := &Definition{
+		Name:      "schema_ticket",
+		Objective: "Classify the support ticket",
+		Schema:    ticketSchema,
+	}
+	response := `{"topic": "outage", "urgency": 4.6, "products": ["router"], "note": "dropped"}`
+
+	tests := []struct {
+		name     string
+		validate bool
+		want     map[string]interface{}
+	}{
+		{
+			name: "conformed",
+			want: map[string]interface{}{"topic": "outage", "urgency": 5.0, "products": []interface{}{"router"}, "processor_type": "schema_ticket"},
+		},
+		{
+			name:     "validated",
+			validate: true,
+			want:     map[string]interface{}{"topic": "other", "urgency": 0.0, "products": []interface{}{}, "processor_type": "schema_ticket"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definition.Validate = tt.validate
+			proc, err := definition.Create(llm.NewMockProviderWithResponse(response), NewDefaultOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			item, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "The internet is down again", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Usage is added to every map result
+			got := item.ProcessingInfo["schema_ticket"].(map[string]interface{})
+			delete(got, "tokens")
+			delete(got, "cost")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	definition.Fields = []FieldDefinition{{Name: "topic"}}
+	if _, err := definition.Create(llm.NewMockProviderWithResponse(response), NewDefaultOptions()); err == nil {
+		t.Error("expected an error for a definition with both fields and a schema")
+	}
+}
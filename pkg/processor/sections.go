@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Names of the standard prompt sections
+const (
+	// SectionJSONOnly demands a response that is nothing but the JSON result. Builder prompts
+	// always end with it.
+	SectionJSONOnly = "json_only"
+	// SectionNoHallucination forbids values the input text doesn't support
+	SectionNoHallucination = "no_hallucination"
+	// SectionTranscript explains how to read conversation transcripts
+	SectionTranscript = "transcript_rules"
+	// SectionOutputLanguage sets the language of free-text values
+	SectionOutputLanguage = "output_language"
+)
+
+// StandardSection is a prompt section shared by the processors referencing it by name with
+// ProcessorBuilder.WithStandardSection, so its wording is the same in every prompt and is
+// updated in one place
+type StandardSection struct {
+	// Title is the section's heading; a section without one is rendered as a plain paragraph
+	Title   string `json:"title,omitempty" yaml:"title,omitempty"`
+	Content string `json:"content" yaml:"content"`
+}
+
+var (
+	standardSectionsLock sync.RWMutex
+	standardSections     = map[string]StandardSection{
+		SectionJSONOnly: {
+			Content: "*** IMPORTANT: Your ENTIRE response must be a single JSON object, without ANY additional text, explanation, or markdown formatting. ***",
+		},
+		SectionNoHallucination: {
+			Title: "Accuracy",
+			Content: "Base every value on the input text. Do not invent facts, names, numbers, dates or quotes, " +
+				"and do not fill gaps with assumptions. If the text doesn't support a value, use an empty or " +
+				"default value and lower the confidence accordingly. Quotes must be copied verbatim.",
+		},
+		SectionTranscript: {
+			Title: "Transcript Handling",
+			Content: "The input may be a transcript of a conversation, with speaker labels such as Agent and " +
+				"Customer and possibly timestamps. Attribute every statement to the speaker who made it, judge " +
+				"the customer by what the customer says, ignore filler words, false starts and obvious " +
+				"transcription errors, and don't treat timestamps or speaker labels as content.",
+		},
+		SectionOutputLanguage: {
+			Title: "Output Language",
+			Content: "Write free-text values, such as explanations and summaries, in the language of the input " +
+				"text. Keep JSON field names and values from fixed sets of allowed values exactly as specified.",
+		},
+	}
+)
+
+// RegisterStandardSection adds a standard section or replaces one, changing the prompts of
+// every processor referencing it, including those already created
+func RegisterStandardSection(name string, section StandardSection) {
+	standardSectionsLock.Lock()
+	defer standardSectionsLock.Unlock()
+	standardSections[name] = section
+}
+
+// LookupStandardSection returns a standard section by name
+func LookupStandardSection(name string) (StandardSection, bool) {
+	standardSectionsLock.RLock()
+	defer standardSectionsLock.RUnlock()
+	section, ok := standardSections[name]
+	return section, ok
+}
+
+// StandardSectionNames returns the names of the standard sections in order
+func StandardSectionNames() []string {
+	standardSectionsLock.RLock()
+	defer standardSectionsLock.RUnlock()
+	names := make([]string, 0, len(standardSections))
+	for name := range standardSections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// render returns the prompt text of a standard section
+func (s StandardSection) render() string {
+	if s.Title == "" {
+		return s.Content
+	}
+	return fmt.Sprintf("**%s:**\n%s", s.Title, s.Content)
+}
+
+// standardSectionText returns the prompt text of a standard section by name
+func standardSectionText(name string) (string, error) {
+	section, ok := LookupStandardSection(name)
+	if !ok {
+		return "", fmt.Errorf("unknown standard prompt section %q", name)
+	}
+	return section.render(), nil
+}
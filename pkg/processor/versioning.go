@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// VersionField is the processing info field recording the schema version of the result a
+// versioned processor produced
+const VersionField = "schema_version"
+
+// Migration upgrades a stored result from one schema version to the next. It may modify
+// and return the result it is given.
+type Migration func(result map[string]interface{}) (map[string]interface{}, error)
+
+// resultVersion is the current schema version of a processor's results and the migrations
+// upgrading older results, keyed by the version they upgrade from
+type resultVersion struct {
+	version    int
+	migrations map[int]Migration
+}
+
+// versions holds the result versions of versioned processors
+var versions = make(map[string]resultVersion)
+
+// RegisterVersion records the current schema version of a registered processor's results
+// and the migrations upgrading older results, keyed by the version each upgrades from.
+// ProcessorBuilder.Register calls it for processors built WithVersion.
+func RegisterVersion(name string, version int, migrations map[int]Migration) {
+	copied := make(map[int]Migration, len(migrations))
+	for from, migration := range migrations {
+		copied[from] = migration
+	}
+
+	globalRegistryLock.Lock()
+	defer globalRegistryLock.Unlock()
+	versions[name] = resultVersion{version: version, migrations: copied}
+}
+
+// ResultVersion returns the current schema version of a processor's results, or 0 if the
+// processor is not versioned
+func ResultVersion(name string) int {
+	globalRegistryLock.RLock()
+	defer globalRegistryLock.RUnlock()
+	return versions[name].version
+}
+
+// Migrate upgrades a stored result of a versioned processor from the schema version that
+// produced it to the current one, applying the migrations of the versions in between in
+// turn. Results stored without a version are taken to be version 1, and versions without
+// a migration are taken to be compatible with the next.
+func Migrate(name string, result map[string]interface{}, from int) (map[string]interface{}, error) {
+	globalRegistryLock.RLock()
+	current, ok := versions[name]
+	globalRegistryLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("processor %s is not versioned", name)
+	}
+	if from <= 0 {
+		from = 1
+	}
+	if from > current.version {
+		return nil, fmt.Errorf("processor %s: result version %d is newer than version %d", name, from, current.version)
+	}
+
+	for version := from; version < current.version; version++ {
+		migration, ok := current.migrations[version]
+		if !ok {
+			continue
+		}
+		migrated, err := migration(result)
+		if err != nil {
+			return nil, fmt.Errorf("processor %s: failed to migrate result from version %d: %w", name, version, err)
+		}
+		result = migrated
+	}
+	return result, nil
+}
+
+// MigrateItem upgrades the result a versioned processor stored in an item, such as one
+// loaded from a sink or checkpoint, to the processor's current schema version. The version
+// is read from the item's processing info, and the item's content is replaced with the
+// migrated result as a map. It reports whether the result was migrated; results already
+// at the current version are left unchanged.
+func MigrateItem(name string, item *data.ProcessItem) (bool, error) {
+	info, _ := item.ProcessingInfo[name].(map[string]interface{})
+	from, err := infoVersion(info[VersionField])
+	if err != nil {
+		return false, fmt.Errorf("item '%s': %w", item.ID, err)
+	}
+	current := ResultVersion(name)
+	if current > 0 && from == current {
+		return false, nil
+	}
+
+	result, err := resultFields(item.Content)
+	if err != nil {
+		return false, fmt.Errorf("item '%s': %w", item.ID, err)
+	}
+	migrated, err := Migrate(name, result, from)
+	if err != nil {
+		return false, fmt.Errorf("item '%s': %w", item.ID, err)
+	}
+
+	item.Content = migrated
+	item.ContentType = "json"
+	if info == nil {
+		info = make(map[string]interface{})
+		if item.ProcessingInfo == nil {
+			item.ProcessingInfo = make(map[string]interface{})
+		}
+		item.ProcessingInfo[name] = info
+	}
+	info[VersionField] = current
+	return true, nil
+}
+
+// infoVersion reads a version recorded in processing info, which is a float64 or string
+// once decoded from JSON, or 0 if none is recorded
+func infoVersion(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case json.Number:
+		version, err := v.Int64()
+		return int(version), err
+	case string:
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", VersionField, v)
+		}
+		return version, nil
+	default:
+		return 0, fmt.Errorf("invalid %s %v", VersionField, value)
+	}
+}
+
+// resultFields returns a result as a map, through its JSON form for structs
+func resultFields(content interface{}) (map[string]interface{}, error) {
+	if fields, ok := content.(map[string]interface{}); ok {
+		return fields, nil
+	}
+	encoded, err := json.Marshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("result is not an object")
+	}
+	return fields, nil
+}
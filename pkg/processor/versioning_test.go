@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// versionedResult is the version 3 result struct of TestVersioning
+type versionedResult struct {
+	Labels        []string `json:"labels"`
+	Confidence    float64  `json:"confidence"`
+	ProcessorType string   `json:"processor_type"`
+}
+
+func TestVersioning(t *testing.T) {
+	NewBuilder("versioned").
+		WithStruct(&versionedResult{}).
+		WithVersion(3).
+		// Version 2 renamed label to labels, as a list
+		WithMigration(1, func(result map[string]interface{}) (map[string]interface{}, error) {
+			label, ok := result["label"].(string)
+			if !ok {
+				return nil, fmt.Errorf("label is missing")
+			}
+			delete(result, "label")
+			result["labels"] = []interface{}{label}
+			return result, nil
+		}).
+		// Version 3 added confidence, with no migration
+		Register()
+
+	proc, err := Create("versioned", llm.NewMockProviderWithResponse(`{"labels": ["billing"], "confidence": 0.9}`), NewDefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := proc.Process(context.Background(), data.NewTextProcessItem("1", "text", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := item.ProcessingInfo["versioned"].(map[string]interface{}); info[VersionField] != 3 {
+		t.Errorf("expected %s 3 in processing info, got %v", VersionField, item.ProcessingInfo["versioned"])
+	}
+	if migrated, err := MigrateItem("versioned", item); migrated || err != nil {
+		t.Errorf("expected a current result to be left unchanged, got %v, %v", migrated, err)
+	}
+
+	tests := []struct {
+		name    string
+		result  map[string]interface{}
+		version interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:    "unversioned result",
+			result:  map[string]interface{}{"label": "billing"},
+			version: nil,
+			want:    map[string]interface{}{"labels": []interface{}{"billing"}},
+		},
+		{
+			name:    "version decoded from JSON",
+			result:  map[string]interface{}{"label": "refund"},
+			version: float64(1),
+			want:    map[string]interface{}{"labels": []interface{}{"refund"}},
+		},
+		{
+			name:    "compatible version",
+			result:  map[string]interface{}{"labels": []interface{}{"billing"}},
+			version: 2,
+			want:    map[string]interface{}{"labels": []interface{}{"billing"}},
+		},
+		{
+			name:    "failed migration",
+			result:  map[string]interface{}{"labels": []interface{}{"billing"}},
+			version: 1,
+			wantErr: true,
+		},
+		{
+			name:    "newer version",
+			result:  map[string]interface{}{"labels": []interface{}{"billing"}},
+			version: 4,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := data.NewTextProcessItem("1", "", nil)
+			item.Content = tt.result
+			item.ProcessingInfo = map[string]interface{}{
+				"versioned": map[string]interface{}{VersionField: tt.version},
+			}
+			migrated, err := MigrateItem("versioned", item)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", item.Content)
+				}
+				return
+			}
+			if err != nil || !migrated {
+				t.Fatalf("expected the result to be migrated, got %v, %v", migrated, err)
+			}
+			if !reflect.DeepEqual(item.Content, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, item.Content)
+			}
+			if info := item.ProcessingInfo["versioned"].(map[string]interface{}); info[VersionField] != 3 {
+				t.Errorf("expected %s 3 after migration, got %v", VersionField, info[VersionField])
+			}
+		})
+	}
+}
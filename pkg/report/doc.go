@@ -0,0 +1,21 @@
+/*
+Package report renders analyses of processed batches as self-contained HTML or Markdown
+reports, replacing the summaries analysts otherwise assemble by hand in notebooks.
+
+A report combines a run's data.RunReport with the items it produced: summary statistics of
+the run, a breakdown of the items by each configured result field with chart data, the
+top examples of each value and drill-down tables listing its items.
+
+Core components:
+
+1. Reports (report.go):
+  - Build: Analyze processed items and an optional RunReport into a Report
+  - Config / Category: Result fields to break items down by, and how many examples and rows to show
+  - Breakdown / ValueSummary: Item counts, shares, top examples and rows per category value
+
+2. Rendering (render.go):
+  - WriteHTML: Self-contained HTML page with CSS bar charts and expandable drill-downs
+  - WriteMarkdown: Markdown with the same sections as tables
+  - Charts: Bar chart data per breakdown, also embedded in HTML reports as JSON
+*/
+package report
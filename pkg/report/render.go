@@ -0,0 +1,211 @@
+package report
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Chart is the data of a bar chart of a breakdown's value counts
+type Chart struct {
+	Title  string   `json:"title"`
+	Labels []string `json:"labels"`
+	Counts []int    `json:"counts"`
+}
+
+// Charts returns the data of a bar chart per breakdown, for charting libraries. HTML
+// reports embed it as JSON in a script element with the id "chart-data".
+func (r *Report) Charts() []Chart {
+	charts := make([]Chart, 0, len(r.Breakdowns))
+	for _, breakdown := range r.Breakdowns {
+		chart := Chart{Title: breakdown.Title, Labels: []string{}, Counts: []int{}}
+		for _, value := range breakdown.Values {
+			chart.Labels = append(chart.Labels, value.Value)
+			chart.Counts = append(chart.Counts, value.Count)
+		}
+		charts = append(charts, chart)
+	}
+	return charts
+}
+
+// templateFuncs are the functions available to both report templates
+var templateFuncs = map[string]interface{}{
+	"percent": func(share float64) string {
+		return fmt.Sprintf("%.1f%%", share*100)
+	},
+	"ms": func(value float64) string {
+		return fmt.Sprintf("%.0f ms", value)
+	},
+	"cost": func(value float64) string {
+		return fmt.Sprintf("$%.4f", value)
+	},
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04 MST")
+	},
+	"confidence": func(value *float64) string {
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", *value)
+	},
+	// cell escapes a value for a Markdown table cell
+	"cell": func(value string) string {
+		return strings.NewReplacer("|", `\|`, "\n", " ").Replace(value)
+	},
+}
+
+// WriteHTML renders the report as a self-contained HTML page, with inline styles, bar
+// charts drawn in CSS and drill-down tables that expand per category value
+func (r *Report) WriteHTML(w io.Writer) error {
+	// The template encodes the chart data as JSON safe to embed in a script element
+	page := struct {
+		*Report
+		ChartData []Chart
+	}{r, r.Charts()}
+	if err := htmlTemplate.Execute(w, page); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return nil
+}
+
+// WriteMarkdown renders the report as Markdown, with drill-down tables per category value
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	if err := markdownTemplate.Execute(w, r); err != nil {
+		return fmt.Errorf("failed to render Markdown report: %w", err)
+	}
+	return nil
+}
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("report").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem auto; max-width: 64rem; color: #222; }
+table { border-collapse: collapse; margin: 0.5rem 0 1rem; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.3rem 0.5rem; text-align: left; vertical-align: top; }
+th { background: #f5f5f5; }
+.stats { display: flex; flex-wrap: wrap; gap: 1rem; }
+.stat { background: #f5f5f5; border-radius: 4px; padding: 0.5rem 1rem; }
+.stat b { display: block; font-size: 1.4rem; }
+.bar { background: #4a7bd0; height: 0.9rem; }
+.muted { color: #777; }
+details { margin: 0.3rem 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="muted">Generated {{date .Generated}}</p>
+
+<h2>Summary</h2>
+<div class="stats">
+<div class="stat"><b>{{.Items}}</b>items</div>
+{{- with .Run}}
+<div class="stat"><b>{{ms .DurationMS}}</b>duration</div>
+<div class="stat"><b>{{.InputTokens}} / {{.OutputTokens}}</b>input / output tokens</div>
+<div class="stat"><b>{{cost .Cost}}</b>estimated cost</div>
+{{- end}}
+</div>
+{{- with .Run}}
+{{- if .Error}}
+<p><strong>Run stopped:</strong> {{.Error}}</p>
+{{- end}}
+{{- if .Processors}}
+<table>
+<tr><th>Processor</th><th>Items</th><th>Succeeded</th><th>Failed</th><th>p50</th><th>p95</th><th>Cost</th></tr>
+{{- range .Processors}}
+<tr><td>{{.Name}}</td><td>{{.Items}}</td><td>{{.Succeeded}}</td><td>{{.Failed}}</td><td>{{ms .Latency.P50MS}}</td><td>{{ms .Latency.P95MS}}</td><td>{{cost .Cost}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{range .Breakdowns}}
+<h2>{{.Title}}</h2>
+<p class="muted">{{.Items}} items with a value, {{.Missing}} without</p>
+<table>
+<tr><th>Value</th><th>Items</th><th>Share</th><th></th></tr>
+{{- range .Values}}
+<tr><td>{{.Value}}</td><td>{{.Count}}</td><td>{{percent .Share}}</td><td style="width: 40%"><div class="bar" style="width: {{percent .Share}}"></div></td></tr>
+{{- end}}
+</table>
+{{- range .Values}}
+<details>
+<summary>{{.Value}} ({{.Count}})</summary>
+{{- if .Examples}}
+<h4>Top examples</h4>
+<ul>
+{{- range .Examples}}
+<li><code>{{.ID}}</code>{{with confidence .Confidence}} <span class="muted">({{.}})</span>{{end}}: {{.Text}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Rows}}
+<table>
+<tr><th>ID</th><th>Confidence</th><th>Text</th></tr>
+{{- range .Rows}}
+<tr><td>{{.ID}}</td><td>{{confidence .Confidence}}</td><td>{{.Text}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</details>
+{{- end}}
+{{end}}
+<script type="application/json" id="chart-data">{{.ChartData}}</script>
+</body>
+</html>
+`))
+
+var markdownTemplate = template.Must(template.New("report").Funcs(templateFuncs).Parse(`# {{.Title}}
+
+Generated {{date .Generated}}
+
+## Summary
+
+- Items: {{.Items}}
+{{- with .Run}}
+- Duration: {{ms .DurationMS}}
+- Tokens: {{.InputTokens}} input, {{.OutputTokens}} output
+- Estimated cost: {{cost .Cost}}
+{{- if .Error}}
+- Run stopped: {{.Error}}
+{{- end}}
+{{- if .Processors}}
+
+| Processor | Items | Succeeded | Failed | p50 | p95 | Cost |
+|-----------|-------|-----------|--------|-----|-----|------|
+{{- range .Processors}}
+| {{cell .Name}} | {{.Items}} | {{.Succeeded}} | {{.Failed}} | {{ms .Latency.P50MS}} | {{ms .Latency.P95MS}} | {{cost .Cost}} |
+{{- end}}
+{{- end}}
+{{- end}}
+{{range .Breakdowns}}
+## {{.Title}}
+
+{{.Items}} items with a value, {{.Missing}} without.
+
+| Value | Items | Share |
+|-------|-------|-------|
+{{- range .Values}}
+| {{cell .Value}} | {{.Count}} | {{percent .Share}} |
+{{- end}}
+{{range .Values}}
+### {{.Value}} ({{.Count}})
+{{if .Examples}}
+Top examples:
+{{range .Examples}}
+- ` + "`{{.ID}}`" + `{{with confidence .Confidence}} ({{.}}){{end}}: {{.Text}}
+{{- end}}
+{{end}}
+{{- if .Rows}}
+| ID | Confidence | Text |
+|----|------------|------|
+{{- range .Rows}}
+| {{cell .ID}} | {{confidence .Confidence}} | {{cell .Text}} |
+{{- end}}
+{{end}}
+{{- end}}
+{{- end}}`))
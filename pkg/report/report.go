@@ -0,0 +1,292 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+const (
+	// DefaultExamples is the number of top examples shown per category value
+	DefaultExamples = 3
+	// DefaultMaxRows is the number of items listed per category value in drill-down tables
+	DefaultMaxRows = 100
+	// DefaultSnippetLength is the number of characters of item text shown in examples
+	DefaultSnippetLength = 200
+)
+
+// Config configures a report
+type Config struct {
+	// Title is the report's title (defaults to the run's name, or "Analysis Report")
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+	// Categories are the result fields the items are broken down by
+	Categories []Category `json:"categories" yaml:"categories"`
+	// Examples is the number of top examples shown per category value (defaults to
+	// DefaultExamples; -1 shows none)
+	Examples int `json:"examples,omitempty" yaml:"examples,omitempty"`
+	// MaxRows is the number of items listed per category value in drill-down tables
+	// (defaults to DefaultMaxRows; -1 lists none)
+	MaxRows int `json:"max_rows,omitempty" yaml:"max_rows,omitempty"`
+	// SnippetLength is the number of characters of item text shown (defaults to
+	// DefaultSnippetLength)
+	SnippetLength int `json:"snippet_length,omitempty" yaml:"snippet_length,omitempty"`
+}
+
+// Category is a result field items are broken down by, such as the label_name of intent.
+// Items whose field is a list count once towards each of its values.
+type Category struct {
+	// Processor is the processor whose result holds the field
+	Processor string `json:"processor" yaml:"processor"`
+	// Field is the JSON name of the field
+	Field string `json:"field" yaml:"field"`
+	// ConfidenceField is the field of the same result that ranks examples, highest first
+	// (defaults to "confidence")
+	ConfidenceField string `json:"confidence_field,omitempty" yaml:"confidence_field,omitempty"`
+	// Title is the category's heading (defaults to "<processor> <field>")
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+}
+
+// Report is an analysis of a batch of items, ready to render as HTML or Markdown. It is
+// serializable to JSON, e.g. for charting elsewhere.
+type Report struct {
+	Title     string    `json:"title"`
+	Generated time.Time `json:"generated"`
+	// Run is the run's report, if one was given
+	Run *data.RunReport `json:"run,omitempty"`
+	// Items is the number of items analyzed
+	Items int `json:"items"`
+	// Breakdowns are the items broken down by each category, in config order
+	Breakdowns []Breakdown `json:"breakdowns"`
+}
+
+// Breakdown counts the items of each value of a category
+type Breakdown struct {
+	Title     string `json:"title"`
+	Processor string `json:"processor"`
+	Field     string `json:"field"`
+	// Items is the number of items with a value; Missing is the number without one
+	Items   int `json:"items"`
+	Missing int `json:"missing"`
+	// Values are the category's values, most frequent first
+	Values []ValueSummary `json:"values"`
+}
+
+// ValueSummary holds the items of one value of a category
+type ValueSummary struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+	// Share is the fraction of the breakdown's items with the value
+	Share float64 `json:"share"`
+	// Examples are the items with the highest confidence
+	Examples []Example `json:"examples,omitempty"`
+	// Rows are the items listed in the drill-down table, in item order
+	Rows []Example `json:"rows,omitempty"`
+}
+
+// Example is an item shown in a report
+type Example struct {
+	ID string `json:"id"`
+	// Text is a snippet of the item's original text
+	Text       string   `json:"text,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// withDefaults returns the config with defaults applied
+func (c Config) withDefaults() Config {
+	if c.Examples == 0 {
+		c.Examples = DefaultExamples
+	}
+	if c.MaxRows == 0 {
+		c.MaxRows = DefaultMaxRows
+	}
+	if c.SnippetLength <= 0 {
+		c.SnippetLength = DefaultSnippetLength
+	}
+	return c
+}
+
+// Build analyzes processed items into a report. run is the run's report and may be nil.
+func Build(run *data.RunReport, items []*data.ProcessItem, config Config) (*Report, error) {
+	config = config.withDefaults()
+	report := &Report{
+		Title:     config.Title,
+		Generated: time.Now(),
+		Run:       run,
+		Items:     len(items),
+	}
+	if report.Title == "" && run != nil {
+		report.Title = run.Name
+	}
+	if report.Title == "" {
+		report.Title = "Analysis Report"
+	}
+
+	for _, category := range config.Categories {
+		if category.Processor == "" || category.Field == "" {
+			return nil, fmt.Errorf("category requires a processor and a field")
+		}
+		breakdown, err := buildBreakdown(category, items, config)
+		if err != nil {
+			return nil, err
+		}
+		report.Breakdowns = append(report.Breakdowns, breakdown)
+	}
+	return report, nil
+}
+
+// buildBreakdown counts the items of each value of a category
+func buildBreakdown(category Category, items []*data.ProcessItem, config Config) (Breakdown, error) {
+	if category.ConfidenceField == "" {
+		category.ConfidenceField = "confidence"
+	}
+	breakdown := Breakdown{
+		Title:     category.Title,
+		Processor: category.Processor,
+		Field:     category.Field,
+	}
+	if breakdown.Title == "" {
+		breakdown.Title = category.Processor + " " + category.Field
+	}
+
+	members := make(map[string][]Example)
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		result, err := resultFields(item, category.Processor)
+		if err != nil {
+			return Breakdown{}, err
+		}
+		values := categoryValues(result[category.Field])
+		if len(values) == 0 {
+			breakdown.Missing++
+			continue
+		}
+		breakdown.Items++
+
+		example := Example{ID: item.ID, Text: snippet(itemText(item), config.SnippetLength)}
+		if confidence, ok := result[category.ConfidenceField].(float64); ok {
+			example.Confidence = &confidence
+		}
+		for _, value := range values {
+			members[value] = append(members[value], example)
+		}
+	}
+
+	for value, examples := range members {
+		summary := ValueSummary{Value: value, Count: len(examples)}
+		summary.Share = float64(summary.Count) / float64(breakdown.Items)
+		if config.MaxRows > 0 {
+			summary.Rows = examples[:min(len(examples), config.MaxRows)]
+		}
+		if config.Examples > 0 {
+			summary.Examples = topExamples(examples, config.Examples)
+		}
+		breakdown.Values = append(breakdown.Values, summary)
+	}
+	sort.Slice(breakdown.Values, func(i, j int) bool {
+		if breakdown.Values[i].Count != breakdown.Values[j].Count {
+			return breakdown.Values[i].Count > breakdown.Values[j].Count
+		}
+		return breakdown.Values[i].Value < breakdown.Values[j].Value
+	})
+	return breakdown, nil
+}
+
+// resultFields returns the result a processor recorded in an item's processing info as a
+// map, or nil if there is none. The result goes through its JSON form, as it may hold
+// structs before the item has been through JSON.
+func resultFields(item *data.ProcessItem, processor string) (map[string]interface{}, error) {
+	info, ok := item.ProcessingInfo[processor]
+	if !ok || info == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("item '%s': failed to read %s result: %w", item.ID, processor, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("item '%s': %s result is not an object", item.ID, processor)
+	}
+	return fields, nil
+}
+
+// categoryValues returns the values of a category field: each element of a list, or the
+// field itself, ignoring empty values
+func categoryValues(field interface{}) []string {
+	var values []string
+	add := func(value interface{}) {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		default:
+			values = append(values, fmt.Sprint(v))
+		}
+	}
+
+	list, ok := field.([]interface{})
+	if !ok {
+		add(field)
+		return values
+	}
+	for _, element := range list {
+		add(element)
+	}
+	// An item counts once towards each value
+	seen := make(map[string]bool, len(values))
+	distinct := values[:0]
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			distinct = append(distinct, value)
+		}
+	}
+	return distinct
+}
+
+// topExamples returns the n examples with the highest confidence, in item order among equals
+func topExamples(examples []Example, n int) []Example {
+	ranked := make([]Example, len(examples))
+	copy(ranked, examples)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return confidence(ranked[i]) > confidence(ranked[j])
+	})
+	return ranked[:min(len(ranked), n)]
+}
+
+// confidence returns an example's confidence, ranking examples without one last
+func confidence(example Example) float64 {
+	if example.Confidence == nil {
+		return -1
+	}
+	return *example.Confidence
+}
+
+// itemText returns the original text of a processed item
+func itemText(item *data.ProcessItem) string {
+	if text, ok := item.Metadata["original_text"].(string); ok {
+		return text
+	}
+	if text, ok := item.Content.(string); ok {
+		return text
+	}
+	return ""
+}
+
+// snippet shortens text to at most n characters, collapsing whitespace
+func snippet(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+)
+
+// reportItem returns a processed item with an intent result
+func reportItem(id, text string, intent map[string]interface{}) *data.ProcessItem {
+	item := data.NewTextProcessItem(id, "", map[string]interface{}{"original_text": text})
+	item.AddProcessingInfo("intent", intent)
+	return item
+}
+
+func TestReport(t *testing.T) {
+	items := []*data.ProcessItem{
+		reportItem("1", "I want my money back", map[string]interface{}{"label_name": "refund", "confidence": 0.6, "tags": []interface{}{"billing", "billing", "urgent"}}),
+		reportItem("2", "Please refund <b>me</b>", map[string]interface{}{"label_name": "refund", "confidence": 0.9, "tags": []interface{}{"billing"}}),
+		reportItem("3", "Cancel my plan", map[string]interface{}{"label_name": "cancel", "confidence": 0.8}),
+		reportItem("4", "Hello?", map[string]interface{}{"label_name": "", "confidence": 0.1}),
+	}
+	run := &data.RunReport{Name: "support batch", Items: 4, Processors: []data.ProcessorReport{{Name: "intent", Items: 4, Succeeded: 4}}}
+
+	report, err := Build(run, items, Config{
+		Categories: []Category{
+			{Processor: "intent", Field: "label_name", Title: "Intents"},
+			{Processor: "intent", Field: "tags"},
+		},
+		Examples: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Title != "support batch" {
+		t.Errorf("expected the run's name as title, got %q", report.Title)
+	}
+
+	intents := report.Breakdowns[0]
+	if intents.Items != 3 || intents.Missing != 1 {
+		t.Errorf("expected 3 items with an intent and 1 without, got %d and %d", intents.Items, intents.Missing)
+	}
+	if len(intents.Values) != 2 || intents.Values[0].Value != "refund" || intents.Values[0].Count != 2 {
+		t.Fatalf("expected refund to be the most frequent intent, got %+v", intents.Values)
+	}
+	if examples := intents.Values[0].Examples; len(examples) != 1 || examples[0].ID != "2" {
+		t.Errorf("expected the most confident refund as the example, got %+v", examples)
+	}
+	if rows := intents.Values[0].Rows; len(rows) != 2 || rows[0].ID != "1" {
+		t.Errorf("expected the refunds in item order, got %+v", rows)
+	}
+
+	tags := report.Breakdowns[1]
+	if tags.Title != "intent tags" || tags.Values[0].Value != "billing" || tags.Values[0].Count != 2 {
+		t.Errorf("expected each item to count once towards billing, got %+v", tags)
+	}
+
+	var html bytes.Buffer
+	if err := report.WriteHTML(&html); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<h2>Intents</h2>",
+		"Please refund &lt;b&gt;me&lt;/b&gt;",
+		`<script type="application/json" id="chart-data">[{"title":"Intents","labels":["refund","cancel"],"counts":[2,1]}`,
+	} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("expected the HTML report to contain %q", want)
+		}
+	}
+
+	var markdown bytes.Buffer
+	if err := report.WriteMarkdown(&markdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# support batch", "| refund | 2 | 66.7% |", "| 1 | 0.60 | I want my money back |"} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("expected the Markdown report to contain %q, got\n%s", want, markdown.String())
+		}
+	}
+
+	if _, err := Build(nil, items, Config{Categories: []Category{{Processor: "intent"}}}); err == nil {
+		t.Error("expected an error for a category without a field")
+	}
+}
@@ -0,0 +1,345 @@
+package serve
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// AuditConfig configures audit logging of API requests
+type AuditConfig struct {
+	// Path is a JSON Lines file audit records are appended to ("-" for standard output).
+	// Records can also be sent to Config.AuditSink, e.g. for OTLP.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// MaxInputChars limits the input text kept in each record (defaults to 200; -1 keeps none)
+	MaxInputChars int `json:"max_input_chars,omitempty" yaml:"max_input_chars,omitempty"`
+	// Redact selects the personal data removed from input text, e.g. emails, phone numbers,
+	// account numbers and custom patterns; input is kept as is if unset
+	Redact *processor.RedactionConfig `json:"redact,omitempty" yaml:"redact,omitempty"`
+	// InputTokenCost and OutputTokenCost are prices per million tokens, used to estimate cost
+	InputTokenCost  float64 `json:"input_token_cost,omitempty" yaml:"input_token_cost,omitempty"`
+	OutputTokenCost float64 `json:"output_token_cost,omitempty" yaml:"output_token_cost,omitempty"`
+}
+
+// AuditRecord describes one API request, or one finished job
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	// Caller is the API key's name, or empty if authentication is disabled
+	Caller string `json:"caller,omitempty"`
+	// Tenant is the tenant the request was made for, if the server has tenants
+	Tenant     string `json:"tenant,omitempty"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	Method     string `json:"method"`
+	// Route is the endpoint's path, or "job" for a finished job
+	Route     string `json:"route"`
+	Status    int    `json:"status"`
+	Processor string `json:"processor,omitempty"`
+	Pipeline  string `json:"pipeline,omitempty"`
+	Model     string `json:"model,omitempty"`
+	// JobID is the ID of a submitted or finished job
+	JobID string `json:"job_id,omitempty"`
+	// Input is the request's text, redacted and truncated
+	Input string `json:"input,omitempty"`
+	// InputTokens and OutputTokens are estimated at four characters per token, as
+	// providers don't report usage
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+	LatencyMS    float64 `json:"latency_ms"`
+	// Error is the error code of a failed request
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink receives audit records
+type AuditSink interface {
+	Record(ctx context.Context, record AuditRecord) error
+}
+
+// JSONLAuditSink writes audit records as JSON Lines
+type JSONLAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLAuditSink creates a sink writing one record per line to w
+func NewJSONLAuditSink(w io.Writer) *JSONLAuditSink {
+	return &JSONLAuditSink{w: w}
+}
+
+// Record implements AuditSink
+func (s *JSONLAuditSink) Record(_ context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the writer if it is an io.Closer
+func (s *JSONLAuditSink) Close() error {
+	if closer, ok := s.w.(io.Closer); ok && s.w != os.Stdout {
+		return closer.Close()
+	}
+	return nil
+}
+
+// SlogAuditSink logs audit records with a slog.Logger, so they can go wherever its handler
+// sends them, e.g. to an OpenTelemetry collector through the otelslog bridge
+type SlogAuditSink struct {
+	logger *slog.Logger
+}
+
+// NewSlogAuditSink creates a sink logging each record as an "api audit" message
+func NewSlogAuditSink(logger *slog.Logger) *SlogAuditSink {
+	return &SlogAuditSink{logger: logger}
+}
+
+// Record implements AuditSink
+func (s *SlogAuditSink) Record(ctx context.Context, r AuditRecord) error {
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "api audit",
+		slog.Time("time", r.Time),
+		slog.String("request_id", r.RequestID),
+		slog.String("caller", r.Caller),
+		slog.String("tenant", r.Tenant),
+		slog.String("remote_addr", r.RemoteAddr),
+		slog.String("method", r.Method),
+		slog.String("route", r.Route),
+		slog.Int("status", r.Status),
+		slog.String("processor", r.Processor),
+		slog.String("pipeline", r.Pipeline),
+		slog.String("model", r.Model),
+		slog.String("job_id", r.JobID),
+		slog.String("input", r.Input),
+		slog.Int64("input_tokens", r.InputTokens),
+		slog.Int64("output_tokens", r.OutputTokens),
+		slog.Float64("cost", r.Cost),
+		slog.Float64("latency_ms", r.LatencyMS),
+		slog.String("error", r.Error),
+	)
+	return nil
+}
+
+// auditor builds audit records and sends them to the configured sinks
+type auditor struct {
+	sinks []AuditSink
+	// redactor redacts input text, or is nil if the config doesn't redact
+	redactor *processor.Redactor
+	maxInput int
+	config   AuditConfig
+}
+
+// newAuditor creates an auditor for the config, or nil if auditing is disabled
+func newAuditor(config Config) (*auditor, error) {
+	a := &auditor{maxInput: config.Audit.MaxInputChars, config: config.Audit}
+	if a.maxInput == 0 {
+		a.maxInput = 200
+	}
+	if config.Audit.Redact != nil {
+		redactor, err := processor.NewRedactor(*config.Audit.Redact)
+		if err != nil {
+			return nil, fmt.Errorf("audit: %w", err)
+		}
+		a.redactor = redactor
+	}
+
+	switch config.Audit.Path {
+	case "":
+	case "-":
+		a.sinks = append(a.sinks, NewJSONLAuditSink(os.Stdout))
+	default:
+		file, err := os.OpenFile(config.Audit.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("audit: failed to open log: %w", err)
+		}
+		a.sinks = append(a.sinks, NewJSONLAuditSink(file))
+	}
+	if config.AuditSink != nil {
+		a.sinks = append(a.sinks, config.AuditSink)
+	}
+
+	if len(a.sinks) == 0 {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// record prices an entry's usage and sends it to every sink. Sink failures must not fail
+// the request, so they are reported on standard error.
+func (a *auditor) record(ctx context.Context, e *auditEntry) {
+	if a == nil {
+		return
+	}
+	e.mu.Lock()
+	record := e.record
+	record.Input = e.input.String()
+	e.mu.Unlock()
+
+	record.Cost = llm.EstimateCost(record.InputTokens, record.OutputTokens, a.config.InputTokenCost, a.config.OutputTokenCost)
+	record.LatencyMS = float64(time.Since(e.start).Microseconds()) / 1000
+	for _, sink := range a.sinks {
+		if err := sink.Record(ctx, record); err != nil {
+			fmt.Fprintf(os.Stderr, "audit: %v\n", err)
+		}
+	}
+}
+
+// close closes the sinks that can be closed
+func (a *auditor) close() {
+	if a == nil {
+		return
+	}
+	for _, sink := range a.sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// auditEntry collects a record while a request or job runs
+type auditEntry struct {
+	mu       sync.Mutex
+	auditor  *auditor
+	start    time.Time
+	record   AuditRecord
+	input    strings.Builder
+	inputLen int
+}
+
+// auditKey is the context key of the request's audit entry
+type auditKey struct{}
+
+// newAuditEntry starts an entry for a request, with a random request ID
+func (a *auditor) newAuditEntry(r *http.Request, route string) *auditEntry {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &auditEntry{
+		auditor: a,
+		start:   time.Now(),
+		record: AuditRecord{
+			Time:       time.Now(),
+			RequestID:  hex.EncodeToString(id),
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			Route:      route,
+		},
+	}
+}
+
+// newJobEntry starts an entry for a job submitted by the request of entry e, or returns
+// nil without one. It shares the request's ID and caller but collects its own input and
+// usage, since the job runs after the request has finished.
+func (e *auditEntry) newJobEntry(job *Job) *auditEntry {
+	if e == nil {
+		return nil
+	}
+	e.update(func(record *AuditRecord) { record.JobID = job.ID })
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return &auditEntry{
+		auditor: e.auditor,
+		start:   time.Now(),
+		record: AuditRecord{
+			Time:       time.Now(),
+			RequestID:  e.record.RequestID,
+			Caller:     e.record.Caller,
+			Tenant:     e.record.Tenant,
+			RemoteAddr: e.record.RemoteAddr,
+			Method:     e.record.Method,
+			Route:      "job",
+			Processor:  job.Processor,
+			Pipeline:   job.Pipeline,
+			Model:      e.record.Model,
+			JobID:      job.ID,
+		},
+	}
+}
+
+// auditFrom returns the context's audit entry, or nil if auditing is disabled
+func auditFrom(ctx context.Context) *auditEntry {
+	e, _ := ctx.Value(auditKey{}).(*auditEntry)
+	return e
+}
+
+// update changes the entry's record; it does nothing without an entry
+func (e *auditEntry) update(fn func(record *AuditRecord)) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fn(&e.record)
+}
+
+// fail records the error code of a failed request
+func (e *auditEntry) fail(err error) {
+	code := asAPIError(err).Code
+	e.update(func(record *AuditRecord) { record.Error = code })
+}
+
+// addInput adds redacted text to the entry's input, up to the input limit
+func (e *auditEntry) addInput(text string) {
+	if e == nil || e.auditor.maxInput < 0 {
+		return
+	}
+	if e.auditor.redactor != nil {
+		text = e.auditor.redactor.Redact(text)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.inputLen >= e.auditor.maxInput {
+		return
+	}
+	if e.inputLen > 0 {
+		e.input.WriteString("\n")
+	}
+	runes := []rune(text)
+	if remaining := e.auditor.maxInput - e.inputLen; len(runes) > remaining {
+		runes = append(runes[:remaining], '…')
+	}
+	e.input.WriteString(string(runes))
+	e.inputLen += len(runes)
+}
+
+// auditInput adds the text of each item read from source to the context's audit entry
+func auditInput(ctx context.Context, source data.ProcessItemSource) data.ProcessItemSource {
+	e := auditFrom(ctx)
+	if e == nil {
+		return source
+	}
+	return data.Map(source, func(item *data.ProcessItem) (*data.ProcessItem, error) {
+		if text, err := item.GetTextContent(); err == nil {
+			e.addInput(text)
+		}
+		return item, nil
+	})
+}
+
+// recordUsage adds the estimated tokens of a call to the context's audit entry; it meters
+// providers with llm.WithMetering
+func recordUsage(ctx context.Context, prompt, response string) {
+	auditFrom(ctx).update(func(record *AuditRecord) {
+		record.InputTokens += llm.EstimateTokens(prompt)
+		record.OutputTokens += llm.EstimateTokens(response)
+	})
+}
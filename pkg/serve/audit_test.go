@@ -0,0 +1,188 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// auditRecords decodes the records a JSONLAuditSink wrote to buf
+func auditRecords(t *testing.T, buf *bytes.Buffer) []AuditRecord {
+	t.Helper()
+	var records []AuditRecord
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid audit record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAuditInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		redact    *processor.RedactionConfig
+		maxInput  int
+		text      string
+		wantInput string
+	}{
+		{name: "unredacted", text: "Great support", wantInput: "Great support"},
+		{name: "email", redact: &processor.RedactionConfig{Emails: true}, text: "Mail jane@example.com", wantInput: "Mail [REDACTED:email]"},
+		{
+			name:      "card and phone",
+			redact:    &processor.RedactionConfig{PhoneNumbers: true, AccountNumbers: true},
+			text:      "Card 4111 1111 1111 1111, call +1 555 123 4567 on 2024-01-15",
+			wantInput: "Card [REDACTED:account_number], call [REDACTED:phone] on 2024-01-15",
+		},
+		{
+			name:      "custom pattern",
+			redact:    &processor.RedactionConfig{Patterns: map[string]string{"order": `ORD-\d+`}},
+			text:      "Where is ORD-1234?",
+			wantInput: "Where is [REDACTED:order]?",
+		},
+		{name: "truncated", maxInput: 5, text: "Great support", wantInput: "Great…"},
+		{name: "no input kept", maxInput: -1, text: "Great support", wantInput: ""},
+		{
+			name:      "redacted before truncation",
+			redact:    &processor.RedactionConfig{Emails: true},
+			maxInput:  21,
+			text:      "Mail jane@example.com now",
+			wantInput: "Mail [REDACTED:email]…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			s := newTestServer(t, Config{
+				Audit:     AuditConfig{Redact: tt.redact, MaxInputChars: tt.maxInput},
+				AuditSink: NewJSONLAuditSink(&buf),
+			})
+			body, _ := json.Marshal(ProcessRequest{Text: tt.text, Processor: "sentiment"})
+			if w := do(s, http.MethodPost, "/api/process", string(body), nil); w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			records := auditRecords(t, &buf)
+			if len(records) != 1 {
+				t.Fatalf("expected 1 audit record, got %d", len(records))
+			}
+			if records[0].Input != tt.wantInput {
+				t.Errorf("expected input %q, got %q", tt.wantInput, records[0].Input)
+			}
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := NewWithProvider(Config{
+			Audit:     AuditConfig{Redact: &processor.RedactionConfig{Patterns: map[string]string{"ssn": `(`}}},
+			AuditSink: NewJSONLAuditSink(&bytes.Buffer{}),
+		}, llm.NewMockProviderWithResponse(sentimentResponse))
+		if err == nil || !strings.Contains(err.Error(), "redaction pattern ssn") {
+			t.Errorf("expected an invalid pattern error, got %v", err)
+		}
+	})
+}
+
+func TestAuditRecord(t *testing.T) {
+	var buf bytes.Buffer
+	s := newTestServer(t, Config{
+		Tenants: map[string]pipeline.TenantConfig{
+			"acme": {Provider: pipeline.ProviderConfig{Type: "mock", Model: "mock", Options: map[string]interface{}{
+				"response": sentimentResponse,
+			}}},
+		},
+		Auth:      AuthConfig{Keys: []APIKey{{Name: "alice", Key: "alice-key", Tenant: "acme"}}},
+		Audit:     AuditConfig{InputTokenCost: 2, OutputTokenCost: 4},
+		AuditSink: NewJSONLAuditSink(&buf),
+	})
+
+	header := http.Header{"X-Api-Key": {"alice-key"}}
+	processed := do(s, http.MethodPost, "/api/process", `{"text": "Great support!", "processor": "sentiment"}`, header)
+	if processed.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, processed.Code, processed.Body.String())
+	}
+	w := do(s, http.MethodPost, "/api/process", `{"text": "Great support!", "processor": "missing"}`, header)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+
+	records := auditRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 audit records, got %d", len(records))
+	}
+	record := records[0]
+	if record.Caller != "alice" || record.Tenant != "acme" {
+		t.Errorf("expected caller alice and tenant acme, got %q and %q", record.Caller, record.Tenant)
+	}
+	if record.Method != http.MethodPost || record.Route != "/api/process" || record.Status != http.StatusOK {
+		t.Errorf("unexpected request fields %+v", record)
+	}
+	if record.Processor != "sentiment" || record.Input != "Great support!" {
+		t.Errorf("expected the processor and input, got %q and %q", record.Processor, record.Input)
+	}
+	if record.RequestID == "" || record.RequestID != processed.Header().Get("X-Request-ID") {
+		t.Errorf("expected the request ID of the response, got %q", record.RequestID)
+	}
+	if record.InputTokens <= 0 || record.OutputTokens <= 0 {
+		t.Fatalf("expected estimated tokens, got %d in and %d out", record.InputTokens, record.OutputTokens)
+	}
+	wantCost := float64(record.InputTokens*2+record.OutputTokens*4) / 1e6
+	if record.Cost != wantCost {
+		t.Errorf("expected cost %v, got %v", wantCost, record.Cost)
+	}
+
+	failed := records[1]
+	if failed.Status != http.StatusNotFound || failed.Error != CodeUnknownProcessor || failed.InputTokens != 0 {
+		t.Errorf("unexpected record of a failed request %+v", failed)
+	}
+}
+
+func TestAuditJob(t *testing.T) {
+	var buf bytes.Buffer
+	s := newTestServerWithProvider(t, Config{
+		Audit:     AuditConfig{Redact: &processor.RedactionConfig{Emails: true}},
+		AuditSink: NewJSONLAuditSink(&buf),
+	}, newGatedProvider())
+
+	path := submitJob(t, s, `{"texts": ["Great, jane@example.com", "Broken"], "processor": "sentiment"}`)
+	job := waitForJob(t, s, path, finished)
+
+	// Polling the job is audited too, so the records are found by route
+	var submitted, finishedJob AuditRecord
+	for _, record := range auditRecords(t, &buf) {
+		switch record.Route {
+		case "/api/jobs":
+			submitted = record
+		case "job":
+			finishedJob = record
+		}
+	}
+	if submitted.Route != "/api/jobs" || submitted.Status != http.StatusAccepted || submitted.JobID != job.ID {
+		t.Errorf("unexpected submission record %+v", submitted)
+	}
+	if finishedJob.Route != "job" || finishedJob.JobID != job.ID || finishedJob.RequestID != submitted.RequestID {
+		t.Errorf("expected the job's record to share the submission's request ID, got %+v", finishedJob)
+	}
+	if finishedJob.Status != http.StatusOK || finishedJob.Processor != "sentiment" {
+		t.Errorf("unexpected job fields %+v", finishedJob)
+	}
+	if finishedJob.Input != "Great, [REDACTED:email]\nBroken" {
+		t.Errorf("expected the job's redacted input, got %q", finishedJob.Input)
+	}
+	// Only the successful item is metered
+	if finishedJob.InputTokens <= 0 || finishedJob.OutputTokens <= 0 {
+		t.Errorf("expected the job's estimated tokens, got %d in and %d out", finishedJob.InputTokens, finishedJob.OutputTokens)
+	}
+}
@@ -0,0 +1,194 @@
+package serve
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthConfig restricts the API to known keys. The API is open if no keys are configured.
+type AuthConfig struct {
+	// Keys are the accepted API keys
+	Keys []APIKey `json:"keys,omitempty" yaml:"keys,omitempty"`
+}
+
+// APIKey is a key clients send in an X-API-Key header or as an Authorization bearer token
+type APIKey struct {
+	// Name identifies the key in errors and logs
+	Name string `json:"name" yaml:"name"`
+	// Key is the key itself; prefer KeyEnv to keep keys out of config files
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// KeyEnv is the environment variable holding the key
+	KeyEnv string `json:"key_env,omitempty" yaml:"key_env,omitempty"`
+	// RateLimit is the number of requests per minute the key may make (0 for no limit)
+	RateLimit int `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+	// Processors are the processors the key may run (all if empty)
+	Processors []string `json:"processors,omitempty" yaml:"processors,omitempty"`
+	// Pipelines are the pipelines the key may run as jobs (all if empty)
+	Pipelines []string `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+	// Models are the configured models the key may select (all if empty)
+	Models []string `json:"models,omitempty" yaml:"models,omitempty"`
+	// Model is the configured model the key's requests use unless they select one
+	// (defaults to the server's provider)
+	Model string `json:"model,omitempty" yaml:"model,omitempty"`
+	// Tenant binds the key to one of the configured tenants, whose provider and budget its
+	// requests use; keys without one name the tenant in the X-Tenant-ID header
+	Tenant string `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+}
+
+// client is an authenticated API key and its rate limiter
+type client struct {
+	key     APIKey
+	limiter *rateLimiter
+}
+
+// clientKey is the context key of the request's client
+type clientKey struct{}
+
+// clients resolves the configured keys, indexed by their SHA-256 hash so lookups don't
+// depend on how much of a key matches
+func (c AuthConfig) clients() (map[[32]byte]*client, error) {
+	clients := make(map[[32]byte]*client, len(c.Keys))
+	for i, key := range c.Keys {
+		if key.Name == "" {
+			key.Name = fmt.Sprintf("key-%d", i+1)
+		}
+		if key.KeyEnv != "" {
+			key.Key = os.Getenv(key.KeyEnv)
+			if key.Key == "" {
+				return nil, fmt.Errorf("API key %s: environment variable %s is not set", key.Name, key.KeyEnv)
+			}
+		}
+		if key.Key == "" {
+			return nil, fmt.Errorf("API key %s: key or key_env is required", key.Name)
+		}
+
+		hash := sha256.Sum256([]byte(key.Key))
+		if _, ok := clients[hash]; ok {
+			return nil, fmt.Errorf("API key %s: duplicate key", key.Name)
+		}
+		clients[hash] = &client{key: key, limiter: newRateLimiter(key.RateLimit)}
+	}
+	return clients, nil
+}
+
+// authenticate identifies the request's client and applies its rate limit. The client is
+// nil if authentication is disabled.
+func (s *Server) authenticate(r *http.Request) (*client, error) {
+	if len(s.settings.clients) == 0 {
+		return nil, nil
+	}
+
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			key = strings.TrimSpace(token)
+		}
+	}
+	if key == "" {
+		return nil, newError(http.StatusUnauthorized, CodeUnauthorized, "an API key is required")
+	}
+
+	c, ok := s.settings.clients[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, newError(http.StatusUnauthorized, CodeUnauthorized, "invalid API key")
+	}
+	if wait := c.limiter.wait(); wait > 0 {
+		return nil, &rateLimitError{
+			APIError: newError(http.StatusTooManyRequests, CodeRateLimited,
+				fmt.Sprintf("rate limit of %d requests per minute exceeded", c.key.RateLimit)),
+			retryAfter: wait,
+		}
+	}
+	return c, nil
+}
+
+// rateLimitError is an APIError telling the client when to retry
+type rateLimitError struct {
+	*APIError
+	retryAfter time.Duration
+}
+
+// Unwrap lets writeError find the APIError
+func (e *rateLimitError) Unwrap() error {
+	return e.APIError
+}
+
+// writeAuthError sends an authentication failure with the headers it calls for
+func writeAuthError(w http.ResponseWriter, err error) {
+	if limited, ok := err.(*rateLimitError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limited.retryAfter.Seconds()))))
+	} else {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="agentic-text"`)
+	}
+	writeError(w, err)
+}
+
+// requestClient returns the request's client, or nil if authentication is disabled
+func requestClient(ctx context.Context) *client {
+	c, _ := ctx.Value(clientKey{}).(*client)
+	return c
+}
+
+// keyName returns the name of the request's API key, or "" if authentication is disabled
+func keyName(ctx context.Context) string {
+	if c := requestClient(ctx); c != nil {
+		return c.key.Name
+	}
+	return ""
+}
+
+// allowProcessor reports whether the request's client may run a processor
+func allowProcessor(ctx context.Context, name string) bool {
+	c := requestClient(ctx)
+	return c == nil || len(c.key.Processors) == 0 || slices.Contains(c.key.Processors, name)
+}
+
+// allowPipeline reports whether the request's client may run a pipeline
+func allowPipeline(ctx context.Context, name string) bool {
+	c := requestClient(ctx)
+	return c == nil || len(c.key.Pipelines) == 0 || slices.Contains(c.key.Pipelines, name)
+}
+
+// rateLimiter is a token bucket allowing a number of requests per minute, with bursts up
+// to the same number
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a limiter for perMinute requests per minute, or nil for no limit
+func newRateLimiter(perMinute int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &rateLimiter{limit: float64(perMinute), tokens: float64(perMinute), last: time.Now()}
+}
+
+// wait takes a token, returning zero if one was available or else how long until one is
+func (l *rateLimiter) wait() time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.limit, l.tokens+now.Sub(l.last).Minutes()*l.limit)
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.limit * float64(time.Minute))
+}
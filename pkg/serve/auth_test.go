@@ -0,0 +1,157 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+)
+
+func TestAuthentication(t *testing.T) {
+	config := Config{Auth: AuthConfig{Keys: []APIKey{
+		{Name: "full", Key: "full-key"},
+		{Name: "intent-only", Key: "intent-key", Processors: []string{"intent"}},
+	}}}
+	body := `{"text": "Great support!", "processor": "sentiment"}`
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "missing key", wantStatus: http.StatusUnauthorized, wantCode: CodeUnauthorized},
+		{name: "invalid key", header: http.Header{"X-Api-Key": {"wrong"}}, wantStatus: http.StatusUnauthorized, wantCode: CodeUnauthorized},
+		{name: "malformed authorization", header: http.Header{"Authorization": {"Basic full-key"}}, wantStatus: http.StatusUnauthorized, wantCode: CodeUnauthorized},
+		{name: "X-API-Key header", header: http.Header{"X-Api-Key": {"full-key"}}, wantStatus: http.StatusOK},
+		{name: "bearer token", header: http.Header{"Authorization": {"Bearer full-key"}}, wantStatus: http.StatusOK},
+		{name: "processor not allowed", header: http.Header{"X-Api-Key": {"intent-key"}}, wantStatus: http.StatusForbidden, wantCode: CodeForbidden},
+	}
+
+	s := newTestServer(t, config)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(s, http.MethodPost, "/api/process", body, tt.header)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			if code := errorCode(t, w); code != tt.wantCode {
+				t.Errorf("expected code %s, got %s", tt.wantCode, code)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected a WWW-Authenticate header")
+			}
+		})
+	}
+
+	// Public endpoints don't need a key
+	if w := do(s, http.MethodGet, "/api/openapi.json", "", nil); w.Code != http.StatusOK {
+		t.Errorf("expected the OpenAPI document without a key, got status %d", w.Code)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := newTestServer(t, Config{Auth: AuthConfig{Keys: []APIKey{
+		{Name: "limited", Key: "limited-key", RateLimit: 2},
+		{Name: "other", Key: "other-key", RateLimit: 2},
+	}}})
+	body := `{"text": "Great support!", "processor": "sentiment"}`
+	limited := http.Header{"X-Api-Key": {"limited-key"}}
+
+	for i := 0; i < 2; i++ {
+		if w := do(s, http.MethodPost, "/api/process", body, limited); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200 within the limit, got %d", i+1, w.Code)
+		}
+	}
+
+	w := do(s, http.MethodPost, "/api/process", body, limited)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 over the limit, got %d: %s", w.Code, w.Body.String())
+	}
+	if code := errorCode(t, w); code != CodeRateLimited {
+		t.Errorf("expected code %s, got %s", CodeRateLimited, code)
+	}
+	// One token comes back every 30s at 2 requests per minute
+	if retry := w.Header().Get("Retry-After"); retry != "30" {
+		t.Errorf("expected Retry-After 30, got %q", retry)
+	}
+
+	// Each key has its own limit
+	if w := do(s, http.MethodPost, "/api/process", body, http.Header{"X-Api-Key": {"other-key"}}); w.Code != http.StatusOK {
+		t.Errorf("expected another key to be unaffected, got status %d", w.Code)
+	}
+}
+
+func TestModelAllowList(t *testing.T) {
+	// Each model answers with a different sentiment, so responses show which one ran
+	model := func(sentiment string) pipeline.ProviderConfig {
+		return pipeline.ProviderConfig{Type: "mock", Options: map[string]interface{}{
+			"response": `{"sentiment": "` + sentiment + `", "score": 0, "confidence": 0.9, "keywords": []}`,
+		}}
+	}
+	config := Config{
+		Models: map[string]pipeline.ProviderConfig{"small": model("neutral"), "large": model("negative")},
+		Auth: AuthConfig{Keys: []APIKey{
+			{Name: "any", Key: "any-key"},
+			{Name: "small-only", Key: "small-key", Models: []string{"small"}},
+			{Name: "large-default", Key: "large-key", Model: "large"},
+		}},
+	}
+
+	tests := []struct {
+		name          string
+		key           string
+		model         string
+		wantStatus    int
+		wantCode      string
+		wantSentiment string
+	}{
+		{name: "server default", key: "any-key", wantStatus: http.StatusOK, wantSentiment: "positive"},
+		{name: "selected model", key: "any-key", model: "large", wantStatus: http.StatusOK, wantSentiment: "negative"},
+		{name: "allowed model", key: "small-key", model: "small", wantStatus: http.StatusOK, wantSentiment: "neutral"},
+		{name: "disallowed model", key: "small-key", model: "large", wantStatus: http.StatusForbidden, wantCode: CodeForbidden},
+		{name: "unknown model", key: "any-key", model: "huge", wantStatus: http.StatusNotFound, wantCode: CodeUnknownModel},
+		{name: "key default model", key: "large-key", wantStatus: http.StatusOK, wantSentiment: "negative"},
+		{name: "key default overridden", key: "large-key", model: "small", wantStatus: http.StatusOK, wantSentiment: "neutral"},
+	}
+
+	s := newTestServer(t, config)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"text": "Great support!", "processor": "sentiment", "model": "` + tt.model + `"}`
+			w := do(s, http.MethodPost, "/api/process", body, http.Header{"X-Api-Key": {tt.key}})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, w); code != tt.wantCode {
+					t.Errorf("expected code %s, got %s", tt.wantCode, code)
+				}
+				return
+			}
+
+			var response struct {
+				Result map[string]interface{} `json:"result"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Result["sentiment"] != tt.wantSentiment {
+				t.Errorf("expected sentiment %s, got %v", tt.wantSentiment, response.Result)
+			}
+		})
+	}
+
+	// Keys can't allow or default to models the server doesn't have
+	for _, key := range []APIKey{{Name: "bad", Key: "bad-key", Models: []string{"huge"}}, {Name: "bad", Key: "bad-key", Model: "huge"}} {
+		config := Config{Models: config.Models, Auth: AuthConfig{Keys: []APIKey{key}}}
+		if _, err := NewWithProvider(config, llm.NewMockProviderWithResponse(sentimentResponse)); err == nil {
+			t.Errorf("expected key %+v to be rejected", key)
+		}
+	}
+}
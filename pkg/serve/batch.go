@@ -0,0 +1,194 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// jsonlContentTypes are the request content types read as JSON Lines uploads
+var jsonlContentTypes = map[string]bool{
+	"application/jsonl":       true,
+	"application/x-ndjson":    true,
+	"application/x-jsonlines": true,
+}
+
+// BatchRequest is the body of a JSON batch request
+type BatchRequest struct {
+	// Texts are the texts to process
+	Texts []string `json:"texts"`
+	// Processor is the registered processor type to run
+	Processor string `json:"processor"`
+	// Model is the name of a model from the server config to use instead of the default
+	Model string `json:"model,omitempty"`
+	// Concurrency is the number of texts processed at once (defaults to 4, capped by the
+	// server's MaxConcurrency)
+	Concurrency int `json:"concurrency,omitempty"`
+	// Options are LLM options for this request, added to the server's default options; only
+	// the options listed in the config's RequestOptions may be set
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// BatchResponse is the body of a batch response. Items that failed have an error
+// instead of a result; the request still succeeds.
+type BatchResponse struct {
+	Results   []BatchResult `json:"results"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Success   bool          `json:"success"`
+}
+
+// BatchResult is the outcome of one item of a batch, in request order
+type BatchResult struct {
+	// Index is the item's position in the request
+	Index int `json:"index"`
+	// ID is the item's ID: "item-N" for texts, or the id field of a JSON Lines upload
+	ID string `json:"id"`
+	// Result is the processor's result, if the item succeeded
+	Result interface{} `json:"result,omitempty"`
+	// Error describes why the item failed
+	Error *ErrorDetail `json:"error,omitempty"`
+}
+
+// handleBatch runs a processor over a batch of texts, sent either as a BatchRequest or as
+// a JSON Lines upload with the processor, model and concurrency in query parameters
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) error {
+	var (
+		req    BatchRequest
+		source data.ProcessItemSource
+	)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if jsonlContentTypes[mediaType] {
+		query := r.URL.Query()
+		req.Processor = query.Get("processor")
+		req.Model = query.Get("model")
+		if concurrency := query.Get("concurrency"); concurrency != "" {
+			n, err := strconv.Atoi(concurrency)
+			if err != nil {
+				return newError(http.StatusBadRequest, CodeInvalidRequest, "concurrency must be an integer")
+			}
+			req.Concurrency = n
+		}
+
+		// Lines hold an object with the text in text_field (default "text")
+		textField := query.Get("text_field")
+		if textField == "" {
+			textField = "text"
+		}
+		body := http.MaxBytesReader(w, r.Body, s.settings.maxBodyBytes)
+		source = s.limitItems(data.NewJSONLReaderSource(body, data.JSONLSourceConfig{ContentField: textField}))
+	} else {
+		if err := s.decode(w, r, &req); err != nil {
+			return err
+		}
+		if len(req.Texts) == 0 {
+			return newError(http.StatusBadRequest, CodeInvalidRequest, "texts is required")
+		}
+		if len(req.Texts) > s.settings.maxBatchItems {
+			return newError(http.StatusRequestEntityTooLarge, CodeRequestTooLarge,
+				fmt.Sprintf("batch exceeds %d texts", s.settings.maxBatchItems))
+		}
+
+		items := make([]*data.ProcessItem, len(req.Texts))
+		for i, text := range req.Texts {
+			items[i] = tenantItem(r.Context(), fmt.Sprintf("item-%d", i), text)
+		}
+		source = data.NewProcessItemSliceSource(items)
+	}
+	defer source.Close()
+	source = auditInput(r.Context(), source)
+
+	proc, err := s.newProcessor(r.Context(), req.Processor, req.Model, req.Options)
+	if err != nil {
+		return err
+	}
+
+	results, err := s.processBatch(r.Context(), proc, source, s.concurrency(req.Concurrency))
+	if err != nil {
+		return err
+	}
+
+	response := BatchResponse{Results: results}
+	for _, result := range results {
+		if result.Error != nil {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+	response.Success = response.Failed == 0
+	writeJSON(w, http.StatusOK, response)
+	return nil
+}
+
+// processBatch processes every item of a source with up to concurrency workers, recording
+// item failures in the results instead of stopping. The returned error is a failure to
+// read the source.
+func (s *Server) processBatch(ctx context.Context, proc processor.Processor, source data.ProcessItemSource, concurrency int) ([]BatchResult, error) {
+	var (
+		mu     sync.Mutex
+		failed = make(map[*data.ProcessItem]error)
+	)
+
+	parallel := data.NewProcessItemParallelProcessor(source, concurrency, concurrency)
+	defer parallel.Close()
+
+	// Failed items are passed through unchanged so one failure doesn't stop the batch
+	processed, err := parallel.ProcessAll(ctx, func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		result, err := proc.Process(ctx, item)
+		if err != nil {
+			mu.Lock()
+			failed[item] = err
+			mu.Unlock()
+			return item, nil
+		}
+		return result, nil
+	})
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return nil, apiErr
+		}
+		return nil, bodyError(err)
+	}
+
+	results := make([]BatchResult, len(processed))
+	for i, item := range processed {
+		results[i] = BatchResult{Index: i, ID: item.ID}
+		if err, ok := failed[item]; ok {
+			results[i].Error = &ErrorDetail{Code: CodeProcessingFailed, Message: err.Error()}
+			continue
+		}
+		results[i].Result = item.ProcessingInfo[proc.GetName()]
+	}
+	return results, nil
+}
+
+// concurrency returns the concurrency for a request, applying the default and the cap
+func (s *Server) concurrency(requested int) int {
+	if requested <= 0 {
+		requested = 4
+	}
+	return min(requested, s.settings.maxConcurrency)
+}
+
+// limitItems fails a source once it yields more than the maximum batch size
+func (s *Server) limitItems(source data.ProcessItemSource) data.ProcessItemSource {
+	count := 0
+	return data.Map(source, func(item *data.ProcessItem) (*data.ProcessItem, error) {
+		count++
+		if count > s.settings.maxBatchItems {
+			return nil, newError(http.StatusRequestEntityTooLarge, CodeRequestTooLarge,
+				fmt.Sprintf("batch exceeds %d items", s.settings.maxBatchItems))
+		}
+		return item, nil
+	})
+}
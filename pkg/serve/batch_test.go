@@ -0,0 +1,133 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// failingProvider answers like its mock provider but fails prompts containing "Broken"
+type failingProvider struct {
+	*llm.MockProvider
+}
+
+func (p failingProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	if strings.Contains(prompt, "Broken") {
+		return "", errors.New("provider unavailable")
+	}
+	return p.MockProvider.Generate(ctx, prompt)
+}
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentType   string
+		path          string
+		body          string
+		wantStatus    int
+		wantCode      string
+		wantSucceeded int
+		wantFailed    []string
+	}{
+		{
+			name:          "all succeed",
+			body:          `{"texts": ["Great", "Fine"], "processor": "sentiment"}`,
+			wantStatus:    http.StatusOK,
+			wantSucceeded: 2,
+		},
+		{
+			name:          "partial failure",
+			body:          `{"texts": ["Great", "Broken", "Fine"], "processor": "sentiment"}`,
+			wantStatus:    http.StatusOK,
+			wantSucceeded: 2,
+			wantFailed:    []string{"item-1"},
+		},
+		{
+			name:          "JSON Lines partial failure",
+			contentType:   "application/x-ndjson",
+			path:          "?processor=sentiment",
+			body:          "{\"id\": \"a\", \"text\": \"Broken\"}\n{\"id\": \"b\", \"text\": \"Great\"}\n",
+			wantStatus:    http.StatusOK,
+			wantSucceeded: 1,
+			wantFailed:    []string{"a"},
+		},
+		{
+			name:       "too many texts",
+			body:       `{"texts": ["1", "2", "3", "4"], "processor": "sentiment"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   CodeRequestTooLarge,
+		},
+		{
+			name:        "too many JSON Lines items",
+			contentType: "application/jsonl",
+			path:        "?processor=sentiment",
+			body:        strings.Repeat("{\"text\": \"Great\"}\n", 4),
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantCode:    CodeRequestTooLarge,
+		},
+		{
+			name:       "no texts",
+			body:       `{"texts": [], "processor": "sentiment"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeInvalidRequest,
+		},
+	}
+
+	s := newTestServerWithProvider(t, Config{MaxBatchItems: 3},
+		failingProvider{llm.NewMockProviderWithResponse(sentimentResponse)})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header http.Header
+			if tt.contentType != "" {
+				header = http.Header{"Content-Type": {tt.contentType}}
+			}
+			w := do(s, http.MethodPost, "/api/process/batch"+tt.path, tt.body, header)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, w); code != tt.wantCode {
+					t.Errorf("expected code %s, got %s", tt.wantCode, code)
+				}
+				return
+			}
+
+			var response BatchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Succeeded != tt.wantSucceeded || response.Failed != len(tt.wantFailed) {
+				t.Errorf("expected %d succeeded and %d failed, got %d and %d",
+					tt.wantSucceeded, len(tt.wantFailed), response.Succeeded, response.Failed)
+			}
+			if response.Success != (len(tt.wantFailed) == 0) {
+				t.Errorf("expected success %v, got %v", len(tt.wantFailed) == 0, response.Success)
+			}
+
+			var failed []string
+			for i, result := range response.Results {
+				if result.Index != i {
+					t.Errorf("result %d: expected results in request order, got index %d", i, result.Index)
+				}
+				if result.Error == nil {
+					if result.Result == nil {
+						t.Errorf("result %d: expected a result", i)
+					}
+					continue
+				}
+				if result.Error.Code != CodeProcessingFailed || result.Result != nil {
+					t.Errorf("result %d: expected a %s error only, got %+v", i, CodeProcessingFailed, result)
+				}
+				failed = append(failed, result.ID)
+			}
+			if strings.Join(failed, ",") != strings.Join(tt.wantFailed, ",") {
+				t.Errorf("expected failed items %v, got %v", tt.wantFailed, failed)
+			}
+		})
+	}
+}
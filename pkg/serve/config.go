@@ -0,0 +1,287 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+)
+
+// Config configures a Server. Durations use Go syntax such as "30s".
+type Config struct {
+	// Addr is the address to listen on (defaults to ":8080")
+	Addr string `json:"addr,omitempty" yaml:"addr,omitempty"`
+	// Routes are the paths the endpoints are served on
+	Routes Routes `json:"routes,omitempty" yaml:"routes,omitempty"`
+	// MaxBodyBytes limits the size of request bodies (defaults to 1 MiB)
+	MaxBodyBytes int64 `json:"max_body_bytes,omitempty" yaml:"max_body_bytes,omitempty"`
+	// MaxBatchItems limits the number of texts in a batch request (defaults to 1000)
+	MaxBatchItems int `json:"max_batch_items,omitempty" yaml:"max_batch_items,omitempty"`
+	// MaxConcurrency caps the concurrency a batch request may ask for (defaults to 8)
+	MaxConcurrency int `json:"max_concurrency,omitempty" yaml:"max_concurrency,omitempty"`
+	// ReadTimeout limits the time to read a request, including its body (defaults to "30s")
+	ReadTimeout string `json:"read_timeout,omitempty" yaml:"read_timeout,omitempty"`
+	// ShutdownTimeout is how long in-flight requests may take to finish once the
+	// server is shutting down (defaults to "30s")
+	ShutdownTimeout string `json:"shutdown_timeout,omitempty" yaml:"shutdown_timeout,omitempty"`
+	// JobWorkers is the number of asynchronous jobs run at once (defaults to 2)
+	JobWorkers int `json:"job_workers,omitempty" yaml:"job_workers,omitempty"`
+	// JobQueueSize is the number of jobs that may wait for a worker (defaults to 100)
+	JobQueueSize int `json:"job_queue_size,omitempty" yaml:"job_queue_size,omitempty"`
+	// JobRetention is how long the default job store keeps a job after its last update
+	// (defaults to "24h")
+	JobRetention string `json:"job_retention,omitempty" yaml:"job_retention,omitempty"`
+	// MaxStoredJobs is the number of jobs the default job store keeps, evicting the oldest
+	// (defaults to 10000)
+	MaxStoredJobs int `json:"max_stored_jobs,omitempty" yaml:"max_stored_jobs,omitempty"`
+	// JobStore persists jobs (defaults to a MemoryJobStore using JobRetention and
+	// MaxStoredJobs)
+	JobStore JobStore `json:"-" yaml:"-"`
+	// Pipelines maps pipeline names that jobs can run to pipeline config files
+	Pipelines map[string]string `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+	// HealthCheckInterval is how long the health endpoint reuses a provider check
+	// (defaults to "30s")
+	HealthCheckInterval string `json:"health_check_interval,omitempty" yaml:"health_check_interval,omitempty"`
+	// SkipModelCheck skips checking that the provider is reachable and its model exists
+	// when serving starts
+	SkipModelCheck bool `json:"skip_model_check,omitempty" yaml:"skip_model_check,omitempty"`
+	// Auth restricts the API to known keys (the API is open if no keys are configured)
+	Auth AuthConfig `json:"auth,omitempty" yaml:"auth,omitempty"`
+	// Audit logs every API request and finished job (disabled unless a path or sink is set)
+	Audit AuditConfig `json:"audit,omitempty" yaml:"audit,omitempty"`
+	// AuditSink receives audit records in addition to the audit log file
+	AuditSink AuditSink `json:"-" yaml:"-"`
+	// Provider declares the LLM provider used by New
+	Provider pipeline.ProviderConfig `json:"provider" yaml:"provider"`
+	// Models are the models requests may select by name, in addition to the default Provider
+	Models map[string]pipeline.ProviderConfig `json:"models,omitempty" yaml:"models,omitempty"`
+	// Tenants are the providers, with their API keys and models, and budgets of the tenants
+	// the server serves. If set, every request is made for a tenant and uses its provider
+	// instead of Provider and Models.
+	Tenants map[string]pipeline.TenantConfig `json:"tenants,omitempty" yaml:"tenants,omitempty"`
+	// Options are the default LLM options for every processor; requests can add to them
+	Options map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+	// RequestOptions are the LLM options requests may set (defaults to
+	// DefaultRequestOptions). Other options are rejected, so clients can't override the
+	// server's settings or turn on debug output, which returns prompts and raw responses.
+	RequestOptions []string `json:"request_options,omitempty" yaml:"request_options,omitempty"`
+}
+
+// DefaultRequestOptions are the LLM options requests may set unless the config lists others
+var DefaultRequestOptions = []string{"json_output"}
+
+// Routes are the paths of the server's endpoints. Each path is relative to Prefix;
+// an endpoint whose path is "-" is not served.
+type Routes struct {
+	// Prefix is prepended to every path (defaults to "/api")
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// Process is the single text processing endpoint (defaults to "/process")
+	Process string `json:"process,omitempty" yaml:"process,omitempty"`
+	// Processors lists the available processors (defaults to "/processors")
+	Processors string `json:"processors,omitempty" yaml:"processors,omitempty"`
+	// Batch is the batch processing endpoint (defaults to "/process/batch")
+	Batch string `json:"batch,omitempty" yaml:"batch,omitempty"`
+	// Stream is the server-sent events endpoint (defaults to "/process/stream")
+	Stream string `json:"stream,omitempty" yaml:"stream,omitempty"`
+	// Jobs submits asynchronous jobs; a job's status is served under it (defaults to "/jobs")
+	Jobs string `json:"jobs,omitempty" yaml:"jobs,omitempty"`
+	// OpenAPI serves the OpenAPI document, without authentication (defaults to "/openapi.json")
+	OpenAPI string `json:"openapi,omitempty" yaml:"openapi,omitempty"`
+	// Docs serves a Swagger UI page for the OpenAPI document, without authentication
+	// (defaults to "/docs")
+	Docs string `json:"docs,omitempty" yaml:"docs,omitempty"`
+	// Health checks the provider, for liveness probes (defaults to "/healthz"). Health,
+	// Ready and Metrics are served without authentication and without Prefix, where
+	// probes and scrapers expect them.
+	Health string `json:"health,omitempty" yaml:"health,omitempty"`
+	// Ready reports whether the server accepts requests, for readiness probes
+	// (defaults to "/readyz")
+	Ready string `json:"ready,omitempty" yaml:"ready,omitempty"`
+	// Metrics serves Prometheus metrics (defaults to "/metrics")
+	Metrics string `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+}
+
+// DefaultConfig returns the default configuration, using a Google provider
+func DefaultConfig() Config {
+	return Config{
+		Provider: pipeline.ProviderConfig{
+			Type:        "google",
+			Model:       "gemini-2.0-flash",
+			MaxTokens:   1024,
+			Temperature: 0.2,
+		},
+	}
+}
+
+// LoadConfig reads a server config file in YAML (.yaml, .yml) or JSON (.json)
+func LoadConfig(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server config: %w", err)
+	}
+
+	config := DefaultConfig()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(raw, &config)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &config)
+	default:
+		return nil, fmt.Errorf("unsupported server config format: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server config %s: %w", path, err)
+	}
+
+	return &config, nil
+}
+
+// settings are the parsed values of a Config, with defaults applied
+type settings struct {
+	addr                string
+	routes              Routes
+	maxBodyBytes        int64
+	maxBatchItems       int
+	maxConcurrency      int
+	jobWorkers          int
+	jobQueueSize        int
+	jobRetention        time.Duration
+	maxStoredJobs       int
+	readTimeout         time.Duration
+	shutdownTimeout     time.Duration
+	healthCheckInterval time.Duration
+	clients             map[[32]byte]*client
+	requestOptions      map[string]bool
+}
+
+// settings applies the defaults and parses the durations
+func (c Config) settings() (settings, error) {
+	s := settings{
+		addr:           c.Addr,
+		routes:         c.Routes,
+		maxBodyBytes:   c.MaxBodyBytes,
+		maxBatchItems:  c.MaxBatchItems,
+		maxConcurrency: c.MaxConcurrency,
+		jobWorkers:     c.JobWorkers,
+		jobQueueSize:   c.JobQueueSize,
+		maxStoredJobs:  c.MaxStoredJobs,
+	}
+	if s.addr == "" {
+		s.addr = ":8080"
+	}
+	if s.maxBodyBytes <= 0 {
+		s.maxBodyBytes = 1 << 20
+	}
+	if s.maxBatchItems <= 0 {
+		s.maxBatchItems = 1000
+	}
+	if s.maxConcurrency <= 0 {
+		s.maxConcurrency = 8
+	}
+	if s.jobWorkers <= 0 {
+		s.jobWorkers = 2
+	}
+	if s.jobQueueSize <= 0 {
+		s.jobQueueSize = 100
+	}
+	if s.maxStoredJobs <= 0 {
+		s.maxStoredJobs = 10000
+	}
+
+	requestOptions := c.RequestOptions
+	if requestOptions == nil {
+		requestOptions = DefaultRequestOptions
+	}
+	s.requestOptions = make(map[string]bool, len(requestOptions))
+	for _, option := range requestOptions {
+		s.requestOptions[option] = true
+	}
+
+	if s.routes.Prefix == "" {
+		s.routes.Prefix = "/api"
+	}
+	s.routes.Prefix = "/" + strings.Trim(s.routes.Prefix, "/")
+	if s.routes.Process == "" {
+		s.routes.Process = "/process"
+	}
+	if s.routes.Processors == "" {
+		s.routes.Processors = "/processors"
+	}
+	if s.routes.Batch == "" {
+		s.routes.Batch = "/process/batch"
+	}
+	if s.routes.Stream == "" {
+		s.routes.Stream = "/process/stream"
+	}
+	if s.routes.Jobs == "" {
+		s.routes.Jobs = "/jobs"
+	}
+	if s.routes.OpenAPI == "" {
+		s.routes.OpenAPI = "/openapi.json"
+	}
+	if s.routes.Docs == "" {
+		s.routes.Docs = "/docs"
+	}
+	if s.routes.Health == "" {
+		s.routes.Health = "/healthz"
+	}
+	if s.routes.Ready == "" {
+		s.routes.Ready = "/readyz"
+	}
+	if s.routes.Metrics == "" {
+		s.routes.Metrics = "/metrics"
+	}
+
+	var err error
+	if s.readTimeout, err = parseDuration(c.ReadTimeout, 30*time.Second); err != nil {
+		return settings{}, fmt.Errorf("read_timeout: %w", err)
+	}
+	if s.shutdownTimeout, err = parseDuration(c.ShutdownTimeout, 30*time.Second); err != nil {
+		return settings{}, fmt.Errorf("shutdown_timeout: %w", err)
+	}
+	if s.healthCheckInterval, err = parseDuration(c.HealthCheckInterval, 30*time.Second); err != nil {
+		return settings{}, fmt.Errorf("health_check_interval: %w", err)
+	}
+	if s.jobRetention, err = parseDuration(c.JobRetention, 24*time.Hour); err != nil {
+		return settings{}, fmt.Errorf("job_retention: %w", err)
+	}
+	if s.clients, err = c.Auth.clients(); err != nil {
+		return settings{}, fmt.Errorf("auth: %w", err)
+	}
+	return s, nil
+}
+
+// path returns the full path of a route, or "" if the route is disabled
+func (r Routes) path(route string) string {
+	if route == "-" {
+		return ""
+	}
+	return strings.TrimSuffix(r.Prefix, "/") + "/" + strings.TrimPrefix(route, "/")
+}
+
+// rootPath returns the full path of a route served outside the prefix, or "" if the
+// route is disabled
+func (r Routes) rootPath(route string) string {
+	if route == "-" {
+		return ""
+	}
+	return "/" + strings.TrimPrefix(route, "/")
+}
+
+// parseDuration parses a duration, returning fallback for an empty string
+func parseDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+	}
+	return d, nil
+}
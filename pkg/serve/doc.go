@@ -0,0 +1,72 @@
+/*
+Package serve exposes the registered processors as a JSON HTTP API.
+
+The serve package turns the processor registry into a production-ready HTTP service that can
+be run directly or embedded in an existing server through its http.Handler.
+
+Core components:
+
+1. Server (server.go):
+  - Server: HTTP server for the registered processors
+  - New / NewWithProvider: Create a server from a config, with or without an existing provider
+  - Handler: The server's http.Handler, for embedding
+  - ListenAndServe / Serve: Check the provider, then serve requests until the context is done
+    and shut down gracefully
+  - Close: Stop the job workers, when only the Handler is used
+
+2. Configuration (config.go):
+  - Config: Address, routes, limits, timeouts, provider and default LLM options
+  - Routes: Configurable endpoint paths under a shared prefix
+  - LoadConfig: Read a config from a YAML or JSON file
+  - Models: Allow-list of models requests may select by name (models.go)
+  - Tenants: Per-tenant providers and budgets, selected by API key or X-Tenant-ID header (tenants.go)
+
+3. Authentication (auth.go):
+  - AuthConfig / APIKey: API keys with per-key rate limits, allowed processors, pipelines and
+    models, and a default model
+  - Keys are accepted in an X-API-Key header or as an Authorization bearer token
+
+4. Batches (batch.go):
+  - BatchRequest / BatchResponse: Batch of texts and their per-item results or errors
+  - JSON Lines uploads read with data.NewJSONLReaderSource
+
+5. Streaming (stream.go):
+  - Server-sent token events with the raw model output, then a result or error event
+
+6. Jobs (jobs.go):
+  - Job / JobRequest: Asynchronous batch or pipeline run with its status, progress and partial results
+  - JobStore: Pluggable job persistence, with MemoryJobStore and RedisJobStore
+  - Fixed pool of job workers fed by a bounded queue
+
+7. API documentation (openapi.go):
+  - OpenAPI: OpenAPI 3 document of the enabled endpoints and processor result schemas
+  - Swagger UI page for the document
+
+8. Operations (health.go, metrics.go):
+  - Provider health check, cached for the health check interval
+  - Readiness that fails once the server starts shutting down
+  - Prometheus metrics for requests, jobs, provider health and pipeline steps
+
+9. Auditing (audit.go):
+  - AuditConfig / AuditRecord: Per-request and per-job records of caller, processor, model,
+    estimated tokens and cost, latency, and redacted, truncated input
+  - AuditSink: Pluggable record destination, with JSONLAuditSink and SlogAuditSink
+  - Input redaction with processor.Redactor: emails, phone and account numbers, and custom
+    patterns
+
+10. Errors (errors.go):
+  - ErrorResponse: Structured error body with a stable code
+  - APIError: Error carrying the HTTP status and code to report
+
+Endpoints:
+  - POST {prefix}/process: Run a processor over a text
+  - GET {prefix}/processors: List the registered processors
+  - POST {prefix}/process/stream: Run a processor, streaming the model output as server-sent events
+  - POST {prefix}/process/batch: Run a processor over a batch of texts or a JSON Lines upload
+  - POST {prefix}/jobs: Queue a batch or pipeline job
+  - GET {prefix}/jobs/{id}: Get a job's status, progress and results so far
+  - GET {prefix}/openapi.json: The OpenAPI document
+  - GET {prefix}/docs: Swagger UI for the OpenAPI document
+  - GET /healthz, /readyz, /metrics: Health, readiness and Prometheus metrics
+*/
+package serve
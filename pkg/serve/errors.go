@@ -0,0 +1,99 @@
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// Error codes returned in ErrorResponse
+const (
+	// CodeMethodNotAllowed means the endpoint doesn't accept the request method
+	CodeMethodNotAllowed = "method_not_allowed"
+	// CodeUnauthorized means the request has no API key or an unknown one
+	CodeUnauthorized = "unauthorized"
+	// CodeForbidden means the API key may not run the requested processor or pipeline
+	CodeForbidden = "forbidden"
+	// CodeRateLimited means the API key has exceeded its rate limit
+	CodeRateLimited = "rate_limited"
+	// CodeInvalidRequest means the request body is malformed or missing fields
+	CodeInvalidRequest = "invalid_request"
+	// CodeRequestTooLarge means the request body exceeds the configured limit
+	CodeRequestTooLarge = "request_too_large"
+	// CodeUnknownProcessor means the requested processor isn't registered
+	CodeUnknownProcessor = "unknown_processor"
+	// CodeUnknownModel means the requested model isn't configured
+	CodeUnknownModel = "unknown_model"
+	// CodeUnknownTenant means the request's tenant isn't configured
+	CodeUnknownTenant = "unknown_tenant"
+	// CodeBudgetExceeded means the request's tenant has used up its budget
+	CodeBudgetExceeded = "budget_exceeded"
+	// CodeUnknownPipeline means the requested pipeline isn't configured or registered
+	CodeUnknownPipeline = "unknown_pipeline"
+	// CodeJobNotFound means no job has the requested ID
+	CodeJobNotFound = "job_not_found"
+	// CodeQueueFull means the job queue is full and the job should be submitted later
+	CodeQueueFull = "queue_full"
+	// CodeProcessingFailed means the processor returned an error
+	CodeProcessingFailed = "processing_failed"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Success bool        `json:"success"`
+	Error   ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes an error with a stable code clients can match on
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIError is an error with the HTTP status and code to report it with
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// newError creates an APIError
+func newError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// asAPIError returns the APIError in err's chain. Other errors are reported as processing
+// failures.
+func asAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.Is(err, llm.ErrBudgetExceeded) && !errors.As(err, &apiErr) {
+		return newError(http.StatusTooManyRequests, CodeBudgetExceeded, err.Error())
+	}
+	if !errors.As(err, &apiErr) {
+		apiErr = newError(http.StatusInternalServerError, CodeProcessingFailed, err.Error())
+	}
+	return apiErr
+}
+
+// writeError sends err as an ErrorResponse
+func writeError(w http.ResponseWriter, err error) {
+	apiErr := asAPIError(err)
+	writeJSON(w, apiErr.Status, ErrorResponse{
+		Success: false,
+		Error:   ErrorDetail{Code: apiErr.Code, Message: apiErr.Message},
+	})
+}
+
+// writeJSON sends value as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,83 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// HealthResponse is the body of the health and readiness endpoints
+type HealthResponse struct {
+	// Status is "ok" or "unavailable"
+	Status string `json:"status"`
+	// Error describes why the server is unavailable
+	Error string `json:"error,omitempty"`
+}
+
+// providerHealth caches the result of checking the provider, so frequent probes don't
+// call the provider's API each time
+type providerHealth struct {
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// checkProvider checks the provider, reusing the last result within the health check interval
+func (s *Server) checkProvider(ctx context.Context) error {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	if !s.health.checked.IsZero() && time.Since(s.health.checked) < s.settings.healthCheckInterval {
+		return s.health.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	s.health.err = llm.Check(ctx, s.provider)
+	s.health.checked = time.Now()
+	return s.health.err
+}
+
+// checkModels checks the provider and every configured model
+func (s *Server) checkModels(ctx context.Context) error {
+	if err := s.checkProvider(ctx); err != nil {
+		return fmt.Errorf("provider check failed: %w", err)
+	}
+	for _, name := range s.modelNames() {
+		if err := llm.Check(ctx, s.models[name]); err != nil {
+			return fmt.Errorf("model %s check failed: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// providerUp reports the result of the last provider check, with false if there hasn't been one
+func (s *Server) providerUp() (up, checked bool) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	return s.health.err == nil, !s.health.checked.IsZero()
+}
+
+// handleHealth reports whether the provider is reachable and its model exists
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) error {
+	if err := s.checkProvider(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: "unavailable", Error: err.Error()})
+		return nil
+	}
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+	return nil
+}
+
+// handleReady reports whether the server accepts requests, which it stops doing once it
+// starts shutting down
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) error {
+	if s.stopping.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: "unavailable", Error: "server is shutting down"})
+		return nil
+	}
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+	return nil
+}
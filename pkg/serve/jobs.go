@@ -0,0 +1,514 @@
+package serve
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+)
+
+// JobStatus is the state of an asynchronous job
+type JobStatus string
+
+const (
+	// JobQueued means the job is waiting for a worker
+	JobQueued JobStatus = "queued"
+	// JobRunning means the job's items are being processed
+	JobRunning JobStatus = "running"
+	// JobSucceeded means every item was processed; individual items may still have failed
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed means the job as a whole failed, e.g. because the server shut down
+	JobFailed JobStatus = "failed"
+)
+
+// ErrJobNotFound is returned by a JobStore for unknown job IDs
+var ErrJobNotFound = errors.New("job not found")
+
+// Job is an asynchronous batch or pipeline run and its progress
+type Job struct {
+	ID     string    `json:"id"`
+	Status JobStatus `json:"status"`
+	// Processor or Pipeline is what the job runs
+	Processor string `json:"processor,omitempty"`
+	Pipeline  string `json:"pipeline,omitempty"`
+	// Model is the selected model, if any
+	Model string `json:"model,omitempty"`
+	// Owner is the name of the API key that submitted the job, and Tenant the tenant it was
+	// submitted for. Only requests with the same key and tenant can poll the job.
+	Owner  string `json:"owner,omitempty"`
+	Tenant string `json:"tenant,omitempty"`
+	// Total is the number of texts; Completed counts those done, including Failed ones
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	// Results holds the results so far, in request order once the job has finished
+	Results []BatchResult `json:"results"`
+	// Error describes why the job failed
+	Error      *ErrorDetail `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
+
+// JobStore persists jobs so their status can be polled, possibly from another server instance
+type JobStore interface {
+	// Save stores the current state of a job
+	Save(ctx context.Context, job *Job) error
+	// Get returns a job, or ErrJobNotFound
+	Get(ctx context.Context, id string) (*Job, error)
+}
+
+// JobRequest is the body of a job submission. Exactly one of Processor or Pipeline is set.
+type JobRequest struct {
+	// Texts are the texts to process
+	Texts []string `json:"texts"`
+	// Processor is the registered processor type to run
+	Processor string `json:"processor,omitempty"`
+	// Pipeline is a pipeline from the server config or one registered with
+	// pipeline.RegisterSubPipeline
+	Pipeline string `json:"pipeline,omitempty"`
+	// Model is the name of a model from the server config to use instead of the default.
+	// Pipelines from the server config use the models they declare.
+	Model string `json:"model,omitempty"`
+	// Concurrency is the number of texts processed at once (defaults to 4, capped by the
+	// server's MaxConcurrency)
+	Concurrency int `json:"concurrency,omitempty"`
+	// Options are LLM options for a processor job, added to the server's default options;
+	// only the options listed in the config's RequestOptions may be set
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// MemoryJobStore is a JobStore keeping jobs in memory. A job is evicted once it hasn't
+// been saved for the store's TTL, and the oldest jobs are evicted when the store holds
+// more than its maximum number of jobs.
+type MemoryJobStore struct {
+	mu      sync.Mutex
+	jobs    map[string]memoryJob
+	order   []string
+	ttl     time.Duration
+	maxJobs int
+	now     func() time.Time
+}
+
+// memoryJob is an encoded job and when it expires
+type memoryJob struct {
+	encoded []byte
+	expires time.Time
+}
+
+// NewMemoryJobStore creates an empty in-memory job store evicting jobs ttl after they were
+// last saved and, beyond maxJobs jobs, the oldest jobs. Zero values mean no limit.
+func NewMemoryJobStore(ttl time.Duration, maxJobs int) *MemoryJobStore {
+	return &MemoryJobStore{jobs: make(map[string]memoryJob), ttl: ttl, maxJobs: maxJobs, now: time.Now}
+}
+
+// Save implements JobStore
+func (m *MemoryJobStore) Save(_ context.Context, job *Job) error {
+	// Jobs are stored encoded so later changes by the runner aren't visible until saved
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if _, ok := m.jobs[job.ID]; !ok {
+		m.order = append(m.order, job.ID)
+	}
+	m.jobs[job.ID] = memoryJob{encoded: encoded, expires: now.Add(m.ttl)}
+	m.evict(now)
+	return nil
+}
+
+// Get implements JobStore
+func (m *MemoryJobStore) Get(_ context.Context, id string) (*Job, error) {
+	m.mu.Lock()
+	stored, ok := m.jobs[id]
+	if ok && m.expired(stored, m.now()) {
+		delete(m.jobs, id)
+		ok = false
+	}
+	m.mu.Unlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	var job Job
+	if err := json.Unmarshal(stored.encoded, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return &job, nil
+}
+
+// evict removes the oldest jobs while the store holds too many of them or they have
+// expired. Expired jobs behind a live one are removed when they are read. The lock must be
+// held.
+func (m *MemoryJobStore) evict(now time.Time) {
+	for len(m.order) > 0 {
+		id := m.order[0]
+		stored, ok := m.jobs[id]
+		if ok && !m.expired(stored, now) && (m.maxJobs <= 0 || len(m.jobs) <= m.maxJobs) {
+			return
+		}
+		delete(m.jobs, id)
+		m.order = m.order[1:]
+	}
+}
+
+// expired reports whether a job's TTL has passed
+func (m *MemoryJobStore) expired(stored memoryJob, now time.Time) bool {
+	return m.ttl > 0 && now.After(stored.expires)
+}
+
+// RedisClient is the subset of a Redis client RedisJobStore needs, so any client library
+// can be used through a small adapter
+type RedisClient interface {
+	// Get returns the value of key, with false if the key doesn't exist
+	Get(ctx context.Context, key string) (string, bool, error)
+	// Set stores value under key, expiring after ttl if it is positive
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+}
+
+// RedisJobStore is a JobStore keeping jobs in Redis, so several server instances can
+// share job status
+type RedisJobStore struct {
+	client RedisClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisJobStore creates a job store writing each job as JSON under prefix+ID, expiring
+// after ttl (jobs don't expire if ttl is zero)
+func NewRedisJobStore(client RedisClient, prefix string, ttl time.Duration) *RedisJobStore {
+	return &RedisJobStore{client: client, prefix: prefix, ttl: ttl}
+}
+
+// Save implements JobStore
+func (r *RedisJobStore) Save(ctx context.Context, job *Job) error {
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+	if err := r.client.Set(ctx, r.prefix+job.ID, string(encoded), r.ttl); err != nil {
+		return fmt.Errorf("failed to save job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// Get implements JobStore
+func (r *RedisJobStore) Get(ctx context.Context, id string) (*Job, error) {
+	encoded, ok, err := r.client.Get(ctx, r.prefix+id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job %s: %w", id, err)
+	}
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(encoded), &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return &job, nil
+}
+
+// jobTask is a queued job with everything needed to run it
+type jobTask struct {
+	job         *Job
+	items       []*data.ProcessItem
+	concurrency int
+	process     func(ctx context.Context, item *data.ProcessItem) (interface{}, error)
+	// audit collects the job's audit record, or is nil if auditing is disabled
+	audit *auditEntry
+}
+
+// jobQueue runs queued jobs on a fixed pool of workers
+type jobQueue struct {
+	store   JobStore
+	tasks   chan *jobTask
+	running atomic.Int64
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// newJobQueue starts workers running jobs from a queue of the given size
+func newJobQueue(store JobStore, workers, size int) *jobQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &jobQueue{store: store, tasks: make(chan *jobTask, size), cancel: cancel}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case task := <-q.tasks:
+					q.run(ctx, task)
+				}
+			}
+		}()
+	}
+	return q
+}
+
+// enqueue queues a job, returning false if the queue is full
+func (q *jobQueue) enqueue(task *jobTask) bool {
+	select {
+	case q.tasks <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops the workers, failing running jobs and any still queued
+func (q *jobQueue) close() {
+	q.once.Do(func() {
+		q.cancel()
+		q.wg.Wait()
+		for {
+			select {
+			case task := <-q.tasks:
+				q.finish(task, errors.New("server shut down"))
+			default:
+				return
+			}
+		}
+	})
+}
+
+// run processes a job's items, saving its progress as each item completes
+func (q *jobQueue) run(ctx context.Context, task *jobTask) {
+	job := task.job
+	var mu sync.Mutex
+	q.running.Add(1)
+	defer q.running.Add(-1)
+	ctx = context.WithValue(ctx, auditKey{}, task.audit)
+
+	started := time.Now()
+	job.Status = JobRunning
+	job.StartedAt = &started
+	q.save(job)
+
+	index := make(map[*data.ProcessItem]int, len(task.items))
+	for i, item := range task.items {
+		index[item] = i
+	}
+
+	parallel := data.NewProcessItemParallelProcessor(data.NewProcessItemSliceSource(task.items), task.concurrency, task.concurrency)
+	defer parallel.Close()
+	_, err := parallel.ProcessAll(ctx, func(ctx context.Context, item *data.ProcessItem) (*data.ProcessItem, error) {
+		result := BatchResult{Index: index[item], ID: item.ID}
+		value, err := task.process(ctx, item)
+		if err != nil {
+			result.Error = &ErrorDetail{Code: CodeProcessingFailed, Message: err.Error()}
+		} else {
+			result.Result = value
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		job.Results = append(job.Results, result)
+		job.Completed++
+		if err != nil {
+			job.Failed++
+		}
+		q.save(job)
+		return item, nil
+	})
+	// Items cut short by the shutdown fail with the context's error
+	if ctx.Err() != nil {
+		err = errors.New("server shut down")
+	}
+	q.finish(task, err)
+}
+
+// finish records the end of a job, sorting its results into request order, and audits it
+func (q *jobQueue) finish(task *jobTask, err error) {
+	job := task.job
+	finished := time.Now()
+	job.FinishedAt = &finished
+	sort.Slice(job.Results, func(i, j int) bool { return job.Results[i].Index < job.Results[j].Index })
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = &ErrorDetail{Code: CodeProcessingFailed, Message: err.Error()}
+	} else {
+		job.Status = JobSucceeded
+	}
+
+	// The job is audited before it is saved, so its record is written once pollers see it
+	// finished
+	if task.audit != nil {
+		task.audit.update(func(record *AuditRecord) {
+			record.Status = http.StatusOK
+			if job.Error != nil {
+				record.Status = http.StatusInternalServerError
+				record.Error = job.Error.Code
+			}
+		})
+		task.audit.auditor.record(context.Background(), task.audit)
+	}
+	q.save(job)
+}
+
+// save stores a job's progress. Failures only delay what pollers see, so they are ignored;
+// the final state is saved when the job finishes.
+func (q *jobQueue) save(job *Job) {
+	_ = q.store.Save(context.Background(), job)
+}
+
+// handleSubmitJob queues a batch or pipeline job and responds with its initial state
+func (s *Server) handleSubmitJob(w http.ResponseWriter, r *http.Request) error {
+	var req JobRequest
+	if err := s.decode(w, r, &req); err != nil {
+		return err
+	}
+	if len(req.Texts) == 0 {
+		return newError(http.StatusBadRequest, CodeInvalidRequest, "texts is required")
+	}
+	if len(req.Texts) > s.settings.maxBatchItems {
+		return newError(http.StatusRequestEntityTooLarge, CodeRequestTooLarge,
+			fmt.Sprintf("job exceeds %d texts", s.settings.maxBatchItems))
+	}
+	if (req.Processor == "") == (req.Pipeline == "") {
+		return newError(http.StatusBadRequest, CodeInvalidRequest, "exactly one of processor or pipeline is required")
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return err
+	}
+	task := &jobTask{
+		job: &Job{
+			ID:        id,
+			Status:    JobQueued,
+			Processor: req.Processor,
+			Pipeline:  req.Pipeline,
+			Model:     req.Model,
+			Owner:     keyName(r.Context()),
+			Tenant:    tenantFrom(r.Context()),
+			Total:     len(req.Texts),
+			Results:   []BatchResult{},
+			CreatedAt: time.Now(),
+		},
+		concurrency: s.concurrency(req.Concurrency),
+	}
+	for i, text := range req.Texts {
+		task.items = append(task.items, tenantItem(r.Context(), fmt.Sprintf("item-%d", i), text))
+	}
+
+	// Processors and pipelines are created now so unknown names fail the request
+	if req.Processor != "" {
+		proc, err := s.newProcessor(r.Context(), req.Processor, req.Model, req.Options)
+		if err != nil {
+			return err
+		}
+		task.process = func(ctx context.Context, item *data.ProcessItem) (interface{}, error) {
+			result, err := proc.Process(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			return result.ProcessingInfo[proc.GetName()], nil
+		}
+	} else {
+		p, err := s.pipeline(r.Context(), req.Pipeline, req.Model)
+		if err != nil {
+			return err
+		}
+		task.process = func(ctx context.Context, item *data.ProcessItem) (interface{}, error) {
+			result, err := p.Process(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			return result.ProcessingInfo, nil
+		}
+	}
+
+	task.audit = auditFrom(r.Context()).newJobEntry(task.job)
+	for _, text := range req.Texts {
+		auditFrom(r.Context()).addInput(text)
+		task.audit.addInput(text)
+	}
+
+	if err := s.jobs.store.Save(r.Context(), task.job); err != nil {
+		return err
+	}
+	// The job is copied before queueing since a worker may start updating it at once
+	queued := *task.job
+	if !s.jobs.enqueue(task) {
+		s.jobs.finish(task, errors.New("job queue is full"))
+		return newError(http.StatusServiceUnavailable, CodeQueueFull, "job queue is full, retry later")
+	}
+
+	w.Header().Set("Location", s.settings.routes.path(s.settings.routes.Jobs)+"/"+id)
+	writeJSON(w, http.StatusAccepted, queued)
+	return nil
+}
+
+// handleGetJob returns a job's status, progress and results so far
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) error {
+	job, err := s.jobs.store.Get(r.Context(), r.PathValue("id"))
+	if err != nil && !errors.Is(err, ErrJobNotFound) {
+		return err
+	}
+	// Other callers' jobs are reported as missing, so job IDs can't be probed
+	if err != nil || !ownsJob(r.Context(), job) {
+		return newError(http.StatusNotFound, CodeJobNotFound, fmt.Sprintf("job not found: %s", r.PathValue("id")))
+	}
+	writeJSON(w, http.StatusOK, job)
+	return nil
+}
+
+// ownsJob reports whether the request was made with the API key and for the tenant that
+// submitted a job
+func ownsJob(ctx context.Context, job *Job) bool {
+	return job.Owner == keyName(ctx) && job.Tenant == tenantFrom(ctx)
+}
+
+// pipeline returns a pipeline from the server config, or from the sub-pipeline registry
+// using the selected model, if the request's client may run it
+func (s *Server) pipeline(ctx context.Context, name, model string) (pipeline.Pipeline, error) {
+	if !allowPipeline(ctx, name) {
+		return nil, newError(http.StatusForbidden, CodeForbidden, fmt.Sprintf("API key may not run pipeline %s", name))
+	}
+	auditFrom(ctx).update(func(record *AuditRecord) { record.Pipeline = name })
+	if p, ok := s.pipelines[name]; ok {
+		if model != "" {
+			return nil, newError(http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("pipeline %s uses the models it declares; model can't be set", name))
+		}
+		return p, nil
+	}
+	if !slices.Contains(pipeline.ListSubPipelines(), name) {
+		return nil, newError(http.StatusNotFound, CodeUnknownPipeline, fmt.Sprintf("unknown pipeline: %s", name))
+	}
+	provider, err := s.modelProvider(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	p, err := pipeline.CreateSubPipeline(name, provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipeline %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,300 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+)
+
+// gatedProvider answers like its mock provider but holds prompts containing "Slow" until
+// the gate is closed or their context is done
+type gatedProvider struct {
+	*llm.MockProvider
+	gate chan struct{}
+}
+
+func newGatedProvider() *gatedProvider {
+	return &gatedProvider{MockProvider: llm.NewMockProviderWithResponse(sentimentResponse), gate: make(chan struct{})}
+}
+
+func (p *gatedProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	if strings.Contains(prompt, "Slow") {
+		select {
+		case <-p.gate:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if strings.Contains(prompt, "Broken") {
+		return "", errors.New("provider unavailable")
+	}
+	return p.MockProvider.Generate(ctx, prompt)
+}
+
+// submitJob submits a job and returns its path, failing unless it is accepted
+func submitJob(t *testing.T, s *Server, body string) string {
+	t.Helper()
+	w := do(s, http.MethodPost, "/api/jobs", body, nil)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	var job Job
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobQueued || job.ID == "" {
+		t.Fatalf("expected a queued job, got %+v", job)
+	}
+	location := w.Header().Get("Location")
+	if location != "/api/jobs/"+job.ID {
+		t.Fatalf("unexpected Location %q for job %s", location, job.ID)
+	}
+	return location
+}
+
+// waitForJob polls a job until done reports true for it
+func waitForJob(t *testing.T, s *Server, path string, done func(job *Job) bool) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w := do(s, http.MethodGet, path, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("polling %s: status %d: %s", path, w.Code, w.Body.String())
+		}
+		var job Job
+		if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+			t.Fatal(err)
+		}
+		if done(&job) {
+			return &job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for job %s, last state %+v", path, job)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// finished reports whether a job has succeeded or failed
+func finished(job *Job) bool {
+	return job.Status == JobSucceeded || job.Status == JobFailed
+}
+
+func TestJobs(t *testing.T) {
+	t.Run("results in request order", func(t *testing.T) {
+		s := newTestServerWithProvider(t, Config{}, newGatedProvider())
+		path := submitJob(t, s, `{"texts": ["Great", "Broken", "Fine"], "processor": "sentiment", "concurrency": 3}`)
+
+		job := waitForJob(t, s, path, finished)
+		if job.Status != JobSucceeded || job.Total != 3 || job.Completed != 3 || job.Failed != 1 {
+			t.Fatalf("unexpected job %+v", job)
+		}
+		for i, result := range job.Results {
+			if result.Index != i || result.ID != fmt.Sprintf("item-%d", i) {
+				t.Errorf("result %d is out of order: %+v", i, result)
+			}
+			if failed := result.Error != nil; failed != (i == 1) {
+				t.Errorf("result %d: unexpected error %v", i, result.Error)
+			}
+		}
+		if job.StartedAt == nil || job.FinishedAt == nil {
+			t.Error("expected start and finish times")
+		}
+	})
+
+	t.Run("partial results", func(t *testing.T) {
+		provider := newGatedProvider()
+		s := newTestServerWithProvider(t, Config{}, provider)
+		path := submitJob(t, s, `{"texts": ["Great", "Slow"], "processor": "sentiment", "concurrency": 1}`)
+
+		job := waitForJob(t, s, path, func(job *Job) bool { return job.Completed == 1 })
+		if job.Status != JobRunning || len(job.Results) != 1 || job.Results[0].ID != "item-0" {
+			t.Errorf("expected the finished item of a running job, got %+v", job)
+		}
+
+		close(provider.gate)
+		job = waitForJob(t, s, path, finished)
+		if job.Status != JobSucceeded || len(job.Results) != 2 || job.Results[1].ID != "item-1" {
+			t.Errorf("expected both results, got %+v", job)
+		}
+	})
+
+	t.Run("worker pool", func(t *testing.T) {
+		provider := newGatedProvider()
+		s := newTestServerWithProvider(t, Config{JobWorkers: 1, JobQueueSize: 1}, provider)
+		running := submitJob(t, s, `{"texts": ["Slow"], "processor": "sentiment"}`)
+		waitForJob(t, s, running, func(job *Job) bool { return job.Status == JobRunning })
+
+		queued := submitJob(t, s, `{"texts": ["Great"], "processor": "sentiment"}`)
+		w := do(s, http.MethodPost, "/api/jobs", `{"texts": ["Fine"], "processor": "sentiment"}`, nil)
+		if w.Code != http.StatusServiceUnavailable || errorCode(t, w) != CodeQueueFull {
+			t.Errorf("expected the queue to be full, got %d: %s", w.Code, w.Body.String())
+		}
+		if job := waitForJob(t, s, queued, func(*Job) bool { return true }); job.Status != JobQueued {
+			t.Errorf("expected the second job to wait for the worker, got %s", job.Status)
+		}
+
+		close(provider.gate)
+		for _, path := range []string{running, queued} {
+			if job := waitForJob(t, s, path, finished); job.Status != JobSucceeded {
+				t.Errorf("job %s: expected success, got %+v", path, job)
+			}
+		}
+	})
+
+	t.Run("Close fails unfinished jobs", func(t *testing.T) {
+		s := newTestServerWithProvider(t, Config{JobWorkers: 1}, newGatedProvider())
+		running := submitJob(t, s, `{"texts": ["Slow"], "processor": "sentiment"}`)
+		waitForJob(t, s, running, func(job *Job) bool { return job.Status == JobRunning })
+		queued := submitJob(t, s, `{"texts": ["Great"], "processor": "sentiment"}`)
+
+		s.Close()
+		for _, path := range []string{running, queued} {
+			job := waitForJob(t, s, path, func(*Job) bool { return true })
+			if job.Status != JobFailed || job.Error == nil || !strings.Contains(job.Error.Message, "shut down") {
+				t.Errorf("job %s: expected failure on shutdown, got %+v", path, job)
+			}
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		s := newTestServer(t, Config{MaxBatchItems: 2})
+		tests := []struct {
+			body       string
+			wantStatus int
+			wantCode   string
+		}{
+			{`{"processor": "sentiment"}`, http.StatusBadRequest, CodeInvalidRequest},
+			{`{"texts": ["a"], "processor": "sentiment", "pipeline": "support"}`, http.StatusBadRequest, CodeInvalidRequest},
+			{`{"texts": ["a", "b", "c"], "processor": "sentiment"}`, http.StatusRequestEntityTooLarge, CodeRequestTooLarge},
+			{`{"texts": ["a"], "processor": "unknown"}`, http.StatusNotFound, CodeUnknownProcessor},
+		}
+		for _, tt := range tests {
+			w := do(s, http.MethodPost, "/api/jobs", tt.body, nil)
+			if w.Code != tt.wantStatus || errorCode(t, w) != tt.wantCode {
+				t.Errorf("%s: expected %d %s, got %d: %s", tt.body, tt.wantStatus, tt.wantCode, w.Code, w.Body.String())
+			}
+		}
+
+		w := do(s, http.MethodGet, "/api/jobs/unknown", "", nil)
+		if w.Code != http.StatusNotFound || errorCode(t, w) != CodeJobNotFound {
+			t.Errorf("expected an unknown job to be not found, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestJobOwnership(t *testing.T) {
+	tenant := pipeline.TenantConfig{Provider: pipeline.ProviderConfig{Type: "mock", Model: "mock", Options: map[string]interface{}{
+		"response": sentimentResponse,
+	}}}
+	s := newTestServer(t, Config{
+		Tenants: map[string]pipeline.TenantConfig{"acme": tenant, "globex": tenant},
+		Auth: AuthConfig{Keys: []APIKey{
+			{Name: "alice", Key: "alice-key"},
+			{Name: "bob", Key: "bob-key"},
+		}},
+	})
+	// header returns the headers of a request with a key for a tenant
+	header := func(key, tenant string) http.Header {
+		h := http.Header{"X-Api-Key": {key}}
+		h.Set(TenantHeader, tenant)
+		return h
+	}
+
+	w := do(s, http.MethodPost, "/api/jobs", `{"texts": ["Great"], "processor": "sentiment"}`, header("alice-key", "acme"))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	var submitted Job
+	if err := json.Unmarshal(w.Body.Bytes(), &submitted); err != nil {
+		t.Fatal(err)
+	}
+	if submitted.Owner != "alice" || submitted.Tenant != "acme" {
+		t.Errorf("expected the job to be owned by alice for acme, got %q and %q", submitted.Owner, submitted.Tenant)
+	}
+	path := w.Header().Get("Location")
+
+	tests := []struct {
+		name       string
+		key        string
+		tenant     string
+		wantStatus int
+	}{
+		{name: "owner", key: "alice-key", tenant: "acme", wantStatus: http.StatusOK},
+		{name: "other key", key: "bob-key", tenant: "acme", wantStatus: http.StatusNotFound},
+		{name: "other tenant", key: "alice-key", tenant: "globex", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(s, http.MethodGet, path, "", header(tt.key, tt.tenant))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusNotFound && errorCode(t, w) != CodeJobNotFound {
+				t.Errorf("expected another caller's job to be reported as missing, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMemoryJobStore(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		maxJobs int
+		// elapsed is the time that passes before the last job is saved
+		elapsed  time.Duration
+		wantKept []string
+	}{
+		{name: "no limits", elapsed: 48 * time.Hour, wantKept: []string{"a", "b", "c"}},
+		{name: "max jobs", maxJobs: 2, wantKept: []string{"b", "c"}},
+		{name: "ttl", ttl: time.Hour, elapsed: 2 * time.Hour, wantKept: []string{"c"}},
+		{name: "within ttl", ttl: time.Hour, elapsed: 30 * time.Minute, wantKept: []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			store := NewMemoryJobStore(tt.ttl, tt.maxJobs)
+			store.now = func() time.Time { return now }
+
+			for _, id := range []string{"a", "b"} {
+				if err := store.Save(ctx, &Job{ID: id, Status: JobQueued}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Saving an existing job neither adds it again nor changes its age order
+			if err := store.Save(ctx, &Job{ID: "a", Status: JobSucceeded}); err != nil {
+				t.Fatal(err)
+			}
+			now = now.Add(tt.elapsed)
+			if err := store.Save(ctx, &Job{ID: "c", Status: JobQueued}); err != nil {
+				t.Fatal(err)
+			}
+
+			var kept []string
+			for _, id := range []string{"a", "b", "c"} {
+				job, err := store.Get(ctx, id)
+				if errors.Is(err, ErrJobNotFound) {
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				kept = append(kept, job.ID)
+			}
+			if fmt.Sprint(kept) != fmt.Sprint(tt.wantKept) {
+				t.Errorf("expected jobs %v to be kept, got %v", tt.wantKept, kept)
+			}
+		})
+	}
+}
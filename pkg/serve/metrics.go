@@ -0,0 +1,216 @@
+package serve
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+)
+
+// durationBuckets are the upper bounds in seconds of the request duration histogram;
+// LLM calls take seconds, so the buckets reach a minute
+var durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// requestLabels identify a request counter
+type requestLabels struct {
+	route  string
+	method string
+	code   int
+}
+
+// histogram counts observations into cumulative buckets
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// metrics records request counts and durations by route
+type metrics struct {
+	mu        sync.Mutex
+	requests  map[requestLabels]uint64
+	durations map[string]*histogram
+}
+
+// newMetrics creates empty metrics
+func newMetrics() *metrics {
+	return &metrics{
+		requests:  make(map[requestLabels]uint64),
+		durations: make(map[string]*histogram),
+	}
+}
+
+// observe records a finished request. The route is the registered path, not the request's,
+// so path parameters such as job IDs don't create new series.
+func (m *metrics) observe(route, method string, code int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestLabels{route: route, method: method, code: code}]++
+
+	h, ok := m.durations[route]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(durationBuckets))}
+		m.durations[route] = h
+	}
+	seconds := duration.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// statusRecorder captures the status code a handler writes
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush events
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// handleMetrics serves the metrics in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.writeMetrics(w)
+	return nil
+}
+
+// writeMetrics writes the metrics in the Prometheus text format, sorted so output is stable
+func (s *Server) writeMetrics(w io.Writer) {
+	s.metrics.mu.Lock()
+	labels := make([]requestLabels, 0, len(s.metrics.requests))
+	for l := range s.metrics.requests {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].route != labels[j].route {
+			return labels[i].route < labels[j].route
+		}
+		if labels[i].method != labels[j].method {
+			return labels[i].method < labels[j].method
+		}
+		return labels[i].code < labels[j].code
+	})
+
+	fmt.Fprintln(w, "# HELP agentic_text_requests_total Requests handled, by route, method and status code.")
+	fmt.Fprintln(w, "# TYPE agentic_text_requests_total counter")
+	for _, l := range labels {
+		fmt.Fprintf(w, "agentic_text_requests_total{route=%q,method=%q,code=\"%d\"} %d\n",
+			l.route, l.method, l.code, s.metrics.requests[l])
+	}
+
+	routes := make([]string, 0, len(s.metrics.durations))
+	for route := range s.metrics.durations {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	fmt.Fprintln(w, "# HELP agentic_text_request_duration_seconds Request durations, by route.")
+	fmt.Fprintln(w, "# TYPE agentic_text_request_duration_seconds histogram")
+	for _, route := range routes {
+		h := s.metrics.durations[route]
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(w, "agentic_text_request_duration_seconds_bucket{route=%q,le=%q} %d\n",
+				route, strconv.FormatFloat(bound, 'g', -1, 64), h.buckets[i])
+		}
+		fmt.Fprintf(w, "agentic_text_request_duration_seconds_bucket{route=%q,le=\"+Inf\"} %d\n", route, h.count)
+		fmt.Fprintf(w, "agentic_text_request_duration_seconds_sum{route=%q} %g\n", route, h.sum)
+		fmt.Fprintf(w, "agentic_text_request_duration_seconds_count{route=%q} %d\n", route, h.count)
+	}
+	s.metrics.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP agentic_text_jobs_queued Jobs waiting for a worker.")
+	fmt.Fprintln(w, "# TYPE agentic_text_jobs_queued gauge")
+	fmt.Fprintf(w, "agentic_text_jobs_queued %d\n", len(s.jobs.tasks))
+	fmt.Fprintln(w, "# HELP agentic_text_jobs_running Jobs being run.")
+	fmt.Fprintln(w, "# TYPE agentic_text_jobs_running gauge")
+	fmt.Fprintf(w, "agentic_text_jobs_running %d\n", s.jobs.running.Load())
+
+	s.writePipelineMetrics(w)
+
+	if up, checked := s.providerUp(); checked {
+		value := 0
+		if up {
+			value = 1
+		}
+		fmt.Fprintln(w, "# HELP agentic_text_provider_up Whether the last provider check passed.")
+		fmt.Fprintln(w, "# TYPE agentic_text_provider_up gauge")
+		fmt.Fprintf(w, "agentic_text_provider_up %d\n", value)
+	}
+}
+
+// statsPipeline is a pipeline that records per-step metrics, such as a pipeline.Chain
+type statsPipeline interface {
+	ChainStats() pipeline.ChainStats
+}
+
+// writePipelineMetrics writes the per-step metrics of the configured pipelines that record
+// them, accumulated since the server started
+func (s *Server) writePipelineMetrics(w io.Writer) {
+	names := make([]string, 0, len(s.pipelines))
+	for name, p := range s.pipelines {
+		if _, ok := p.(statsPipeline); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	stats := make([]pipeline.ChainStats, len(names))
+	for i, name := range names {
+		stats[i] = s.pipelines[name].(statsPipeline).ChainStats()
+	}
+
+	series := []struct {
+		name, help, kind string
+		value            func(pipeline.StepStats) string
+	}{
+		{"agentic_text_pipeline_step_items_total", "Items produced by each pipeline step.", "counter",
+			func(step pipeline.StepStats) string { return strconv.Itoa(step.Items) }},
+		{"agentic_text_pipeline_step_errors_total", "Failed calls and skipped items of each pipeline step.", "counter",
+			func(step pipeline.StepStats) string { return strconv.Itoa(step.Errors) }},
+		{"agentic_text_pipeline_step_duration_seconds_total", "Time spent in each pipeline step.", "counter",
+			func(step pipeline.StepStats) string { return strconv.FormatFloat(step.Duration.Seconds(), 'g', -1, 64) }},
+		{"agentic_text_pipeline_step_tokens_total", "Estimated tokens used by each pipeline step.", "counter",
+			func(step pipeline.StepStats) string { return strconv.FormatInt(step.Tokens, 10) }},
+		{"agentic_text_pipeline_step_cost_total", "Estimated cost of each pipeline step.", "counter",
+			func(step pipeline.StepStats) string { return strconv.FormatFloat(step.Cost, 'g', -1, 64) }},
+	}
+	for _, metric := range series {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", metric.name, metric.kind)
+		for i, name := range names {
+			for _, step := range stats[i].Steps {
+				fmt.Fprintf(w, "%s{pipeline=%q,step=%q} %s\n", metric.name, name, step.Name, metric.value(step))
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// newModels creates the providers of the models requests may select
+func (c Config) newModels() (map[string]llm.Provider, error) {
+	models := make(map[string]llm.Provider, len(c.Models))
+	for name, config := range c.Models {
+		provider, err := config.NewProvider()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create model %s: %w", name, err)
+		}
+		models[name] = provider
+	}
+	return models, nil
+}
+
+// modelNames returns the names of the models requests may select, sorted
+func (s *Server) modelNames() []string {
+	names := make([]string, 0, len(s.models))
+	for name := range s.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// modelProvider returns the provider of a model the request's client selected. Without a
+// selection it is the client's default model, or the server's provider. With tenants it is
+// the provider of the request's tenant.
+func (s *Server) modelProvider(ctx context.Context, model string) (llm.Provider, error) {
+	if s.tenants != nil {
+		return s.tenantProvider(ctx, model)
+	}
+	c := requestClient(ctx)
+	if model == "" && c != nil {
+		model = c.key.Model
+	}
+	if model == "" {
+		return s.provider, nil
+	}
+	auditFrom(ctx).update(func(record *AuditRecord) { record.Model = model })
+
+	provider, ok := s.models[model]
+	if !ok {
+		return nil, newError(http.StatusNotFound, CodeUnknownModel, fmt.Sprintf("unknown model: %s", model))
+	}
+	if c != nil && len(c.key.Models) > 0 && !slices.Contains(c.key.Models, model) {
+		return nil, newError(http.StatusForbidden, CodeForbidden, fmt.Sprintf("API key may not use model %s", model))
+	}
+	return provider, nil
+}
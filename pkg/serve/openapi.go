@@ -0,0 +1,205 @@
+package serve
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+)
+
+// object is a JSON object in the OpenAPI document
+type object = map[string]interface{}
+
+// docsPage is the Swagger UI page, loading the UI from a CDN
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>agentic-text API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: {{.}}, dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`))
+
+// handleOpenAPI serves the OpenAPI document
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) error {
+	writeJSON(w, http.StatusOK, s.OpenAPI())
+	return nil
+}
+
+// handleDocs serves a Swagger UI page for the OpenAPI document
+func (s *Server) handleDocs(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return docsPage.Execute(w, s.settings.routes.path(s.settings.routes.OpenAPI))
+}
+
+// OpenAPI returns an OpenAPI 3 document describing the server's enabled endpoints, with
+// each registered processor's result schema
+func (s *Server) OpenAPI() map[string]interface{} {
+	processors := processor.ListProcessors()
+	sort.Strings(processors)
+
+	schemas := object{}
+	var results []interface{}
+	for _, name := range processors {
+		description, err := processor.Describe(name)
+		if err != nil || description.ResultSchema == nil {
+			continue
+		}
+		schema := resultSchemaName(name)
+		schemas[schema] = description.ResultSchema
+		results = append(results, ref(schema))
+	}
+
+	// Requests name one of the registered processors
+	processorEnum := object{"type": "string", "enum": processors}
+	processRequest := processor.JSONSchema(ProcessRequest{})
+	processRequest["properties"].(object)["processor"] = processorEnum
+	batchRequest := processor.JSONSchema(BatchRequest{})
+	batchRequest["properties"].(object)["processor"] = processorEnum
+	jobRequest := processor.JSONSchema(JobRequest{})
+	jobRequest["properties"].(object)["processor"] = processorEnum
+	if models := s.modelNames(); len(models) > 0 {
+		modelEnum := object{"type": "string", "enum": models}
+		processRequest["properties"].(object)["model"] = modelEnum
+		batchRequest["properties"].(object)["model"] = modelEnum
+		jobRequest["properties"].(object)["model"] = modelEnum
+	}
+
+	processResponse := processor.JSONSchema(ProcessResponse{})
+	if len(results) > 0 {
+		processResponse["properties"].(object)["result"] = object{"oneOf": results}
+	}
+
+	schemas["ProcessRequest"] = processRequest
+	schemas["ProcessResponse"] = processResponse
+	schemas["ProcessorsResponse"] = processor.JSONSchema(ProcessorsResponse{})
+	schemas["BatchRequest"] = batchRequest
+	schemas["BatchResponse"] = processor.JSONSchema(BatchResponse{})
+	schemas["TokenEvent"] = processor.JSONSchema(TokenEvent{})
+	schemas["JobRequest"] = jobRequest
+	schemas["Job"] = processor.JSONSchema(Job{})
+	schemas["ErrorResponse"] = processor.JSONSchema(ErrorResponse{})
+
+	routes := s.settings.routes
+	paths := object{}
+	addPath := func(route, method string, operation object) {
+		path := routes.path(route)
+		if path == "" {
+			return
+		}
+		operation["responses"].(object)["default"] = response("Error", "application/json", ref("ErrorResponse"))
+		if paths[path] == nil {
+			paths[path] = object{}
+		}
+		paths[path].(object)[method] = operation
+	}
+
+	addPath(routes.Process, "post", object{
+		"operationId": "process",
+		"summary":     "Run a processor over a text",
+		"requestBody": requestBody(object{"application/json": object{"schema": ref("ProcessRequest")}}),
+		"responses":   object{"200": response("The processor's result", "application/json", ref("ProcessResponse"))},
+	})
+	addPath(routes.Processors, "get", object{
+		"operationId": "listProcessors",
+		"summary":     "List the processors the client may run",
+		"responses":   object{"200": response("The processors", "application/json", ref("ProcessorsResponse"))},
+	})
+	addPath(routes.Batch, "post", object{
+		"operationId": "processBatch",
+		"summary":     "Run a processor over a batch of texts or a JSON Lines upload",
+		"parameters": []interface{}{
+			queryParameter("processor", "The processor, for JSON Lines uploads", processorEnum),
+			queryParameter("model", "The model, for JSON Lines uploads", object{"type": "string"}),
+			queryParameter("concurrency", "The number of texts processed at once, for JSON Lines uploads", object{"type": "integer"}),
+			queryParameter("text_field", "The field holding each line's text (defaults to text)", object{"type": "string"}),
+		},
+		"requestBody": requestBody(object{
+			"application/json":     object{"schema": ref("BatchRequest")},
+			"application/x-ndjson": object{"schema": object{"type": "string"}},
+		}),
+		"responses": object{"200": response("Each item's result or error", "application/json", ref("BatchResponse"))},
+	})
+	addPath(routes.Stream, "post", object{
+		"operationId": "processStream",
+		"summary":     "Run a processor, streaming the model output as server-sent events",
+		"description": "Sends token events with TokenEvent data, then a result event with ProcessResponse data or an error event with ErrorDetail data.",
+		"requestBody": requestBody(object{"application/json": object{"schema": ref("ProcessRequest")}}),
+		"responses":   object{"200": response("Server-sent events", "text/event-stream", object{"type": "string"})},
+	})
+	addPath(routes.Jobs, "post", object{
+		"operationId": "submitJob",
+		"summary":     "Queue a batch or pipeline job",
+		"requestBody": requestBody(object{"application/json": object{"schema": ref("JobRequest")}}),
+		"responses":   object{"202": response("The queued job", "application/json", ref("Job"))},
+	})
+	if routes.Jobs != "-" {
+		addPath(routes.Jobs+"/{id}", "get", object{
+			"operationId": "getJob",
+			"summary":     "Get a job's status, progress and results so far",
+			"parameters": []interface{}{
+				object{"name": "id", "in": "path", "required": true, "schema": object{"type": "string"}},
+			},
+			"responses": object{"200": response("The job", "application/json", ref("Job"))},
+		})
+	}
+
+	components := object{"schemas": schemas}
+	document := object{
+		"openapi":    "3.0.3",
+		"info":       object{"title": "agentic-text API", "version": "1.0.0"},
+		"paths":      paths,
+		"components": components,
+	}
+	if len(s.settings.clients) > 0 {
+		components["securitySchemes"] = object{
+			"apiKey":     object{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			"bearerAuth": object{"type": "http", "scheme": "bearer"},
+		}
+		document["security"] = []interface{}{object{"apiKey": []string{}}, object{"bearerAuth": []string{}}}
+	}
+	return document
+}
+
+// resultSchemaName returns the schema name of a processor's result, e.g. KeywordExtractionResult
+func resultSchemaName(processorName string) string {
+	var name strings.Builder
+	for _, word := range strings.Split(processorName, "_") {
+		if word != "" {
+			name.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	name.WriteString("Result")
+	return name.String()
+}
+
+// ref returns a reference to a component schema
+func ref(schema string) object {
+	return object{"$ref": fmt.Sprintf("#/components/schemas/%s", schema)}
+}
+
+// requestBody returns a required request body with the given content
+func requestBody(content object) object {
+	return object{"required": true, "content": content}
+}
+
+// response returns a response with a body of one media type
+func response(description, mediaType string, schema object) object {
+	return object{"description": description, "content": object{mediaType: object{"schema": schema}}}
+}
+
+// queryParameter returns an optional query parameter
+func queryParameter(name, description string, schema object) object {
+	return object{"name": name, "in": "query", "description": description, "schema": schema}
+}
@@ -0,0 +1,418 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+	"github.com/eisenzopf/agentic-text/pkg/processor"
+
+	// Import the builtin processors
+	_ "github.com/eisenzopf/agentic-text/pkg/processor/builtin"
+)
+
+// Server serves the registered processors over HTTP
+type Server struct {
+	config    Config
+	settings  settings
+	provider  llm.Provider
+	models    map[string]llm.Provider
+	tenants   *llm.ProviderPool
+	pipelines map[string]pipeline.Pipeline
+	jobs      *jobQueue
+	health    providerHealth
+	metrics   *metrics
+	audit     *auditor
+	stopping  atomic.Bool
+	mux       *http.ServeMux
+}
+
+// ProcessRequest is the body of a process request
+type ProcessRequest struct {
+	// Text is the text to process
+	Text string `json:"text"`
+	// Processor is the registered processor type to run
+	Processor string `json:"processor"`
+	// Model is the name of a model from the server config to use instead of the default
+	Model string `json:"model,omitempty"`
+	// Options are LLM options for this request, added to the server's default options; only
+	// the options listed in the config's RequestOptions may be set
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// ProcessResponse is the body of a successful process response
+type ProcessResponse struct {
+	Original string      `json:"original"`
+	Result   interface{} `json:"result"`
+	Success  bool        `json:"success"`
+}
+
+// ProcessorsResponse is the body of a processor list response
+type ProcessorsResponse struct {
+	Processors []string `json:"processors"`
+	Count      int      `json:"count"`
+}
+
+// handlerFunc is an endpoint handler; a returned error is sent as an ErrorResponse
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// New creates a server whose provider is created from the config
+func New(config Config) (*Server, error) {
+	provider, err := config.Provider.NewProvider()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	return NewWithProvider(config, provider)
+}
+
+// NewWithProvider creates a server using an existing provider; the config's Provider is ignored
+func NewWithProvider(config Config, provider llm.Provider) (*Server, error) {
+	settings, err := config.settings()
+	if err != nil {
+		return nil, fmt.Errorf("invalid server config: %w", err)
+	}
+
+	var tenants *llm.ProviderPool
+	if len(config.Tenants) > 0 {
+		if tenants, err = pipeline.NewProviderPool(config.Tenants); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range settings.clients {
+		if c.key.Tenant == "" {
+			continue
+		}
+		if tenants == nil {
+			return nil, fmt.Errorf("API key %s: tenant set but no tenants are configured", c.key.Name)
+		}
+		if _, err := tenants.Provider(c.key.Tenant); err != nil {
+			return nil, fmt.Errorf("API key %s: %w", c.key.Name, err)
+		}
+	}
+
+	pipelines := make(map[string]pipeline.Pipeline, len(config.Pipelines))
+	for name, path := range config.Pipelines {
+		p, err := loadPipeline(path, tenants)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pipeline %s: %w", name, err)
+		}
+		pipelines[name] = p
+	}
+
+	models, err := config.newModels()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range settings.clients {
+		for _, model := range append(slices.Clip(c.key.Models), c.key.Model) {
+			if _, ok := models[model]; model != "" && !ok {
+				return nil, fmt.Errorf("API key %s: unknown model %s", c.key.Name, model)
+			}
+		}
+	}
+
+	store := config.JobStore
+	if store == nil {
+		store = NewMemoryJobStore(settings.jobRetention, settings.maxStoredJobs)
+	}
+
+	audit, err := newAuditor(config)
+	if err != nil {
+		return nil, err
+	}
+	if audit != nil {
+		// Providers are metered so each request's audit record has its token usage
+		provider = llm.WithMetering(provider, recordUsage)
+		for name, model := range models {
+			models[name] = llm.WithMetering(model, recordUsage)
+		}
+	}
+
+	s := &Server{
+		config:    config,
+		settings:  settings,
+		provider:  provider,
+		models:    models,
+		tenants:   tenants,
+		pipelines: pipelines,
+		jobs:      newJobQueue(store, settings.jobWorkers, settings.jobQueueSize),
+		metrics:   newMetrics(),
+		audit:     audit,
+		mux:       http.NewServeMux(),
+	}
+	s.handle(http.MethodPost, settings.routes.Process, s.handleProcess)
+	s.handle(http.MethodGet, settings.routes.Processors, s.handleProcessors)
+	s.handle(http.MethodPost, settings.routes.Batch, s.handleBatch)
+	s.handle(http.MethodPost, settings.routes.Stream, s.handleStream)
+	s.handle(http.MethodPost, settings.routes.Jobs, s.handleSubmitJob)
+	if settings.routes.Jobs != "-" {
+		s.handle(http.MethodGet, settings.routes.Jobs+"/{id}", s.handleGetJob)
+	}
+	s.handlePublic(http.MethodGet, settings.routes.OpenAPI, s.handleOpenAPI)
+	if settings.routes.OpenAPI != "-" {
+		s.handlePublic(http.MethodGet, settings.routes.Docs, s.handleDocs)
+	}
+	s.route(http.MethodGet, settings.routes.rootPath(settings.routes.Health), s.handleHealth, false)
+	s.route(http.MethodGet, settings.routes.rootPath(settings.routes.Ready), s.handleReady, false)
+	s.route(http.MethodGet, settings.routes.rootPath(settings.routes.Metrics), s.handleMetrics, false)
+	return s, nil
+}
+
+// Close stops the job workers and closes the audit log. Jobs still running or queued are
+// marked failed.
+func (s *Server) Close() error {
+	s.jobs.close()
+	s.audit.close()
+	return nil
+}
+
+// Handler returns the server's HTTP handler, for embedding in another server or mux
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe listens on the configured address and serves requests until ctx is done,
+// then shuts down gracefully, letting in-flight requests finish within the shutdown timeout
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.settings.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.settings.addr, err)
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves requests on listener until ctx is done, then shuts down gracefully and
+// closes the server. Unless the config's SkipModelCheck is set, it first checks that the
+// providers are reachable and their models exist.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	defer s.Close()
+
+	if !s.config.SkipModelCheck {
+		if err := s.checkModels(ctx); err != nil {
+			listener.Close()
+			return err
+		}
+	}
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadTimeout:       s.settings.readTimeout,
+		ReadHeaderTimeout: s.settings.readTimeout,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Readiness probes fail from here on so load balancers stop sending requests
+	s.stopping.Store(true)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.settings.shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down gracefully: %w", err)
+	}
+	return nil
+}
+
+// handle registers a handler for one method on a route, unless the route is disabled.
+// Requests are authenticated before the handler runs, and audited.
+func (s *Server) handle(method, route string, handler handlerFunc) {
+	s.route(method, s.settings.routes.path(route), handler, true)
+}
+
+// handlePublic registers a handler like handle, without authentication
+func (s *Server) handlePublic(method, route string, handler handlerFunc) {
+	s.route(method, s.settings.routes.path(route), handler, false)
+}
+
+// route registers a handler for one method on a full path, unless the path is empty.
+// Every request is recorded in the metrics; authenticated requests are also audited.
+func (s *Server) route(method, path string, handler handlerFunc, authenticate bool) {
+	if path == "" {
+		return
+	}
+
+	s.mux.HandleFunc(path, func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		w := &statusRecorder{ResponseWriter: rw}
+
+		var audit *auditEntry
+		if authenticate && s.audit != nil {
+			audit = s.audit.newAuditEntry(r, path)
+			w.Header().Set("X-Request-ID", audit.record.RequestID)
+			r = r.WithContext(context.WithValue(r.Context(), auditKey{}, audit))
+		}
+
+		defer func() {
+			if w.status == 0 {
+				w.status = http.StatusOK
+			}
+			s.metrics.observe(path, r.Method, w.status, time.Since(start))
+			if audit != nil {
+				audit.update(func(record *AuditRecord) { record.Status = w.status })
+				s.audit.record(r.Context(), audit)
+			}
+		}()
+
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			err := newError(http.StatusMethodNotAllowed, CodeMethodNotAllowed,
+				fmt.Sprintf("method %s not allowed, use %s", r.Method, method))
+			audit.fail(err)
+			writeError(w, err)
+			return
+		}
+		if authenticate {
+			c, err := s.authenticate(r)
+			if err != nil {
+				audit.fail(err)
+				writeAuthError(w, err)
+				return
+			}
+			if c != nil {
+				audit.update(func(record *AuditRecord) { record.Caller = c.key.Name })
+				r = r.WithContext(context.WithValue(r.Context(), clientKey{}, c))
+			}
+			if s.tenants != nil {
+				tenant, err := s.requestTenant(r, c)
+				if err != nil {
+					audit.fail(err)
+					writeError(w, err)
+					return
+				}
+				audit.update(func(record *AuditRecord) { record.Tenant = tenant })
+				r = r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant))
+			}
+		}
+		if err := handler(w, r); err != nil {
+			audit.fail(err)
+			writeError(w, err)
+		}
+	})
+}
+
+// handleProcess runs a processor over the request's text
+func (s *Server) handleProcess(w http.ResponseWriter, r *http.Request) error {
+	var req ProcessRequest
+	if err := s.decode(w, r, &req); err != nil {
+		return err
+	}
+	if req.Text == "" {
+		return newError(http.StatusBadRequest, CodeInvalidRequest, "text is required")
+	}
+	auditFrom(r.Context()).addInput(req.Text)
+
+	proc, err := s.newProcessor(r.Context(), req.Processor, req.Model, req.Options)
+	if err != nil {
+		return err
+	}
+
+	result, err := proc.Process(r.Context(), data.NewTextProcessItem("api-request", req.Text, nil))
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, ProcessResponse{
+		Original: req.Text,
+		Result:   result.ProcessingInfo[req.Processor],
+		Success:  true,
+	})
+	return nil
+}
+
+// handleProcessors lists the registered processors the client may run
+func (s *Server) handleProcessors(w http.ResponseWriter, r *http.Request) error {
+	processors := slices.DeleteFunc(processor.ListProcessors(), func(name string) bool {
+		return !allowProcessor(r.Context(), name)
+	})
+	sort.Strings(processors)
+	writeJSON(w, http.StatusOK, ProcessorsResponse{Processors: processors, Count: len(processors)})
+	return nil
+}
+
+// newProcessor creates a registered processor using the selected model, with the server's
+// LLM options overridden by the request's, if the request's client may run it and the
+// config allows requests to set those options
+func (s *Server) newProcessor(ctx context.Context, name, model string, options map[string]interface{}) (processor.Processor, error) {
+	if name == "" {
+		return nil, newError(http.StatusBadRequest, CodeInvalidRequest, "processor is required")
+	}
+	auditFrom(ctx).update(func(record *AuditRecord) { record.Processor = name })
+	if !slices.Contains(processor.ListProcessors(), name) {
+		return nil, newError(http.StatusNotFound, CodeUnknownProcessor, fmt.Sprintf("unknown processor: %s", name))
+	}
+	if !allowProcessor(ctx, name) {
+		return nil, newError(http.StatusForbidden, CodeForbidden, fmt.Sprintf("API key may not run processor %s", name))
+	}
+	provider, err := s.modelProvider(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+
+	llmOptions := make(map[string]interface{}, len(s.config.Options)+len(options))
+	for k, v := range s.config.Options {
+		llmOptions[k] = v
+	}
+	for k, v := range options {
+		if !s.settings.requestOptions[k] {
+			return nil, newError(http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("option %s may not be set by requests", k))
+		}
+		llmOptions[k] = v
+	}
+
+	proc, err := processor.Create(name, provider, processor.Options{LLMOptions: llmOptions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create processor %s: %w", name, err)
+	}
+	return proc, nil
+}
+
+// loadPipeline loads a pipeline config file, building it with the server's tenants, if
+// any, so their budgets cover the pipeline's calls too
+func loadPipeline(path string, tenants *llm.ProviderPool) (pipeline.Pipeline, error) {
+	if tenants == nil {
+		return pipeline.LoadFromFile(path)
+	}
+	config, err := pipeline.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.BuildWithProviderPool(tenants)
+}
+
+// decode reads a JSON request body into v, enforcing the body size limit
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.settings.maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return bodyError(err)
+	}
+	return nil
+}
+
+// bodyError converts a failure to read a request body into an APIError
+func bodyError(err error) *APIError {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return newError(http.StatusRequestEntityTooLarge, CodeRequestTooLarge,
+			fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+	}
+	return newError(http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("invalid request body: %v", err))
+}
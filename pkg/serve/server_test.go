@@ -0,0 +1,96 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// sentimentResponse is a valid response of the builtin sentiment processor
+const sentimentResponse = `{"sentiment": "positive", "score": 0.8, "confidence": 0.9, "keywords": ["great"]}`
+
+// newTestServer creates a server answering every prompt with sentimentResponse
+func newTestServer(t *testing.T, config Config) *Server {
+	t.Helper()
+	return newTestServerWithProvider(t, config, llm.NewMockProviderWithResponse(sentimentResponse))
+}
+
+// newTestServerWithProvider creates a server using provider
+func newTestServerWithProvider(t *testing.T, config Config, provider llm.Provider) *Server {
+	t.Helper()
+	s, err := NewWithProvider(config, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// do sends a request to the server and returns the recorded response
+func do(s *Server, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	return w
+}
+
+// errorCode returns the code of an error response
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var response ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid error response %q: %v", w.Body.String(), err)
+	}
+	return response.Error.Code
+}
+
+func TestProcessRequestOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestOptions []string
+		options        string
+		wantStatus     int
+		wantDebug      bool
+	}{
+		{name: "no options", options: `{}`, wantStatus: http.StatusOK},
+		{name: "default allow-list", options: `{"json_output": false}`, wantStatus: http.StatusOK},
+		{name: "debug rejected by default", options: `{"debug": true}`, wantStatus: http.StatusBadRequest},
+		{name: "provider option rejected", options: `{"temperature": 2}`, wantStatus: http.StatusBadRequest},
+		{name: "debug allowed by config", requestOptions: []string{"debug"}, options: `{"debug": true}`, wantStatus: http.StatusOK, wantDebug: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, Config{RequestOptions: tt.requestOptions})
+			w := do(s, http.MethodPost, "/api/process",
+				`{"text": "Great support!", "processor": "sentiment", "options": `+tt.options+`}`, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				if code := errorCode(t, w); code != CodeInvalidRequest {
+					t.Errorf("expected code %s, got %s", CodeInvalidRequest, code)
+				}
+				return
+			}
+
+			var response struct {
+				Result map[string]interface{} `json:"result"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if _, debug := response.Result["debug"]; debug != tt.wantDebug {
+				t.Errorf("expected debug output %v, got %v", tt.wantDebug, debug)
+			}
+		})
+	}
+}
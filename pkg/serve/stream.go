@@ -0,0 +1,86 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// Server-sent event types sent by the stream endpoint
+const (
+	// EventToken carries a TokenEvent with a chunk of the raw model output
+	EventToken = "token"
+	// EventResult carries the final ProcessResponse and ends the stream
+	EventResult = "result"
+	// EventError carries an ErrorDetail and ends the stream
+	EventError = "error"
+)
+
+// TokenEvent is the data of a token event
+type TokenEvent struct {
+	Chunk string `json:"chunk"`
+}
+
+// handleStream runs a processor over the request's text like handleProcess, streaming
+// the raw model output as token events followed by a result or error event
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) error {
+	var req ProcessRequest
+	if err := s.decode(w, r, &req); err != nil {
+		return err
+	}
+	if req.Text == "" {
+		return newError(http.StatusBadRequest, CodeInvalidRequest, "text is required")
+	}
+
+	auditFrom(r.Context()).addInput(req.Text)
+
+	proc, err := s.newProcessor(r.Context(), req.Processor, req.Model, req.Options)
+	if err != nil {
+		return err
+	}
+
+	// Request errors above are plain error responses; from here on they are events
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	events := &eventWriter{w: w, rc: http.NewResponseController(w)}
+
+	ctx := llm.WithStream(r.Context(), func(chunk string) {
+		events.send(EventToken, TokenEvent{Chunk: chunk})
+	})
+	result, err := proc.Process(ctx, data.NewTextProcessItem("api-request", req.Text, nil))
+	if err != nil {
+		apiErr := asAPIError(err)
+		auditFrom(r.Context()).update(func(record *AuditRecord) { record.Error = apiErr.Code })
+		events.send(EventError, ErrorDetail{Code: apiErr.Code, Message: apiErr.Message})
+		return nil
+	}
+
+	events.send(EventResult, ProcessResponse{
+		Original: req.Text,
+		Result:   result.ProcessingInfo[req.Processor],
+		Success:  true,
+	})
+	return nil
+}
+
+// eventWriter writes server-sent events, flushing each one to the client
+type eventWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// send writes an event with JSON data. Write errors mean the client has gone away, which
+// the request context already reports, so they are ignored.
+func (e *eventWriter) send(event string, value interface{}) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event, payload)
+	e.rc.Flush()
+}
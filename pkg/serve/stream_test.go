@@ -0,0 +1,127 @@
+package serve
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+)
+
+// event is a parsed server-sent event
+type event struct {
+	name string
+	data string
+}
+
+// parseEvents splits a server-sent event stream into events, failing on malformed framing
+func parseEvents(t *testing.T, body string) []event {
+	t.Helper()
+	if !strings.HasSuffix(body, "\n\n") {
+		t.Fatalf("expected the stream to end with a blank line, got %q", body)
+	}
+
+	var (
+		events  []event
+		current event
+	)
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if current.name == "" || current.data == "" {
+				t.Fatalf("expected an event and data line before each blank line, got %+v", current)
+			}
+			events = append(events, current)
+			current = event{}
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+			if !json.Valid([]byte(current.data)) {
+				t.Fatalf("expected JSON data on one line, got %q", current.data)
+			}
+		default:
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+	return events
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   llm.Provider
+		body       string
+		wantStatus int
+		wantEvents []string
+		wantLast   string
+	}{
+		{
+			name:       "tokens then result",
+			provider:   llm.NewMockProviderWithResponse(sentimentResponse),
+			body:       `{"text": "Great support!", "processor": "sentiment"}`,
+			wantStatus: http.StatusOK,
+			wantEvents: []string{EventToken, EventResult},
+			wantLast:   `"sentiment":"positive"`,
+		},
+		{
+			name:       "processing error as an event",
+			provider:   llm.NewMockProviderWithResponse(sentimentResponse).WithError(errors.New("provider unavailable")),
+			body:       `{"text": "Great support!", "processor": "sentiment"}`,
+			wantStatus: http.StatusOK,
+			wantEvents: []string{EventError},
+			wantLast:   `"code":"` + CodeProcessingFailed + `"`,
+		},
+		{
+			name:       "request error before the stream",
+			provider:   llm.NewMockProviderWithResponse(sentimentResponse),
+			body:       `{"processor": "sentiment"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServerWithProvider(t, Config{}, tt.provider)
+			w := do(s, http.MethodPost, "/api/process/stream", tt.body, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				if contentType := w.Header().Get("Content-Type"); contentType == "text/event-stream" {
+					t.Error("expected a plain error response, got an event stream")
+				}
+				if code := errorCode(t, w); code != CodeInvalidRequest {
+					t.Errorf("expected code %s, got %s", CodeInvalidRequest, code)
+				}
+				return
+			}
+
+			if contentType := w.Header().Get("Content-Type"); contentType != "text/event-stream" {
+				t.Errorf("expected content type text/event-stream, got %s", contentType)
+			}
+			events := parseEvents(t, w.Body.String())
+			var names []string
+			for _, e := range events {
+				names = append(names, e.name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantEvents, ",") {
+				t.Fatalf("expected events %v, got %v", tt.wantEvents, names)
+			}
+			if last := events[len(events)-1].data; !strings.Contains(last, tt.wantLast) {
+				t.Errorf("expected the last event to contain %s, got %s", tt.wantLast, last)
+			}
+			if tt.wantEvents[0] == EventToken {
+				var token TokenEvent
+				if err := json.Unmarshal([]byte(events[0].data), &token); err != nil || token.Chunk != sentimentResponse {
+					t.Errorf("expected the raw model output as a token, got %s", events[0].data)
+				}
+			}
+		})
+	}
+}
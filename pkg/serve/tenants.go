@@ -0,0 +1,80 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eisenzopf/agentic-text/pkg/data"
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+)
+
+// TenantHeader is the header naming the tenant a request is made for, for API keys not
+// bound to a tenant
+const TenantHeader = "X-Tenant-ID"
+
+// tenantKey is the context key of the request's tenant
+type tenantKey struct{}
+
+// requestTenant returns the tenant a request is made for: the tenant its API key is bound
+// to, or the one its TenantHeader names
+func (s *Server) requestTenant(r *http.Request, c *client) (string, error) {
+	tenant := strings.TrimSpace(r.Header.Get(TenantHeader))
+	if c != nil && c.key.Tenant != "" {
+		if tenant != "" && tenant != c.key.Tenant {
+			return "", newError(http.StatusForbidden, CodeForbidden, fmt.Sprintf("API key may not act for tenant %s", tenant))
+		}
+		tenant = c.key.Tenant
+	}
+	if tenant == "" {
+		return "", newError(http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("a tenant is required; set the %s header", TenantHeader))
+	}
+	if _, err := s.tenants.Provider(tenant); err != nil {
+		return "", newError(http.StatusNotFound, CodeUnknownTenant, fmt.Sprintf("unknown tenant: %s", tenant))
+	}
+	return tenant, nil
+}
+
+// tenantFrom returns the request's tenant, or "" if the server has no tenants
+func tenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// tenantProvider returns the provider of the request's tenant. Tenants use the provider
+// their config declares, so requests can't select a model.
+func (s *Server) tenantProvider(ctx context.Context, model string) (llm.Provider, error) {
+	tenant := tenantFrom(ctx)
+	if model != "" {
+		return nil, newError(http.StatusBadRequest, CodeInvalidRequest,
+			fmt.Sprintf("tenant %s uses the model its config declares; model can't be set", tenant))
+	}
+	if exceeded, _ := s.tenants.Exceeded(tenant); exceeded {
+		return nil, budgetError(tenant)
+	}
+	provider, err := s.tenants.Provider(tenant)
+	if err != nil {
+		return nil, err
+	}
+	if s.audit != nil {
+		provider = llm.WithMetering(provider, recordUsage)
+	}
+	return provider, nil
+}
+
+// budgetError is the error of a request refused because its tenant's budget is used up
+func budgetError(tenant string) *APIError {
+	return newError(http.StatusTooManyRequests, CodeBudgetExceeded, fmt.Sprintf("tenant %s has used up its budget", tenant))
+}
+
+// tenantItem creates a text item whose metadata names the request's tenant, so pipelines
+// run it with the tenant's providers
+func tenantItem(ctx context.Context, id, text string) *data.ProcessItem {
+	var metadata map[string]interface{}
+	if tenant := tenantFrom(ctx); tenant != "" {
+		metadata = map[string]interface{}{pipeline.TenantField: tenant}
+	}
+	return data.NewTextProcessItem(id, text, metadata)
+}
@@ -0,0 +1,79 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/eisenzopf/agentic-text/pkg/llm"
+	"github.com/eisenzopf/agentic-text/pkg/pipeline"
+)
+
+func TestTenants(t *testing.T) {
+	tenant := func(score string, budget llm.TenantBudget) pipeline.TenantConfig {
+		return pipeline.TenantConfig{
+			Provider: pipeline.ProviderConfig{Type: "mock", Model: "mock", Options: map[string]interface{}{
+				"response": `{"sentiment": "positive", "score": ` + score + `, "confidence": 0.9, "keywords": []}`,
+			}},
+			Budget: budget,
+		}
+	}
+	config := Config{
+		Tenants: map[string]pipeline.TenantConfig{
+			"acme":   tenant("0.1", llm.TenantBudget{}),
+			"globex": tenant("0.2", llm.TenantBudget{MaxTokens: 1}),
+		},
+		Auth: AuthConfig{Keys: []APIKey{
+			{Name: "shared", Key: "shared-key"},
+			{Name: "acme", Key: "acme-key", Tenant: "acme"},
+		}},
+	}
+	s := newTestServer(t, config)
+
+	tests := []struct {
+		name       string
+		key        string
+		tenant     string
+		wantStatus int
+		wantCode   string
+		wantScore  float64
+	}{
+		{name: "tenant header", key: "shared-key", tenant: "acme", wantStatus: http.StatusOK, wantScore: 0.1},
+		{name: "bound key", key: "acme-key", wantStatus: http.StatusOK, wantScore: 0.1},
+		{name: "bound key, other tenant", key: "acme-key", tenant: "globex", wantStatus: http.StatusForbidden, wantCode: CodeForbidden},
+		{name: "no tenant", key: "shared-key", wantStatus: http.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{name: "unknown tenant", key: "shared-key", tenant: "initech", wantStatus: http.StatusNotFound, wantCode: CodeUnknownTenant},
+		// The first call uses up globex's budget of one token
+		{name: "within budget", key: "shared-key", tenant: "globex", wantStatus: http.StatusOK, wantScore: 0.2},
+		{name: "budget used up", key: "shared-key", tenant: "globex", wantStatus: http.StatusTooManyRequests, wantCode: CodeBudgetExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"X-Api-Key": {tt.key}}
+			if tt.tenant != "" {
+				header.Set(TenantHeader, tt.tenant)
+			}
+			w := do(s, http.MethodPost, "/api/process", `{"text": "Great support!", "processor": "sentiment"}`, header)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, w); code != tt.wantCode {
+					t.Errorf("expected code %s, got %s", tt.wantCode, code)
+				}
+				return
+			}
+
+			var response struct {
+				Result map[string]interface{} `json:"result"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if score := response.Result["score"]; score != tt.wantScore {
+				t.Errorf("expected the tenant's score %v, got %v", tt.wantScore, score)
+			}
+		})
+	}
+}